@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/compare"
+)
+
+// runCompare compares the latencies of two runs' sample CSVs and reports
+// which differences are statistically significant
+func runCompare(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	alpha := fs.Float64("alpha", compare.DefaultOptions.Alpha, "significance level; confidence intervals are at 1-alpha")
+	iterations := fs.Int("bootstrap", compare.DefaultOptions.Iterations, "number of bootstrap resamples for confidence intervals")
+	seed := fs.Uint64("seed", 0, "bootstrap random seed, for reproducible intervals")
+
+	if err := fs.Parse(args); err != nil {
+		return agent.ExitInvalid
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "Usage: agent compare [flags] <baseline-samples.csv> <current-samples.csv>")
+		return agent.ExitInvalid
+	}
+	if *alpha <= 0 || *alpha >= 1 || *iterations <= 0 {
+		fmt.Fprintln(stderr, "error: -alpha must be between 0 and 1 and -bootstrap greater than 0")
+		return agent.ExitInvalid
+	}
+
+	var runs [2]*compare.Run
+	for i, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+		runs[i], err = compare.ReadSamples(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %s: %v\n", path, err)
+			return agent.ExitInvalid
+		}
+	}
+
+	opts := compare.DefaultOptions
+	opts.Alpha, opts.Iterations, opts.Seed = *alpha, *iterations, *seed
+	results := compare.Compare(runs[0], runs[1], opts)
+
+	confidence := fmt.Sprintf("%g%% ci", (1-*alpha)*100)
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "step\tbase p50\tbase p95\tcur p50\tcur p95\tp95 change\t%s\tp-value\tresult\t\n", confidence)
+	for _, c := range results {
+		verdict := "not significant"
+		if c.Significant {
+			verdict = "SIGNIFICANT"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t[%s, %s]\t%.4f\t%s\t\n",
+			c.Step, formatMS(c.Baseline.P50), formatMS(c.Baseline.P95),
+			formatMS(c.Current.P50), formatMS(c.Current.P95),
+			formatMS(c.Current.P95-c.Baseline.P95), formatMS(c.P95Low), formatMS(c.P95High),
+			c.PValue, verdict)
+	}
+	tw.Flush()
+	return agent.ExitOK
+}
+
+// formatMS formats a latency or latency difference in milliseconds
+func formatMS(ms float64) string {
+	if ms == 0 {
+		return "0s"
+	}
+	return time.Duration(ms * float64(time.Millisecond)).Round(100 * time.Microsecond).String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/metrics"
+)
+
+// dashboardInterval is how often the live dashboard refreshes
+const dashboardInterval = time.Second
+
+// dashboard renders per-step metrics of the last interval while a run is in
+// progress. On a terminal every frame redraws the previous one in place;
+// otherwise each refresh appends plain lines that are readable in CI logs.
+type dashboard struct {
+	w      io.Writer
+	tty    bool
+	window *metrics.Window
+	vus    func() int64
+	maxVUs uint64
+	paused func() bool
+	// keys is set when pauseKey presses are read from the terminal
+	keys bool
+
+	// lines is the height of the last terminal frame, erased before the next
+	lines int
+}
+
+func (d *dashboard) run(ctx context.Context) {
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := start
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			interval := d.window.Flush(now.Sub(last))
+			last = now
+			if d.tty {
+				d.redraw(now.Sub(start), interval)
+			} else {
+				d.print(now.Sub(start), interval)
+			}
+		}
+	}
+}
+
+func (d *dashboard) redraw(elapsed time.Duration, s metrics.Summary) {
+	var frame bytes.Buffer
+	fmt.Fprintf(&frame, "elapsed %s  VUs %d/%d", elapsed.Round(time.Second), d.vus(), d.maxVUs)
+	switch {
+	case d.paused() && d.keys:
+		fmt.Fprintf(&frame, "  PAUSED, press %c to resume", pauseKey)
+	case d.paused():
+		fmt.Fprint(&frame, "  PAUSED")
+	case d.keys:
+		fmt.Fprintf(&frame, "  press %c to pause", pauseKey)
+	}
+	fmt.Fprint(&frame, "\n\n")
+
+	tw := tabwriter.NewWriter(&frame, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "step\trps\tp95\terrors\t")
+	for _, step := range s.Steps {
+		printDashboardRow(tw, step)
+	}
+	printDashboardRow(tw, s.Total)
+	tw.Flush()
+
+	if d.lines > 0 {
+		// Move the cursor back to the top of the previous frame and clear it
+		fmt.Fprintf(d.w, "\x1b[%dA\x1b[J", d.lines)
+	}
+	d.lines = strings.Count(frame.String(), "\n")
+	d.w.Write(frame.Bytes())
+}
+
+func (d *dashboard) print(elapsed time.Duration, s metrics.Summary) {
+	prefix := fmt.Sprintf("[%s] vus=%d", elapsed.Round(time.Second), d.vus())
+	if d.paused() {
+		prefix += " paused"
+	}
+	for _, step := range append(s.Steps, s.Total) {
+		fmt.Fprintf(d.w, "%s %s: rps=%.1f p95=%s errors=%.2f%%\n",
+			prefix, step.Name, step.RPS, formatLatency(step.Latency.P95), step.ErrorRate*100)
+	}
+}
+
+// pauseKey toggles pausing the run from the terminal dashboard
+const pauseKey = 'p'
+
+// readKeys pauses a on a press of pauseKey read from in, and resumes it on
+// the next, until reading fails
+func readKeys(in io.Reader, a *agent.Agent) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := in.Read(buf); err != nil {
+			return
+		}
+		if buf[0] == pauseKey && !a.Pause() {
+			a.Resume()
+		}
+	}
+}
+
+func printDashboardRow(w io.Writer, s metrics.StepStats) {
+	fmt.Fprintf(w, "%s\t%.1f\t%s\t%.2f%%\t\n",
+		s.Name, s.RPS, formatLatency(s.Latency.P95), s.ErrorRate*100)
+}
+
+// isTerminal reports whether w is attached to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/openapi"
+	"loadforge-agent/internal/scenario"
+)
+
+// runGenerate converts an OpenAPI document into a scenario file
+func runGenerate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	tags := fs.String("tags", "", "comma-separated tags; only their operations become steps (default all operations)")
+	methods := fs.String("methods", "", "comma-separated methods, e.g. GET,POST; only their operations become steps")
+	paths := fs.String("paths", "", "comma-separated path prefixes, e.g. /v2/; only the operations under them become steps")
+	includeDeprecated := fs.Bool("include-deprecated", false, "also generate steps for operations marked deprecated")
+	baseURL := fs.String("base-url", "", "base URL of the scenario (default the document's first server)")
+	serverVars := fs.String("server-vars", "", "comma-separated name=value variables of the document's first server, e.g. region=eu (default their defaults)")
+	vus := fs.Uint64("vus", 1, "virtual users of the scenario")
+	duration := fs.Uint64("duration", 60, "duration of the scenario in seconds")
+	out := fs.String("o", "", "write the scenario to this file instead of stdout")
+
+	if err := fs.Parse(args); err != nil {
+		return agent.ExitInvalid
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: agent generate [flags] <openapi.yaml or URL>")
+		return agent.ExitInvalid
+	}
+
+	spec := fs.Arg(0)
+	doc := openapi.New()
+	if err := doc.Load(spec); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	opts := openapi.GenerateOptions{BaseURL: *baseURL, VirtualUsers: *vus, Duration: *duration, IncludeDeprecated: *includeDeprecated}
+	if *tags != "" {
+		opts.Tags = strings.Split(*tags, ",")
+	}
+	if *methods != "" {
+		opts.Methods = strings.Split(*methods, ",")
+	}
+	if *paths != "" {
+		opts.PathPrefixes = strings.Split(*paths, ",")
+	}
+	if *serverVars != "" {
+		opts.ServerVariables = make(map[string]string)
+		for _, pair := range strings.Split(*serverVars, ",") {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok || name == "" {
+				fmt.Fprintf(stderr, "error: invalid server variable %q, expected name=value\n", pair)
+				return agent.ExitInvalid
+			}
+			opts.ServerVariables[name] = value
+		}
+	}
+	sc, err := doc.Generate(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	sc.OpenAPI = &scenario.OpenAPI{Spec: specPath(spec, *out), Path: spec}
+	if sc.OpenAPI.Pinned, err = agent.PinEndpoints(sc); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInternal
+	}
+
+	data, err := yaml.Marshal(sc)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInternal
+	}
+	if *out == "" {
+		stdout.Write(data)
+		return agent.ExitOK
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInternal
+	}
+	return agent.ExitOK
+}
+
+// specPath returns the path of spec relative to the directory of the
+// scenario written to out, or to the working directory for stdout. URLs
+// are kept as they are.
+func specPath(spec, out string) string {
+	if openapi.IsURL(spec) {
+		return spec
+	}
+	abs, err := filepath.Abs(spec)
+	if err != nil {
+		return spec
+	}
+	dir, err := filepath.Abs(filepath.Dir(out))
+	if out == "" {
+		dir, err = os.Getwd()
+	}
+	if err != nil {
+		return abs
+	}
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return rel
+	}
+	return abs
+}
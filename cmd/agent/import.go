@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/har"
+	"loadforge-agent/internal/jmeter"
+	"loadforge-agent/internal/postman"
+	"loadforge-agent/internal/scenario"
+)
+
+// runImport converts a Postman collection, a HAR file or a JMeter test
+// plan into a scenario file
+func runImport(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	env := fs.String("env", "", "Postman environment file whose values override the collection's variables")
+	origin := fs.String("origin", "", "for HAR files, the origin whose requests become steps, e.g. https://app.example.com (default the one most requests were sent to)")
+	includeStatic := fs.Bool("include-static", false, "for HAR files, also keep the requests of scripts, stylesheets, images and fonts")
+	baseURL := fs.String("base-url", "", "base URL of the scenario, replacing the scheme and host of every request (default that of the first request, or the HAR origin)")
+	vus := fs.Uint64("vus", 1, "virtual users of the scenario (default for JMeter test plans the threads of the thread groups)")
+	duration := fs.Uint64("duration", 60, "duration of the scenario in seconds (default for JMeter test plans that of the thread groups)")
+	out := fs.String("o", "", "write the scenario to this file instead of stdout")
+
+	if err := fs.Parse(args); err != nil {
+		return agent.ExitInvalid
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: agent import [flags] <collection.json, recording.har or plan.jmx>")
+		return agent.ExitInvalid
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to read file: %v\n", err)
+		return agent.ExitInvalid
+	}
+
+	var (
+		sc       *scenario.Scenario
+		warnings []string
+	)
+	if jmeter.IsPlan(data) {
+		// A test plan sets the load unless the flags override it
+		opts := jmeter.Options{BaseURL: *baseURL}
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "vus":
+				opts.VirtualUsers = *vus
+			case "duration":
+				opts.Duration = *duration
+			}
+		})
+		sc, warnings, err = importJMeter(data, opts)
+	} else if har.IsHAR(data) {
+		opts := har.Options{Origin: *origin, BaseURL: *baseURL, VirtualUsers: *vus, Duration: *duration, IncludeStatic: *includeStatic}
+		sc, warnings, err = importHAR(data, opts)
+	} else {
+		opts := postman.Options{BaseURL: *baseURL, VirtualUsers: *vus, Duration: *duration}
+		sc, warnings, err = importPostman(data, *env, opts)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "warning: %s\n", w)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+
+	data, err = yaml.Marshal(sc)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInternal
+	}
+	if *out == "" {
+		stdout.Write(data)
+		return agent.ExitOK
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInternal
+	}
+	return agent.ExitOK
+}
+
+// importHAR converts a HAR file
+func importHAR(data []byte, opts har.Options) (*scenario.Scenario, []string, error) {
+	archive, err := har.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return har.Convert(archive, opts)
+}
+
+// importJMeter converts a JMeter test plan
+func importJMeter(data []byte, opts jmeter.Options) (*scenario.Scenario, []string, error) {
+	plan, err := jmeter.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return jmeter.Convert(plan, opts)
+}
+
+// importPostman converts a Postman collection, with the values of the
+// environment file env if set
+func importPostman(data []byte, env string, opts postman.Options) (*scenario.Scenario, []string, error) {
+	collection, err := postman.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if env != "" {
+		data, err := os.ReadFile(env)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		if opts.Environment, err = postman.ParseEnvironment(data); err != nil {
+			return nil, nil, err
+		}
+	}
+	return postman.Convert(collection, opts)
+}
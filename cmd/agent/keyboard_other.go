@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// cbreak is not supported on this platform, so the dashboard has no
+// keyboard shortcuts
+func cbreak(f *os.File) (restore func(), err error) {
+	return nil, errors.New("unbuffered terminal input is not supported on this platform")
+}
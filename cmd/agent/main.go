@@ -1,3 +1,620 @@
 package main
 
-func main() {}
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/apiauth"
+	"loadforge-agent/internal/artifacts"
+	"loadforge-agent/internal/cluster"
+	"loadforge-agent/internal/container"
+	"loadforge-agent/internal/diag"
+	"loadforge-agent/internal/live"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/preflight"
+	"loadforge-agent/internal/report"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/timeline"
+	"loadforge-agent/internal/version"
+)
+
+const usage = `Usage: agent <command> [flags]
+
+Commands:
+  run       Run a scenario file
+  worker    Serve runs for a coordinating agent (agent run -workers)
+  serve     Accept tests from the LoadForge backend over gRPC or REST
+  compare   Test whether latency changed significantly between two runs
+  generate  Write a scenario with a step per operation of an OpenAPI document
+  import    Write a scenario with a step per request of a Postman collection, HAR file or JMeter test plan
+  pin       Print the fingerprints of the OpenAPI operations a scenario exercises
+  version   Print the agent version, commit and build date
+
+Exit codes:
+  0  success; for runs, every threshold passed
+  1  the run completed but a threshold failed
+  2  the scenario or the command line is invalid
+  3  the run was interrupted or failed before completing
+  4  internal error, e.g. a report file could not be written
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return agent.ExitInvalid
+	}
+
+	switch args[0] {
+	case "run":
+		return runScenario(args[1:], stdout, stderr)
+	case "worker":
+		return runWorker(args[1:], stderr)
+	case "serve":
+		return runServe(args[1:], stderr)
+	case "compare":
+		return runCompare(args[1:], stdout, stderr)
+	case "generate":
+		return runGenerate(args[1:], stdout, stderr)
+	case "import":
+		return runImport(args[1:], stdout, stderr)
+	case "pin":
+		return runPin(args[1:], stdout, stderr)
+	case "version", "-version", "--version":
+		fmt.Fprintf(stdout, "agent %s\n", version.Get())
+		return agent.ExitOK
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return agent.ExitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return agent.ExitInvalid
+	}
+}
+
+func runScenario(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	traceVU := fs.Int("trace-vu", 0, "1-based index of a VU to record in full (0 disables)")
+	traceOut := fs.String("trace-out", "trace.jsonl", "file the traced VU's events are written to")
+	debugSample := fs.Float64("debug-sample", 0, "fraction of requests, between 0 and 1, logged in full with their responses to -debug-out")
+	debugFirst := fs.Int("debug-first", 0, "number of first requests of every step logged in full with their responses to -debug-out")
+	debugBodyLimit := fs.Int("debug-body-limit", agent.DefaultDebugBodyLimit, "bytes of request and response bodies kept in the debug log (negative keeps them whole)")
+	debugOut := fs.String("debug-out", "debug.jsonl", "file the requests logged by -debug-sample and -debug-first are written to")
+	statsdAddr := fs.String("statsd", "", "StatsD agent address (host:port) to emit metrics to")
+	statsdPrefix := fs.String("statsd-prefix", "loadforge", "prefix for StatsD metric names")
+	dogStatsD := fs.Bool("dogstatsd", false, "send DogStatsD tags with StatsD metrics")
+	estimator := fs.String("percentiles", metrics.EstimatorHDR, "percentile estimator: hdr or tdigest")
+	showDashboard := fs.Bool("dashboard", false, "show live per-step metrics every second while the run is in progress; on a terminal, press p to pause and resume")
+	liveAddr := fs.String("live-addr", "", "serve live metrics snapshots over WebSocket at ws://<addr>/live")
+	samplesOut := fs.String("samples-out", "", "stream every request sample as CSV to this file")
+	summaryOut := fs.String("summary-out", "", "write the end-of-run summary as JSON to this file")
+	junitOut := fs.String("junit", "", "write threshold results as JUnit XML to this file")
+	bundleOut := fs.String("bundle", "", "archive the scenario, resolved config, summary, HTML report, failed requests and event log to this .tar.gz or self-contained .html file")
+	compression := fs.Float64("tdigest-compression", metrics.DefaultCompression, "t-digest compression (higher is more accurate)")
+	workers := fs.String("workers", "", "comma-separated worker addresses (host:port) to split the virtual users across")
+	tolerateFailures := fs.Bool("tolerate-failures", false, "with -workers, keep the run going when workers stop answering, as long as one is left")
+	redistribute := fs.Bool("redistribute", false, "with -workers, hand the VUs of lost workers to the remaining ones (implies -tolerate-failures)")
+	workerTokenFile := fs.String("worker-token-file", "", "with -workers, file holding the token shared with the workers (default $"+apiauth.EnvToken+")")
+	heartbeat := fs.Duration("heartbeat", cluster.DefaultHeartbeat, fmt.Sprintf("with -workers, how often workers are checked to be alive; a worker missing %d in a row is lost", cluster.DefaultMissedHeartbeats))
+	drainTimeout := fs.Duration("drain-timeout", 10*time.Second, "how long requests in flight when the run ends may take to complete (0 abandons them)")
+	pprofAddr := fs.String("pprof-addr", "", "serve pprof at http://<addr>/debug/pprof/ and expvar at /debug/vars")
+	profileDir := fs.String("profile-dir", "", "capture CPU and heap profiles of the agent to this directory when it cannot keep up with its load")
+	seed := fs.Uint64("seed", 0, "seed VU identities are derived from, to reproduce those of an earlier run (0 uses the scenario's seed or a random one)")
+	skipPreflight := fs.Bool("skip-preflight", false, "run even if open file, ephemeral port or somaxconn limits are too low for the virtual users")
+	readOnly := fs.Bool("read-only", false, "skip POST, PUT, PATCH and DELETE steps, or send their dry_run request instead")
+	readOnlyOut := fs.String("read-only-out", "read-only.jsonl", "file the requests skipped by -read-only are written to")
+	noColor := fs.Bool("no-color", false, "print the end-of-run summary without colors, which are otherwise used on terminals unless NO_COLOR is set")
+
+	if err := fs.Parse(args); err != nil {
+		return agent.ExitInvalid
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: agent run [flags] <scenario.yaml>")
+		return agent.ExitInvalid
+	}
+
+	parser := scenario.NewParser()
+	if err := parser.ParseFile(fs.Arg(0)); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	if err := parser.Validate(); err != nil {
+		fmt.Fprintf(stderr, "error: invalid scenario: %v\n", err)
+		return agent.ExitInvalid
+	}
+	sc, err := parser.GetScenario()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	applyContainerLimits(stderr)
+	if sc.TLS != nil && sc.TLS.InsecureSkipVerify {
+		fmt.Fprintln(stderr, "warning: tls.insecure_skip_verify is set, server certificates are not verified")
+	}
+	checkDrift(sc, stderr)
+	checkParameters(sc, stderr)
+
+	var bundle *artifacts.Bundle
+	if *bundleOut != "" {
+		if _, err := artifacts.Format(*bundleOut); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+		source, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to read file: %v\n", err)
+			return agent.ExitInternal
+		}
+		bundle = &artifacts.Bundle{Source: source, Scenario: sc}
+	}
+	style := summaryStyle{color: !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(stdout)}
+
+	// Run events are echoed to stderr as they happen
+	events := timeline.New(stderr)
+	opts := agent.Options{
+		TraceVU:        *traceVU,
+		DebugSample:    *debugSample,
+		DebugFirst:     *debugFirst,
+		DebugBodyLimit: *debugBodyLimit,
+		Estimator:      *estimator,
+		Compression:    *compression,
+		DrainTimeout:   *drainTimeout,
+		Timeline:       events,
+		ProfileDir:     *profileDir,
+		Seed:           *seed,
+		ReadOnly:       *readOnly,
+	}
+
+	if *pprofAddr != "" {
+		stop, err := serveDiagnostics(*pprofAddr, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+		defer stop()
+	}
+
+	if *workers != "" {
+		debug := *debugSample > 0 || *debugFirst > 0
+		if *traceVU > 0 || debug || *statsdAddr != "" || *samplesOut != "" || *showDashboard || *liveAddr != "" || *readOnly {
+			fmt.Fprintln(stderr, "error: -workers cannot be combined with -trace-vu, -debug-sample, -debug-first, -statsd, -samples-out, -dashboard, -live-addr or -read-only")
+			return agent.ExitInvalid
+		}
+		data, err := parser.Resolved()
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to encode scenario: %v\n", err)
+			return agent.ExitInternal
+		}
+		token, err := apiauth.LoadToken(*workerTokenFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+		coordinator, err := cluster.NewCoordinator(strings.Split(*workers, ","), token)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+		coordinator.Heartbeat = *heartbeat
+		coordinator.TolerateFailures = *tolerateFailures
+		coordinator.Redistribute = *redistribute
+
+		ctx, stop := interruptContext()
+		defer stop()
+
+		result, err := coordinator.Run(ctx, data, sc, opts)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: distributed run failed: %v\n", err)
+			return agent.ExitAborted
+		}
+		return finishRun(stdout, stderr, sc, result, style, *summaryOut, *junitOut, *bundleOut, bundle)
+	}
+
+	findings := preflight.Check(sc.VirtualUsers)
+	for _, f := range findings {
+		fmt.Fprintf(stderr, "preflight: %s\n", f)
+	}
+	if err := preflight.Err(findings); err != nil && !*skipPreflight {
+		fmt.Fprintln(stderr, "error: preflight checks failed, raise the limits above or pass -skip-preflight")
+		return agent.ExitInternal
+	}
+
+	if *traceVU > 0 {
+		f, err := os.Create(*traceOut)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to create trace file: %v\n", err)
+			return agent.ExitInternal
+		}
+		defer f.Close()
+		opts.Trace = f
+	}
+
+	if *debugSample > 0 || *debugFirst > 0 {
+		f, err := os.Create(*debugOut)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to create debug log: %v\n", err)
+			return agent.ExitInternal
+		}
+		defer f.Close()
+		opts.Debug = f
+	}
+
+	if *readOnly {
+		f, err := os.Create(*readOnlyOut)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to create read-only log: %v\n", err)
+			return agent.ExitInternal
+		}
+		defer f.Close()
+		opts.ReadOnlyLog = f
+	}
+
+	if *statsdAddr != "" {
+		sink, err := metrics.NewStatsD(metrics.StatsDConfig{
+			Addr:      *statsdAddr,
+			Prefix:    *statsdPrefix,
+			DogStatsD: *dogStatsD,
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+		opts.Sinks = append(opts.Sinks, sink)
+	}
+
+	var samples *metrics.CSVExporter
+	if *samplesOut != "" {
+		f, err := os.Create(*samplesOut)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: failed to create samples file: %v\n", err)
+			return agent.ExitInternal
+		}
+		defer f.Close()
+		samples = metrics.NewCSVExporter(f)
+		// Flushes the samples on the returns before the run's end too
+		defer samples.Close()
+		opts.SampleSinks = append(opts.SampleSinks, samples)
+	}
+
+	var window, liveWindow *metrics.Window
+	if *showDashboard {
+		if window, err = newWindow(sc, *estimator, *compression); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+		opts.SampleSinks = append(opts.SampleSinks, window)
+	}
+	if *liveAddr != "" {
+		if liveWindow, err = newWindow(sc, *estimator, *compression); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+		opts.SampleSinks = append(opts.SampleSinks, liveWindow)
+	}
+
+	latency, err := newTrend(*estimator, *compression)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	opts.SampleSinks = append(opts.SampleSinks, latency.window)
+
+	// Workers do not send their samples, so only local runs keep failures
+	// for the bundle
+	if bundle != nil {
+		bundle.Failures = artifacts.NewFailureRecorder(artifacts.DefaultMaxFailures)
+		opts.SampleSinks = append(opts.SampleSinks, bundle.Failures)
+	}
+
+	a, err := agent.New(sc, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	stopLive := func() {}
+	if liveWindow != nil {
+		stopLive, err = serveLive(ctx, *liveAddr, liveWindow, a.ActiveVUs)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+	}
+
+	stopDashboard := func() {}
+	if window != nil {
+		d := &dashboard{
+			w:      stdout,
+			tty:    isTerminal(stdout),
+			window: window,
+			vus:    a.ActiveVUs,
+			maxVUs: sc.VirtualUsers,
+			paused: a.Paused,
+		}
+		restoreTerminal := func() {}
+		if d.tty && isTerminal(os.Stdin) {
+			if restore, err := cbreak(os.Stdin); err == nil {
+				restoreTerminal = restore
+				d.keys = true
+				go readKeys(os.Stdin, a)
+			}
+		}
+		dashCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			d.run(dashCtx)
+		}()
+		stopDashboard = func() {
+			cancel()
+			<-done
+			restoreTerminal()
+			fmt.Fprintln(stdout)
+		}
+	}
+
+	stopTrend := latency.start(ctx)
+	result, err := a.Run(ctx)
+	style.trend = stopTrend()
+	stopDashboard()
+	stopLive()
+	for _, sink := range opts.Sinks {
+		if err := sink.Close(); err != nil {
+			events.Add(timeline.SinkFailed, fmt.Sprintf("failed to flush: %v", err), nil)
+		}
+	}
+	// The samples of a failed run are flushed too, they may tell why
+	var samplesErr error
+	if samples != nil {
+		if samplesErr = samples.Close(); samplesErr != nil {
+			fmt.Fprintf(stderr, "error: %v\n", samplesErr)
+		}
+		if dropped := samples.Dropped(); dropped > 0 {
+			events.Add(timeline.SinkFailed, fmt.Sprintf("%d samples were dropped because the disk could not keep up", dropped),
+				map[string]string{"file": *samplesOut})
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: run failed: %v\n", err)
+		return agent.ExitAborted
+	}
+	if samplesErr != nil {
+		return agent.ExitInternal
+	}
+	result.Events = events.Events()
+
+	return finishRun(stdout, stderr, sc, result, style, *summaryOut, *junitOut, *bundleOut, bundle)
+}
+
+// applyContainerLimits keeps the GC under the container's memory limit
+// and reports the limits the agent is sized to. GOMAXPROCS already follows
+// the CPU limit.
+func applyContainerLimits(stderr io.Writer) {
+	limits, err := container.Detect()
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+		return
+	}
+	if !limits.Limited() {
+		return
+	}
+
+	msg := fmt.Sprintf("container: %s (GOMAXPROCS %d", limits, runtime.GOMAXPROCS(0))
+	if mem := container.ApplyMemoryLimit(limits); mem > 0 {
+		msg += fmt.Sprintf(", GC memory limit %d MiB", mem>>20)
+	}
+	fmt.Fprintln(stderr, msg+")")
+}
+
+// interruptContext returns a context cancelled on the first SIGINT or
+// SIGTERM, with the signal as cause. The run then drains; a second signal
+// gets the default behavior and exits at once.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case sig := <-signals:
+			cancel(fmt.Errorf("received %s", sig))
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(nil) }
+}
+
+// finishRun prints the summary of result, writes the requested reports and
+// bundle, and returns the exit code of the run
+func finishRun(stdout, stderr io.Writer, sc *scenario.Scenario, result *agent.Result, style summaryStyle,
+	summaryOut, junitOut, bundleOut string, bundle *artifacts.Bundle) int {
+	printSummary(stdout, sc, result, style)
+
+	if summaryOut != "" {
+		if err := writeFile(summaryOut, func(w io.Writer) error {
+			return report.WriteJSON(w, sc.Name, result)
+		}); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+	}
+
+	if junitOut != "" {
+		if err := writeFile(junitOut, func(w io.Writer) error {
+			return report.WriteJUnit(w, sc.Name, result)
+		}); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+	}
+
+	if bundle != nil {
+		bundle.Result = result
+		if err := bundle.WriteFile(bundleOut); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+	}
+
+	return result.ExitCode()
+}
+
+func runWorker(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", "127.0.0.1:7070", "address to accept runs from a coordinator on")
+	tokenFile := fs.String("token-file", "", "file holding the token shared with the coordinator (default $"+apiauth.EnvToken+")")
+	pprofAddr := fs.String("pprof-addr", "", "serve pprof at http://<addr>/debug/pprof/ and expvar at /debug/vars")
+
+	if err := fs.Parse(args); err != nil {
+		return agent.ExitInvalid
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintln(stderr, "Usage: agent worker [flags]")
+		return agent.ExitInvalid
+	}
+	// Whoever can reach the worker can make it send requests anywhere,
+	// so only the coordinator holding the token may
+	token, err := apiauth.LoadToken(*tokenFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	applyContainerLimits(stderr)
+
+	if *pprofAddr != "" {
+		stop, err := serveDiagnostics(*pprofAddr, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+		defer stop()
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: failed to listen for runs: %v\n", err)
+		return agent.ExitInternal
+	}
+	fmt.Fprintf(stderr, "worker %s listening on %s\n", version.Label(), ln.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Handler: cluster.NewWorker(token).Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(ln); err != http.ErrServerClosed {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInternal
+	}
+	return agent.ExitOK
+}
+
+// newWindow creates a live metrics window with the steps of sc registered
+func newWindow(sc *scenario.Scenario, estimator string, compression float64) (*metrics.Window, error) {
+	window, err := metrics.NewWindow(estimator, compression)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sc.Steps {
+		window.Register(sc.Steps[i].ID())
+		if sc.Steps[i].Retry != nil {
+			window.Register(scenario.RetryStep(sc.Steps[i].ID()))
+		}
+	}
+	for _, pool := range sc.AuthPools {
+		for i := range pool.Login {
+			window.Register(scenario.LoginStep(pool.Name, pool.Login[i].ID()))
+		}
+	}
+	return window, nil
+}
+
+// serveLive publishes snapshots of window on a WebSocket endpoint at addr.
+// The returned function disconnects clients and stops the server.
+func serveLive(ctx context.Context, addr string, window *metrics.Window, activeVUs func() int64) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for live metrics: %w", err)
+	}
+
+	hub := live.NewHub()
+	mux := http.NewServeMux()
+	mux.Handle("/live", hub)
+	server := &http.Server{Handler: mux}
+	go server.Serve(ln)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		live.Publish(ctx, hub, window, activeVUs, time.Second)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		hub.Close()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}, nil
+}
+
+// serveDiagnostics serves the agent's pprof and expvar endpoints at addr.
+// The returned function stops the server.
+func serveDiagnostics(addr string, stderr io.Writer) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for diagnostics: %w", err)
+	}
+	fmt.Fprintf(stderr, "diagnostics listening on http://%s/debug/pprof/\n", ln.Addr())
+
+	server := &http.Server{Handler: diag.Handler()}
+	go server.Serve(ln)
+	return func() { server.Close() }, nil
+}
+
+// writeFile creates path and fills it using write
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/scenario"
+)
+
+// runPin prints the openapi.pinned section of a scenario: the fingerprints
+// of the operations its steps exercise, which runs check for drift
+func runPin(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return agent.ExitInvalid
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: agent pin <scenario.yaml>")
+		return agent.ExitInvalid
+	}
+
+	parser := scenario.NewParser()
+	if err := parser.ParseFile(fs.Arg(0)); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	if err := parser.Validate(); err != nil {
+		fmt.Fprintf(stderr, "error: invalid scenario: %v\n", err)
+		return agent.ExitInvalid
+	}
+	sc, err := parser.GetScenario()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+
+	pinned, err := agent.PinEndpoints(sc)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInvalid
+	}
+	data, err := yaml.Marshal(map[string]any{"pinned": pinned})
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return agent.ExitInternal
+	}
+	fmt.Fprintln(stdout, "# add under the scenario's openapi section")
+	stdout.Write(data)
+	return agent.ExitOK
+}
+
+// checkDrift warns about the operations pinned by sc whose shape changed
+// in its OpenAPI document since they were pinned
+func checkDrift(sc *scenario.Scenario, stderr io.Writer) {
+	warnings, err := agent.CheckDrift(sc)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: cannot check the pinned operations: %v\n", err)
+		return
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "drift: %s\n", w)
+	}
+}
+
+// checkParameters warns about the required parameters of the OpenAPI
+// document of sc that its steps do not set
+func checkParameters(sc *scenario.Scenario, stderr io.Writer) {
+	warnings, err := agent.CheckParameters(sc)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: cannot check the steps' parameters: %v\n", err)
+		return
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "openapi: %s\n", w)
+	}
+}
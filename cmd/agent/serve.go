@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/apiauth"
+	"loadforge-agent/internal/control"
+	"loadforge-agent/internal/version"
+)
+
+// runServe accepts tests from the LoadForge backend until interrupted
+func runServe(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	grpcAddr := fs.String("grpc-addr", defaultGRPCAddr, "address to serve the gRPC control API on (empty disables it)")
+	httpAddr := fs.String("http-addr", "", "address to serve the REST control API on (empty disables it)")
+	pprofAddr := fs.String("pprof-addr", "", "serve pprof at http://<addr>/debug/pprof/ and expvar at /debug/vars")
+	tokenFile := fs.String("token-file", "", "file holding the token clients must send as a bearer token (default $"+apiauth.EnvToken+")")
+	tlsCert := fs.String("tls-cert", "", "certificate file to serve the control APIs over TLS with")
+	tlsKey := fs.String("tls-key", "", "private key file of -tls-cert")
+	tlsClientCA := fs.String("tls-client-ca", "", "CA file clients must present a certificate of (mTLS); requires -tls-cert")
+
+	if err := fs.Parse(args); err != nil {
+		return agent.ExitInvalid
+	}
+	if fs.NArg() != 0 || (*grpcAddr == "" && *httpAddr == "") {
+		fmt.Fprintln(stderr, "Usage: agent serve [-grpc-addr addr] [-http-addr addr] [-token-file file] [-tls-cert file -tls-key file [-tls-client-ca file]]")
+		return agent.ExitInvalid
+	}
+
+	// Submitted tests make the agent send requests anywhere, so clients
+	// must prove who they are with a token or a client certificate
+	token, err := apiauth.LoadToken(*tokenFile)
+	if err != nil && (*tokenFile != "" || !errors.Is(err, apiauth.ErrNoToken) || *tlsClientCA == "") {
+		fmt.Fprintf(stderr, "error: %v; the control APIs require a token or -tls-client-ca\n", err)
+		return agent.ExitInvalid
+	}
+	if (*tlsCert == "") != (*tlsKey == "") || (*tlsClientCA != "" && *tlsCert == "") {
+		fmt.Fprintln(stderr, "error: -tls-cert and -tls-key go together, and -tls-client-ca requires them")
+		return agent.ExitInvalid
+	}
+	var tlsConfig *tls.Config
+	if *tlsCert != "" {
+		if tlsConfig, err = apiauth.ServerTLS(*tlsCert, *tlsKey, *tlsClientCA); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInvalid
+		}
+	}
+	applyContainerLimits(stderr)
+
+	if *pprofAddr != "" {
+		stop, err := serveDiagnostics(*pprofAddr, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+		defer stop()
+	}
+
+	fmt.Fprintf(stderr, "agent %s\n", version.Get())
+	manager := control.NewManager()
+	var grpcServer controlServer
+	var grpcLn, httpLn net.Listener
+	if *grpcAddr != "" {
+		if grpcServer, err = newGRPCServer(manager, token, tlsConfig); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return agent.ExitInternal
+		}
+		if grpcLn, err = net.Listen("tcp", *grpcAddr); err != nil {
+			fmt.Fprintf(stderr, "error: failed to listen for gRPC: %v\n", err)
+			return agent.ExitInternal
+		}
+		fmt.Fprintf(stderr, "gRPC control API listening on %s\n", grpcLn.Addr())
+	}
+	if *httpAddr != "" {
+		if httpLn, err = net.Listen("tcp", *httpAddr); err != nil {
+			fmt.Fprintf(stderr, "error: failed to listen for HTTP: %v\n", err)
+			return agent.ExitInternal
+		}
+		fmt.Fprintf(stderr, "REST control API listening on %s\n", httpLn.Addr())
+	}
+
+	handler := control.NewHTTPHandler(manager)
+	if token != "" {
+		handler = apiauth.Handler(handler, token)
+	}
+	httpServer := &http.Server{Handler: handler, TLSConfig: tlsConfig}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		// Stopping the tests ends their metrics streams, so the graceful
+		// stops do not wait on them
+		manager.StopAll()
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	if grpcLn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := grpcServer.Serve(grpcLn); err != nil {
+				errs <- err
+				stop()
+			}
+		}()
+	}
+	if httpLn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve := httpServer.Serve
+			if tlsConfig != nil {
+				serve = func(ln net.Listener) error { return httpServer.ServeTLS(ln, "", "") }
+			}
+			if err := serve(httpLn); err != http.ErrServerClosed {
+				errs <- err
+				stop()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	code := agent.ExitOK
+	for err := range errs {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		code = agent.ExitInternal
+	}
+	return code
+}
+
+// controlServer is the gRPC server of the control API, see newGRPCServer
+type controlServer interface {
+	Serve(ln net.Listener) error
+	GracefulStop()
+}
@@ -0,0 +1,32 @@
+//go:build !nogrpc
+
+package main
+
+import (
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"loadforge-agent/internal/control"
+	"loadforge-agent/pkg/agentpb"
+)
+
+// defaultGRPCAddr is where the gRPC control API listens unless told
+// otherwise, only reachable from the agent's host
+const defaultGRPCAddr = "127.0.0.1:9090"
+
+// newGRPCServer serves m, requiring token when set and serving TLS with
+// config when set
+func newGRPCServer(m *control.Manager, token string, config *tls.Config) (controlServer, error) {
+	var opts []grpc.ServerOption
+	if token != "" {
+		opts = append(opts, control.TokenOptions(token)...)
+	}
+	if config != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	server := grpc.NewServer(opts...)
+	agentpb.RegisterAgentServiceServer(server, control.NewGRPCServer(m))
+	return server, nil
+}
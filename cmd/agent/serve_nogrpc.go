@@ -0,0 +1,17 @@
+//go:build nogrpc
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"loadforge-agent/internal/control"
+)
+
+// defaultGRPCAddr is empty, this build has no gRPC control API
+const defaultGRPCAddr = ""
+
+func newGRPCServer(*control.Manager, string, *tls.Config) (controlServer, error) {
+	return nil, errors.New("the gRPC control API is not included in this build of the agent (nogrpc tag), use -http-addr")
+}
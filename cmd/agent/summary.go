@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/version"
+)
+
+// summaryStyle controls how the end-of-run summary is rendered
+type summaryStyle struct {
+	// color paints verdicts and rows with errors, for terminals
+	color bool
+	// trend holds the p95 latency of every second of the run, nil when
+	// it was not collected
+	trend []time.Duration
+}
+
+// ANSI codes the summary is painted with
+const (
+	ansiBold   = "1"
+	ansiRed    = "31"
+	ansiGreen  = "32"
+	ansiYellow = "33"
+)
+
+// paint wraps text in the ANSI code when the style has color
+func (st summaryStyle) paint(code, text string) string {
+	if !st.color || code == "" {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+func printSummary(w io.Writer, sc *scenario.Scenario, r *agent.Result, st summaryStyle) {
+	fmt.Fprintf(w, "scenario:    %s\n", st.paint(ansiBold, sc.Name))
+	fmt.Fprintf(w, "duration:    %s\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "iterations:  %d\n", r.Iterations)
+	fmt.Fprintf(w, "agent:       %s\n", version.Label())
+	if r.SkippedWrites > 0 {
+		fmt.Fprintf(w, "read-only:   %d mutating requests skipped\n", r.SkippedWrites)
+	}
+	for _, g := range r.CapacityGaps {
+		fill := "not redistributed"
+		if g.Redistributed {
+			fill = "redistributed"
+		}
+		fmt.Fprintf(w, "%s   %s lost at %s, %d VUs missing for %s (%s), its metrics are not included\n",
+			st.paint(ansiYellow, "capacity:"), g.Worker, g.Start.Format(time.TimeOnly), g.VirtualUsers,
+			g.End.Sub(g.Start).Round(time.Millisecond), fill)
+	}
+	if len(st.trend) > 1 {
+		lo, hi := slices.Min(st.trend), slices.Max(st.trend)
+		fmt.Fprintf(w, "p95 trend:   %s  %s to %s\n",
+			sparkline(st.trend, maxTrendPoints), formatLatency(lo), formatLatency(hi))
+	}
+	fmt.Fprintln(w)
+
+	printStepTable(w, st, "step", append(slices.Clone(r.Metrics.Steps), r.Metrics.Total), r.Noise)
+
+	// With a single protocol the breakdown would repeat the total
+	if len(r.Metrics.Protocols) > 1 {
+		fmt.Fprintln(w)
+		printStepTable(w, st, "protocol", r.Metrics.Protocols, nil)
+	}
+
+	if len(r.Metrics.Labels) > 0 {
+		fmt.Fprintln(w)
+		printStepTable(w, st, "label", r.Metrics.Labels, nil)
+	}
+
+	if len(r.Budgets) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "budgets:")
+		for _, b := range r.Budgets {
+			fmt.Fprintf(w, "  %s (max %s): %d/%d iterations over budget (%.2f%%), p95 %s",
+				b.Name, b.Max, b.Violations, b.Iterations, b.ViolationRate*100,
+				formatLatency(b.Duration.P95))
+			if b.Cancelled > 0 {
+				fmt.Fprintf(w, ", %d requests cancelled", b.Cancelled)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	if len(r.Checks) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "checks:")
+		for _, c := range r.Checks {
+			fmt.Fprintf(w, "  %s: %s: %.2f%% passed (%d/%d)\n",
+				c.Step, c.Name, c.PassRate*100, c.Passes, c.Passes+c.Fails)
+			for _, e := range c.Errors {
+				fmt.Fprintf(w, "    %s\n", e)
+			}
+		}
+	}
+
+	if len(r.Contract) > 0 {
+		fmt.Fprintln(w)
+		var violations int64
+		for _, c := range r.Contract {
+			violations += c.Violations
+		}
+		if violations == 0 {
+			fmt.Fprintln(w, "contract:    no violations of the OpenAPI document")
+		} else {
+			fmt.Fprintln(w, "contract violations:")
+		}
+		for _, c := range r.Contract {
+			if c.Violations == 0 {
+				continue
+			}
+			operation := c.Operation
+			if operation == "" {
+				operation = "not in the document"
+			}
+			fmt.Fprintf(w, "  %s (%s): %d of %d responses\n", c.Step, operation, c.Violations, c.Responses)
+			for _, e := range c.Errors {
+				fmt.Fprintf(w, "    %s\n", e)
+			}
+		}
+	}
+
+	if len(r.Idempotency) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "idempotency:")
+		for _, i := range r.Idempotency {
+			fmt.Fprintf(w, "  %s: %d of %d replays differed (compare %s)\n", i.Step, i.Violations, i.Replays, i.Compare)
+			for _, e := range i.Errors {
+				fmt.Fprintf(w, "    %s\n", e)
+			}
+		}
+	}
+
+	if b := r.Burst; b != nil {
+		fmt.Fprintln(w)
+		recovery := "recovered in " + b.RecoveryTime.Round(time.Millisecond).String()
+		if !b.Recovered {
+			recovery = st.paint(ansiRed, "not recovered after "+b.RecoveryTime.Round(time.Millisecond).String())
+		}
+		fmt.Fprintf(w, "burst:       p95 %s baseline, %s peak, %s\n",
+			formatLatency(b.BaselineP95), formatLatency(b.PeakP95), recovery)
+	}
+
+	if r.Outliers != nil {
+		printOutliers(w, r.Outliers)
+	}
+
+	if s := r.Sockets; s != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "sockets:     peak %d open (%d established, %d TIME_WAIT), ephemeral ports %d/%d (%.1f%%), open files %d/%d\n",
+			s.Open, s.Established, s.TimeWait, s.EphemeralInUse, s.EphemeralPorts, s.EphemeralUsage()*100,
+			s.OpenFiles, s.FileLimit)
+	}
+
+	if len(r.Profiles) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "agent saturated, profiles captured:")
+		for _, p := range r.Profiles {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+	}
+
+	if len(r.Thresholds) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "thresholds:")
+		for _, t := range r.Thresholds {
+			mark := st.paint(ansiGreen, "✓")
+			if !t.Passed {
+				mark = st.paint(ansiRed, "✗")
+			}
+			scope := "total"
+			if t.Step != "" {
+				scope = t.Step
+			}
+			fmt.Fprintf(w, "  %s %s: %s (actual %s)\n", mark, scope, t.Expr, t.Actual)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, verdict(r, st))
+}
+
+// verdict is the summary's last line, telling at a glance whether the run
+// passed its thresholds
+func verdict(r *agent.Result, st summaryStyle) string {
+	errors := fmt.Sprintf("%.2f%% errors", r.Metrics.Total.ErrorRate*100)
+	if len(r.Thresholds) == 0 {
+		return st.paint(ansiBold, "done: no thresholds, "+errors)
+	}
+	failed := 0
+	for _, t := range r.Thresholds {
+		if !t.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return st.paint(ansiRed, fmt.Sprintf("✗ FAILED: %d of %d thresholds failed, %s", failed, len(r.Thresholds), errors))
+	}
+	return st.paint(ansiGreen, fmt.Sprintf("✓ PASSED: %d thresholds held, %s", len(r.Thresholds), errors))
+}
+
+// maxOutlierWindows bounds how many outlier windows the summary lists
+const maxOutlierWindows = 10
+
+func printOutliers(w io.Writer, o *metrics.OutlierReport) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "outliers: %d requests in %d windows\n", o.Count, len(o.Windows))
+
+	// The windows with the most outliers come first
+	windows := slices.Clone(o.Windows)
+	slices.SortStableFunc(windows, func(a, b metrics.OutlierWindow) int {
+		return len(b.Outliers) - len(a.Outliers)
+	})
+	for i, win := range windows {
+		if i == maxOutlierWindows {
+			fmt.Fprintf(w, "  ... %d more windows\n", len(windows)-i)
+			break
+		}
+		slowest := win.Outliers[0]
+		for _, out := range win.Outliers {
+			if out.Duration > slowest.Duration {
+				slowest = out
+			}
+		}
+		fmt.Fprintf(w, "  +%s: %d requests, slowest %s on %s (threshold %s)\n",
+			win.Offset, len(win.Outliers), formatLatency(slowest.Duration), slowest.Step,
+			formatLatency(o.Thresholds[slowest.Step]))
+		for _, cause := range win.Causes {
+			fmt.Fprintf(w, "      - %s\n", cause)
+		}
+	}
+}
+
+// printStepTable prints a table of rows with a first column named name,
+// and the noise row if any. Rows with errors are painted yellow after
+// alignment, as tabwriter would count color codes as width.
+func printStepTable(w io.Writer, st summaryStyle, name string, rows []metrics.StepStats, noise *metrics.StepStats) {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, name+"\trequests\terrors\trps\tavg\tp50\tp95\tp99\tmax\t")
+	codes := []string{ansiBold}
+	for _, row := range rows {
+		printStepRow(tw, row)
+		code := ""
+		if row.Errors > 0 {
+			code = ansiYellow
+		}
+		codes = append(codes, code)
+	}
+	if noise != nil {
+		printStepRow(tw, *noise)
+		codes = append(codes, "")
+	}
+	tw.Flush()
+
+	lines := strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		fmt.Fprintln(w, st.paint(codes[i], strings.TrimSuffix(line, "\n")))
+	}
+}
+
+func printStepRow(w io.Writer, s metrics.StepStats) {
+	l := s.Latency
+	fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%.1f\t%s\t%s\t%s\t%s\t%s\t\n",
+		s.Name, s.Requests, s.ErrorRate*100, s.RPS,
+		formatLatency(l.Mean), formatLatency(l.P50), formatLatency(l.P95),
+		formatLatency(l.P99), formatLatency(l.Max))
+}
+
+func formatLatency(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	default:
+		return d.Round(100 * time.Microsecond).String()
+	}
+}
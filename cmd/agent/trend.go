@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+)
+
+// trendInterval is how often the latency trend takes a point
+const trendInterval = time.Second
+
+// maxTrendPoints bounds the width of the latency sparkline; longer trends
+// are shown by the slowest point of each group of seconds
+const maxTrendPoints = 60
+
+// trend collects the p95 latency of every second of a run for the
+// sparkline of the end-of-run summary
+type trend struct {
+	window *metrics.Window
+	points []time.Duration
+}
+
+func newTrend(estimator string, compression float64) (*trend, error) {
+	window, err := metrics.NewWindow(estimator, compression)
+	if err != nil {
+		return nil, err
+	}
+	return &trend{window: window}, nil
+}
+
+// start takes a point every trendInterval until the returned function is
+// called, which takes the last, partial one and returns all points
+func (t *trend) start(ctx context.Context) func() []time.Duration {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	last := time.Now()
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(trendInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				t.take(now.Sub(last))
+				last = now
+			}
+		}
+	}()
+	return func() []time.Duration {
+		cancel()
+		<-done
+		t.take(time.Since(last))
+		return t.points
+	}
+}
+
+func (t *trend) take(elapsed time.Duration) {
+	s := t.window.Flush(elapsed)
+	if s.Total.Requests > 0 {
+		t.points = append(t.points, s.Total.Latency.P95)
+	}
+}
+
+// sparkBars are the sparkline's levels, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws points as bars scaled between their minimum and maximum,
+// at most width of them
+func sparkline(points []time.Duration, width int) string {
+	if len(points) > width {
+		grouped := make([]time.Duration, width)
+		for i, p := range points {
+			j := i * width / len(points)
+			grouped[j] = max(grouped[j], p)
+		}
+		points = grouped
+	}
+
+	lo, hi := points[0], points[0]
+	for _, p := range points {
+		lo, hi = min(lo, p), max(hi, p)
+	}
+	var b strings.Builder
+	for _, p := range points {
+		level := 0
+		if hi > lo {
+			level = int(int64(p-lo) * int64(len(sparkBars)-1) / int64(hi-lo))
+		}
+		b.WriteRune(sparkBars[level])
+	}
+	return b.String()
+}
@@ -1 +1,852 @@
 package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/netstat"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/secrets"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/timeline"
+	"loadforge-agent/internal/version"
+	"loadforge-agent/pkg/ratelimit"
+)
+
+// Options configures a test run
+type Options struct {
+	// TraceVU is the 1-based index of the VU whose every request, variable
+	// mutation and branch decision is written to Trace. 0 disables tracing.
+	TraceVU int
+	Trace   io.Writer
+
+	// Sinks receive every measurement as it is recorded
+	Sinks []metrics.Sink
+	// SampleSinks receive every raw request sample
+	SampleSinks []metrics.SampleSink
+
+	// Estimator selects how latency percentiles are computed, one of
+	// metrics.EstimatorHDR (default) or metrics.EstimatorTDigest.
+	// Compression tunes the t-digest estimator.
+	Estimator   string
+	Compression float64
+
+	// DrainTimeout is how long requests in flight when the run ends may
+	// take to complete and be recorded. VUs stop starting new requests as
+	// soon as the run ends; 0 abandons in-flight requests.
+	DrainTimeout time.Duration
+
+	// Timeline receives run lifecycle events; when nil the agent keeps its
+	// own log
+	Timeline *timeline.Log
+
+	// Worker (0-based) and Workers select the share of the data file's rows
+	// this agent feeds in a distributed run. Workers 0 feeds all rows.
+	Worker  int
+	Workers int
+	// RetiredWorkers are workers lost during a distributed run; with the
+	// hash data partition, their rows are not handed to the other workers,
+	// since they may have been used already
+	RetiredWorkers []int
+	// FirstVU offsets the numbers of this agent's VUs, whose identities
+	// are derived from them, so that VUs of different workers never share
+	// an identity
+	FirstVU int
+
+	// SkipLifecycle leaves the scenario's setup and teardown steps to the
+	// coordinator of a distributed run, which passes the variables saved
+	// in setup as SetupVars
+	SkipLifecycle bool
+	SetupVars     map[string]string
+
+	// Seed derives the VUs' identities, overriding the scenario's seed;
+	// when both are 0 a random seed is picked
+	Seed uint64
+
+	// ProfileDir is where CPU and heap profiles of the agent are written
+	// when it saturates; empty disables profiling
+	ProfileDir string
+
+	// RateLimits receives the scenario's rate limits, so step types
+	// outside the agent can share them; nil gives the agent its own
+	RateLimits *ratelimit.Registry
+
+	// ReadOnly skips the scenario's mutating steps, POST, PUT, PATCH and
+	// DELETE requests, or sends their dry_run request instead. The requests
+	// that would have been sent are written to ReadOnlyLog as JSON lines.
+	// Setup, teardown and login steps are no exception; a login that does
+	// not mutate, e.g. POST /login, sets itself as its dry_run to be sent.
+	ReadOnly    bool
+	ReadOnlyLog io.Writer
+
+	// DebugSample is the fraction of requests, and DebugFirst the number
+	// of first requests of every step, written to Debug in full with
+	// their responses, see DebugEntry. Bodies are truncated to
+	// DebugBodyLimit bytes, DefaultDebugBodyLimit if 0 and none if
+	// negative; credentials headers and secrets are masked.
+	DebugSample    float64
+	DebugFirst     int
+	DebugBodyLimit int
+	Debug          io.Writer
+}
+
+// Result holds the aggregated outcome of a run
+type Result struct {
+	Iterations int64
+	Duration   time.Duration
+	// Metrics holds totals and the per-step breakdown
+	Metrics metrics.Summary
+	// Thresholds holds the outcome of every configured threshold
+	Thresholds []threshold.Result
+	// Noise holds background noise traffic stats, if noise was enabled
+	Noise *metrics.StepStats
+	// Budgets holds the outcome of every step group time budget
+	Budgets []BudgetResult
+	// Checks counts the passes and failures of every check
+	Checks []CheckResult
+	// Contract counts the violations of the OpenAPI document by step,
+	// when the scenario validates responses against it
+	Contract []ContractResult
+	// Idempotency counts the replays of every step with an idempotency
+	// check whose response differed
+	Idempotency []IdempotencyResult
+	// Outliers isolates the slowest requests and their possible causes,
+	// if there were any
+	Outliers *metrics.OutlierReport
+	// Events is the run's timeline up to the moment the result was built
+	Events []timeline.Event
+	// Sockets holds the peak socket usage of the agent host, where
+	// supported
+	Sockets *netstat.Stats
+	// Profiles are the paths of profiles captured when the agent saturated
+	Profiles []string
+	// Burst is how the target coped with the burst of a burst run
+	Burst *BurstResult
+	// Aborted is why the run ended before its duration, e.g. an interrupt;
+	// empty when it ran to the end
+	Aborted string
+	// SkippedWrites is the number of mutating requests a read-only run did
+	// not send
+	SkippedWrites int64
+	// CapacityGaps are the windows of a distributed run during which the
+	// VUs of a lost worker were not running
+	CapacityGaps []CapacityGap
+}
+
+// CapacityGap is a window of a distributed run missing a lost worker's VUs.
+// The metrics the worker recorded are lost with it.
+type CapacityGap struct {
+	Worker string
+	// VirtualUsers is the lost worker's share of the VUs
+	VirtualUsers uint64
+	Start        time.Time
+	// End is when the VUs were handed to the remaining workers, or the end
+	// of the run if they were not
+	End           time.Time
+	Redistributed bool
+}
+
+// Passed reports whether all thresholds passed
+func (r *Result) Passed() bool {
+	return threshold.Passed(r.Thresholds)
+}
+
+// Exit codes of the agent, for automation to branch on the outcome of a
+// run. Commands other than runs use ExitOK, ExitInvalid and ExitInternal.
+const (
+	// ExitOK: the run completed and every threshold passed
+	ExitOK = 0
+	// ExitThresholdsFailed: the run completed but a threshold failed
+	ExitThresholdsFailed = 1
+	// ExitInvalid: the scenario or the command line is invalid
+	ExitInvalid = 2
+	// ExitAborted: the run was interrupted or failed before completing
+	ExitAborted = 3
+	// ExitInternal: the agent failed on its own, e.g. it could not create
+	// a report file
+	ExitInternal = 4
+)
+
+// ExitCode returns the exit code for the outcome of the run. An aborted
+// run is ExitAborted even if its thresholds passed so far.
+func (r *Result) ExitCode() int {
+	switch {
+	case r.Aborted != "":
+		return ExitAborted
+	case !r.Passed():
+		return ExitThresholdsFailed
+	}
+	return ExitOK
+}
+
+// Agent runs a scenario with the configured number of virtual users
+type Agent struct {
+	scenario *scenario.Scenario
+	opts     Options
+
+	// stepIndex maps next_steps targets to positions in scenario.Steps
+	stepIndex map[string]int
+	// conditions holds the parsed when of every conditional step
+	conditions map[string]*scenario.Condition
+	// payloads holds the bodies of steps with a payload
+	payloads map[string]*payloadBody
+	// flows are what VUs iterate over
+	flows []flow
+	// flowOrder holds the flow of each VU position within the scenario's
+	// VUs, interleaved so that any number of running VUs keeps the mix
+	flowOrder []int
+	// data feeds data file rows to VUs, nil without a data file
+	data *dataFeeder
+	// pools are the auth pools, VUs are assigned to them in order
+	pools []authPool
+	// secrets are the resolved ${secrets.<name>} variables, whose values
+	// redactor masks in traces and sample errors
+	secrets  map[string]string
+	redactor *secrets.Redactor
+	// rateLimits holds the named rate limits, limited maps rate-limited
+	// steps to theirs
+	rateLimits *ratelimit.Registry
+	limited    map[string]*ratelimit.Bucket
+	// maxRPS caps the requests of all VUs, nil if unlimited
+	maxRPS *ratelimit.Bucket
+	// debug logs sampled requests, nil if disabled
+	debug *debugLogger
+	// retry is the transport_retry policy of the VUs' executors, nil if
+	// unset
+	retry *executor.RetryPolicy
+	// seed derives the VUs' identities
+	seed uint64
+	// setupVars are the variables saved by the setup steps
+	setupVars map[string]string
+	// stages ramp the VU count; gateWindow collects the samples their
+	// gates are evaluated on, nil without gates
+	stages     []stage
+	gateWindow *metrics.Window
+	// burst drives the VU count of burst runs, its window is gateWindow
+	burst *burstTracker
+
+	*recorder
+	classify map[string][]classifyRule
+
+	active   atomic.Int64
+	sockets  *netstat.Stats
+	profiles []string
+	// aborted is the cause the run's context ended with before its duration
+	aborted string
+	// readOnly is set in read-only runs, see Options.ReadOnly
+	readOnly *readOnly
+
+	pauseMu sync.Mutex
+	// resumed is closed once the last pause holder releases the run; it is
+	// nil while the run is not paused
+	resumed chan struct{}
+	// holders are what keeps the run paused: the operator and every
+	// tripped hold guard
+	holders map[string]bool
+
+	scaleMu sync.Mutex
+	// vus is the VU count, changed by Scale. VUs numbered above it idle
+	// until scaled is closed, when the count changes again.
+	vus    int
+	scaled chan struct{}
+	// spawned is the number of VUs started so far; spawn starts another
+	// one and is nil while the run is not in progress. ended is set once
+	// no more VUs can start.
+	spawned int
+	spawn   func(id int) error
+	ended   bool
+}
+
+// New creates an Agent for sc, which must already be validated
+func New(sc *scenario.Scenario, opts Options) (*Agent, error) {
+	if sc == nil {
+		return nil, fmt.Errorf("scenario cannot be nil")
+	}
+
+	if opts.TraceVU < 0 || uint64(opts.TraceVU) > sc.VirtualUsers {
+		return nil, fmt.Errorf("trace VU must be between 1 and %d, got %d",
+			sc.VirtualUsers, opts.TraceVU)
+	}
+
+	if opts.TraceVU > 0 && opts.Trace == nil {
+		return nil, fmt.Errorf("trace VU %d requested without a trace writer", opts.TraceVU)
+	}
+
+	if opts.DebugSample < 0 || opts.DebugSample > 1 {
+		return nil, fmt.Errorf("debug sample must be between 0 and 1, got %v", opts.DebugSample)
+	}
+	if opts.DebugFirst < 0 {
+		return nil, fmt.Errorf("debug first requests cannot be negative, got %d", opts.DebugFirst)
+	}
+	if (opts.DebugSample > 0 || opts.DebugFirst > 0) && opts.Debug == nil {
+		return nil, fmt.Errorf("debug logging requested without a debug writer")
+	}
+
+	rec, err := newRecorder(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stepIndex := make(map[string]int, len(sc.Steps))
+	for i := range sc.Steps {
+		for _, next := range sc.Steps[i].NextSteps {
+			idx := sc.StepIndex(next.Target())
+			if idx < 0 {
+				return nil, fmt.Errorf("next step target '%s' not found", next.Target())
+			}
+			stepIndex[next.Target()] = idx
+		}
+	}
+
+	conditions := make(map[string]*scenario.Condition)
+	for i := range sc.Steps {
+		if sc.Steps[i].When == "" {
+			continue
+		}
+		cond, err := scenario.ParseCondition(sc.Steps[i].When)
+		if err != nil {
+			return nil, err
+		}
+		conditions[sc.Steps[i].ID()] = cond
+	}
+
+	classify, err := newClassifyRules(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads, err := generatePayloads(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimits := opts.RateLimits
+	if rateLimits == nil {
+		rateLimits = ratelimit.NewRegistry()
+	}
+	limited, err := defineRateLimits(sc, opts, rateLimits)
+	if err != nil {
+		return nil, err
+	}
+	flows := newFlows(sc)
+	maxRPS, err := defineMaxRPS(sc, opts, rateLimits, flows)
+	if err != nil {
+		return nil, err
+	}
+
+	secretVars, redactor, err := resolveSecrets(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	stages, gateWindow, err := newStages(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+	vus := int(sc.VirtualUsers)
+	if len(stages) > 0 {
+		vus = stages[0].vus
+	}
+	burst, err := newBurst(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+	if burst != nil {
+		vus = int(burst.spec.BaselineVUs)
+		gateWindow = burst.window
+	}
+
+	pools, err := newAuthPools(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Opened last, since nothing closes it if New fails
+	data, err := openData(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Agent{
+		scenario:   sc,
+		opts:       opts,
+		stepIndex:  stepIndex,
+		conditions: conditions,
+		payloads:   payloads,
+		flows:      flows,
+		flowOrder:  interleaveFlows(flows),
+		data:       data,
+		pools:      pools,
+		secrets:    secretVars,
+		redactor:   redactor,
+		rateLimits: rateLimits,
+		limited:    limited,
+		maxRPS:     maxRPS,
+		retry:      retryPolicy(sc.TransportRetry),
+		debug:      newDebugLogger(opts, redactor),
+		seed:       cmp.Or(opts.Seed, sc.Seed, rand.Uint64()),
+		stages:     stages,
+		gateWindow: gateWindow,
+		burst:      burst,
+		recorder:   rec,
+		classify:   classify,
+		readOnly:   newReadOnly(opts, redactor),
+		vus:        vus,
+		scaled:     make(chan struct{}),
+	}, nil
+}
+
+// Run executes the scenario until its duration elapses or ctx is
+// cancelled, then waits up to Options.DrainTimeout for in-flight requests
+func (a *Agent) Run(parent context.Context) (*Result, error) {
+	defer a.data.close()
+
+	// Guards abort the run like an interrupt
+	parent, stop := context.WithCancelCause(parent)
+	defer stop(nil)
+
+	// Setup does not count towards the run's duration
+	if err := a.setup(parent); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(parent, time.Duration(a.scenario.Duration)*time.Second)
+	defer cancel()
+
+	a.started = time.Now()
+	a.events.Add(timeline.RunStarted, fmt.Sprintf("running '%s'", a.scenario.Name), map[string]string{
+		"vus":      strconv.Itoa(a.VUs()),
+		"duration": (time.Duration(a.scenario.Duration) * time.Second).String(),
+		"seed":     strconv.FormatUint(a.seed, 10),
+	})
+
+	// Requests use their own context so those in flight when the run ends
+	// can drain
+	requests, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-requests.Done():
+			return
+		}
+		if parent.Err() != nil {
+			msg := context.Cause(parent).Error()
+			if a.opts.DrainTimeout > 0 {
+				msg += fmt.Sprintf(", draining in-flight requests for up to %s", a.opts.DrainTimeout)
+			}
+			a.events.Add(timeline.Aborted, msg, nil)
+		}
+
+		timer := time.NewTimer(a.opts.DrainTimeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			if a.opts.DrainTimeout > 0 {
+				a.events.Add(timeline.DrainTimedOut,
+					fmt.Sprintf("abandoned requests still in flight after %s", a.opts.DrainTimeout), nil)
+			}
+			cancelRequests()
+		case <-requests.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	gcDone := make(chan []metrics.GCPause, 1)
+	go func() {
+		gcDone <- watchGC(ctx)
+	}()
+	socketsDone := make(chan *netstat.Stats, 1)
+	go func() {
+		socketsDone <- a.watchSockets(ctx)
+	}()
+	profilesDone := make(chan []string, 1)
+	go func() {
+		profilesDone <- a.watchSaturation(ctx)
+	}()
+
+	if a.noise != nil {
+		exec, err := executor.NewWithTransport(transportOptions(a.scenario))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create noise executor: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer exec.Close()
+			runNoise(ctx, exec, a.scenario.BaseURL, a.scenario.Noise, a.noise)
+		}()
+	}
+
+	a.scaleMu.Lock()
+	a.spawn = func(i int) error {
+		var tr *tracer
+		if i == a.opts.TraceVU {
+			tr = newTracer(i, a.opts.Trace, a.redactor)
+		}
+
+		vu, err := newVirtualUser(i, a, a.flowOf(i), tr)
+		if err != nil {
+			return fmt.Errorf("failed to create VU %d: %w", i, err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vu.run(ctx, requests)
+		}()
+		return nil
+	}
+	err := a.startVUs(a.vus)
+	if err != nil {
+		cancel()
+	}
+	a.scaleMu.Unlock()
+
+	if len(a.stages) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runStages(ctx)
+		}()
+	}
+	if a.burst != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runBurst(ctx)
+		}()
+	}
+	for i := range a.scenario.Guards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runGuard(ctx, stop, &a.scenario.Guards[i])
+		}()
+	}
+
+	// VUs run until ctx ends. Scale may start more until then, but not
+	// once the wait below began.
+	<-ctx.Done()
+	a.scaleMu.Lock()
+	a.spawn = nil
+	a.ended = true
+	a.scaleMu.Unlock()
+	wg.Wait()
+
+	if err != nil {
+		<-gcDone
+		<-socketsDone
+		<-profilesDone
+		return nil, err
+	}
+
+	a.elapsed = time.Since(a.started)
+	if parent.Err() != nil {
+		a.aborted = context.Cause(parent).Error()
+	}
+	cancelRequests()
+	cancel()
+	a.gcPauses = <-gcDone
+	a.sockets = <-socketsDone
+	a.profiles = <-profilesDone
+	a.events.Add(timeline.RunFinished, fmt.Sprintf("run finished after %s", a.elapsed.Round(time.Millisecond)),
+		map[string]string{"iterations": strconv.FormatInt(a.iterations.Load(), 10)})
+	a.teardown(parent)
+	return a.result(), nil
+}
+
+// result aggregates everything recorded so far
+func (a *Agent) result() *Result {
+	var (
+		burst *BurstResult
+		extra []threshold.Result
+	)
+	if a.burst != nil {
+		outcome := a.burst.outcome(a.started.Add(a.elapsed))
+		burst = &outcome
+		if max := a.scenario.Burst.MaxRecovery.Duration; max > 0 {
+			extra = append(extra, recoveryThreshold(max, outcome))
+		}
+	}
+
+	result := a.recorder.result(extra)
+	result.Burst = burst
+	result.Sockets = a.sockets
+	result.Profiles = a.profiles
+	result.Aborted = a.aborted
+	if a.readOnly != nil {
+		result.SkippedWrites = a.readOnly.skipped.Load()
+	}
+	return result
+}
+
+// result aggregates the recorded metrics and trackers. extra are
+// thresholds evaluated by the caller, reported after the scenario's.
+func (r *recorder) result(extra []threshold.Result) *Result {
+	summary := r.collector.Summary(r.elapsed)
+	r.countChecks(&summary)
+
+	result := &Result{
+		Iterations: r.iterations.Load(),
+		Duration:   r.elapsed,
+		Metrics:    summary,
+		Thresholds: append(r.thresholds.Evaluate(summary), extra...),
+	}
+
+	for _, t := range result.Thresholds {
+		if t.Passed {
+			continue
+		}
+		attrs := map[string]string{"actual": t.Actual}
+		if t.Step != "" {
+			attrs["step"] = t.Step
+		}
+		r.events.Add(timeline.ThresholdBreached, t.Expr, attrs)
+	}
+
+	for _, b := range r.budgets {
+		result.Budgets = append(result.Budgets, b.result())
+	}
+
+	for _, c := range r.checks {
+		result.Checks = append(result.Checks, c.result())
+	}
+
+	for _, c := range r.contracts {
+		result.Contract = append(result.Contract, c.result())
+	}
+
+	for _, t := range r.idempotency {
+		result.Idempotency = append(result.Idempotency, t.result())
+	}
+
+	if r.noise != nil {
+		stats := r.noise.Summary(r.elapsed).Total
+		stats.Name = noiseStep
+		result.Noise = &stats
+	}
+
+	result.Events = r.events.Events()
+	thresholds := r.collector.OutlierThresholds(metrics.DefaultIQRFactor)
+	outliers := r.outliers.Detect(r.started, thresholds, r.gcPauses, stageChanges(result.Events))
+	if outliers.Count > 0 {
+		result.Outliers = outliers
+	}
+	return result
+}
+
+// flowOf returns the flow of the 1-based VU index i. VUs added by Scale
+// repeat the assignment of the scenario's VUs to flows.
+func (a *Agent) flowOf(i int) *flow {
+	return &a.flows[a.flowOrder[(i-1)%len(a.flowOrder)]]
+}
+
+// RateLimits returns the registry holding the scenario's rate limits, for
+// step types that share them with HTTP steps
+func (a *Agent) RateLimits() *ratelimit.Registry {
+	return a.rateLimits
+}
+
+// Seed returns the seed the VUs' identities are derived from, which
+// reproduces them when passed as Options.Seed
+func (a *Agent) Seed() uint64 {
+	return a.seed
+}
+
+// ActiveVUs returns the number of VUs currently running iterations
+func (a *Agent) ActiveVUs() int64 {
+	return a.active.Load()
+}
+
+// Pause stops VUs from starting new iterations; iterations in progress run
+// to completion. The run's duration keeps elapsing while paused. Pause
+// reports whether the operator had not already paused the run.
+func (a *Agent) Pause() bool {
+	return a.hold(operatorHold)
+}
+
+// Resume lets VUs start iterations again after Pause. It reports whether
+// the operator had paused the run. The run stays paused while a hold guard
+// is tripped.
+func (a *Agent) Resume() bool {
+	return a.release(operatorHold)
+}
+
+// operatorHold is the pause holder of Pause and Resume
+const operatorHold = "operator"
+
+// hold pauses the run on behalf of holder. It reports whether holder did
+// not hold the run already.
+func (a *Agent) hold(holder string) bool {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+	if a.holders[holder] {
+		return false
+	}
+	if a.holders == nil {
+		a.holders = make(map[string]bool)
+	}
+	a.holders[holder] = true
+	if a.resumed == nil {
+		a.resumed = make(chan struct{})
+		a.events.Add(timeline.Paused, "VUs idle after their current iteration", map[string]string{"by": holder})
+	}
+	return true
+}
+
+// release drops the pause of holder and resumes the run when no other
+// holder remains. It reports whether holder held the run.
+func (a *Agent) release(holder string) bool {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+	if !a.holders[holder] {
+		return false
+	}
+	delete(a.holders, holder)
+	if len(a.holders) == 0 {
+		close(a.resumed)
+		a.resumed = nil
+		a.events.Add(timeline.Resumed, "VUs start iterations again", map[string]string{"by": holder})
+	}
+	return true
+}
+
+// Paused reports whether the run is paused
+func (a *Agent) Paused() bool {
+	a.pauseMu.Lock()
+	defer a.pauseMu.Unlock()
+	return a.resumed != nil
+}
+
+// waitResumed blocks while the run is paused. It reports false if ctx ended
+// first.
+func (a *Agent) waitResumed(ctx context.Context) bool {
+	a.pauseMu.Lock()
+	resumed := a.resumed
+	a.pauseMu.Unlock()
+	if resumed == nil {
+		return true
+	}
+
+	// An idle VU is not active
+	a.active.Add(-1)
+	defer a.active.Add(1)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resumed:
+		return true
+	}
+}
+
+// labelTag turns a label header into a tag name, e.g. X-Served-By into
+// x_served_by
+func labelTag(header string) string {
+	return strings.ReplaceAll(strings.ToLower(header), "-", "_")
+}
+
+// retryPolicy returns the executor policy of r, nil if r is
+func retryPolicy(r *scenario.TransportRetry) *executor.RetryPolicy {
+	if r == nil {
+		return nil
+	}
+	return &executor.RetryPolicy{
+		Max:        r.Max,
+		Backoff:    r.Backoff.Duration,
+		MaxBackoff: r.MaxBackoff.Duration,
+		RetryStatus: func(status int) bool {
+			return slices.ContainsFunc(r.On, func(code string) bool { return scenario.MatchStatus(code, status) })
+		},
+		RetryNonIdempotent: r.NonIdempotent,
+		Budget:             executor.NewRetryBudget(r.Budget),
+	}
+}
+
+// transportOptions returns the transport settings of the requests of sc
+func transportOptions(sc *scenario.Scenario) executor.TransportOptions {
+	c := cmp.Or(sc.Connections, &scenario.Connections{})
+	opts := executor.TransportOptions{
+		HTTPVersion:         sc.HTTPVersion,
+		MaxIdleConns:        c.MaxIdle,
+		MaxIdleConnsPerHost: cmp.Or(c.MaxIdlePerHost, int(sc.VirtualUsers)),
+		MaxConnsPerHost:     c.MaxPerHost,
+		IdleConnTimeout:     cmp.Or(c.IdleTimeout.Duration, scenario.DefaultIdleTimeout),
+		DisableKeepAlives:   c.DisableKeepAlives,
+	}
+	if sc.Proxy != nil {
+		opts.Proxy, opts.NoProxy = sc.Proxy.ProxyURL(), strings.Join(sc.Proxy.NoProxy, ",")
+	}
+	if sc.TLS != nil {
+		opts.ClientCert, opts.RootCAs = sc.TLS.Certificate, sc.TLS.RootCAs
+		opts.InsecureSkipVerify = sc.TLS.InsecureSkipVerify
+	}
+	if sc.DNS != nil {
+		opts.DNSServer, opts.DNSCacheTTL = sc.DNS.Server, sc.DNS.CacheTTL.Duration
+	}
+	// Sorted, so the options of a scenario always are the same
+	hosts := make([]string, 0, len(sc.Hosts))
+	for _, host := range slices.Sorted(maps.Keys(sc.Hosts)) {
+		hosts = append(hosts, host+"="+sc.Hosts[host])
+	}
+	opts.Hosts = strings.Join(hosts, ",")
+	if sc.Redirects != nil {
+		opts.Redirects, opts.MaxRedirects = sc.Redirects.Policy, sc.Redirects.Max
+	}
+	return opts
+}
+
+// record accounts for a single request outcome
+func (a *Agent) record(sample metrics.Sample) {
+	a.collector.Add(sample)
+	a.outliers.Add(sample)
+	if a.gateWindow != nil {
+		a.gateWindow.Add(sample)
+	}
+
+	for _, sink := range a.opts.SampleSinks {
+		sink.Add(sample)
+	}
+
+	if len(a.opts.Sinks) == 0 {
+		return
+	}
+
+	tags := map[string]string{"step": sample.Step, "agent_version": version.Label()}
+	if sample.Scenario != "" {
+		tags["scenario"] = sample.Scenario
+	}
+	if sample.Protocol != "" {
+		tags["protocol"] = sample.Protocol
+	}
+	for header, value := range sample.Labels {
+		tags[labelTag(header)] = value
+	}
+	if sample.Err == nil {
+		tags["status"] = strconv.Itoa(sample.Status)
+	}
+
+	for _, sink := range a.opts.Sinks {
+		if sample.Err == nil {
+			sink.Timing("http_req_duration", sample.Duration, tags)
+		}
+		sink.Count("http_reqs", 1, tags)
+		if sample.Failed || sample.Err != nil {
+			sink.Count("http_req_failed", 1, tags)
+		}
+	}
+}
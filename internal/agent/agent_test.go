@@ -0,0 +1,3256 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/feeder"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/secrets"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/timeline"
+	"loadforge-agent/pkg/ratelimit"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var profileHits atomic.Int64
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token":"abc","user":{"id":42}}`))
+	})
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" || r.PathValue("id") != "42" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		profileHits.Add(1)
+		w.Write([]byte(`{"name":"alice"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &profileHits
+}
+
+func newTestScenario(baseURL string) *scenario.Scenario {
+	return &scenario.Scenario{
+		Name:         "test",
+		BaseURL:      baseURL,
+		VirtualUsers: 2,
+		Duration:     60,
+		Steps: []scenario.Step{
+			{
+				Request:       "POST /login",
+				Body:          map[string]any{"username": "alice"},
+				SaveToContext: map[string]string{"token": "response.token"},
+				NextSteps: []scenario.NextStep{{
+					Request:     "GET /users/{id}",
+					StatusCodes: []string{"2xx"},
+					Map:         map[string]string{"response.user.id": "path_params.id"},
+				}},
+			},
+			{
+				Request: "GET /users/{id}",
+				Headers: map[string]string{"Authorization": "Bearer ${token}"},
+			},
+		},
+	}
+}
+
+func TestNew_NilScenario(t *testing.T) {
+	if _, err := New(nil, Options{}); err == nil {
+		t.Error("expected error for nil scenario, got nil")
+	}
+}
+
+func TestNew_InvalidTraceVU(t *testing.T) {
+	sc := newTestScenario("http://localhost")
+
+	if _, err := New(sc, Options{TraceVU: 3, Trace: &bytes.Buffer{}}); err == nil {
+		t.Error("expected error for trace VU out of range, got nil")
+	}
+	if _, err := New(sc, Options{TraceVU: 1}); err == nil {
+		t.Error("expected error for trace VU without writer, got nil")
+	}
+}
+
+func TestRun_FollowsNextStepsWithMappedValues(t *testing.T) {
+	server, profileHits := newTestServer(t)
+
+	a, err := New(newTestScenario(server.URL), Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	total := result.Metrics.Total
+	if total.Requests == 0 {
+		t.Fatal("expected requests to be made")
+	}
+	if total.Errors != 0 {
+		t.Errorf("expected no errors, got %d", total.Errors)
+	}
+	if profileHits.Load() == 0 {
+		t.Error("expected authorized profile requests")
+	}
+	if total.Latency.Count != total.Requests {
+		t.Errorf("expected %d latency samples, got %d", total.Requests, total.Latency.Count)
+	}
+
+	if len(result.Metrics.Steps) != 2 {
+		t.Fatalf("expected 2 steps in breakdown, got %d", len(result.Metrics.Steps))
+	}
+	login, profile := result.Metrics.Steps[0], result.Metrics.Steps[1]
+	if login.Name != "POST /login" || profile.Name != "GET /users/{id}" {
+		t.Errorf("unexpected step names: '%s', '%s'", login.Name, profile.Name)
+	}
+	if login.Requests+profile.Requests != total.Requests {
+		t.Errorf("per-step requests %d+%d do not add up to %d",
+			login.Requests, profile.Requests, total.Requests)
+	}
+}
+
+func TestRun_TracesSingleVU(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	var trace bytes.Buffer
+	a, err := New(newTestScenario(server.URL), Options{TraceVU: 2, Trace: &trace})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(&trace)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var ev TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid trace line %q: %v", scanner.Text(), err)
+		}
+		if ev.VU != 2 {
+			t.Fatalf("expected only VU 2 events, got VU %d", ev.VU)
+		}
+		seen[ev.Event] = true
+
+		if ev.Event == TraceVariable && ev.Variable == "token" && ev.Value != "abc" {
+			t.Errorf("expected token 'abc', got '%s'", ev.Value)
+		}
+		if ev.Event == TraceBranch && ev.Next != "GET /users/{id}" {
+			t.Errorf("unexpected branch target '%s'", ev.Next)
+		}
+	}
+
+	for _, kind := range []string{TraceIterationStart, TraceRequest, TraceResponse, TraceVariable, TraceBranch} {
+		if !seen[kind] {
+			t.Errorf("expected %s event in trace", kind)
+		}
+	}
+}
+
+func TestRun_DebugLog(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	var debug bytes.Buffer
+	a, err := New(newTestScenario(server.URL), Options{DebugFirst: 2, DebugBodyLimit: 8, Debug: &debug})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	entries := make(map[string][]DebugEntry)
+	scanner := bufio.NewScanner(&debug)
+	for scanner.Scan() {
+		var entry DebugEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid debug line %q: %v", scanner.Text(), err)
+		}
+		entries[entry.Step] = append(entries[entry.Step], entry)
+	}
+	if len(entries) != 2 || len(entries["POST /login"]) != 2 || len(entries["GET /users/{id}"]) != 2 {
+		t.Fatalf("expected the first 2 requests of both steps, got %v", entries)
+	}
+	login := entries["POST /login"][0]
+	if login.Status != http.StatusOK || login.ResponseBody != `{"token"` || login.ResponseBodySize != 32 {
+		t.Errorf("expected the login response truncated to 8 bytes, got %+v", login)
+	}
+	if auth := entries["GET /users/{id}"][0].Headers["Authorization"]; auth != secrets.Redacted {
+		t.Errorf("expected the Authorization header masked, got %q", auth)
+	}
+
+	if _, err := New(newTestScenario(server.URL), Options{DebugSample: 0.5}); err == nil {
+		t.Error("expected an error for debug logging without a writer")
+	}
+}
+
+func TestMaskRawHeaders(t *testing.T) {
+	raw := "GET / HTTP/1.1\r\nHost: api\r\nauthorization : Basic c2VjcmV0\r\n\r\nbody"
+	want := "GET / HTTP/1.1\r\nHost: api\r\nauthorization : " + secrets.Redacted + "\r\n\r\nbody"
+	if got := string(maskRawHeaders([]byte(raw))); got != want {
+		t.Errorf("maskRawHeaders() = %q, want %q", got, want)
+	}
+}
+
+func TestRun_UnmatchedNextStepsEndsIteration(t *testing.T) {
+	server, profileHits := newTestServer(t)
+
+	sc := newTestScenario(server.URL)
+	sc.Steps[0].NextSteps[0].StatusCodes = []string{"500"}
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if profileHits.Load() != 0 {
+		t.Errorf("expected no profile requests, got %d", profileHits.Load())
+	}
+	if result.Iterations == 0 {
+		t.Error("expected completed iterations")
+	}
+}
+
+func TestNextStep_Weighted(t *testing.T) {
+	step := &scenario.Step{NextSteps: []scenario.NextStep{
+		{Step: "browse", Weight: 90},
+		{Step: "buy", Weight: 10},
+		{Step: "retry", Weight: 50, StatusCodes: []string{"5xx"}},
+	}}
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	picks := make(map[string]int)
+	for range 10_000 {
+		picks[nextStep(step, 200, rng).Target()]++
+	}
+	if picks["retry"] != 0 {
+		t.Errorf("expected entries not matching the status to be skipped, got %v", picks)
+	}
+	if buy := picks["buy"]; buy < 800 || buy > 1200 {
+		t.Errorf("expected ~10%% buy, got %v", picks)
+	}
+
+	picks = make(map[string]int)
+	for range 10_000 {
+		picks[nextStep(step, 503, rng).Target()]++
+	}
+	if retry := picks["retry"]; retry < 3000 || retry > 3600 {
+		t.Errorf("expected ~1/3 retry on 503, got %v", picks)
+	}
+}
+
+func TestSetBodyField_DoesNotModifyOriginal(t *testing.T) {
+	original := map[string]any{"user": map[string]any{"name": "alice"}}
+
+	updated, err := setBodyField(original, []string{"user", "id"}, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user := updated.(map[string]any)["user"].(map[string]any)
+	if user["id"] != "42" || user["name"] != "alice" {
+		t.Errorf("unexpected body: %v", updated)
+	}
+	if _, ok := original["user"].(map[string]any)["id"]; ok {
+		t.Error("original body must not be modified")
+	}
+
+	if _, err := setBodyField("raw", []string{"id"}, "1"); err == nil {
+		t.Error("expected error for non-object body, got nil")
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	step := scenario.Step{
+		Request:    "POST /users/{id}/items",
+		PathParams: map[string]string{"id": "a b"},
+		Query:      map[string]string{"page": "2"},
+		Body:       map[string]any{"qty": 1},
+	}
+
+	req, err := buildRequest("http://example.com/", step)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.URL != "http://example.com/users/a%20b/items?page=2" {
+		t.Errorf("unexpected URL: %s", req.URL)
+	}
+	if string(req.Body) != `{"qty":1}` {
+		t.Errorf("unexpected body: %s", req.Body)
+	}
+	if req.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected JSON content type, got '%s'", req.Headers["Content-Type"])
+	}
+}
+
+func TestBuildRequest_Raw(t *testing.T) {
+	for _, tt := range []struct {
+		raw  string
+		want string
+	}{
+		{"GET / HTTP/1.1\nHost: a\n", "GET / HTTP/1.1\r\nHost: a\r\n\r\n"},
+		{"GET / HTTP/1.1\r\nHost: a", "GET / HTTP/1.1\r\nHost: a\r\n\r\n"},
+		{"GET / HTTP/1.1\nX-Folded: a\n b\n", "GET / HTTP/1.1\r\nX-Folded: a\r\n b\r\n\r\n"},
+		{"POST / HTTP/1.1\nContent-Length: 2\n\nhi", "POST / HTTP/1.1\r\nContent-Length: 2\r\n\r\nhi"},
+		{"POST / HTTP/1.1\nContent-Length: 6\n\na\nb\nc\n", "POST / HTTP/1.1\r\nContent-Length: 6\r\n\r\na\nb\nc\n"},
+		{"POST / HTTP/1.1\r\nContent-Length: 4\r\n\r\na\r\n\n", "POST / HTTP/1.1\r\nContent-Length: 4\r\n\r\na\r\n\n"},
+	} {
+		req, err := buildRequest("http://example.com/", scenario.Step{Request: "GET /", Raw: tt.raw})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(req.Raw) != tt.want || req.URL != "http://example.com/" {
+			t.Errorf("raw %q: expected %q to %s, got %q to %s", tt.raw, tt.want, "http://example.com/", req.Raw, req.URL)
+		}
+	}
+}
+
+func TestBuildRequest_Files(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := buildRequest("http://example.com", scenario.Step{Request: "PUT /files", BodyFile: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.ContentLength != 10 || requestSize(req) != 10 || req.Headers["Content-Type"] != "application/octet-stream" {
+		t.Errorf("unexpected body_file request: %d bytes of %s", req.ContentLength, req.Headers["Content-Type"])
+	}
+
+	req, err = buildRequest("http://example.com", scenario.Step{
+		Request:   "POST /files",
+		Multipart: &scenario.Multipart{Files: []scenario.MultipartFile{{Field: "file", Path: path}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.ContentLength <= 10 || requestSize(req) != req.ContentLength ||
+		!strings.HasPrefix(req.Headers["Content-Type"], "multipart/form-data; boundary=") {
+		t.Errorf("unexpected multipart request: %d bytes of %s", req.ContentLength, req.Headers["Content-Type"])
+	}
+
+	if _, err := buildRequest("http://example.com", scenario.Step{Request: "PUT /files", BodyFile: path + ".missing"}); err == nil {
+		t.Error("expected error for missing body_file, got nil")
+	}
+}
+
+func TestRun_DuplicateRequestsResolvedByName(t *testing.T) {
+	var pages [3]atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "1":
+			pages[1].Add(1)
+		case "2":
+			pages[2].Add(1)
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "pages",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{
+			{
+				Name:    "page_1",
+				Request: "GET /items",
+				Query:   map[string]string{"page": "1"},
+				NextSteps: []scenario.NextStep{
+					{Step: "page_2", StatusCodes: []string{"200"}},
+				},
+			},
+			{
+				Name:      "page_2",
+				Request:   "GET /items",
+				Query:     map[string]string{"page": "2"},
+				NextSteps: []scenario.NextStep{{Step: "page_1", StatusCodes: []string{"500"}}},
+			},
+		},
+	}
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if pages[1].Load() == 0 || pages[2].Load() == 0 {
+		t.Errorf("expected both pages to be requested, got page 1: %d, page 2: %d",
+			pages[1].Load(), pages[2].Load())
+	}
+}
+
+func TestRun_BackgroundNoise(t *testing.T) {
+	var noiseHits, scenarioHits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/static/app.js" || r.URL.Path == "/static/app.css" {
+			noiseHits.Add(1)
+			time.Sleep(5 * time.Millisecond)
+			return
+		}
+		scenarioHits.Add(1)
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "noise",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Noise: &scenario.Noise{
+			URLs: []string{"/static/app.js", server.URL + "/static/app.css"},
+			Rate: 100,
+		},
+		Steps: []scenario.Step{{Request: "GET /"}},
+	}
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if noiseHits.Load() == 0 {
+		t.Error("expected noise requests")
+	}
+	if result.Noise == nil || result.Noise.Requests == 0 {
+		t.Fatalf("expected noise stats, got %+v", result.Noise)
+	}
+	// Requests cut off by the end of the run reach the server without
+	// being recorded, so recorded counts can only be lower than hits
+	if result.Metrics.Total.Requests > scenarioHits.Load() {
+		t.Errorf("noise must not be counted in scenario totals: %d recorded, %d scenario hits",
+			result.Metrics.Total.Requests, scenarioHits.Load())
+	}
+	for _, step := range result.Metrics.Steps {
+		if step.Name == noiseStep {
+			t.Error("noise must not appear in the scenario step breakdown")
+		}
+	}
+	// Noise requests in flight are done before Run returns
+	time.Sleep(20 * time.Millisecond)
+	if got := a.noise.Summary(0).Total.Requests; got != result.Noise.Requests {
+		t.Errorf("expected no noise recorded after Run returned, got %d more", got-result.Noise.Requests)
+	}
+}
+
+func TestRun_StepGroupBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "budgets",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Budgets: []scenario.Budget{
+			{Name: "fast", Steps: []string{"GET /fast"}, Max: scenario.Duration{Duration: time.Second}},
+			{Name: "slow", Steps: []string{"GET /fast", "GET /slow"}, Max: scenario.Duration{Duration: time.Millisecond}},
+		},
+		Steps: []scenario.Step{{Request: "GET /fast"}, {Request: "GET /slow"}},
+	}
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if len(result.Budgets) != 2 {
+		t.Fatalf("expected 2 budgets, got %d", len(result.Budgets))
+	}
+
+	fast, slow := result.Budgets[0], result.Budgets[1]
+	if fast.Iterations == 0 || fast.Violations != 0 {
+		t.Errorf("expected fast budget to hold, got %+v", fast)
+	}
+	if slow.Iterations == 0 || slow.ViolationRate != 1 {
+		t.Errorf("expected slow budget to be violated every iteration, got %+v", slow)
+	}
+	if slow.Duration.Min < 5*time.Millisecond {
+		t.Errorf("expected group duration to include the slow step, got min %s", slow.Duration.Min)
+	}
+}
+
+func TestRun_StepGroupBudgetCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "budgets",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Budgets: []scenario.Budget{
+			{Name: "login", Steps: []string{"GET /fast", "GET /slow"}, Max: scenario.Duration{Duration: 20 * time.Millisecond}, Cancel: true},
+		},
+		Steps: []scenario.Step{{Request: "GET /fast"}, {Request: "GET /slow"}, {Request: "GET /after"}},
+	}
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// Without cancelling, the slow step would hold the VU for the whole run
+	if result.Iterations < 3 {
+		t.Errorf("expected iterations to keep their pace, got %d", result.Iterations)
+	}
+	login := result.Budgets[0]
+	if login.Cancelled == 0 || login.Cancelled != login.Violations || login.Violations != login.Iterations {
+		t.Errorf("expected every iteration to be cancelled over budget, got %+v", login)
+	}
+	for _, step := range result.Metrics.Steps {
+		switch step.Name {
+		case "GET /slow":
+			if step.ErrorKinds["over budget"] == 0 || step.ErrorKinds["timeout"] != 0 {
+				t.Errorf("expected over budget errors distinct from timeouts, got %v", step.ErrorKinds)
+			}
+		case "GET /after":
+			if step.Requests != 0 {
+				t.Errorf("expected cancelled iterations to end, got %d requests after", step.Requests)
+			}
+		}
+	}
+}
+
+func TestRun_RateLimit(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "limited",
+		BaseURL:      server.URL,
+		VirtualUsers: 5,
+		Duration:     60,
+		RateLimits:   map[string]scenario.RateLimit{"api": {Rate: 50, Burst: 1}},
+		Steps:        []scenario.Step{{Request: "GET /", RateLimit: "api"}},
+	}
+	shared := ratelimit.NewRegistry()
+	a, err := New(sc, Options{RateLimits: shared})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if bucket, ok := a.RateLimits().Get("api"); !ok || a.RateLimits() != shared || bucket.Rate() != 50 {
+		t.Fatalf("expected the rate limit in the shared registry, got %v", bucket)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// 50/s for 200ms is about 10 requests, 5 VUs unlimited would send hundreds
+	if n := requests.Load(); n < 5 || n > 15 {
+		t.Errorf("expected about 10 requests, got %d", n)
+	}
+}
+
+func TestRun_MaxRPS(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.URL.Path]++
+	}))
+	defer server.Close()
+
+	p := scenario.NewParser()
+	err := p.ParseData([]byte(`
+name: capped
+base_url: ` + server.URL + `
+virtual_users: 10
+duration: 60
+max_rps: 50
+scenarios:
+  - name: browse
+    weight: 1
+    steps:
+      - request: GET /home
+  - name: search
+    weight: 1
+    max_rps: 10
+    steps:
+      - request: GET /search
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sc, _ := p.GetScenario()
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if bucket, ok := a.RateLimits().Get("max_rps.search"); !ok || bucket.Rate() != 10 {
+		t.Fatalf("expected the search scenario's max_rps in the registry, got %v", bucket)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	// 50/s for 400ms is about 20 requests, 10/s of which at most 5 searches
+	mu.Lock()
+	defer mu.Unlock()
+	if n := requests["/home"] + requests["/search"]; n < 10 || n > 25 {
+		t.Errorf("expected about 20 requests, got %d", n)
+	}
+	if n := requests["/search"]; n < 1 || n > 6 {
+		t.Errorf("expected at most 5 searches, got %d", n)
+	}
+}
+
+func TestRun_TransportRetry(t *testing.T) {
+	// Every request of a VU fails once, the cookie in the VU's jar making
+	// its retry succeed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("retried"); err != nil {
+			http.SetCookie(w, &http.Cookie{Name: "retried", Value: "1"})
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "retried", MaxAge: -1})
+	}))
+	defer server.Close()
+
+	sc := newTestScenario(server.URL)
+	sc.TransportRetry = &scenario.TransportRetry{
+		Max:        2,
+		Backoff:    scenario.Duration{Duration: time.Millisecond},
+		MaxBackoff: scenario.Duration{Duration: time.Millisecond},
+		On:         []string{"503"},
+		Budget:     1,
+		// The step is a POST
+		NonIdempotent: true,
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	steps := make(map[string]metrics.StepStats)
+	for _, st := range result.Metrics.Steps {
+		steps[st.Name] = st
+	}
+	name := sc.Steps[0].ID()
+	if st := steps[name]; st.Requests == 0 || st.Errors != 0 {
+		t.Errorf("expected the retried step to succeed, got %+v", st)
+	}
+	if st := steps[scenario.TransportRetryStep(name)]; st.Requests == 0 || st.Errors != st.Requests {
+		t.Errorf("expected the retried 503s reported apart, got %+v", st)
+	}
+}
+
+func TestNew_RateLimitSplitAcrossWorkers(t *testing.T) {
+	sc := newTestScenario("http://127.0.0.1:1")
+	sc.RateLimits = map[string]scenario.RateLimit{"api": {Rate: 100, Burst: 10}}
+	sc.Steps[0].RateLimit = "api"
+
+	a, err := New(sc, Options{Worker: 1, Workers: 4})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	bucket, ok := a.RateLimits().Get("api")
+	if !ok || bucket.Rate() != 25 || bucket.Burst() != 2 {
+		t.Errorf("expected a quarter of the limit, got %v", bucket)
+	}
+}
+
+type sampleRecorder struct {
+	mu      sync.Mutex
+	samples []metrics.Sample
+}
+
+func (r *sampleRecorder) Add(s metrics.Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples = append(r.samples, s)
+}
+
+func (r *sampleRecorder) Close() error { return nil }
+
+func TestRun_SampleSinks(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	recorder := &sampleRecorder{}
+	a, err := New(newTestScenario(server.URL), Options{SampleSinks: []metrics.SampleSink{recorder}})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if int64(len(recorder.samples)) != result.Metrics.Total.Requests {
+		t.Fatalf("expected %d samples, got %d", result.Metrics.Total.Requests, len(recorder.samples))
+	}
+
+	login := recorder.samples[0]
+	if login.Step != "POST /login" || login.BytesSent == 0 || login.BytesReceived == 0 || login.Time.IsZero() {
+		t.Errorf("unexpected login sample: %+v", login)
+	}
+}
+
+func TestRun_LabelHeaders(t *testing.T) {
+	var n atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", fmt.Sprintf("web-%d", n.Add(1)%2))
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "labels",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		LabelHeaders: []string{"X-Served-By", "X-Backend-Pool"},
+		Steps:        []scenario.Step{{Request: "GET /"}},
+	}
+	recorder := &sampleRecorder{}
+	a, err := New(sc, Options{SampleSinks: []metrics.SampleSink{recorder}})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for _, s := range recorder.samples {
+		if len(s.Labels) != 1 || !strings.HasPrefix(s.Labels["X-Served-By"], "web-") {
+			t.Fatalf("expected only the X-Served-By label, got %v", s.Labels)
+		}
+	}
+
+	if labels := result.Metrics.Labels; len(labels) != 2 || !strings.HasPrefix(labels[0].Name, "X-Served-By=web-") {
+		t.Errorf("expected a breakdown per backend, got %+v", labels)
+	}
+}
+
+func TestRun_BaseURLs(t *testing.T) {
+	var eu, us atomic.Int64
+	euServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { eu.Add(1) }))
+	defer euServer.Close()
+	usServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { us.Add(1) }))
+	defer usServer.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "regions",
+		BaseURL:      euServer.URL,
+		BaseURLs:     []scenario.BaseURL{{URL: euServer.URL, Weight: 60, Name: "eu"}, {URL: usServer.URL, Weight: 40, Name: "us"}},
+		VirtualUsers: 2,
+		Duration:     60,
+		Steps:        []scenario.Step{{Request: "GET /"}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if eu.Load() == 0 || us.Load() == 0 {
+		t.Fatalf("expected requests to both base URLs, got eu=%d us=%d", eu.Load(), us.Load())
+	}
+	labels := make(map[string]int64)
+	for _, l := range result.Metrics.Labels {
+		labels[l.Name] = l.Requests
+	}
+	if len(labels) != 2 || labels["base_url=eu"] == 0 || labels["base_url=us"] == 0 {
+		t.Errorf("expected a breakdown per base URL, got %v", labels)
+	}
+	if total := labels["base_url=eu"] + labels["base_url=us"]; total != result.Metrics.Total.Requests {
+		t.Errorf("expected the base URLs to add up to %d requests, got %d", result.Metrics.Total.Requests, total)
+	}
+}
+
+func TestRun_WeightedScenarios(t *testing.T) {
+	var mu sync.Mutex
+	paths := make(map[string]map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if paths[r.URL.Path] == nil {
+			paths[r.URL.Path] = make(map[string]bool)
+		}
+		paths[r.URL.Path][r.Header.Get("X-Flow")] = true
+	}))
+	defer server.Close()
+
+	p := scenario.NewParser()
+	err := p.ParseData([]byte(`
+name: shop
+base_url: ` + server.URL + `
+virtual_users: 4
+duration: 60
+variables: {flow: none}
+scenarios:
+  - name: browse
+    weight: 3
+    variables: {flow: browse}
+    steps:
+      - request: GET /home
+        headers: {X-Flow: "${flow}"}
+  - name: checkout
+    weight: 1
+    variables: {flow: checkout}
+    steps:
+      - request: POST /pay
+        headers: {X-Flow: "${flow}"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sc, _ := p.GetScenario()
+
+	recorder := &sampleRecorder{}
+	a, err := New(sc, Options{SampleSinks: []metrics.SampleSink{recorder}})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if a.flows[0].vus != 3 || a.flows[1].vus != 1 {
+		t.Errorf("expected VUs split 3/1, got %d/%d", a.flows[0].vus, a.flows[1].vus)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	requests := make(map[string]int64)
+	for _, st := range result.Metrics.Steps {
+		requests[st.Name] = st.Requests
+	}
+	for _, step := range []string{"browse: GET /home", "checkout: POST /pay"} {
+		if requests[step] == 0 {
+			t.Errorf("expected requests for step '%s'", step)
+		}
+	}
+
+	mu.Lock()
+	if len(paths["/home"]) != 1 || !paths["/home"]["browse"] || len(paths["/pay"]) != 1 || !paths["/pay"]["checkout"] {
+		t.Errorf("expected each flow to run with its own variables, got %v", paths)
+	}
+	mu.Unlock()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for _, s := range recorder.samples {
+		if want, _, _ := strings.Cut(s.Step, ":"); s.Scenario != want {
+			t.Errorf("sample of step '%s' tagged with scenario '%s'", s.Step, s.Scenario)
+			break
+		}
+	}
+}
+
+func TestRun_WeightedScenariosKeepMixWhenScaled(t *testing.T) {
+	var mu sync.Mutex
+	vus := make(map[string]map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if vus[r.URL.Path] == nil {
+			vus[r.URL.Path] = make(map[string]bool)
+		}
+		vus[r.URL.Path][r.URL.Query().Get("vu")] = true
+	}))
+	defer server.Close()
+
+	p := scenario.NewParser()
+	err := p.ParseData([]byte(`
+name: shop
+base_url: ` + server.URL + `
+virtual_users: 10
+duration: 60
+scenarios:
+  - name: browse
+    weight: 70
+    steps:
+      - request: GET /home
+        query: {vu: "${vu.id}"}
+  - name: checkout
+    weight: 30
+    steps:
+      - request: POST /pay
+        query: {vu: "${vu.id}"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sc, _ := p.GetScenario()
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if err := a.Scale(4); err != nil {
+		t.Fatalf("Scale() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(vus["/home"]) != 3 || len(vus["/pay"]) != 1 {
+		t.Errorf("expected 3 browse and 1 checkout VUs at 4 of 10 VUs, got %v", vus)
+	}
+}
+
+func TestInterleaveFlows(t *testing.T) {
+	flows := []flow{{vus: 7}, {vus: 3}}
+	order := interleaveFlows(flows)
+	if len(order) != 10 {
+		t.Fatalf("expected 10 positions, got %d", len(order))
+	}
+
+	var counts [2]int
+	for i, f := range order {
+		counts[f]++
+		// Every prefix stays within one VU of the 70/30 mix
+		if want := float64(i+1) * 0.3; math.Abs(float64(counts[1])-want) > 1 {
+			t.Errorf("prefix of %d VUs has %d checkout VUs, want about %.1f", i+1, counts[1], want)
+		}
+	}
+	if counts != [2]int{7, 3} {
+		t.Errorf("expected 7/3 VUs, got %v", counts)
+	}
+}
+
+func TestSplitByWeight(t *testing.T) {
+	tests := []struct {
+		total   uint64
+		weights []float64
+		want    []uint64
+	}{
+		{10, []float64{70, 30}, []uint64{7, 3}},
+		{10, []float64{1, 1, 1}, []uint64{4, 3, 3}},
+		{4, []float64{0.5, 0.25, 0.25}, []uint64{2, 1, 1}},
+		{2, []float64{0.9, 0.1}, []uint64{1, 1}},
+		{5, []float64{1}, []uint64{5}},
+	}
+
+	for _, tt := range tests {
+		if got := splitByWeight(tt.total, tt.weights); !slices.Equal(got, tt.want) {
+			t.Errorf("splitByWeight(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+		}
+	}
+}
+
+func TestRun_DataFeeder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte("username,password\nalice,a1\nbob,b2\ncarol,c3\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	tests := []struct {
+		strategy string
+		vus      uint64
+		want     map[string]bool
+	}{
+		{scenario.DataUnique, 2, map[string]bool{"alice:a1": true, "bob:b2": true}},
+		{scenario.DataRoundRobin, 1, map[string]bool{"alice:a1": true, "bob:b2": true, "carol:c3": true}},
+		{scenario.DataRandom, 2, nil},
+	}
+
+	for _, tt := range tests {
+		var mu sync.Mutex
+		seen := make(map[string]bool)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[r.URL.Query().Get("login")] = true
+		}))
+
+		sc := &scenario.Scenario{
+			Name:         "data",
+			BaseURL:      server.URL,
+			VirtualUsers: tt.vus,
+			Duration:     60,
+			Data:         &scenario.Data{File: path, Strategy: tt.strategy},
+			Steps: []scenario.Step{{
+				Request: "GET /login",
+				Query:   map[string]string{"login": "${csv.username}:${csv.password}"},
+			}},
+		}
+		a, err := New(sc, Options{})
+		if err != nil {
+			t.Fatalf("%s: New() failed: %v", tt.strategy, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		result, err := a.Run(ctx)
+		cancel()
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: Run() failed: %v", tt.strategy, err)
+		}
+		if result.Metrics.Total.Errors != 0 {
+			t.Errorf("%s: expected no errors, got %d", tt.strategy, result.Metrics.Total.Errors)
+		}
+
+		mu.Lock()
+		if tt.want != nil && !maps.Equal(seen, tt.want) {
+			t.Errorf("%s: expected rows %v, got %v", tt.strategy, tt.want, seen)
+		}
+		if len(seen) == 0 {
+			t.Errorf("%s: no requests", tt.strategy)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestRun_DataFeederNDJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(path, []byte(`{"username": "alice", "id": 1}`+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	var login atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		login.Store(r.URL.Query().Get("login"))
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "data",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Data:         &scenario.Data{File: path, Format: scenario.DataFormatNDJSON},
+		Steps: []scenario.Step{{
+			Request: "GET /login",
+			Query:   map[string]string{"login": "${csv.username}:${csv.id}"},
+		}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if got := login.Load(); got != "alice:1" {
+		t.Errorf("expected the NDJSON row, got %v", got)
+	}
+}
+
+func TestRun_DataFeederQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	mock.ExpectQuery("SELECT username FROM users").WillReturnRows(
+		sqlmock.NewRows([]string{"username"}).AddRow("alice").AddRow("bob"))
+	mock.ExpectClose()
+
+	var driver, dsn string
+	openDB = func(ctx context.Context, d, s string) (*sql.DB, error) {
+		driver, dsn = d, s
+		return db, nil
+	}
+	t.Cleanup(func() { openDB = feeder.OpenDB })
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.URL.Query().Get("user")] = true
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "data",
+		BaseURL:      server.URL,
+		VirtualUsers: 2,
+		Duration:     60,
+		Data: &scenario.Data{
+			Query: &scenario.DataQuery{
+				Driver: scenario.DataDriverPostgres,
+				DSN:    "postgres://loadforge@db/shop",
+				SQL:    "SELECT username FROM users",
+			},
+			Strategy: scenario.DataUnique,
+		},
+		Steps: []scenario.Step{{Request: "GET /", Query: map[string]string{"user": "${csv.username}"}}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if driver != scenario.DataDriverPostgres || dsn != "postgres://loadforge@db/shop" {
+		t.Errorf("unexpected database %s %s", driver, dsn)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !maps.Equal(seen, map[string]bool{"alice": true, "bob": true}) {
+		t.Errorf("expected the rows of the query, got %v", seen)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRun_DataFeederURL(t *testing.T) {
+	var codes atomic.Value
+	codes.Store(`[{"code": "SPRING"}]`)
+	data := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, codes.Load())
+	}))
+	defer data.Close()
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.URL.Query().Get("code")] = true
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "data",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Data: &scenario.Data{
+			URL:     data.URL,
+			Refresh: scenario.Duration{Duration: 10 * time.Millisecond},
+		},
+		Steps: []scenario.Step{{
+			Request: "GET /checkout",
+			Query:   map[string]string{"code": "${csv.code}"},
+			Delay:   scenario.Delay{Duration: scenario.Duration{Duration: time.Millisecond}},
+		}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		codes.Store(`[{"code": "SUMMER"}]`)
+	}()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !maps.Equal(seen, map[string]bool{"SPRING": true, "SUMMER": true}) {
+		t.Errorf("expected the rows before and after the refresh, got %v", seen)
+	}
+}
+
+func TestNew_DataFeederTooFewRowsForUnique(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte("username\nalice\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	sc := &scenario.Scenario{
+		Name:         "data",
+		BaseURL:      "http://localhost",
+		VirtualUsers: 2,
+		Duration:     1,
+		Data:         &scenario.Data{File: path, Strategy: scenario.DataUnique},
+		Steps:        []scenario.Step{{Request: "GET /"}},
+	}
+	if _, err := New(sc, Options{}); err == nil {
+		t.Error("expected error for fewer rows than VUs, got nil")
+	}
+
+	// Each of two workers feeds one VU from its own half of the rows
+	sc.VirtualUsers = 1
+	if _, err := New(sc, Options{Worker: 1, Workers: 2}); err != nil {
+		t.Errorf("unexpected error for the worker holding the row: %v", err)
+	}
+	if _, err := New(sc, Options{Worker: 0, Workers: 2}); err == nil {
+		t.Error("expected error for the worker without rows, got nil")
+	}
+}
+
+func TestNew_DataFeederHashPartition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	var sb strings.Builder
+	sb.WriteString("username,password\n")
+	for i := range 40 {
+		fmt.Fprintf(&sb, "user%d,p%d\n", i, i)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	sc := &scenario.Scenario{
+		Name:         "data",
+		BaseURL:      "http://localhost",
+		VirtualUsers: 1,
+		Duration:     1,
+		Data: &scenario.Data{File: path, Strategy: scenario.DataUnique,
+			Partition: scenario.DataPartitionHash, Key: "username"},
+		Steps: []scenario.Step{{Request: "GET /"}},
+	}
+	total := 0
+	for worker := range 3 {
+		a, err := New(sc, Options{Worker: worker, Workers: 3})
+		if err != nil {
+			t.Fatalf("worker %d: New() failed: %v", worker, err)
+		}
+		total += a.data.rows.Len()
+		a.data.close()
+	}
+	if total != 40 {
+		t.Errorf("expected the workers to share the 40 rows, got %d", total)
+	}
+
+	// A retired worker's rows go to no one
+	a, err := New(sc, Options{Worker: 0, Workers: 3})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	before := a.data.rows.Len()
+	a.data.close()
+	if a, err = New(sc, Options{Worker: 0, Workers: 3, RetiredWorkers: []int{1}}); err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if after := a.data.rows.Len(); after != before {
+		t.Errorf("expected worker 0 to keep its %d rows when worker 1 retires, got %d", before, after)
+	}
+	a.data.close()
+
+	// Unique rows need distinct keys
+	if err := os.WriteFile(path, []byte(sb.String()+"user7,other\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	if _, err := New(sc, Options{Worker: 0, Workers: 3}); err == nil || !strings.Contains(err.Error(), `key "user7" is shared by records 8, 41`) {
+		t.Errorf("expected an overlap error, got %v", err)
+	}
+	sc.Data.Strategy = scenario.DataRoundRobin
+	if a, err := New(sc, Options{Worker: 0, Workers: 3}); err != nil {
+		t.Errorf("unexpected error for round robin rows: %v", err)
+	} else {
+		a.data.close()
+	}
+}
+
+func TestRun_Secrets(t *testing.T) {
+	t.Setenv("LOADFORGE_TEST_TOKEN", "s3cr3t-token")
+
+	var authorized atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/abort" {
+			panic(http.ErrAbortHandler)
+		}
+		if r.Header.Get("Authorization") == "Bearer s3cr3t-token" {
+			authorized.Add(1)
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "secrets",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Secrets:      map[string]string{"token": "env:LOADFORGE_TEST_TOKEN"},
+		Steps: []scenario.Step{
+			{Request: "GET /me", Headers: map[string]string{"Authorization": "Bearer ${secrets.token}"}},
+			{Request: "GET /abort", Query: map[string]string{"key": "${secrets.token}"}},
+		},
+	}
+	var trace bytes.Buffer
+	recorder := &sampleRecorder{}
+	a, err := New(sc, Options{TraceVU: 1, Trace: &trace, SampleSinks: []metrics.SampleSink{recorder}})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if authorized.Load() == 0 {
+		t.Error("expected requests authorized with the secret")
+	}
+	if strings.Contains(trace.String(), "s3cr3t-token") || !strings.Contains(trace.String(), secrets.Redacted) {
+		t.Errorf("expected the secret to be redacted from the trace, got %s", trace.String())
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	redacted := 0
+	for _, s := range recorder.samples {
+		if s.Err == nil {
+			continue
+		}
+		if strings.Contains(s.Err.Error(), "s3cr3t-token") {
+			t.Fatalf("expected the secret to be redacted from errors, got %v", s.Err)
+		}
+		if strings.Contains(s.Err.Error(), secrets.Redacted) {
+			redacted++
+		}
+	}
+	if redacted == 0 {
+		t.Error("expected failures of the aborted step with the secret in their URL")
+	}
+}
+
+func TestNew_UnresolvableSecret(t *testing.T) {
+	sc := newTestScenario("http://127.0.0.1:1")
+	sc.Secrets = map[string]string{"token": "env:LOADFORGE_TEST_UNSET"}
+	if _, err := New(sc, Options{}); err == nil {
+		t.Error("expected error for a secret that cannot be resolved, got nil")
+	}
+}
+
+func TestRun_GeneratedPayload(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string][]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		if r.URL.Path == "/json" && !json.Valid(buf.Bytes()) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		key := r.URL.Path + " " + r.Header.Get("Content-Type")
+		mu.Lock()
+		bodies[key] = append(bodies[key], buf.Len())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	credentials := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(credentials, []byte("user\nalice\n"), 0o644); err != nil {
+		t.Fatalf("failed to write credentials: %v", err)
+	}
+	binary := func(size int) *scenario.Payload {
+		return &scenario.Payload{Kind: scenario.PayloadBinary, Size: scenario.Size{Bytes: size}}
+	}
+	sc := &scenario.Scenario{
+		Name:         "payloads",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Setup:        []scenario.Step{{Request: "POST /setup", Payload: binary(10)}},
+		Teardown:     []scenario.Step{{Request: "POST /teardown", Payload: binary(20)}},
+		AuthPools: []scenario.AuthPool{{
+			Name:        "users",
+			Weight:      1,
+			Credentials: credentials,
+			Login:       []scenario.Step{{Request: "POST /login", Payload: binary(30)}},
+		}},
+		Steps: []scenario.Step{
+			{Request: "POST /json", Payload: &scenario.Payload{Kind: scenario.PayloadJSON, Size: scenario.Size{Bytes: 4096}, Depth: 3}},
+			{Request: "PUT /blob", Payload: binary(1000)},
+		},
+	}
+
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for key, size := range map[string]int{
+		"/json application/json":             4096,
+		"/blob application/octet-stream":     1000,
+		"/setup application/octet-stream":    10,
+		"/teardown application/octet-stream": 20,
+		"/login application/octet-stream":    30,
+	} {
+		if len(bodies[key]) == 0 {
+			t.Errorf("expected requests for %s, got %v", key, bodies)
+			continue
+		}
+		for _, n := range bodies[key] {
+			if n != size {
+				t.Errorf("%s: expected %d byte bodies, got %d", key, size, n)
+				break
+			}
+		}
+	}
+}
+
+func TestRun_FilePayload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	if err := os.WriteFile(path, []byte(`{"id":1}`+"\n"+`{"id":2}`+"\n"+`{"id":3}`+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write payload file: %v", err)
+	}
+
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		buf.ReadFrom(r.Body)
+		mu.Lock()
+		bodies = append(bodies, buf.String())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "payloads",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{{
+			Request: "POST /users",
+			Payload: &scenario.Payload{Source: scenario.PayloadFile, Path: path, Order: scenario.PayloadNext},
+		}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) < 4 {
+		t.Fatalf("expected at least 4 requests, got %d", len(bodies))
+	}
+	for i, want := range []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":1}`} {
+		if bodies[i] != want {
+			t.Errorf("request %d: expected body %s, got %s", i, want, bodies[i])
+		}
+	}
+}
+
+func TestRun_PauseResume(t *testing.T) {
+	server, profileHits := newTestServer(t)
+	a, err := New(newTestScenario(server.URL), Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if !a.Pause() {
+		t.Fatal("expected Pause() to pause a running test")
+	}
+	if a.Pause() {
+		t.Error("expected second Pause() to report already paused")
+	}
+
+	// VUs finish their current iteration, then go idle
+	deadline := time.Now().Add(5 * time.Second)
+	for a.ActiveVUs() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected no active VUs while paused, got %d", a.ActiveVUs())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	paused := profileHits.Load()
+	time.Sleep(50 * time.Millisecond)
+	if hits := profileHits.Load(); hits != paused {
+		t.Errorf("expected no requests while paused, got %d more", hits-paused)
+	}
+
+	if !a.Resume() {
+		t.Fatal("expected Resume() to resume a paused test")
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for profileHits.Load() == paused {
+		if time.Now().After(deadline) {
+			t.Fatal("expected requests after resuming")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Ending the run while paused does not hang
+	a.Pause()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not end while paused")
+	}
+}
+
+func TestRun_Repeat(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"page": "` + r.URL.Query().Get("page") + `"}`))
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "repeat",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{
+			{
+				Request:       "GET /items",
+				Query:         map[string]string{"page": "${repeat.index}"},
+				SaveToContext: map[string]string{"last": "response.page"},
+				Repeat:        3,
+			},
+			{Request: "GET /done", Query: map[string]string{"last": "${last}"}},
+		},
+	}
+	a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"/items?page=0", "/items?page=1", "/items?page=2", "/done?last=2"}
+	if len(requests) < len(want) || !slices.Equal(requests[:len(want)], want) {
+		t.Errorf("expected the step repeated before the next one, got %v", requests)
+	}
+}
+
+func TestRun_Retry(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		fail    func(n int64) bool
+		retry   scenario.Retry
+		retries int64
+	}{
+		// Every other request fails, so each first attempt is retried once
+		{"transient", func(n int64) bool { return n%2 == 1 }, scenario.Retry{Max: 3, On: []string{"5xx"}}, 1},
+		{"persistent", func(int64) bool { return true }, scenario.Retry{Max: 2, On: []string{"503"}}, 2},
+		{"not matching", func(int64) bool { return true }, scenario.Retry{Max: 2, On: []string{"502"}}, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var n atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.fail(n.Add(1)) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			tt.retry.Backoff = scenario.Duration{Duration: time.Millisecond}
+			sc := &scenario.Scenario{
+				Name:         "retry",
+				BaseURL:      server.URL,
+				VirtualUsers: 1,
+				Duration:     60,
+				Steps:        []scenario.Step{{Request: "GET /", Retry: &tt.retry}},
+			}
+			a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			result, err := a.Run(ctx)
+			if err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+
+			first, retries := result.Metrics.Steps[0], result.Metrics.Steps[1]
+			if retries.Name != "GET / (retry)" {
+				t.Fatalf("expected retries reported apart, got %+v", result.Metrics.Steps)
+			}
+			if first.Requests == 0 || first.Errors != first.Requests {
+				t.Errorf("expected every first attempt to fail, got %+v", first)
+			}
+			// The run may end between an attempt and its retry
+			if want := first.Requests * tt.retries; retries.Requests < want-tt.retries || retries.Requests > want {
+				t.Errorf("expected ~%d retries, got %d", want, retries.Requests)
+			}
+			if tt.name == "transient" && retries.Errors != 0 {
+				t.Errorf("expected retries to succeed, got %d errors", retries.Errors)
+			}
+		})
+	}
+}
+
+func TestRun_Checks(t *testing.T) {
+	var n atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%4 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "checks",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{{Request: "GET /", Checks: []scenario.Check{
+			{Name: "status is 200", Condition: "${last.status} == 200"},
+			{Name: "has id", Condition: "${response.id} == 7"},
+		}}},
+	}
+	a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if len(result.Checks) != 2 {
+		t.Fatalf("expected 2 checks, got %+v", result.Checks)
+	}
+	requests := result.Metrics.Total.Requests
+	for _, c := range result.Checks {
+		if c.Passes+c.Fails != requests || c.Fails != requests/4 {
+			t.Errorf("check '%s': expected %d of %d to fail, got %+v", c.Name, requests/4, requests, c)
+		}
+	}
+	// Failed checks do not fail the run
+	if !result.Passed() {
+		t.Error("expected the run to pass")
+	}
+
+	state, err := a.State()
+	if err != nil {
+		t.Fatalf("State() failed: %v", err)
+	}
+	merged, err := Merge(sc, Options{}, []*State{state, state})
+	if err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+	if got := merged.Checks[0].Passes; got != 2*result.Checks[0].Passes {
+		t.Errorf("expected merged passes to add up, got %d", got)
+	}
+}
+
+func TestMerge_OnlyReadsScenario(t *testing.T) {
+	// The data and secret files are on the workers, not where states merge
+	dir := t.TempDir()
+	sc := &scenario.Scenario{
+		Name:         "merge",
+		BaseURL:      "http://127.0.0.1:1",
+		VirtualUsers: 1,
+		Duration:     60,
+		Thresholds:   []string{"requests == 6"},
+		Data:         &scenario.Data{File: filepath.Join(dir, "users.csv"), Strategy: scenario.DataUnique},
+		Secrets:      map[string]string{"token": "file:" + filepath.Join(dir, "token")},
+		Steps:        []scenario.Step{{Request: "GET /"}},
+	}
+	if _, err := New(sc, Options{}); err == nil {
+		t.Fatal("expected New() to fail without the data file")
+	}
+
+	c, err := metrics.NewCollector(metrics.EstimatorHDR, 0)
+	if err != nil {
+		t.Fatalf("NewCollector() failed: %v", err)
+	}
+	c.Register("GET /")
+	for range 3 {
+		c.Add(metrics.Sample{Step: "GET /", Status: 200, Duration: 10 * time.Millisecond})
+	}
+	collected, err := c.State()
+	if err != nil {
+		t.Fatalf("State() failed: %v", err)
+	}
+	state := &State{Iterations: 3, Duration: time.Second, Metrics: collected}
+
+	result, err := Merge(sc, Options{}, []*State{state, state})
+	if err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+	if result.Iterations != 6 || !result.Passed() {
+		t.Errorf("expected 6 merged iterations passing the threshold, got %d and %+v", result.Iterations, result.Thresholds)
+	}
+}
+
+func TestRun_ChecksThreshold(t *testing.T) {
+	var n atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%4 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "checks",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Thresholds:   []string{"checks_failed / requests < 0.01", "checks_passed > 0"},
+		Steps: []scenario.Step{{Request: "GET /", Checks: []scenario.Check{
+			{Name: "status is 200", Condition: "${last.status} == 200"},
+		}}},
+	}
+	a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if len(result.Thresholds) != 2 {
+		t.Fatalf("expected 2 threshold results, got %+v", result.Thresholds)
+	}
+	if r := result.Thresholds[0]; r.Passed {
+		t.Errorf("expected a quarter of failed checks to fail the threshold, got %+v", r)
+	} else if actual, _ := strconv.ParseFloat(r.Actual, 64); math.Abs(actual-0.25) > 0.05 {
+		t.Errorf("expected about a quarter of the checks to fail, got %s", r.Actual)
+	}
+	if r := result.Thresholds[1]; !r.Passed {
+		t.Errorf("expected passed checks to be counted, got %+v", r)
+	}
+	if got := result.Metrics.Total.ChecksFailed; got != result.Checks[0].Fails {
+		t.Errorf("expected %d failed checks in the run total, got %d", result.Checks[0].Fails, got)
+	}
+}
+
+func TestRun_AuthPools(t *testing.T) {
+	var mu sync.Mutex
+	logins := make(map[string]int)
+	roles := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			user := r.URL.Query().Get("user")
+			logins[user]++
+			fmt.Fprintf(w, `{"token": "%s-%d"}`, user, logins[user])
+		case "/items":
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			user, _, ok := strings.Cut(token, "-")
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			roles[user] = r.URL.Query().Get("role")
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	login := []scenario.Step{{
+		Request:       "POST /login",
+		Query:         map[string]string{"user": "${auth.user}"},
+		SaveToContext: map[string]string{"token": "response.token"},
+	}}
+
+	for _, tt := range []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"once", 0},
+		{"expiring", 10 * time.Millisecond},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(logins)
+			clear(roles)
+			sc := &scenario.Scenario{
+				Name:         "auth",
+				BaseURL:      server.URL,
+				VirtualUsers: 4,
+				Duration:     60,
+				AuthPools: []scenario.AuthPool{
+					{Name: "admin", Weight: 1, Credentials: write("admins.csv", "user\nroot\n"), Login: login,
+						TTL: scenario.Duration{Duration: tt.ttl}},
+					{Name: "reader", Weight: 3, Credentials: write("readers.csv", "user\nann\nbob\ncid\n"),
+						Login: login, TTL: scenario.Duration{Duration: tt.ttl}},
+				},
+				Steps: []scenario.Step{{
+					Request: "GET /items",
+					Headers: map[string]string{"Authorization": "Bearer ${token}"},
+					Query:   map[string]string{"role": "${auth.pool}"},
+					Delay:   scenario.Delay{Duration: scenario.Duration{Duration: time.Millisecond}},
+				}},
+			}
+			a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			result, err := a.Run(ctx)
+			if err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			want := map[string]string{"root": "admin", "ann": "reader", "bob": "reader", "cid": "reader"}
+			if !maps.Equal(roles, want) {
+				t.Errorf("expected users in roles %v, got %v", want, roles)
+			}
+			for user, n := range logins {
+				if tt.ttl == 0 && n != 1 || tt.ttl > 0 && n < 2 {
+					t.Errorf("user %s logged in %d times", user, n)
+				}
+			}
+			if result.Metrics.Total.Errors != 0 {
+				t.Errorf("expected no errors, got %d", result.Metrics.Total.Errors)
+			}
+			steps := make([]string, len(result.Metrics.Steps))
+			for i, step := range result.Metrics.Steps {
+				steps[i] = step.Name
+			}
+			if want := []string{"GET /items", "login admin: POST /login", "login reader: POST /login"}; !slices.Equal(steps, want) {
+				t.Errorf("expected steps %v, got %v", want, steps)
+			}
+		})
+	}
+}
+
+func TestRun_CheckAssertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 42, "state": "ok"}`))
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "assertions",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{{Request: "POST /items", Checks: []scenario.Check{
+			{Name: "created", Status: []string{"201", "3xx"}},
+			{Name: "not found", Status: []string{"404"}},
+			{Name: "state ok", BodyContains: `"state": "ok"`, BodyMatches: `"id": \d+`},
+			{Name: "state failed", BodyContains: `"state": "failed"`},
+			{Name: "json", Headers: map[string]string{"content-type": "application/json"}},
+			{Name: "fast", MaxLatency: scenario.Duration{Duration: time.Minute}},
+			{Name: "mixed", Status: []string{"2xx"}, Condition: "${response.id} == 41"},
+		}}},
+	}
+	a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	want := map[string]bool{"created": true, "not found": false, "state ok": true, "state failed": false,
+		"json": true, "fast": true, "mixed": false}
+	for _, c := range result.Checks {
+		if passed := c.Fails == 0 && c.Passes > 0; passed != want[c.Name] {
+			t.Errorf("check '%s' (%s): expected passing %v, got %+v", c.Name, c.Condition, want[c.Name], c)
+		}
+	}
+	if len(result.Checks) != len(want) {
+		t.Errorf("expected %d checks, got %d", len(want), len(result.Checks))
+	}
+}
+
+func TestRun_CheckSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "42", "name": "alice"}`))
+	}))
+	defer server.Close()
+
+	schema := func(props map[string]any) *scenario.Schema {
+		return &scenario.Schema{Inline: map[string]any{"type": "object", "required": []any{"id"}, "properties": props}}
+	}
+	sc := &scenario.Scenario{
+		Name:         "schema",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{{Request: "GET /users/42", Checks: []scenario.Check{
+			{Name: "valid", Schema: schema(map[string]any{"name": map[string]any{"type": "string"}})},
+			{Name: "invalid", Schema: schema(map[string]any{
+				"id":   map[string]any{"type": "integer"},
+				"name": map[string]any{"type": "string", "maxLength": 3},
+			})},
+		}}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	valid, invalid := result.Checks[0], result.Checks[1]
+	if valid.Passes == 0 || valid.Fails != 0 || len(valid.Errors) != 0 {
+		t.Errorf("expected the valid check to pass, got %+v", valid)
+	}
+	if invalid.Passes != 0 || invalid.Fails == 0 {
+		t.Errorf("expected the invalid check to fail, got %+v", invalid)
+	}
+	slices.Sort(invalid.Errors)
+	if len(invalid.Errors) != 2 || !strings.HasPrefix(invalid.Errors[0], "/id: ") ||
+		!strings.HasPrefix(invalid.Errors[1], "/name: ") {
+		t.Errorf("expected an error for /id and /name, got %q", invalid.Errors)
+	}
+}
+
+func TestRun_Classify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders":
+			w.Write([]byte(`{"ok": false, "error": "out of stock"}`))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte(`{"ok": true}`))
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "classify",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{
+			{Request: "POST /orders", Classify: []scenario.Classification{
+				{Result: scenario.ClassifyFailure, Check: scenario.Check{Condition: "${response.ok} == false"}},
+			}},
+			{Request: "GET /missing", Classify: []scenario.Classification{
+				{Result: scenario.ClassifySuccess, Check: scenario.Check{Status: []string{"404"}}},
+			}},
+			{Request: "GET /status", Classify: []scenario.Classification{
+				{Result: scenario.ClassifyFailure, Check: scenario.Check{BodyContains: `"ok": false`}},
+			}},
+		},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	for _, step := range result.Metrics.Steps {
+		wantErrors := step.Name == "POST /orders"
+		if step.Requests == 0 || (step.Errors == step.Requests) != wantErrors || (step.Errors == 0) == wantErrors {
+			t.Errorf("step '%s': expected errors %v, got %d errors of %d requests",
+				step.Name, wantErrors, step.Errors, step.Requests)
+		}
+	}
+}
+
+func TestRun_Idempotency(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		orders = make(map[string]int)
+		next   int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := r.Header.Get("Idempotency-Key")
+		id, ok := orders[key]
+		// /broken creates a new order for every duplicate
+		if !ok || r.URL.Path == "/broken" {
+			next++
+			id = next
+			orders[key] = id
+		}
+		fmt.Fprintf(w, `{"id": %d, "created_at": %q}`, id, time.Now().Format(time.RFC3339Nano))
+	}))
+	defer server.Close()
+
+	headers := map[string]string{"Idempotency-Key": "${uuid()}"}
+	sc := &scenario.Scenario{
+		Name:         "idempotency",
+		BaseURL:      server.URL,
+		VirtualUsers: 2,
+		Duration:     60,
+		Steps: []scenario.Step{
+			{Request: "POST /orders", Headers: headers, Idempotency: &scenario.Idempotency{
+				Replays: 3, Concurrent: true, Compare: scenario.CompareJSON, Ignore: []string{"created_at"}}},
+			{Request: "POST /broken", Headers: headers, Idempotency: &scenario.Idempotency{
+				Replays: 2, Compare: scenario.CompareJSON, Ignore: []string{"created_at"}}},
+		},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if len(result.Idempotency) != 2 {
+		t.Fatalf("expected an idempotency result per step, got %+v", result.Idempotency)
+	}
+	created, broken := result.Idempotency[0], result.Idempotency[1]
+	if created.Replays == 0 || created.Violations != 0 {
+		t.Errorf("expected POST /orders to be idempotent, got %+v", created)
+	}
+	if broken.Replays == 0 || broken.Violations != broken.Replays || !slices.Equal(broken.Errors, []string{"id differs"}) {
+		t.Errorf("expected every POST /broken replay to differ in id, got %+v", broken)
+	}
+
+	var replays int64
+	for _, s := range result.Metrics.Steps {
+		if s.Name == scenario.ReplayStep("POST /orders") || s.Name == scenario.ReplayStep("POST /broken") {
+			replays += s.Requests
+		}
+	}
+	if replays < created.Replays+broken.Replays {
+		t.Errorf("expected the replays in the metrics, got %d requests for %d replays", replays, created.Replays+broken.Replays)
+	}
+}
+
+func TestJSONComparer(t *testing.T) {
+	for _, tt := range []struct {
+		ignore           []string
+		original, replay string
+		want             string
+	}{
+		{nil, `{"id": 1}`, `{"id": 1}`, ""},
+		{nil, `{"id": 1, "at": "a"}`, `{"at": "b", "id": 1}`, "at differs"},
+		{[]string{"at"}, `{"id": 1, "at": "a"}`, `{"at": "b", "id": 1}`, ""},
+		{[]string{"items.*.at"}, `{"items": [{"id": 1, "at": "a"}]}`, `{"items": [{"id": 1, "at": "b"}]}`, ""},
+		{[]string{"items.*.at"}, `{"items": [{"id": 1}]}`, `{"items": [{"id": 2}]}`, "items.0.id differs"},
+		{nil, `{"items": [1]}`, `{"items": [1, 2]}`, "items differs"},
+		{nil, `{"id": 1}`, `{"id": 1, "extra": true}`, "extra differs"},
+		{nil, `[1]`, `{"id": 1}`, "body differs"},
+		{nil, `ok`, `ok`, ""},
+		{nil, `ok`, `ko`, "body differs"},
+	} {
+		c := newJSONComparer(tt.ignore)
+		original := &executor.Response{StatusCode: 200, Body: []byte(tt.original)}
+		replay := &executor.Response{StatusCode: 200, Body: []byte(tt.replay)}
+		if got := c.compare(original, replay); got != tt.want {
+			t.Errorf("%s vs %s ignoring %v: expected %q, got %q", tt.original, tt.replay, tt.ignore, tt.want, got)
+		}
+	}
+
+	c := newJSONComparer(nil)
+	if got := c.compare(&executor.Response{StatusCode: 201}, &executor.Response{StatusCode: 409}); got != "status 409, original 201" {
+		t.Errorf("expected a status difference, got %q", got)
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	const api = `openapi: 3.0.3
+info: {title: Users, version: 1.0.0}
+paths:
+  /users:
+    post:
+      responses:
+        '201': {description: Created}
+  /users/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+      responses:
+        '200': {description: User}
+`
+	spec := filepath.Join(t.TempDir(), "api.yaml")
+	if err := os.WriteFile(spec, []byte(api), 0o644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+	sc := &scenario.Scenario{
+		OpenAPI: &scenario.OpenAPI{Spec: spec},
+		Steps: []scenario.Step{
+			{Request: "POST /users"},
+			{Request: "GET /users/${user_id}"},
+			{Request: "GET /health"},
+		},
+	}
+
+	pinned, err := PinEndpoints(sc)
+	if err != nil {
+		t.Fatalf("PinEndpoints() failed: %v", err)
+	}
+	if len(pinned) != 2 || pinned["POST /users"] == "" || pinned["GET /users/{id}"] == "" {
+		t.Fatalf("expected the two documented operations pinned, got %v", pinned)
+	}
+	sc.OpenAPI.Pinned = pinned
+	if warnings, err := CheckDrift(sc); err != nil || len(warnings) != 0 {
+		t.Fatalf("expected no drift, got %v, %v", warnings, err)
+	}
+
+	changed := strings.Replace(api, "type: integer", "type: string", 1)
+	changed = strings.Replace(changed, "  /users:\n    post:", "  /accounts:\n    post:", 1)
+	if err := os.WriteFile(spec, []byte(changed), 0o644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+	warnings, err := CheckDrift(sc)
+	if err != nil {
+		t.Fatalf("CheckDrift() failed: %v", err)
+	}
+	if len(warnings) != 2 || !strings.HasPrefix(warnings[0], "GET /users/{id} changed") ||
+		warnings[1] != "POST /users is no longer in the document" {
+		t.Errorf("expected a changed and a removed operation, got %q", warnings)
+	}
+}
+
+func TestCheckParameters(t *testing.T) {
+	const api = `openapi: 3.0.3
+info: {title: Users, version: 1.0.0}
+paths:
+  /users:
+    get:
+      parameters:
+        - {name: page, in: query, required: true, schema: {type: integer}}
+        - {name: X-Tenant, in: header, required: true, schema: {type: string}}
+        - {name: sort, in: query, schema: {type: string}}
+      responses:
+        '200': {description: Users}
+`
+	spec := filepath.Join(t.TempDir(), "api.yaml")
+	if err := os.WriteFile(spec, []byte(api), 0o644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+	sc := &scenario.Scenario{
+		OpenAPI: &scenario.OpenAPI{Spec: spec},
+		Steps: []scenario.Step{
+			{Name: "complete", Request: "GET /users?page=1", Headers: map[string]string{"x-tenant": "acme"}},
+			{Name: "bare", Request: "GET /users"},
+			{Request: "GET /health"},
+		},
+	}
+
+	warnings, err := CheckParameters(sc)
+	if err != nil {
+		t.Fatalf("CheckParameters() failed: %v", err)
+	}
+	want := []string{
+		"step 'bare': GET /users requires the query parameter page",
+		"step 'bare': GET /users requires the header parameter X-Tenant",
+	}
+	if !slices.Equal(warnings, want) {
+		t.Errorf("expected %q, got %q", want, warnings)
+	}
+}
+
+func TestRun_Contract(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users/42":
+			w.Write([]byte(`{"id": "42"}`))
+		case "/users":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	spec := filepath.Join(t.TempDir(), "api.yaml")
+	err := os.WriteFile(spec, []byte(`openapi: 3.0.3
+info: {title: Users, version: 1.0.0}
+paths:
+  /users:
+    post:
+      responses:
+        '201': {description: Created}
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer}}
+    get:
+      responses:
+        '200':
+          description: User
+          content:
+            application/json:
+              schema: {type: object, properties: {id: {type: integer}}}
+`), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	sc := &scenario.Scenario{
+		Name:         "contract",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		OpenAPI:      &scenario.OpenAPI{Spec: spec, ValidateResponses: true},
+		Steps: []scenario.Step{
+			{Request: "POST /users"},
+			{Request: "GET /users/{id}", PathParams: map[string]string{"id": "42"}},
+			{Request: "GET /health"},
+		},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if len(result.Contract) != 3 {
+		t.Fatalf("expected a contract result per step, got %+v", result.Contract)
+	}
+	created, user, health := result.Contract[0], result.Contract[1], result.Contract[2]
+	if created.Operation != "POST /users" || created.Responses == 0 || created.Violations != 0 {
+		t.Errorf("expected POST /users to conform, got %+v", created)
+	}
+	if user.Operation != "GET /users/{id}" || user.Violations != user.Responses ||
+		!slices.Equal(user.Errors, []string{"status 200: /id: value must be an integer"}) {
+		t.Errorf("expected every GET /users/{id} response to violate the schema, got %+v", user)
+	}
+	if health.Operation != "" || health.Violations == 0 ||
+		!slices.Equal(health.Errors, []string{"GET /health is not in the document"}) {
+		t.Errorf("expected GET /health to be undocumented, got %+v", health)
+	}
+}
+
+func TestRun_ReadOnly(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	credentials := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(credentials, []byte("user\nann\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sc := &scenario.Scenario{
+		Name:         "read-only",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Setup:        []scenario.Step{{Request: "DELETE /fixtures"}},
+		AuthPools: []scenario.AuthPool{{
+			Name:        "users",
+			Weight:      1,
+			Credentials: credentials,
+			Login: []scenario.Step{
+				{Request: "POST /login", DryRun: "POST /login"},
+				{Request: "PUT /sessions/current"},
+			},
+		}},
+		Steps: []scenario.Step{
+			{Request: "POST /orders", Body: map[string]any{"item": "book"}},
+			{Request: "PUT /orders/1", DryRun: "POST /orders/1/validate"},
+			{Request: "GET /orders"},
+		},
+	}
+	var log bytes.Buffer
+	a, err := New(sc, Options{ReadOnly: true, ReadOnlyLog: &log})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["POST /orders"] != 0 || hits["PUT /orders/1"] != 0 || hits["DELETE /fixtures"] != 0 ||
+		hits["PUT /sessions/current"] != 0 {
+		t.Errorf("expected no mutating requests to be sent, got %v", hits)
+	}
+	if hits["POST /orders/1/validate"] == 0 || hits["GET /orders"] == 0 || hits["POST /login"] == 0 {
+		t.Errorf("expected the dry run and GET requests to be sent, got %v", hits)
+	}
+
+	var events []TraceEvent
+	scanner := bufio.NewScanner(&log)
+	for scanner.Scan() {
+		var ev TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) < 3 || int64(len(events)) != result.SkippedWrites {
+		t.Fatalf("expected a log line per skipped request, got %d lines and %d skipped",
+			len(events), result.SkippedWrites)
+	}
+	setup, login, order := events[0], events[1], events[2]
+	if setup.Event != TraceSkipWrite || setup.Method != "DELETE" || setup.URL != server.URL+"/fixtures" {
+		t.Errorf("expected the setup request first, got %+v", setup)
+	}
+	if login.Method != "PUT" || login.URL != server.URL+"/sessions/current" || login.VU != 1 {
+		t.Errorf("expected the PUT /sessions/current login request, got %+v", login)
+	}
+	if order.Step != "POST /orders" || order.Method != "POST" || order.VU != 1 || order.Body != `{"item":"book"}` {
+		t.Errorf("expected the POST /orders request, got %+v", order)
+	}
+}
+
+func TestRun_StepTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	timeout := scenario.Duration{Duration: 20 * time.Millisecond}
+	sc := &scenario.Scenario{
+		Name:         "timeout",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Steps: []scenario.Step{
+			{Request: "GET /fast", Timeout: timeout},
+			{Request: "GET /slow", Timeout: timeout},
+		},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	fast, slow := result.Metrics.Steps[0], result.Metrics.Steps[1]
+	if fast.Requests == 0 || fast.Errors != 0 {
+		t.Errorf("expected fast requests to succeed, got %+v", fast)
+	}
+	if slow.Errors == 0 || slow.ErrorKinds["timeout"] != slow.Errors {
+		t.Errorf("expected slow requests to time out, got %+v", slow)
+	}
+}
+
+func TestRun_When(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		hits = make(map[string]map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		vu := r.URL.Query().Get("vu")
+		if hits[vu] == nil {
+			hits[vu] = make(map[string]int)
+		}
+		hits[vu][r.URL.Path]++
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	query := map[string]string{"vu": "${vu.id}"}
+	sc := &scenario.Scenario{
+		Name:         "when",
+		BaseURL:      server.URL,
+		VirtualUsers: 2,
+		Duration:     60,
+		Steps: []scenario.Step{
+			{Request: "GET /missing", Query: query},
+			{Request: "GET /admin", Query: query, When: "${vu.id} == 1 && ${last.status} == 404"},
+			{Request: "GET /retry", Query: query, When: "${last.status} >= 500"},
+			{Request: "GET /end", Query: query},
+		},
+	}
+	a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["1"]["/admin"] == 0 || hits["2"]["/admin"] != 0 {
+		t.Errorf("expected only VU 1 to request /admin, got %v", hits)
+	}
+	if hits["1"]["/retry"] != 0 || hits["2"]["/retry"] != 0 {
+		t.Errorf("expected /retry to be skipped, got %v", hits)
+	}
+	if hits["1"]["/end"] == 0 || hits["2"]["/end"] == 0 {
+		t.Errorf("expected skipped steps to fall through to /end, got %v", hits)
+	}
+}
+
+func TestRun_Scale(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		hits = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Query().Get("vu")]++
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "scale",
+		BaseURL:      server.URL,
+		VirtualUsers: 2,
+		Duration:     60,
+		Steps:        []scenario.Step{{Request: "GET /", Query: map[string]string{"vu": "${vu.id}"}}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	// Scaling before the run changes the VUs it starts with
+	if err := a.Scale(3); err != nil {
+		t.Fatalf("Scale() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Run(ctx)
+	}()
+
+	waitActive := func(n int64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for a.ActiveVUs() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d active VUs, got %d", n, a.ActiveVUs())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitActive(3)
+	if err := a.Scale(0); err == nil {
+		t.Error("expected error scaling to 0 VUs, got nil")
+	}
+	if err := a.Scale(4); err != nil {
+		t.Fatalf("Scale() failed: %v", err)
+	}
+	waitActive(4)
+	if a.VUs() != 4 {
+		t.Errorf("expected 4 VUs, got %d", a.VUs())
+	}
+
+	if err := a.Scale(1); err != nil {
+		t.Fatalf("Scale() failed: %v", err)
+	}
+	waitActive(1)
+	mu.Lock()
+	before := maps.Clone(hits)
+	mu.Unlock()
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	for _, vu := range []string{"2", "3", "4"} {
+		if hits[vu] != before[vu] {
+			t.Errorf("expected VU %s to idle after scaling down, got %d more requests", vu, hits[vu]-before[vu])
+		}
+	}
+	if hits["1"] == before["1"] {
+		t.Error("expected VU 1 to keep running")
+	}
+	mu.Unlock()
+
+	// Idle VUs continue when scaled up again
+	if err := a.Scale(3); err != nil {
+		t.Fatalf("Scale() failed: %v", err)
+	}
+	waitActive(3)
+
+	cancel()
+	<-done
+	var scaled int
+	for _, e := range a.events.Events() {
+		if e.Kind == timeline.Scaled {
+			scaled++
+		}
+	}
+	if scaled != 4 {
+		t.Errorf("expected 4 scaled events, got %d", scaled)
+	}
+	if err := a.Scale(2); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning once the run ended, got %v", err)
+	}
+}
+
+func TestRun_StageGates(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		status      int
+		wantVUs     int
+		wantStages  int
+		wantBlocked bool
+	}{
+		{"held", http.StatusOK, 3, 2, false},
+		{"blocked", http.StatusInternalServerError, 1, 1, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			stage := scenario.Duration{Duration: 50 * time.Millisecond}
+			sc := &scenario.Scenario{
+				Name:         "stages",
+				BaseURL:      server.URL,
+				VirtualUsers: 3,
+				Duration:     60,
+				Stages: []scenario.Stage{
+					{VUs: 1, Duration: stage, Gate: &scenario.Gate{Conditions: []string{"error_rate < 1%"}}},
+					{VUs: 3, Duration: stage},
+				},
+				Steps: []scenario.Step{{Request: "GET /", Delay: scenario.Delay{Duration: scenario.Duration{Duration: time.Millisecond}}}},
+			}
+			a, err := New(sc, Options{})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			if a.VUs() != 1 {
+				t.Fatalf("expected to start with the first stage's VUs, got %d", a.VUs())
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), gateInterval+500*time.Millisecond)
+			defer cancel()
+			result, err := a.Run(ctx)
+			if err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+
+			if a.VUs() != tt.wantVUs {
+				t.Errorf("expected to end at %d VUs, got %d", tt.wantVUs, a.VUs())
+			}
+			var (
+				stages  int
+				blocked bool
+			)
+			for _, e := range result.Events {
+				switch e.Kind {
+				case timeline.StageStarted:
+					stages++
+				case timeline.GateBlocked:
+					blocked = true
+				}
+			}
+			if stages != tt.wantStages || blocked != tt.wantBlocked {
+				t.Errorf("expected %d stages started and blocked %v, got %d and %v",
+					tt.wantStages, tt.wantBlocked, stages, blocked)
+			}
+		})
+	}
+}
+
+func TestRun_Burst(t *testing.T) {
+	// The target is slow while the burst runs
+	var a *Agent
+	var ready atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ready.Load() && a.VUs() > 1 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "burst",
+		BaseURL:      server.URL,
+		VirtualUsers: 8,
+		Duration:     60,
+		Burst: &scenario.Burst{
+			BaselineVUs: 1,
+			Baseline:    scenario.Duration{Duration: 100 * time.Millisecond},
+			VUs:         8,
+			Duration:    scenario.Duration{Duration: 100 * time.Millisecond},
+			Tolerance:   100,
+			For:         scenario.Duration{Duration: time.Nanosecond},
+			MaxRecovery: scenario.Duration{Duration: 10 * time.Second},
+		},
+		Steps: []scenario.Step{{Request: "GET /", Delay: scenario.Delay{Duration: scenario.Duration{Duration: time.Millisecond}}}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if a.VUs() != 1 {
+		t.Fatalf("expected to start with the baseline VUs, got %d", a.VUs())
+	}
+	ready.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*gateInterval+500*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	b := result.Burst
+	if b == nil || !b.Recovered || b.RecoveryTime < 0 || b.RecoveryTime > gateInterval {
+		t.Fatalf("expected to recover within the first second after the burst, got %+v", b)
+	}
+	if b.PeakP95 < 20*time.Millisecond || b.BaselineP95 >= b.PeakP95 {
+		t.Errorf("expected the burst to raise p95 above the baseline, got %+v", b)
+	}
+	if a.VUs() != 1 {
+		t.Errorf("expected to end at the baseline VUs, got %d", a.VUs())
+	}
+
+	var kinds []timeline.Kind
+	for _, e := range result.Events {
+		switch e.Kind {
+		case timeline.BurstStarted, timeline.BurstEnded, timeline.Recovered:
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	if want := []timeline.Kind{timeline.BurstStarted, timeline.BurstEnded, timeline.Recovered}; !slices.Equal(kinds, want) {
+		t.Errorf("expected events %v, got %v", want, kinds)
+	}
+	if len(result.Thresholds) != 1 || !result.Thresholds[0].Passed || result.Thresholds[0].Expr != "recovery_time <= 10s" {
+		t.Errorf("expected the max recovery to pass, got %+v", result.Thresholds)
+	}
+}
+
+func TestRecoveryThreshold(t *testing.T) {
+	for _, tt := range []struct {
+		result     BurstResult
+		wantActual string
+		wantPassed bool
+	}{
+		{BurstResult{Recovered: true, RecoveryTime: 5 * time.Second}, "5s", true},
+		{BurstResult{Recovered: true, RecoveryTime: 15 * time.Second}, "15s", false},
+		{BurstResult{RecoveryTime: 2 * time.Second}, "not recovered after 2s", false},
+	} {
+		r := recoveryThreshold(10*time.Second, tt.result)
+		if r.Actual != tt.wantActual || r.Passed != tt.wantPassed {
+			t.Errorf("%+v: expected %q passed %v, got %+v", tt.result, tt.wantActual, tt.wantPassed, r)
+		}
+	}
+}
+
+func TestRun_Guards(t *testing.T) {
+	var cpu atomic.Value
+	signals := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/query":
+			fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": [{"value": [0, "%s"]}]}}`, cpu.Load())
+		case "/stats":
+			fmt.Fprintf(w, `{"queue": {"depth": %s}}`, cpu.Load())
+		}
+	}))
+	defer signals.Close()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	newAgent := func(guard scenario.Guard) *Agent {
+		guard.Name = "cpu"
+		guard.Interval = scenario.Duration{Duration: 20 * time.Millisecond}
+		sc := &scenario.Scenario{
+			Name:         "guards",
+			BaseURL:      target.URL,
+			VirtualUsers: 1,
+			Duration:     60,
+			Guards:       []scenario.Guard{guard},
+			Steps:        []scenario.Step{{Request: "GET /", Delay: scenario.Delay{Duration: scenario.Duration{Duration: time.Millisecond}}}},
+		}
+		a, err := New(sc, Options{})
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		return a
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		cpu.Store("0.95")
+		a := newAgent(scenario.Guard{Prometheus: signals.URL, Query: "avg(cpu)", Condition: "> 0.9", Action: scenario.GuardStop})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result, err := a.Run(ctx)
+		if err != nil {
+			t.Fatalf("Run() failed: %v", err)
+		}
+		if result.Aborted != "guard 'cpu' tripped: 0.95 > 0.9" || result.Duration > time.Second {
+			t.Errorf("expected the guard to end the run early, got %q after %s", result.Aborted, result.Duration)
+		}
+		if result.ExitCode() != ExitAborted {
+			t.Errorf("expected exit code %d, got %d", ExitAborted, result.ExitCode())
+		}
+	})
+
+	t.Run("hold", func(t *testing.T) {
+		cpu.Store("5000")
+		a := newAgent(scenario.Guard{URL: signals.URL + "/stats", Field: "queue.depth", Condition: ">= 1000", Action: scenario.GuardHold})
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		go func() {
+			time.Sleep(150 * time.Millisecond)
+			cpu.Store("10")
+		}()
+		result, err := a.Run(ctx)
+		if err != nil {
+			t.Fatalf("Run() failed: %v", err)
+		}
+
+		var kinds []timeline.Kind
+		for _, e := range result.Events {
+			switch e.Kind {
+			case timeline.GuardTripped, timeline.GuardCleared, timeline.Paused, timeline.Resumed:
+				kinds = append(kinds, e.Kind)
+			}
+		}
+		want := []timeline.Kind{timeline.GuardTripped, timeline.Paused, timeline.GuardCleared, timeline.Resumed}
+		if !slices.Equal(kinds, want) {
+			t.Errorf("expected events %v, got %v", want, kinds)
+		}
+		if a.Paused() {
+			t.Error("expected the run to resume once the guard cleared")
+		}
+	})
+}
+
+func TestPause_HoldersAreIndependent(t *testing.T) {
+	a, err := New(newTestScenario("http://localhost"), Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	guard := &scenario.Guard{Name: "cpu", Condition: "> 0.9", Action: scenario.GuardHold}
+
+	// A guard clearing does not resume a run the operator paused
+	a.Pause()
+	if _, err := a.checkGuard(guard, 0.95, false); err != nil {
+		t.Fatalf("checkGuard() failed: %v", err)
+	}
+	if _, err := a.checkGuard(guard, 0.5, true); err != nil {
+		t.Fatalf("checkGuard() failed: %v", err)
+	}
+	if !a.Paused() {
+		t.Error("expected the run to stay paused by the operator after the guard cleared")
+	}
+	a.Resume()
+	if a.Paused() {
+		t.Error("expected the run to resume")
+	}
+
+	// The operator resuming does not resume a run a tripped guard holds
+	a.Pause()
+	if _, err := a.checkGuard(guard, 0.95, false); err != nil {
+		t.Fatalf("checkGuard() failed: %v", err)
+	}
+	if !a.Resume() {
+		t.Error("expected Resume() to release the operator's pause")
+	}
+	if !a.Paused() {
+		t.Error("expected the run to stay paused while the guard is tripped")
+	}
+	if _, err := a.checkGuard(guard, 0.5, true); err != nil {
+		t.Fatalf("checkGuard() failed: %v", err)
+	}
+	if a.Paused() {
+		t.Error("expected the run to resume once the guard cleared")
+	}
+}
+
+func TestPrometheusValue(t *testing.T) {
+	for _, tt := range []struct {
+		body    string
+		want    float64
+		wantErr bool
+	}{
+		{`{"status": "success", "data": {"resultType": "scalar", "result": [1700000000, "42.5"]}}`, 42.5, false},
+		{`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1700000000, "7"]}]}}`, 7, false},
+		{`{"status": "success", "data": {"resultType": "vector", "result": []}}`, 0, true},
+		{`{"status": "success", "data": {"resultType": "matrix", "result": []}}`, 0, true},
+		{`{"status": "error", "error": "parse error"}`, 0, true},
+		{`{"status": "success", "data": {"resultType": "scalar", "result": [1700000000, "NaN?"]}}`, 0, true},
+	} {
+		got, err := prometheusValue([]byte(tt.body))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: expected %v (error %v), got %v, %v", tt.body, tt.want, tt.wantErr, got, err)
+		}
+	}
+}
+
+func TestRun_DrainsInFlightRequests(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		drain time.Duration
+		want  int64
+	}{
+		{name: "drain", drain: 5 * time.Second, want: 1},
+		{name: "abandon", drain: 0, want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			arrived := make(chan struct{}, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case arrived <- struct{}{}:
+				default:
+				}
+				time.Sleep(200 * time.Millisecond)
+			}))
+			defer server.Close()
+
+			sc := &scenario.Scenario{
+				Name:         "drain",
+				BaseURL:      server.URL,
+				VirtualUsers: 1,
+				Duration:     60,
+				Steps:        []scenario.Step{{Request: "GET /slow"}},
+			}
+			a, err := New(sc, Options{DrainTimeout: tc.drain})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-arrived
+				cancel()
+			}()
+			result, err := a.Run(ctx)
+			if err != nil {
+				t.Fatalf("Run() failed: %v", err)
+			}
+
+			total := result.Metrics.Total
+			if total.Requests != tc.want || total.Errors != 0 {
+				t.Errorf("expected %d requests without errors, got %d requests and %d errors",
+					tc.want, total.Requests, total.Errors)
+			}
+
+			var kinds []timeline.Kind
+			for _, e := range result.Events {
+				kinds = append(kinds, e.Kind)
+			}
+			want := []timeline.Kind{timeline.RunStarted, timeline.Aborted, timeline.RunFinished}
+			if !slices.Equal(kinds, want) {
+				t.Errorf("expected events %v, got %v", want, kinds)
+			}
+		})
+	}
+}
+
+func TestRun_Identity(t *testing.T) {
+	run := func(seed uint64, firstVU int) map[string]map[string]int {
+		var mu sync.Mutex
+		seen := make(map[string]map[string]int)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[q.Get("id")] == nil {
+				seen[q.Get("id")] = make(map[string]int)
+			}
+			seen[q.Get("id")][q.Get("uuid")+" "+q.Get("email")+" "+q.Get("device")]++
+		}))
+		defer server.Close()
+
+		sc := &scenario.Scenario{
+			Name:         "identity",
+			BaseURL:      server.URL,
+			VirtualUsers: 2,
+			Duration:     60,
+			Seed:         seed,
+			Steps: []scenario.Step{{Request: "GET /cart", Query: map[string]string{
+				"id":     "${vu.id}",
+				"uuid":   "${vu.uuid}",
+				"email":  "${vu.email}",
+				"device": "${vu.device_id}",
+			}}},
+		}
+		a, err := New(sc, Options{FirstVU: firstVU})
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		if a.Seed() != seed {
+			t.Errorf("expected seed %d, got %d", seed, a.Seed())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := a.Run(ctx); err != nil {
+			t.Fatalf("Run() failed: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+
+	first := run(42, 0)
+	if len(first) != 2 || first["1"] == nil || first["2"] == nil {
+		t.Fatalf("expected VUs 1 and 2, got %v", first)
+	}
+	for id, identities := range first {
+		if len(identities) != 1 {
+			t.Errorf("expected VU %s to keep its identity across iterations, got %v", id, identities)
+		}
+	}
+	if maps.Equal(first["1"], first["2"]) {
+		t.Errorf("expected distinct identities, got %v", first)
+	}
+	for identity := range first["1"] {
+		if !strings.Contains(identity, "@example.com") {
+			t.Errorf("expected an example.com email, got %q", identity)
+		}
+	}
+
+	again := run(42, 0)
+	for id := range first {
+		for identity := range first[id] {
+			if again[id][identity] == 0 {
+				t.Errorf("expected VU %s to get the same identity with the same seed, got %v", id, again[id])
+			}
+		}
+	}
+
+	if other := run(42, 2); other["3"] == nil || other["4"] == nil {
+		t.Errorf("expected VUs numbered after FirstVU, got %v", other)
+	}
+}
+
+func TestRun_SetupTeardown(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "42"}`))
+		}
+		if len(requests) == 0 || requests[len(requests)-1] != r.Method+" "+r.URL.Path {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "lifecycle",
+		BaseURL:      server.URL,
+		VirtualUsers: 2,
+		Duration:     60,
+		Setup: []scenario.Step{{
+			Request:       "POST /items",
+			SaveToContext: map[string]string{"item": "response.id"},
+		}},
+		Teardown: []scenario.Step{{Request: "DELETE /items/${item}"}},
+		Steps:    []scenario.Step{{Request: "GET /items/${item}"}},
+	}
+	// Draining lets the VUs' last requests complete before teardown
+	a, err := New(sc, Options{DrainTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"POST /items", "GET /items/42", "DELETE /items/42"}
+	if !slices.Equal(requests, want) {
+		t.Errorf("expected setup once, then the VUs, then teardown once, got %v", requests)
+	}
+	if len(result.Metrics.Steps) != 1 || result.Metrics.Steps[0].Requests != result.Metrics.Total.Requests {
+		t.Errorf("expected setup and teardown requests to be left out of the metrics, got %+v", result.Metrics.Steps)
+	}
+
+	var kinds []timeline.Kind
+	for _, e := range result.Events {
+		kinds = append(kinds, e.Kind)
+	}
+	if !slices.Contains(kinds, timeline.SetupFinished) || !slices.Contains(kinds, timeline.TeardownFinished) {
+		t.Errorf("expected setup and teardown events, got %v", kinds)
+	}
+}
+
+func TestRun_SetupFails(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/seed" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "lifecycle",
+		BaseURL:      server.URL,
+		VirtualUsers: 1,
+		Duration:     60,
+		Setup:        []scenario.Step{{Request: "POST /seed"}},
+		Steps:        []scenario.Step{{Request: "GET /"}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := a.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "setup step 'POST /seed': HTTP 500") {
+		t.Errorf("expected the setup failure, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected the run not to start, got %d requests", n)
+	}
+}
+
+func TestRun_Aborted(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	s := newTestScenario(server.URL)
+	s.Duration = 1
+
+	a, err := New(s, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	result, err := a.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if result.Aborted != "" || result.ExitCode() != ExitOK {
+		t.Errorf("expected a completed run, got aborted %q and exit code %d", result.Aborted, result.ExitCode())
+	}
+
+	a, err = New(newTestScenario(server.URL), Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(50*time.Millisecond, func() { cancel(errors.New("interrupted")) })
+	result, err = a.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if result.Aborted != "interrupted" {
+		t.Errorf("expected run aborted with 'interrupted', got %q", result.Aborted)
+	}
+	if result.ExitCode() != ExitAborted {
+		t.Errorf("expected exit code %d, got %d", ExitAborted, result.ExitCode())
+	}
+}
+
+func TestResult_ExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		want   int
+	}{
+		{"passed", Result{Thresholds: []threshold.Result{{Passed: true}}}, ExitOK},
+		{"failed", Result{Thresholds: []threshold.Result{{Passed: false}}}, ExitThresholdsFailed},
+		{"aborted", Result{Aborted: "interrupted", Thresholds: []threshold.Result{{Passed: false}}}, ExitAborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"loadforge-agent/internal/feeder"
+	"loadforge-agent/internal/scenario"
+)
+
+// authPool is a scenario auth pool with its credentials read
+type authPool struct {
+	*scenario.AuthPool
+	credentials []feeder.Record
+	vus         uint64
+}
+
+// newAuthPools reads the credentials of the scenario's auth pools and
+// splits the VUs between them. In a distributed run each agent only uses
+// its own partition of every credentials file, so workers never share a
+// row.
+func newAuthPools(sc *scenario.Scenario, opts Options) ([]authPool, error) {
+	if len(sc.AuthPools) == 0 {
+		return nil, nil
+	}
+
+	weights := make([]float64, len(sc.AuthPools))
+	for i := range sc.AuthPools {
+		weights[i] = sc.AuthPools[i].Weight
+	}
+	shares := splitByWeight(sc.VirtualUsers, weights)
+
+	pools := make([]authPool, len(sc.AuthPools))
+	for i := range sc.AuthPools {
+		def := &sc.AuthPools[i]
+		credentials, err := readCredentials(cmp.Or(def.Path, def.Credentials), opts)
+		if err != nil {
+			return nil, fmt.Errorf("auth pool '%s': %w", def.Name, err)
+		}
+		pools[i] = authPool{AuthPool: def, credentials: credentials, vus: shares[i]}
+	}
+	return pools, nil
+}
+
+// readCredentials reads the rows of a credentials file, or of the agent's
+// partition of it
+func readCredentials(path string, opts Options) ([]feeder.Record, error) {
+	file, err := feeder.Open(path, feeder.Options{Format: feeder.FormatCSV})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credentials file: %w", err)
+	}
+	defer file.Close()
+
+	var rows records = file
+	if opts.Workers > 1 {
+		if rows, err = file.Partition(opts.Worker, opts.Workers); err != nil {
+			return nil, err
+		}
+	}
+	if rows.Len() == 0 {
+		return nil, fmt.Errorf("credentials file %s has no rows", path)
+	}
+
+	credentials := make([]feeder.Record, rows.Len())
+	for i := range credentials {
+		if credentials[i], err = rows.Record(i); err != nil {
+			return nil, err
+		}
+	}
+	return credentials, nil
+}
+
+// poolOf returns the auth pool of the 1-based VU index i and the
+// credentials it logs in with, or nil without auth pools. Like flows, VUs
+// are assigned to pools in order, and VUs added by Scale repeat the split
+// while taking the pool's next rows.
+func (a *Agent) poolOf(i int) (*authPool, map[string]string) {
+	if len(a.pools) == 0 {
+		return nil, nil
+	}
+
+	round := (uint64(i) - 1) / a.scenario.VirtualUsers
+	n := (uint64(i)-1)%a.scenario.VirtualUsers + 1
+	pool := &a.pools[len(a.pools)-1]
+	for j := range a.pools {
+		if n <= a.pools[j].vus {
+			pool = &a.pools[j]
+			break
+		}
+		n -= a.pools[j].vus
+	}
+
+	row := pool.credentials[(round*pool.vus+n-1)%uint64(len(pool.credentials))]
+	credentials := make(map[string]string, len(row)+1)
+	for column, value := range row {
+		credentials[scenario.AuthPrefix+column] = value
+	}
+	credentials[scenario.AuthPoolVar] = pool.Name
+	return pool, credentials
+}
+
+// login adds the VU's credentials and session to its variables, first
+// running the login steps of its pool when it has no session yet or the
+// session expired. A failed login step ends the iteration and the next
+// iteration logs in again.
+func (vu *virtualUser) login(ctx, requests context.Context) bool {
+	pool := vu.pool
+	if pool == nil {
+		return true
+	}
+	maps.Copy(vu.vars, vu.credentials)
+	if vu.session != nil && (pool.TTL.IsZero() || time.Since(vu.loggedIn) < pool.TTL.Duration) {
+		maps.Copy(vu.vars, vu.session)
+		return true
+	}
+
+	vu.session = nil
+	started := time.Now()
+	session := make(map[string]string)
+	for i := range pool.Login {
+		def := &pool.Login[i]
+		name := scenario.LoginStep(pool.Name, def.ID())
+		if def.When != "" {
+			// Validated with the scenario
+			cond, _ := scenario.ParseCondition(def.When)
+			run, err := cond.Eval(vu.vars)
+			if err != nil {
+				vu.fail(name, err)
+				return false
+			}
+			if !run {
+				continue
+			}
+		}
+		for j := range def.Repetitions() {
+			if def.Repeat > 0 {
+				vu.vars[scenario.RepeatIndex] = strconv.Itoa(j)
+			}
+			if vu.throttle(ctx) != nil {
+				return false
+			}
+			step := *def
+			if !vu.agent.readOnly.allows(def, &step) {
+				vu.skipWrite(def, step)
+				continue
+			}
+			ex, err := vu.execute(requests, name, step, 0)
+			if err != nil || ex.response.StatusCode >= 400 {
+				return false
+			}
+			vu.vars[scenario.LastStatus] = strconv.Itoa(ex.response.StatusCode)
+			for variable, ref := range def.SaveToContext {
+				value, err := lookup(ex, vu.vars, ref)
+				if err != nil {
+					vu.fail(name, fmt.Errorf("save_to_context %s: %w", variable, err))
+					return false
+				}
+				vu.vars[variable] = value
+				session[variable] = value
+			}
+			if !def.Delay.IsZero() && !sleep(ctx, def.Delay.Sample(vu.rng)) {
+				return false
+			}
+		}
+		delete(vu.vars, scenario.RepeatIndex)
+	}
+	vu.session, vu.loggedIn = session, started
+	return true
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"math/rand/v2"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/scenario"
+)
+
+// pickBaseURL picks one of urls with rng in proportion to its weight
+func pickBaseURL(urls []scenario.BaseURL, rng *rand.Rand) *scenario.BaseURL {
+	var total float64
+	for _, u := range urls {
+		total += u.Weight
+	}
+	r := rng.Float64() * total
+	for i := range urls {
+		if r < urls[i].Weight {
+			return &urls[i]
+		}
+		r -= urls[i].Weight
+	}
+	return &urls[len(urls)-1]
+}
+
+// chooseBaseURL sets the base URL of the VU's next iteration, picked by
+// weight when the scenario has several
+func (vu *virtualUser) chooseBaseURL() {
+	sc := vu.agent.scenario
+	if len(sc.BaseURLs) == 0 {
+		vu.baseURL = sc.BaseURL
+		return
+	}
+	b := pickBaseURL(sc.BaseURLs, vu.rng)
+	vu.baseURL, vu.baseName = b.URL, b.Name
+}
+
+// labels returns the metrics labels of resp: the values of the scenario's
+// label headers and the name of the base URL it came from
+func (vu *virtualUser) labels(resp *executor.Response) map[string]string {
+	labels := responseLabels(vu.agent.scenario.LabelHeaders, resp)
+	if vu.baseName == "" {
+		return labels
+	}
+	if labels == nil {
+		labels = make(map[string]string, 1)
+	}
+	labels[scenario.BaseURLLabel] = vu.baseName
+	return labels
+}
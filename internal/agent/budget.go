@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+)
+
+// BudgetResult reports how often a step group exceeded its time budget
+type BudgetResult struct {
+	Name  string
+	Steps []string
+	Max   time.Duration
+	// Iterations is the number of iterations in which every step of the
+	// group ran or a request was cancelled over budget, the only ones a
+	// budget can be judged on
+	Iterations    int64
+	Violations    int64
+	ViolationRate float64
+	// Cancelled counts the violations that ended with a request cancelled
+	// over budget, with Budget.Cancel
+	Cancelled int64
+	// Duration is the distribution of the group's combined duration
+	Duration metrics.LatencyStats
+}
+
+type budgetTracker struct {
+	name  string
+	steps []string
+	max   time.Duration
+	// cancel aborts requests once max is spent
+	cancel bool
+
+	iterations atomic.Int64
+	violations atomic.Int64
+	cancelled  atomic.Int64
+	durations  metrics.LatencyRecorder
+}
+
+func newBudgetTrackers(sc *scenario.Scenario, opts Options) ([]*budgetTracker, error) {
+	trackers := make([]*budgetTracker, 0, len(sc.Budgets))
+	for _, b := range sc.Budgets {
+		durations, err := metrics.NewLatencyRecorder(opts.Estimator, opts.Compression)
+		if err != nil {
+			return nil, err
+		}
+
+		steps := make([]string, len(b.Steps))
+		for i, ref := range b.Steps {
+			steps[i] = sc.FindStep(ref).ID()
+		}
+
+		trackers = append(trackers, &budgetTracker{
+			name:      b.Name,
+			steps:     steps,
+			max:       b.Max.Duration,
+			cancel:    b.Cancel,
+			durations: durations,
+		})
+	}
+	return trackers, nil
+}
+
+// observe judges one iteration given the time spent in each step. Steps
+// that ran more than once count with their combined time. An iteration
+// cancelled over this budget is a violation even though some of the
+// group's steps did not run.
+func (b *budgetTracker) observe(stepTime map[string]time.Duration, cancelled bool) {
+	var total time.Duration
+	for _, step := range b.steps {
+		d, ok := stepTime[step]
+		if !ok && !cancelled {
+			return
+		}
+		total += d
+	}
+
+	b.iterations.Add(1)
+	b.durations.Record(total)
+	if cancelled {
+		b.cancelled.Add(1)
+	}
+	if total > b.max || cancelled {
+		b.violations.Add(1)
+	}
+}
+
+// remaining returns how much of the budget is left in an iteration given
+// the time spent in each step so far, and whether step belongs to the group
+func (b *budgetTracker) remaining(step string, stepTime map[string]time.Duration) (time.Duration, bool) {
+	if !slices.Contains(b.steps, step) {
+		return 0, false
+	}
+	left := b.max
+	for _, s := range b.steps {
+		left -= stepTime[s]
+	}
+	return left, true
+}
+
+func (b *budgetTracker) result() BudgetResult {
+	r := BudgetResult{
+		Name:       b.name,
+		Steps:      b.steps,
+		Max:        b.max,
+		Iterations: b.iterations.Load(),
+		Violations: b.violations.Load(),
+		Cancelled:  b.cancelled.Load(),
+		Duration:   metrics.NewLatencyStats(b.durations),
+	}
+	if r.Iterations > 0 {
+		r.ViolationRate = float64(r.Violations) / float64(r.Iterations)
+	}
+	return r
+}
+
+// BudgetState is the serializable content of a budget tracker
+type BudgetState struct {
+	Name       string `json:"name"`
+	Iterations int64  `json:"iterations"`
+	Violations int64  `json:"violations"`
+	Cancelled  int64  `json:"cancelled,omitempty"`
+	// Durations is the duration recorder encoded with metrics.EncodeRecorder
+	Durations []byte `json:"durations"`
+}
+
+func (b *budgetTracker) state() (BudgetState, error) {
+	durations, err := metrics.EncodeRecorder(b.durations)
+	if err != nil {
+		return BudgetState{}, err
+	}
+	return BudgetState{
+		Name:       b.name,
+		Iterations: b.iterations.Load(),
+		Violations: b.violations.Load(),
+		Cancelled:  b.cancelled.Load(),
+		Durations:  durations,
+	}, nil
+}
+
+func (b *budgetTracker) merge(state BudgetState) error {
+	if err := metrics.MergeEncoded(b.durations, state.Durations); err != nil {
+		return err
+	}
+	b.iterations.Add(state.Iterations)
+	b.violations.Add(state.Violations)
+	b.cancelled.Add(state.Cancelled)
+	return nil
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/timeline"
+)
+
+// BurstResult is how the target coped with the burst of a burst run
+type BurstResult struct {
+	BaselineP95 time.Duration
+	// PeakP95 is the highest p95 latency of a second from the start of
+	// the burst on
+	PeakP95 time.Duration
+	// Recovered reports whether the p95 latency returned within the
+	// tolerance of the baseline before the run ended
+	Recovered bool
+	// RecoveryTime is the time from the end of the burst until latency
+	// recovered; when it did not, until the run ended
+	RecoveryTime time.Duration
+}
+
+// burstTracker drives the VU count of a burst run and measures the
+// recovery from the burst
+type burstTracker struct {
+	spec scenario.Burst
+	// window collects the samples of every second, it is the agent's
+	// gateWindow
+	window *metrics.Window
+
+	mu     sync.Mutex
+	result BurstResult
+	// ended is when the burst ended, zero before
+	ended time.Time
+}
+
+// newBurst returns the tracker of the scenario's burst, nil without one
+func newBurst(sc *scenario.Scenario, opts Options) (*burstTracker, error) {
+	if sc.Burst == nil {
+		return nil, nil
+	}
+	window, err := metrics.NewWindow(opts.Estimator, opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	return &burstTracker{spec: *sc.Burst, window: window}, nil
+}
+
+// runBurst measures the baseline, runs the burst and waits for the target
+// to recover from it, or for ctx to end
+func (a *Agent) runBurst(ctx context.Context) {
+	b := a.burst
+	b.window.Flush(0)
+	if !sleep(ctx, b.spec.Baseline.Duration) {
+		return
+	}
+	baseline := b.window.Flush(b.spec.Baseline.Duration).Total.Latency.P95
+	b.mu.Lock()
+	b.result.BaselineP95 = baseline
+	b.mu.Unlock()
+
+	if a.Scale(int(b.spec.VUs)) != nil {
+		return
+	}
+	a.events.Add(timeline.BurstStarted, fmt.Sprintf("burst: %d VUs for %s, baseline p95 %s",
+		b.spec.VUs, b.spec.Duration, baseline), map[string]string{
+		"vus":          strconv.FormatUint(b.spec.VUs, 10),
+		"baseline_p95": baseline.String(),
+	})
+	if !b.watch(ctx, b.spec.Duration.Duration, nil) {
+		return
+	}
+
+	if a.Scale(int(b.spec.BaselineVUs)) != nil {
+		return
+	}
+	ended := time.Now()
+	b.mu.Lock()
+	b.ended = ended
+	b.mu.Unlock()
+	a.events.Add(timeline.BurstEnded, fmt.Sprintf("burst ended, back to %d VUs", b.spec.BaselineVUs), nil)
+
+	limit := time.Duration(float64(baseline) * (1 + b.spec.Tolerance/100))
+	var since time.Time
+	recovered := func(start time.Time, stats metrics.StepStats) bool {
+		if stats.Requests == 0 || stats.Latency.P95 > limit {
+			since = time.Time{}
+			return false
+		}
+		if since.IsZero() {
+			since = start
+		}
+		return start.Add(gateInterval).Sub(since) >= b.spec.For.Duration
+	}
+	if !b.watch(ctx, 0, recovered) {
+		return
+	}
+
+	b.mu.Lock()
+	b.result.Recovered = true
+	b.result.RecoveryTime = since.Sub(ended)
+	b.mu.Unlock()
+	a.events.Add(timeline.Recovered, fmt.Sprintf("recovered %s after the burst, p95 within %s of the baseline",
+		since.Sub(ended).Round(time.Millisecond), limit), map[string]string{
+		"recovery_time": since.Sub(ended).String(),
+	})
+}
+
+// watch records the peak p95 latency of every second for d, if set, or
+// until done reports true for the second starting at start. It reports
+// false if ctx ended first.
+func (b *burstTracker) watch(ctx context.Context, d time.Duration, done func(start time.Time, stats metrics.StepStats) bool) bool {
+	ticker := time.NewTicker(gateInterval)
+	defer ticker.Stop()
+	var deadline <-chan time.Time
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	last := time.Now()
+	b.window.Flush(0)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case now := <-deadline:
+			b.peak(b.window.Flush(now.Sub(last)).Total)
+			return true
+		case now := <-ticker.C:
+			stats := b.window.Flush(now.Sub(last)).Total
+			b.peak(stats)
+
+			start := last
+			last = now
+			if done != nil && done(start, stats) {
+				return true
+			}
+		}
+	}
+}
+
+func (b *burstTracker) peak(stats metrics.StepStats) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.result.PeakP95 = max(b.result.PeakP95, stats.Latency.P95)
+}
+
+// outcome returns the result of the burst as of end, the time the run ended
+func (b *burstTracker) outcome(end time.Time) BurstResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r := b.result
+	if !r.Recovered && !b.ended.IsZero() {
+		r.RecoveryTime = end.Sub(b.ended)
+	}
+	return r
+}
+
+// recoveryThreshold returns the outcome of the max_recovery of the burst as
+// a threshold result
+func recoveryThreshold(max time.Duration, r BurstResult) threshold.Result {
+	result := threshold.Result{
+		Expr:   "recovery_time <= " + max.String(),
+		Actual: r.RecoveryTime.String(),
+		Passed: r.Recovered && r.RecoveryTime <= max,
+	}
+	if !r.Recovered {
+		result.Actual = "not recovered after " + r.RecoveryTime.String()
+	}
+	return result
+}
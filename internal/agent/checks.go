@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/openapi"
+	"loadforge-agent/internal/scenario"
+)
+
+// CheckResult counts how often a check passed
+type CheckResult struct {
+	Step      string
+	Name      string
+	Condition string
+	Passes    int64
+	Fails     int64
+	// PassRate is the share of evaluations that passed
+	PassRate float64
+	// Errors are the first distinct reasons the check failed, e.g. schema
+	// validation errors
+	Errors []string
+}
+
+// maxReasons is how many failure reasons a check or contract keeps
+const maxReasons = 5
+
+// reasons keeps the first distinct reasons of failures, up to maxReasons
+type reasons struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *reasons) add(msgs []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range msgs {
+		if len(r.msgs) == maxReasons {
+			return
+		}
+		if !slices.Contains(r.msgs, msg) {
+			r.msgs = append(r.msgs, msg)
+		}
+	}
+}
+
+func (r *reasons) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.msgs)
+}
+
+type checkTracker struct {
+	step string
+	name string
+	// expr describes all the conditions of the check
+	expr string
+	// condition is nil for checks without one
+	condition *scenario.Condition
+	// responseRefs are the variables of condition resolved against the
+	// response rather than the VU's variables
+	responseRefs []string
+	status       []string
+	bodyContains []byte
+	bodyMatches  *regexp.Regexp
+	headers      map[string]string
+	maxLatency   time.Duration
+	schema       *openapi3.Schema
+
+	passes atomic.Int64
+	fails  atomic.Int64
+	errors reasons
+}
+
+// responseSources are the lookup sources a check can reference
+var responseSources = []string{"response.", "headers.", "cookies."}
+
+// newCheckTrackers returns the trackers of every check in step order, and
+// by step
+func newCheckTrackers(sc *scenario.Scenario) ([]*checkTracker, map[string][]*checkTracker, error) {
+	var (
+		all    []*checkTracker
+		byStep = make(map[string][]*checkTracker)
+	)
+	for i := range sc.Steps {
+		step := sc.Steps[i].ID()
+		for _, c := range sc.Steps[i].Checks {
+			t, err := newCheckTracker(step, &c)
+			if err != nil {
+				return nil, nil, fmt.Errorf("step '%s', check '%s': %w", step, c.Name, err)
+			}
+			all = append(all, t)
+			byStep[step] = append(byStep[step], t)
+		}
+	}
+	return all, byStep, nil
+}
+
+func newCheckTracker(step string, c *scenario.Check) (*checkTracker, error) {
+	t := &checkTracker{
+		step:       step,
+		name:       c.Name,
+		expr:       c.Describe(),
+		status:     c.Status,
+		headers:    c.Headers,
+		maxLatency: c.MaxLatency.Duration,
+	}
+	if c.BodyContains != "" {
+		t.bodyContains = []byte(c.BodyContains)
+	}
+	if c.BodyMatches != "" {
+		re, err := regexp.Compile(c.BodyMatches)
+		if err != nil {
+			return nil, err
+		}
+		t.bodyMatches = re
+	}
+	if c.Schema != nil {
+		schema, err := c.Schema.Load()
+		if err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+		t.schema = schema
+	}
+	if c.Condition != "" {
+		cond, err := scenario.ParseCondition(c.Condition)
+		if err != nil {
+			return nil, err
+		}
+		t.condition = cond
+		for _, name := range cond.Vars() {
+			for _, source := range responseSources {
+				if strings.HasPrefix(name, source) {
+					t.responseRefs = append(t.responseRefs, name)
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+// eval evaluates the check against ex and vars and counts the outcome
+func (t *checkTracker) eval(ex *exchange, vars map[string]string) (bool, error) {
+	passed, err := t.match(ex, vars)
+	if passed {
+		t.passes.Add(1)
+	} else {
+		t.fails.Add(1)
+	}
+	return passed, err
+}
+
+// match reports whether ex passes all the conditions of the check. A
+// condition that cannot be evaluated, e.g. over a missing response field,
+// fails. So does a body that does not validate against the schema; err
+// then holds the validation errors.
+func (t *checkTracker) match(ex *exchange, vars map[string]string) (bool, error) {
+	if !t.assert(ex.response) {
+		return false, nil
+	}
+	if t.schema != nil {
+		if errs := t.validate(ex.response.Body); len(errs) > 0 {
+			t.errors.add(errs)
+			return false, fmt.Errorf("schema: %s", strings.Join(errs, "; "))
+		}
+	}
+	if t.condition != nil {
+		return t.evalCondition(ex, vars)
+	}
+	return true, nil
+}
+
+// validate returns why body does not validate against the schema
+func (t *checkTracker) validate(body []byte) []string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{"body is not JSON: " + err.Error()}
+	}
+	return openapi.SchemaErrors(t.schema.VisitJSON(value, openapi3.MultiErrors()))
+}
+
+// assert reports whether resp passes the status, body, header and latency
+// conditions of the check
+func (t *checkTracker) assert(resp *executor.Response) bool {
+	if len(t.status) > 0 && !slices.ContainsFunc(t.status, func(code string) bool {
+		return scenario.MatchStatus(code, resp.StatusCode)
+	}) {
+		return false
+	}
+	if t.bodyContains != nil && !bytes.Contains(resp.Body, t.bodyContains) {
+		return false
+	}
+	if t.bodyMatches != nil && !t.bodyMatches.Match(resp.Body) {
+		return false
+	}
+	for name, want := range t.headers {
+		if http.Header(resp.Headers).Get(name) != want {
+			return false
+		}
+	}
+	return t.maxLatency == 0 || resp.Duration <= t.maxLatency
+}
+
+func (t *checkTracker) evalCondition(ex *exchange, vars map[string]string) (bool, error) {
+	if len(t.responseRefs) > 0 {
+		vars = maps.Clone(vars)
+		for _, ref := range t.responseRefs {
+			value, err := lookup(ex, vars, ref)
+			if err != nil {
+				return false, err
+			}
+			vars[ref] = value
+		}
+	}
+	return t.condition.Eval(vars)
+}
+
+// countChecks adds the check evaluations to the run total and to the steps
+// of summary, so thresholds can refer to checks_passed and checks_failed
+func (r *recorder) countChecks(summary *metrics.Summary) {
+	for _, c := range r.checks {
+		passes, fails := c.passes.Load(), c.fails.Load()
+		summary.Total.ChecksPassed += passes
+		summary.Total.ChecksFailed += fails
+		for i := range summary.Steps {
+			if summary.Steps[i].Name == c.step {
+				summary.Steps[i].ChecksPassed += passes
+				summary.Steps[i].ChecksFailed += fails
+			}
+		}
+	}
+}
+
+func (t *checkTracker) result() CheckResult {
+	r := CheckResult{
+		Step:      t.step,
+		Name:      t.name,
+		Condition: t.expr,
+		Passes:    t.passes.Load(),
+		Fails:     t.fails.Load(),
+		Errors:    t.errors.list(),
+	}
+	if total := r.Passes + r.Fails; total > 0 {
+		r.PassRate = float64(r.Passes) / float64(total)
+	}
+	return r
+}
+
+// CheckState is the serializable content of a check tracker
+type CheckState struct {
+	Step   string   `json:"step"`
+	Name   string   `json:"name"`
+	Passes int64    `json:"passes"`
+	Fails  int64    `json:"fails"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+func (t *checkTracker) state() CheckState {
+	return CheckState{Step: t.step, Name: t.name, Passes: t.passes.Load(), Fails: t.fails.Load(),
+		Errors: t.errors.list()}
+}
+
+func (t *checkTracker) merge(state CheckState) {
+	t.passes.Add(state.Passes)
+	t.fails.Add(state.Fails)
+	t.errors.add(state.Errors)
+}
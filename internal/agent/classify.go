@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"fmt"
+
+	"loadforge-agent/internal/scenario"
+)
+
+// classifyRule is a classify rule of a step, matched like a check
+type classifyRule struct {
+	failure bool
+	check   *checkTracker
+}
+
+// newClassifyRules returns the classify rules of the steps having some,
+// by step
+func newClassifyRules(sc *scenario.Scenario) (map[string][]classifyRule, error) {
+	rules := make(map[string][]classifyRule)
+	for i := range sc.Steps {
+		step := sc.Steps[i].ID()
+		for j := range sc.Steps[i].Classify {
+			c := &sc.Steps[i].Classify[j]
+			t, err := newCheckTracker(step, &c.Check)
+			if err != nil {
+				return nil, fmt.Errorf("step '%s', classify[%d]: %w", step, j, err)
+			}
+			rules[step] = append(rules[step], classifyRule{failure: c.Result == scenario.ClassifyFailure, check: t})
+		}
+	}
+	return rules, nil
+}
+
+// failed reports whether the response of ex is a failure, as the first
+// classify rule of its step matching it says, or else when its status is
+// 4xx or 5xx
+func (vu *virtualUser) failed(ex *exchange) bool {
+	for _, rule := range vu.agent.classify[ex.name] {
+		if matched, _ := rule.check.match(ex, vu.vars); matched {
+			return rule.failure
+		}
+	}
+	return ex.response.StatusCode >= 400
+}
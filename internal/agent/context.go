@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/extractor"
+	"loadforge-agent/internal/scenario"
+)
+
+// exchange is a step as it was sent together with the response it got back.
+// It is the source for save_to_context and next_steps map lookups.
+type exchange struct {
+	name     string
+	step     scenario.Step
+	response *executor.Response
+	// request is the request sent, kept to replay it
+	request *executor.Request
+}
+
+var jsonExtractor = extractor.New()
+
+// lookup resolves a "source.field" reference against ex and vars.
+func lookup(ex *exchange, vars map[string]string, ref string) (string, error) {
+	source, field, ok := strings.Cut(ref, ".")
+	if !ok || field == "" {
+		return "", fmt.Errorf("invalid reference %q, expected 'source.field'", ref)
+	}
+
+	switch source {
+	case "response":
+		value, err := jsonExtractor.Extract(ex.response.Body, field)
+		if err != nil {
+			return "", err
+		}
+		return stringify(value), nil
+	case "headers":
+		value := http.Header(ex.response.Headers).Get(field)
+		if value == "" {
+			return "", fmt.Errorf("response header %q not found", field)
+		}
+		return value, nil
+	case "cookies":
+		resp := &http.Response{Header: ex.response.Headers}
+		for _, c := range resp.Cookies() {
+			if c.Name == field {
+				return c.Value, nil
+			}
+		}
+		return "", fmt.Errorf("response cookie %q not found", field)
+	case "query":
+		return lookupMap(ex.step.Query, "query param", field)
+	case "path_params":
+		return lookupMap(ex.step.PathParams, "path param", field)
+	case "body":
+		raw, err := json.Marshal(ex.step.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		value, err := jsonExtractor.Extract(raw, field)
+		if err != nil {
+			return "", err
+		}
+		return stringify(value), nil
+	case "variables":
+		return lookupMap(vars, "variable", field)
+	default:
+		return "", fmt.Errorf("unknown source %q", source)
+	}
+}
+
+func lookupMap(m map[string]string, kind, key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", fmt.Errorf("%s %q not found", kind, key)
+	}
+	return value, nil
+}
+
+// assign writes value to the "target.field" location of step, or to vars
+// for variables targets. step must already be a private copy.
+func assign(step *scenario.Step, vars map[string]string, ref, value string) error {
+	target, field, ok := strings.Cut(ref, ".")
+	if !ok || field == "" {
+		return fmt.Errorf("invalid target %q, expected 'target.field'", ref)
+	}
+
+	switch target {
+	case "headers":
+		step.Headers[field] = value
+	case "query":
+		step.Query[field] = value
+	case "path_params":
+		step.PathParams[field] = value
+	case "cookies":
+		setCookie(step.Headers, field, value)
+	case "variables":
+		vars[field] = value
+	case "body":
+		body, err := setBodyField(step.Body, strings.Split(field, "."), value)
+		if err != nil {
+			return err
+		}
+		step.Body = body
+	default:
+		return fmt.Errorf("unknown target %q", target)
+	}
+	return nil
+}
+
+// setBodyField returns a copy of body with the object field at path set to
+// value. Objects along the path are copied, never modified in place, since
+// step bodies are shared between VUs.
+func setBodyField(body any, path []string, value string) (any, error) {
+	var obj map[string]any
+	switch b := body.(type) {
+	case nil:
+		obj = make(map[string]any, 1)
+	case map[string]any:
+		obj = maps.Clone(b)
+	default:
+		return nil, fmt.Errorf("cannot set field %q on non-object body", strings.Join(path, "."))
+	}
+
+	if len(path) == 1 {
+		obj[path[0]] = value
+		return obj, nil
+	}
+
+	child, err := setBodyField(obj[path[0]], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	obj[path[0]] = child
+	return obj, nil
+}
+
+// cloneStep returns a copy of step whose maps can be modified without
+// affecting the scenario definition.
+func cloneStep(step scenario.Step) scenario.Step {
+	step.Headers = cloneMap(step.Headers)
+	step.Query = cloneMap(step.Query)
+	step.PathParams = cloneMap(step.PathParams)
+	return step
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return make(map[string]string)
+	}
+	return maps.Clone(m)
+}
+
+func stringify(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(raw)
+	}
+}
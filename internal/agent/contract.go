@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/openapi"
+	"loadforge-agent/internal/scenario"
+)
+
+// ContractResult counts the responses of a step that violated the OpenAPI
+// document of the scenario
+type ContractResult struct {
+	Step string
+	// Operation is the operation of the document the step was validated
+	// against, empty when the document has none for it
+	Operation  string
+	Responses  int64
+	Violations int64
+	// Errors are the first distinct violations
+	Errors []string
+}
+
+// contractTracker validates the responses of a step against its operation
+// in the OpenAPI document
+type contractTracker struct {
+	step string
+	// operation is nil when the document has no operation for the step,
+	// then every response is a violation
+	operation *openapi.Operation
+	request   string
+
+	responses  atomic.Int64
+	violations atomic.Int64
+	errors     reasons
+}
+
+// newContractTrackers returns a tracker for every step in step order, and
+// by step, when the scenario validates responses against its OpenAPI
+// document
+func newContractTrackers(sc *scenario.Scenario) ([]*contractTracker, map[string]*contractTracker, error) {
+	if sc.OpenAPI == nil || !sc.OpenAPI.ValidateResponses {
+		return nil, nil, nil
+	}
+
+	doc, err := loadDocument(sc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		all    []*contractTracker
+		byStep = make(map[string]*contractTracker)
+	)
+	for i := range sc.Steps {
+		step := &sc.Steps[i]
+		method, path, err := scenario.ParseRequest(step.Request)
+		if err != nil {
+			return nil, nil, fmt.Errorf("step '%s': %w", step.ID(), err)
+		}
+		op, err := doc.FindOperation(method, contractPath(path))
+		if err != nil {
+			return nil, nil, fmt.Errorf("openapi: %w", err)
+		}
+		t := &contractTracker{step: step.ID(), operation: op, request: method + " " + path}
+		all = append(all, t)
+		byStep[t.step] = t
+	}
+	return all, byStep, nil
+}
+
+// contractPath returns path without its query and with the segments
+// holding variables, e.g. ${user_id}, turned into parameters
+func contractPath(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.Contains(s, "${") {
+			segments[i] = "{" + s + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// validate counts resp and whether it violates the document
+func (t *contractTracker) validate(resp *executor.Response) {
+	t.responses.Add(1)
+	var errs []string
+	if t.operation == nil {
+		errs = []string{t.request + " is not in the document"}
+	} else {
+		errs = t.operation.ValidateResponse(resp.StatusCode, http.Header(resp.Headers), resp.Body)
+	}
+	if len(errs) > 0 {
+		t.violations.Add(1)
+		t.errors.add(errs)
+	}
+}
+
+func (t *contractTracker) result() ContractResult {
+	r := ContractResult{
+		Step:       t.step,
+		Responses:  t.responses.Load(),
+		Violations: t.violations.Load(),
+		Errors:     t.errors.list(),
+	}
+	if t.operation != nil {
+		r.Operation = t.operation.String()
+	}
+	return r
+}
+
+// ContractState is the serializable content of a contract tracker
+type ContractState struct {
+	Step       string   `json:"step"`
+	Responses  int64    `json:"responses"`
+	Violations int64    `json:"violations"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+func (t *contractTracker) state() ContractState {
+	return ContractState{Step: t.step, Responses: t.responses.Load(), Violations: t.violations.Load(),
+		Errors: t.errors.list()}
+}
+
+func (t *contractTracker) merge(state ContractState) {
+	t.responses.Add(state.Responses)
+	t.violations.Add(state.Violations)
+	t.errors.add(state.Errors)
+}
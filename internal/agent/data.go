@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+
+	"loadforge-agent/internal/feeder"
+	"loadforge-agent/internal/scenario"
+)
+
+// records is a feeder.File or one partition of it
+type records interface {
+	Len() int
+	Record(i int) (feeder.Record, error)
+}
+
+// openDB connects to the database of a data query, replaced in tests
+var openDB = feeder.OpenDB
+
+// dataFeeder hands the rows of the scenario's data file or query to VUs
+type dataFeeder struct {
+	file *feeder.File
+	rows records
+	// stream replaces file and rows for a streamed query
+	stream *feeder.Stream
+	// remote replaces file for data from an HTTP endpoint
+	remote *feeder.Remote
+	// db is the database of a data query, nil for a file
+	db       *sql.DB
+	strategy string
+	// next is the index of the next round robin row
+	next atomic.Uint64
+}
+
+// openData opens the scenario's data file or runs its data query, or
+// returns nil when the scenario has neither. In a distributed run each
+// agent only feeds its own partition of the rows, so workers never share
+// a row.
+func openData(sc *scenario.Scenario, opts Options) (*dataFeeder, error) {
+	if sc.Data == nil {
+		return nil, nil
+	}
+	if sc.Data.Query != nil {
+		return openDataQuery(sc, opts)
+	}
+	if sc.Data.URL != "" {
+		return openDataURL(sc, opts)
+	}
+
+	path := sc.Data.Path
+	if path == "" {
+		path = sc.Data.File
+	}
+	file, err := feeder.Open(path, feeder.Options{Format: feeder.Format(sc.Data.Format)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+	d, err := newDataFeeder(sc, opts, file, "data file "+path)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// openDataQuery runs the scenario's data query, or prepares it when it is
+// streamed
+func openDataQuery(sc *scenario.Scenario, opts Options) (*dataFeeder, error) {
+	q := sc.Data.Query
+	if q.Stream && opts.Workers > 1 {
+		return nil, fmt.Errorf("a streamed data query cannot be partitioned between %d workers", opts.Workers)
+	}
+
+	ctx := context.Background()
+	db, err := openDB(ctx, q.Driver, q.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("data query: %w", err)
+	}
+
+	if q.Stream {
+		stream, err := feeder.OpenStream(ctx, db, q.SQL, feeder.Options{})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("data query: %w", err)
+		}
+		return &dataFeeder{stream: stream, db: db, strategy: sc.Data.Strategy}, nil
+	}
+
+	file, err := feeder.OpenQuery(ctx, db, q.SQL, feeder.Options{})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("data query: %w", err)
+	}
+	d, err := newDataFeeder(sc, opts, file, "data query")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	d.db = db
+	return d, nil
+}
+
+// openDataURL fetches the rows of the scenario's data endpoint and keeps
+// refreshing them. Every worker feeds all the rows, which may change with
+// each refresh.
+func openDataURL(sc *scenario.Scenario, opts Options) (*dataFeeder, error) {
+	if opts.Workers > 1 && sc.Data.Strategy == scenario.DataUnique {
+		return nil, fmt.Errorf("data url %s: the %s strategy cannot be used with %d workers, which share the rows",
+			sc.Data.URL, scenario.DataUnique, opts.Workers)
+	}
+	remote, err := feeder.OpenRemote(context.Background(), sc.Data.URL, sc.Data.Refresh.Duration, feeder.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("data url: %w", err)
+	}
+
+	if err := checkRows(sc, remote, "data url "+sc.Data.URL); err != nil {
+		remote.Close()
+		return nil, err
+	}
+	return &dataFeeder{remote: remote, rows: remote, strategy: sc.Data.Strategy}, nil
+}
+
+// newDataFeeder feeds the rows of file, or those of the worker's
+// partition of them, checking there are enough. what names file in
+// errors.
+func newDataFeeder(sc *scenario.Scenario, opts Options, file *feeder.File, what string) (*dataFeeder, error) {
+	var err error
+	var rows records = file
+	switch {
+	case opts.Workers > 1 && sc.Data.Partition == scenario.DataPartitionHash:
+		rows, err = hashPartition(file, sc.Data, opts)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", what, err)
+		}
+	case opts.Workers > 1:
+		rows, err = file.Partition(opts.Worker, opts.Workers)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRows(sc, rows, what); err != nil {
+		return nil, err
+	}
+	return &dataFeeder{file: file, rows: rows, strategy: sc.Data.Strategy}, nil
+}
+
+// checkRows reports rows that are empty or too few for the unique
+// strategy. what names rows in errors.
+func checkRows(sc *scenario.Scenario, rows records, what string) error {
+	switch {
+	case rows.Len() == 0:
+		return fmt.Errorf("%s has no rows", what)
+	case sc.Data.Strategy == scenario.DataUnique && uint64(rows.Len()) < sc.VirtualUsers:
+		return fmt.Errorf("%s has %d rows for %d VUs, the %s strategy needs one per VU",
+			what, rows.Len(), sc.VirtualUsers, scenario.DataUnique)
+	}
+	return nil
+}
+
+// hashPartition returns the rows of file the worker of opts owns on a
+// consistent hash ring of the run's workers. The unique strategy fails on
+// rows sharing a key, which would be handed to two VUs.
+func hashPartition(file *feeder.File, data *scenario.Data, opts Options) (records, error) {
+	nodes := make([]string, opts.Workers)
+	for i := range nodes {
+		nodes[i] = workerNode(i)
+	}
+	retired := make([]string, len(opts.RetiredWorkers))
+	for i, w := range opts.RetiredWorkers {
+		retired[i] = workerNode(w)
+	}
+	ring, err := feeder.NewRing(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, overlaps, err := file.HashPartition(ring, workerNode(opts.Worker), data.Key, retired)
+	if err != nil {
+		return nil, err
+	}
+	if len(overlaps) > 0 && data.Strategy == scenario.DataUnique {
+		return nil, fmt.Errorf("%d keys are shared by several rows, the %s strategy needs distinct keys: %s",
+			len(overlaps), scenario.DataUnique, overlaps[0])
+	}
+	return rows, nil
+}
+
+// workerNode names worker on the data partition ring
+func workerNode(worker int) string {
+	return "worker-" + strconv.Itoa(worker)
+}
+
+// record returns the row for the next iteration of the 1-based VU id
+func (d *dataFeeder) record(id int) (feeder.Record, error) {
+	if d.stream != nil {
+		return d.stream.Next()
+	}
+
+	// The rows of a refreshed endpoint may change between calls
+	n := d.rows.Len()
+	if n == 0 {
+		return nil, fmt.Errorf("no data rows")
+	}
+
+	var i int
+	switch d.strategy {
+	case scenario.DataUnique:
+		i = id - 1
+	case scenario.DataRandom:
+		i = rand.IntN(n)
+	default:
+		i = int((d.next.Add(1) - 1) % uint64(n))
+	}
+	return d.rows.Record(i)
+}
+
+func (d *dataFeeder) close() error {
+	if d == nil {
+		return nil
+	}
+	var err error
+	switch {
+	case d.stream != nil:
+		err = d.stream.Close()
+	case d.remote != nil:
+		err = d.remote.Close()
+	default:
+		err = d.file.Close()
+	}
+	if d.db != nil {
+		err = errors.Join(err, d.db.Close())
+	}
+	return err
+}
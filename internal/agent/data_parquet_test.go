@@ -0,0 +1,74 @@
+//go:build !noparquet
+
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"loadforge-agent/internal/scenario"
+)
+
+func TestRun_DataFeederParquet(t *testing.T) {
+	type user struct {
+		Username string `parquet:"username"`
+		ID       int64  `parquet:"id"`
+	}
+	path := filepath.Join(t.TempDir(), "users.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create data file: %v", err)
+	}
+	w := parquet.NewGenericWriter[user](f)
+	if _, err := w.Write([]user{{"alice", 1}, {"bob", 2}}); err != nil {
+		t.Fatalf("failed to write rows: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	f.Close()
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.URL.Query().Get("user")] = true
+	}))
+	defer server.Close()
+
+	sc := &scenario.Scenario{
+		Name:         "parquet",
+		BaseURL:      server.URL,
+		VirtualUsers: 2,
+		Duration:     60,
+		Data:         &scenario.Data{File: path, Strategy: scenario.DataUnique},
+		Steps: []scenario.Step{{
+			Request: "GET /",
+			Query:   map[string]string{"user": "${csv.username}:${csv.id}"},
+		}},
+	}
+	a, err := New(sc, Options{})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Run(ctx); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 || !seen["alice:1"] || !seen["bob:2"] {
+		t.Errorf("expected the rows of the Parquet file, got %v", seen)
+	}
+}
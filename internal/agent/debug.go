@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/secrets"
+)
+
+// DefaultDebugBodyLimit is the number of bytes of bodies the debug log
+// keeps unless Options.DebugBodyLimit is set
+const DefaultDebugBodyLimit = 2048
+
+// sensitiveHeaders are masked in the debug log whatever their value, as
+// they carry credentials
+var sensitiveHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-Api-Key", "X-Auth-Token", "X-Csrf-Token",
+}
+
+// DebugEntry is a request and its response, or error, in the debug log
+type DebugEntry struct {
+	Time      time.Time         `json:"time"`
+	VU        int               `json:"vu"`
+	Iteration uint64            `json:"iteration"`
+	Step      string            `json:"step"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"request_headers,omitempty"`
+	Body      string            `json:"request_body,omitempty"`
+	// BodySize is the size of the request body, which Body may be
+	// truncated from
+	BodySize        int                 `json:"request_body_size,omitempty"`
+	Status          int                 `json:"status,omitempty"`
+	Proto           string              `json:"proto,omitempty"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	ResponseBody    string              `json:"response_body,omitempty"`
+	// ResponseBodySize is the size of the response body, which
+	// ResponseBody may be truncated from
+	ResponseBodySize int64         `json:"response_body_size,omitempty"`
+	Duration         time.Duration `json:"duration_ns,omitempty"`
+	Error            string        `json:"error,omitempty"`
+}
+
+// debugLogger writes the requests of a sampled fraction, and the first
+// ones of every step, with their responses to the debug log as JSON lines.
+// A nil debugLogger logs nothing.
+type debugLogger struct {
+	sample    float64
+	first     int64
+	bodyLimit int
+	redact    *secrets.Redactor
+
+	// counts holds the requests of every step so far, as *atomic.Int64
+	counts sync.Map
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// newDebugLogger returns the debug logger of opts, nil unless it samples
+// requests
+func newDebugLogger(opts Options, redact *secrets.Redactor) *debugLogger {
+	if opts.DebugSample <= 0 && opts.DebugFirst <= 0 {
+		return nil
+	}
+	limit := opts.DebugBodyLimit
+	if limit == 0 {
+		limit = DefaultDebugBodyLimit
+	}
+	return &debugLogger{
+		sample:    opts.DebugSample,
+		first:     int64(opts.DebugFirst),
+		bodyLimit: limit,
+		redact:    redact,
+		enc:       json.NewEncoder(opts.Debug),
+	}
+}
+
+// sampled reports whether a request of step is logged, counting it
+func (d *debugLogger) sampled(step string) bool {
+	if d == nil {
+		return false
+	}
+	count, _ := d.counts.LoadOrStore(step, new(atomic.Int64))
+	if count.(*atomic.Int64).Add(1) <= d.first {
+		return true
+	}
+	return d.sample > 0 && rand.Float64() < d.sample
+}
+
+// log writes the exchange of req, with resp or err, to the debug log
+func (d *debugLogger) log(vu int, iteration uint64, step string, req *executor.Request, resp *executor.Response, err error) {
+	body := req.Body
+	if req.Raw != nil {
+		body = maskRawHeaders(req.Raw)
+	}
+	entry := DebugEntry{
+		Time:      time.Now(),
+		VU:        vu,
+		Iteration: iteration,
+		Step:      step,
+		Method:    req.Method,
+		URL:       d.redact.String(req.URL),
+		Body:      d.truncate(body),
+		BodySize:  len(body),
+	}
+	if req.BodyReader != nil {
+		entry.BodySize = int(req.ContentLength)
+	}
+	if len(req.Headers) > 0 {
+		entry.Headers = make(map[string]string, len(req.Headers))
+		for k, v := range req.Headers {
+			entry.Headers[k] = d.header(k, v)
+		}
+	}
+	if err != nil {
+		entry.Error = d.redact.String(err.Error())
+	}
+	if resp != nil {
+		entry.Status = resp.StatusCode
+		entry.Proto = resp.Proto
+		entry.Duration = resp.Duration
+		entry.ResponseBody = d.truncate(resp.Body)
+		entry.ResponseBodySize = resp.BodySize
+		entry.ResponseHeaders = make(map[string][]string, len(resp.Headers))
+		for k, values := range resp.Headers {
+			masked := make([]string, len(values))
+			for i, v := range values {
+				masked[i] = d.header(k, v)
+			}
+			entry.ResponseHeaders[k] = masked
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_ = d.enc.Encode(entry)
+}
+
+// header returns the value of header name as logged
+func (d *debugLogger) header(name, value string) string {
+	name = http.CanonicalHeaderKey(name)
+	for _, sensitive := range sensitiveHeaders {
+		if name == sensitive {
+			return secrets.Redacted
+		}
+	}
+	return d.redact.String(value)
+}
+
+// maskRawHeaders returns the raw request raw with the values of its
+// sensitive headers masked
+func maskRawHeaders(raw []byte) []byte {
+	head, body, _ := strings.Cut(string(raw), "\r\n\r\n")
+	lines := strings.Split(head, "\r\n")
+	for i, line := range lines[1:] {
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		for _, sensitive := range sensitiveHeaders {
+			if strings.EqualFold(strings.TrimSpace(name), sensitive) {
+				lines[i+1] = name + ": " + secrets.Redacted
+			}
+		}
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n\r\n" + body)
+}
+
+// truncate returns body as logged, at most bodyLimit bytes of it
+func (d *debugLogger) truncate(body []byte) string {
+	if d.bodyLimit > 0 && len(body) > d.bodyLimit {
+		body = body[:d.bodyLimit]
+	}
+	return d.redact.String(string(body))
+}
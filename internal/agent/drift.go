@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"loadforge-agent/internal/openapi"
+	"loadforge-agent/internal/scenario"
+)
+
+// loadDocument parses the OpenAPI document of sc
+func loadDocument(sc *scenario.Scenario) (*openapi.Parser, error) {
+	doc := openapi.New()
+	if err := doc.Load(cmp.Or(sc.OpenAPI.Path, sc.OpenAPI.Spec)); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	return doc, nil
+}
+
+// PinEndpoints returns the fingerprints of the operations of the OpenAPI
+// document that the steps of sc exercise, as scenario.OpenAPI.Pinned. Steps
+// without an operation in the document are left out.
+func PinEndpoints(sc *scenario.Scenario) (map[string]string, error) {
+	if sc.OpenAPI == nil {
+		return nil, fmt.Errorf("the scenario has no openapi document")
+	}
+	doc, err := loadDocument(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := make(map[string]string)
+	for i := range sc.Steps {
+		method, path, err := scenario.ParseRequest(sc.Steps[i].Request)
+		if err != nil {
+			return nil, fmt.Errorf("step '%s': %w", sc.Steps[i].ID(), err)
+		}
+		op, err := doc.FindOperation(method, contractPath(path))
+		if err != nil {
+			return nil, fmt.Errorf("openapi: %w", err)
+		}
+		if op == nil {
+			continue
+		}
+		if pinned[op.String()], err = op.Fingerprint(); err != nil {
+			return nil, fmt.Errorf("openapi: %s: %w", op, err)
+		}
+	}
+	return pinned, nil
+}
+
+// CheckDrift compares the operations pinned by sc with its OpenAPI
+// document, returning a warning for every operation that changed shape or
+// was removed since it was pinned
+func CheckDrift(sc *scenario.Scenario) ([]string, error) {
+	if sc.OpenAPI == nil || len(sc.OpenAPI.Pinned) == 0 {
+		return nil, nil
+	}
+	doc, err := loadDocument(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, pin := range slices.Sorted(maps.Keys(sc.OpenAPI.Pinned)) {
+		method, path, err := scenario.ParseRequest(pin)
+		if err != nil {
+			return nil, err
+		}
+		op, err := doc.FindOperation(method, path)
+		if err != nil {
+			return nil, fmt.Errorf("openapi: %w", err)
+		}
+		if op == nil {
+			warnings = append(warnings, fmt.Sprintf("%s is no longer in the document", pin))
+			continue
+		}
+		fingerprint, err := op.Fingerprint()
+		if err != nil {
+			return nil, fmt.Errorf("openapi: %s: %w", op, err)
+		}
+		if fingerprint != sc.OpenAPI.Pinned[pin] {
+			warnings = append(warnings, fmt.Sprintf("%s changed since the scenario was pinned (%s, pinned %s)", pin, fingerprint, sc.OpenAPI.Pinned[pin]))
+		}
+	}
+	return warnings, nil
+}
+
+// CheckParameters returns a warning for every required query or header
+// parameter that a step of sc does not set, according to the OpenAPI
+// document of sc
+func CheckParameters(sc *scenario.Scenario) ([]string, error) {
+	if sc.OpenAPI == nil {
+		return nil, nil
+	}
+	doc, err := loadDocument(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for i := range sc.Steps {
+		step := &sc.Steps[i]
+		method, path, err := scenario.ParseRequest(step.Request)
+		if err != nil {
+			return nil, fmt.Errorf("step '%s': %w", step.ID(), err)
+		}
+		op, err := doc.FindOperation(method, contractPath(path))
+		if err != nil {
+			return nil, fmt.Errorf("openapi: %w", err)
+		}
+		if op == nil {
+			continue
+		}
+		for _, p := range openapi.MissingParameters(op.Parameters(), step) {
+			warnings = append(warnings, fmt.Sprintf("step '%s': %s requires the %s parameter %s", step.ID(), op, p.In, p.Name))
+		}
+	}
+	return warnings, nil
+}
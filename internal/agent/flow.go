@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"math"
+	"slices"
+
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/pkg/ratelimit"
+)
+
+// flow is the part of the scenario a VU iterates over: all steps, or the
+// steps of one weighted scenario
+type flow struct {
+	// name is the weighted scenario's name, empty for a single scenario
+	name string
+	// first and end bound the flow's steps in scenario.Steps
+	first, end int
+	variables  map[string]string
+	vus        uint64
+	// maxRPS caps the requests of the flow's VUs, nil if unlimited
+	maxRPS *ratelimit.Bucket
+}
+
+// newFlows splits the scenario's VUs across its weighted scenarios, or
+// returns a single flow over all steps
+func newFlows(sc *scenario.Scenario) []flow {
+	if len(sc.Scenarios) == 0 {
+		return []flow{{first: 0, end: len(sc.Steps), vus: sc.VirtualUsers}}
+	}
+
+	weights := make([]float64, len(sc.Scenarios))
+	for i := range sc.Scenarios {
+		weights[i] = sc.Scenarios[i].Weight
+	}
+	shares := splitByWeight(sc.VirtualUsers, weights)
+
+	flows := make([]flow, len(sc.Scenarios))
+	for i := range sc.Scenarios {
+		w := &sc.Scenarios[i]
+		flows[i] = flow{
+			name:      w.Name,
+			first:     w.FirstStep,
+			end:       w.FirstStep + w.StepCount,
+			variables: w.Variables,
+			vus:       shares[i],
+		}
+	}
+	return flows
+}
+
+// interleaveFlows orders the flows' VUs with a smooth weighted round-robin,
+// so VU ids are assigned to flows in an interleaved sequence. Every prefix of
+// the sequence keeps the flows' mix, which holds the mix while fewer VUs run
+// under a ramp or after scaling down. The result has one flow index per
+// scenario VU.
+func interleaveFlows(flows []flow) []int {
+	var total int64
+	for i := range flows {
+		total += int64(flows[i].vus)
+	}
+
+	order := make([]int, 0, total)
+	current := make([]int64, len(flows))
+	for range total {
+		best := 0
+		for i := range flows {
+			current[i] += int64(flows[i].vus)
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		order = append(order, best)
+	}
+	return order
+}
+
+// splitByWeight divides total in proportion to weights. Rounding uses the
+// largest remainder method, so the shares always add up to total; a share
+// rounded down to zero takes one from the largest share when total allows.
+func splitByWeight(total uint64, weights []float64) []uint64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+
+	shares := make([]uint64, len(weights))
+	remainders := make([]float64, len(weights))
+	var assigned uint64
+	for i, w := range weights {
+		exact := float64(total) * w / sum
+		shares[i] = uint64(math.Floor(exact))
+		remainders[i] = exact - math.Floor(exact)
+		assigned += shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case remainders[a] > remainders[b]:
+			return -1
+		case remainders[a] < remainders[b]:
+			return 1
+		}
+		return 0
+	})
+	for _, i := range order[:total-assigned] {
+		shares[i]++
+	}
+
+	for i := range shares {
+		if shares[i] > 0 {
+			continue
+		}
+		largest := 0
+		for j := range shares {
+			if shares[j] > shares[largest] {
+				largest = j
+			}
+		}
+		if shares[largest] <= 1 {
+			break
+		}
+		shares[largest]--
+		shares[i]++
+	}
+	return shares
+}
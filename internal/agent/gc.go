@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+)
+
+// gcPollInterval is how often GC pauses are collected. The runtime only
+// remembers the last 256 pauses, far more than happen in this interval.
+const gcPollInterval = 500 * time.Millisecond
+
+// watchGC collects the agent's garbage collection pauses until ctx ends, so
+// latency outliers caused by the agent itself can be told apart from slow
+// responses
+func watchGC(ctx context.Context) []metrics.GCPause {
+	var pauses []metrics.GCPause
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	last := stats.NumGC
+
+	ticker := time.NewTicker(gcPollInterval)
+	defer ticker.Stop()
+	for {
+		done := false
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+		}
+
+		debug.ReadGCStats(&stats)
+		// Pause and PauseEnd are ordered most recent first
+		n := min(int(stats.NumGC-last), len(stats.Pause), len(stats.PauseEnd))
+		for i := n - 1; i >= 0; i-- {
+			pauses = append(pauses, metrics.GCPause{End: stats.PauseEnd[i], Duration: stats.Pause[i]})
+		}
+		last = stats.NumGC
+
+		if done {
+			return pauses
+		}
+	}
+}
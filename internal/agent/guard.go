@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/timeline"
+)
+
+// maxGuardTimeout caps how long a guard poll may take
+const maxGuardTimeout = 10 * time.Second
+
+// maxGuardResponse caps the response bodies guards read
+const maxGuardResponse = 1 << 20
+
+// runGuard polls g until ctx ends. A stop guard ends the run through stop
+// when it trips; a hold guard pauses the run until it clears. Failed polls
+// are logged to the timeline and do not trip the guard.
+func (a *Agent) runGuard(ctx context.Context, stop context.CancelCauseFunc, g *scenario.Guard) {
+	client := &http.Client{Timeout: min(g.Interval.Duration, maxGuardTimeout)}
+	ticker := time.NewTicker(g.Interval.Duration)
+	defer ticker.Stop()
+
+	var tripped, failing bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		value, err := pollGuard(ctx, client, g)
+		if err == nil {
+			tripped, err = a.checkGuard(g, value, tripped)
+		}
+		if err != nil {
+			if ctx.Err() == nil && !failing {
+				a.events.Add(timeline.GuardFailed, fmt.Sprintf("guard '%s': %v", g.Name, err),
+					map[string]string{"guard": g.Name})
+			}
+			failing = true
+			continue
+		}
+		failing = false
+
+		if tripped && g.Action == scenario.GuardStop {
+			stop(fmt.Errorf("guard '%s' tripped: %s %s", g.Name, formatSignal(value), g.Condition))
+			return
+		}
+	}
+}
+
+// checkGuard evaluates g against value and holds or releases the run when
+// a hold guard changes state. It returns whether g is tripped.
+func (a *Agent) checkGuard(g *scenario.Guard, value float64, tripped bool) (bool, error) {
+	trips, err := g.Trips(value)
+	if err != nil || trips == tripped {
+		return tripped, err
+	}
+
+	attrs := map[string]string{"guard": g.Name, "value": formatSignal(value)}
+	if !trips {
+		a.events.Add(timeline.GuardCleared, fmt.Sprintf("guard '%s' cleared at %s", g.Name, formatSignal(value)), attrs)
+		if g.Action == scenario.GuardHold {
+			a.release(guardHold(g))
+		}
+		return false, nil
+	}
+	a.events.Add(timeline.GuardTripped, fmt.Sprintf("guard '%s' tripped: %s %s, %s",
+		g.Name, formatSignal(value), g.Condition, g.Action), attrs)
+	if g.Action == scenario.GuardHold {
+		a.hold(guardHold(g))
+	}
+	return true, nil
+}
+
+// guardHold is the pause holder of the hold guard g
+func guardHold(g *scenario.Guard) string {
+	return "guard '" + g.Name + "'"
+}
+
+// pollGuard returns the current value of the signal of g
+func pollGuard(ctx context.Context, client *http.Client, g *scenario.Guard) (float64, error) {
+	target := g.URL
+	if g.Prometheus != "" {
+		target = strings.TrimSuffix(g.Prometheus, "/") + "/api/v1/query?query=" + url.QueryEscape(g.Query)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGuardResponse))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	switch {
+	case g.Prometheus != "":
+		return prometheusValue(body)
+	case g.Field != "":
+		value, err := jsonExtractor.Extract(body, g.Field)
+		if err != nil {
+			return 0, err
+		}
+		return parseSignal(stringify(value))
+	}
+	return parseSignal(string(body))
+}
+
+// prometheusValue returns the value of a scalar result of an instant
+// query, or that of the first sample of a vector result
+func prometheusValue(body []byte) (float64, error) {
+	var resp struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, fmt.Errorf("invalid Prometheus response: %w", err)
+	}
+	if resp.Status != "success" {
+		return 0, fmt.Errorf("Prometheus query failed: %s", resp.Error)
+	}
+
+	// A sample value is [<timestamp>, "<value>"]
+	var sample []any
+	switch resp.Data.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(resp.Data.Result, &sample); err != nil {
+			return 0, fmt.Errorf("invalid Prometheus result: %w", err)
+		}
+	case "vector":
+		var vector []struct {
+			Value []any `json:"value"`
+		}
+		if err := json.Unmarshal(resp.Data.Result, &vector); err != nil {
+			return 0, fmt.Errorf("invalid Prometheus result: %w", err)
+		}
+		if len(vector) == 0 {
+			return 0, fmt.Errorf("Prometheus query returned no samples")
+		}
+		sample = vector[0].Value
+	default:
+		return 0, fmt.Errorf("unsupported Prometheus result type %q", resp.Data.ResultType)
+	}
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("invalid Prometheus sample %v", sample)
+	}
+	return parseSignal(fmt.Sprint(sample[1]))
+}
+
+func parseSignal(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("signal %q is not a number", strings.TrimSpace(s))
+	}
+	return v, nil
+}
+
+func formatSignal(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
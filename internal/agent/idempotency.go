@@ -0,0 +1,314 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+)
+
+// IdempotencyResult counts the replays of a step whose response differed
+// from the response to the original request
+type IdempotencyResult struct {
+	Step    string
+	Compare string
+	// Replays counts the replays that got a response
+	Replays    int64
+	Violations int64
+	// Errors are the first distinct differences
+	Errors []string
+}
+
+// comparer compares the response to a replay with the original response,
+// returning how they differ or an empty string when they match
+type comparer interface {
+	compare(original, replay *executor.Response) string
+}
+
+// comparers build the comparer of each scenario.Idempotency compare
+var comparers = map[string]func(*scenario.Idempotency) comparer{
+	scenario.CompareJSON:   func(i *scenario.Idempotency) comparer { return newJSONComparer(i.Ignore) },
+	scenario.CompareBody:   func(*scenario.Idempotency) comparer { return bodyComparer{} },
+	scenario.CompareStatus: func(*scenario.Idempotency) comparer { return statusComparer{} },
+}
+
+type statusComparer struct{}
+
+func (statusComparer) compare(original, replay *executor.Response) string {
+	if original.StatusCode != replay.StatusCode {
+		return fmt.Sprintf("status %d, original %d", replay.StatusCode, original.StatusCode)
+	}
+	return ""
+}
+
+type bodyComparer struct{}
+
+func (bodyComparer) compare(original, replay *executor.Response) string {
+	if diff := (statusComparer{}).compare(original, replay); diff != "" {
+		return diff
+	}
+	if !bytes.Equal(original.Body, replay.Body) {
+		return "body differs"
+	}
+	return ""
+}
+
+// jsonComparer compares JSON bodies leaving out the ignored paths, split
+// into their keys
+type jsonComparer struct {
+	ignore [][]string
+}
+
+func newJSONComparer(ignore []string) *jsonComparer {
+	c := &jsonComparer{}
+	for _, path := range ignore {
+		c.ignore = append(c.ignore, strings.Split(path, "."))
+	}
+	return c
+}
+
+func (c *jsonComparer) compare(original, replay *executor.Response) string {
+	if diff := (statusComparer{}).compare(original, replay); diff != "" {
+		return diff
+	}
+	var a, b any
+	if json.Unmarshal(original.Body, &a) != nil || json.Unmarshal(replay.Body, &b) != nil {
+		return bodyComparer{}.compare(original, replay)
+	}
+	for _, path := range c.ignore {
+		a, b = removePath(a, path), removePath(b, path)
+	}
+	if path, ok := diffJSON(a, b, nil); ok {
+		if len(path) == 0 {
+			return "body differs"
+		}
+		return strings.Join(path, ".") + " differs"
+	}
+	return ""
+}
+
+// removePath returns v without the values at path, where * matches any
+// key or index
+func removePath(v any, path []string) any {
+	if len(path) == 0 {
+		return nil
+	}
+	key, rest := path[0], path[1:]
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, k)
+			} else {
+				v[k] = removePath(child, rest)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			if key != "*" && key != strconv.Itoa(i) {
+				continue
+			}
+			if len(rest) == 0 {
+				v[i] = nil
+			} else {
+				v[i] = removePath(child, rest)
+			}
+		}
+	}
+	return v
+}
+
+// diffJSON returns the path of the first value that differs between a and
+// b, in key order
+func diffJSON(a, b any, path []string) ([]string, bool) {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			return path, true
+		}
+		keys := slices.Sorted(maps.Keys(a))
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			if diff, ok := diffJSON(a[k], b[k], append(path, k)); ok {
+				return diff, true
+			}
+		}
+		return nil, false
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return path, true
+		}
+		for i := range a {
+			if diff, ok := diffJSON(a[i], b[i], append(path, strconv.Itoa(i))); ok {
+				return diff, true
+			}
+		}
+		return nil, false
+	}
+	return path, !reflect.DeepEqual(a, b)
+}
+
+// idempotencyTracker replays the requests of a step and counts the
+// replays whose response differs
+type idempotencyTracker struct {
+	step     string
+	spec     *scenario.Idempotency
+	comparer comparer
+
+	replays    atomic.Int64
+	violations atomic.Int64
+	errors     reasons
+}
+
+// newIdempotencyTrackers returns the trackers of the steps with an
+// idempotency check in step order, and by step
+func newIdempotencyTrackers(sc *scenario.Scenario) ([]*idempotencyTracker, map[string]*idempotencyTracker, error) {
+	var (
+		all    []*idempotencyTracker
+		byStep = make(map[string]*idempotencyTracker)
+	)
+	for i := range sc.Steps {
+		spec := sc.Steps[i].Idempotency
+		if spec == nil {
+			continue
+		}
+		newComparer, ok := comparers[spec.Compare]
+		if !ok {
+			return nil, nil, fmt.Errorf("step '%s', idempotency: unknown compare '%s'", sc.Steps[i].ID(), spec.Compare)
+		}
+		t := &idempotencyTracker{step: sc.Steps[i].ID(), spec: spec, comparer: newComparer(spec)}
+		all = append(all, t)
+		byStep[t.step] = t
+	}
+	return all, byStep, nil
+}
+
+// observe counts a replay whose response is resp, returning how it
+// differs from original
+func (t *idempotencyTracker) observe(original, resp *executor.Response) string {
+	t.replays.Add(1)
+	diff := t.comparer.compare(original, resp)
+	if diff != "" {
+		t.violations.Add(1)
+		t.errors.add([]string{diff})
+	}
+	return diff
+}
+
+func (t *idempotencyTracker) result() IdempotencyResult {
+	return IdempotencyResult{
+		Step:       t.step,
+		Compare:    t.spec.Compare,
+		Replays:    t.replays.Load(),
+		Violations: t.violations.Load(),
+		Errors:     t.errors.list(),
+	}
+}
+
+// IdempotencyState is the serializable content of an idempotency tracker
+type IdempotencyState struct {
+	Step       string   `json:"step"`
+	Replays    int64    `json:"replays"`
+	Violations int64    `json:"violations"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+func (t *idempotencyTracker) state() IdempotencyState {
+	return IdempotencyState{Step: t.step, Replays: t.replays.Load(), Violations: t.violations.Load(),
+		Errors: t.errors.list()}
+}
+
+func (t *idempotencyTracker) merge(state IdempotencyState) {
+	t.replays.Add(state.Replays)
+	t.violations.Add(state.Violations)
+	t.errors.add(state.Errors)
+}
+
+// replay sends the request of ex again as the idempotency check t of the
+// step specifies, recording the replays under their own metric and
+// comparing their responses with that of ex
+func (vu *virtualUser) replay(ctx context.Context, t *idempotencyTracker, ex *exchange) {
+	n := t.spec.Replays
+	responses := make([]*executor.Response, n)
+	errs := make([]error, n)
+	send := func(i int) {
+		if errs[i] = vu.throttle(ctx); errs[i] != nil {
+			return
+		}
+		req := *ex.request
+		responses[i], errs[i] = vu.exec.Execute(ctx, &req)
+	}
+	if t.spec.Concurrent {
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				send(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range n {
+			send(i)
+		}
+	}
+
+	metric := scenario.ReplayStep(ex.name)
+	for i, resp := range responses {
+		if errs[i] != nil {
+			if ctx.Err() == nil {
+				vu.fail(metric, errs[i])
+			}
+			continue
+		}
+		vu.agent.record(metrics.Sample{
+			Time:          time.Now(),
+			Step:          metric,
+			Status:        resp.StatusCode,
+			Duration:      resp.Duration,
+			Failed:        vu.failed(&exchange{name: ex.name, step: ex.step, response: resp}),
+			BytesSent:     requestSize(ex.request),
+			BytesReceived: resp.BodySize,
+			Scenario:      vu.flow.name,
+			Protocol:      vu.protocol(resp),
+			Labels:        vu.labels(resp),
+		})
+
+		diff := t.observe(ex.response, resp)
+		if vu.trace.enabled() {
+			passed := diff == ""
+			vu.trace.emit(TraceEvent{
+				Iteration: vu.iteration,
+				Event:     TraceReplay,
+				Step:      metric,
+				Status:    resp.StatusCode,
+				Duration:  resp.Duration,
+				Body:      string(resp.Body),
+				Passed:    &passed,
+				Error:     diff,
+			})
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"loadforge-agent/internal/scenario"
+)
+
+// identityDomain is the reserved domain of generated email addresses, so
+// nothing is ever delivered to them
+const identityDomain = "example.com"
+
+// newIdentity derives the ${vu.*} variables of the VU numbered id from
+// seed. The same seed and id always give the same identity.
+func newIdentity(seed uint64, id int) (map[string]string, error) {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:8], seed)
+	binary.LittleEndian.PutUint64(key[8:16], uint64(id))
+	rng := rand.NewChaCha8(key)
+
+	user, err := uuid.NewRandomFromReader(rng)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity of VU %d: %w", id, err)
+	}
+	device := make([]byte, 8)
+	rng.Read(device)
+
+	return map[string]string{
+		scenario.IdentityPrefix + "id":        strconv.Itoa(id),
+		scenario.IdentityPrefix + "uuid":      user.String(),
+		scenario.IdentityPrefix + "email":     fmt.Sprintf("vu%d-%s@%s", id, user.String()[:8], identityDomain),
+		scenario.IdentityPrefix + "device_id": hex.EncodeToString(device),
+	}, nil
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/secrets"
+	"loadforge-agent/internal/timeline"
+)
+
+// Setup runs the setup steps of sc once and returns the variables they
+// saved, which every VU and the teardown steps start with. It is for
+// coordinators of distributed runs; Run does this itself unless
+// Options.SkipLifecycle is set.
+func Setup(ctx context.Context, sc *scenario.Scenario) (map[string]string, error) {
+	if len(sc.Setup) == 0 {
+		return nil, nil
+	}
+	secretVars, redactor, err := resolveSecrets(sc)
+	if err != nil {
+		return nil, err
+	}
+	return runLifecycle(ctx, "setup", sc, sc.Setup, lifecycleVars(sc, nil, secretVars), redactor, nil)
+}
+
+// Teardown runs the teardown steps of sc once, with the variables saved
+// by Setup
+func Teardown(ctx context.Context, sc *scenario.Scenario, setupVars map[string]string) error {
+	if len(sc.Teardown) == 0 {
+		return nil
+	}
+	secretVars, redactor, err := resolveSecrets(sc)
+	if err != nil {
+		return err
+	}
+	_, err = runLifecycle(ctx, "teardown", sc, sc.Teardown, lifecycleVars(sc, setupVars, secretVars), redactor, nil)
+	return err
+}
+
+func lifecycleVars(sc *scenario.Scenario, setupVars, secretVars map[string]string) map[string]string {
+	vars := maps.Clone(sc.Variables)
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	maps.Copy(vars, setupVars)
+	maps.Copy(vars, secretVars)
+	return vars
+}
+
+// runLifecycle sends steps in order with their own cookie jar and returns
+// the variables they saved. Unlike VU iterations, a failed request, an
+// error status or a value that cannot be saved aborts the remaining
+// steps, since what follows depends on them. Requests are not recorded
+// in the run's metrics. In read-only runs, ro skips or rewrites mutating
+// steps.
+func runLifecycle(ctx context.Context, phase string, sc *scenario.Scenario, steps []scenario.Step,
+	vars map[string]string, redactor *secrets.Redactor, ro *readOnly) (map[string]string, error) {
+	exec, err := executor.NewWithTransport(transportOptions(sc))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", phase, err)
+	}
+	defer exec.Close()
+	subst := scenario.NewSubstitutor()
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+
+	saved := make(map[string]string)
+	for i := range steps {
+		step := &steps[i]
+		if step.When != "" {
+			cond, err := scenario.ParseCondition(step.When)
+			if err != nil {
+				return nil, fmt.Errorf("%s step '%s': %w", phase, step.ID(), err)
+			}
+			run, err := cond.Eval(vars)
+			if err != nil {
+				return nil, fmt.Errorf("%s step '%s': %w", phase, step.ID(), redactError(redactor, err))
+			}
+			if !run {
+				continue
+			}
+		}
+		var body *payloadBody
+		if step.Payload != nil {
+			if body, err = newPayloadBody(step.Payload); err != nil {
+				return nil, fmt.Errorf("%s step '%s': payload: %w", phase, step.ID(), err)
+			}
+		}
+		for j := range step.Repetitions() {
+			if step.Repeat > 0 {
+				vars[scenario.RepeatIndex] = strconv.Itoa(j)
+			}
+			send := *step
+			if !ro.allows(step, &send) {
+				ro.record(skippedRequest(sc.BaseURL, send, subst, vars))
+				continue
+			}
+			if err := runLifecycleStep(ctx, exec, subst, sc.BaseURL, &send, body, vars, saved); err != nil {
+				return nil, fmt.Errorf("%s step '%s': %w", phase, step.ID(), redactError(redactor, err))
+			}
+			if !step.Delay.IsZero() && !sleep(ctx, step.Delay.Sample(rng)) {
+				return nil, fmt.Errorf("%s: %w", phase, context.Cause(ctx))
+			}
+		}
+		delete(vars, scenario.RepeatIndex)
+	}
+	return saved, nil
+}
+
+func runLifecycleStep(ctx context.Context, exec *executor.Executor, subst *scenario.Substitutor, baseURL string,
+	step *scenario.Step, body *payloadBody, vars, saved map[string]string) error {
+	resolved, err := subst.ApplyToStep(*step, vars)
+	if err != nil {
+		return err
+	}
+	req, err := buildRequest(baseURL, resolved)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		body.apply(req)
+	}
+	resp, err := exec.Execute(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	vars[scenario.LastStatus] = strconv.Itoa(resp.StatusCode)
+
+	ex := &exchange{name: step.ID(), step: resolved, response: resp}
+	for name, ref := range step.SaveToContext {
+		value, err := lookup(ex, vars, ref)
+		if err != nil {
+			return fmt.Errorf("save_to_context %s: %w", name, err)
+		}
+		vars[name] = value
+		saved[name] = value
+	}
+	return nil
+}
+
+// setup runs the scenario's setup steps, unless the coordinator of a
+// distributed run already did
+func (a *Agent) setup(ctx context.Context) error {
+	if a.opts.SkipLifecycle {
+		a.setupVars = a.opts.SetupVars
+		return nil
+	}
+	if len(a.scenario.Setup) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	vars, err := runLifecycle(ctx, "setup", a.scenario, a.scenario.Setup,
+		lifecycleVars(a.scenario, nil, a.secrets), a.redactor, a.readOnly)
+	if err != nil {
+		return err
+	}
+	a.setupVars = vars
+	a.events.Add(timeline.SetupFinished, fmt.Sprintf("ran %d setup steps in %s",
+		len(a.scenario.Setup), time.Since(start).Round(time.Millisecond)), nil)
+	return nil
+}
+
+// teardown runs the scenario's teardown steps. They also run when the run
+// was aborted, to clean up after it; a failure is logged to the timeline
+// rather than discarding the run's results.
+func (a *Agent) teardown(parent context.Context) {
+	if a.opts.SkipLifecycle || len(a.scenario.Teardown) == 0 {
+		return
+	}
+
+	start := time.Now()
+	_, err := runLifecycle(context.WithoutCancel(parent), "teardown", a.scenario, a.scenario.Teardown,
+		lifecycleVars(a.scenario, a.setupVars, a.secrets), a.redactor, a.readOnly)
+	if err != nil {
+		a.events.Add(timeline.TeardownFailed, err.Error(), nil)
+		return
+	}
+	a.events.Add(timeline.TeardownFinished, fmt.Sprintf("ran %d teardown steps in %s",
+		len(a.scenario.Teardown), time.Since(start).Round(time.Millisecond)), nil)
+}
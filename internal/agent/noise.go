@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+)
+
+// noiseStep is the step name background noise requests are recorded under
+const noiseStep = "noise"
+
+// runNoise sends random GET requests across the noise URLs at the
+// configured rate until ctx ends, then waits for those in flight. Noise is
+// recorded into its own collector so it never skews the scenario's metrics
+// or thresholds.
+func runNoise(ctx context.Context, exec *executor.Executor, baseURL string,
+	noise *scenario.Noise, collector *metrics.Collector) {
+	urls := make([]string, len(noise.URLs))
+	for i, u := range noise.URLs {
+		if strings.HasPrefix(u, "/") {
+			u = strings.TrimSuffix(baseURL, "/") + u
+		}
+		urls[i] = u
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / noise.Rate))
+	defer ticker.Stop()
+
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Requests are fired asynchronously so a slow URL does not lower
+		// the noise rate.
+		target := urls[rand.IntN(len(urls))]
+		inflight.Add(1)
+		go func() {
+			defer inflight.Done()
+			resp, err := exec.Execute(ctx, &executor.Request{Method: http.MethodGet, URL: target})
+			switch {
+			case err != nil && ctx.Err() != nil:
+			case err != nil:
+				collector.Add(metrics.Sample{Time: time.Now(), Step: noiseStep, Err: err, Protocol: metrics.ProtocolHTTP})
+			default:
+				collector.Add(metrics.Sample{
+					Time:     time.Now(),
+					Step:     noiseStep,
+					Status:   resp.StatusCode,
+					Duration: resp.Duration,
+					Failed:   resp.StatusCode >= 400,
+					Protocol: metrics.HTTPProtocol(resp.Proto),
+				})
+			}
+		}()
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/pkg/ratelimit"
+)
+
+// defineRateLimits adds the scenario's rate limits to registry and returns
+// the bucket of every rate-limited step by step name. In a distributed
+// run each of the workers gets an even share of every limit.
+func defineRateLimits(sc *scenario.Scenario, opts Options, registry *ratelimit.Registry) (map[string]*ratelimit.Bucket, error) {
+	workers := max(opts.Workers, 1)
+
+	steps := make(map[string]*ratelimit.Bucket)
+	for i := range sc.Steps {
+		name := sc.Steps[i].RateLimit
+		if name == "" {
+			continue
+		}
+		limit := sc.RateLimits[name]
+		bucket, err := registry.Define(name, limit.Rate/float64(workers), max(limit.Burst/workers, 1))
+		if err != nil {
+			return nil, err
+		}
+		steps[sc.Steps[i].ID()] = bucket
+	}
+	return steps, nil
+}
+
+// defineMaxRPS adds the limits of the scenario's max_rps and of its flows'
+// to registry, as scenario.MaxRPSLimit and scenario.MaxRPSLimit.<flow>,
+// and returns the scenario's bucket, nil without max_rps. The buckets
+// hold a single token, so requests never exceed the rate, and are split
+// across workers like rate limits.
+func defineMaxRPS(sc *scenario.Scenario, opts Options, registry *ratelimit.Registry, flows []flow) (*ratelimit.Bucket, error) {
+	workers := float64(max(opts.Workers, 1))
+
+	// Weighted scenarios and flows share their indices
+	for i := range sc.Scenarios {
+		w := &sc.Scenarios[i]
+		if w.MaxRPS == 0 {
+			continue
+		}
+		bucket, err := registry.Define(scenario.MaxRPSLimit+"."+w.Name, w.MaxRPS/workers, 1)
+		if err != nil {
+			return nil, err
+		}
+		flows[i].maxRPS = bucket
+	}
+	if sc.MaxRPS == 0 {
+		return nil, nil
+	}
+	return registry.Define(scenario.MaxRPSLimit, sc.MaxRPS/workers, 1)
+}
+
+// throttle waits until the VU's flow and the scenario allow another
+// request under their max_rps
+func (vu *virtualUser) throttle(ctx context.Context) error {
+	if b := vu.flow.maxRPS; b != nil {
+		if err := b.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if b := vu.agent.maxRPS; b != nil {
+		return b.Wait(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/secrets"
+)
+
+// readOnly keeps the mutating requests a read-only run did not send. A nil
+// readOnly lets every step through.
+type readOnly struct {
+	redact  *secrets.Redactor
+	skipped atomic.Int64
+
+	mu sync.Mutex
+	// enc writes skipped requests to Options.ReadOnlyLog, nil without one
+	enc *json.Encoder
+}
+
+func newReadOnly(opts Options, redact *secrets.Redactor) *readOnly {
+	if !opts.ReadOnly {
+		return nil
+	}
+	r := &readOnly{redact: redact}
+	if opts.ReadOnlyLog != nil {
+		r.enc = json.NewEncoder(opts.ReadOnlyLog)
+	}
+	return r
+}
+
+// allows reports whether step, whose definition is def, may be sent,
+// rewriting it to the dry_run request of def when it is mutating
+func (r *readOnly) allows(def *scenario.Step, step *scenario.Step) bool {
+	if r == nil || !def.Mutating() {
+		return true
+	}
+	if def.DryRun == "" {
+		return false
+	}
+	step.Request = def.DryRun
+	return true
+}
+
+// record counts ev, the request of a skipped step, and writes it to the log
+func (r *readOnly) record(ev TraceEvent) {
+	r.skipped.Add(1)
+	if r.enc == nil {
+		return
+	}
+	ev.Time = time.Now()
+	ev.redact(r.redact)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(ev)
+}
+
+// skippedRequest returns the event of a request of step that was not sent,
+// with what would have been sent
+func skippedRequest(baseURL string, step scenario.Step, subst *scenario.Substitutor, vars map[string]string) TraceEvent {
+	ev := TraceEvent{Event: TraceSkipWrite, Step: step.ID()}
+	resolved, err := subst.ApplyToStep(step, vars)
+	if err != nil {
+		ev.Error = err.Error()
+		return ev
+	}
+	req, err := buildRequest(baseURL, resolved)
+	if err != nil {
+		ev.Error = err.Error()
+		return ev
+	}
+	ev.Method, ev.URL, ev.Headers = req.Method, req.URL, req.Headers
+	ev.Body = string(req.Body)
+	if req.Raw != nil {
+		ev.Body = string(req.Raw)
+	}
+	return ev
+}
+
+// skipWrite records step, whose definition is def, as not sent by a
+// read-only run
+func (vu *virtualUser) skipWrite(def *scenario.Step, step scenario.Step) {
+	ev := skippedRequest(vu.baseURL, step, vu.subst, vu.vars)
+	ev.Step = def.ID()
+	ev.Iteration = vu.iteration
+	vu.trace.emit(ev)
+	ev.VU = vu.id
+	vu.agent.readOnly.record(ev)
+}
@@ -0,0 +1,316 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/payload"
+	"loadforge-agent/internal/scenario"
+)
+
+// Payload shape defaults, see scenario.Payload
+const (
+	defaultPayloadDepth  = 1
+	defaultPayloadFields = 4
+)
+
+// payloadBody holds the bodies sent in place of a step's body. They are
+// generated or loaded once per run so that neither adds to measured
+// latency.
+type payloadBody struct {
+	bodies      [][]byte
+	contentType string
+	random      bool
+	// next is the index of the next body sent in order
+	next atomic.Uint64
+}
+
+// generatePayloads creates the bodies of the VUs' steps with a payload,
+// keyed by the name they are recorded under: the steps of every flow,
+// which sc.Steps holds once validated, and the login steps of the auth
+// pools. Setup and teardown steps get theirs from runLifecycle.
+func generatePayloads(sc *scenario.Scenario) (map[string]*payloadBody, error) {
+	bodies := make(map[string]*payloadBody)
+	add := func(name string, step *scenario.Step) error {
+		if step.Payload == nil {
+			return nil
+		}
+		body, err := newPayloadBody(step.Payload)
+		if err != nil {
+			return fmt.Errorf("step '%s': payload: %w", name, err)
+		}
+		bodies[name] = body
+		return nil
+	}
+
+	for i := range sc.Steps {
+		if err := add(sc.Steps[i].ID(), &sc.Steps[i]); err != nil {
+			return nil, err
+		}
+	}
+	for _, pool := range sc.AuthPools {
+		for i := range pool.Login {
+			if err := add(scenario.LoginStep(pool.Name, pool.Login[i].ID()), &pool.Login[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return bodies, nil
+}
+
+// newPayloadBody generates or loads the bodies of p
+func newPayloadBody(p *scenario.Payload) (*payloadBody, error) {
+	body := &payloadBody{bodies: make([][]byte, 1)}
+	var err error
+	switch {
+	case p.Source == scenario.PayloadFile:
+		path := p.ResolvedPath
+		if path == "" {
+			path = p.Path
+		}
+		body.bodies, err = payload.LoadFile(path)
+		body.contentType = "application/json"
+		body.random = p.Order == scenario.PayloadRandom
+	case p.Kind == scenario.PayloadJSON:
+		depth, fields := p.Depth, p.Fields
+		if depth == 0 {
+			depth = defaultPayloadDepth
+		}
+		if fields == 0 {
+			fields = defaultPayloadFields
+		}
+		body.bodies[0], err = payload.JSON(p.Size.Bytes, depth, fields)
+		body.contentType = "application/json"
+	default:
+		body.bodies[0], err = payload.Binary(p.Size.Bytes)
+		body.contentType = "application/octet-stream"
+	}
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// apply sets the body of req, keeping a Content-Type set by the step
+func (b *payloadBody) apply(req *executor.Request) {
+	switch {
+	case len(b.bodies) == 1:
+		req.Body = b.bodies[0]
+	case b.random:
+		req.Body = b.bodies[rand.IntN(len(b.bodies))]
+	default:
+		req.Body = b.bodies[(b.next.Add(1)-1)%uint64(len(b.bodies))]
+	}
+	if !hasHeader(req.Headers, "Content-Type") {
+		req.Headers["Content-Type"] = b.contentType
+	}
+}
+
+// buildRequest turns a fully substituted step into an executor request
+// against baseURL.
+func buildRequest(baseURL string, step scenario.Step) (*executor.Request, error) {
+	method, path, err := scenario.ParseRequest(step.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range step.PathParams {
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+
+	target := strings.TrimSuffix(baseURL, "/") + path
+
+	if step.Raw != "" {
+		return &executor.Request{
+			Method:  method,
+			URL:     target,
+			Raw:     rawRequest(step.Raw),
+			Timeout: step.Timeout.Duration,
+		}, nil
+	}
+
+	if len(step.Query) > 0 {
+		query := url.Values{}
+		for k, v := range step.Query {
+			query.Set(k, v)
+		}
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + query.Encode()
+	}
+
+	headers := make(map[string]string, len(step.Headers)+1)
+	for k, v := range step.Headers {
+		headers[k] = v
+	}
+
+	if step.BodyFile != "" {
+		return fileRequest(method, target, headers, step)
+	}
+	if step.Multipart != nil {
+		return multipartRequest(method, target, headers, step)
+	}
+
+	var body []byte
+	switch b := step.Body.(type) {
+	case nil:
+	case string:
+		body = []byte(b)
+	case []byte:
+		body = b
+	default:
+		body, err = json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal body: %w", err)
+		}
+		if !hasHeader(headers, "Content-Type") {
+			headers["Content-Type"] = "application/json"
+		}
+	}
+
+	return &executor.Request{
+		Method:  method,
+		URL:     target,
+		Headers: headers,
+		Body:    body,
+		Timeout: step.Timeout.Duration,
+	}, nil
+}
+
+// fileRequest builds a request streaming the body_file of a step, opened
+// anew for each attempt
+func fileRequest(method, target string, headers map[string]string, step scenario.Step) (*executor.Request, error) {
+	info, err := os.Stat(step.BodyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body_file: %w", err)
+	}
+	if !hasHeader(headers, "Content-Type") {
+		headers["Content-Type"] = "application/octet-stream"
+	}
+	path := step.BodyFile
+	return &executor.Request{
+		Method:  method,
+		URL:     target,
+		Headers: headers,
+		BodyReader: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+		ContentLength: info.Size(),
+		Timeout:       step.Timeout.Duration,
+	}, nil
+}
+
+// multipartRequest builds a request sending the multipart body of a step,
+// its files streamed from disk
+func multipartRequest(method, target string, headers map[string]string, step scenario.Step) (*executor.Request, error) {
+	m := &executor.Multipart{Fields: step.Multipart.Fields}
+	for _, f := range step.Multipart.Files {
+		m.Files = append(m.Files, executor.MultipartFile{
+			Field:       f.Field,
+			Path:        f.Path,
+			Filename:    f.Filename,
+			ContentType: f.ContentType,
+		})
+	}
+	req := &executor.Request{
+		Method:  method,
+		URL:     target,
+		Headers: headers,
+		Timeout: step.Timeout.Duration,
+	}
+	if err := req.SetMultipart(m); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// requestSize is the size of the body of a request, streamed or not
+func requestSize(req *executor.Request) int64 {
+	if req.BodyReader != nil {
+		return req.ContentLength
+	}
+	return int64(len(req.Body))
+}
+
+// rawRequest turns the raw request of a step into the bytes sent: the line
+// endings of the request line and headers become CRLF, and a blank line
+// ends the headers if the request has none, since YAML block scalars drop
+// trailing blank lines. The body after the blank line is sent as is, so that
+// its Content-Length holds.
+func rawRequest(raw string) []byte {
+	var b strings.Builder
+	for raw != "" {
+		line, rest, _ := strings.Cut(raw, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		b.WriteString(line + "\r\n")
+		raw = rest
+		if line == "" {
+			b.WriteString(raw)
+			return []byte(b.String())
+		}
+	}
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// setCookie adds name=value to the Cookie header in headers.
+func setCookie(headers map[string]string, name, value string) {
+	cookie := (&http.Cookie{Name: name, Value: value}).String()
+	for k, v := range headers {
+		if strings.EqualFold(k, "Cookie") {
+			headers[k] = v + "; " + cookie
+			return
+		}
+	}
+	headers["Cookie"] = cookie
+}
+
+// nextStep returns the first next_steps entry whose status codes match
+// status, or nil when the iteration should move on without branching.
+// When the entries have weights, one of the matching entries is picked
+// with rng in proportion to its weight instead.
+func nextStep(step *scenario.Step, status int, rng *rand.Rand) *scenario.NextStep {
+	var (
+		picked *scenario.NextStep
+		total  float64
+	)
+	for i := range step.NextSteps {
+		next := &step.NextSteps[i]
+		if len(next.StatusCodes) > 0 &&
+			!slices.ContainsFunc(next.StatusCodes, func(code string) bool {
+				return scenario.MatchStatus(code, status)
+			}) {
+			continue
+		}
+		if next.Weight == 0 {
+			return next
+		}
+		// Weighted reservoir sampling picks each match with probability
+		// weight/total in a single pass
+		total += next.Weight
+		if rng.Float64()*total < next.Weight {
+			picked = next
+		}
+	}
+	return picked
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"loadforge-agent/internal/diag"
+	"loadforge-agent/internal/timeline"
+)
+
+const (
+	// saturationPollInterval is how often scheduling latency is measured
+	saturationPollInterval = time.Second
+	// saturationIntervals is the number of consecutive saturated intervals
+	// that count as saturation, so a single GC cycle does not
+	saturationIntervals = 3
+	// profileDuration is how long the CPU profile of a saturated agent runs
+	profileDuration = 10 * time.Second
+)
+
+// watchSaturation measures the agent's scheduling latency until ctx ends.
+// The first time the agent stays saturated the timeline is told, since the
+// run then partly measures the agent, and profiles are captured to
+// Options.ProfileDir if set. It returns the paths of the profiles.
+func (a *Agent) watchSaturation(ctx context.Context) []string {
+	sampler := diag.NewSchedSampler()
+	ticker := time.NewTicker(saturationPollInterval)
+	defer ticker.Stop()
+
+	saturated := 0
+	for saturated < saturationIntervals {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		latency := sampler.Sample()
+		if latency < diag.SaturationLatency {
+			saturated = 0
+			continue
+		}
+		saturated++
+		if saturated == saturationIntervals {
+			a.events.Add(timeline.Saturated,
+				fmt.Sprintf("p99 scheduling latency %s for %ds, the agent cannot keep up with its load",
+					latency, saturationIntervals), map[string]string{"active_vus": fmt.Sprint(a.active.Load())})
+		}
+	}
+
+	if a.opts.ProfileDir == "" {
+		return nil
+	}
+	profiles, err := diag.CaptureProfiles(ctx, a.opts.ProfileDir, profileDuration)
+	if err != nil {
+		a.events.Add(timeline.Saturated, fmt.Sprintf("profiling failed: %v", err), nil)
+	}
+	for _, p := range profiles {
+		a.events.Add(timeline.Saturated, "captured profile", map[string]string{"file": p})
+	}
+	return profiles
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/timeline"
+)
+
+// ErrNotRunning is returned when scaling an agent whose run has ended
+var ErrNotRunning = errors.New("run has ended")
+
+// Scale changes the VU count of the run to n. VUs that are added start
+// right away, or with the run if it has not started yet; VUs numbered
+// above n idle once their current iteration finishes, and continue if the
+// run is scaled up again.
+func (a *Agent) Scale(n int) error {
+	if n < 1 {
+		return fmt.Errorf("VU count must be at least 1, got %d", n)
+	}
+	if a.data != nil && a.data.strategy == scenario.DataUnique && n > a.data.rows.Len() {
+		return fmt.Errorf("cannot scale to %d VUs, the data file has %d rows for the %s strategy",
+			n, a.data.rows.Len(), scenario.DataUnique)
+	}
+
+	a.scaleMu.Lock()
+	defer a.scaleMu.Unlock()
+	if a.ended {
+		return ErrNotRunning
+	}
+	if a.spawn != nil {
+		if err := a.startVUs(n); err != nil {
+			return err
+		}
+	}
+
+	prev := a.vus
+	a.vus = n
+	close(a.scaled)
+	a.scaled = make(chan struct{})
+	a.events.Add(timeline.Scaled, fmt.Sprintf("scaled from %d to %d VUs", prev, n),
+		map[string]string{"vus": strconv.Itoa(n)})
+	return nil
+}
+
+// VUs returns the VU count of the run, which Scale may have changed from
+// the scenario's
+func (a *Agent) VUs() int {
+	a.scaleMu.Lock()
+	defer a.scaleMu.Unlock()
+	return a.vus
+}
+
+// startVUs starts the VUs numbered up to n that were not started yet. The
+// caller holds scaleMu.
+func (a *Agent) startVUs(n int) error {
+	for a.spawned < n {
+		if err := a.spawn(a.spawned + 1); err != nil {
+			return err
+		}
+		a.spawned++
+	}
+	return nil
+}
+
+// waitScaled blocks while the VU numbered id is beyond the VU count. It
+// reports false if ctx ended first.
+func (a *Agent) waitScaled(ctx context.Context, id int) bool {
+	for {
+		a.scaleMu.Lock()
+		vus, scaled := a.vus, a.scaled
+		a.scaleMu.Unlock()
+		if id <= vus {
+			return true
+		}
+
+		// An idle VU is not active
+		a.active.Add(-1)
+		select {
+		case <-ctx.Done():
+			a.active.Add(1)
+			return false
+		case <-scaled:
+			a.active.Add(1)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/secrets"
+)
+
+// resolveSecrets looks up the scenario's secrets and returns them as
+// ${secrets.<name>} variables, with a Redactor masking their values
+func resolveSecrets(sc *scenario.Scenario) (map[string]string, *secrets.Redactor, error) {
+	if len(sc.Secrets) == 0 {
+		return nil, nil, nil
+	}
+
+	values, err := secrets.Resolve(context.Background(), sc.Secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars := make(map[string]string, len(values))
+	for name, value := range values {
+		vars[scenario.SecretPrefix+name] = value
+	}
+	return vars, secrets.NewRedactor(values), nil
+}
+
+// redactedError masks secrets in the message of err while keeping it
+// available to errors.Is and errors.As, so failures are still classified
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+func redactError(r *secrets.Redactor, err error) error {
+	if r == nil || err == nil {
+		return err
+	}
+	msg := r.String(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
+
+// redact masks secrets in everything ev carries
+func (ev *TraceEvent) redact(r *secrets.Redactor) {
+	if r == nil {
+		return
+	}
+	ev.URL = r.String(ev.URL)
+	ev.Body = r.String(ev.Body)
+	ev.Value = r.String(ev.Value)
+	ev.Error = r.String(ev.Error)
+	if ev.Previous != nil {
+		prev := r.String(*ev.Previous)
+		ev.Previous = &prev
+	}
+	if len(ev.Headers) > 0 {
+		headers := make(map[string]string, len(ev.Headers))
+		for k, v := range ev.Headers {
+			headers[k] = r.String(v)
+		}
+		ev.Headers = headers
+	}
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"loadforge-agent/internal/netstat"
+	"loadforge-agent/internal/timeline"
+)
+
+// socketPollInterval is how often socket usage is sampled
+const socketPollInterval = time.Second
+
+// watchSockets samples the host's socket usage until ctx ends and returns
+// the peak, or nil where socket telemetry is unsupported. Usage nearing a
+// limit is reported to the timeline once per limit, since exhaustion
+// otherwise only shows up as dial errors.
+func (a *Agent) watchSockets(ctx context.Context) *netstat.Stats {
+	peak, err := netstat.Read()
+	if err != nil {
+		return nil
+	}
+
+	var portsWarned, filesWarned bool
+	ticker := time.NewTicker(socketPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return &peak
+		case <-ticker.C:
+		}
+
+		s, err := netstat.Read()
+		if err != nil {
+			continue
+		}
+		peak = peak.Max(s)
+
+		if !portsWarned && s.EphemeralUsage() >= netstat.WarnRatio {
+			portsWarned = true
+			a.events.Add(timeline.SocketPressure,
+				fmt.Sprintf("%d of %d ephemeral ports in use (%d in TIME_WAIT), new connections may fail",
+					s.EphemeralInUse, s.EphemeralPorts, s.TimeWait), nil)
+		}
+		if !filesWarned && s.FileUsage() >= netstat.WarnRatio {
+			filesWarned = true
+			a.events.Add(timeline.SocketPressure,
+				fmt.Sprintf("%d of %d open files in use, new connections may fail", s.OpenFiles, s.FileLimit), nil)
+		}
+	}
+}
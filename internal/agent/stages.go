@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/timeline"
+)
+
+// gateInterval is the window gate conditions are evaluated over
+const gateInterval = time.Second
+
+// stage is a scenario stage with its gate conditions parsed
+type stage struct {
+	vus      int
+	duration time.Duration
+	gate     []*threshold.Threshold
+	hold     time.Duration
+}
+
+// newStages parses the stages of sc, and returns the window gates are
+// evaluated on, nil if no stage has a gate
+func newStages(sc *scenario.Scenario, opts Options) ([]stage, *metrics.Window, error) {
+	var (
+		stages = make([]stage, 0, len(sc.Stages))
+		window *metrics.Window
+	)
+	for i := range sc.Stages {
+		s := &sc.Stages[i]
+		st := stage{vus: int(s.VUs), duration: s.Duration.Duration}
+		if s.Gate != nil {
+			for _, expr := range s.Gate.Conditions {
+				t, err := threshold.Parse(expr)
+				if err != nil {
+					return nil, nil, fmt.Errorf("stage %d gate: %w", i+1, err)
+				}
+				st.gate = append(st.gate, t)
+			}
+			st.hold = s.Gate.For.Duration
+		}
+		if st.gate != nil && window == nil {
+			var err error
+			if window, err = metrics.NewWindow(opts.Estimator, opts.Compression); err != nil {
+				return nil, nil, err
+			}
+		}
+		stages = append(stages, st)
+	}
+	return stages, window, nil
+}
+
+// runStages moves the run through its stages until the last one or until
+// ctx ends. The first stage's VU count is the run's initial one.
+func (a *Agent) runStages(ctx context.Context) {
+	for i, st := range a.stages {
+		if i > 0 {
+			if err := a.Scale(st.vus); err != nil {
+				// The run ended, or the count was already checked against
+				// the data file when the agent was created
+				return
+			}
+		}
+		a.events.Add(timeline.StageStarted, fmt.Sprintf("stage %d/%d: %d VUs for %s",
+			i+1, len(a.stages), st.vus, st.duration), map[string]string{
+			"stage": strconv.Itoa(i + 1),
+			"vus":   strconv.Itoa(st.vus),
+		})
+
+		if !sleep(ctx, st.duration) {
+			return
+		}
+		if st.gate != nil && !a.awaitGate(ctx, i, st) {
+			return
+		}
+	}
+}
+
+// awaitGate blocks until every gate condition of the stage numbered i
+// passed over consecutive intervals spanning its hold duration. The run
+// stays at the stage's VU count meanwhile. It reports false if ctx ended
+// first.
+func (a *Agent) awaitGate(ctx context.Context, i int, st stage) bool {
+	ticker := time.NewTicker(gateInterval)
+	defer ticker.Stop()
+
+	// Only what happens from now on counts
+	last := time.Now()
+	a.gateWindow.Flush(0)
+
+	var (
+		held    time.Duration
+		blocked bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case now := <-ticker.C:
+			total := a.gateWindow.Flush(now.Sub(last)).Total
+			held += now.Sub(last)
+			last = now
+
+			if failed, ok := failedCondition(st.gate, total); !ok {
+				held = 0
+				if !blocked {
+					blocked = true
+					a.events.Add(timeline.GateBlocked, fmt.Sprintf("stage %d gate: %s not met (actual %s), holding at %d VUs",
+						i+1, failed.Expr, failed.Actual, st.vus), map[string]string{
+						"stage":     strconv.Itoa(i + 1),
+						"condition": failed.Expr,
+						"actual":    failed.Actual,
+					})
+				}
+				continue
+			}
+			if held < st.hold {
+				continue
+			}
+			if blocked {
+				a.events.Add(timeline.GateCleared, fmt.Sprintf("stage %d gate held for %s", i+1, held.Round(time.Second)),
+					map[string]string{"stage": strconv.Itoa(i + 1)})
+			}
+			return true
+		}
+	}
+}
+
+// stageChanges returns the stage starts recorded in events
+func stageChanges(events []timeline.Event) []metrics.StageChange {
+	var changes []metrics.StageChange
+	for _, e := range events {
+		if e.Kind != timeline.StageStarted {
+			continue
+		}
+		stage, _ := strconv.Atoi(e.Attrs["stage"])
+		vus, _ := strconv.Atoi(e.Attrs["vus"])
+		changes = append(changes, metrics.StageChange{Time: e.Time, Stage: stage, VUs: vus})
+	}
+	return changes
+}
+
+// failedCondition returns the first condition not met by stats
+func failedCondition(conditions []*threshold.Threshold, stats metrics.StepStats) (threshold.Result, bool) {
+	for _, t := range conditions {
+		if r := t.Evaluate(stats); !r.Passed {
+			return r, false
+		}
+	}
+	return threshold.Result{}, true
+}
@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/timeline"
+	"loadforge-agent/internal/version"
+)
+
+// State is the serializable outcome of a run. A coordinator combines the
+// states of its workers into a single Result with Merge.
+type State struct {
+	Iterations int64                   `json:"iterations"`
+	Duration   time.Duration           `json:"duration_ns"`
+	Metrics    *metrics.CollectorState `json:"metrics"`
+	Noise      *metrics.CollectorState `json:"noise,omitempty"`
+	Budgets    []BudgetState           `json:"budgets,omitempty"`
+	Checks     []CheckState            `json:"checks,omitempty"`
+	Contracts  []ContractState         `json:"contracts,omitempty"`
+	// Idempotency holds the idempotency checks in step order
+	Idempotency []IdempotencyState `json:"idempotency,omitempty"`
+	// AgentVersion is the version.Label of the agent that ran
+	AgentVersion string `json:"agent_version,omitempty"`
+}
+
+// State captures everything recorded by the agent, normally after Run
+// has returned
+func (a *Agent) State() (*State, error) {
+	collected, err := a.collector.State()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{
+		Iterations: a.iterations.Load(),
+		Duration:   a.elapsed,
+		Metrics:    collected,
+
+		AgentVersion: version.Label(),
+	}
+
+	if a.noise != nil {
+		if state.Noise, err = a.noise.State(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, b := range a.budgets {
+		budget, err := b.state()
+		if err != nil {
+			return nil, err
+		}
+		state.Budgets = append(state.Budgets, budget)
+	}
+
+	for _, c := range a.checks {
+		state.Checks = append(state.Checks, c.state())
+	}
+
+	for _, c := range a.contracts {
+		state.Contracts = append(state.Contracts, c.state())
+	}
+
+	for _, t := range a.idempotency {
+		state.Idempotency = append(state.Idempotency, t.state())
+	}
+
+	return state, nil
+}
+
+// recorder holds what a run records: its metrics, the trackers of its
+// checks and budgets, and its timeline. Merge combines the states of runs
+// into one without the rest of an Agent.
+type recorder struct {
+	collector  *metrics.Collector
+	noise      *metrics.Collector
+	thresholds *threshold.Set
+	budgets    []*budgetTracker
+	checks     []*checkTracker
+	stepChecks map[string][]*checkTracker
+	contracts  []*contractTracker
+	contractOf map[string]*contractTracker
+	// idempotency holds the idempotency checks in step order and
+	// idempotencyOf by step
+	idempotency   []*idempotencyTracker
+	idempotencyOf map[string]*idempotencyTracker
+
+	outliers   *metrics.OutlierTracker
+	events     *timeline.Log
+	iterations atomic.Int64
+	started    time.Time
+	elapsed    time.Duration
+	gcPauses   []metrics.GCPause
+}
+
+func newRecorder(sc *scenario.Scenario, opts Options) (*recorder, error) {
+	collector, err := metrics.NewCollector(opts.Estimator, opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	stepThresholds := make(map[string][]string)
+	stepOrder := make([]string, 0, len(sc.Steps))
+	for i := range sc.Steps {
+		id := sc.Steps[i].ID()
+		collector.Register(id)
+		if sc.Steps[i].Retry != nil {
+			collector.Register(scenario.RetryStep(id))
+		}
+		if sc.Steps[i].Idempotency != nil {
+			collector.Register(scenario.ReplayStep(id))
+		}
+		stepThresholds[id] = sc.Steps[i].Thresholds
+		stepOrder = append(stepOrder, id)
+	}
+	for _, pool := range sc.AuthPools {
+		for i := range pool.Login {
+			collector.Register(scenario.LoginStep(pool.Name, pool.Login[i].ID()))
+		}
+	}
+
+	thresholds, err := threshold.NewSet(sc.Thresholds, stepThresholds, stepOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	budgets, err := newBudgetTrackers(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	checks, stepChecks, err := newCheckTrackers(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	contracts, contractOf, err := newContractTrackers(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotency, idempotencyOf, err := newIdempotencyTrackers(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	var noise *metrics.Collector
+	if sc.Noise != nil {
+		// The estimator was already validated by the main collector
+		noise, _ = metrics.NewCollector(opts.Estimator, opts.Compression)
+		noise.Register(noiseStep)
+	}
+
+	events := opts.Timeline
+	if events == nil {
+		events = timeline.New(nil)
+	}
+
+	return &recorder{
+		collector:     collector,
+		noise:         noise,
+		thresholds:    thresholds,
+		budgets:       budgets,
+		checks:        checks,
+		stepChecks:    stepChecks,
+		contracts:     contracts,
+		contractOf:    contractOf,
+		idempotency:   idempotency,
+		idempotencyOf: idempotencyOf,
+		outliers:      metrics.NewOutlierTracker(metrics.DefaultOutlierKeep),
+		events:        events,
+	}, nil
+}
+
+// Merge combines the states of runs of sc into one Result, evaluating
+// thresholds against the combined metrics. The run duration is that of the
+// longest run. opts must use the estimator the states were recorded with.
+// Merge only reads sc: it neither opens its data nor resolves its secrets.
+func Merge(sc *scenario.Scenario, opts Options, states []*State) (*Result, error) {
+	r, err := newRecorder(sc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, state := range states {
+		if err := r.merge(state); err != nil {
+			return nil, fmt.Errorf("failed to merge run %d: %w", i, err)
+		}
+	}
+	return r.result(nil), nil
+}
+
+func (r *recorder) merge(state *State) error {
+	r.iterations.Add(state.Iterations)
+	r.elapsed = max(r.elapsed, state.Duration)
+
+	if state.Metrics != nil {
+		if err := r.collector.Merge(state.Metrics); err != nil {
+			return err
+		}
+	}
+
+	if r.noise != nil && state.Noise != nil {
+		if err := r.noise.Merge(state.Noise); err != nil {
+			return fmt.Errorf("noise: %w", err)
+		}
+	}
+
+	if len(state.Budgets) != len(r.budgets) {
+		return fmt.Errorf("expected %d budgets, got %d", len(r.budgets), len(state.Budgets))
+	}
+	for i, b := range r.budgets {
+		if state.Budgets[i].Name != b.name {
+			return fmt.Errorf("expected budget '%s', got '%s'", b.name, state.Budgets[i].Name)
+		}
+		if err := b.merge(state.Budgets[i]); err != nil {
+			return fmt.Errorf("budget '%s': %w", b.name, err)
+		}
+	}
+
+	if len(state.Checks) != len(r.checks) {
+		return fmt.Errorf("expected %d checks, got %d", len(r.checks), len(state.Checks))
+	}
+	for i, c := range r.checks {
+		if state.Checks[i].Step != c.step || state.Checks[i].Name != c.name {
+			return fmt.Errorf("expected check '%s' of step '%s', got '%s' of '%s'",
+				c.name, c.step, state.Checks[i].Name, state.Checks[i].Step)
+		}
+		c.merge(state.Checks[i])
+	}
+
+	if len(state.Contracts) != len(r.contracts) {
+		return fmt.Errorf("expected %d contracts, got %d", len(r.contracts), len(state.Contracts))
+	}
+	for i, c := range r.contracts {
+		if state.Contracts[i].Step != c.step {
+			return fmt.Errorf("expected contract of step '%s', got '%s'", c.step, state.Contracts[i].Step)
+		}
+		c.merge(state.Contracts[i])
+	}
+
+	if len(state.Idempotency) != len(r.idempotency) {
+		return fmt.Errorf("expected %d idempotency checks, got %d", len(r.idempotency), len(state.Idempotency))
+	}
+	for i, t := range r.idempotency {
+		if state.Idempotency[i].Step != t.step {
+			return fmt.Errorf("expected the idempotency check of step '%s', got '%s'", t.step, state.Idempotency[i].Step)
+		}
+		t.merge(state.Idempotency[i])
+	}
+	return nil
+}
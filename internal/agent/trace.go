@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/secrets"
+)
+
+// Trace event kinds written by the flight recorder
+const (
+	TraceIterationStart = "iteration_start"
+	TraceRequest        = "request"
+	TraceResponse       = "response"
+	TraceError          = "error"
+	TraceVariable       = "variable"
+	TraceBranch         = "branch"
+	TraceSkip           = "skip"
+	TraceCheck          = "check"
+	TraceSkipWrite      = "skip_write"
+	TraceReplay         = "replay"
+)
+
+// TraceEvent is a single entry in a VU flight recorder trace
+type TraceEvent struct {
+	Time      time.Time         `json:"time"`
+	VU        int               `json:"vu"`
+	Iteration uint64            `json:"iteration"`
+	Event     string            `json:"event"`
+	Step      string            `json:"step,omitempty"`
+	Method    string            `json:"method,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      string            `json:"body,omitempty"`
+	Status    int               `json:"status,omitempty"`
+	Duration  time.Duration     `json:"duration_ns,omitempty"`
+	// Proto is the protocol of a response, e.g. HTTP/2.0
+	Proto     string  `json:"proto,omitempty"`
+	Variable  string  `json:"variable,omitempty"`
+	Value     string  `json:"value,omitempty"`
+	Previous  *string `json:"previous,omitempty"`
+	Next      string  `json:"next,omitempty"`
+	Condition string  `json:"condition,omitempty"`
+	Check     string  `json:"check,omitempty"`
+	Passed    *bool   `json:"passed,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// tracer writes the flight recorder narrative for one VU as JSON lines.
+// A nil tracer discards everything, so untraced VUs pay no cost.
+type tracer struct {
+	vu     int
+	redact *secrets.Redactor
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newTracer(vu int, w io.Writer, redact *secrets.Redactor) *tracer {
+	return &tracer{vu: vu, redact: redact, enc: json.NewEncoder(w)}
+}
+
+func (t *tracer) emit(ev TraceEvent) {
+	if t == nil {
+		return
+	}
+
+	ev.Time = time.Now()
+	ev.VU = t.vu
+	ev.redact(t.redact)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_ = t.enc.Encode(ev)
+}
+
+func (t *tracer) enabled() bool {
+	return t != nil
+}
@@ -0,0 +1,641 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"loadforge-agent/internal/executor"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+)
+
+// errNotSent is returned by execute when a step was not sent or its
+// request was abandoned, which is not retried
+var errNotSent = errors.New("request not sent")
+
+// virtualUser executes scenario iterations back to back. Each VU has its
+// own cookie jar and variable context, so VUs never share session state.
+type virtualUser struct {
+	id    int
+	agent *Agent
+	flow  *flow
+	exec  *executor.Executor
+	subst *scenario.Substitutor
+	trace *tracer
+
+	vars      map[string]string
+	iteration uint64
+	// identity holds the ${vu.*} variables, kept across iterations
+	identity map[string]string
+	// rng picks weighted next_steps, seeded like identity so runs with the
+	// same seed take the same branches
+	rng *rand.Rand
+	// stepTime accumulates response time per step within an iteration
+	stepTime map[string]time.Duration
+	// overBudget is the budget a request of this iteration was cancelled
+	// over, nil if none was
+	overBudget *budgetTracker
+	// baseURL is the base URL of this iteration's requests and baseName
+	// its name, empty unless the scenario has several base URLs
+	baseURL  string
+	baseName string
+
+	// pool is the VU's auth pool, nil without auth pools. credentials
+	// holds its ${auth.*} variables and session the variables its login
+	// steps saved at loggedIn, nil until it logged in.
+	pool        *authPool
+	credentials map[string]string
+	session     map[string]string
+	loggedIn    time.Time
+}
+
+func newVirtualUser(id int, a *Agent, f *flow, tr *tracer) (*virtualUser, error) {
+	opts := transportOptions(a.scenario)
+	opts.Retry = a.retry
+	exec, err := executor.NewWithTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := newIdentity(a.seed, a.opts.FirstVU+id)
+	if err != nil {
+		return nil, err
+	}
+	pool, credentials := a.poolOf(id)
+
+	return &virtualUser{
+		id:    id,
+		agent: a,
+		flow:  f,
+		exec:  exec,
+		subst: scenario.NewSubstitutor(),
+		trace: tr,
+		vars:  make(map[string]string),
+
+		identity: identity,
+		rng:      rand.New(rand.NewPCG(a.seed, uint64(a.opts.FirstVU+id))),
+		stepTime: make(map[string]time.Duration),
+		baseURL:  a.scenario.BaseURL,
+
+		pool:        pool,
+		credentials: credentials,
+	}, nil
+}
+
+// run executes iterations until ctx ends. Requests are sent with requests,
+// which outlives ctx while in-flight requests drain.
+func (vu *virtualUser) run(ctx, requests context.Context) {
+	vu.agent.active.Add(1)
+	defer vu.agent.active.Add(-1)
+	defer vu.exec.Close()
+
+	for ctx.Err() == nil {
+		if !vu.agent.waitScaled(ctx, vu.id) || !vu.agent.waitResumed(ctx) {
+			return
+		}
+		vu.iteration++
+		clear(vu.stepTime)
+		vu.overBudget = nil
+		vu.chooseBaseURL()
+		vu.runIteration(ctx, requests)
+		if ctx.Err() == nil {
+			vu.agent.iterations.Add(1)
+			for _, b := range vu.agent.budgets {
+				b.observe(vu.stepTime, b == vu.overBudget)
+			}
+		}
+	}
+}
+
+// runIteration executes steps starting from the first one. After each step
+// the first next_steps entry matching the response status, or a weighted
+// pick among the matching entries, is followed; a step without next_steps
+// falls through to the step after it, and a step whose next_steps all fail
+// to match ends the iteration.
+func (vu *virtualUser) runIteration(ctx, requests context.Context) {
+	steps := vu.agent.scenario.Steps
+
+	// Variables saved in a previous iteration do not leak into the next one
+	clear(vu.vars)
+	maps.Copy(vu.vars, vu.agent.scenario.Variables)
+	maps.Copy(vu.vars, vu.flow.variables)
+	maps.Copy(vu.vars, vu.agent.setupVars)
+	maps.Copy(vu.vars, vu.identity)
+	maps.Copy(vu.vars, vu.agent.secrets)
+
+	vu.trace.emit(TraceEvent{Iteration: vu.iteration, Event: TraceIterationStart})
+
+	if !vu.login(ctx, requests) {
+		return
+	}
+
+	idx := vu.flow.first
+	if !vu.feed(steps[idx].ID()) {
+		return
+	}
+	step := cloneStep(steps[idx])
+
+	for ctx.Err() == nil {
+		def := &steps[idx]
+		run, ok := vu.when(def)
+		if !ok {
+			return
+		}
+		if run && !vu.agent.readOnly.allows(def, &step) {
+			vu.skipWrite(def, step)
+			run = false
+		}
+		if !run {
+			idx++
+			if idx >= vu.flow.end {
+				return
+			}
+			step = cloneStep(steps[idx])
+			continue
+		}
+
+		ex, ok := vu.runStep(ctx, requests, def, step)
+		if !ok {
+			return
+		}
+
+		next := nextStep(def, ex.response.StatusCode, vu.rng)
+		switch {
+		case next != nil:
+			idx = vu.agent.stepIndex[next.Target()]
+			vu.trace.emit(TraceEvent{
+				Iteration: vu.iteration,
+				Event:     TraceBranch,
+				Step:      def.ID(),
+				Status:    ex.response.StatusCode,
+				Next:      steps[idx].ID(),
+			})
+
+			step = cloneStep(steps[idx])
+			if !vu.applyMap(ex, steps[idx].ID(), &step, next.Map) {
+				return
+			}
+		case len(def.NextSteps) > 0:
+			vu.trace.emit(TraceEvent{
+				Iteration: vu.iteration,
+				Event:     TraceBranch,
+				Step:      def.ID(),
+				Status:    ex.response.StatusCode,
+			})
+			return
+		default:
+			idx++
+			if idx >= vu.flow.end {
+				return
+			}
+			step = cloneStep(steps[idx])
+		}
+	}
+}
+
+// when reports whether the condition of def, if any, holds. A condition
+// that cannot be evaluated is recorded as a failure of the step and
+// reported as false ok.
+func (vu *virtualUser) when(def *scenario.Step) (run, ok bool) {
+	cond, conditional := vu.agent.conditions[def.ID()]
+	if !conditional {
+		return true, true
+	}
+	run, err := cond.Eval(vu.vars)
+	if err != nil {
+		vu.fail(def.ID(), err)
+		return false, false
+	}
+	if !run {
+		vu.trace.emit(TraceEvent{
+			Iteration: vu.iteration,
+			Event:     TraceSkip,
+			Step:      def.ID(),
+			Condition: cond.Expr,
+		})
+	}
+	return run, true
+}
+
+// check evaluates the checks of the step named step against its exchange
+func (vu *virtualUser) check(step string, ex *exchange) {
+	for _, c := range vu.agent.stepChecks[step] {
+		passed, err := c.eval(ex, vu.vars)
+		if !vu.trace.enabled() {
+			continue
+		}
+		ev := TraceEvent{
+			Iteration: vu.iteration,
+			Event:     TraceCheck,
+			Step:      step,
+			Check:     c.name,
+			Condition: c.expr,
+			Passed:    &passed,
+		}
+		if err != nil {
+			ev.Error = err.Error()
+		}
+		vu.trace.emit(ev)
+	}
+}
+
+// runStep sends step, whose definition is def, as many times as def
+// repeats it and returns the last exchange. It reports false when the
+// iteration must stop.
+func (vu *virtualUser) runStep(ctx, requests context.Context, def *scenario.Step, step scenario.Step) (*exchange, bool) {
+	var ex *exchange
+	for i := range def.Repetitions() {
+		if def.Repeat > 0 {
+			vu.vars[scenario.RepeatIndex] = strconv.Itoa(i)
+		}
+
+		// Waiting for the rate limit ends with the run, unlike requests,
+		// which may drain
+		if bucket, ok := vu.agent.limited[def.ID()]; ok && bucket.Wait(ctx) != nil {
+			return nil, false
+		}
+
+		var ok bool
+		if ex, ok = vu.send(ctx, requests, def, step); !ok {
+			return nil, false
+		}
+		vu.vars[scenario.LastStatus] = strconv.Itoa(ex.response.StatusCode)
+
+		vu.check(def.ID(), ex)
+		if c, ok := vu.agent.contractOf[def.ID()]; ok {
+			c.validate(ex.response)
+		}
+		if t, ok := vu.agent.idempotencyOf[def.ID()]; ok {
+			vu.replay(requests, t, ex)
+		}
+		vu.saveToContext(ex, def.SaveToContext)
+
+		if !def.Delay.IsZero() && !sleep(ctx, def.Delay.Sample(vu.rng)) {
+			return nil, false
+		}
+	}
+	delete(vu.vars, scenario.RepeatIndex)
+	return ex, true
+}
+
+// send executes step, retrying it as def's retry policy allows, and
+// returns the last exchange. It reports false when no attempt produced a
+// response.
+func (vu *virtualUser) send(ctx, requests context.Context, def *scenario.Step, step scenario.Step) (*exchange, bool) {
+	for attempt := 0; ; attempt++ {
+		// Like rate limits, max_rps ends with the run
+		if vu.throttle(ctx) != nil {
+			return nil, false
+		}
+		ex, err := vu.execute(requests, def.ID(), step, attempt)
+		if def.Retry == nil || attempt == def.Retry.Max || !shouldRetry(def.Retry, ex, err) {
+			return ex, err == nil
+		}
+		// Backing off ends with the run, like delays
+		if !sleep(ctx, def.Retry.Delay(attempt+1)) {
+			return nil, false
+		}
+	}
+}
+
+// shouldRetry reports whether the outcome of an attempt matches one of the
+// conditions of r
+func shouldRetry(r *scenario.Retry, ex *exchange, err error) bool {
+	if errors.Is(err, errNotSent) {
+		return false
+	}
+	for _, cond := range r.On {
+		switch {
+		case err == nil:
+			if scenario.MatchStatus(cond, ex.response.StatusCode) {
+				return true
+			}
+		case cond == scenario.RetryOnError:
+			return true
+		case cond == scenario.RetryOnTimeout:
+			if metrics.ErrorKind(metrics.Sample{Err: err}) == "timeout" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// feed sets the columns of the VU's data row for this iteration as
+// variables. A row that cannot be read is recorded as a failure of step.
+func (vu *virtualUser) feed(step string) bool {
+	if vu.agent.data == nil {
+		return true
+	}
+
+	record, err := vu.agent.data.record(vu.id)
+	if err != nil {
+		vu.fail(step, err)
+		return false
+	}
+	for column, value := range record {
+		vu.vars[scenario.DataPrefix+column] = value
+	}
+	return true
+}
+
+// execute substitutes, sends and records step under name, or under its
+// retry name from the second attempt on. It returns the error of a request
+// that produced no response, or errNotSent when the step was not sent or
+// was abandoned; either way the iteration cannot go on with it.
+func (vu *virtualUser) execute(ctx context.Context, name string, step scenario.Step, attempt int) (*exchange, error) {
+	metric := name
+	if attempt > 0 {
+		metric = scenario.RetryStep(name)
+	}
+
+	resolved, err := vu.subst.ApplyToStep(step, vu.vars)
+	if err != nil {
+		vu.fail(metric, err)
+		return nil, errNotSent
+	}
+
+	req, err := buildRequest(vu.baseURL, resolved)
+	if err != nil {
+		vu.fail(metric, err)
+		return nil, errNotSent
+	}
+	if body, ok := vu.agent.payloads[name]; ok {
+		body.apply(req)
+	}
+	if body := cmp.Or(resolved.ResponseBody, vu.agent.scenario.ResponseBody); body != nil {
+		req.MaxBodySize, req.DiscardBody = int64(body.MaxSize.Bytes), body.Discard
+	}
+
+	if vu.trace.enabled() {
+		body := req.Body
+		if req.Raw != nil {
+			body = req.Raw
+		}
+		vu.trace.emit(TraceEvent{
+			Iteration: vu.iteration,
+			Event:     TraceRequest,
+			Step:      metric,
+			Method:    req.Method,
+			URL:       req.URL,
+			Headers:   req.Headers,
+			Body:      string(body),
+		})
+	}
+
+	reqCtx := ctx
+	budget, left := vu.budgetFor(name)
+	if budget != nil {
+		if left <= 0 {
+			vu.cancelOverBudget(name, metric, budget, 0)
+			return nil, errNotSent
+		}
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeoutCause(ctx, left, metrics.ErrOverBudget)
+		defer cancel()
+	}
+
+	resp, err := vu.exec.Execute(reqCtx, req)
+	if vu.agent.debug.sampled(metric) {
+		vu.agent.debug.log(vu.id, vu.iteration, metric, req, resp, err)
+	}
+	if retries := (*executor.RetriesError)(nil); errors.As(err, &retries) {
+		vu.recordRetried(name, metric, retries.Retried)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// The request was abandoned when the run ended; this is not a
+			// target failure
+			return nil, errNotSent
+		}
+		if budget != nil && errors.Is(context.Cause(reqCtx), metrics.ErrOverBudget) {
+			vu.cancelOverBudget(name, metric, budget, left)
+			return nil, errNotSent
+		}
+		vu.fail(metric, err)
+		return nil, err
+	}
+
+	ex := &exchange{name: name, step: resolved, response: resp, request: req}
+	vu.stepTime[name] += resp.Duration
+	vu.recordRetried(name, metric, resp.Retried)
+	for i, hop := range resp.Redirects {
+		vu.stepTime[name] += hop.Duration
+		vu.agent.record(metrics.Sample{
+			Time:     time.Now(),
+			Step:     redirectStep(metric, i),
+			Status:   hop.StatusCode,
+			Duration: hop.Duration,
+			Scenario: vu.flow.name,
+			Protocol: vu.protocol(resp),
+		})
+	}
+	vu.agent.record(metrics.Sample{
+		Time:          time.Now(),
+		Step:          metric,
+		Status:        resp.StatusCode,
+		Duration:      resp.Duration,
+		Failed:        vu.failed(ex),
+		BytesSent:     requestSize(req),
+		BytesReceived: resp.BodySize,
+		Scenario:      vu.flow.name,
+		Protocol:      vu.protocol(resp),
+		Labels:        vu.labels(resp),
+	})
+
+	if vu.trace.enabled() {
+		headers := make(map[string]string, len(resp.Headers))
+		for k, v := range resp.Headers {
+			if len(v) > 0 {
+				headers[k] = v[0]
+			}
+		}
+		vu.trace.emit(TraceEvent{
+			Iteration: vu.iteration,
+			Event:     TraceResponse,
+			Step:      metric,
+			Status:    resp.StatusCode,
+			Duration:  resp.Duration,
+			Proto:     resp.Proto,
+			Headers:   headers,
+			Body:      string(resp.Body),
+		})
+	}
+
+	return ex, nil
+}
+
+// recordRetried records the attempts of a request of step, reported under
+// metric, that transport_retry retried, under their own metric
+func (vu *virtualUser) recordRetried(step, metric string, attempts []executor.Attempt) {
+	for _, attempt := range attempts {
+		vu.stepTime[step] += attempt.Duration
+		vu.agent.record(metrics.Sample{
+			Time:     time.Now(),
+			Step:     scenario.TransportRetryStep(metric),
+			Status:   attempt.StatusCode,
+			Duration: attempt.Duration,
+			Failed:   attempt.Err == nil,
+			Err:      redactError(vu.agent.redactor, attempt.Err),
+			Scenario: vu.flow.name,
+			Protocol: vu.protocol(nil),
+		})
+	}
+}
+
+// redirectStep returns the metric name of the i-th redirect followed by
+// the requests of metric, e.g. "login redirect 1"
+func redirectStep(metric string, i int) string {
+	return fmt.Sprintf("%s redirect %d", metric, i+1)
+}
+
+// protocol returns the protocol resp was received over, or when the
+// request failed, nil resp, the one the scenario's HTTP version forces
+func (vu *virtualUser) protocol(resp *executor.Response) string {
+	if resp != nil {
+		return metrics.HTTPProtocol(resp.Proto)
+	}
+	switch vu.agent.scenario.HTTPVersion {
+	case scenario.HTTPVersion1:
+		return metrics.ProtocolHTTP1
+	case scenario.HTTPVersion2:
+		return metrics.ProtocolHTTP2
+	}
+	return metrics.ProtocolHTTP
+}
+
+// responseLabels returns the values of the label headers present in resp
+func responseLabels(headers []string, resp *executor.Response) map[string]string {
+	var labels map[string]string
+	for _, header := range headers {
+		values := resp.Headers[header]
+		if len(values) == 0 {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(headers))
+		}
+		labels[header] = values[0]
+	}
+	return labels
+}
+
+// budgetFor returns the cancelling budget of step with the least time left
+// in this iteration, or nil when no such budget covers step
+func (vu *virtualUser) budgetFor(step string) (*budgetTracker, time.Duration) {
+	var (
+		budget *budgetTracker
+		least  time.Duration
+	)
+	for _, b := range vu.agent.budgets {
+		if !b.cancel {
+			continue
+		}
+		left, ok := b.remaining(step, vu.stepTime)
+		if ok && (budget == nil || left < least) {
+			budget, least = b, left
+		}
+	}
+	return budget, least
+}
+
+// cancelOverBudget records a request of step cancelled after spent, when
+// budget ran out, under metric and marks the iteration as over budget
+func (vu *virtualUser) cancelOverBudget(step, metric string, budget *budgetTracker, spent time.Duration) {
+	vu.stepTime[step] += spent
+	vu.overBudget = budget
+	vu.fail(metric, fmt.Errorf("%w: budget '%s' of %s spent", metrics.ErrOverBudget, budget.name, budget.max))
+}
+
+func (vu *virtualUser) fail(step string, err error) {
+	err = redactError(vu.agent.redactor, err)
+	vu.agent.record(metrics.Sample{Time: time.Now(), Step: step, Err: err, Scenario: vu.flow.name,
+		Protocol: vu.protocol(nil)})
+	vu.trace.emit(TraceEvent{
+		Iteration: vu.iteration,
+		Event:     TraceError,
+		Step:      step,
+		Error:     err.Error(),
+	})
+}
+
+// saveToContext stores the values referenced by save_to_context as VU
+// variables. Missing values are traced and skipped.
+func (vu *virtualUser) saveToContext(ex *exchange, save map[string]string) {
+	for name, ref := range save {
+		value, err := lookup(ex, vu.vars, ref)
+		if err != nil {
+			vu.trace.emit(TraceEvent{
+				Iteration: vu.iteration,
+				Event:     TraceError,
+				Step:      ex.name,
+				Variable:  name,
+				Error:     err.Error(),
+			})
+			continue
+		}
+		vu.setVar(ex.name, name, value)
+	}
+}
+
+// applyMap copies values from ex into the next step according to a
+// next_steps map. A missing source value ends the iteration, since the
+// next request would be sent without the data it depends on.
+func (vu *virtualUser) applyMap(ex *exchange, nextName string, next *scenario.Step, mapping map[string]string) bool {
+	for source, target := range mapping {
+		value, err := lookup(ex, vu.vars, source)
+		if err == nil {
+			if name, ok := variableTarget(target); ok {
+				vu.setVar(ex.name, name, value)
+				continue
+			}
+			err = assign(next, vu.vars, target, value)
+		}
+		if err != nil {
+			vu.fail(nextName, err)
+			return false
+		}
+	}
+	return true
+}
+
+func (vu *virtualUser) setVar(step, name, value string) {
+	if vu.trace.enabled() {
+		ev := TraceEvent{
+			Iteration: vu.iteration,
+			Event:     TraceVariable,
+			Step:      step,
+			Variable:  name,
+			Value:     value,
+		}
+		if prev, ok := vu.vars[name]; ok {
+			ev.Previous = &prev
+		}
+		vu.trace.emit(ev)
+	}
+	vu.vars[name] = value
+}
+
+func variableTarget(target string) (string, bool) {
+	name, ok := strings.CutPrefix(target, "variables.")
+	return name, ok && name != ""
+}
+
+// sleep waits for d and reports false if ctx ended first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
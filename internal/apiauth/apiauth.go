@@ -0,0 +1,100 @@
+// Package apiauth guards the APIs the agent serves to other machines, the
+// control API of agent serve and the worker API of agent worker: clients
+// must send a shared token, or present a certificate when mutual TLS is
+// configured.
+package apiauth
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// EnvToken is the environment variable the token is read from when no
+// token file is given
+const EnvToken = "LOADFORGE_AGENT_TOKEN"
+
+// MinTokenLength is the length tokens must have at least, so they cannot
+// be guessed
+const MinTokenLength = 16
+
+// bearerPrefix starts the Authorization header carrying the token
+const bearerPrefix = "Bearer "
+
+// ErrNoToken is returned by LoadToken when neither a token file nor
+// EnvToken is set
+var ErrNoToken = errors.New("no token: pass a token file or set " + EnvToken)
+
+// LoadToken reads the token from the file at path, or from EnvToken when
+// path is empty. A trailing newline is not part of the token.
+func LoadToken(path string) (string, error) {
+	token := os.Getenv(EnvToken)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token = strings.TrimRight(string(data), "\r\n")
+	}
+	if token == "" {
+		return "", ErrNoToken
+	}
+	if len(token) < MinTokenLength {
+		return "", fmt.Errorf("token must be at least %d characters long", MinTokenLength)
+	}
+	return token, nil
+}
+
+// Header returns the Authorization header value carrying token
+func Header(token string) string {
+	return bearerPrefix + token
+}
+
+// Valid reports whether the Authorization header value authorization
+// carries token. An empty token never matches.
+func Valid(authorization, token string) bool {
+	got, ok := strings.CutPrefix(authorization, bearerPrefix)
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// Handler responds 401 Unauthorized to requests to h whose Authorization
+// header does not carry token
+func Handler(h http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Valid(r.Header.Get("Authorization"), token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ServerTLS returns the TLS configuration of a server presenting the
+// certificate in certFile and keyFile. When clientCAFile is set, clients
+// must present a certificate signed by one of its CAs.
+func ServerTLS(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile != "" {
+		data, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("client CA file %s has no PEM certificates", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
@@ -0,0 +1,90 @@
+package apiauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadToken(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	if err := os.WriteFile(file, []byte("from-the-token-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvToken, "")
+	if _, err := LoadToken(""); !errors.Is(err, ErrNoToken) {
+		t.Errorf("expected ErrNoToken, got %v", err)
+	}
+
+	t.Setenv(EnvToken, "from-the-environment")
+	if token, err := LoadToken(""); err != nil || token != "from-the-environment" {
+		t.Errorf("expected the token of the environment, got %q (%v)", token, err)
+	}
+	if token, err := LoadToken(file); err != nil || token != "from-the-token-file" {
+		t.Errorf("expected the token of the file, got %q (%v)", token, err)
+	}
+	if _, err := LoadToken(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing token file")
+	}
+
+	t.Setenv(EnvToken, "short")
+	if _, err := LoadToken(""); err == nil {
+		t.Error("expected an error for a short token")
+	}
+}
+
+func TestValid(t *testing.T) {
+	const token = "0123456789abcdef"
+	tests := []struct {
+		authorization string
+		token         string
+		want          bool
+	}{
+		{Header(token), token, true},
+		{token, token, false},
+		{"Bearer 0123456789abcdeX", token, false},
+		{"Basic " + token, token, false},
+		{"", token, false},
+		{Header(""), "", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.authorization, tt.token); got != tt.want {
+			t.Errorf("Valid(%q, %q) = %v, want %v", tt.authorization, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	const token = "0123456789abcdef"
+	server := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), token))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		authorization string
+		want          int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer not-the-token-at-all", http.StatusUnauthorized},
+		{Header(token), http.StatusNoContent},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/tests", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("authorization %q: expected %d, got %d", tt.authorization, tt.want, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package artifacts packages the evidence of a run into a single file for
+// sharing and archiving: a .tar.gz bundle, or a self-contained HTML report
+// embedding the same files.
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/report"
+	"loadforge-agent/internal/scenario"
+)
+
+// Files of a bundle
+const (
+	ScenarioFile = "scenario.yaml"
+	ConfigFile   = "config.yaml"
+	SummaryFile  = "summary.json"
+	ReportFile   = "report.html"
+	FailuresFile = "failures.jsonl"
+	EventsFile   = "events.log"
+)
+
+// Bundle formats, selected by the extension of the file written
+const (
+	FormatTarGz = "tar.gz"
+	FormatHTML  = "html"
+)
+
+// Bundle is the evidence of one run
+type Bundle struct {
+	// Source is the scenario file as written
+	Source []byte
+	// Scenario is the scenario as resolved by the agent, with includes
+	// merged and defaults filled in
+	Scenario *scenario.Scenario
+	Result   *agent.Result
+	// Failures holds the failed requests kept during the run, nil if they
+	// were not recorded
+	Failures *FailureRecorder
+}
+
+// Format returns the format of a bundle written to path
+func Format(path string) (string, error) {
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		return FormatTarGz, nil
+	case strings.HasSuffix(path, ".html"), strings.HasSuffix(path, ".htm"):
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("bundle %s must end in .tar.gz, .tgz or .html", path)
+}
+
+// WriteFile writes b to path in the format its extension selects
+func (b *Bundle) WriteFile(path string) error {
+	format, err := Format(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	if format == FormatHTML {
+		err = b.WriteHTML(f)
+	} else {
+		err = b.WriteTarGz(f)
+	}
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to write bundle: %w", cerr)
+	}
+	return err
+}
+
+// WriteTarGz writes b as a gzipped tarball of the bundle files
+func (b *Bundle) WriteTarGz(w io.Writer) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	var html bytes.Buffer
+	if err := report.WriteHTML(&html, b.Scenario.Name, b.Result); err != nil {
+		return err
+	}
+	files = append(files, report.Attachment{Name: ReportFile, Content: html.Bytes()})
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    f.Name,
+			Mode:    0o644,
+			Size:    int64(len(f.Content)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Name, err)
+		}
+		if _, err := tw.Write(f.Content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// WriteHTML writes b as an HTML report with the other bundle files
+// embedded in it
+func (b *Bundle) WriteHTML(w io.Writer) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	return report.WriteHTML(w, b.Scenario.Name, b.Result, files...)
+}
+
+// files renders every bundle file but the HTML report
+func (b *Bundle) files() ([]report.Attachment, error) {
+	config, err := yaml.Marshal(b.Scenario)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode resolved scenario: %w", err)
+	}
+
+	var summary bytes.Buffer
+	if err := report.WriteJSON(&summary, b.Scenario.Name, b.Result); err != nil {
+		return nil, err
+	}
+
+	var events bytes.Buffer
+	for _, e := range b.Result.Events {
+		fmt.Fprintln(&events, e)
+	}
+
+	files := []report.Attachment{
+		{Name: ScenarioFile, Content: b.Source},
+		{Name: ConfigFile, Content: config},
+		{Name: SummaryFile, Content: summary.Bytes()},
+		{Name: EventsFile, Content: events.Bytes()},
+	}
+
+	if b.Failures != nil {
+		var failures bytes.Buffer
+		enc := json.NewEncoder(&failures)
+		kept, _ := b.Failures.Failures()
+		for _, f := range kept {
+			if err := enc.Encode(f); err != nil {
+				return nil, fmt.Errorf("failed to encode failures: %w", err)
+			}
+		}
+		files = append(files, report.Attachment{Name: FailuresFile, Content: failures.Bytes()})
+	}
+	return files, nil
+}
@@ -0,0 +1,126 @@
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/timeline"
+)
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	source := []byte(`
+name: checkout
+base_url: http://localhost:8080
+virtual_users: 2
+duration: 10
+steps:
+  - request: GET /items
+`)
+	parser := scenario.NewParser()
+	if err := parser.ParseData(source); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	if err := parser.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	sc, err := parser.GetScenario()
+	if err != nil {
+		t.Fatalf("GetScenario() failed: %v", err)
+	}
+
+	failures := NewFailureRecorder(0)
+	failures.Add(metrics.Sample{Step: "GET /items", Status: 503, Failed: true})
+	return &Bundle{
+		Source:   source,
+		Scenario: sc,
+		Result: &agent.Result{
+			Iterations: 3,
+			Duration:   time.Second,
+			Metrics:    metrics.Summary{Total: metrics.StepStats{Name: "total", Requests: 3, Errors: 1}},
+			Events:     []timeline.Event{{Time: time.Now(), Kind: timeline.RunStarted, Message: "started 2 VUs"}},
+		},
+		Failures: failures,
+	}
+}
+
+func TestBundle_WriteTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBundle(t).WriteTarGz(&buf); err != nil {
+		t.Fatalf("WriteTarGz() failed: %v", err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid tar: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(content)
+	}
+
+	wants := map[string]string{
+		ScenarioFile: "name: checkout",
+		ConfigFile:   "virtual_users: 2",
+		SummaryFile:  `"scenario": "checkout"`,
+		ReportFile:   "<title>checkout load test report</title>",
+		FailuresFile: `"status":503`,
+		EventsFile:   "run_started: started 2 VUs",
+	}
+	for name, want := range wants {
+		if !strings.Contains(files[name], want) {
+			t.Errorf("expected %s to contain %q, got %q", name, want, files[name])
+		}
+	}
+	if len(files) != len(wants) {
+		t.Errorf("expected %d files, got %d", len(wants), len(files))
+	}
+}
+
+func TestBundle_WriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBundle(t).WriteHTML(&buf); err != nil {
+		t.Fatalf("WriteHTML() failed: %v", err)
+	}
+	for _, name := range []string{ScenarioFile, ConfigFile, SummaryFile, FailuresFile, EventsFile} {
+		if !strings.Contains(buf.String(), "<summary>"+name+"</summary>") {
+			t.Errorf("expected %s to be embedded in the report", name)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "run.tar.gz", want: FormatTarGz},
+		{path: "out/run.tgz", want: FormatTarGz},
+		{path: "run.html", want: FormatHTML},
+		{path: "run.zip", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Format(tt.path)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Format(%q) = %q, %v, want %q, error %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package artifacts
+
+import (
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+)
+
+// DefaultMaxFailures is how many failed requests a FailureRecorder keeps
+// unless told otherwise
+const DefaultMaxFailures = 100
+
+// Failure is a failed request kept as evidence
+type Failure struct {
+	Time       time.Time `json:"time"`
+	Step       string    `json:"step"`
+	Status     int       `json:"status,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// FailureRecorder is a metrics.SampleSink keeping the first failed
+// requests of a run, safe for concurrent use
+type FailureRecorder struct {
+	max int
+
+	mu       sync.Mutex
+	failures []Failure
+	total    int64
+}
+
+// NewFailureRecorder creates a FailureRecorder keeping up to max failures,
+// DefaultMaxFailures if max is not positive
+func NewFailureRecorder(max int) *FailureRecorder {
+	if max <= 0 {
+		max = DefaultMaxFailures
+	}
+	return &FailureRecorder{max: max}
+}
+
+// Add keeps s if it failed and fewer than the maximum were kept
+func (r *FailureRecorder) Add(s metrics.Sample) {
+	if !s.Failed && s.Err == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total++
+	if len(r.failures) >= r.max {
+		return
+	}
+	f := Failure{
+		Time:       s.Time,
+		Step:       s.Step,
+		Status:     s.Status,
+		DurationMS: float64(s.Duration) / float64(time.Millisecond),
+	}
+	if s.Err != nil {
+		f.Error = s.Err.Error()
+	}
+	r.failures = append(r.failures, f)
+}
+
+// Close does nothing, failures are kept in memory
+func (r *FailureRecorder) Close() error {
+	return nil
+}
+
+// Failures returns the failures kept and the number of failed requests,
+// including those that were not kept
+func (r *FailureRecorder) Failures() ([]Failure, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Failure(nil), r.failures...), r.total
+}
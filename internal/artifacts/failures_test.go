@@ -0,0 +1,31 @@
+package artifacts
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+)
+
+func TestFailureRecorder(t *testing.T) {
+	r := NewFailureRecorder(2)
+	r.Add(metrics.Sample{Step: "GET /ok", Status: 200})
+	r.Add(metrics.Sample{Step: "GET /missing", Status: 404, Failed: true, Duration: 1500 * time.Microsecond})
+	r.Add(metrics.Sample{Step: "GET /down", Failed: true, Err: errors.New("connection refused")})
+	r.Add(metrics.Sample{Step: "GET /down", Failed: true, Err: errors.New("connection refused")})
+
+	failures, total := r.Failures()
+	if total != 3 {
+		t.Errorf("expected 3 failed requests, got %d", total)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures to be kept, got %+v", failures)
+	}
+	if f := failures[0]; f.Step != "GET /missing" || f.Status != 404 || f.DurationMS != 1.5 {
+		t.Errorf("unexpected first failure %+v", f)
+	}
+	if f := failures[1]; f.Error != "connection refused" {
+		t.Errorf("expected the error of the second failure, got %+v", f)
+	}
+}
@@ -0,0 +1,534 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/apiauth"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/timeline"
+	"loadforge-agent/internal/version"
+)
+
+// testToken is the secret the workers and coordinators of the tests share
+const testToken = "0123456789abcdef"
+
+func TestSplitVUs(t *testing.T) {
+	tests := []struct {
+		total   uint64
+		n       int
+		want    []uint64
+		wantErr bool
+	}{
+		{total: 10, n: 1, want: []uint64{10}},
+		{total: 10, n: 2, want: []uint64{5, 5}},
+		{total: 10, n: 3, want: []uint64{4, 3, 3}},
+		{total: 3, n: 3, want: []uint64{1, 1, 1}},
+		{total: 2, n: 3, wantErr: true},
+		{total: 2, n: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitVUs(tt.total, tt.n)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitVUs(%d, %d) error = %v, wantErr %v", tt.total, tt.n, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SplitVUs(%d, %d) = %v, want %v", tt.total, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestShareOf(t *testing.T) {
+	tests := []struct{ vus, share, total, want uint64 }{
+		{vus: 10, share: 5, total: 10, want: 5},
+		{vus: 5, share: 4, total: 10, want: 2},
+		{vus: 1, share: 3, total: 10, want: 1},
+		{vus: 10, share: 3, total: 10, want: 3},
+	}
+	for _, tt := range tests {
+		if got := shareOf(tt.vus, tt.share, tt.total); got != tt.want {
+			t.Errorf("shareOf(%d, %d, %d) = %d, want %d", tt.vus, tt.share, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestNewCoordinator_NormalizesAddresses(t *testing.T) {
+	c, err := NewCoordinator([]string{"10.0.0.2:7070", " https://w2:7070/ "}, testToken)
+	if err != nil {
+		t.Fatalf("NewCoordinator() failed: %v", err)
+	}
+	want := []string{"http://10.0.0.2:7070", "https://w2:7070"}
+	if !slices.Equal(c.Workers, want) {
+		t.Errorf("expected workers %v, got %v", want, c.Workers)
+	}
+
+	if _, err := NewCoordinator([]string{"a:1", ""}, testToken); err == nil {
+		t.Error("expected error for empty worker address, got nil")
+	}
+}
+
+func parseScenario(t *testing.T, data []byte) *scenario.Scenario {
+	t.Helper()
+	parser := scenario.NewParser()
+	if err := parser.ParseData(data); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	sc, err := parser.GetScenario()
+	if err != nil {
+		t.Fatalf("GetScenario() failed: %v", err)
+	}
+	return sc
+}
+
+func TestCoordinator_RunMergesWorkers(t *testing.T) {
+	var hits atomic.Int64
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(time.Millisecond)
+	}))
+	defer target.Close()
+
+	var workers []string
+	for range 2 {
+		w := httptest.NewServer(NewWorker(testToken).Handler())
+		defer w.Close()
+		workers = append(workers, w.URL)
+	}
+
+	data := []byte(fmt.Sprintf(`
+name: distributed
+base_url: %s
+virtual_users: 3
+duration: 60
+thresholds:
+  - "error_rate < 1%%"
+steps:
+  - request: GET /ping
+`, target.URL))
+	sc := parseScenario(t, data)
+
+	c, err := NewCoordinator(workers, testToken)
+	if err != nil {
+		t.Fatalf("NewCoordinator() failed: %v", err)
+	}
+
+	// Cancelling stops the workers, which still report their metrics
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := c.Run(ctx, data, sc, agent.Options{})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("workers were not stopped when the context was cancelled")
+	}
+
+	// Requests in flight when the workers stop reach the target but are
+	// not recorded
+	total := result.Metrics.Total
+	if total.Requests == 0 || total.Requests > hits.Load() {
+		t.Errorf("expected merged requests to be at most %d target hits, got %d", hits.Load(), total.Requests)
+	}
+	if result.Iterations == 0 {
+		t.Error("expected merged iterations, got 0")
+	}
+	if len(result.Metrics.Steps) != 1 || result.Metrics.Steps[0].Requests != total.Requests {
+		t.Errorf("expected merged per-step metrics, got %+v", result.Metrics.Steps)
+	}
+	if !result.Passed() {
+		t.Errorf("expected thresholds to pass on merged metrics, got %+v", result.Thresholds)
+	}
+
+	counts := make(map[timeline.Kind]int)
+	for _, e := range result.Events {
+		counts[e.Kind]++
+		if e.Kind == timeline.WorkerLeft && e.Attrs["agent_version"] != version.Label() {
+			t.Errorf("expected the worker's agent version, got %v", e.Attrs)
+		}
+	}
+	if counts[timeline.WorkerJoined] != 2 || counts[timeline.WorkerLeft] != 2 || counts[timeline.Aborted] != 1 {
+		t.Errorf("expected 2 workers to join and leave after an abort, got %v", counts)
+	}
+}
+
+func TestCoordinator_RunsLifecycleOnce(t *testing.T) {
+	var seeded, items, deleted atomic.Int64
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			seeded.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "7"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/items/7":
+			deleted.Add(1)
+		case r.URL.Path == "/items/7":
+			items.Add(1)
+		}
+	}))
+	defer target.Close()
+
+	var workers []string
+	for range 2 {
+		w := httptest.NewServer(NewWorker(testToken).Handler())
+		defer w.Close()
+		workers = append(workers, w.URL)
+	}
+
+	data := []byte(fmt.Sprintf(`
+name: lifecycle
+base_url: %s
+virtual_users: 2
+duration: 60
+setup:
+  - request: POST /items
+    save_to_context: {item: response.id}
+teardown:
+  - request: DELETE /items/${item}
+steps:
+  - request: GET /items/${item}
+`, target.URL))
+	sc := parseScenario(t, data)
+
+	c, err := NewCoordinator(workers, testToken)
+	if err != nil {
+		t.Fatalf("NewCoordinator() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := c.Run(ctx, data, sc, agent.Options{}); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if seeded.Load() != 1 || deleted.Load() != 1 {
+		t.Errorf("expected setup and teardown to run once, got %d and %d", seeded.Load(), deleted.Load())
+	}
+	if items.Load() == 0 {
+		t.Error("expected the workers' VUs to use the variables saved in setup")
+	}
+}
+
+func TestCoordinator_ResolvesScenarioFiles(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]string)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		seen[r.URL.Query().Get("user")] = string(body)
+		mu.Unlock()
+	}))
+	defer target.Close()
+
+	var workers []string
+	for range 2 {
+		w := httptest.NewServer(NewWorker(testToken).Handler())
+		defer w.Close()
+		workers = append(workers, w.URL)
+	}
+
+	// The scenario's files are relative to its directory, which is not
+	// the workers' working directory
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	write("data/users.csv", "username\nalice\nbob\n")
+	write("bodies/order.json", `{"sku": 1}`)
+	write("shared/steps.yaml", `
+steps:
+  - request: POST /orders?user=${csv.username}
+    body_file: ../bodies/order.json
+`)
+	path := write("scenario.yaml", fmt.Sprintf(`
+include: [shared/steps.yaml]
+name: files
+base_url: %s
+virtual_users: 2
+duration: 60
+data:
+  file: data/users.csv
+`, target.URL))
+
+	parser := scenario.NewParser()
+	if err := parser.ParseFile(path); err != nil {
+		t.Fatalf("ParseFile() failed: %v", err)
+	}
+	if err := parser.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	sc, _ := parser.GetScenario()
+	data, err := parser.Resolved()
+	if err != nil {
+		t.Fatalf("Resolved() failed: %v", err)
+	}
+
+	c, err := NewCoordinator(workers, testToken)
+	if err != nil {
+		t.Fatalf("NewCoordinator() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := c.Run(ctx, data, sc, agent.Options{}); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 || seen["alice"] != `{"sku": 1}` || seen["bob"] != `{"sku": 1}` {
+		t.Errorf("expected both workers to read the scenario's files, got %v", seen)
+	}
+}
+
+func TestCoordinator_RunFailsOnWorkerError(t *testing.T) {
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "a run is already in progress", http.StatusConflict)
+	}))
+	defer busy.Close()
+
+	data := []byte(`
+name: distributed
+base_url: http://127.0.0.1:1
+virtual_users: 1
+duration: 1
+steps:
+  - request: GET /ping
+`)
+	c, _ := NewCoordinator([]string{busy.URL}, testToken)
+	events := timeline.New(nil)
+	_, err := c.Run(context.Background(), data, parseScenario(t, data), agent.Options{Timeline: events})
+	if err == nil {
+		t.Fatal("expected error from busy worker, got nil")
+	}
+	if got := events.Events(); len(got) != 2 || got[1].Kind != timeline.WorkerFailed {
+		t.Errorf("expected the worker failure in the timeline, got %+v", got)
+	}
+}
+
+func TestCoordinator_RedistributesLostWorker(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	defer target.Close()
+
+	live := httptest.NewServer(NewWorker(testToken).Handler())
+	defer live.Close()
+
+	// The dead worker accepts its run but never answers again
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stop" {
+			return
+		}
+		// The connection is only watched for closing once the body is read
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer dead.Close()
+
+	data := []byte(fmt.Sprintf(`
+name: distributed
+base_url: %s
+virtual_users: 4
+duration: 60
+steps:
+  - request: GET /ping
+`, target.URL))
+
+	c, _ := NewCoordinator([]string{live.URL, dead.URL}, testToken)
+	c.Heartbeat = 20 * time.Millisecond
+	c.Redistribute = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	result, err := c.Run(ctx, data, parseScenario(t, data), agent.Options{})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	if result.Metrics.Total.Requests == 0 {
+		t.Error("expected the metrics of the remaining worker, got none")
+	}
+	if len(result.CapacityGaps) != 1 {
+		t.Fatalf("expected 1 capacity gap, got %+v", result.CapacityGaps)
+	}
+	gap := result.CapacityGaps[0]
+	if gap.Worker != c.Workers[1] || gap.VirtualUsers != 2 || !gap.Redistributed {
+		t.Errorf("expected the dead worker's 2 VUs to be redistributed, got %+v", gap)
+	}
+	if !gap.End.After(gap.Start) {
+		t.Errorf("expected the gap to end after it started, got %+v", gap)
+	}
+
+	counts := make(map[timeline.Kind]int)
+	for _, e := range result.Events {
+		counts[e.Kind]++
+	}
+	if counts[timeline.WorkerFailed] != 1 || counts[timeline.CapacityRestored] != 1 {
+		t.Errorf("expected the loss and the redistribution in the timeline, got %v", counts)
+	}
+}
+
+func TestCoordinator_RunFailsWhenAllWorkersAreLost(t *testing.T) {
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "a run is already in progress", http.StatusConflict)
+	}))
+	defer busy.Close()
+
+	data := []byte(`
+name: distributed
+base_url: http://127.0.0.1:1
+virtual_users: 2
+duration: 1
+steps:
+  - request: GET /ping
+`)
+	c, _ := NewCoordinator([]string{busy.URL, busy.URL}, testToken)
+	c.TolerateFailures = true
+	_, err := c.Run(context.Background(), data, parseScenario(t, data), agent.Options{})
+	if err == nil || !strings.Contains(err.Error(), "all workers were lost") {
+		t.Errorf("expected all workers to be lost, got %v", err)
+	}
+}
+
+func TestWorker_Heartbeat(t *testing.T) {
+	w := httptest.NewServer(NewWorker(testToken).Handler())
+	defer w.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, w.URL+"/heartbeat", nil)
+	req.Header.Set("Authorization", apiauth.Header(testToken))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /heartbeat failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var hb Heartbeat
+	if err := json.NewDecoder(resp.Body).Decode(&hb); err != nil {
+		t.Fatalf("invalid heartbeat: %v", err)
+	}
+	if hb.Running {
+		t.Errorf("expected an idle worker, got %+v", hb)
+	}
+
+	c, _ := NewCoordinator([]string{w.URL}, testToken)
+	if err := c.scale(c.Workers[0], 3); err == nil {
+		t.Error("expected error scaling an idle worker, got nil")
+	}
+}
+
+func TestWorker_RejectsInvalidAssignment(t *testing.T) {
+	w := httptest.NewServer(NewWorker(testToken).Handler())
+	defer w.Close()
+
+	c, _ := NewCoordinator([]string{w.URL}, testToken)
+	_, err := c.run(context.Background(), c.Workers[0], &Assignment{
+		Scenario:     []byte("name: [unterminated"),
+		VirtualUsers: 1,
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid scenario, got nil")
+	}
+}
+
+func TestWorker_RequiresToken(t *testing.T) {
+	w := httptest.NewServer(NewWorker(testToken).Handler())
+	defer w.Close()
+
+	for _, token := range []string{"", "fedcba9876543210"} {
+		c, _ := NewCoordinator([]string{w.URL}, token)
+		_, err := c.run(context.Background(), c.Workers[0], &Assignment{
+			Scenario:     []byte("name: test\nbase_url: " + w.URL + "\nvirtual_users: 1\nduration: 1\nsteps:\n  - request: GET /\n"),
+			VirtualUsers: 1,
+		})
+		if err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+			t.Errorf("token %q: expected the run to be refused, got %v", token, err)
+		}
+	}
+}
+
+func TestCoordinator_Scale(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	var (
+		workers []*Worker
+		urls    []string
+	)
+	for range 2 {
+		w := NewWorker(testToken)
+		server := httptest.NewServer(w.Handler())
+		defer server.Close()
+		workers = append(workers, w)
+		urls = append(urls, server.URL)
+	}
+
+	data := []byte(fmt.Sprintf(`
+name: scaled
+base_url: %s
+virtual_users: 2
+duration: 60
+steps:
+  - request: GET /ping
+`, target.URL))
+	sc := parseScenario(t, data)
+
+	c, _ := NewCoordinator(urls, testToken)
+	if err := c.Scale(4); !errors.Is(err, agent.ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning without a run, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := timeline.New(nil)
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Run(ctx, data, sc, agent.Options{Timeline: events})
+		done <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for workers[0].current() == nil || workers[1].current() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("workers did not start their runs")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := c.Scale(5); err != nil {
+		t.Fatalf("Scale() failed: %v", err)
+	}
+	if got := []int{workers[0].current().VUs(), workers[1].current().VUs()}; !slices.Equal(got, []int{3, 2}) {
+		t.Errorf("expected the workers to run 3 and 2 VUs, got %v", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !slices.ContainsFunc(events.Events(), func(e timeline.Event) bool {
+		return e.Kind == timeline.Scaled && e.Attrs["vus"] == "5"
+	}) {
+		t.Errorf("expected a scaled event, got %v", events.Events())
+	}
+}
@@ -0,0 +1,350 @@
+package cluster
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/apiauth"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/timeline"
+	"loadforge-agent/internal/version"
+)
+
+// stopTimeout bounds how long the coordinator waits for a worker to
+// acknowledge a stop request
+const stopTimeout = 5 * time.Second
+
+// Coordinator drives a run across a set of workers
+type Coordinator struct {
+	// Workers are the base URLs of the workers, e.g. http://10.0.0.2:7070
+	Workers []string
+	// Client sends requests to workers. Runs are long-lived requests, so
+	// the client should not have a timeout.
+	Client *http.Client
+	// Token is the secret shared with the workers, sent with every request
+	// to them, see Worker
+	Token string
+
+	// Heartbeat is how often workers are checked to be alive, and how long
+	// they have to answer; DefaultHeartbeat if zero
+	Heartbeat time.Duration
+	// MissedHeartbeats is how many heartbeats in a row a worker may miss
+	// before it is lost; DefaultMissedHeartbeats if zero
+	MissedHeartbeats int
+	// TolerateFailures keeps the run going when workers are lost, as long
+	// as one is left. Otherwise the first lost worker fails the run.
+	TolerateFailures bool
+	// Redistribute hands the VUs of a lost worker to the workers still
+	// running. It implies TolerateFailures.
+	Redistribute bool
+
+	mu sync.Mutex
+	// fleet and events are those of the run in progress, for Scale
+	fleet  *fleet
+	events *timeline.Log
+}
+
+// NewCoordinator creates a Coordinator for workers given as host:port
+// addresses or base URLs, authenticating to them with token
+func NewCoordinator(workers []string, token string) (*Coordinator, error) {
+	if len(workers) == 0 {
+		return nil, fmt.Errorf("at least one worker is required")
+	}
+
+	urls := make([]string, len(workers))
+	for i, w := range workers {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			return nil, fmt.Errorf("worker %d: address cannot be empty", i+1)
+		}
+		if !strings.Contains(w, "://") {
+			w = "http://" + w
+		}
+		urls[i] = strings.TrimSuffix(w, "/")
+	}
+	return &Coordinator{Workers: urls, Client: &http.Client{}, Token: token}, nil
+}
+
+// newRequest creates a request to a worker, carrying the token
+func (c *Coordinator) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", apiauth.Header(c.Token))
+	return req, nil
+}
+
+// SplitVUs divides total virtual users between n workers as evenly as
+// possible, the first workers taking the remainder
+func SplitVUs(total uint64, n int) ([]uint64, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("at least one worker is required")
+	}
+	if total < uint64(n) {
+		return nil, fmt.Errorf("cannot split %d virtual users across %d workers", total, n)
+	}
+
+	shares := make([]uint64, n)
+	for i := range shares {
+		shares[i] = total / uint64(n)
+		if uint64(i) < total%uint64(n) {
+			shares[i]++
+		}
+	}
+	return shares, nil
+}
+
+// Run runs sc across all workers and merges their metrics into one Result.
+// data is sc as YAML, see scenario.Parser.Resolved; workers parse it
+// themselves.
+// Cancelling ctx stops the workers, and the result covers what they
+// recorded until then.
+func (c *Coordinator) Run(ctx context.Context, data []byte, sc *scenario.Scenario, opts agent.Options) (*agent.Result, error) {
+	if opts.TraceVU > 0 || len(opts.Sinks) > 0 || len(opts.SampleSinks) > 0 {
+		return nil, fmt.Errorf("tracing and metric sinks are not supported in distributed mode")
+	}
+	if opts.ReadOnly {
+		return nil, fmt.Errorf("read-only runs are not supported in distributed mode")
+	}
+	if sc.Burst != nil {
+		return nil, fmt.Errorf("burst runs are not supported in distributed mode")
+	}
+	if len(sc.Guards) > 0 {
+		return nil, fmt.Errorf("guards are not supported in distributed mode")
+	}
+	if c.Redistribute && len(sc.Stages) > 0 {
+		return nil, fmt.Errorf("the VUs of lost workers cannot be redistributed in runs with stages")
+	}
+	tolerate := c.TolerateFailures || c.Redistribute
+
+	shares, err := SplitVUs(sc.VirtualUsers, len(c.Workers))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Timeline == nil {
+		opts.Timeline = timeline.New(nil)
+	}
+	events := opts.Timeline
+
+	var noiseRate float64
+	if sc.Noise != nil {
+		noiseRate = sc.Noise.Rate / float64(len(c.Workers))
+	}
+
+	// Runs are not bound to ctx: on cancellation the workers are stopped
+	// and still report what they recorded. Cancelling runCtx instead
+	// aborts the runs, dropping their state.
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	defer cancelRuns()
+
+	// Setup and teardown run once here rather than on every worker
+	setupVars, err := agent.Setup(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	if len(sc.Setup) > 0 {
+		events.Add(timeline.SetupFinished, fmt.Sprintf("ran %d setup steps", len(sc.Setup)), nil)
+	}
+
+	// Workers share one seed so that VU identities are reproducible and
+	// numbered across them
+	seed := cmp.Or(opts.Seed, sc.Seed, rand.Uint64())
+	firstVU := 0
+
+	states := make([]*agent.State, len(c.Workers))
+	fleet := newFleet(slices.Clone(shares))
+	c.mu.Lock()
+	c.fleet, c.events = fleet, events
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.fleet, c.events = nil, nil
+		c.mu.Unlock()
+	}()
+	var (
+		wg      sync.WaitGroup
+		failed  sync.Once
+		failure error
+	)
+	for i, worker := range c.Workers {
+		asg := &Assignment{
+			Scenario:     data,
+			VirtualUsers: shares[i],
+			NoiseRate:    noiseRate,
+			Estimator:    opts.Estimator,
+			Compression:  opts.Compression,
+			DrainTimeout: opts.DrainTimeout,
+			Worker:       i,
+			Workers:      len(c.Workers),
+			Seed:         seed,
+			FirstVU:      firstVU,
+			SetupVars:    setupVars,
+		}
+		firstVU += int(shares[i])
+		attrs := map[string]string{"worker": worker}
+		events.Add(timeline.WorkerJoined, fmt.Sprintf("assigned %d VUs", asg.VirtualUsers), map[string]string{
+			"worker": worker,
+			"seed":   strconv.FormatUint(seed, 10),
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// A worker that stops answering heartbeats is lost even if its
+			// run request hangs
+			workerCtx, lose := context.WithCancelCause(runCtx)
+			defer lose(nil)
+			go c.watch(workerCtx, worker, lose)
+
+			state, err := c.run(workerCtx, worker, asg)
+			if err != nil {
+				var lost *lostError
+				if errors.As(context.Cause(workerCtx), &lost) {
+					err = lost
+				}
+				events.Add(timeline.WorkerFailed, err.Error(), attrs)
+				if !tolerate {
+					// One failed worker fails the run, abort the others.
+					// Only the first error is reported, the rest are
+					// cancellations.
+					failed.Do(func() {
+						failure = fmt.Errorf("worker %s: %w", worker, err)
+						cancelRuns()
+					})
+					return
+				}
+
+				since := time.Now()
+				if lost != nil {
+					// The worker may only be unreachable, its run must not
+					// go on unaccounted for
+					since = lost.since
+					c.stop(worker)
+				}
+				c.failover(fleet, i, since, events)
+				return
+			}
+			fleet.done(i)
+			msg := "run completed"
+			if state.AgentVersion != version.Label() {
+				msg += fmt.Sprintf(" on agent %s, the coordinator is %s", state.AgentVersion, version.Label())
+			}
+			events.Add(timeline.WorkerLeft, msg, map[string]string{"worker": worker, "agent_version": state.AgentVersion})
+			states[i] = state
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var aborted string
+	select {
+	case <-done:
+	case <-ctx.Done():
+		aborted = context.Cause(ctx).Error()
+		events.Add(timeline.Aborted, fmt.Sprintf("%s, stopping workers", aborted), nil)
+		c.stopAll()
+		<-done
+	}
+
+	if err := agent.Teardown(context.WithoutCancel(ctx), sc, setupVars); err != nil {
+		events.Add(timeline.TeardownFailed, err.Error(), nil)
+	} else if len(sc.Teardown) > 0 {
+		events.Add(timeline.TeardownFinished, fmt.Sprintf("ran %d teardown steps", len(sc.Teardown)), nil)
+	}
+
+	if failure != nil {
+		return nil, failure
+	}
+
+	// The metrics of lost workers are lost with them
+	states = slices.DeleteFunc(states, func(s *agent.State) bool { return s == nil })
+	if len(states) == 0 {
+		return nil, fmt.Errorf("all workers were lost")
+	}
+
+	result, err := agent.Merge(sc, opts, states)
+	if err != nil {
+		return nil, err
+	}
+	result.Aborted = aborted
+	result.CapacityGaps = fleet.capacityGaps(time.Now())
+	return result, nil
+}
+
+// run sends asg to worker and waits for the state of its run
+func (c *Coordinator) run(ctx context.Context, worker string, asg *Assignment) (*agent.State, error) {
+	body, err := json.Marshal(asg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode assignment: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, worker+"/run", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start run: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("run failed with HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var state agent.State
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("invalid run state: %w", err)
+	}
+	return &state, nil
+}
+
+// stopAll asks every worker to end its run early. Errors are ignored: a
+// worker that cannot be reached has no run to stop or will fail its run.
+func (c *Coordinator) stopAll() {
+	var wg sync.WaitGroup
+	for _, worker := range c.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.stop(worker)
+		}()
+	}
+	wg.Wait()
+}
+
+// stop asks worker to end its run early, ignoring errors
+func (c *Coordinator) stop(worker string) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, http.MethodPost, worker+"/stop", nil)
+	if err != nil {
+		return
+	}
+	if resp, err := c.Client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,239 @@
+package cluster
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/timeline"
+)
+
+// Defaults of the coordinator's failure detection
+const (
+	DefaultHeartbeat        = time.Second
+	DefaultMissedHeartbeats = 3
+)
+
+// lostError is why a worker that stopped answering heartbeats was given up
+type lostError struct {
+	missed int
+	// since is when the worker last answered, or when its run started
+	since time.Time
+}
+
+func (e *lostError) Error() string {
+	return fmt.Sprintf("missed %d heartbeats", e.missed)
+}
+
+// watch checks that worker answers heartbeats until ctx is done, and calls
+// lost once it missed too many in a row
+func (c *Coordinator) watch(ctx context.Context, worker string, lost func(error)) {
+	interval := cmp.Or(c.Heartbeat, DefaultHeartbeat)
+	limit := cmp.Or(c.MissedHeartbeats, DefaultMissedHeartbeats)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	seen, missed := time.Now(), 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := c.heartbeat(ctx, worker, interval); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if missed++; missed >= limit {
+				lost(&lostError{missed: missed, since: seen})
+				return
+			}
+			continue
+		}
+		seen, missed = time.Now(), 0
+	}
+}
+
+// heartbeat checks that worker answers within timeout. Any response will
+// do: the process is alive, and workers of older versions have no
+// /heartbeat to answer with.
+func (c *Coordinator) heartbeat(ctx context.Context, worker string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, http.MethodGet, worker+"/heartbeat", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// fleet tracks the VUs of the workers still running, to hand those of lost
+// workers to the others
+type fleet struct {
+	mu      sync.Mutex
+	vus     []uint64
+	running []bool
+	gaps    []agent.CapacityGap
+}
+
+func newFleet(shares []uint64) *fleet {
+	f := &fleet{vus: shares, running: make([]bool, len(shares))}
+	for i := range f.running {
+		f.running[i] = true
+	}
+	return f
+}
+
+// done records that worker i finished its run
+func (f *fleet) done(i int) {
+	f.mu.Lock()
+	f.running[i] = false
+	f.mu.Unlock()
+}
+
+// capacityGaps returns the gaps left by lost workers, those that were not
+// closed ending at end
+func (f *fleet) capacityGaps(end time.Time) []agent.CapacityGap {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.gaps {
+		if f.gaps[i].End.IsZero() {
+			f.gaps[i].End = end
+		}
+	}
+	return f.gaps
+}
+
+// failover records worker i as lost since the given time and, if the
+// coordinator redistributes, hands its VUs to the workers still running
+func (c *Coordinator) failover(f *fleet, i int, since time.Time, events *timeline.Log) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.running[i] = false
+	gap := agent.CapacityGap{Worker: c.Workers[i], VirtualUsers: f.vus[i], Start: since}
+	if c.Redistribute && c.scaleUp(f, gap.VirtualUsers, gap.Worker, events) {
+		gap.End = time.Now()
+		gap.Redistributed = true
+	}
+	f.gaps = append(f.gaps, gap)
+}
+
+// scaleUp spreads vus over the workers still running and reports whether
+// all of them took their part. The caller holds f.mu.
+func (c *Coordinator) scaleUp(f *fleet, vus uint64, lost string, events *timeline.Log) bool {
+	var survivors []int
+	for j, running := range f.running {
+		if running {
+			survivors = append(survivors, j)
+		}
+	}
+	if len(survivors) == 0 {
+		return false
+	}
+
+	ok := true
+	for k, j := range survivors {
+		extra := vus / uint64(len(survivors))
+		if uint64(k) < vus%uint64(len(survivors)) {
+			extra++
+		}
+		if extra == 0 {
+			continue
+		}
+		if err := c.scale(c.Workers[j], f.vus[j]+extra); err != nil {
+			events.Add(timeline.WorkerFailed, fmt.Sprintf("failed to take over %d VUs of %s: %v", extra, lost, err),
+				map[string]string{"worker": c.Workers[j]})
+			ok = false
+			continue
+		}
+		f.vus[j] += extra
+	}
+	if ok {
+		events.Add(timeline.CapacityRestored, fmt.Sprintf("handed %d VUs to %d workers", vus, len(survivors)),
+			map[string]string{"worker": lost, "vus": strconv.FormatUint(vus, 10)})
+	}
+	return ok
+}
+
+// Scale changes the VU count of the run in progress to vus, split between
+// the workers still running as the VUs were at the start. It returns
+// agent.ErrNotRunning when no run is in progress.
+func (c *Coordinator) Scale(vus uint64) error {
+	c.mu.Lock()
+	f, events := c.fleet, c.events
+	c.mu.Unlock()
+	if f == nil {
+		return agent.ErrNotRunning
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var running []int
+	var prev uint64
+	for j := range f.running {
+		if f.running[j] {
+			running = append(running, j)
+			prev += f.vus[j]
+		}
+	}
+	if len(running) == 0 {
+		return agent.ErrNotRunning
+	}
+	shares, err := SplitVUs(vus, len(running))
+	if err != nil {
+		return err
+	}
+	for k, j := range running {
+		if err := c.scale(c.Workers[j], shares[k]); err != nil {
+			return fmt.Errorf("worker %s: %w", c.Workers[j], err)
+		}
+		f.vus[j] = shares[k]
+	}
+	events.Add(timeline.Scaled, fmt.Sprintf("scaled from %d to %d VUs", prev, vus),
+		map[string]string{"vus": strconv.FormatUint(vus, 10)})
+	return nil
+}
+
+// scale sets the VU count of worker's run to vus
+func (c *Coordinator) scale(worker string, vus uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(ScaleRequest{VirtualUsers: vus})
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, worker+"/scale", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("scale failed with HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
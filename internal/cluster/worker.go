@@ -0,0 +1,256 @@
+// Package cluster spreads a run across several agents: a coordinator splits
+// the virtual users of a scenario between workers, starts and stops them and
+// merges their metrics into a single result.
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/apiauth"
+	"loadforge-agent/internal/preflight"
+	"loadforge-agent/internal/scenario"
+)
+
+// Assignment is the share of a run the coordinator hands to one worker
+type Assignment struct {
+	// Scenario is the scenario as YAML with includes merged and file
+	// references absolute, parsed by the worker
+	Scenario []byte `json:"scenario"`
+	// VirtualUsers overrides the scenario's VU count with this worker's share
+	VirtualUsers uint64 `json:"virtual_users"`
+	// NoiseRate, when set, overrides the noise rate so that the combined
+	// background traffic of all workers matches the scenario
+	NoiseRate   float64 `json:"noise_rate,omitempty"`
+	Estimator   string  `json:"estimator,omitempty"`
+	Compression float64 `json:"compression,omitempty"`
+	// DrainTimeout is how long in-flight requests may complete once the
+	// run ends, see agent.Options
+	DrainTimeout time.Duration `json:"drain_timeout,omitempty"`
+	// Worker (0-based) and Workers select this worker's share of the data
+	// file's rows
+	Worker  int `json:"worker"`
+	Workers int `json:"workers"`
+	// RetiredWorkers are workers lost earlier in the run, whose rows no
+	// other worker is handed, see agent.Options
+	RetiredWorkers []int `json:"retired_workers,omitempty"`
+	// Seed and FirstVU give this worker's VUs identities distinct from
+	// those of the other workers' VUs, see agent.Options
+	Seed    uint64 `json:"seed"`
+	FirstVU int    `json:"first_vu"`
+	// SetupVars are the variables saved by the scenario's setup steps,
+	// which the coordinator runs instead of the workers
+	SetupVars map[string]string `json:"setup_vars,omitempty"`
+}
+
+// Heartbeat is the worker's response to GET /heartbeat
+type Heartbeat struct {
+	// Running is whether the worker has a run in progress
+	Running bool `json:"running"`
+	// VirtualUsers is the VU count of the run in progress
+	VirtualUsers uint64 `json:"virtual_users,omitempty"`
+}
+
+// ScaleRequest is the body of POST /scale
+type ScaleRequest struct {
+	VirtualUsers uint64 `json:"virtual_users"`
+}
+
+// Worker runs assignments received over HTTP, one at a time, from a
+// coordinator sending the worker's token, see apiauth.Handler:
+//
+//	POST /run       runs an Assignment and responds with its agent.State
+//	POST /stop      ends the current run early; /run still responds with
+//	                the state recorded so far
+//	POST /scale     changes the VU count of the current run, within the
+//	                host's limits
+//	GET /heartbeat  responds with a Heartbeat, for the coordinator to tell
+//	                the worker is alive
+type Worker struct {
+	token string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	agent  *agent.Agent
+}
+
+// NewWorker creates an idle Worker accepting requests carrying token
+func NewWorker(token string) *Worker {
+	return &Worker{token: token}
+}
+
+// Handler returns the worker's HTTP API
+func (w *Worker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /run", w.handleRun)
+	mux.HandleFunc("POST /stop", w.handleStop)
+	mux.HandleFunc("POST /scale", w.handleScale)
+	mux.HandleFunc("GET /heartbeat", w.handleHeartbeat)
+	return apiauth.Handler(mux, w.token)
+}
+
+func (w *Worker) handleRun(rw http.ResponseWriter, r *http.Request) {
+	var asg Assignment
+	if err := json.NewDecoder(r.Body).Decode(&asg); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid assignment: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	a, err := newAgent(&asg)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The run ends on /stop or when the coordinator goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	if !w.start(cancel, a) {
+		http.Error(rw, "a run is already in progress", http.StatusConflict)
+		return
+	}
+	defer w.finish()
+
+	if _, err := a.Run(ctx); err != nil {
+		http.Error(rw, fmt.Sprintf("run failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	state, err := a.State()
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("failed to capture state: %v", err), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(state)
+}
+
+func (w *Worker) handleStop(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.mu.Unlock()
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (w *Worker) handleScale(rw http.ResponseWriter, r *http.Request) {
+	var req ScaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid scale request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	a := w.current()
+	if a == nil {
+		http.Error(rw, "no run is in progress", http.StatusConflict)
+		return
+	}
+	if req.VirtualUsers > uint64(a.VUs()) {
+		if err := preflight.Err(preflight.Check(req.VirtualUsers)); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := a.Scale(int(req.VirtualUsers)); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, agent.ErrNotRunning) {
+			status = http.StatusConflict
+		}
+		http.Error(rw, err.Error(), status)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (w *Worker) handleHeartbeat(rw http.ResponseWriter, r *http.Request) {
+	var hb Heartbeat
+	if a := w.current(); a != nil {
+		hb = Heartbeat{Running: true, VirtualUsers: uint64(a.VUs())}
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(hb)
+}
+
+func (w *Worker) start(cancel context.CancelFunc, a *agent.Agent) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return false
+	}
+	w.cancel = cancel
+	w.agent = a
+	return true
+}
+
+func (w *Worker) finish() {
+	w.mu.Lock()
+	w.cancel = nil
+	w.agent = nil
+	w.mu.Unlock()
+}
+
+// current returns the agent of the run in progress, or nil
+func (w *Worker) current() *agent.Agent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.agent
+}
+
+// shareOf scales vus out of total to a worker's share, rounding to the
+// nearest VU but keeping at least one
+func shareOf(vus, share, total uint64) uint64 {
+	return max(1, (vus*share+total/2)/total)
+}
+
+// newAgent parses the assignment's scenario and applies its share of the load
+func newAgent(asg *Assignment) (*agent.Agent, error) {
+	if asg.VirtualUsers == 0 {
+		return nil, fmt.Errorf("assignment has no virtual users")
+	}
+
+	parser := scenario.NewParser()
+	if err := parser.ParseData(asg.Scenario); err != nil {
+		return nil, err
+	}
+	if err := parser.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	sc, err := parser.GetScenario()
+	if err != nil {
+		return nil, err
+	}
+
+	// Stages ramp this worker's share of the VUs
+	for i := range sc.Stages {
+		sc.Stages[i].VUs = shareOf(sc.Stages[i].VUs, asg.VirtualUsers, sc.VirtualUsers)
+	}
+	sc.VirtualUsers = asg.VirtualUsers
+	if sc.Noise != nil && asg.NoiseRate > 0 {
+		sc.Noise.Rate = asg.NoiseRate
+	}
+
+	if err := preflight.Err(preflight.Check(sc.VirtualUsers)); err != nil {
+		return nil, err
+	}
+
+	return agent.New(sc, agent.Options{
+		Estimator:    asg.Estimator,
+		Compression:  asg.Compression,
+		DrainTimeout: asg.DrainTimeout,
+		Worker:       asg.Worker,
+		Workers:      asg.Workers,
+		FirstVU:      asg.FirstVU,
+		Seed:         asg.Seed,
+
+		SkipLifecycle:  true,
+		SetupVars:      asg.SetupVars,
+		RetiredWorkers: asg.RetiredWorkers,
+	})
+}
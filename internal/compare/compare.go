@@ -0,0 +1,154 @@
+// Package compare tells whether latency changed significantly between two
+// runs, from the raw samples both runs exported with -samples-out.
+package compare
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+)
+
+// TotalStep is the name the comparison of all steps combined is reported
+// under
+const TotalStep = "total"
+
+// Options tunes the statistical tests
+type Options struct {
+	// Alpha is the significance level, e.g. 0.05
+	Alpha float64
+	// Iterations is the number of bootstrap resamples
+	Iterations int
+	// MaxResample caps the size of each bootstrap resample
+	MaxResample int
+	// Seed makes the bootstrap reproducible
+	Seed uint64
+}
+
+// DefaultOptions are a 5% significance level, 95% confidence intervals
+// and 1000 bootstrap resamples of at most 10000 values
+var DefaultOptions = Options{Alpha: 0.05, Iterations: 1000, MaxResample: 10000}
+
+// Run holds the latencies of successful requests of one run, in
+// milliseconds, per step in first-seen order and for all steps combined
+type Run struct {
+	Steps     []string
+	Latencies map[string][]float64
+	Total     []float64
+}
+
+// ReadSamples loads a samples CSV. Requests without a response are
+// skipped, since they carry no latency.
+func ReadSamples(r io.Reader) (*Run, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read samples header: %w", err)
+	}
+
+	step, latency, errCol := -1, -1, -1
+	for i, name := range header {
+		switch name {
+		case "step":
+			step = i
+		case "latency_ms":
+			latency = i
+		case "error":
+			errCol = i
+		}
+	}
+	if step < 0 || latency < 0 {
+		return nil, fmt.Errorf("samples CSV must have step and latency_ms columns")
+	}
+
+	run := &Run{Latencies: make(map[string][]float64)}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read samples: %w", err)
+		}
+		if errCol >= 0 && record[errCol] != "" {
+			continue
+		}
+
+		ms, err := strconv.ParseFloat(record[latency], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latency %q", line, record[latency])
+		}
+		name := record[step]
+		if _, ok := run.Latencies[name]; !ok {
+			run.Steps = append(run.Steps, name)
+		}
+		run.Latencies[name] = append(run.Latencies[name], ms)
+		run.Total = append(run.Total, ms)
+	}
+	return run, nil
+}
+
+// Quantiles holds latency percentiles in milliseconds
+type Quantiles struct {
+	Count         int
+	P50, P95, P99 float64
+}
+
+// StepComparison is the outcome of comparing one step between two runs
+type StepComparison struct {
+	Step     string
+	Baseline Quantiles
+	Current  Quantiles
+	// P95Low and P95High bound the confidence interval of the p95
+	// difference (current minus baseline)
+	P95Low, P95High float64
+	// PValue is the Mann-Whitney U test's probability of a difference at
+	// least this large if both runs had the same latency distribution
+	PValue float64
+	// Significant is set when PValue is below alpha and the p95
+	// confidence interval excludes zero
+	Significant bool
+}
+
+// Compare compares every step present in both runs, then all steps
+// combined
+func Compare(baseline, current *Run, opts Options) []StepComparison {
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	confidence := 1 - opts.Alpha
+
+	var results []StepComparison
+	compare := func(step string, base, cur []float64) {
+		if len(base) == 0 || len(cur) == 0 {
+			return
+		}
+
+		c := StepComparison{
+			Step:     step,
+			Baseline: quantiles(base),
+			Current:  quantiles(cur),
+		}
+		_, c.PValue = MannWhitney(base, cur)
+		c.P95Low, c.P95High = BootstrapCI(base, cur, 0.95, confidence, opts.Iterations, opts.MaxResample, rng)
+		c.Significant = c.PValue < opts.Alpha && (c.P95Low > 0 || c.P95High < 0)
+		results = append(results, c)
+	}
+
+	for _, step := range baseline.Steps {
+		compare(step, baseline.Latencies[step], current.Latencies[step])
+	}
+	compare(TotalStep, baseline.Total, current.Total)
+	return results
+}
+
+func quantiles(values []float64) Quantiles {
+	sorted := slices.Sorted(slices.Values(values))
+	return Quantiles{
+		Count: len(sorted),
+		P50:   Quantile(sorted, 0.50),
+		P95:   Quantile(sorted, 0.95),
+		P99:   Quantile(sorted, 0.99),
+	}
+}
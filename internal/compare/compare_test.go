@@ -0,0 +1,100 @@
+package compare
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestMannWhitney(t *testing.T) {
+	// Only one pair has a above b, and the difference is not significant
+	a := []float64{1, 2, 4}
+	b := []float64{3, 5, 6, 7}
+	u, p := MannWhitney(a, b)
+	if u != 1 {
+		t.Errorf("expected U = 1, got %v", u)
+	}
+	if p < 0.05 || p > 0.2 {
+		t.Errorf("expected p around 0.1, got %v", p)
+	}
+
+	if _, p := MannWhitney([]float64{5, 5, 5}, []float64{5, 5}); p != 1 {
+		t.Errorf("expected p = 1 for identical values, got %v", p)
+	}
+	if _, p := MannWhitney(nil, b); p != 1 {
+		t.Errorf("expected p = 1 for an empty sample, got %v", p)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	for q, want := range map[float64]float64{0: 10, 0.5: 50, 0.95: 100, 1: 100} {
+		if got := Quantile(sorted, q); got != want {
+			t.Errorf("Quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+	if !math.IsNaN(Quantile(nil, 0.5)) {
+		t.Error("expected NaN for an empty sample")
+	}
+}
+
+func samples(rng *rand.Rand, n int, mean float64) string {
+	var b strings.Builder
+	b.WriteString("timestamp,step,status,latency_ms,bytes_sent,bytes_received,error\n")
+	for range n {
+		fmt.Fprintf(&b, "2026-01-01T00:00:00Z,GET /a,200,%.3f,0,10,\n", mean+rng.NormFloat64()*10)
+	}
+	b.WriteString("2026-01-01T00:00:00Z,GET /b,0,0.000,0,0,connection refused\n")
+	return b.String()
+}
+
+func TestCompare(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	read := func(csv string) *Run {
+		t.Helper()
+		run, err := ReadSamples(strings.NewReader(csv))
+		if err != nil {
+			t.Fatalf("ReadSamples() failed: %v", err)
+		}
+		return run
+	}
+
+	baseline := read(samples(rng, 2000, 180))
+	same := read(samples(rng, 2000, 180))
+	slower := read(samples(rng, 2000, 195))
+
+	if len(baseline.Steps) != 1 || len(baseline.Total) != 2000 {
+		t.Fatalf("expected failed requests to be skipped, got steps %v and %d samples",
+			baseline.Steps, len(baseline.Total))
+	}
+
+	opts := DefaultOptions
+	opts.Iterations = 200
+
+	results := Compare(baseline, slower, opts)
+	if len(results) != 2 || results[0].Step != "GET /a" || results[1].Step != TotalStep {
+		t.Fatalf("unexpected comparisons: %+v", results)
+	}
+	c := results[0]
+	if !c.Significant || c.PValue > 0.001 {
+		t.Errorf("expected a 15ms shift to be significant, got %+v", c)
+	}
+	if c.P95Low <= 0 || c.P95High > 30 {
+		t.Errorf("expected p95 difference interval around 15ms, got [%v, %v]", c.P95Low, c.P95High)
+	}
+
+	if c := Compare(baseline, same, opts)[0]; c.Significant {
+		t.Errorf("expected no significant difference between equal runs, got %+v", c)
+	}
+}
+
+func TestReadSamples_Invalid(t *testing.T) {
+	if _, err := ReadSamples(strings.NewReader("a,b\n1,2\n")); err == nil {
+		t.Error("expected error for missing columns, got nil")
+	}
+	if _, err := ReadSamples(strings.NewReader("step,latency_ms\nGET /a,fast\n")); err == nil {
+		t.Error("expected error for invalid latency, got nil")
+	}
+}
@@ -0,0 +1,105 @@
+package compare
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+)
+
+// MannWhitney runs a two-sided Mann-Whitney U test of whether values in a
+// and b come from the same distribution, using the normal approximation
+// with tie correction. It returns U for a and the p-value; p is 1 when
+// either sample is empty.
+func MannWhitney(a, b []float64) (u, p float64) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type ranked struct {
+		value float64
+		fromA bool
+	}
+	all := make([]ranked, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, ranked{v, true})
+	}
+	for _, v := range b {
+		all = append(all, ranked{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Tied values share the mean of their ranks
+	var rankSumA, tieTerm float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+
+	u = rankSumA - n1*(n1+1)/2
+	n := n1 + n2
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		// Every value is identical
+		return u, 1
+	}
+
+	// Continuity correction towards the mean
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	return u, math.Erfc(z / math.Sqrt2)
+}
+
+// BootstrapCI estimates a confidence interval for the difference of the q
+// quantile between b and a (b minus a) by resampling both with
+// replacement. Samples larger than maxSize are resampled at maxSize
+// values, which keeps large runs tractable at the cost of a slightly wider
+// interval.
+func BootstrapCI(a, b []float64, q, confidence float64, iterations, maxSize int, rng *rand.Rand) (lo, hi float64) {
+	if len(a) == 0 || len(b) == 0 || iterations <= 0 {
+		return math.NaN(), math.NaN()
+	}
+
+	diffs := make([]float64, iterations)
+	bufA := make([]float64, min(len(a), maxSize))
+	bufB := make([]float64, min(len(b), maxSize))
+	for i := range diffs {
+		diffs[i] = resampledQuantile(b, bufB, q, rng) - resampledQuantile(a, bufA, q, rng)
+	}
+	slices.Sort(diffs)
+
+	tail := (1 - confidence) / 2
+	return Quantile(diffs, tail), Quantile(diffs, 1-tail)
+}
+
+func resampledQuantile(values, buf []float64, q float64, rng *rand.Rand) float64 {
+	for i := range buf {
+		buf[i] = values[rng.IntN(len(values))]
+	}
+	slices.Sort(buf)
+	return Quantile(buf, q)
+}
+
+// Quantile returns the q quantile of sorted using the nearest-rank method
+func Quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
@@ -0,0 +1,222 @@
+// Package container detects the CPU and memory limits of the cgroup the
+// agent runs in, so that a containerized agent sizes itself to its
+// allocation rather than to the host.
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+const (
+	// VUsPerCPU is roughly how many VUs one CPU keeps busy against a fast
+	// target before the agent itself becomes the bottleneck
+	VUsPerCPU = 2000
+	// MemoryPerVU is the typical memory use of a VU: its goroutine, cookie
+	// jar, connection buffers and response bodies in flight
+	MemoryPerVU = 256 << 10
+
+	// memoryLimitRatio is the share of the container's memory the Go
+	// runtime is asked to stay under, leaving room for non-heap memory
+	memoryLimitRatio = 0.9
+)
+
+// Limits are the resource limits of the agent's cgroup. Zero values mean
+// no limit.
+type Limits struct {
+	// CPUs is the CPU quota in cores, e.g. 1.5
+	CPUs float64
+	// Memory is the memory limit in bytes
+	Memory int64
+}
+
+// Limited reports whether any limit is set
+func (l Limits) Limited() bool {
+	return l.CPUs > 0 || l.Memory > 0
+}
+
+// Capacity returns roughly how many VUs the limits can sustain, or 0 when
+// there are no limits
+func (l Limits) Capacity() uint64 {
+	capacity := uint64(math.MaxUint64)
+	if l.CPUs > 0 {
+		capacity = uint64(l.CPUs * VUsPerCPU)
+	}
+	if l.Memory > 0 {
+		capacity = min(capacity, uint64(float64(l.Memory)*memoryLimitRatio)/MemoryPerVU)
+	}
+	if capacity == math.MaxUint64 {
+		return 0
+	}
+	return capacity
+}
+
+func (l Limits) String() string {
+	var parts []string
+	if l.CPUs > 0 {
+		parts = append(parts, strconv.FormatFloat(l.CPUs, 'f', -1, 64)+" CPUs")
+	}
+	if l.Memory > 0 {
+		parts = append(parts, fmt.Sprintf("%d MiB memory", l.Memory>>20))
+	}
+	if len(parts) == 0 {
+		return "unlimited"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ApplyMemoryLimit sets the Go runtime's soft memory limit just below the
+// container's memory limit, so the GC works harder before the container
+// is OOM killed. An explicit GOMEMLIMIT wins. It returns the limit set, or
+// 0 if none was.
+func ApplyMemoryLimit(l Limits) int64 {
+	if l.Memory <= 0 || os.Getenv("GOMEMLIMIT") != "" {
+		return 0
+	}
+	limit := int64(float64(l.Memory) * memoryLimitRatio)
+	debug.SetMemoryLimit(limit)
+	return limit
+}
+
+// detect reads the limits of the cgroups listed in self, in
+// /proc/self/cgroup format, from the cgroup filesystem fsys. Both cgroup
+// v1 and v2 hierarchies are supported.
+func detect(fsys fs.FS, self []byte) (Limits, error) {
+	var l Limits
+
+	sc := bufio.NewScanner(bytes.NewReader(self))
+	for sc.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(sc.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		controllers, dir := fields[1], strings.TrimPrefix(fields[2], "/")
+
+		var err error
+		switch {
+		case controllers == "":
+			err = readV2(fsys, hierarchy{".", dir}, &l)
+		case hasController(controllers, "cpu"):
+			err = readV1CPU(fsys, hierarchy{controllers, dir}, &l)
+		case hasController(controllers, "memory"):
+			err = readV1Memory(fsys, hierarchy{controllers, dir}, &l)
+		}
+		if err != nil {
+			return l, err
+		}
+	}
+	return l, sc.Err()
+}
+
+func hasController(list, name string) bool {
+	for c := range strings.SplitSeq(list, ",") {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hierarchy locates the agent's cgroup: root is where the hierarchy is
+// mounted within the cgroup filesystem, dir the cgroup's path in it
+type hierarchy struct {
+	root, dir string
+}
+
+// read returns the content of the cgroup file name. Inside a cgroup
+// namespace the cgroup's own directory may not be visible, in which case
+// the hierarchy's root is the cgroup. It returns "" if the file does not
+// exist, e.g. because the controller is not enabled.
+func (h hierarchy) read(fsys fs.FS, name string) (string, error) {
+	for _, p := range []string{path.Join(h.root, h.dir, name), path.Join(h.root, name)} {
+		data, err := fs.ReadFile(fsys, p)
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to read cgroup %s: %w", name, err)
+		}
+	}
+	return "", nil
+}
+
+// readV2 reads cpu.max ("<quota> <period>" or "max <period>") and
+// memory.max (bytes or "max")
+func readV2(fsys fs.FS, h hierarchy, l *Limits) error {
+	cpu, err := h.read(fsys, "cpu.max")
+	if err != nil {
+		return err
+	}
+	if quota, period, ok := strings.Cut(cpu, " "); ok && quota != "max" {
+		if l.CPUs, err = parseQuota(quota, period); err != nil {
+			return err
+		}
+	}
+
+	memory, err := h.read(fsys, "memory.max")
+	if err != nil {
+		return err
+	}
+	if memory != "" && memory != "max" {
+		if l.Memory, err = strconv.ParseInt(memory, 10, 64); err != nil {
+			return fmt.Errorf("invalid cgroup memory.max %q", memory)
+		}
+	}
+	return nil
+}
+
+// readV1CPU reads cpu.cfs_quota_us, -1 without a limit, and
+// cpu.cfs_period_us
+func readV1CPU(fsys fs.FS, h hierarchy, l *Limits) error {
+	quota, err := h.read(fsys, "cpu.cfs_quota_us")
+	if err != nil || quota == "" || quota == "-1" {
+		return err
+	}
+	period, err := h.read(fsys, "cpu.cfs_period_us")
+	if err != nil {
+		return err
+	}
+	l.CPUs, err = parseQuota(quota, period)
+	return err
+}
+
+// unlimitedV1Memory is the smallest memory.limit_in_bytes that stands for
+// no limit; the kernel reports the maximum rounded down to a page
+const unlimitedV1Memory = 1 << 62
+
+func readV1Memory(fsys fs.FS, h hierarchy, l *Limits) error {
+	memory, err := h.read(fsys, "memory.limit_in_bytes")
+	if err != nil || memory == "" {
+		return err
+	}
+	limit, err := strconv.ParseInt(memory, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid cgroup memory.limit_in_bytes %q", memory)
+	}
+	if limit < unlimitedV1Memory {
+		l.Memory = limit
+	}
+	return nil
+}
+
+func parseQuota(quota, period string) (float64, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cgroup CPU quota %q", quota)
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("invalid cgroup CPU period %q", period)
+	}
+	return q / p, nil
+}
@@ -0,0 +1,15 @@
+package container
+
+import (
+	"fmt"
+	"os"
+)
+
+// Detect returns the limits of the agent's cgroup
+func Detect() (Limits, error) {
+	self, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return Limits{}, fmt.Errorf("failed to read cgroups: %w", err)
+	}
+	return detect(os.DirFS("/sys/fs/cgroup"), self)
+}
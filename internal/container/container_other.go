@@ -0,0 +1,8 @@
+//go:build !linux
+
+package container
+
+// Detect returns no limits, cgroups only exist on Linux
+func Detect() (Limits, error) {
+	return Limits{}, nil
+}
@@ -0,0 +1,84 @@
+package container
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetect_V2(t *testing.T) {
+	fsys := fstest.MapFS{
+		"cpu.max":    {Data: []byte("max 100000\n")},
+		"memory.max": {Data: []byte("max\n")},
+		"agent.scope/cpu.max": {
+			Data: []byte("150000 100000\n"),
+		},
+		"agent.scope/memory.max": {Data: []byte("536870912\n")},
+	}
+
+	l, err := detect(fsys, []byte("0::/agent.scope\n"))
+	if err != nil {
+		t.Fatalf("detect() failed: %v", err)
+	}
+	if l.CPUs != 1.5 || l.Memory != 512<<20 {
+		t.Errorf("unexpected limits: %+v", l)
+	}
+}
+
+func TestDetect_V2Namespaced(t *testing.T) {
+	// Inside a cgroup namespace the listed path is not visible and the
+	// root holds the container's own limits
+	fsys := fstest.MapFS{
+		"cpu.max":    {Data: []byte("max 100000\n")},
+		"memory.max": {Data: []byte("1073741824\n")},
+	}
+
+	l, err := detect(fsys, []byte("0::/../../kubepods/pod1\n"))
+	if err != nil {
+		t.Fatalf("detect() failed: %v", err)
+	}
+	if l.CPUs != 0 || l.Memory != 1<<30 {
+		t.Errorf("unexpected limits: %+v", l)
+	}
+}
+
+func TestDetect_V1(t *testing.T) {
+	fsys := fstest.MapFS{
+		"cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  {Data: []byte("200000\n")},
+		"cpu,cpuacct/docker/abc/cpu.cfs_period_us": {Data: []byte("100000\n")},
+		"memory/docker/abc/memory.limit_in_bytes":  {Data: []byte("9223372036854771712\n")},
+	}
+
+	self := "12:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/abc\n0::/\n"
+	l, err := detect(fsys, []byte(self))
+	if err != nil {
+		t.Fatalf("detect() failed: %v", err)
+	}
+	if l.CPUs != 2 || l.Memory != 0 {
+		t.Errorf("unexpected limits: %+v", l)
+	}
+}
+
+func TestDetect_Invalid(t *testing.T) {
+	fsys := fstest.MapFS{"memory.max": {Data: []byte("lots\n")}}
+	if _, err := detect(fsys, []byte("0::/\n")); err == nil {
+		t.Error("expected error for invalid memory.max, got nil")
+	}
+}
+
+func TestLimits_Capacity(t *testing.T) {
+	tests := []struct {
+		limits Limits
+		want   uint64
+	}{
+		{Limits{}, 0},
+		{Limits{CPUs: 0.5}, VUsPerCPU / 2},
+		{Limits{Memory: 1 << 30}, 3686},
+		{Limits{CPUs: 4, Memory: 256 << 20}, 921},
+	}
+
+	for _, tt := range tests {
+		if got := tt.limits.Capacity(); got != tt.want {
+			t.Errorf("%+v: expected capacity %d, got %d", tt.limits, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/version"
+)
+
+func newTarget(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func testScenario(baseURL string, duration int) []byte {
+	return []byte(fmt.Sprintf(`
+name: remote
+base_url: %s
+virtual_users: 2
+duration: %d
+steps:
+  - request: GET /ping
+`, baseURL, duration))
+}
+
+func TestManager_StartStop(t *testing.T) {
+	m := NewManager()
+	m.Interval = 10 * time.Millisecond
+
+	test, err := m.Start(testScenario(newTarget(t), 60), StartOptions{})
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	if s := test.Status(); s.State != StateRunning || s.Result != nil {
+		t.Errorf("expected running test without result, got %+v", s)
+	}
+
+	snapshots, unsubscribe := test.Subscribe()
+	defer unsubscribe()
+	select {
+	case snap := <-snapshots:
+		if len(snap.Metrics.Steps) != 1 || snap.Metrics.Steps[0].Name != "GET /ping" {
+			t.Errorf("unexpected snapshot steps: %+v", snap.Metrics.Steps)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no snapshot published")
+	}
+
+	if _, err := m.Stop(test.ID); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	s := test.Status()
+	if s.State != StateStopped {
+		t.Errorf("expected stopped state, got %s", s.State)
+	}
+	if s.Result == nil || s.Result.Metrics.Total.Requests == 0 {
+		t.Errorf("expected results of the run so far, got %+v", s.Result)
+	}
+	// Snapshots published before the test finished may still be buffered
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-snapshots:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("expected snapshots to be closed when the test finished")
+		}
+	}
+}
+
+func TestManager_PauseResume(t *testing.T) {
+	m := NewManager()
+	test, err := m.Start(testScenario(newTarget(t), 60), StartOptions{})
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer m.Stop(test.ID)
+
+	if _, err := m.Pause(test.ID); err != nil {
+		t.Fatalf("Pause() failed: %v", err)
+	}
+	if s := test.Status(); s.State != StatePaused {
+		t.Errorf("expected paused state, got %s", s.State)
+	}
+	if _, err := m.Resume(test.ID); err != nil {
+		t.Fatalf("Resume() failed: %v", err)
+	}
+	if s := test.Status(); s.State != StateRunning {
+		t.Errorf("expected running state, got %s", s.State)
+	}
+
+	m.Stop(test.ID)
+	if _, err := m.Pause(test.ID); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning pausing a stopped test, got %v", err)
+	}
+}
+
+func TestManager_Scale(t *testing.T) {
+	m := NewManager()
+	test, err := m.Start(testScenario(newTarget(t), 60), StartOptions{})
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer m.Stop(test.ID)
+
+	tests := []struct {
+		scale   func() (*Test, error)
+		want    int
+		wantErr error
+	}{
+		{func() (*Test, error) { return m.Scale(test.ID, 5) }, 5, nil},
+		{func() (*Test, error) { return m.ScaleBy(test.ID, 2) }, 10, nil},
+		{func() (*Test, error) { return m.ScaleBy(test.ID, 0.1) }, 1, nil},
+		{func() (*Test, error) { return m.ScaleBy(test.ID, 0.01) }, 1, nil},
+		{func() (*Test, error) { return m.Scale(test.ID, 0) }, 1, ErrInvalidScale},
+		{func() (*Test, error) { return m.ScaleBy(test.ID, -1) }, 1, ErrInvalidScale},
+		{func() (*Test, error) { return m.ScaleBy(test.ID, 11) }, 1, ErrInvalidScale},
+		{func() (*Test, error) { return m.Scale("missing", 2) }, 1, ErrNotFound},
+	}
+	for i, tt := range tests {
+		_, err := tt.scale()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%d: expected error %v, got %v", i, tt.wantErr, err)
+		}
+		if s := test.Status(); s.VUs != tt.want {
+			t.Errorf("%d: expected %d VUs, got %d", i, tt.want, s.VUs)
+		}
+	}
+
+	m.Stop(test.ID)
+	if _, err := m.Scale(test.ID, 2); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning scaling a stopped test, got %v", err)
+	}
+}
+
+func TestManager_Completes(t *testing.T) {
+	m := NewManager()
+	test, err := m.Start(testScenario(newTarget(t), 1), StartOptions{})
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	select {
+	case <-test.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("test did not finish")
+	}
+	if s := test.Status(); s.State != StateCompleted || s.Result == nil {
+		t.Errorf("expected completed test with result, got %+v", s)
+	}
+}
+
+func TestManager_Errors(t *testing.T) {
+	m := NewManager()
+	if _, err := m.Start([]byte("name: broken\n"), StartOptions{}); err == nil {
+		t.Error("expected error for invalid scenario, got nil")
+	}
+	if _, err := m.Start(testScenario("http://localhost", 1), StartOptions{Estimator: "median"}); err == nil {
+		t.Error("expected error for unknown estimator, got nil")
+	}
+	if _, err := m.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := m.Stop("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := m.Resume("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	m := NewManager()
+	server := httptest.NewServer(NewHTTPHandler(m))
+	defer server.Close()
+
+	do := func(method, path string, body []byte, want int) map[string]any {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			msg, _ := io.ReadAll(resp.Body)
+			t.Fatalf("%s %s: expected HTTP %d, got %d: %s", method, path, want, resp.StatusCode, msg)
+		}
+		var v map[string]any
+		json.NewDecoder(resp.Body).Decode(&v)
+		return v
+	}
+
+	if v := do(http.MethodGet, "/version", nil, http.StatusOK); v["version"] != version.Version {
+		t.Errorf("expected the agent build, got %v", v)
+	}
+
+	do(http.MethodPost, "/tests", []byte("name: broken\n"), http.StatusBadRequest)
+	do(http.MethodGet, "/tests/missing", nil, http.StatusNotFound)
+	do(http.MethodPost, "/tests/missing/stop", nil, http.StatusNotFound)
+
+	first := do(http.MethodPost, "/tests", testScenario(newTarget(t), 60), http.StatusCreated)
+	second := do(http.MethodPost, "/tests", testScenario(newTarget(t), 60), http.StatusCreated)
+	id, _ := first["id"].(string)
+	if id == "" || id == second["id"] {
+		t.Fatalf("expected distinct test IDs, got %v and %v", first["id"], second["id"])
+	}
+	if first["state"] != string(StateRunning) {
+		t.Errorf("expected running state, got %v", first["state"])
+	}
+
+	do(http.MethodGet, "/tests/"+id+"/results", nil, http.StatusConflict)
+
+	paused := do(http.MethodPost, "/tests/"+id+"/pause", nil, http.StatusOK)
+	if paused["state"] != string(StatePaused) {
+		t.Errorf("expected paused state, got %v", paused["state"])
+	}
+	do(http.MethodGet, "/tests/"+id+"/results", nil, http.StatusConflict)
+	resumed := do(http.MethodPost, "/tests/"+id+"/resume", nil, http.StatusOK)
+	if resumed["state"] != string(StateRunning) {
+		t.Errorf("expected running state, got %v", resumed["state"])
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/tests/"+id+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET events failed: %v", err)
+	}
+	var events []map[string]any
+	json.NewDecoder(resp.Body).Decode(&events)
+	resp.Body.Close()
+	var kinds []any
+	for _, e := range events {
+		kinds = append(kinds, e["kind"])
+	}
+	if len(kinds) != 3 || kinds[0] != "run_started" || kinds[1] != "paused" || kinds[2] != "resumed" {
+		t.Errorf("unexpected events: %v", events)
+	}
+
+	scaled := do(http.MethodPost, "/tests/"+id+"/scale", []byte(`{"vus": 6}`), http.StatusOK)
+	if scaled["vus"] != 6.0 {
+		t.Errorf("expected 6 VUs, got %v", scaled["vus"])
+	}
+	do(http.MethodPost, "/tests/"+id+"/scale", []byte(`{"vus": 2, "factor": 2}`), http.StatusBadRequest)
+	do(http.MethodPost, "/tests/"+id+"/scale", []byte(`{"factor": -1}`), http.StatusBadRequest)
+	do(http.MethodPost, "/tests/"+id+"/scale", []byte(`[`), http.StatusBadRequest)
+
+	stopped := do(http.MethodPost, "/tests/"+id+"/stop", nil, http.StatusOK)
+	if stopped["state"] != string(StateStopped) || stopped["passed"] != true {
+		t.Errorf("expected stopped test that passed, got %v", stopped)
+	}
+
+	// Stopping one test leaves the other running
+	other := do(http.MethodGet, "/tests/"+second["id"].(string), nil, http.StatusOK)
+	if other["state"] != string(StateRunning) {
+		t.Errorf("expected second test to keep running, got %v", other["state"])
+	}
+
+	do(http.MethodPost, "/tests/"+id+"/pause", nil, http.StatusConflict)
+
+	results := do(http.MethodGet, "/tests/"+id+"/results", nil, http.StatusOK)
+	if results["scenario"] != "remote" {
+		t.Errorf("expected JSON summary of the run, got %v", results)
+	}
+
+	m.StopAll()
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/tests", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /tests failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var list []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("invalid test list: %v", err)
+	}
+	if len(list) != 2 || list[0]["id"] != id {
+		t.Errorf("expected both tests oldest first, got %v", list)
+	}
+}
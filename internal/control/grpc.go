@@ -0,0 +1,286 @@
+//go:build !nogrpc
+
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/apiauth"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/version"
+	"loadforge-agent/pkg/agentpb"
+)
+
+// GRPCServer exposes a Manager as the agentpb.AgentService
+type GRPCServer struct {
+	agentpb.UnimplementedAgentServiceServer
+	manager *Manager
+}
+
+// NewGRPCServer creates the gRPC service for m
+func NewGRPCServer(m *Manager) *GRPCServer {
+	return &GRPCServer{manager: m}
+}
+
+// TokenOptions returns the server options rejecting calls whose
+// authorization metadata does not carry token, see apiauth.Valid
+func TokenOptions(token string) []grpc.ServerOption {
+	check := func(ctx context.Context) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get("authorization") {
+			if apiauth.Valid(v, token) {
+				return nil
+			}
+		}
+		return status.Error(codes.Unauthenticated, "missing or invalid token")
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := check(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+func (s *GRPCServer) StartTest(ctx context.Context, req *agentpb.StartTestRequest) (*agentpb.StartTestResponse, error) {
+	t, err := s.manager.Start(req.GetScenario(), StartOptions{
+		Estimator:   req.GetEstimator(),
+		Compression: req.GetTdigestCompression(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &agentpb.StartTestResponse{TestId: t.ID}, nil
+}
+
+func (s *GRPCServer) StopTest(ctx context.Context, req *agentpb.StopTestRequest) (*agentpb.StopTestResponse, error) {
+	t, err := s.manager.Stop(req.GetTestId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &agentpb.StopTestResponse{Status: statusToProto(t.Status())}, nil
+}
+
+func (s *GRPCServer) PauseTest(ctx context.Context, req *agentpb.PauseTestRequest) (*agentpb.PauseTestResponse, error) {
+	t, err := s.manager.Pause(req.GetTestId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &agentpb.PauseTestResponse{Status: statusToProto(t.Status())}, nil
+}
+
+func (s *GRPCServer) ResumeTest(ctx context.Context, req *agentpb.ResumeTestRequest) (*agentpb.ResumeTestResponse, error) {
+	t, err := s.manager.Resume(req.GetTestId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &agentpb.ResumeTestResponse{Status: statusToProto(t.Status())}, nil
+}
+
+func (s *GRPCServer) ScaleTest(ctx context.Context, req *agentpb.ScaleTestRequest) (*agentpb.ScaleTestResponse, error) {
+	var (
+		t   *Test
+		err error
+	)
+	switch {
+	case req.GetVus() != 0 && req.GetFactor() != 0:
+		err = fmt.Errorf("%w: vus and factor are mutually exclusive", ErrInvalidScale)
+	case req.GetFactor() != 0:
+		t, err = s.manager.ScaleBy(req.GetTestId(), req.GetFactor())
+	default:
+		t, err = s.manager.Scale(req.GetTestId(), int(req.GetVus()))
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &agentpb.ScaleTestResponse{Status: statusToProto(t.Status())}, nil
+}
+
+func (s *GRPCServer) GetStatus(ctx context.Context, req *agentpb.GetStatusRequest) (*agentpb.GetStatusResponse, error) {
+	t, err := s.manager.Get(req.GetTestId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &agentpb.GetStatusResponse{Status: statusToProto(t.Status())}, nil
+}
+
+func (s *GRPCServer) GetEvents(ctx context.Context, req *agentpb.GetEventsRequest) (*agentpb.GetEventsResponse, error) {
+	t, err := s.manager.Get(req.GetTestId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &agentpb.GetEventsResponse{}
+	for _, e := range t.Events() {
+		resp.Events = append(resp.Events, &agentpb.Event{
+			Time:    timestamppb.New(e.Time),
+			Kind:    string(e.Kind),
+			Message: e.Message,
+			Attrs:   e.Attrs,
+		})
+	}
+	return resp, nil
+}
+
+func (s *GRPCServer) StreamMetrics(req *agentpb.StreamMetricsRequest, stream agentpb.AgentService_StreamMetricsServer) error {
+	t, err := s.manager.Get(req.GetTestId())
+	if err != nil {
+		return grpcError(err)
+	}
+
+	snapshots, unsubscribe := t.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case snap, ok := <-snapshots:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(snapshotToProto(snap)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *GRPCServer) GetVersion(ctx context.Context, req *agentpb.GetVersionRequest) (*agentpb.GetVersionResponse, error) {
+	info := version.Get()
+	return &agentpb.GetVersionResponse{
+		Version:   info.Version,
+		Commit:    info.Commit,
+		BuildDate: info.Date,
+		GoVersion: info.GoVersion,
+		Platform:  info.Platform,
+		Modified:  info.Modified,
+	}, nil
+}
+
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrNotRunning):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrInvalidScale):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+var protoStates = map[State]agentpb.TestState{
+	StateRunning:   agentpb.TestState_TEST_STATE_RUNNING,
+	StatePaused:    agentpb.TestState_TEST_STATE_PAUSED,
+	StateCompleted: agentpb.TestState_TEST_STATE_COMPLETED,
+	StateStopped:   agentpb.TestState_TEST_STATE_STOPPED,
+	StateFailed:    agentpb.TestState_TEST_STATE_FAILED,
+}
+
+func statusToProto(s Status) *agentpb.TestStatus {
+	pb := &agentpb.TestStatus{
+		TestId:    s.ID,
+		Scenario:  s.Scenario,
+		State:     protoStates[s.State],
+		StartedAt: timestamppb.New(s.StartedAt),
+		Elapsed:   durationpb.New(s.Elapsed),
+		Vus:       int64(s.VUs),
+		ActiveVus: s.ActiveVUs,
+	}
+	if s.Err != nil {
+		pb.Error = s.Err.Error()
+	}
+	if s.Result != nil {
+		pb.Result = resultToProto(s.Result)
+	}
+	return pb
+}
+
+func resultToProto(r *agent.Result) *agentpb.TestResult {
+	pb := &agentpb.TestResult{
+		Iterations: r.Iterations,
+		Duration:   durationpb.New(r.Duration),
+		Passed:     r.Passed(),
+		Total:      stepToProto(r.Metrics.Total),
+	}
+	for _, step := range r.Metrics.Steps {
+		pb.Steps = append(pb.Steps, stepToProto(step))
+	}
+	for _, protocol := range r.Metrics.Protocols {
+		pb.Protocols = append(pb.Protocols, stepToProto(protocol))
+	}
+	for _, label := range r.Metrics.Labels {
+		pb.Labels = append(pb.Labels, stepToProto(label))
+	}
+	for _, t := range r.Thresholds {
+		pb.Thresholds = append(pb.Thresholds, &agentpb.ThresholdResult{
+			Expr:   t.Expr,
+			Step:   t.Step,
+			Actual: t.Actual,
+			Passed: t.Passed,
+		})
+	}
+	return pb
+}
+
+func snapshotToProto(s *Snapshot) *agentpb.MetricsSnapshot {
+	pb := &agentpb.MetricsSnapshot{
+		Time:      timestamppb.New(s.Time),
+		Elapsed:   durationpb.New(s.Elapsed),
+		ActiveVus: s.ActiveVUs,
+		Total:     stepToProto(s.Metrics.Total),
+	}
+	for _, step := range s.Metrics.Steps {
+		pb.Steps = append(pb.Steps, stepToProto(step))
+	}
+	for _, protocol := range s.Metrics.Protocols {
+		pb.Protocols = append(pb.Protocols, stepToProto(protocol))
+	}
+	for _, label := range s.Metrics.Labels {
+		pb.Labels = append(pb.Labels, stepToProto(label))
+	}
+	return pb
+}
+
+func stepToProto(s metrics.StepStats) *agentpb.StepStats {
+	l := s.Latency
+	return &agentpb.StepStats{
+		Name:       s.Name,
+		Requests:   s.Requests,
+		Errors:     s.Errors,
+		ErrorRate:  s.ErrorRate,
+		Rps:        s.RPS,
+		ErrorKinds: s.ErrorKinds,
+		Latency: &agentpb.LatencyStats{
+			Count: l.Count,
+			Min:   durationpb.New(l.Min),
+			Mean:  durationpb.New(l.Mean),
+			P50:   durationpb.New(l.P50),
+			P90:   durationpb.New(l.P90),
+			P95:   durationpb.New(l.P95),
+			P99:   durationpb.New(l.P99),
+			P999:  durationpb.New(l.P999),
+			Max:   durationpb.New(l.Max),
+		},
+	}
+}
@@ -0,0 +1,177 @@
+//go:build !nogrpc
+
+package control
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"loadforge-agent/internal/apiauth"
+	"loadforge-agent/internal/version"
+	"loadforge-agent/pkg/agentpb"
+)
+
+func TestGRPCServer(t *testing.T) {
+	m := NewManager()
+	m.Interval = 10 * time.Millisecond
+
+	ln := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	agentpb.RegisterAgentServiceServer(server, NewGRPCServer(m))
+	go server.Serve(ln)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer conn.Close()
+	client := agentpb.NewAgentServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if v, err := client.GetVersion(ctx, &agentpb.GetVersionRequest{}); err != nil || v.GetVersion() != version.Version {
+		t.Errorf("expected the agent build, got %v (%v)", v, err)
+	}
+	if _, err := client.StartTest(ctx, &agentpb.StartTestRequest{Scenario: []byte("name: [")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for invalid scenario, got %v", err)
+	}
+	if _, err := client.GetStatus(ctx, &agentpb.GetStatusRequest{TestId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for unknown test, got %v", err)
+	}
+
+	started, err := client.StartTest(ctx, &agentpb.StartTestRequest{Scenario: testScenario(newTarget(t), 60)})
+	if err != nil {
+		t.Fatalf("StartTest() failed: %v", err)
+	}
+
+	stream, err := client.StreamMetrics(ctx, &agentpb.StreamMetricsRequest{TestId: started.TestId})
+	if err != nil {
+		t.Fatalf("StreamMetrics() failed: %v", err)
+	}
+	snap, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() failed: %v", err)
+	}
+	if len(snap.Steps) != 1 || snap.Total == nil || snap.Total.Latency == nil {
+		t.Errorf("unexpected snapshot: %v", snap)
+	}
+
+	got, err := client.GetStatus(ctx, &agentpb.GetStatusRequest{TestId: started.TestId})
+	if err != nil {
+		t.Fatalf("GetStatus() failed: %v", err)
+	}
+	if got.Status.State != agentpb.TestState_TEST_STATE_RUNNING || got.Status.Scenario != "remote" {
+		t.Errorf("unexpected status: %v", got.Status)
+	}
+
+	paused, err := client.PauseTest(ctx, &agentpb.PauseTestRequest{TestId: started.TestId})
+	if err != nil {
+		t.Fatalf("PauseTest() failed: %v", err)
+	}
+	if paused.Status.State != agentpb.TestState_TEST_STATE_PAUSED {
+		t.Errorf("expected paused state, got %v", paused.Status.State)
+	}
+	if _, err := client.ResumeTest(ctx, &agentpb.ResumeTestRequest{TestId: started.TestId}); err != nil {
+		t.Fatalf("ResumeTest() failed: %v", err)
+	}
+
+	scaled, err := client.ScaleTest(ctx, &agentpb.ScaleTestRequest{TestId: started.TestId, Factor: 2})
+	if err != nil {
+		t.Fatalf("ScaleTest() failed: %v", err)
+	}
+	if scaled.Status.Vus != 4 {
+		t.Errorf("expected twice the VUs, got %d", scaled.Status.Vus)
+	}
+	_, err = client.ScaleTest(ctx, &agentpb.ScaleTestRequest{TestId: started.TestId, Vus: 3, Factor: 2})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for vus and factor, got %v", err)
+	}
+
+	stopped, err := client.StopTest(ctx, &agentpb.StopTestRequest{TestId: started.TestId})
+	if err != nil {
+		t.Fatalf("StopTest() failed: %v", err)
+	}
+	if stopped.Status.State != agentpb.TestState_TEST_STATE_STOPPED || stopped.Status.Result == nil {
+		t.Errorf("expected stopped test with result, got %v", stopped.Status)
+	}
+	if result := stopped.Status.Result; result != nil && (!result.Passed || result.Total.Requests == 0) {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	_, err = client.ResumeTest(ctx, &agentpb.ResumeTestRequest{TestId: started.TestId})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition resuming a stopped test, got %v", err)
+	}
+
+	// The stream ends with the test
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected stream to end cleanly, got %v", err)
+		}
+	}
+}
+
+func TestTokenOptions(t *testing.T) {
+	const token = "0123456789abcdef"
+	ln := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(TokenOptions(token)...)
+	agentpb.RegisterAgentServiceServer(server, NewGRPCServer(NewManager()))
+	go server.Serve(ln)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer conn.Close()
+	client := agentpb.NewAgentServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, authorization := range []string{"", "Bearer wrong-token-of-length", token} {
+		callCtx := ctx
+		if authorization != "" {
+			callCtx = metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+		}
+		if _, err := client.GetVersion(callCtx, &agentpb.GetVersionRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("authorization %q: expected Unauthenticated, got %v", authorization, err)
+		}
+		stream, err := client.StreamMetrics(callCtx, &agentpb.StreamMetricsRequest{TestId: "missing"})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("authorization %q: expected Unauthenticated for stream, got %v", authorization, err)
+		}
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", apiauth.Header(token))
+	if _, err := client.GetVersion(ctx, &agentpb.GetVersionRequest{}); err != nil {
+		t.Errorf("expected the token to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loadforge-agent/internal/report"
+	"loadforge-agent/internal/version"
+)
+
+// maxScenarioSize bounds the size of a submitted scenario file
+const maxScenarioSize = 10 << 20
+
+// statusJSON is the REST representation of a test's Status
+type statusJSON struct {
+	ID             string    `json:"id"`
+	Scenario       string    `json:"scenario"`
+	State          State     `json:"state"`
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	VUs            int       `json:"vus"`
+	ActiveVUs      int64     `json:"active_vus"`
+	Error          string    `json:"error,omitempty"`
+	// Passed is set once the test has results
+	Passed *bool `json:"passed,omitempty"`
+}
+
+// scaleJSON is the body of a scale request, setting either the VU count
+// or a factor to multiply it by
+type scaleJSON struct {
+	VUs    int     `json:"vus"`
+	Factor float64 `json:"factor"`
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+// NewHTTPHandler exposes m as a REST API:
+//
+//	POST /tests               submits the scenario YAML in the body; the
+//	                          estimator and tdigest_compression query
+//	                          parameters select the percentile estimator
+//	GET  /tests               lists all tests
+//	GET  /tests/{id}          reports the status of a test
+//	POST /tests/{id}/stop     ends a test early
+//	POST /tests/{id}/pause    idles VUs after their current iteration
+//	POST /tests/{id}/resume   lets the VUs of a paused test continue
+//	POST /tests/{id}/scale    changes the VU count of a running test to
+//	                          {"vus": n} or by {"factor": f}, e.g. 0.1
+//	GET  /tests/{id}/events   returns the test's timeline so far
+//	GET  /tests/{id}/results  returns the JSON summary of a finished test
+//	GET  /version             identifies the agent build
+//
+// Submitted scenarios may not reference the agent's files or secrets, see
+// Manager.Start. The handler does not authenticate clients itself: wrap it
+// with apiauth.Handler, or serve it with mutual TLS.
+func NewHTTPHandler(m *Manager) http.Handler {
+	h := &httpHandler{manager: m}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tests", h.start)
+	mux.HandleFunc("GET /tests", h.list)
+	mux.HandleFunc("GET /tests/{id}", h.status)
+	mux.HandleFunc("POST /tests/{id}/stop", h.stop)
+	mux.HandleFunc("POST /tests/{id}/pause", h.pause)
+	mux.HandleFunc("POST /tests/{id}/resume", h.resume)
+	mux.HandleFunc("POST /tests/{id}/scale", h.scale)
+	mux.HandleFunc("GET /tests/{id}/events", h.events)
+	mux.HandleFunc("GET /tests/{id}/results", h.results)
+	mux.HandleFunc("GET /version", h.version)
+	return mux
+}
+
+type httpHandler struct {
+	manager *Manager
+}
+
+func (h *httpHandler) start(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxScenarioSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("failed to read scenario: %w", err))
+		return
+	}
+
+	opts := StartOptions{Estimator: r.URL.Query().Get("estimator")}
+	if raw := r.URL.Query().Get("tdigest_compression"); raw != "" {
+		if opts.Compression, err = strconv.ParseFloat(raw, 64); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tdigest_compression %q", raw))
+			return
+		}
+	}
+
+	t, err := h.manager.Start(data, opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Location", "/tests/"+t.ID)
+	writeJSON(w, http.StatusCreated, newStatusJSON(t.Status()))
+}
+
+func (h *httpHandler) list(w http.ResponseWriter, r *http.Request) {
+	tests := h.manager.List()
+	statuses := make([]statusJSON, 0, len(tests))
+	for _, t := range tests {
+		statuses = append(statuses, newStatusJSON(t.Status()))
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (h *httpHandler) status(w http.ResponseWriter, r *http.Request) {
+	t, err := h.manager.Get(r.PathValue("id"))
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusJSON(t.Status()))
+}
+
+func (h *httpHandler) stop(w http.ResponseWriter, r *http.Request) {
+	t, err := h.manager.Stop(r.PathValue("id"))
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusJSON(t.Status()))
+}
+
+func (h *httpHandler) pause(w http.ResponseWriter, r *http.Request) {
+	t, err := h.manager.Pause(r.PathValue("id"))
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusJSON(t.Status()))
+}
+
+func (h *httpHandler) resume(w http.ResponseWriter, r *http.Request) {
+	t, err := h.manager.Resume(r.PathValue("id"))
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusJSON(t.Status()))
+}
+
+func (h *httpHandler) scale(w http.ResponseWriter, r *http.Request) {
+	var req scaleJSON
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid scale request: %w", err))
+		return
+	}
+
+	var (
+		t   *Test
+		err error
+	)
+	switch {
+	case req.VUs != 0 && req.Factor != 0:
+		err = fmt.Errorf("%w: vus and factor are mutually exclusive", ErrInvalidScale)
+	case req.Factor != 0:
+		t, err = h.manager.ScaleBy(r.PathValue("id"), req.Factor)
+	default:
+		t, err = h.manager.Scale(r.PathValue("id"), req.VUs)
+	}
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusJSON(t.Status()))
+}
+
+func (h *httpHandler) events(w http.ResponseWriter, r *http.Request) {
+	t, err := h.manager.Get(r.PathValue("id"))
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report.NewEvents(t.Events()))
+}
+
+func (h *httpHandler) results(w http.ResponseWriter, r *http.Request) {
+	t, err := h.manager.Get(r.PathValue("id"))
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	s := t.Status()
+	switch {
+	case s.State == StateRunning || s.State == StatePaused:
+		writeError(w, http.StatusConflict, fmt.Errorf("test %s is still running", s.ID))
+	case s.Result == nil:
+		writeError(w, http.StatusConflict, fmt.Errorf("test %s has no results: %v", s.ID, s.Err))
+	default:
+		writeJSON(w, http.StatusOK, report.NewSummary(s.Scenario, s.Result))
+	}
+}
+
+func (h *httpHandler) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version.Get())
+}
+
+func newStatusJSON(s Status) statusJSON {
+	j := statusJSON{
+		ID:             s.ID,
+		Scenario:       s.Scenario,
+		State:          s.State,
+		StartedAt:      s.StartedAt,
+		ElapsedSeconds: s.Elapsed.Seconds(),
+		VUs:            s.VUs,
+		ActiveVUs:      s.ActiveVUs,
+	}
+	if s.Err != nil {
+		j.Error = s.Err.Error()
+	}
+	if s.Result != nil {
+		passed := s.Result.Passed()
+		j.Passed = &passed
+	}
+	return j
+}
+
+func writeManagerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrNotRunning):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrInvalidScale):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorJSON{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,456 @@
+// Package control lets the agent be driven remotely: scenarios are
+// submitted as tests, each running in the background under its own ID,
+// whose status, live metrics and results can be queried while other tests
+// keep running.
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/preflight"
+	"loadforge-agent/internal/scenario"
+	"loadforge-agent/internal/timeline"
+)
+
+// State is the lifecycle stage of a test
+type State string
+
+const (
+	StateRunning   State = "running"
+	StatePaused    State = "paused"
+	StateCompleted State = "completed"
+	StateStopped   State = "stopped"
+	StateFailed    State = "failed"
+)
+
+var (
+	// ErrNotFound is returned for unknown test IDs
+	ErrNotFound = errors.New("test not found")
+	// ErrNotRunning is returned when pausing, resuming or scaling a
+	// finished test
+	ErrNotRunning = errors.New("test is not running")
+	// ErrInvalidScale is returned for VU counts a test cannot be scaled to
+	ErrInvalidScale = errors.New("invalid scale")
+)
+
+// errStopped is the cause of tests ended early through Stop
+var errStopped = errors.New("stopped through the control API")
+
+// maxScaleFactor bounds the factor a test is scaled by at once
+const maxScaleFactor = 10
+
+// subscriberBuffer is the number of snapshots queued per subscriber. A
+// subscriber that falls further behind misses snapshots rather than
+// slowing others.
+const subscriberBuffer = 16
+
+// StartOptions configures a submitted test
+type StartOptions struct {
+	// Estimator and Compression select the percentile estimator, see
+	// agent.Options
+	Estimator   string
+	Compression float64
+}
+
+// Manager runs submitted tests concurrently and keeps their results
+type Manager struct {
+	// Interval is how often live metrics snapshots are published
+	Interval time.Duration
+
+	mu    sync.Mutex
+	tests map[string]*Test
+}
+
+// NewManager creates a Manager publishing snapshots every second
+func NewManager() *Manager {
+	return &Manager{Interval: time.Second, tests: make(map[string]*Test)}
+}
+
+// Test is one submitted scenario run
+type Test struct {
+	ID        string
+	Scenario  *scenario.Scenario
+	StartedAt time.Time
+
+	agent  *agent.Agent
+	window *metrics.Window
+	events *timeline.Log
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+
+	mu          sync.Mutex
+	state       State
+	stopped     bool
+	finishedAt  time.Time
+	result      *agent.Result
+	err         error
+	subscribers map[chan *Snapshot]struct{}
+}
+
+// Status is a point-in-time view of a test
+type Status struct {
+	ID        string
+	Scenario  string
+	State     State
+	StartedAt time.Time
+	Elapsed   time.Duration
+	// VUs is the test's VU count, ActiveVUs those not idle
+	VUs       int
+	ActiveVUs int64
+	// Err is set when the test failed
+	Err error
+	// Result is set once the test has finished
+	Result *agent.Result
+}
+
+// Snapshot holds the metrics of one interval of a running test
+type Snapshot struct {
+	Time      time.Time
+	Elapsed   time.Duration
+	ActiveVUs int64
+	// Metrics covers the interval only
+	Metrics metrics.Summary
+}
+
+// Start validates the scenario file content in data and runs it in the
+// background until its duration elapses or it is stopped. The scenario
+// comes from a remote client, so it may not reference the agent's files or
+// secrets, see scenario.Parser.ParseRemote.
+func (m *Manager) Start(data []byte, opts StartOptions) (*Test, error) {
+	parser := scenario.NewParser()
+	if err := parser.ParseRemote(data); err != nil {
+		return nil, err
+	}
+	if err := parser.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	sc, err := parser.GetScenario()
+	if err != nil {
+		return nil, err
+	}
+
+	window, err := metrics.NewWindow(opts.Estimator, opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sc.Steps {
+		window.Register(sc.Steps[i].ID())
+	}
+
+	events := timeline.New(nil)
+	a, err := agent.New(sc, agent.Options{
+		Estimator:   opts.Estimator,
+		Compression: opts.Compression,
+		SampleSinks: []metrics.SampleSink{window},
+		Timeline:    events,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	t := &Test{
+		ID:          id,
+		Scenario:    sc,
+		StartedAt:   time.Now(),
+		agent:       a,
+		window:      window,
+		events:      events,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		state:       StateRunning,
+		subscribers: make(map[chan *Snapshot]struct{}),
+	}
+
+	m.mu.Lock()
+	m.tests[id] = t
+	m.mu.Unlock()
+
+	go t.run(ctx, m.Interval)
+	return t, nil
+}
+
+// Get returns the test with the given ID
+func (m *Manager) Get(id string) (*Test, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tests[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return t, nil
+}
+
+// List returns all tests, oldest first
+func (m *Manager) List() []*Test {
+	m.mu.Lock()
+	tests := slices.Collect(maps.Values(m.tests))
+	m.mu.Unlock()
+
+	slices.SortFunc(tests, func(a, b *Test) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return tests
+}
+
+// Stop ends the test with the given ID early and waits until its results
+// are available. Stopping a finished test has no effect.
+func (m *Manager) Stop(id string) (*Test, error) {
+	t, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if t.state == StateRunning {
+		t.stopped = true
+	}
+	t.mu.Unlock()
+
+	t.cancel(errStopped)
+	<-t.done
+	return t, nil
+}
+
+// Pause makes the VUs of the test with the given ID idle once their current
+// iteration finishes, until Resume is called. The test's duration keeps
+// elapsing while paused.
+func (m *Manager) Pause(id string) (*Test, error) {
+	return m.setPaused(id, true)
+}
+
+// Resume lets the VUs of a paused test start iterations again
+func (m *Manager) Resume(id string) (*Test, error) {
+	return m.setPaused(id, false)
+}
+
+func (m *Manager) setPaused(id string, paused bool) (*Test, error) {
+	t, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != StateRunning {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotRunning, id, t.state)
+	}
+	if paused {
+		t.agent.Pause()
+	} else {
+		t.agent.Resume()
+	}
+	return t, nil
+}
+
+// Scale changes the VU count of the test with the given ID to vus, see
+// agent.Agent.Scale. Scaling up is refused when the host's limits cannot
+// sustain the new VU count, see preflight.Check.
+func (m *Manager) Scale(id string, vus int) (*Test, error) {
+	return m.scale(id, func(int) int { return vus })
+}
+
+// ScaleBy multiplies the VU count of the test with the given ID by factor,
+// e.g. 2 to double it or 0.1 to drop to 10%, keeping at least one VU. The
+// factor is at most maxScaleFactor.
+func (m *Manager) ScaleBy(id string, factor float64) (*Test, error) {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return nil, fmt.Errorf("%w: factor must be positive, got %g", ErrInvalidScale, factor)
+	}
+	if factor > maxScaleFactor {
+		return nil, fmt.Errorf("%w: factor must be at most %d, got %g", ErrInvalidScale, maxScaleFactor, factor)
+	}
+	return m.scale(id, func(vus int) int {
+		return max(int(math.Round(float64(vus)*factor)), 1)
+	})
+}
+
+func (m *Manager) scale(id string, vus func(current int) int) (*Test, error) {
+	t, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != StateRunning {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotRunning, id, t.state)
+	}
+	current := t.agent.VUs()
+	n := vus(current)
+	if n > current {
+		if err := preflight.Err(preflight.Check(uint64(n))); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidScale, err)
+		}
+	}
+	if err := t.agent.Scale(n); err != nil {
+		if errors.Is(err, agent.ErrNotRunning) {
+			return nil, fmt.Errorf("%w: %s", ErrNotRunning, id)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrInvalidScale, err)
+	}
+	return t, nil
+}
+
+// StopAll stops every running test, for shutdown
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.tests))
+	for id := range m.tests {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	for _, id := range ids {
+		m.Stop(id)
+	}
+}
+
+func (t *Test) run(ctx context.Context, interval time.Duration) {
+	defer close(t.done)
+	defer t.cancel(nil)
+
+	publishCtx, stopPublishing := context.WithCancel(ctx)
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		t.publish(publishCtx, interval)
+	}()
+
+	result, err := t.agent.Run(ctx)
+	stopPublishing()
+	<-published
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finishedAt = time.Now()
+	t.result, t.err = result, err
+	switch {
+	case err != nil:
+		t.state = StateFailed
+	case t.stopped:
+		t.state = StateStopped
+	default:
+		t.state = StateCompleted
+	}
+
+	for ch := range t.subscribers {
+		close(ch)
+		delete(t.subscribers, ch)
+	}
+}
+
+// publish sends a snapshot of every interval to subscribers until ctx ends
+func (t *Test) publish(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := t.StartedAt
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			snap := &Snapshot{
+				Time:      now,
+				Elapsed:   now.Sub(t.StartedAt),
+				ActiveVUs: t.agent.ActiveVUs(),
+				Metrics:   t.window.Flush(now.Sub(last)),
+			}
+			last = now
+
+			t.mu.Lock()
+			for ch := range t.subscribers {
+				select {
+				case ch <- snap:
+				default:
+				}
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+// Subscribe returns a channel receiving a snapshot of every following
+// metrics interval. It is closed when the test finishes or unsubscribe is
+// called.
+func (t *Test) Subscribe() (snapshots <-chan *Snapshot, unsubscribe func()) {
+	ch := make(chan *Snapshot, subscriberBuffer)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != StateRunning {
+		close(ch)
+		return ch, func() {}
+	}
+	t.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Events returns the test's timeline so far
+func (t *Test) Events() []timeline.Event {
+	return t.events.Events()
+}
+
+// Done is closed once the test has finished
+func (t *Test) Done() <-chan struct{} {
+	return t.done
+}
+
+// Status returns the current state of the test
+func (t *Test) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := Status{
+		ID:        t.ID,
+		Scenario:  t.Scenario.Name,
+		State:     t.state,
+		StartedAt: t.StartedAt,
+		VUs:       t.agent.VUs(),
+		Err:       t.err,
+		Result:    t.result,
+	}
+	if t.state == StateRunning {
+		s.Elapsed = time.Since(t.StartedAt)
+		s.ActiveVUs = t.agent.ActiveVUs()
+		if t.agent.Paused() {
+			s.State = StatePaused
+		}
+	} else {
+		s.Elapsed = t.finishedAt.Sub(t.StartedAt)
+	}
+	return s
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate test ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
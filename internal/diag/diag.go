@@ -0,0 +1,75 @@
+// Package diag helps debug the performance of the agent itself: it serves
+// pprof and expvar endpoints, detects when the agent cannot keep up with
+// its own load and captures profiles of it.
+package diag
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+)
+
+// Handler serves pprof under /debug/pprof/ and expvar under /debug/vars
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// CaptureProfiles writes a CPU profile covering d, or until ctx ends, and
+// a heap profile taken after it to dir. Both file names start with the
+// capture time. It returns the paths of the profiles written.
+func CaptureProfiles(ctx context.Context, dir string, d time.Duration) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	stamp := time.Now().Format("20060102-150405")
+
+	cpuPath := filepath.Join(dir, stamp+"-cpu.pprof")
+	f, err := os.Create(cpuPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := runtimepprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		os.Remove(cpuPath)
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	timer := time.NewTimer(d)
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	timer.Stop()
+	runtimepprof.StopCPUProfile()
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write CPU profile: %w", err)
+	}
+
+	heapPath := filepath.Join(dir, stamp+"-heap.pprof")
+	f, err = os.Create(heapPath)
+	if err != nil {
+		return []string{cpuPath}, fmt.Errorf("failed to create heap profile: %w", err)
+	}
+	runtime.GC()
+	err = runtimepprof.WriteHeapProfile(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return []string{cpuPath}, fmt.Errorf("failed to write heap profile: %w", err)
+	}
+	return []string{cpuPath, heapPath}, nil
+}
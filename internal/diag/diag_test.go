@@ -0,0 +1,61 @@
+package diag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/vars"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestCaptureProfiles(t *testing.T) {
+	dir := t.TempDir()
+	profiles, err := CaptureProfiles(context.Background(), dir, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("CaptureProfiles() failed: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("expected CPU and heap profiles, got %v", profiles)
+	}
+	for _, p := range profiles {
+		if info, err := os.Stat(p); err != nil || info.Size() == 0 {
+			t.Errorf("expected a non-empty profile at %s: %v", p, err)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	buckets := []float64{0, 0.001, 0.005, 0.01, 0.1}
+	tests := []struct {
+		counts []uint64
+		want   time.Duration
+	}{
+		{[]uint64{0, 0, 0, 0}, 0},
+		{[]uint64{100, 0, 0, 0}, 0},
+		{[]uint64{98, 1, 1, 0}, time.Millisecond},
+		{[]uint64{90, 5, 3, 2}, 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(tt.counts, buckets, 0.99); got != tt.want {
+			t.Errorf("percentile(%v) = %s, want %s", tt.counts, got, tt.want)
+		}
+	}
+}
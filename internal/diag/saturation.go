@@ -0,0 +1,75 @@
+package diag
+
+import (
+	"math"
+	"runtime/metrics"
+	"time"
+)
+
+// SaturationLatency is the p99 scheduling latency from which the agent
+// counts as saturated. Runnable goroutines waiting this long for a CPU
+// delay sending requests and reading responses, which then shows up as
+// target latency.
+const SaturationLatency = 5 * time.Millisecond
+
+const schedLatencies = "/sched/latencies:seconds"
+
+// SchedSampler measures the agent's goroutine scheduling latency between
+// successive calls to Sample
+type SchedSampler struct {
+	samples []metrics.Sample
+	last    []uint64
+}
+
+// NewSchedSampler starts measuring from now
+func NewSchedSampler() *SchedSampler {
+	s := &SchedSampler{samples: []metrics.Sample{{Name: schedLatencies}}}
+	s.Sample()
+	return s
+}
+
+// Sample returns the 99th percentile scheduling latency since the previous
+// call
+func (s *SchedSampler) Sample() time.Duration {
+	metrics.Read(s.samples)
+	if s.samples[0].Value.Kind() != metrics.KindFloat64Histogram {
+		return 0
+	}
+	h := s.samples[0].Value.Float64Histogram()
+
+	delta := make([]uint64, len(h.Counts))
+	for i, c := range h.Counts {
+		delta[i] = c
+		if i < len(s.last) {
+			delta[i] -= s.last[i]
+		}
+	}
+	s.last = append(s.last[:0], h.Counts...)
+	return percentile(delta, h.Buckets, 0.99)
+}
+
+// percentile returns the lower bound of the bucket holding the p-th
+// quantile of counts, where bucket i spans buckets[i] to buckets[i+1]
+func percentile(counts []uint64, buckets []float64, p float64) time.Duration {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(float64(total) * p))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			lower := buckets[i]
+			if math.IsInf(lower, -1) {
+				lower = 0
+			}
+			return time.Duration(lower * float64(time.Second))
+		}
+	}
+	return 0
+}
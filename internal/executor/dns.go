@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dnsResolver resolves the hosts of connections, to their address in hosts
+// if any, otherwise from a cache of the addresses looked up in the last ttl
+// when ttl is set
+type dnsResolver struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+	hosts    map[string]string
+
+	mu    sync.Mutex
+	cache map[string]*dnsEntry
+}
+
+// dnsEntry is the lookup of a host, ready once done is closed
+type dnsEntry struct {
+	done    chan struct{}
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// newDNSResolver returns a resolver querying server, a host and optional
+// port, or the system's resolver if empty, and caching for ttl. hosts
+// overrides the addresses of hosts, in the format of TransportOptions.Hosts.
+func newDNSResolver(server string, ttl time.Duration, hosts string) (*dnsResolver, error) {
+	r := &dnsResolver{resolver: net.DefaultResolver, ttl: ttl, cache: make(map[string]*dnsEntry)}
+	if hosts != "" {
+		r.hosts = make(map[string]string)
+		for _, entry := range strings.Split(hosts, ",") {
+			host, ip, _ := strings.Cut(entry, "=")
+			if _, err := netip.ParseAddr(ip); err != nil || host == "" {
+				return nil, fmt.Errorf("invalid host override '%s', must be host=ip", entry)
+			}
+			r.hosts[strings.ToLower(host)] = ip
+		}
+	}
+	if server == "" {
+		return r, nil
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	host, _, _ := net.SplitHostPort(server)
+	if _, err := netip.ParseAddr(host); err != nil {
+		return nil, fmt.Errorf("DNS server '%s' must be an IP address", server)
+	}
+	var dialer net.Dialer
+	r.resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+	return r, nil
+}
+
+// lookup returns the addresses of host. Concurrent lookups of a host not
+// cached wait for a single query, so a burst of new connections sends the
+// resolver one query per host; failures are not cached.
+func (r *dnsResolver) lookup(ctx context.Context, host string) ([]string, error) {
+	if ip, ok := r.hosts[strings.ToLower(strings.TrimSuffix(host, "."))]; ok {
+		return []string{ip}, nil
+	}
+	if r.ttl <= 0 {
+		return r.resolver.LookupHost(ctx, host)
+	}
+
+	r.mu.Lock()
+	entry, ok := r.cache[host]
+	if ok {
+		select {
+		case <-entry.done:
+			if entry.err != nil || time.Now().After(entry.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if !ok {
+		entry = &dnsEntry{done: make(chan struct{})}
+		r.cache[host] = entry
+		r.mu.Unlock()
+		// The query outlives the context of the connection it is for, as
+		// others may wait for it
+		entry.addrs, entry.err = r.resolver.LookupHost(context.WithoutCancel(ctx), host)
+		entry.expires = time.Now().Add(r.ttl)
+		close(entry.done)
+		return entry.addrs, entry.err
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		return entry.addrs, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dialContext returns a DialContext resolving host names with r, trying
+// their addresses in turn, and dialing with dialer
+func (r *dnsResolver) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := netip.ParseAddr(host); err == nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := r.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var conn net.Conn
+		for _, ip := range addrs {
+			if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = fmt.Errorf("no addresses found for %s", host)
+		}
+		return nil, err
+	}
+}
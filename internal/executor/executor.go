@@ -2,11 +2,18 @@ package executor
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -15,13 +22,225 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// DefaultTimeout bounds requests without a timeout of their own, from
+// sending them to reading the whole response
+const DefaultTimeout = 30 * time.Second
+
+// HTTP versions an executor sends requests with
+const (
+	// HTTPVersionAuto negotiates HTTP/2 with TLS servers supporting it and
+	// uses HTTP/1.1 otherwise
+	HTTPVersionAuto = "auto"
+	// HTTPVersion1 uses HTTP/1.1 only
+	HTTPVersion1 = "1.1"
+	// HTTPVersion2 uses HTTP/2 only, over TLS or, with plain HTTP servers,
+	// h2c with prior knowledge. Responses over another version are
+	// reported as errors.
+	HTTPVersion2 = "2"
+)
+
+// TransportOptions configure the transport of an executor: the HTTP
+// version, the connection pool and how redirects are followed. The pool settings are those of
+// http.Transport, so a limit of 0 is none but for MaxIdleConnsPerHost,
+// which then is http.DefaultMaxIdleConnsPerHost.
+type TransportOptions struct {
+	// HTTPVersion is one of the HTTPVersion constants, HTTPVersionAuto
+	// if empty
+	HTTPVersion string
+	// MaxIdleConns bounds the idle connections kept across all hosts
+	MaxIdleConns int
+	// MaxIdleConnsPerHost bounds the idle connections kept per host;
+	// connections beyond are closed once their request completed
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost bounds the connections per host, in use or idle;
+	// requests beyond wait for a connection
+	MaxConnsPerHost int
+	// IdleConnTimeout closes connections idle for longer
+	IdleConnTimeout time.Duration
+	// DisableKeepAlives uses a new connection for every request
+	DisableKeepAlives bool
+	// ClientCert is presented to servers requesting a client certificate
+	ClientCert *tls.Certificate
+	// RootCAs verify servers instead of the system's CAs when set
+	RootCAs *x509.CertPool
+	// InsecureSkipVerify accepts any server certificate
+	InsecureSkipVerify bool
+	// Proxy is the URL of the proxy requests are sent through, with
+	// scheme http, https, socks5 or socks5h and any credentials as user
+	// info. Empty uses the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables.
+	Proxy string
+	// NoProxy lists the hosts reached without Proxy, in the format of
+	// NO_PROXY: comma-separated host names, domains, IP addresses and CIDR
+	// ranges, e.g. "internal.example.com,.corp,10.0.0.0/8"
+	NoProxy string
+	// DNSServer is the address of the DNS server host names are resolved
+	// with, an IP address and optional port, instead of the system's
+	// resolver
+	DNSServer string
+	// DNSCacheTTL caches the addresses of host names for this long, so
+	// new connections do not each query the resolver. 0 disables the
+	// cache.
+	DNSCacheTTL time.Duration
+	// Hosts overrides the addresses of host names like /etc/hosts does,
+	// as comma-separated host=ip pairs, e.g.
+	// "api.example.com=10.0.0.5,cdn.example.com=10.0.0.6". Requests keep
+	// the host name for the Host header and TLS; requests through a proxy
+	// are resolved by the proxy.
+	Hosts string
+	// Redirects is one of the Redirect policies, RedirectFollow if empty
+	Redirects string
+	// MaxRedirects bounds the redirects followed, DefaultMaxRedirects if
+	// 0
+	MaxRedirects int
+	// Retry retries failed requests, never if nil. Executors sharing the
+	// policy share its budget.
+	Retry *RetryPolicy
+}
+
+// tlsConfig returns the TLS configuration of opts, nil for the default one
+func (opts TransportOptions) tlsConfig() *tls.Config {
+	if opts.ClientCert == nil && opts.RootCAs == nil && !opts.InsecureSkipVerify {
+		return nil
+	}
+	config := &tls.Config{RootCAs: opts.RootCAs, InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.ClientCert != nil {
+		config.Certificates = []tls.Certificate{*opts.ClientCert}
+	}
+	return config
+}
+
+// DefaultTransportOptions are the settings of http.DefaultTransport
+var DefaultTransportOptions = TransportOptions{
+	HTTPVersion:     HTTPVersionAuto,
+	MaxIdleConns:    100,
+	IdleConnTimeout: 90 * time.Second,
+}
+
+// transports holds the transport of each TransportOptions, so executors
+// with the same options share their connection pool like those using
+// http.DefaultTransport do
+var transports sync.Map
+
+// certificates holds the client certificates and CA pools of the
+// transports, see canonicalTLS
+var certificates struct {
+	sync.Mutex
+	clientCerts []*tls.Certificate
+	rootCAs     []*x509.CertPool
+}
+
+// canonicalTLS replaces the client certificate and CA pool of opts by the
+// first ones seen with the same content. Every run loads them anew, so
+// transports keyed by their pointers would add up, idle connections and
+// all, with every run of an agent that serves tests.
+func canonicalTLS(opts *TransportOptions) {
+	certificates.Lock()
+	defer certificates.Unlock()
+	if cert := opts.ClientCert; cert != nil {
+		i := slices.IndexFunc(certificates.clientCerts, func(c *tls.Certificate) bool {
+			return slices.EqualFunc(c.Certificate, cert.Certificate, bytes.Equal)
+		})
+		if i < 0 {
+			certificates.clientCerts = append(certificates.clientCerts, cert)
+		} else {
+			opts.ClientCert = certificates.clientCerts[i]
+		}
+	}
+	if pool := opts.RootCAs; pool != nil {
+		i := slices.IndexFunc(certificates.rootCAs, pool.Equal)
+		if i < 0 {
+			certificates.rootCAs = append(certificates.rootCAs, pool)
+		} else {
+			opts.RootCAs = certificates.rootCAs[i]
+		}
+	}
+}
+
+// transport returns the transport of opts
+func transport(opts TransportOptions) (http.RoundTripper, error) {
+	opts.HTTPVersion = cmp.Or(opts.HTTPVersion, HTTPVersionAuto)
+	// Redirects are followed by the client and retries by the executor,
+	// so all their policies share the transport
+	opts.Redirects, opts.MaxRedirects, opts.Retry = "", 0, nil
+	var protocols *http.Protocols
+	switch opts.HTTPVersion {
+	case HTTPVersionAuto:
+	case HTTPVersion1:
+		protocols = new(http.Protocols)
+		protocols.SetHTTP1(true)
+	case HTTPVersion2:
+		protocols = new(http.Protocols)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("unknown HTTP version '%s'", opts.HTTPVersion)
+	}
+	proxy, err := opts.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	var dns *dnsResolver
+	if opts.DNSServer != "" || opts.DNSCacheTTL > 0 || opts.Hosts != "" {
+		if dns, err = newDNSResolver(opts.DNSServer, opts.DNSCacheTTL, opts.Hosts); err != nil {
+			return nil, err
+		}
+	}
+	if opts == DefaultTransportOptions {
+		return http.DefaultTransport, nil
+	}
+	canonicalTLS(&opts)
+	if t, ok := transports.Load(opts); ok {
+		return t.(*http.Transport), nil
+	}
+
+	// The other settings of http.DefaultTransport, which Clone cannot be
+	// used for since it keeps the HTTP/2 setup of a transport already used
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	dial := dialer.DialContext
+	if dns != nil {
+		dial = dns.dialContext(dialer)
+	}
+	t, _ := transports.LoadOrStore(opts, &http.Transport{
+		Proxy:                 func(req *http.Request) (*url.URL, error) { return proxy(req.URL) },
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		DisableKeepAlives:     opts.DisableKeepAlives,
+		TLSClientConfig:       opts.tlsConfig(),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		Protocols:             protocols,
+	})
+	return t.(*http.Transport), nil
+}
+
 // Request represents an HTTP request to be executed
 type Request struct {
 	Method  string
 	URL     string
 	Headers map[string]string
 	Body    []byte
+	// BodyReader, when set, opens the body to stream instead of Body, once
+	// per attempt, redirect or connection retry. ContentLength is its
+	// size; 0 sends it chunked.
+	BodyReader    func() (io.ReadCloser, error)
+	ContentLength int64
+	// Timeout overrides the executor's timeout when set
 	Timeout time.Duration
+	// Raw, when set, is written as is to the host of URL instead of the
+	// request built from the other fields, which are ignored. It must be
+	// a complete HTTP/1.1 request; the connection is kept for the next
+	// raw request. Cookies are neither sent nor stored.
+	Raw []byte
+	// MaxBodySize bounds the bytes of the response body kept in
+	// Response.Body; 0 keeps it whole. DiscardBody keeps none. Either way
+	// the body is read to its end.
+	MaxBodySize int64
+	DiscardBody bool
 }
 
 // Response represents an HTTP response
@@ -30,31 +249,94 @@ type Response struct {
 	Status     string
 	Headers    map[string][]string
 	Body       []byte
-	Duration   time.Duration
+	// BodySize is the size of the body received, which Body may keep
+	// only part of
+	BodySize int64
+	// Duration is the time to the response headers, following redirects
+	// but for the RedirectRecord policy, where it is that of the last
+	// request
+	Duration time.Duration
+	// Redirects are the redirects followed with RedirectRecord, in order
+	Redirects []Redirect
+	// Retried are the attempts retried before this response, in order;
+	// Duration is that of the last attempt
+	Retried []Attempt
+	// Proto is the protocol the response was received over, e.g.
+	// HTTP/1.1 or HTTP/2.0
+	Proto string
 }
 
 // Executor handles HTTP request execution
 type Executor struct {
 	client HTTPClient
 	jar    http.CookieJar
+	// timeout applies to requests without a timeout; 0 leaves it to client
+	timeout time.Duration
+	// requireHTTP2 fails responses received over another version than
+	// HTTP/2
+	requireHTTP2 bool
+	// tlsConfig is the TLS configuration of raw requests, nil for the
+	// default one, proxy returns their proxy and dial connects to their
+	// host, resolving it like the transport
+	tlsConfig *tls.Config
+	proxy     func(*url.URL) (*url.URL, error)
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	// redirects is the redirect policy and maxRedirects the redirects
+	// followed at most
+	redirects    string
+	maxRedirects int
+	// retry retries failed requests, nil for none
+	retry *RetryPolicy
+
+	rawMu sync.Mutex
+	raw   *rawConn
 }
 
 // New creates a new Executor with default settings
 func New() (*Executor, error) {
+	return NewWithTransport(DefaultTransportOptions)
+}
+
+// NewWithTransport creates a new Executor whose transport is configured by
+// opts
+func NewWithTransport(opts TransportOptions) (*Executor, error) {
+	rt, err := transport(opts)
+	if err != nil {
+		return nil, err
+	}
+	redirects := cmp.Or(opts.Redirects, RedirectFollow)
+	switch redirects {
+	case RedirectFollow, RedirectNone, RedirectRecord:
+	default:
+		return nil, fmt.Errorf("unknown redirect policy '%s'", redirects)
+	}
+	proxy, err := opts.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
 	}
 
-	client := &http.Client{
-		Jar:     jar,
-		Timeout: 30 * time.Second,
-	}
+	// The timeout is applied per request rather than by the client, so
+	// requests can set a longer one
+	client := &http.Client{Jar: jar, Transport: rt}
 
-	return &Executor{
-		client: client,
-		jar:    jar,
-	}, nil
+	e := &Executor{
+		client:       client,
+		jar:          jar,
+		timeout:      DefaultTimeout,
+		requireHTTP2: opts.HTTPVersion == HTTPVersion2,
+		tlsConfig:    opts.tlsConfig(),
+		proxy:        proxy,
+		dial:         rt.(*http.Transport).DialContext,
+		redirects:    redirects,
+		maxRedirects: cmp.Or(opts.MaxRedirects, DefaultMaxRedirects),
+		retry:        opts.Retry,
+	}
+	client.CheckRedirect = e.checkRedirect
+	return e, nil
 }
 
 // NewWithClient creates a new Executor with a custom HTTP client
@@ -74,41 +356,70 @@ func (e *Executor) Execute(ctx context.Context, req *Request) (*Response, error)
 		return nil, fmt.Errorf("URL cannot be empty")
 	}
 
+	if req.Raw != nil {
+		return e.executeRaw(ctx, req)
+	}
+
 	if req.Method == "" {
 		req.Method = http.MethodGet
 	}
 
+	if e.retry != nil {
+		return e.sendWithRetries(ctx, req)
+	}
+	return e.send(ctx, req)
+}
+
+// send performs a single attempt of req
+func (e *Executor) send(ctx context.Context, req *Request) (*Response, error) {
 	var bodyReader io.Reader
-	if req.Body != nil {
+	if req.BodyReader != nil {
+		body, err := req.BodyReader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open request body: %w", err)
+		}
+		bodyReader = body
+	} else if req.Body != nil {
 		bodyReader = bytes.NewReader(req.Body)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bodyReader)
 	if err != nil {
+		if c, ok := bodyReader.(io.Closer); ok {
+			c.Close()
+		}
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if req.BodyReader != nil {
+		httpReq.ContentLength = req.ContentLength
+		httpReq.GetBody = req.BodyReader
+	}
 
 	for key, value := range req.Headers {
 		httpReq.Header.Set(key, value)
 	}
 
-	if req.Timeout > 0 {
+	if timeout := cmp.Or(req.Timeout, e.timeout); timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 		httpReq = httpReq.WithContext(ctx)
 	}
 
 	start := time.Now()
-	httpResp, err := e.client.Do(httpReq)
-	duration := time.Since(start)
+	ctx, hops := e.withRedirects(ctx, start)
+	httpResp, err := e.client.Do(httpReq.WithContext(ctx))
+	end := time.Now()
 
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer httpResp.Body.Close()
+	if e.requireHTTP2 && httpResp.ProtoMajor != 2 {
+		return nil, fmt.Errorf("request failed: response received over %s rather than HTTP/2", httpResp.Proto)
+	}
 
-	respBody, err := io.ReadAll(httpResp.Body)
+	respBody, size, err := readBody(httpResp.Body, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -118,12 +429,38 @@ func (e *Executor) Execute(ctx context.Context, req *Request) (*Response, error)
 		Status:     httpResp.Status,
 		Headers:    httpResp.Header,
 		Body:       respBody,
-		Duration:   duration,
+		BodySize:   size,
+		Duration:   end.Sub(start),
+		Proto:      httpResp.Proto,
+	}
+	if hops != nil {
+		response.Redirects = hops.hops
+		response.Duration = end.Sub(hops.last)
 	}
 
 	return response, nil
 }
 
+// readBody reads body to its end, returning the part of it req keeps and
+// its size
+func readBody(body io.Reader, req *Request) ([]byte, int64, error) {
+	switch {
+	case req.DiscardBody:
+		n, err := io.Copy(io.Discard, body)
+		return nil, n, err
+	case req.MaxBodySize > 0:
+		kept, err := io.ReadAll(io.LimitReader(body, req.MaxBodySize))
+		if err != nil {
+			return nil, 0, err
+		}
+		n, err := io.Copy(io.Discard, body)
+		return kept, int64(len(kept)) + n, err
+	default:
+		kept, err := io.ReadAll(body)
+		return kept, int64(len(kept)), err
+	}
+}
+
 func (e *Executor) GET(ctx context.Context, url string, headers map[string]string) (*Response, error) {
 	req := &Request{
 		Method:  http.MethodGet,
@@ -1,15 +1,33 @@
 package executor
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 type mockHTTPClient struct {
@@ -39,6 +57,608 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewWithTransport_HTTPVersion(t *testing.T) {
+	// The server speaks HTTP/1.1, and HTTP/2 to clients starting with it
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		version, want string
+	}{
+		{HTTPVersionAuto, "HTTP/1.1"},
+		{HTTPVersion1, "HTTP/1.1"},
+		{HTTPVersion2, "HTTP/2.0"},
+	} {
+		executor, err := NewWithTransport(TransportOptions{HTTPVersion: tt.version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := executor.GET(context.Background(), server.URL, nil)
+		if err != nil {
+			t.Fatalf("version %s: %v", tt.version, err)
+		}
+		if resp.Proto != tt.want || string(resp.Body) != tt.want {
+			t.Errorf("version %s: response over %s to a request over %s, want %s", tt.version, resp.Proto, resp.Body, tt.want)
+		}
+	}
+
+	if _, err := NewWithTransport(TransportOptions{HTTPVersion: "3"}); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestNewWithTransport_RequireHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = false
+	server.StartTLS()
+	defer server.Close()
+
+	// A server negotiating HTTP/1.1 fails the request
+	executor, err := NewWithTransport(TransportOptions{HTTPVersion: HTTPVersion2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetHTTP1(true)
+	executor.client.(*http.Client).Transport = transport
+	if _, err := executor.GET(context.Background(), server.URL, nil); err == nil || !strings.Contains(err.Error(), "rather than HTTP/2") {
+		t.Errorf("expected an error for a response over HTTP/1.1, got %v", err)
+	}
+}
+
+func TestNewWithTransport_Pool(t *testing.T) {
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	for _, tt := range []struct {
+		opts TransportOptions
+		want int64
+	}{
+		{TransportOptions{MaxIdleConnsPerHost: 4}, 1},
+		{TransportOptions{MaxIdleConnsPerHost: 4, DisableKeepAlives: true}, 3},
+	} {
+		executor, err := NewWithTransport(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns.Store(0)
+		for range 3 {
+			if _, err := executor.GET(context.Background(), server.URL, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := conns.Load(); got != tt.want {
+			t.Errorf("%+v: %d connections for 3 requests, want %d", tt.opts, got, tt.want)
+		}
+	}
+
+	// Executors with the same options share their connection pool
+	a, _ := NewWithTransport(TransportOptions{MaxConnsPerHost: 8})
+	b, _ := NewWithTransport(TransportOptions{MaxConnsPerHost: 8})
+	c, _ := NewWithTransport(TransportOptions{MaxConnsPerHost: 16})
+	transport := func(e *Executor) http.RoundTripper { return e.client.(*http.Client).Transport }
+	if transport(a) != transport(b) || transport(a) == transport(c) {
+		t.Error("expected executors to share the transport of the same options only")
+	}
+	if d, _ := New(); transport(d) != http.DefaultTransport {
+		t.Error("expected New to use http.DefaultTransport")
+	}
+
+	// Certificates loaded anew, as every run does, share the transport of
+	// the same certificates
+	cert := clientCertificate(t, "load-tester")
+	poolOf := func(cert *tls.Certificate) *x509.CertPool {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool := x509.NewCertPool()
+		pool.AddCert(leaf)
+		return pool
+	}
+	tlsOpts := func(cert *tls.Certificate, ca *x509.CertPool) TransportOptions {
+		certCopy := *cert
+		return TransportOptions{ClientCert: &certCopy, RootCAs: ca}
+	}
+	a, _ = NewWithTransport(tlsOpts(cert, poolOf(cert)))
+	b, _ = NewWithTransport(tlsOpts(cert, poolOf(cert)))
+	other := clientCertificate(t, "other")
+	c, _ = NewWithTransport(tlsOpts(other, poolOf(cert)))
+	d, _ := NewWithTransport(tlsOpts(cert, poolOf(other)))
+	if transport(a) != transport(b) || transport(a) == transport(c) || transport(a) == transport(d) {
+		t.Error("expected executors to share the transport of the same certificates only")
+	}
+}
+
+// clientCertificate returns a self-signed client certificate
+func clientCertificate(t *testing.T, name string) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestNewWithTransport_ClientCert(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	executor, err := NewWithTransport(TransportOptions{ClientCert: clientCertificate(t, "load-tester"), RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer executor.Close()
+	resp, err := executor.GET(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != "load-tester" {
+		t.Errorf("server saw the client certificate of %q", resp.Body)
+	}
+
+	// Raw requests present it too
+	resp, err = executor.Execute(context.Background(), &Request{
+		URL: server.URL,
+		Raw: []byte("GET / HTTP/1.1\r\nHost: example\r\n\r\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != "load-tester" {
+		t.Errorf("server saw the client certificate of %q over a raw request", resp.Body)
+	}
+
+	// Without the certificate, the server refuses the connection
+	executor, err = NewWithTransport(TransportOptions{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executor.GET(context.Background(), server.URL, nil); err == nil {
+		t.Error("expected the request without a client certificate to fail")
+	}
+}
+
+func TestNewWithTransport_InsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The server's certificate is self-signed
+	for _, tt := range []struct {
+		opts    TransportOptions
+		wantErr bool
+	}{
+		{DefaultTransportOptions, true},
+		{TransportOptions{InsecureSkipVerify: true}, false},
+	} {
+		executor, err := NewWithTransport(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := executor.GET(context.Background(), server.URL, nil); (err != nil) != tt.wantErr {
+			t.Errorf("insecure %v: expected error %v, got %v", tt.opts.InsecureSkipVerify, tt.wantErr, err)
+		}
+	}
+}
+
+func TestNewWithTransport_Proxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "raw")
+	}))
+	defer target.Close()
+
+	// The proxy answers requests itself, and tunnels CONNECT requests to
+	// target whatever their host
+	var proxied atomic.Int64
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		if r.Header.Get("Proxy-Authorization") != "Basic bG9hZDpzM2NyM3Q=" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if r.Method != http.MethodConnect {
+			fmt.Fprintf(w, "proxied %s", r.URL)
+			return
+		}
+		upstream, err := net.Dial("tcp", target.Listener.Addr().String())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		go func() {
+			io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("load", "s3cr3t")
+	executor, err := NewWithTransport(TransportOptions{Proxy: proxyURL.String(), NoProxy: "direct.invalid,.corp.invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer executor.Close()
+
+	resp, err := executor.GET(context.Background(), "http://api.example/users?page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != "proxied http://api.example/users?page=2" {
+		t.Errorf("response = %q, want that of the proxy", resp.Body)
+	}
+
+	resp, err = executor.Execute(context.Background(), &Request{
+		URL: "http://raw.example",
+		Raw: []byte("GET / HTTP/1.1\r\nHost: raw.example\r\n\r\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != "raw" {
+		t.Errorf("raw response = %q, want that of the target through the tunnel", resp.Body)
+	}
+
+	// Hosts of NoProxy and loopback addresses are reached directly
+	proxied.Store(0)
+	for _, u := range []string{"http://direct.invalid", "http://api.corp.invalid", target.URL} {
+		executor.GET(context.Background(), u, nil)
+	}
+	if n := proxied.Load(); n != 0 {
+		t.Errorf("%d requests went through the proxy, want none", n)
+	}
+
+	if _, err := NewWithTransport(TransportOptions{Proxy: "ftp://proxy.example"}); err == nil {
+		t.Error("expected an error for an unsupported proxy scheme")
+	}
+}
+
+// dnsServer serves A records of 127.0.0.1 for every name over UDP, and
+// counts the queries received
+func dnsServer(t *testing.T) (string, *atomic.Int64) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var queries atomic.Int64
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) != 1 {
+				continue
+			}
+			queries.Add(1)
+			q := msg.Questions[0]
+			msg.Header.Response, msg.Header.Authoritative = true, true
+			if q.Type == dnsmessage.TypeA {
+				msg.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+				}}
+			}
+			if reply, err := msg.Pack(); err == nil {
+				conn.WriteTo(reply, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+func TestNewWithTransport_DNS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	target := "http://svc.loadforge.test:" + port
+
+	for _, tt := range []struct {
+		name     string
+		ttl      time.Duration
+		wantMany bool
+	}{
+		{name: "cached", ttl: time.Minute},
+		{name: "uncached", wantMany: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, queries := dnsServer(t)
+			// Without keep-alives every request resolves its host anew
+			executor, err := NewWithTransport(TransportOptions{DNSServer: addr, DNSCacheTTL: tt.ttl, DisableKeepAlives: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range 5 {
+				if _, err := executor.GET(context.Background(), target, nil); err != nil {
+					t.Fatalf("GET() failed: %v", err)
+				}
+			}
+			// A lookup queries both A and AAAA records
+			if n := queries.Load(); (n > 2) != tt.wantMany {
+				t.Errorf("%d DNS queries for 5 requests", n)
+			}
+		})
+	}
+
+	if _, err := NewWithTransport(TransportOptions{DNSServer: "dns.example"}); err == nil {
+		t.Error("expected an error for a DNS server host name")
+	}
+}
+
+func TestNewWithTransport_Hosts(t *testing.T) {
+	// The certificate of httptest servers is valid for example.com
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.TLS.ServerName)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	target := "https://example.com:" + port
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	executor, err := NewWithTransport(TransportOptions{RootCAs: pool, Hosts: "other.example=10.0.0.1,EXAMPLE.com=127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer executor.Close()
+
+	want := "example.com:" + port + " example.com"
+	resp, err := executor.GET(context.Background(), target, nil)
+	if err != nil {
+		t.Fatalf("GET() failed: %v", err)
+	}
+	if string(resp.Body) != want {
+		t.Errorf("response = %q, want %q", resp.Body, want)
+	}
+
+	resp, err = executor.Execute(context.Background(), &Request{
+		URL: target,
+		Raw: []byte("GET / HTTP/1.1\r\nHost: example.com:" + port + "\r\n\r\n"),
+	})
+	if err != nil {
+		t.Fatalf("raw request failed: %v", err)
+	}
+	if string(resp.Body) != want {
+		t.Errorf("raw response = %q, want %q", resp.Body, want)
+	}
+
+	for _, hosts := range []string{"example.com", "example.com=localhost", "=127.0.0.1"} {
+		if _, err := NewWithTransport(TransportOptions{Hosts: hosts}); err == nil {
+			t.Errorf("hosts %q: expected an error", hosts)
+		}
+	}
+}
+
+func TestNewWithTransport_Retry(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every third request succeeds
+		if requests.Add(1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	policy := &RetryPolicy{
+		Max:         3,
+		Backoff:     time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+		RetryStatus: func(status int) bool { return status == http.StatusServiceUnavailable },
+	}
+	executor, err := NewWithTransport(TransportOptions{Retry: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := executor.GET(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatalf("GET() failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(resp.Retried) != 2 || resp.Retried[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 200 after two retried 503s, got %d after %+v", resp.StatusCode, resp.Retried)
+	}
+
+	// The server may have processed a POST it responded to, so its
+	// statuses are only retried when opted in
+	resp, err = executor.POST(context.Background(), server.URL, []byte("{}"), nil)
+	if err != nil {
+		t.Fatalf("POST() failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || len(resp.Retried) != 0 {
+		t.Errorf("expected a 503 without retries, got %d after %+v", resp.StatusCode, resp.Retried)
+	}
+	policy.RetryNonIdempotent = true
+	resp, err = executor.POST(context.Background(), server.URL, []byte("{}"), nil)
+	if err != nil {
+		t.Fatalf("POST() failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(resp.Retried) != 1 {
+		t.Errorf("expected 200 after a retried 503, got %d after %+v", resp.StatusCode, resp.Retried)
+	}
+
+	// Connection errors are retried, within the budget shared by the
+	// executors of the policy
+	policy.Budget = NewRetryBudget(0)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	var retries int
+	for range 5 {
+		executor, err := NewWithTransport(TransportOptions{Retry: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = executor.GET(context.Background(), closed.URL, nil)
+		var retriesErr *RetriesError
+		if errors.As(err, &retriesErr) {
+			retries += len(retriesErr.Retried)
+			if !errors.Is(err, syscall.ECONNREFUSED) {
+				t.Errorf("expected the error to wrap that of the last attempt, got %v", err)
+			}
+		} else if err == nil {
+			t.Fatal("expected GET() to fail")
+		}
+	}
+	if retries != retryBudgetMin {
+		t.Errorf("expected %d retries within the budget, got %d", retryBudgetMin, retries)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		backoff, maxWait time.Duration
+		n                int
+		bound            time.Duration
+	}{
+		{"first", 100 * time.Millisecond, time.Second, 1, 100 * time.Millisecond},
+		{"doubled", 100 * time.Millisecond, time.Second, 3, 400 * time.Millisecond},
+		{"capped", 100 * time.Millisecond, time.Second, 5, time.Second},
+		{"capped far out", 100 * time.Millisecond, time.Second, 80, time.Second},
+		{"unbounded", time.Second, 0, 40, math.MaxInt64},
+		{"unbounded far out", time.Second, 0, 80, math.MaxInt64},
+	} {
+		p := &RetryPolicy{Backoff: tt.backoff, MaxBackoff: tt.maxWait}
+		for range 100 {
+			if d := p.delay(tt.n); d < 0 || d >= tt.bound {
+				t.Fatalf("%s: expected a delay in [0, %s), got %s", tt.name, tt.bound, d)
+			}
+		}
+	}
+	// Past the cap, delays are spread across it rather than collapse
+	p := &RetryPolicy{Backoff: time.Second}
+	var long bool
+	for range 100 {
+		long = long || p.delay(70) > time.Hour
+	}
+	if !long {
+		t.Error("expected delays past an overflowing backoff to stay long")
+	}
+}
+
+func TestNewWithTransport_Redirects(t *testing.T) {
+	delay := 50 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			time.Sleep(delay)
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		redirects  string
+		max        int
+		path       string
+		wantStatus int
+		wantHops   []int
+		wantErr    string
+	}{
+		{name: "follow by default", path: "/a", wantStatus: http.StatusOK},
+		{name: "none", redirects: RedirectNone, path: "/a", wantStatus: http.StatusFound},
+		{name: "record", redirects: RedirectRecord, path: "/a", wantStatus: http.StatusOK,
+			wantHops: []int{http.StatusFound, http.StatusMovedPermanently}},
+		{name: "max", redirects: RedirectFollow, max: 1, path: "/a", wantErr: "stopped after 1 redirects"},
+		{name: "loop", path: "/loop", wantErr: "stopped after 10 redirects"},
+		{name: "record max", redirects: RedirectRecord, max: 3, path: "/loop", wantErr: "stopped after 3 redirects"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultTransportOptions
+			opts.Redirects, opts.MaxRedirects = tt.redirects, tt.max
+			executor, err := NewWithTransport(opts)
+			if err != nil {
+				t.Fatalf("NewWithTransport() failed: %v", err)
+			}
+			resp, err := executor.GET(context.Background(), server.URL+tt.path, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GET() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GET() failed: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(resp.Redirects) != len(tt.wantHops) {
+				t.Fatalf("Redirects = %+v, want statuses %v", resp.Redirects, tt.wantHops)
+			}
+			for i, hop := range resp.Redirects {
+				if hop.StatusCode != tt.wantHops[i] {
+					t.Errorf("Redirects[%d].StatusCode = %d, want %d", i, hop.StatusCode, tt.wantHops[i])
+				}
+			}
+			if tt.redirects == RedirectRecord {
+				if want := server.URL + "/b"; resp.Redirects[1].URL != want {
+					t.Errorf("Redirects[1].URL = %s, want %s", resp.Redirects[1].URL, want)
+				}
+				if resp.Redirects[1].Duration < delay {
+					t.Errorf("Redirects[1].Duration = %s, want >= %s", resp.Redirects[1].Duration, delay)
+				}
+				if resp.Duration >= delay {
+					t.Errorf("Duration = %s includes the redirects", resp.Duration)
+				}
+			}
+		})
+	}
+
+	if _, err := NewWithTransport(TransportOptions{Redirects: "sometimes"}); err == nil {
+		t.Error("NewWithTransport() accepted an unknown redirect policy")
+	}
+}
+
 func TestNewWithClient(t *testing.T) {
 	mockClient := &mockHTTPClient{}
 	executor := NewWithClient(mockClient)
@@ -226,6 +846,26 @@ func TestExecute_Timeout(t *testing.T) {
 	}
 }
 
+func TestExecute_TimeoutOverridesDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	executor, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create executor: %v", err)
+	}
+	executor.timeout = 50 * time.Millisecond
+
+	if _, err := executor.Execute(context.Background(), &Request{URL: server.URL}); err == nil {
+		t.Error("Execute() should time out after the executor's timeout")
+	}
+	if _, err := executor.Execute(context.Background(), &Request{URL: server.URL, Timeout: time.Second}); err != nil {
+		t.Errorf("Execute() with a longer request timeout failed: %v", err)
+	}
+}
+
 func TestExecute_ContextCancellation(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(200 * time.Millisecond)
@@ -604,6 +1244,174 @@ func TestGetCookieJar(t *testing.T) {
 	}
 }
 
+func TestExecute_ResponseBody(t *testing.T) {
+	body := strings.Repeat("x", 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	executor, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		req      Request
+		wantKept int
+	}{
+		{name: "whole", req: Request{}, wantKept: len(body)},
+		{name: "capped", req: Request{MaxBodySize: 100}, wantKept: 100},
+		{name: "cap above size", req: Request{MaxBodySize: 1 << 20}, wantKept: len(body)},
+		{name: "discarded", req: Request{DiscardBody: true}, wantKept: 0},
+		{name: "raw discarded", req: Request{DiscardBody: true, Raw: []byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n")}, wantKept: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.URL = server.URL
+			resp, err := executor.Execute(context.Background(), &tt.req)
+			if err != nil {
+				t.Fatalf("Execute() failed: %v", err)
+			}
+			if len(resp.Body) != tt.wantKept || resp.BodySize != int64(len(body)) {
+				t.Errorf("kept %d of %d bytes, want %d of %d", len(resp.Body), resp.BodySize, tt.wantKept, len(body))
+			}
+		})
+	}
+}
+
+func TestExecute_BodyReader(t *testing.T) {
+	body := strings.Repeat("x", 100000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+			return
+		}
+		n, _ := io.Copy(io.Discard, r.Body)
+		fmt.Fprintf(w, "%d %d", r.ContentLength, n)
+	}))
+	defer server.Close()
+
+	executor, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		path          string
+		contentLength int64
+		want          string
+		wantOpened    int
+	}{
+		{name: "sized", contentLength: int64(len(body)), want: "100000 100000", wantOpened: 1},
+		{name: "chunked", want: "-1 100000", wantOpened: 1},
+		{name: "redirected", path: "/redirect", contentLength: int64(len(body)), want: "100000 100000", wantOpened: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened := 0
+			resp, err := executor.Execute(context.Background(), &Request{
+				Method: http.MethodPost,
+				URL:    server.URL + tt.path,
+				BodyReader: func() (io.ReadCloser, error) {
+					opened++
+					return io.NopCloser(strings.NewReader(body)), nil
+				},
+				ContentLength: tt.contentLength,
+			})
+			if err != nil {
+				t.Fatalf("Execute() failed: %v", err)
+			}
+			if string(resp.Body) != tt.want {
+				t.Errorf("server received %q, want %q", resp.Body, tt.want)
+			}
+			if opened != tt.wantOpened {
+				t.Errorf("body opened %d times, want %d", opened, tt.wantOpened)
+			}
+		})
+	}
+
+	_, err = executor.Execute(context.Background(), &Request{
+		Method:     http.MethodPost,
+		URL:        server.URL,
+		BodyReader: func() (io.ReadCloser, error) { return nil, os.ErrNotExist },
+	})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Execute() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestExecute_Multipart(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(report, []byte("%PDF-1.7"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if int64(len(body)) != r.ContentLength {
+			http.Error(w, fmt.Sprintf("Content-Length %d, body of %d bytes", r.ContentLength, len(body)), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "title=%s", r.FormValue("title"))
+		for _, field := range []string{"document", "attachment"} {
+			for _, fh := range r.MultipartForm.File[field] {
+				f, _ := fh.Open()
+				data, _ := io.ReadAll(f)
+				f.Close()
+				fmt.Fprintf(w, " %s=%s:%s:%s", field, fh.Filename, fh.Header.Get("Content-Type"), data)
+			}
+		}
+	}))
+	defer server.Close()
+
+	executor, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &Request{Method: http.MethodPost, URL: server.URL, Headers: map[string]string{"X-Upload": "1", "content-type": "application/json"}}
+	err = req.SetMultipart(&Multipart{
+		Fields: map[string]string{"title": "Q3"},
+		Files: []MultipartFile{
+			{Field: "document", Path: report, ContentType: "application/pdf"},
+			{Field: "attachment", Path: notes, Filename: "readme.txt"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SetMultipart() failed: %v", err)
+	}
+	if len(req.Headers) != 2 || !strings.HasPrefix(req.Headers["Content-Type"], "multipart/form-data; boundary=") || req.Headers["X-Upload"] != "1" {
+		t.Errorf("unexpected headers: %v", req.Headers)
+	}
+
+	// Sent twice, to check the body is opened anew
+	for range 2 {
+		resp, err := executor.Execute(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		want := "title=Q3 document=report.pdf:application/pdf:%PDF-1.7 attachment=readme.txt:application/octet-stream:hello"
+		if resp.StatusCode != http.StatusOK || string(resp.Body) != want {
+			t.Errorf("server received %d %q, want %q", resp.StatusCode, resp.Body, want)
+		}
+	}
+
+	err = (&Request{}).SetMultipart(&Multipart{Files: []MultipartFile{{Field: "document", Path: filepath.Join(dir, "missing.pdf")}}})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("SetMultipart() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
 func TestResponseDuration(t *testing.T) {
 	delay := 100 * time.Millisecond
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -666,3 +1474,107 @@ func TestExecute_NetworkError(t *testing.T) {
 		t.Error("Execute() should fail with network error")
 	}
 }
+
+func TestExecute_Raw(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// The server echoes the request head as it arrived and closes the
+	// connection after the third request
+	var accepted atomic.Int64
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for n := 1; ; n++ {
+					var head strings.Builder
+					for {
+						line, err := reader.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if line == "\r\n" {
+							break
+						}
+						head.WriteString(line)
+					}
+					closing := ""
+					if n == 3 {
+						closing = "Connection: close\r\n"
+					}
+					fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nX-Served: raw\r\n%sContent-Length: %d\r\n\r\n%s",
+						closing, head.Len(), head.String())
+					if closing != "" {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	executor, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer executor.Close()
+
+	raw := "GET /echo HTTP/1.1\r\nhost: example\r\nX-Folded: a\r\n b\r\n\r\n"
+	for i := range 4 {
+		resp, err := executor.Execute(context.Background(), &Request{
+			URL: "http://" + listener.Addr().String() + "/echo",
+			Raw: []byte(raw),
+		})
+		if err != nil {
+			t.Fatalf("request %d: Execute() failed: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Headers["X-Served"][0] != "raw" {
+			t.Errorf("request %d: unexpected response %d %v", i, resp.StatusCode, resp.Headers)
+		}
+		if want := strings.TrimSuffix(raw, "\r\n"); string(resp.Body) != want {
+			t.Errorf("request %d: expected the request sent as is, server got %q", i, resp.Body)
+		}
+	}
+	// Three requests share a connection, the fourth needs a new one
+	if n := accepted.Load(); n != 2 {
+		t.Errorf("expected 2 connections, got %d", n)
+	}
+}
+
+func TestExecute_RawTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	executor, err := New()
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer executor.Close()
+
+	_, err = executor.Execute(context.Background(), &Request{
+		URL:     "http://" + listener.Addr().String(),
+		Raw:     []byte("GET / HTTP/1.1\r\nHost: example\r\n\r\n"),
+		Timeout: 20 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
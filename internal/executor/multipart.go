@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Multipart is a multipart/form-data request body of form fields and
+// files, the files streamed from disk
+type Multipart struct {
+	Fields map[string]string
+	Files  []MultipartFile
+}
+
+// MultipartFile is a file part of a Multipart body
+type MultipartFile struct {
+	Field string
+	Path  string
+	// Filename defaults to the base name of Path
+	Filename string
+	// ContentType defaults to application/octet-stream
+	ContentType string
+}
+
+// multipartSegment is a part of an encoded multipart body: encoded
+// boundaries, headers and fields, or the content of the file at path
+type multipartSegment struct {
+	data []byte
+	path string
+}
+
+// SetMultipart makes m the body of r: the fields, sorted by name, then the
+// files, between boundaries. The body is streamed with BodyReader, each
+// attempt opening the files anew, and its size, taken from the files now,
+// is the ContentLength. The Content-Type header is set with the boundary,
+// replacing any the request has in whatever case.
+func (r *Request) SetMultipart(m *Multipart) error {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(m.Fields)) {
+		if err := w.WriteField(name, m.Fields[name]); err != nil {
+			return fmt.Errorf("failed to write multipart field %q: %w", name, err)
+		}
+	}
+
+	var segments []multipartSegment
+	var size int64
+	for _, f := range m.Files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read multipart file: %w", err)
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", multipart.FileContentDisposition(f.Field, cmp.Or(f.Filename, filepath.Base(f.Path))))
+		header.Set("Content-Type", cmp.Or(f.ContentType, "application/octet-stream"))
+		if _, err := w.CreatePart(header); err != nil {
+			return fmt.Errorf("failed to write multipart file %q: %w", f.Field, err)
+		}
+		segments = append(segments, multipartSegment{data: bytes.Clone(buf.Bytes())}, multipartSegment{path: f.Path})
+		size += int64(buf.Len()) + info.Size()
+		buf.Reset()
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart body: %w", err)
+	}
+	segments = append(segments, multipartSegment{data: buf.Bytes()})
+	size += int64(buf.Len())
+
+	headers := make(map[string]string, len(r.Headers)+1)
+	for k, v := range r.Headers {
+		if !strings.EqualFold(k, "Content-Type") {
+			headers[k] = v
+		}
+	}
+	headers["Content-Type"] = w.FormDataContentType()
+	r.Headers = headers
+	r.Body = nil
+	r.BodyReader = func() (io.ReadCloser, error) {
+		return openMultipart(segments)
+	}
+	r.ContentLength = size
+	return nil
+}
+
+// multipartBody reads the segments of a multipart body in turn
+type multipartBody struct {
+	io.Reader
+	files []*os.File
+}
+
+func (b *multipartBody) Close() error {
+	var err error
+	for _, f := range b.files {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// openMultipart opens the files of segments and returns their body
+func openMultipart(segments []multipartSegment) (io.ReadCloser, error) {
+	body := &multipartBody{}
+	readers := make([]io.Reader, 0, len(segments))
+	for _, s := range segments {
+		if s.path == "" {
+			readers = append(readers, bytes.NewReader(s.data))
+			continue
+		}
+		f, err := os.Open(s.path)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		body.files = append(body.files, f)
+		readers = append(readers, f)
+	}
+	body.Reader = io.MultiReader(readers...)
+	return body, nil
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// proxyFunc returns the proxy of each request of opts: opts.Proxy unless
+// opts.NoProxy excludes the host, or without opts.Proxy the one the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables select.
+// Requests to localhost and loopback addresses are never proxied.
+func (opts TransportOptions) proxyFunc() (func(*url.URL) (*url.URL, error), error) {
+	if opts.Proxy == "" {
+		return httpproxy.FromEnvironment().ProxyFunc(), nil
+	}
+	u, err := url.Parse(opts.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s', must be http, https, socks5 or socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL '%s' has no host", u.Redacted())
+	}
+	config := &httpproxy.Config{HTTPProxy: opts.Proxy, HTTPSProxy: opts.Proxy, NoProxy: opts.NoProxy}
+	return config.ProxyFunc(), nil
+}
+
+// dialProxy connects to addr through the proxy proxyURL, tunneling with
+// CONNECT through HTTP proxies
+func dialProxy(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	var forward net.Dialer
+	if proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h" {
+		dialer, err := proxy.FromURL(proxyURL, &forward)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	}
+
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	conn, err := forward.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		connect.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	// Cancelling ctx unblocks the exchange
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, contextError(ctx, err)
+	}
+	// The proxy sends nothing after its response until the tunnel is
+	// used, so the reader buffers no more than the response
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+		return nil, contextError(ctx, err)
+	}
+	// The body of a successful response is the tunnel
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused to connect to %s: %s", addr, resp.Status)
+	}
+	return conn, nil
+}
@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// rawConn is the connection raw requests are sent over, kept open between
+// requests unless the server closes it
+type rawConn struct {
+	addr   string
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// executeRaw writes req.Raw as is over a connection to the host of
+// req.URL, reusing the previous raw request's connection when it is still
+// open, and parses the response. A reused connection the server closed
+// meanwhile is replaced once.
+func (e *Executor) executeRaw(ctx context.Context, req *Request) (*Response, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q for a raw request", req.URL)
+	}
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(target.Hostname(), port)
+
+	e.rawMu.Lock()
+	defer e.rawMu.Unlock()
+
+	for {
+		reused := e.raw != nil && e.raw.addr == addr
+		if !reused {
+			e.closeRaw()
+			conn, err := e.dialRaw(ctx, target, addr)
+			if err != nil {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			e.raw = &rawConn{addr: addr, conn: conn, reader: bufio.NewReader(conn)}
+		}
+
+		resp, err := e.roundTripRaw(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		e.closeRaw()
+		if !reused || ctx.Err() != nil || !(errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+	}
+}
+
+// dialRaw connects to addr, the address of target, through the proxy of
+// target if any
+func (e *Executor) dialRaw(ctx context.Context, target *url.URL, addr string) (net.Conn, error) {
+	var proxyURL *url.URL
+	if e.proxy != nil {
+		var err error
+		if proxyURL, err = e.proxy(target); err != nil {
+			return nil, err
+		}
+	}
+
+	var conn net.Conn
+	var err error
+	if proxyURL != nil {
+		conn, err = dialProxy(ctx, proxyURL, addr)
+	} else if e.dial != nil {
+		conn, err = e.dial(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil || target.Scheme != "https" {
+		return conn, err
+	}
+
+	config := &tls.Config{}
+	if e.tlsConfig != nil {
+		config = e.tlsConfig.Clone()
+	}
+	config.ServerName = target.Hostname()
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// roundTripRaw sends req.Raw over the current raw connection and reads the
+// response, closing the connection afterwards if the response asks to
+func (e *Executor) roundTripRaw(ctx context.Context, req *Request) (*Response, error) {
+	conn := e.raw.conn
+	if timeout := cmp.Or(req.Timeout, e.timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Time{})
+	}
+	// Cancelling ctx unblocks reads and writes in progress
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	start := time.Now()
+	if _, err := conn.Write(req.Raw); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	// The method tells whether the response has a body
+	method, _, _ := bytes.Cut(req.Raw, []byte(" "))
+	httpResp, err := http.ReadResponse(e.raw.reader, &http.Request{Method: string(method)})
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	respBody, size, err := readBody(httpResp.Body, req)
+	httpResp.Body.Close()
+	duration := time.Since(start)
+	if err != nil {
+		return nil, contextError(ctx, fmt.Errorf("failed to read response body: %w", err))
+	}
+	if httpResp.Close {
+		e.closeRaw()
+	}
+
+	return &Response{
+		StatusCode: httpResp.StatusCode,
+		Status:     httpResp.Status,
+		Headers:    httpResp.Header,
+		Body:       respBody,
+		BodySize:   size,
+		Duration:   duration,
+		Proto:      httpResp.Proto,
+	}, nil
+}
+
+// contextError reports the end of ctx rather than the failed I/O it caused
+func contextError(ctx context.Context, err error) error {
+	// The connection's deadline is that of ctx, which may pass an instant
+	// before ctx is done
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		<-ctx.Done()
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", context.Cause(ctx), err)
+	}
+	return err
+}
+
+func (e *Executor) closeRaw() {
+	if e.raw != nil {
+		e.raw.conn.Close()
+		e.raw = nil
+	}
+}
+
+// Close closes the connection of raw requests, if any
+func (e *Executor) Close() error {
+	e.rawMu.Lock()
+	defer e.rawMu.Unlock()
+	e.closeRaw()
+	return nil
+}
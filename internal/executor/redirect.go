@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Redirect policies of an executor
+const (
+	// RedirectFollow follows redirects, reporting the duration of the
+	// whole chain
+	RedirectFollow = "follow"
+	// RedirectNone returns redirect responses instead of following them
+	RedirectNone = "none"
+	// RedirectRecord follows redirects, reporting each one in
+	// Response.Redirects and the duration of the last request only
+	RedirectRecord = "record"
+)
+
+// DefaultMaxRedirects bounds the redirects followed unless
+// TransportOptions.MaxRedirects is set
+const DefaultMaxRedirects = 10
+
+// Redirect is a redirect response followed to reach the final response
+type Redirect struct {
+	// URL is the URL of the request redirected
+	URL        string
+	StatusCode int
+	// Duration is the time from sending the request to receiving the
+	// redirect response
+	Duration time.Duration
+}
+
+// redirectsKey is the context key of the *redirects of a request
+type redirectsKey struct{}
+
+// redirects collects the redirects of a request followed with
+// RedirectRecord
+type redirects struct {
+	hops []Redirect
+	// last is when the last request of the chain was sent
+	last time.Time
+}
+
+// checkRedirect is the redirect policy of the client: it stops at the
+// redirect response with RedirectNone, fails past the maximum number of
+// redirects and times each redirect of the requests collecting them
+func (e *Executor) checkRedirect(req *http.Request, via []*http.Request) error {
+	if e.redirects == RedirectNone {
+		return http.ErrUseLastResponse
+	}
+	if r, ok := req.Context().Value(redirectsKey{}).(*redirects); ok {
+		now := time.Now()
+		r.hops = append(r.hops, Redirect{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Duration:   now.Sub(r.last),
+		})
+		r.last = now
+	}
+	if len(via) > e.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", e.maxRedirects)
+	}
+	return nil
+}
+
+// withRedirects returns ctx collecting the redirects of its request when
+// the executor records them, and the collector, nil otherwise
+func (e *Executor) withRedirects(ctx context.Context, start time.Time) (context.Context, *redirects) {
+	if e.redirects != RedirectRecord {
+		return ctx, nil
+	}
+	r := &redirects{last: start}
+	return context.WithValue(ctx, redirectsKey{}, r), r
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy retries requests within the executor when they fail to
+// connect, or their response has a status RetryStatus accepts, waiting an
+// exponential backoff with full jitter in between. Requests whose method
+// is not idempotent are retried only after failing to connect, since the
+// server may have processed them otherwise, unless RetryNonIdempotent
+// opts their statuses in.
+type RetryPolicy struct {
+	// Max is the number of retries after the first attempt
+	Max int
+	// Backoff is the longest delay before the first retry, doubled for
+	// each following one up to MaxBackoff; the delay itself is random
+	// between 0 and that bound
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// RetryStatus reports whether responses with a status are retried,
+	// none if nil
+	RetryStatus func(status int) bool
+	// RetryNonIdempotent applies RetryStatus to requests whose method is
+	// not idempotent too
+	RetryNonIdempotent bool
+	// Budget bounds the retries of the executors sharing the policy, none
+	// if nil
+	Budget *RetryBudget
+}
+
+// retryBudgetMin is the number of retries a budget allows whatever the
+// number of requests, so the first failures of a run are retried
+const retryBudgetMin = 10
+
+// RetryBudget bounds the retries across requests to a ratio of the
+// requests sent, so retries cannot multiply the load on a failing target.
+// It is safe for concurrent use.
+type RetryBudget struct {
+	ratio    float64
+	requests atomic.Int64
+	retries  atomic.Int64
+}
+
+// NewRetryBudget creates a budget allowing ratio retries per request, such
+// as 0.2 for one retry every five requests, beyond the first 10 retries
+func NewRetryBudget(ratio float64) *RetryBudget {
+	return &RetryBudget{ratio: ratio}
+}
+
+// withdraw takes a retry from b if it has one left
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+	allowed := int64(b.ratio*float64(b.requests.Load())) + retryBudgetMin
+	if b.retries.Add(1) > allowed {
+		b.retries.Add(-1)
+		return false
+	}
+	return true
+}
+
+// deposit counts a request towards the retries b allows
+func (b *RetryBudget) deposit() {
+	if b != nil {
+		b.requests.Add(1)
+	}
+}
+
+// Attempt is an attempt of a request that failed and was retried
+type Attempt struct {
+	// StatusCode is the status of the response, 0 if Err is set
+	StatusCode int
+	// Err is the error of an attempt that produced no response
+	Err      error
+	Duration time.Duration
+}
+
+// RetriesError is the error of a request whose last attempt failed after
+// retries
+type RetriesError struct {
+	// Retried are the attempts before the last one
+	Retried []Attempt
+	Err     error
+}
+
+func (e *RetriesError) Error() string {
+	return fmt.Sprintf("%v (after %d retries)", e.Err, len(e.Retried))
+}
+
+func (e *RetriesError) Unwrap() error { return e.Err }
+
+// retry reports whether an attempt of a request with method that got resp
+// or err is retried
+func (p *RetryPolicy) retry(method string, resp *Response, err error) bool {
+	if err == nil {
+		return p.RetryStatus != nil && (p.RetryNonIdempotent || idempotent(method)) && p.RetryStatus(resp.StatusCode)
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	if !idempotent(method) {
+		return false
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// delay returns the backoff before the retry numbered n, from 1
+func (p *RetryPolicy) delay(n int) time.Duration {
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+	// Doubling stops at the limit rather than overflow
+	bound := limit
+	if shift := n - 1; shift < 63 && p.Backoff <= limit>>shift {
+		bound = p.Backoff << shift
+	}
+	if bound <= 0 {
+		return 0
+	}
+	return rand.N(bound)
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// sendWithRetries sends req until an attempt succeeds or is not retried
+// under e.retry
+func (e *Executor) sendWithRetries(ctx context.Context, req *Request) (*Response, error) {
+	p := e.retry
+	p.Budget.deposit()
+	resp, err := e.send(ctx, req)
+	var retried []Attempt
+	for n := 1; n <= p.Max && ctx.Err() == nil && p.retry(req.Method, resp, err) && p.Budget.withdraw(); n++ {
+		attempt := Attempt{Err: err}
+		if err == nil {
+			attempt.StatusCode, attempt.Duration = resp.StatusCode, resp.Duration
+		}
+		retried = append(retried, attempt)
+
+		timer := time.NewTimer(p.delay(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &RetriesError{Retried: retried, Err: fmt.Errorf("request failed: %w", context.Cause(ctx))}
+		case <-timer.C:
+		}
+		resp, err = e.send(ctx, req)
+	}
+
+	switch {
+	case len(retried) == 0:
+	case err != nil:
+		err = &RetriesError{Retried: retried, Err: err}
+	default:
+		resp.Retried = retried
+	}
+	return resp, err
+}
@@ -0,0 +1,10 @@
+//go:build !nosql
+
+package feeder
+
+// Database drivers are registered unless the agent is built with the
+// nosql tag, which leaves them out of HTTP-only binaries
+import (
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
@@ -0,0 +1,220 @@
+package feeder
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Format is the encoding of a data file
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// Record is one row of feeder data keyed by column name
+type Record map[string]string
+
+// Options controls how a data file is read
+type Options struct {
+	// Format is inferred from the file extension when empty
+	Format Format
+	// Columns restricts records to these columns. Empty keeps all columns.
+	Columns []string
+	// Types converts the values of the named columns, see Type
+	Types map[string]Type
+}
+
+func (o Options) validate() error {
+	for column, typ := range o.Types {
+		if !typ.valid() {
+			return fmt.Errorf("column %q has unknown type %q, must be one of: %v", column, typ, types)
+		}
+		if len(o.Columns) > 0 && !slices.Contains(o.Columns, column) {
+			return fmt.Errorf("type given for column %q, which is not selected", column)
+		}
+	}
+	return nil
+}
+
+// checkColumns reports selected or typed columns missing from known
+func (o Options) checkColumns(known []string) error {
+	for _, column := range slices.Concat(o.Columns, slices.Collect(maps.Keys(o.Types))) {
+		if !slices.Contains(known, column) {
+			return fmt.Errorf("column %q not found", column)
+		}
+	}
+	return nil
+}
+
+// source is a format-specific reader of indexed records
+type source interface {
+	Len() int
+	// Columns returns the column names, or nil if the format has no schema
+	Columns() []string
+	// Record decodes the record at index i, which is within [0, Len())
+	Record(i int) (Record, error)
+	Close() error
+}
+
+// File gives random access to the records of a data file without loading
+// it into memory.
+type File struct {
+	src     source
+	columns []string
+	types   map[string]Type
+}
+
+// Open indexes the records of the data file at path
+func Open(path string, opts Options) (*File, error) {
+	format := opts.Format
+	if format == "" {
+		var err error
+		if format, err = FormatFromPath(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	var src source
+	var err error
+	switch format {
+	case FormatCSV, FormatNDJSON:
+		src, err = openLines(path, format)
+	case FormatParquet:
+		src, err = openParquet(path)
+	default:
+		return nil, fmt.Errorf("unsupported data format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Formats with a schema reject unknown columns up front rather than
+	// on every record
+	if known := src.Columns(); known != nil {
+		if err := opts.checkColumns(known); err != nil {
+			src.Close()
+			return nil, fmt.Errorf("%w in %s", err, path)
+		}
+	}
+
+	return &File{src: src, columns: opts.Columns, types: opts.Types}, nil
+}
+
+// FormatFromPath infers the data format from a file extension
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".parquet":
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("cannot infer data format from '%s', set it explicitly", path)
+	}
+}
+
+// Len returns the number of records, excluding a CSV header
+func (f *File) Len() int {
+	return f.src.Len()
+}
+
+// Columns returns the selected columns, or all columns of a CSV header or
+// Parquet schema. It is nil for NDJSON without a column selection.
+func (f *File) Columns() []string {
+	if len(f.columns) > 0 {
+		return f.columns
+	}
+	return f.src.Columns()
+}
+
+// Record returns the record at index i
+func (f *File) Record(i int) (Record, error) {
+	if i < 0 || i >= f.Len() {
+		return nil, fmt.Errorf("record %d out of range [0, %d)", i, f.Len())
+	}
+
+	record, err := f.src.Record(i)
+	if err != nil {
+		return nil, err
+	}
+	return shape(record, f.columns, f.types, i)
+}
+
+// shape applies column selection and type conversion to record i
+func shape(record Record, columns []string, types map[string]Type, i int) (Record, error) {
+	if len(columns) > 0 {
+		selected := make(Record, len(columns))
+		for _, column := range columns {
+			value, ok := record[column]
+			if !ok {
+				return nil, fmt.Errorf("record %d: column %q not found", i, column)
+			}
+			selected[column] = value
+		}
+		record = selected
+	}
+
+	for column, typ := range types {
+		value, ok := record[column]
+		if !ok {
+			continue
+		}
+		converted, err := typ.convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: column %q: %w", i, column, err)
+		}
+		record[column] = converted
+	}
+
+	return record, nil
+}
+
+// Partition returns the contiguous share of records assigned to worker
+// (0-based) out of workers, so distributed agents never reuse each
+// other's rows. Shares differ in size by at most one record.
+func (f *File) Partition(worker, workers int) (*Partition, error) {
+	if workers <= 0 || worker < 0 || worker >= workers {
+		return nil, fmt.Errorf("invalid partition %d of %d", worker, workers)
+	}
+
+	return &Partition{
+		file:  f,
+		start: f.Len() * worker / workers,
+		end:   f.Len() * (worker + 1) / workers,
+	}, nil
+}
+
+// Close releases the file
+func (f *File) Close() error {
+	return f.src.Close()
+}
+
+// Partition is a contiguous range of a File's records
+type Partition struct {
+	file       *File
+	start, end int
+}
+
+// Len returns the number of records in the partition
+func (p *Partition) Len() int {
+	return p.end - p.start
+}
+
+// Record returns the partition's record at index i
+func (p *Partition) Record(i int) (Record, error) {
+	if i < 0 || i >= p.Len() {
+		return nil, fmt.Errorf("record %d out of partition range [0, %d)", i, p.Len())
+	}
+	return p.file.Record(p.start + i)
+}
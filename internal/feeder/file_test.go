@@ -0,0 +1,181 @@
+package feeder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestOpen_CSV(t *testing.T) {
+	path := writeFile(t, "users.csv",
+		"username,password\r\nalice,secret\r\n\r\n\"bob, jr\",\"multi\nline\"\r\ncarol,pw\r\n")
+
+	f, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	if f.Len() != 3 {
+		t.Fatalf("expected 3 records, got %d", f.Len())
+	}
+	if cols := f.Columns(); len(cols) != 2 || cols[0] != "username" {
+		t.Errorf("unexpected columns: %v", cols)
+	}
+
+	rec, err := f.Record(1)
+	if err != nil {
+		t.Fatalf("Record(1) failed: %v", err)
+	}
+	if rec["username"] != "bob, jr" || rec["password"] != "multi\nline" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+
+	rec, err = f.Record(2)
+	if err != nil {
+		t.Fatalf("Record(2) failed: %v", err)
+	}
+	if rec["username"] != "carol" || rec["password"] != "pw" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+
+	if _, err := f.Record(3); err == nil {
+		t.Error("expected out of range error, got nil")
+	}
+}
+
+func TestOpen_NDJSON(t *testing.T) {
+	path := writeFile(t, "users.jsonl",
+		`{"id": 1, "name": "alice", "admin": true}`+"\n\n"+`{"id": 2, "tags": ["a"]}`+"\n")
+
+	f, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	if f.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", f.Len())
+	}
+
+	rec, err := f.Record(0)
+	if err != nil {
+		t.Fatalf("Record(0) failed: %v", err)
+	}
+	if rec["id"] != "1" || rec["name"] != "alice" || rec["admin"] != "true" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+
+	rec, err = f.Record(1)
+	if err != nil {
+		t.Fatalf("Record(1) failed: %v", err)
+	}
+	if rec["tags"] != `["a"]` {
+		t.Errorf("expected nested values as JSON, got %v", rec)
+	}
+}
+
+func TestRecord_AcrossCheckpoints(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id\n")
+	const n = checkpointEvery*3 + 7
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+
+	f, err := Open(writeFile(t, "ids.csv", sb.String()), Options{Format: FormatCSV})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	if f.Len() != n {
+		t.Fatalf("expected %d records, got %d", n, f.Len())
+	}
+	for _, i := range []int{0, 1, checkpointEvery - 1, checkpointEvery, 2*checkpointEvery + 5, n - 1} {
+		rec, err := f.Record(i)
+		if err != nil {
+			t.Fatalf("Record(%d) failed: %v", i, err)
+		}
+		if rec["id"] != fmt.Sprint(i) {
+			t.Errorf("Record(%d) = %v", i, rec)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+
+	f, err := Open(writeFile(t, "ids.csv", sb.String()), Options{})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	for worker := 0; worker < 3; worker++ {
+		p, err := f.Partition(worker, 3)
+		if err != nil {
+			t.Fatalf("Partition(%d, 3) failed: %v", worker, err)
+		}
+		if p.Len() < 3 || p.Len() > 4 {
+			t.Errorf("unbalanced partition %d: %d records", worker, p.Len())
+		}
+		for i := 0; i < p.Len(); i++ {
+			rec, err := p.Record(i)
+			if err != nil {
+				t.Fatalf("Record(%d) failed: %v", i, err)
+			}
+			if seen[rec["id"]] {
+				t.Errorf("record %s assigned to more than one partition", rec["id"])
+			}
+			seen[rec["id"]] = true
+		}
+		if _, err := p.Record(p.Len()); err == nil {
+			t.Error("expected out of range error, got nil")
+		}
+	}
+	if len(seen) != 10 {
+		t.Errorf("expected all 10 records partitioned, got %d", len(seen))
+	}
+
+	if _, err := f.Partition(3, 3); err == nil {
+		t.Error("expected error for invalid partition, got nil")
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	if _, err := Open(writeFile(t, "data.txt", "a\n"), Options{}); err == nil {
+		t.Error("expected error for unknown extension, got nil")
+	}
+	if _, err := Open(writeFile(t, "empty.csv", ""), Options{}); err == nil {
+		t.Error("expected error for CSV without header, got nil")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.csv"), Options{}); err == nil {
+		t.Error("expected error for missing file, got nil")
+	}
+
+	f, err := Open(writeFile(t, "bad.csv", "a,b\n1\n"), Options{})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Record(0); err == nil {
+		t.Error("expected error for short CSV row, got nil")
+	}
+}
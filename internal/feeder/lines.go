@@ -0,0 +1,183 @@
+package feeder
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// checkpointEvery is the number of records between index checkpoints.
+// Only every Nth record offset is kept, so the index of a file with 50M
+// rows is a few MB; reaching any record scans at most N-1 records.
+const checkpointEvery = 64
+
+// lineSource reads CSV and NDJSON files, which hold one record per line.
+// The file is memory-mapped where the platform supports it and the OS
+// pages in only the parts that are read.
+type lineSource struct {
+	data    []byte
+	release func() error
+	format  Format
+
+	header      []string
+	count       int
+	checkpoints []int
+}
+
+func openLines(path string, format Format) (*lineSource, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+
+	f := &lineSource{data: data, release: release, format: format}
+	if err := f.index(); err != nil {
+		release()
+		return nil, fmt.Errorf("failed to index %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *lineSource) index() error {
+	pos := 0
+
+	if f.format == FormatCSV {
+		start, end, next, ok := f.scan(0)
+		if !ok {
+			return fmt.Errorf("CSV header row is missing")
+		}
+		header, err := parseCSV(f.data[start:end])
+		if err != nil {
+			return fmt.Errorf("invalid CSV header: %w", err)
+		}
+		f.header = header
+		pos = next
+	}
+
+	for {
+		start, _, next, ok := f.scan(pos)
+		if !ok {
+			return nil
+		}
+		if f.count%checkpointEvery == 0 {
+			f.checkpoints = append(f.checkpoints, start)
+		}
+		f.count++
+		pos = next
+	}
+}
+
+// scan finds the record starting at or after pos, skipping blank lines. It
+// returns the record bounds without the line terminator and the position
+// after it. Newlines inside quoted CSV fields do not end a record.
+func (f *lineSource) scan(pos int) (start, end, next int, ok bool) {
+	data := f.data
+	for pos < len(data) && (data[pos] == '\n' || data[pos] == '\r') {
+		pos++
+	}
+	if pos >= len(data) {
+		return 0, 0, 0, false
+	}
+
+	start = pos
+	quoted := false
+	for pos < len(data) {
+		c := data[pos]
+		if c == '"' && f.format == FormatCSV {
+			quoted = !quoted
+		} else if c == '\n' && !quoted {
+			break
+		}
+		pos++
+	}
+
+	end = pos
+	if end > start && data[end-1] == '\r' {
+		end--
+	}
+	if pos < len(data) {
+		pos++
+	}
+	return start, end, pos, true
+}
+
+func (f *lineSource) Len() int {
+	return f.count
+}
+
+// Columns returns the CSV header, or nil for NDJSON
+func (f *lineSource) Columns() []string {
+	return f.header
+}
+
+func (f *lineSource) Record(i int) (Record, error) {
+	pos := f.checkpoints[i/checkpointEvery]
+	for skip := i % checkpointEvery; ; skip-- {
+		start, end, next, _ := f.scan(pos)
+		if skip == 0 {
+			return f.decode(f.data[start:end], i)
+		}
+		pos = next
+	}
+}
+
+func (f *lineSource) Close() error {
+	return f.release()
+}
+
+func (f *lineSource) decode(line []byte, i int) (Record, error) {
+	switch f.format {
+	case FormatCSV:
+		fields, err := parseCSV(line)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		if len(fields) != len(f.header) {
+			return nil, fmt.Errorf("record %d: expected %d fields, got %d", i, len(f.header), len(fields))
+		}
+		record := make(Record, len(fields))
+		for j, name := range f.header {
+			record[name] = fields[j]
+		}
+		return record, nil
+	default:
+		var obj map[string]any
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("record %d: invalid JSON object: %w", i, err)
+		}
+		record := make(Record, len(obj))
+		for k, v := range obj {
+			record[k] = stringify(v)
+		}
+		return record, nil
+	}
+}
+
+func parseCSV(line []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(line))
+	r.FieldsPerRecord = -1
+	return r.Read()
+}
+
+func stringify(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(raw)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package feeder
+
+import "os"
+
+// mapFile falls back to reading the whole file on platforms without mmap
+func mapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package feeder
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func mapFile(path string) ([]byte, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	if int64(int(info.Size())) != info.Size() {
+		return nil, nil, fmt.Errorf("file too large to map: %d bytes", info.Size())
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mmap failed: %w", err)
+	}
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
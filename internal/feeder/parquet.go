@@ -0,0 +1,186 @@
+//go:build !noparquet
+
+package feeder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// parquetSource reads flat Parquet files. Only the footer is loaded when
+// the file is opened; row groups are decoded on demand, and a reader is kept
+// open on the last row group read so sequential access does not re-seek.
+type parquetSource struct {
+	file    *os.File
+	columns []string
+	// convert formats the values of each leaf column as strings
+	convert []func(parquet.Value) string
+	groups  []parquet.RowGroup
+	// offsets holds the index of the first record of every row group
+	offsets []int
+	count   int
+
+	mu     sync.Mutex
+	rows   parquet.Rows
+	group  int
+	next   int
+	buffer []parquet.Row
+}
+
+func openParquet(path string) (*parquetSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+
+	src, err := newParquetSource(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return src, nil
+}
+
+func newParquetSource(f *os.File) (*parquetSource, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	pf, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	src := &parquetSource{
+		file:   f,
+		groups: pf.RowGroups(),
+		group:  -1,
+		buffer: make([]parquet.Row, 1),
+	}
+
+	schema := pf.Schema()
+	for _, path := range schema.Columns() {
+		leaf, _ := schema.Lookup(path...)
+		name := strings.Join(path, ".")
+		if leaf.MaxRepetitionLevel > 0 {
+			return nil, fmt.Errorf("repeated column %q is not supported, only flat schemas can be fed", name)
+		}
+		src.columns = append(src.columns, name)
+		src.convert = append(src.convert, parquetConverter(leaf.Node))
+	}
+
+	for _, g := range src.groups {
+		src.offsets = append(src.offsets, src.count)
+		src.count += int(g.NumRows())
+	}
+	return src, nil
+}
+
+// parquetConverter maps a column's physical and logical type to the string
+// form used in requests: dates as 2006-01-02, timestamps as RFC 3339 in UTC.
+func parquetConverter(node parquet.Node) func(parquet.Value) string {
+	var logical format.LogicalTypeValue
+	if lt := node.Type().LogicalType(); lt != nil {
+		logical = lt.Value
+	}
+
+	switch t := logical.(type) {
+	case *format.DateType:
+		return func(v parquet.Value) string {
+			return time.Unix(int64(v.Int32())*86400, 0).UTC().Format(time.DateOnly)
+		}
+	case *format.TimestampType:
+		unit := t.Unit.Value.Duration()
+		return func(v parquet.Value) string {
+			return time.Unix(0, v.Int64()*int64(unit)).UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	switch node.Type().Kind() {
+	case parquet.Boolean:
+		return func(v parquet.Value) string { return strconv.FormatBool(v.Boolean()) }
+	case parquet.Int32:
+		return func(v parquet.Value) string { return strconv.FormatInt(int64(v.Int32()), 10) }
+	case parquet.Int64:
+		return func(v parquet.Value) string { return strconv.FormatInt(v.Int64(), 10) }
+	case parquet.Float:
+		return func(v parquet.Value) string { return strconv.FormatFloat(float64(v.Float()), 'f', -1, 32) }
+	case parquet.Double:
+		return func(v parquet.Value) string { return strconv.FormatFloat(v.Double(), 'f', -1, 64) }
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return func(v parquet.Value) string { return string(v.ByteArray()) }
+	default:
+		return parquet.Value.String
+	}
+}
+
+func (s *parquetSource) Len() int {
+	return s.count
+}
+
+func (s *parquetSource) Columns() []string {
+	return s.columns
+}
+
+func (s *parquetSource) Record(i int) (Record, error) {
+	group := sort.Search(len(s.offsets), func(g int) bool { return s.offsets[g] > i }) - 1
+	local := i - s.offsets[group]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if group != s.group {
+		if s.rows != nil {
+			s.rows.Close()
+		}
+		s.rows = s.groups[group].Rows()
+		s.group = group
+		s.next = 0
+	}
+	if local != s.next {
+		if err := s.rows.SeekToRow(int64(local)); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+	}
+
+	n, err := s.rows.ReadRows(s.buffer)
+	if n == 0 {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		// The reader state is unknown after a failure, start over next time
+		s.rows.Close()
+		s.rows, s.group = nil, -1
+		return nil, fmt.Errorf("record %d: %w", i, err)
+	}
+	s.next = local + 1
+
+	record := make(Record, len(s.columns))
+	for _, v := range s.buffer[0] {
+		col := v.Column()
+		if v.IsNull() {
+			record[s.columns[col]] = ""
+			continue
+		}
+		record[s.columns[col]] = s.convert[col](v)
+	}
+	return record, nil
+}
+
+func (s *parquetSource) Close() error {
+	if s.rows != nil {
+		s.rows.Close()
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,9 @@
+//go:build noparquet
+
+package feeder
+
+import "errors"
+
+func openParquet(string) (source, error) {
+	return nil, errors.New("Parquet files are not supported by this build of the agent (noparquet tag)")
+}
@@ -0,0 +1,188 @@
+//go:build !noparquet
+
+package feeder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type parquetUser struct {
+	ID      int64     `parquet:"id"`
+	Name    string    `parquet:"name"`
+	Score   float64   `parquet:"score"`
+	Active  bool      `parquet:"active"`
+	Email   *string   `parquet:"email,optional"`
+	Created time.Time `parquet:"created,timestamp(millisecond)"`
+}
+
+func writeParquet(t *testing.T, users []parquetUser) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "users.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create parquet file: %v", err)
+	}
+	defer f.Close()
+
+	w := parquet.NewGenericWriter[parquetUser](f, parquet.MaxRowsPerRowGroup(4))
+	if _, err := w.Write(users); err != nil {
+		t.Fatalf("failed to write rows: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	return path
+}
+
+func TestOpen_Parquet(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	email := "alice@example.com"
+
+	users := make([]parquetUser, 10)
+	for i := range users {
+		users[i] = parquetUser{
+			ID:      int64(i),
+			Name:    fmt.Sprintf("user%d", i),
+			Score:   float64(i) + 0.5,
+			Active:  i%2 == 0,
+			Created: created,
+		}
+	}
+	users[0].Email = &email
+
+	f, err := Open(writeParquet(t, users), Options{})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	if f.Len() != 10 {
+		t.Fatalf("expected 10 records, got %d", f.Len())
+	}
+	if cols := f.Columns(); len(cols) != 6 || cols[0] != "id" {
+		t.Errorf("unexpected columns: %v", cols)
+	}
+
+	rec, err := f.Record(0)
+	if err != nil {
+		t.Fatalf("Record(0) failed: %v", err)
+	}
+	want := Record{
+		"id":      "0",
+		"name":    "user0",
+		"score":   "0.5",
+		"active":  "true",
+		"email":   "alice@example.com",
+		"created": "2024-03-01T12:30:00Z",
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("column %s = %q, want %q", k, rec[k], v)
+		}
+	}
+
+	// Out of order access across row groups, then sequential within one
+	for _, i := range []int{9, 2, 5, 6, 7, 1} {
+		rec, err := f.Record(i)
+		if err != nil {
+			t.Fatalf("Record(%d) failed: %v", i, err)
+		}
+		if rec["id"] != fmt.Sprint(i) || rec["email"] != "" {
+			t.Errorf("Record(%d) = %v", i, rec)
+		}
+	}
+}
+
+func TestOpen_ColumnSelection(t *testing.T) {
+	path := writeFile(t, "users.csv", "id,name,score\n1,alice,3.0\n")
+
+	f, err := Open(path, Options{
+		Columns: []string{"id", "score"},
+		Types:   map[string]Type{"score": TypeInt},
+	})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	rec, err := f.Record(0)
+	if err != nil {
+		t.Fatalf("Record(0) failed: %v", err)
+	}
+	if len(rec) != 2 || rec["id"] != "1" || rec["score"] != "3" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+
+	if _, err := Open(path, Options{Columns: []string{"email"}}); err == nil {
+		t.Error("expected error for unknown CSV column, got nil")
+	}
+	if _, err := Open(path, Options{Columns: []string{"id"}, Types: map[string]Type{"name": TypeString}}); err == nil {
+		t.Error("expected error for type on unselected column, got nil")
+	}
+	if _, err := Open(path, Options{Types: map[string]Type{"id": "uuid"}}); err == nil {
+		t.Error("expected error for unknown type, got nil")
+	}
+}
+
+func TestOpen_NDJSONTypes(t *testing.T) {
+	path := writeFile(t, "users.ndjson", `{"id": 1.0, "admin": "TRUE"}`+"\n"+`{"id": "x"}`+"\n"+`{"admin": false}`+"\n")
+
+	f, err := Open(path, Options{
+		Columns: []string{"id", "admin"},
+		Types:   map[string]Type{"id": TypeInt, "admin": TypeBool},
+	})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	rec, err := f.Record(0)
+	if err != nil {
+		t.Fatalf("Record(0) failed: %v", err)
+	}
+	if rec["id"] != "1" || rec["admin"] != "true" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+
+	if _, err := f.Record(1); err == nil {
+		t.Error("expected conversion error, got nil")
+	}
+	if _, err := f.Record(2); err == nil {
+		t.Error("expected missing column error, got nil")
+	}
+}
+
+func TestType_Convert(t *testing.T) {
+	tests := []struct {
+		typ   Type
+		in    string
+		want  string
+		fails bool
+	}{
+		{TypeString, " a ", " a ", false},
+		{TypeInt, "-42", "-42", false},
+		{TypeInt, "42.0", "42", false},
+		{TypeInt, "42.5", "", true},
+		{TypeFloat, "1e3", "1000", false},
+		{TypeFloat, "abc", "", true},
+		{TypeBool, "1", "true", false},
+		{TypeBool, "yes", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.typ.convert(tt.in)
+		if (err != nil) != tt.fails {
+			t.Errorf("%s(%q): unexpected error state: %v", tt.typ, tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s(%q) = %q, want %q", tt.typ, tt.in, got, tt.want)
+		}
+	}
+}
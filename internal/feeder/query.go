@@ -0,0 +1,217 @@
+package feeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Database drivers accepted by OpenDB
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// sqlDrivers maps OpenDB driver names to registered database/sql drivers
+var sqlDrivers = map[string]string{
+	DriverPostgres: "pgx",
+	DriverMySQL:    "mysql",
+}
+
+// OpenDB connects to a database and checks that it is reachable
+func OpenDB(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	name, ok := sqlDrivers[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q, must be one of: %v",
+			driver, slices.Sorted(maps.Keys(sqlDrivers)))
+	}
+	if !slices.Contains(sql.Drivers(), name) {
+		return nil, fmt.Errorf("database driver %q is not included in this build of the agent (nosql tag)", driver)
+	}
+
+	db, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", driver, err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", driver, err)
+	}
+	return db, nil
+}
+
+// OpenQuery runs query once and holds its result set in memory, so the
+// records reflect the state of the environment when the run started. NULL
+// values become empty strings. opts.Format is ignored.
+func OpenQuery(ctx context.Context, db *sql.DB, query string, opts Options) (*File, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("feeder query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("feeder query failed: %w", err)
+	}
+	if err := opts.checkColumns(columns); err != nil {
+		return nil, fmt.Errorf("%w in query result", err)
+	}
+
+	src := &memorySource{columns: columns}
+	scan := newRowScanner(columns)
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(src.records), err)
+		}
+		src.records = append(src.records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("feeder query failed: %w", err)
+	}
+
+	return &File{src: src, columns: opts.Columns, types: opts.Types}, nil
+}
+
+// newRowScanner returns a function that reads the current row of a result
+// set with the given columns as a Record
+func newRowScanner(columns []string) func(*sql.Rows) (Record, error) {
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	return func(rows *sql.Rows) (Record, error) {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		record := make(Record, len(columns))
+		for i, column := range columns {
+			record[column] = values[i].String
+		}
+		return record, nil
+	}
+}
+
+// memorySource serves records that were loaded up front
+type memorySource struct {
+	columns []string
+	records []Record
+}
+
+func (m *memorySource) Len() int {
+	return len(m.records)
+}
+
+func (m *memorySource) Columns() []string {
+	return m.columns
+}
+
+func (m *memorySource) Record(i int) (Record, error) {
+	return maps.Clone(m.records[i]), nil
+}
+
+func (m *memorySource) Close() error {
+	return nil
+}
+
+// Stream reads the records of a query as the database returns them instead
+// of holding the result set in memory, for results too large to load. When
+// the result set is exhausted the query runs again, so records cycle like
+// they do for a file feeder. A Stream is safe for concurrent use.
+type Stream struct {
+	ctx   context.Context
+	db    *sql.DB
+	query string
+	opts  Options
+
+	mu   sync.Mutex
+	rows *sql.Rows
+	scan func(*sql.Rows) (Record, error)
+	read int
+}
+
+// OpenStream prepares a streamed query feeder. The query first runs on the
+// first call to Next and is cancelled with ctx.
+func OpenStream(ctx context.Context, db *sql.DB, query string, opts Options) (*Stream, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Stream{ctx: ctx, db: db, query: query, opts: opts}, nil
+}
+
+// Next returns the next record, restarting the query after the last one
+func (s *Stream) Next() (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A second pass only happens when the first one hit the end of the
+	// result set, so an empty result set is reported rather than retried
+	for range 2 {
+		if s.rows == nil {
+			if err := s.start(); err != nil {
+				return nil, err
+			}
+		}
+
+		if s.rows.Next() {
+			record, err := s.scan(s.rows)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %w", s.read, err)
+			}
+			s.read++
+			return shape(record, s.opts.Columns, s.opts.Types, s.read-1)
+		}
+
+		err := s.rows.Err()
+		s.rows.Close()
+		s.rows = nil
+		if err != nil {
+			return nil, fmt.Errorf("feeder query failed: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("feeder query returned no rows")
+}
+
+func (s *Stream) start() error {
+	rows, err := s.db.QueryContext(s.ctx, s.query)
+	if err != nil {
+		return fmt.Errorf("feeder query failed: %w", err)
+	}
+
+	columns, err := rows.Columns()
+	if err == nil {
+		err = s.opts.checkColumns(columns)
+	}
+	if err != nil {
+		rows.Close()
+		return fmt.Errorf("feeder query failed: %w", err)
+	}
+
+	s.rows = rows
+	s.scan = newRowScanner(columns)
+	s.read = 0
+	return nil
+}
+
+// Close stops the query in progress, if any
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rows == nil {
+		return nil
+	}
+	err := s.rows.Close()
+	s.rows = nil
+	return err
+}
@@ -0,0 +1,138 @@
+package feeder
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOpenQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, sku, price FROM products").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "sku", "price"}).
+			AddRow(1, "A-1", "9.50").
+			AddRow(2, nil, "12"),
+	)
+
+	f, err := OpenQuery(context.Background(), db, "SELECT id, sku, price FROM products", Options{
+		Types: map[string]Type{"price": TypeFloat},
+	})
+	if err != nil {
+		t.Fatalf("OpenQuery() failed: %v", err)
+	}
+	defer f.Close()
+
+	if f.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", f.Len())
+	}
+	if cols := f.Columns(); len(cols) != 3 || cols[1] != "sku" {
+		t.Errorf("unexpected columns: %v", cols)
+	}
+
+	rec, err := f.Record(0)
+	if err != nil {
+		t.Fatalf("Record(0) failed: %v", err)
+	}
+	if rec["id"] != "1" || rec["sku"] != "A-1" || rec["price"] != "9.5" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+
+	rec, err = f.Record(1)
+	if err != nil {
+		t.Fatalf("Record(1) failed: %v", err)
+	}
+	if rec["sku"] != "" {
+		t.Errorf("expected NULL as empty string, got %q", rec["sku"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOpenQuery_Errors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnError(errors.New("relation does not exist"))
+	if _, err := OpenQuery(context.Background(), db, "SELECT * FROM missing", Options{}); err == nil {
+		t.Error("expected query error, got nil")
+	}
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	if _, err := OpenQuery(context.Background(), db, "SELECT id FROM users", Options{Columns: []string{"email"}}); err == nil {
+		t.Error("expected error for unknown column, got nil")
+	}
+}
+
+func TestStream_Cycles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	for range 2 {
+		mock.ExpectQuery("SELECT id FROM users").WillReturnRows(
+			sqlmock.NewRows([]string{"id"}).AddRow("a").AddRow("b"))
+	}
+
+	s, err := OpenStream(context.Background(), db, "SELECT id FROM users", Options{})
+	if err != nil {
+		t.Fatalf("OpenStream() failed: %v", err)
+	}
+	defer s.Close()
+
+	var got []string
+	for range 3 {
+		rec, err := s.Next()
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		got = append(got, rec["id"])
+	}
+	if got[0] != "a" || got[1] != "b" || got[2] != "a" {
+		t.Errorf("expected records to cycle, got %v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStream_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s, err := OpenStream(context.Background(), db, "SELECT id FROM users", Options{})
+	if err != nil {
+		t.Fatalf("OpenStream() failed: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Next(); err == nil {
+		t.Error("expected error for empty result set, got nil")
+	}
+}
+
+func TestOpenDB_UnknownDriver(t *testing.T) {
+	if _, err := OpenDB(context.Background(), "oracle", "dsn"); err == nil {
+		t.Error("expected error for unknown driver, got nil")
+	}
+}
@@ -0,0 +1,179 @@
+package feeder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRemoteBody bounds the size of a remote feeder response
+const maxRemoteBody = 64 << 20
+
+// Remote is a feeder backed by an HTTP endpoint returning a JSON array of
+// objects, optionally refetched at an interval so long soaks pick up data
+// that changes during the run, like currently active promotion codes.
+// A Remote is safe for concurrent use.
+type Remote struct {
+	url    string
+	client *http.Client
+	opts   Options
+
+	mu      sync.RWMutex
+	current *File
+	lastErr error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// OpenRemote fetches the records at url, failing if the first fetch does.
+// When refresh is positive the records are refetched every refresh until
+// ctx ends or the feeder is closed. A failed refresh keeps the previous
+// records, see LastError. opts.Format is ignored.
+func OpenRemote(ctx context.Context, url string, refresh time.Duration, opts Options) (*Remote, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Remote{
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+		opts:   opts,
+		done:   make(chan struct{}),
+	}
+
+	f, err := r.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.current = f
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	if refresh <= 0 {
+		close(r.done)
+		return r, nil
+	}
+
+	go func() {
+		defer close(r.done)
+		r.refresh(ctx, refresh)
+	}()
+	return r, nil
+}
+
+func (r *Remote) refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		f, err := r.fetch(ctx)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+
+		r.mu.Lock()
+		r.lastErr = err
+		if err == nil {
+			r.current = f
+		}
+		r.mu.Unlock()
+	}
+}
+
+// fetch downloads and decodes the records. Records are shaped up front so
+// that a response with missing or mistyped columns never replaces good data.
+func (r *Remote) fetch(ctx context.Context) (*File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feeder URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feeder data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch feeder data: HTTP %d from %s", resp.StatusCode, r.url)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feeder data: %w", err)
+	}
+	if len(body) > maxRemoteBody {
+		return nil, fmt.Errorf("feeder data from %s exceeds %d bytes", r.url, maxRemoteBody)
+	}
+
+	var objects []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("feeder data from %s is not a JSON array of objects: %w", r.url, err)
+	}
+
+	src := &memorySource{columns: r.opts.Columns, records: make([]Record, 0, len(objects))}
+	for i, obj := range objects {
+		record := make(Record, len(obj))
+		for k, v := range obj {
+			record[k] = stringify(v)
+		}
+		shaped, err := shape(record, r.opts.Columns, r.opts.Types, i)
+		if err != nil {
+			return nil, err
+		}
+		src.records = append(src.records, shaped)
+	}
+	return &File{src: src}, nil
+}
+
+func (r *Remote) file() *File {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Len returns the number of records of the latest successful fetch
+func (r *Remote) Len() int {
+	return r.file().Len()
+}
+
+// Columns returns the selected columns, or nil without a column selection
+func (r *Remote) Columns() []string {
+	return r.file().Columns()
+}
+
+// Record returns the record at index i of the latest successful fetch. A
+// refresh may change the number of records, so i is checked against the
+// records current at the time of the call.
+func (r *Remote) Record(i int) (Record, error) {
+	return r.file().Record(i)
+}
+
+// LastError returns the error of the latest refresh, or nil if it
+// succeeded
+func (r *Remote) LastError() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastErr
+}
+
+// Close stops refreshing
+func (r *Remote) Close() error {
+	r.cancel()
+	<-r.done
+	return nil
+}
@@ -0,0 +1,115 @@
+package feeder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOpenRemote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"code":"SPRING","discount":10.0,"active":true},{"code":"VIP","discount":25}]`))
+	}))
+	defer server.Close()
+
+	f, err := OpenRemote(context.Background(), server.URL, 0, Options{
+		Columns: []string{"code", "discount"},
+		Types:   map[string]Type{"discount": TypeInt},
+	})
+	if err != nil {
+		t.Fatalf("OpenRemote() failed: %v", err)
+	}
+	defer f.Close()
+
+	if f.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", f.Len())
+	}
+	rec, err := f.Record(0)
+	if err != nil {
+		t.Fatalf("Record(0) failed: %v", err)
+	}
+	if len(rec) != 2 || rec["code"] != "SPRING" || rec["discount"] != "10" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+	if _, err := f.Record(2); err == nil {
+		t.Error("expected error for out of range record, got nil")
+	}
+}
+
+func TestOpenRemote_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		opts   Options
+	}{
+		{name: "status", status: http.StatusServiceUnavailable, body: `[]`},
+		{name: "not an array", status: http.StatusOK, body: `{"code":"VIP"}`},
+		{name: "missing column", status: http.StatusOK, body: `[{"code":"VIP"}]`, opts: Options{Columns: []string{"sku"}}},
+		{name: "bad type", status: http.StatusOK, body: `[{"n":"x"}]`, opts: Options{Types: map[string]Type{"n": TypeInt}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			if _, err := OpenRemote(context.Background(), server.URL, 0, tt.opts); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRemote_Refresh(t *testing.T) {
+	var body atomic.Value
+	body.Store(`[{"code":"SPRING"}]`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := body.Load().(string)
+		if b == "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(b))
+	}))
+	defer server.Close()
+
+	f, err := OpenRemote(context.Background(), server.URL, 10*time.Millisecond, Options{})
+	if err != nil {
+		t.Fatalf("OpenRemote() failed: %v", err)
+	}
+	defer f.Close()
+
+	waitFor := func(cond func() bool, msg string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal(msg)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// A failed refresh keeps the previous records
+	body.Store("")
+	waitFor(func() bool { return f.LastError() != nil }, "refresh error was never reported")
+	if rec, err := f.Record(0); err != nil || rec["code"] != "SPRING" || f.Len() != 1 {
+		t.Errorf("expected previous records after failed refresh, got %v (%v)", rec, err)
+	}
+
+	body.Store(`[{"code":"SUMMER"},{"code":"VIP"}]`)
+	waitFor(func() bool { return f.Len() == 2 }, "feeder did not pick up refreshed records")
+	if rec, err := f.Record(0); err != nil || rec["code"] != "SUMMER" {
+		t.Errorf("expected refreshed record, got %v (%v)", rec, err)
+	}
+	if err := f.LastError(); err != nil {
+		t.Errorf("expected no error after a successful refresh, got %v", err)
+	}
+}
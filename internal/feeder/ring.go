@@ -0,0 +1,157 @@
+package feeder
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ringReplicas is the number of points each node has on a Ring, which
+// evens out the share of keys per node
+const ringReplicas = 128
+
+// Ring assigns keys to nodes by consistent hashing: a key belongs to the
+// first node point at or after the key's hash. Adding or removing a node
+// only moves the keys of the points it gains or loses, so the other
+// nodes keep theirs.
+type Ring struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+// NewRing returns a ring of nodes, which must be distinct
+func NewRing(nodes []string) (*Ring, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("a ring needs at least one node")
+	}
+	r := &Ring{owners: make(map[uint64]string, len(nodes)*ringReplicas)}
+	for i, node := range nodes {
+		if slices.Contains(nodes[:i], node) {
+			return nil, fmt.Errorf("duplicate ring node %q", node)
+		}
+		for i := range ringReplicas {
+			point := hashKey(node + "#" + strconv.Itoa(i))
+			if _, taken := r.owners[point]; taken {
+				continue
+			}
+			r.owners[point] = node
+			r.points = append(r.points, point)
+		}
+	}
+	slices.Sort(r.points)
+	return r, nil
+}
+
+// Owner returns the node key belongs to
+func (r *Ring) Owner(key string) string {
+	h := hashKey(key)
+	i, _ := slices.BinarySearch(r.points, h)
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	// FNV spreads short, similar keys poorly over the high bits; mix them
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+// Overlap is a key shared by several records of a file, which a
+// partition by that key cannot keep apart
+type Overlap struct {
+	Key string
+	// Records are the indexes of the records with the key
+	Records []int
+}
+
+func (o Overlap) String() string {
+	records := make([]string, len(o.Records))
+	for i, n := range o.Records {
+		records[i] = strconv.Itoa(n + 1)
+	}
+	return fmt.Sprintf("key %q is shared by records %s", o.Key, strings.Join(records, ", "))
+}
+
+// HashPartition returns the records of the file that node owns on ring,
+// keyed by the value of column, or by the whole record when column is
+// empty. Records owned by a retired node are left out rather than moved
+// to another node, so no node is handed records a lost node may already
+// have used. Records sharing a key all go to the same node; they are
+// reported as overlaps, as VUs of that node may still share the key.
+func (f *File) HashPartition(ring *Ring, node, column string, retired []string) (*Subset, []Overlap, error) {
+	if column != "" && !slices.Contains(f.Columns(), column) {
+		return nil, nil, fmt.Errorf("column %q not found", column)
+	}
+
+	s := &Subset{file: f}
+	first := make(map[string]int, f.Len())
+	shared := make(map[string][]int)
+	for i := range f.Len() {
+		rec, err := f.Record(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		key := recordKey(rec, column, f.Columns())
+		if j, seen := first[key]; seen {
+			if shared[key] == nil {
+				shared[key] = []int{j}
+			}
+			shared[key] = append(shared[key], i)
+		} else {
+			first[key] = i
+		}
+
+		owner := ring.Owner(key)
+		if owner == node && !slices.Contains(retired, owner) {
+			s.records = append(s.records, i)
+		}
+	}
+
+	var overlaps []Overlap
+	for key, records := range shared {
+		overlaps = append(overlaps, Overlap{Key: key, Records: records})
+	}
+	slices.SortFunc(overlaps, func(a, b Overlap) int { return a.Records[0] - b.Records[0] })
+	return s, overlaps, nil
+}
+
+// recordKey returns the value of column in rec, or all of rec's values in
+// column order when column is empty
+func recordKey(rec Record, column string, columns []string) string {
+	if column != "" {
+		return rec[column]
+	}
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = rec[c]
+	}
+	return strings.Join(values, "\x1f")
+}
+
+// Subset is a selection of a File's records, in file order
+type Subset struct {
+	file    *File
+	records []int
+}
+
+// Len returns the number of records in the subset
+func (s *Subset) Len() int {
+	return len(s.records)
+}
+
+// Record returns the subset's record at index i
+func (s *Subset) Record(i int) (Record, error) {
+	if i < 0 || i >= s.Len() {
+		return nil, fmt.Errorf("record %d out of subset range [0, %d)", i, s.Len())
+	}
+	return s.file.Record(s.records[i])
+}
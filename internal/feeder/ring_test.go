@@ -0,0 +1,114 @@
+package feeder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	nodes := []string{"a", "b", "c"}
+	ring, err := NewRing(nodes)
+	if err != nil {
+		t.Fatalf("NewRing() failed: %v", err)
+	}
+	smaller, err := NewRing([]string{"a", "c"})
+	if err != nil {
+		t.Fatalf("NewRing() failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for i := range 3000 {
+		key := fmt.Sprintf("user%d", i)
+		owner := ring.Owner(key)
+		counts[owner]++
+		if owner != ring.Owner(key) {
+			t.Fatalf("%s: owner changed between calls", key)
+		}
+		// Only the keys of the removed node move
+		if owner != "b" && smaller.Owner(key) != owner {
+			t.Fatalf("%s moved from %s to %s when b was removed", key, owner, smaller.Owner(key))
+		}
+	}
+	for _, node := range nodes {
+		if counts[node] < 700 || counts[node] > 1300 {
+			t.Errorf("unbalanced ring: %v", counts)
+			break
+		}
+	}
+
+	if _, err := NewRing(nil); err == nil {
+		t.Error("expected error for an empty ring, got nil")
+	}
+	if _, err := NewRing([]string{"a", "a"}); err == nil {
+		t.Error("expected error for duplicate nodes, got nil")
+	}
+}
+
+func TestHashPartition(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("user,session\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&sb, "u%d,s%d\n", i%25, i)
+	}
+	f, err := Open(writeFile(t, "users.csv", sb.String()), Options{})
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	nodes := []string{"w0", "w1", "w2"}
+	ring, err := NewRing(nodes)
+	if err != nil {
+		t.Fatalf("NewRing() failed: %v", err)
+	}
+
+	owners := make(map[string]string)
+	total := 0
+	for _, node := range nodes {
+		s, overlaps, err := f.HashPartition(ring, node, "user", nil)
+		if err != nil {
+			t.Fatalf("HashPartition(%s) failed: %v", node, err)
+		}
+		if len(overlaps) != 5 || overlaps[0].String() != `key "u0" is shared by records 1, 26` {
+			t.Errorf("expected the 5 repeated users as overlaps, got %v", overlaps)
+		}
+		total += s.Len()
+		for i := range s.Len() {
+			rec, err := s.Record(i)
+			if err != nil {
+				t.Fatalf("Record(%d) failed: %v", i, err)
+			}
+			// Rows sharing a key go to the same node
+			if owner, ok := owners[rec["user"]]; ok && owner != node {
+				t.Errorf("user %s assigned to %s and %s", rec["user"], owner, node)
+			}
+			owners[rec["user"]] = node
+		}
+		if _, err := s.Record(s.Len()); err == nil {
+			t.Error("expected out of range error, got nil")
+		}
+	}
+	if total != 30 {
+		t.Errorf("expected all 30 records partitioned, got %d", total)
+	}
+
+	// Retiring a node leaves its records out instead of moving them
+	before, _, _ := f.HashPartition(ring, "w0", "user", nil)
+	after, _, err := f.HashPartition(ring, "w0", "user", []string{"w1"})
+	if err != nil || after.Len() != before.Len() {
+		t.Errorf("expected w0 to keep its %d records, got %d, %v", before.Len(), after.Len(), err)
+	}
+	retired, _, _ := f.HashPartition(ring, "w1", "user", []string{"w1"})
+	if retired.Len() != 0 {
+		t.Errorf("expected no records for a retired node, got %d", retired.Len())
+	}
+
+	// Without a key column, whole records are hashed
+	if _, overlaps, err := f.HashPartition(ring, "w0", "", nil); err != nil || len(overlaps) != 0 {
+		t.Errorf("expected distinct records, got %v, %v", overlaps, err)
+	}
+	if _, _, err := f.HashPartition(ring, "w0", "missing", nil); err == nil {
+		t.Error("expected error for a missing key column, got nil")
+	}
+}
@@ -0,0 +1,59 @@
+package feeder
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Type is the type a column's values are converted to. Values stay strings,
+// since they are substituted into requests, but are validated and written
+// in canonical form: "1.0" in an int column becomes "1", "TRUE" in a bool
+// column becomes "true".
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeFloat  Type = "float"
+	TypeBool   Type = "bool"
+)
+
+var types = []Type{TypeString, TypeInt, TypeFloat, TypeBool}
+
+func (t Type) valid() bool {
+	switch t {
+	case TypeString, TypeInt, TypeFloat, TypeBool:
+		return true
+	}
+	return false
+}
+
+func (t Type) convert(value string) (string, error) {
+	switch t {
+	case TypeInt:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10), nil
+		}
+		// Exporters often write integers as floats, e.g. 42.0
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return "", fmt.Errorf("%q is not an integer", value)
+		}
+		return strconv.FormatInt(int64(f), 10), nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case TypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a boolean", value)
+		}
+		return strconv.FormatBool(b), nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,515 @@
+package har
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"loadforge-agent/internal/importutil"
+	"loadforge-agent/internal/scenario"
+)
+
+// Options select the requests Convert turns into steps and the load of the
+// scenario
+type Options struct {
+	// Origin selects the requests that become steps, e.g.
+	// https://app.example.com, by default the origin most requests were
+	// sent to. Requests to other origins, such as analytics or CDNs, are
+	// left out.
+	Origin string
+	// BaseURL is the base URL of the scenario, by default Origin, to
+	// replay a flow recorded against one environment on another
+	BaseURL      string
+	VirtualUsers uint64
+	// Duration is in seconds
+	Duration uint64
+	// IncludeStatic keeps the requests of static assets such as scripts,
+	// stylesheets, images and fonts
+	IncludeStatic bool
+}
+
+// MinThinkTime is the shortest pause of the recording between two requests
+// kept as the delay of a step, shorter ones being the browser's own
+const MinThinkTime = 500 * time.Millisecond
+
+// skippedHeaders are the request headers the agent's HTTP client or cookie
+// jar set, or that would make the server answer from the recording's
+// cache
+var skippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"keep-alive":        true,
+	"transfer-encoding": true,
+	"te":                true,
+	"upgrade":           true,
+	"proxy-connection":  true,
+	"accept-encoding":   true,
+	"cookie":            true,
+	"if-none-match":     true,
+	"if-modified-since": true,
+}
+
+// skippedHeaderPrefixes are prefixes of headers only browsers send, and of
+// HTTP/2 pseudo-headers
+var skippedHeaderPrefixes = []string{":", "sec-fetch-", "sec-ch-"}
+
+var staticExtensions = []string{
+	".js", ".mjs", ".css", ".map", ".png", ".jpg", ".jpeg", ".gif", ".svg", ".ico", ".webp", ".avif",
+	".woff", ".woff2", ".ttf", ".otf", ".eot", ".mp4", ".webm", ".mp3", ".wasm",
+}
+
+var staticTypes = []string{"image/", "font/", "video/", "audio/", "text/css", "javascript", "application/wasm"}
+
+// source is where a value of an earlier response can be read from
+type source struct {
+	step int
+	// lookup reads the value in save_to_context, e.g. response.user.id
+	lookup string
+	// name is the variable it is saved to unless taken
+	name string
+}
+
+type converter struct {
+	sc *scenario.Scenario
+	// sources maps values of the responses so far to where they are read
+	// from, the first response holding a value winning
+	sources map[string]source
+	// vars maps the values saved so far to their variables
+	vars map[string]string
+	// taken are the variable names in use
+	taken map[string]bool
+	// requests counts the steps of each request, to name repeated ones
+	requests map[string]int
+	// cookies are those set by the responses so far
+	cookies  map[string]bool
+	warnings []string
+	warned   map[string]bool
+}
+
+// Convert converts the requests of h to one origin into a scenario, a step
+// per request in the order they were sent. Pauses of at least MinThinkTime
+// between requests become the delays of the steps.
+//
+// Values a request reuses from an earlier response, such as an ID in its
+// path or a token in its headers, are saved from that response with
+// save_to_context and referenced as variables. Values that look dynamic
+// but do not come from a response, such as UUIDs, JWTs and timestamps, are
+// kept as recorded and flagged in the warnings Convert returns along with
+// what it left out, to be parameterized by hand.
+//
+// Cookies are left to the agent's cookie jar: those set before the
+// recording started are flagged, since replays do not send them.
+func Convert(h *HAR, opts Options) (*scenario.Scenario, []string, error) {
+	c := &converter{
+		sources:  make(map[string]source),
+		vars:     make(map[string]string),
+		taken:    make(map[string]bool),
+		requests: make(map[string]int),
+		cookies:  make(map[string]bool),
+		warned:   make(map[string]bool),
+	}
+
+	var (
+		entries         []*Entry
+		origins         []string
+		counts          = make(map[string]int)
+		static, failing int
+	)
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+		if e.Response.Status == 0 {
+			// Blocked or cancelled by the browser
+			failing++
+			continue
+		}
+		if !opts.IncludeStatic && isStatic(e, u) {
+			static++
+			continue
+		}
+		o := u.Scheme + "://" + u.Host
+		if counts[o] == 0 {
+			origins = append(origins, o)
+		}
+		counts[o]++
+		entries = append(entries, e)
+	}
+
+	origin := opts.Origin
+	if origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" {
+			return nil, nil, fmt.Errorf("invalid origin %q, expected e.g. https://app.example.com", origin)
+		}
+		origin = u.Scheme + "://" + u.Host
+	} else {
+		for _, o := range origins {
+			if counts[o] > counts[origin] {
+				origin = o
+			}
+		}
+	}
+	if counts[origin] == 0 {
+		if origin == "" {
+			return nil, nil, fmt.Errorf("no requests to convert")
+		}
+		return nil, nil, fmt.Errorf("no requests to %s", origin)
+	}
+	for _, o := range origins {
+		if o != origin {
+			c.warn("left out %d requests to %s", counts[o], o)
+		}
+	}
+	if static > 0 {
+		c.warn("left out %d requests of static assets", static)
+	}
+	if failing > 0 {
+		c.warn("left out %d requests without a response", failing)
+	}
+
+	c.sc = &scenario.Scenario{
+		Name:         strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://"),
+		BaseURL:      cmp.Or(strings.TrimSuffix(opts.BaseURL, "/"), origin),
+		VirtualUsers: max(opts.VirtualUsers, 1),
+		Duration:     max(opts.Duration, 1),
+	}
+	var prev *Entry
+	for _, e := range entries {
+		if u, _ := url.Parse(e.Request.URL); u.Scheme+"://"+u.Host != origin {
+			continue
+		}
+		if prev != nil {
+			if pause := e.StartedDateTime.Sub(prev.end()); pause >= MinThinkTime {
+				last := &c.sc.Steps[len(c.sc.Steps)-1]
+				last.Delay = scenario.Delay{Duration: scenario.Duration{Duration: pause.Round(100 * time.Millisecond)}}
+			}
+		}
+		c.sc.Steps = append(c.sc.Steps, c.step(e))
+		c.collect(len(c.sc.Steps)-1, e)
+		prev = e
+	}
+	return c.sc, c.warnings, nil
+}
+
+func (c *converter) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if !c.warned[msg] {
+		c.warned[msg] = true
+		c.warnings = append(c.warnings, msg)
+	}
+}
+
+// isStatic reports whether e fetched a static asset, by the extension of
+// its path or the type of its response
+func isStatic(e *Entry, u *url.URL) bool {
+	if slices.Contains(staticExtensions, strings.ToLower(path.Ext(u.Path))) {
+		return true
+	}
+	mime, _, _ := strings.Cut(strings.ToLower(e.Response.Content.MimeType), ";")
+	return slices.ContainsFunc(staticTypes, func(t string) bool { return strings.Contains(mime, t) })
+}
+
+// step converts the request of e
+func (c *converter) step(e *Entry) scenario.Step {
+	u, _ := url.Parse(e.Request.URL)
+	method := strings.ToUpper(e.Request.Method)
+	var step scenario.Step
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, seg := range segments {
+		value, err := url.PathUnescape(seg)
+		if err != nil || value == "" {
+			continue
+		}
+		if name, ok := c.reference(value); ok {
+			segments[i] = "{" + name + "}"
+			step.PathParams = importutil.SetKey(step.PathParams, name, "${"+name+"}")
+		}
+	}
+	p := strings.Join(segments, "/")
+	if p == "" {
+		p = "/"
+	}
+	step.Request = method + " " + p
+	if n := c.requests[step.Request] + 1; n > 1 {
+		step.Name = fmt.Sprintf("%s (%d)", step.Request, n)
+	}
+	c.requests[step.Request]++
+
+	for _, seg := range segments {
+		if value, err := url.PathUnescape(seg); err == nil && !strings.HasPrefix(value, "{") {
+			c.flag(&step, "path segment "+value, value, e)
+		}
+	}
+
+	query := u.Query()
+	for _, key := range slices.Sorted(maps.Keys(query)) {
+		value := query.Get(key)
+		if name, ok := c.reference(value); ok {
+			value = "${" + name + "}"
+		} else {
+			c.flag(&step, "query "+key, value, e)
+		}
+		step.Query = importutil.SetKey(step.Query, key, value)
+	}
+
+	for _, h := range e.Request.Headers {
+		name := strings.ToLower(h.Name)
+		if name == "cookie" {
+			c.checkCookies(h.Value)
+		}
+		if skippedHeaders[name] || slices.ContainsFunc(skippedHeaderPrefixes, func(p string) bool {
+			return strings.HasPrefix(name, p)
+		}) {
+			continue
+		}
+		value, ok := c.substitute(h.Value)
+		if !ok {
+			fields := strings.Fields(h.Value)
+			if len(fields) > 0 {
+				c.flag(&step, "header "+h.Name, fields[len(fields)-1], e)
+			}
+		}
+		step.Headers = importutil.SetKey(step.Headers, h.Name, value)
+	}
+
+	if e.Request.PostData != nil && e.Request.PostData.Text != "" &&
+		method != http.MethodGet && method != http.MethodHead && method != http.MethodTrace {
+		c.body(&step, e)
+	}
+	return step
+}
+
+// body converts the body of the request of e. JSON bodies are kept
+// structured so they can be read and set by save_to_context and
+// next_steps.
+func (c *converter) body(step *scenario.Step, e *Entry) {
+	data := e.Request.PostData
+	mime := strings.ToLower(data.MimeType)
+	if strings.HasPrefix(mime, "multipart/") {
+		c.warn("step %s: multipart body left out, set a payload", step.ID())
+		return
+	}
+	if strings.Contains(mime, "json") {
+		var v any
+		if json.Unmarshal([]byte(data.Text), &v) == nil {
+			step.Body = c.bodyValue(step, v, nil, e)
+			if !importutil.HasKey(step.Headers, "Content-Type") {
+				step.Headers = importutil.SetKey(step.Headers, "Content-Type", data.MimeType)
+			}
+			return
+		}
+	}
+	step.Body, _ = c.substitute(data.Text)
+	if data.MimeType != "" && !importutil.HasKey(step.Headers, "Content-Type") {
+		step.Headers = importutil.SetKey(step.Headers, "Content-Type", data.MimeType)
+	}
+}
+
+// bodyValue returns v, the value at path in a JSON request body, with the
+// strings of earlier responses replaced by their variables
+func (c *converter) bodyValue(step *scenario.Step, v any, path []string, e *Entry) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			v[k] = c.bodyValue(step, v[k], append(path, k), e)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = c.bodyValue(step, item, append(path, strconv.Itoa(i)), e)
+		}
+	case string:
+		if name, ok := c.reference(v); ok {
+			return "${" + name + "}"
+		}
+		c.flag(step, "body field "+strings.Join(path, "."), v, e)
+	}
+	return v
+}
+
+// checkCookies flags the cookies a request sends that no earlier response
+// set
+func (c *converter) checkCookies(header string) {
+	cookies, err := http.ParseCookie(header)
+	if err != nil {
+		return
+	}
+	for _, cookie := range cookies {
+		if !c.cookies[cookie.Name] {
+			c.warn("cookie %s was set before the recording started, log in within the flow or set it in headers", cookie.Name)
+		}
+	}
+}
+
+// collect records the values of the response of e, the step-th step,
+// that later requests may reuse: fields of its JSON body, token-like
+// headers and cookies
+func (c *converter) collect(step int, e *Entry) {
+	add := func(value, lookup, name string) {
+		if _, ok := c.sources[value]; !ok && reusable(value) {
+			c.sources[value] = source{step: step, lookup: lookup, name: name}
+		}
+	}
+
+	for _, h := range e.Response.Headers {
+		name := strings.ToLower(h.Name)
+		if name == "set-cookie" {
+			if cookie, err := http.ParseSetCookie(h.Value); err == nil {
+				c.cookies[cookie.Name] = true
+				add(cookie.Value, "cookies."+cookie.Name, cookie.Name)
+			}
+			continue
+		}
+		if strings.Contains(name, "token") || strings.Contains(name, "csrf") ||
+			strings.Contains(name, "xsrf") || strings.Contains(name, "session") {
+			add(h.Value, "headers."+h.Name, h.Name)
+		}
+	}
+
+	if !strings.Contains(strings.ToLower(e.Response.Content.MimeType), "json") {
+		return
+	}
+	var body any
+	if json.Unmarshal(e.Response.Content.body(), &body) != nil {
+		return
+	}
+	var walk func(v any, path []string)
+	walk = func(v any, path []string) {
+		switch v := v.(type) {
+		case map[string]any:
+			for _, k := range slices.Sorted(maps.Keys(v)) {
+				// Keys gjson would read as syntax
+				if !strings.ContainsAny(k, ".*?#|@\\") {
+					walk(v[k], append(path, k))
+				}
+			}
+		case []any:
+			for i, item := range v {
+				walk(item, append(path, strconv.Itoa(i)))
+			}
+		case string:
+			add(v, "response."+strings.Join(path, "."), varName(path))
+		case float64:
+			if v == float64(int64(v)) {
+				add(strconv.FormatInt(int64(v), 10), "response."+strings.Join(path, "."), varName(path))
+			}
+		}
+	}
+	walk(body, nil)
+}
+
+// reusable reports whether a value of a response is specific enough that
+// a request sending it is taken to reuse it: an ID of at least 4 digits,
+// or a string of at least 6 characters without spaces holding a digit,
+// such as a UUID or a token, but not a date
+func reusable(value string) bool {
+	if len(value) < 4 || strings.ContainsAny(value, " \t\n") || strings.Contains(value, "://") {
+		return false
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return true
+	}
+	if len(value) < 6 || !strings.ContainsAny(value, "0123456789") {
+		return false
+	}
+	if _, err := time.Parse(time.DateOnly, value[:min(len(value), len(time.DateOnly))]); err == nil {
+		return false
+	}
+	return true
+}
+
+// varName returns the variable name of the value at path in a response,
+// e.g. user_id for user.id or token for data.token
+func varName(path []string) string {
+	var keys []string
+	for _, k := range path {
+		if _, err := strconv.Atoi(k); err != nil {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return "value"
+	}
+	name := keys[len(keys)-1]
+	if strings.EqualFold(name, "id") && len(keys) > 1 {
+		name = keys[len(keys)-2] + "_" + name
+	}
+	return name
+}
+
+// reference returns the variable holding value if an earlier response
+// had it, saving the value from that response
+func (c *converter) reference(value string) (string, bool) {
+	if name, ok := c.vars[value]; ok {
+		return name, true
+	}
+	src, ok := c.sources[value]
+	if !ok {
+		return "", false
+	}
+	name := sanitize(src.name)
+	for n := 2; c.taken[name]; n++ {
+		name = sanitize(src.name) + "_" + strconv.Itoa(n)
+	}
+	c.taken[name] = true
+	c.vars[value] = name
+	step := &c.sc.Steps[src.step]
+	step.SaveToContext = importutil.SetKey(step.SaveToContext, name, src.lookup)
+	return name, true
+}
+
+// substitute returns s with the values of earlier responses it holds
+// replaced by their variables, e.g. a token in an Authorization header,
+// reporting whether there were any
+func (c *converter) substitute(s string) (string, bool) {
+	if name, ok := c.reference(s); ok {
+		return "${" + name + "}", true
+	}
+	// Only long values, shorter ones being likely to appear by chance
+	var found []string
+	for value := range c.sources {
+		if len(value) >= 16 && strings.Contains(s, value) {
+			found = append(found, value)
+		}
+	}
+	slices.SortFunc(found, func(a, b string) int { return cmp.Or(len(b)-len(a), strings.Compare(a, b)) })
+	for _, value := range found {
+		if !strings.Contains(s, value) {
+			continue
+		}
+		name, _ := c.reference(value)
+		s = strings.ReplaceAll(s, value, "${"+name+"}")
+	}
+	return s, len(found) > 0
+}
+
+// flag warns about value, found at where in the request of step, if it
+// looks dynamic
+func (c *converter) flag(step *scenario.Step, where, value string, e *Entry) {
+	if kind := dynamicKind(value, e.StartedDateTime); kind != "" {
+		c.warn("step %s: %s looks like %s, parameterize it", step.ID(), where, kind)
+	}
+}
+
+// sanitize turns name into a variable name, replacing the characters
+// other than letters, digits and underscores
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
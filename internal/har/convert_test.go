@@ -0,0 +1,182 @@
+package har
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/scenario"
+)
+
+// recorded is when the requests of the test archive were sent
+var recorded = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+func entry(offset time.Duration, method, url, request, response string) string {
+	return fmt.Sprintf(`{"startedDateTime": %q, "time": 100,
+		"request": {"method": %q, "url": %q %s},
+		"response": {"status": 200 %s}}`,
+		recorded.Add(offset).Format(time.RFC3339Nano), method, url, request, response)
+}
+
+func testArchive() string {
+	ts := recorded.Add(2 * time.Second).UnixMilli()
+	entries := []string{
+		entry(0, "POST", "https://shop.example.com/api/login",
+			`, "headers": [{"name": "Host", "value": "shop.example.com"}, {"name": "Content-Type", "value": "application/json"},
+				{"name": "Cookie", "value": "consent=yes"}],
+			"postData": {"mimeType": "application/json", "text": "{\"user\": \"ada\", \"password\": \"pw\"}"}`,
+			`, "headers": [{"name": "Set-Cookie", "value": "session=abc123xyz; Path=/; HttpOnly"}],
+			"content": {"mimeType": "application/json", "text": "{\"token\": \"tok_9f8e7d6c5b4a3210ffee\", \"user\": {\"id\": 4242}}"}`),
+		entry(200*time.Millisecond, "GET", "https://cdn.example.com/app.js", ``, `, "content": {"mimeType": "text/javascript"}`),
+		entry(300*time.Millisecond, "GET", "https://shop.example.com/logo", ``, `, "content": {"mimeType": "image/png"}`),
+		entry(400*time.Millisecond, "GET", "https://www.google-analytics.com/collect?v=1", ``, ``),
+		entry(2100*time.Millisecond, "GET", fmt.Sprintf("https://shop.example.com/api/users/4242/orders?ts=%d&request_id=0b7c2a55-4c1e-4d7e-9d1a-3f2b8c6e5a10", ts),
+			`, "headers": [{"name": ":authority", "value": "shop.example.com"}, {"name": "Authorization", "value": "Bearer tok_9f8e7d6c5b4a3210ffee"},
+				{"name": "Cookie", "value": "session=abc123xyz; consent=yes"}, {"name": "If-None-Match", "value": "\"v1\""},
+				{"name": "sec-fetch-mode", "value": "cors"}]`,
+			`, "content": {"mimeType": "application/json", "text": "{\"orders\": [{\"id\": \"ord_81c2f3\", \"total\": 10}]}"}`),
+		entry(2300*time.Millisecond, "PUT", "https://shop.example.com/api/orders/ord_81c2f3",
+			`, "postData": {"mimeType": "application/json",
+				"text": "{\"order_id\": \"ord_81c2f3\", \"note\": \"gift\", \"trace\": \"eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln\"}"}`, ``),
+		entry(2500*time.Millisecond, "GET", "https://shop.example.com/api/users/4242/orders", ``, ``),
+		`{"startedDateTime": "2026-03-02T10:00:03Z", "time": 0, "request": {"method": "GET", "url": "https://shop.example.com/api/ping"}, "response": {"status": 0}}`,
+	}
+	return `{"log": {"version": "1.2", "entries": [` + strings.Join(entries, ",") + `]}}`
+}
+
+func TestConvert(t *testing.T) {
+	data := []byte(testArchive())
+	if !IsHAR(data) {
+		t.Fatal("IsHAR = false")
+	}
+	h, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, warnings, err := Convert(h, Options{VirtualUsers: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sc.Name != "shop.example.com" || sc.BaseURL != "https://shop.example.com" || sc.VirtualUsers != 10 {
+		t.Errorf("scenario = %s %s %d VUs", sc.Name, sc.BaseURL, sc.VirtualUsers)
+	}
+	var ids []string
+	for i := range sc.Steps {
+		ids = append(ids, sc.Steps[i].ID())
+	}
+	want := []string{"POST /api/login", "GET /api/users/{user_id}/orders", "PUT /api/orders/{orders_id}", "GET /api/users/{user_id}/orders (2)"}
+	if !slices.Equal(ids, want) {
+		t.Fatalf("steps = %q, want %q", ids, want)
+	}
+
+	login := sc.Steps[0]
+	if !reflect.DeepEqual(login.Headers, map[string]string{"Content-Type": "application/json"}) {
+		t.Errorf("login headers = %v", login.Headers)
+	}
+	if !reflect.DeepEqual(login.Body, map[string]any{"user": "ada", "password": "pw"}) {
+		t.Errorf("login body = %v", login.Body)
+	}
+	if login.Delay.Duration.Duration != 2*time.Second {
+		t.Errorf("login delay = %v, want the 2s pause before the next request", login.Delay.Duration)
+	}
+	if want := map[string]string{"token": "response.token", "user_id": "response.user.id"}; !reflect.DeepEqual(login.SaveToContext, want) {
+		t.Errorf("login save_to_context = %v, want %v", login.SaveToContext, want)
+	}
+
+	orders := sc.Steps[1]
+	if !reflect.DeepEqual(orders.PathParams, map[string]string{"user_id": "${user_id}"}) {
+		t.Errorf("orders path params = %v", orders.PathParams)
+	}
+	if !reflect.DeepEqual(orders.Headers, map[string]string{"Authorization": "Bearer ${token}"}) {
+		t.Errorf("orders headers = %v", orders.Headers)
+	}
+	if orders.Query["request_id"] != "0b7c2a55-4c1e-4d7e-9d1a-3f2b8c6e5a10" || !orders.Delay.IsZero() {
+		t.Errorf("orders query = %v with delay %v", orders.Query, orders.Delay.Duration)
+	}
+	if want := map[string]string{"orders_id": "response.orders.0.id"}; !reflect.DeepEqual(orders.SaveToContext, want) {
+		t.Errorf("orders save_to_context = %v, want %v", orders.SaveToContext, want)
+	}
+
+	update := sc.Steps[2]
+	if body, _ := update.Body.(map[string]any); body["order_id"] != "${orders_id}" || body["note"] != "gift" {
+		t.Errorf("update body = %v", update.Body)
+	}
+
+	wantWarnings := []string{
+		"left out 1 requests to https://www.google-analytics.com",
+		"left out 2 requests of static assets",
+		"left out 1 requests without a response",
+		"cookie consent was set before the recording started, log in within the flow or set it in headers",
+		"step GET /api/users/{user_id}/orders: query request_id looks like a UUID, parameterize it",
+		"step GET /api/users/{user_id}/orders: query ts looks like a timestamp, parameterize it",
+		"step PUT /api/orders/{orders_id}: body field trace looks like a JWT, parameterize it",
+	}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings =\n%s\nwant\n%s", strings.Join(warnings, "\n"), strings.Join(wantWarnings, "\n"))
+	}
+
+	out, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := scenario.NewParser()
+	if err := p.ParseData(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Errorf("converted scenario is invalid: %v\n%s", err, out)
+	}
+}
+
+func TestConvert_Options(t *testing.T) {
+	h, err := Parse([]byte(testArchive()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc, _, err := Convert(h, Options{IncludeStatic: true, BaseURL: "https://staging.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.BaseURL != "https://staging.example.com" || len(sc.Steps) != 5 || sc.Steps[1].Request != "GET /logo" {
+		t.Errorf("base URL %s with %d steps, want the static logo kept", sc.BaseURL, len(sc.Steps))
+	}
+
+	sc, _, err = Convert(h, Options{Origin: "https://www.google-analytics.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Steps) != 1 || sc.Steps[0].Request != "GET /collect" || sc.Steps[0].Query["v"] != "1" {
+		t.Errorf("steps = %+v, want the analytics request", sc.Steps)
+	}
+
+	if _, _, err := Convert(h, Options{Origin: "https://nowhere.example.com"}); err == nil {
+		t.Error("expected an error for an origin without requests")
+	}
+}
+
+func TestDynamicKind(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"0b7c2a55-4c1e-4d7e-9d1a-3f2b8c6e5a10", "a UUID"},
+		{"eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln", "a JWT"},
+		{fmt.Sprint(recorded.Unix()), "a timestamp"},
+		{fmt.Sprint(recorded.Add(-time.Hour).UnixMilli()), "a timestamp"},
+		{"1234567890", ""},
+		{"d41d8cd98f00b204e9800998ecf8427e", "a token"},
+		{"application/json", ""},
+		{"checkout-confirmation-page", ""},
+	}
+	for _, tt := range tests {
+		if got := dynamicKind(tt.value, recorded); got != tt.want {
+			t.Errorf("dynamicKind(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
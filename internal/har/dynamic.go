@@ -0,0 +1,50 @@
+package har
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	jwtPattern   = regexp.MustCompile(`^eyJ[\w-]+\.eyJ[\w-]+\.[\w-]*$`)
+	tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_\-+/=.]{20,}$`)
+)
+
+// dynamicKind returns what value looks like if it is likely to change
+// from one session to the next, e.g. "a UUID", or "" if it looks static.
+// Timestamps must be within a day of sent, when the request was recorded.
+func dynamicKind(value string, sent time.Time) string {
+	switch {
+	case uuidPattern.MatchString(value):
+		return "a UUID"
+	case jwtPattern.MatchString(value):
+		return "a JWT"
+	case isTimestamp(value, sent):
+		return "a timestamp"
+	case tokenPattern.MatchString(value) && strings.ContainsAny(value, "0123456789") &&
+		strings.IndexFunc(value, func(r rune) bool { return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' }) >= 0:
+		return "a token"
+	}
+	return ""
+}
+
+// isTimestamp reports whether value is a Unix time in seconds or
+// milliseconds within a day of sent
+func isTimestamp(value string, sent time.Time) bool {
+	if len(value) != 10 && len(value) != 13 {
+		return false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	t := time.Unix(n, 0)
+	if len(value) == 13 {
+		t = time.UnixMilli(n)
+	}
+	d := t.Sub(sent)
+	return d > -24*time.Hour && d < 24*time.Hour
+}
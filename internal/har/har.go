@@ -0,0 +1,106 @@
+// Package har converts HTTP Archives, the HAR files browsers export from
+// their network panel, into scenarios replaying the recorded user flow:
+// a step per request, with the values a request reuses from an earlier
+// response saved and referenced as variables.
+package har
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HAR is an HTTP Archive
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log is the recording of an HTTP Archive
+type Log struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is a recorded request and its response
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the duration of the exchange in milliseconds
+	Time     float64  `json:"time"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// end returns when the exchange completed
+func (e *Entry) end() time.Time {
+	return e.StartedDateTime.Add(time.Duration(e.Time * float64(time.Millisecond)))
+}
+
+// Request is a recorded request
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData"`
+}
+
+// PostData is the body of a recorded request
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status  int         `json:"status"`
+	Headers []NameValue `json:"headers"`
+	Content Content     `json:"content"`
+}
+
+// Content is the body of a recorded response
+type Content struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	// Encoding is base64 for binary bodies
+	Encoding string `json:"encoding"`
+}
+
+// body returns the decoded body
+func (c *Content) body() []byte {
+	if c.Encoding == "base64" {
+		b, err := base64.StdEncoding.DecodeString(c.Text)
+		if err != nil {
+			return nil
+		}
+		return b
+	}
+	return []byte(c.Text)
+}
+
+// NameValue is a header, query parameter or cookie
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// IsHAR reports whether data looks like an HTTP Archive rather than
+// another JSON document
+func IsHAR(data []byte) bool {
+	var probe struct {
+		Log json.RawMessage `json:"log"`
+	}
+	return json.Unmarshal(data, &probe) == nil && bytes.HasPrefix(bytes.TrimSpace(probe.Log), []byte("{"))
+}
+
+// Parse reads an HTTP Archive
+func Parse(data []byte) (*HAR, error) {
+	var h HAR
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR: %w", err)
+	}
+	if len(h.Log.Entries) == 0 {
+		return nil, fmt.Errorf("the HAR file has no requests")
+	}
+	return &h, nil
+}
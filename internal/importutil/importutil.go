@@ -0,0 +1,46 @@
+// Package importutil holds the helpers shared by the converters that write
+// scenarios from the files of other tools: Postman collections, HAR files
+// and JMeter test plans.
+package importutil
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// placeholder matches the ${name} placeholders of scenarios
+var placeholder = regexp.MustCompile(`\$\{[^{}]+\}`)
+
+// SetKey sets key to value in m, creating m if it is nil, and returns m
+func SetKey(m map[string]string, key, value string) map[string]string {
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	return m
+}
+
+// HasKey reports whether m has key, ignoring case as header names do
+func HasKey(m map[string]string, key string) bool {
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormEscape escapes s for a form body, leaving its placeholders as they
+// are
+func FormEscape(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholder.FindAllStringIndex(s, -1) {
+		b.WriteString(url.QueryEscape(s[last:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(url.QueryEscape(s[last:]))
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package importutil
+
+import "testing"
+
+func TestSetKey(t *testing.T) {
+	m := SetKey(nil, "Accept", "application/json")
+	m = SetKey(m, "X-Team", "qa")
+	if len(m) != 2 || m["Accept"] != "application/json" || m["X-Team"] != "qa" {
+		t.Errorf("unexpected map: %v", m)
+	}
+}
+
+func TestHasKey(t *testing.T) {
+	m := map[string]string{"Content-Type": "text/plain"}
+	if !HasKey(m, "content-type") || HasKey(m, "Accept") || HasKey(nil, "Accept") {
+		t.Errorf("unexpected keys found in %v", m)
+	}
+}
+
+func TestFormEscape(t *testing.T) {
+	if got, want := FormEscape("a b&c=${user.name}/${__uuid()}"), "a+b%26c%3D${user.name}%2F${__uuid()}"; got != want {
+		t.Errorf("FormEscape() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,663 @@
+package jmeter
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"loadforge-agent/internal/importutil"
+	"loadforge-agent/internal/scenario"
+)
+
+// Options set what a test plan does not say about the scenario, or
+// override what it does
+type Options struct {
+	// BaseURL replaces the scheme, host and port of every sampler, by
+	// default those of the first
+	BaseURL string
+	// VirtualUsers replaces the threads of the thread groups
+	VirtualUsers uint64
+	// Duration, in seconds, replaces that of the thread groups
+	Duration uint64
+}
+
+// DefaultDuration is the duration of the scenario, in seconds, when the
+// thread groups run a number of loops rather than for a duration
+const DefaultDuration = 60
+
+// maxRampStages is the number of stages a thread group's ramp-up is split
+// into at most
+const maxRampStages = 10
+
+// Bits of the test type of a ResponseAssertion
+const (
+	assertMatch     = 1
+	assertContains  = 2
+	assertNot       = 4
+	assertEquals    = 8
+	assertSubstring = 16
+	assertOr        = 32
+)
+
+// threadGroups are the thread group types converted; others, such as those
+// of plugins, are converted like a ThreadGroup after a warning
+var threadGroups = []string{"ThreadGroup", "SetupThreadGroup", "PostThreadGroup"}
+
+// controllers are the controllers whose samplers are converted as if they
+// were not there; those not listed have logic the scenario cannot express
+var controllers = []string{"GenericController", "TransactionController"}
+
+// scoped are the elements that apply to the samplers in their scope
+var scoped = []string{
+	"HeaderManager", "ConfigTestElement", "CSVDataSet", "Arguments",
+	"ConstantTimer", "UniformRandomTimer", "GaussianRandomTimer",
+	"ResponseAssertion", "DurationAssertion", "JSONPathAssertion", "JSONPostProcessor",
+	// The agent keeps cookies and connections on its own
+	"CookieManager", "CacheManager", "DNSCacheManager",
+}
+
+// ignored are the elements that have no effect on the load, such as
+// listeners
+var ignored = []string{"ResultCollector", "BackendListener", "Summariser", "TestFragmentController"}
+
+// placeholder matches ${name} variables and ${__name(args)} functions
+var placeholder = regexp.MustCompile(`\$\{([^{}]+)\}`)
+
+// scope is what the elements around a sampler set
+type scope struct {
+	headers map[string]string
+	// protocol, domain, port and path are the HTTP Request Defaults
+	protocol, domain, port, path string
+	checks                       []scenario.Check
+	save                         map[string]string
+	delay                        scenario.Delay
+}
+
+func (s scope) clone() scope {
+	s.headers = maps.Clone(s.headers)
+	s.checks = slices.Clone(s.checks)
+	s.save = maps.Clone(s.save)
+	return s
+}
+
+// flow is the steps of a thread group
+type flow struct {
+	name    string
+	threads uint64
+	// ramp is the ramp-up period and duration the scheduled duration, 0
+	// when the thread group runs loops
+	ramp, duration time.Duration
+	steps          []scenario.Step
+}
+
+type converter struct {
+	opts Options
+	sc   *scenario.Scenario
+	// columns are the variables of the CSV data set, read as ${csv.name}
+	columns []string
+	// names are the step names of the current thread group
+	names    map[string]bool
+	warnings []string
+	warned   map[string]bool
+}
+
+// Convert converts p into a scenario. The HTTP samplers of a thread group
+// become its steps; several thread groups become weighted scenarios,
+// sharing the VUs by their threads. A setUp or tearDown thread group
+// becomes the setup or teardown steps.
+//
+// Header managers, HTTP Request Defaults, timers, assertions and JSON
+// extractors apply to the samplers in their scope, like in JMeter; timers,
+// which JMeter applies before each sampler, become the delay after it.
+// Response, duration and JSON path assertions become checks, and JSON
+// extractors save_to_context. The variables of a CSV data set are read
+// from the scenario's data as ${csv.<name>}.
+//
+// Convert also returns warnings about what it could not convert, such as
+// logic controllers, scripts or other samplers than HTTP ones.
+func Convert(p *Plan, opts Options) (*scenario.Scenario, []string, error) {
+	c := &converter{opts: opts, warned: make(map[string]bool)}
+	c.sc = &scenario.Scenario{
+		Name:      cmp.Or(p.Root.Name, "converted"),
+		BaseURL:   strings.TrimSuffix(opts.BaseURL, "/"),
+		Variables: make(map[string]string),
+	}
+	for _, arg := range p.Root.Arguments("Argument.name", "Argument.value", "TestPlan.user_defined_variables", "Arguments.arguments") {
+		if arg[0] != "" {
+			c.sc.Variables[arg[0]] = c.expand(arg[1])
+		}
+	}
+
+	root := c.scope(scope{}, p.Root.Children, false)
+	var flows []flow
+	for _, e := range p.Root.Children {
+		if !e.Enabled || !strings.HasSuffix(e.Type, "ThreadGroup") {
+			continue
+		}
+		if !slices.Contains(threadGroups, e.Type) {
+			c.warn("%s %q converted as a ThreadGroup", e.Type, e.Name)
+		}
+		c.names = make(map[string]bool)
+		steps := c.steps(e.Children, root)
+		switch e.Type {
+		case "SetupThreadGroup":
+			c.sc.Setup = append(c.sc.Setup, steps...)
+		case "PostThreadGroup":
+			c.sc.Teardown = append(c.sc.Teardown, steps...)
+		default:
+			if len(steps) > 0 {
+				flows = append(flows, c.threadGroup(e, steps))
+			}
+		}
+	}
+	if len(flows) == 0 {
+		return nil, c.warnings, fmt.Errorf("the test plan has no thread group with HTTP samplers")
+	}
+	if c.sc.BaseURL == "" {
+		return nil, c.warnings, fmt.Errorf("no HTTP sampler has a server name, set a base URL")
+	}
+
+	var threads uint64
+	var ramp, duration time.Duration
+	for _, f := range flows {
+		threads += f.threads
+		ramp, duration = max(ramp, f.ramp), max(duration, f.duration)
+	}
+	c.sc.VirtualUsers = cmp.Or(opts.VirtualUsers, threads)
+	c.sc.Duration = opts.Duration
+	if c.sc.Duration == 0 {
+		c.sc.Duration = uint64(duration / time.Second)
+	}
+	if c.sc.Duration == 0 {
+		c.sc.Duration = DefaultDuration
+		c.warn("the thread groups run loops rather than for a duration, the scenario runs for %ds", DefaultDuration)
+	}
+	if ramp > 0 && ramp < time.Duration(c.sc.Duration)*time.Second {
+		c.sc.Stages = rampStages(c.sc.VirtualUsers, ramp)
+	}
+
+	if len(flows) == 1 {
+		c.sc.Steps = flows[0].steps
+	} else {
+		for _, f := range flows {
+			c.sc.Scenarios = append(c.sc.Scenarios, scenario.Weighted{Name: f.name, Weight: float64(f.threads), Steps: f.steps})
+		}
+		c.sc.VirtualUsers = max(c.sc.VirtualUsers, uint64(len(flows)))
+	}
+	if len(c.sc.Variables) == 0 {
+		c.sc.Variables = nil
+	}
+	return c.sc, c.warnings, nil
+}
+
+func (c *converter) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if !c.warned[msg] {
+		c.warned[msg] = true
+		c.warnings = append(c.warnings, msg)
+	}
+}
+
+// threadGroup returns the flow of the thread group e
+func (c *converter) threadGroup(e *Element, steps []scenario.Step) flow {
+	f := flow{name: cmp.Or(e.Name, e.Type), threads: 1, steps: steps}
+	if n, ok := c.number(e.Prop("ThreadGroup.num_threads")); ok && n > 0 {
+		f.threads = uint64(n)
+	}
+	if n, ok := c.number(e.Prop("ThreadGroup.ramp_time")); ok {
+		f.ramp = time.Duration(n) * time.Second
+	}
+	if e.Prop("ThreadGroup.scheduler") == "true" {
+		if n, ok := c.number(e.Prop("ThreadGroup.duration")); ok {
+			f.duration = time.Duration(n) * time.Second
+		}
+	}
+	return f
+}
+
+// number returns the integer value of a property, which may be a property
+// function with a default such as ${__P(threads,10)}
+func (c *converter) number(value string) (int, bool) {
+	value = strings.TrimSpace(c.expand(value))
+	if name, ok := strings.CutPrefix(value, "${"); ok {
+		value = c.sc.Variables[strings.TrimSuffix(name, "}")]
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// rampStages splits a ramp-up to vus over ramp into stages
+func rampStages(vus uint64, ramp time.Duration) []scenario.Stage {
+	n := min(vus, maxRampStages)
+	stages := make([]scenario.Stage, n)
+	for k := range n {
+		stages[k] = scenario.Stage{
+			VUs:      (vus*(k+1) + n - 1) / n,
+			Duration: scenario.Duration{Duration: ramp / time.Duration(n)},
+		}
+	}
+	return stages
+}
+
+// steps converts the samplers among elements, and those of the
+// controllers among them, in order
+func (c *converter) steps(elements []*Element, parent scope) []scenario.Step {
+	s := c.scope(parent, elements, false)
+	var steps []scenario.Step
+	for _, e := range elements {
+		switch {
+		case !e.Enabled || slices.Contains(scoped, e.Type) || slices.Contains(ignored, e.Type):
+		case e.Type == "HTTPSamplerProxy" || e.Type == "HTTPSampler":
+			if step, ok := c.sampler(e, s); ok {
+				steps = append(steps, step)
+			}
+		case slices.Contains(controllers, e.Type):
+			steps = append(steps, c.steps(e.Children, s)...)
+		case strings.HasSuffix(e.Type, "Controller"):
+			c.warn("%s %q not converted, its samplers run once per iteration", e.Type, e.Name)
+			steps = append(steps, c.steps(e.Children, s)...)
+		default:
+			c.warn("%s %q not converted", e.Type, e.Name)
+		}
+	}
+	return steps
+}
+
+// scope returns parent with the scoped elements among elements applied.
+// Elements that are neither scoped nor samplers or controllers are
+// reported when sampler is set, elements being the children of a sampler.
+func (c *converter) scope(parent scope, elements []*Element, sampler bool) scope {
+	s := parent.clone()
+	for _, e := range elements {
+		if !e.Enabled {
+			continue
+		}
+		switch e.Type {
+		case "HeaderManager":
+			for _, h := range e.Arguments("Header.name", "Header.value", "HeaderManager.headers") {
+				if h[0] != "" {
+					s.headers = importutil.SetKey(s.headers, h[0], c.expand(h[1]))
+				}
+			}
+		case "ConfigTestElement":
+			s.protocol = cmp.Or(e.Prop("HTTPSampler.protocol"), s.protocol)
+			s.domain = cmp.Or(e.Prop("HTTPSampler.domain"), s.domain)
+			s.port = cmp.Or(e.Prop("HTTPSampler.port"), s.port)
+			s.path = cmp.Or(e.Prop("HTTPSampler.path"), s.path)
+		case "Arguments":
+			for _, arg := range e.Arguments("Argument.name", "Argument.value", "Arguments.arguments") {
+				if arg[0] != "" {
+					c.sc.Variables[arg[0]] = c.expand(arg[1])
+				}
+			}
+		case "CSVDataSet":
+			c.data(e)
+		case "ConstantTimer", "UniformRandomTimer", "GaussianRandomTimer":
+			if delay, ok := c.timer(e); ok {
+				s.delay = delay
+			}
+		case "ResponseAssertion":
+			s.checks = append(s.checks, c.responseAssertion(e)...)
+		case "DurationAssertion":
+			if ms, ok := c.number(e.Prop("DurationAssertion.duration")); ok {
+				s.checks = append(s.checks, scenario.Check{Name: cmp.Or(e.Name, "Duration Assertion"),
+					MaxLatency: scenario.Duration{Duration: time.Duration(ms) * time.Millisecond}})
+			}
+		case "JSONPathAssertion":
+			if check, ok := c.jsonPathAssertion(e); ok {
+				s.checks = append(s.checks, check)
+			}
+		case "JSONPostProcessor":
+			s.save = c.jsonExtractor(e, s.save)
+		case "CookieManager", "CacheManager", "DNSCacheManager":
+		default:
+			if sampler && !slices.Contains(ignored, e.Type) {
+				c.warn("%s %q not converted", e.Type, e.Name)
+			}
+		}
+	}
+	return s
+}
+
+// sampler converts the HTTP sampler e, reporting false if it is left out
+func (c *converter) sampler(e *Element, parent scope) (scenario.Step, bool) {
+	s := c.scope(parent, e.Children, true)
+	name := c.uniqueName(cmp.Or(e.Name, "HTTP Request"))
+	method := strings.ToUpper(cmp.Or(e.Prop("HTTPSampler.method"), http.MethodGet))
+	protocol := cmp.Or(e.Prop("HTTPSampler.protocol"), s.protocol, "http")
+	domain := cmp.Or(e.Prop("HTTPSampler.domain"), s.domain)
+	port := cmp.Or(e.Prop("HTTPSampler.port"), s.port)
+	path := cmp.Or(e.Prop("HTTPSampler.path"), s.path, "/")
+
+	// The path may be a full URL
+	if scheme, rest, ok := strings.Cut(path, "://"); ok {
+		host, p, _ := strings.Cut(rest, "/")
+		protocol, domain, port, path = scheme, host, "", "/"+p
+	}
+	if c.opts.BaseURL == "" {
+		if domain == "" {
+			c.warn("HTTP sampler %q left out, it has no server name", name)
+			return scenario.Step{}, false
+		}
+		origin := c.resolve(strings.ToLower(protocol) + "://" + domain)
+		if port != "" && !(port == "80" && protocol == "http" || port == "443" && protocol == "https") {
+			origin += ":" + c.resolve(port)
+		}
+		if c.sc.BaseURL == "" {
+			c.sc.BaseURL = origin
+		} else if origin != c.sc.BaseURL {
+			c.warn("HTTP sampler %q left out, it is sent to %s rather than the base URL %s", name, origin, c.sc.BaseURL)
+			return scenario.Step{}, false
+		}
+	}
+
+	step := scenario.Step{
+		Name:          name,
+		Headers:       s.headers,
+		Checks:        s.checks,
+		SaveToContext: s.save,
+		Delay:         s.delay,
+	}
+	path, rawQuery, _ := strings.Cut(path, "?")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	step.Request = method + " " + c.expand(path)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if key, value, _ := strings.Cut(pair, "="); key != "" {
+			step.Query = importutil.SetKey(step.Query, key, c.expand(value))
+		}
+	}
+
+	args := e.Arguments("Argument.name", "Argument.value", "HTTPsampler.Arguments", "Arguments.arguments")
+	hasBody := method != http.MethodGet && method != http.MethodHead && method != http.MethodTrace &&
+		method != http.MethodDelete && method != http.MethodOptions
+	switch {
+	case len(args) == 0:
+	case e.Prop("HTTPSampler.postBodyRaw") == "true":
+		body := c.expand(args[0][1])
+		var v any
+		if trimmed := strings.TrimSpace(body); (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) &&
+			json.Unmarshal([]byte(body), &v) == nil {
+			step.Body = v
+		} else {
+			step.Body = body
+		}
+	case hasBody:
+		var pairs []string
+		for _, arg := range args {
+			pairs = append(pairs, importutil.FormEscape(c.expand(arg[0]))+"="+importutil.FormEscape(c.expand(arg[1])))
+		}
+		step.Body = strings.Join(pairs, "&")
+		if !importutil.HasKey(step.Headers, "Content-Type") {
+			step.Headers = importutil.SetKey(step.Headers, "Content-Type", "application/x-www-form-urlencoded")
+		}
+	default:
+		for _, arg := range args {
+			if arg[0] != "" {
+				step.Query = importutil.SetKey(step.Query, c.expand(arg[0]), c.expand(arg[1]))
+			}
+		}
+	}
+	if step.Body != nil && !hasBody {
+		c.warn("HTTP sampler %q: body of a %s request left out", name, method)
+		step.Body = nil
+	}
+	if len(e.Arguments("File.path", "File.paramname", "HTTPsampler.Files", "HTTPFileArgs.files")) > 0 {
+		c.warn("HTTP sampler %q: file uploads not converted, set a payload", name)
+	}
+	return step, true
+}
+
+// uniqueName returns name, numbered if an earlier step of the thread group
+// has it
+func (c *converter) uniqueName(name string) string {
+	unique := name
+	for n := 2; c.names[unique]; n++ {
+		unique = name + " (" + strconv.Itoa(n) + ")"
+	}
+	c.names[unique] = true
+	return unique
+}
+
+// resolve replaces the variables in s with their values, for the parts of
+// a sampler that make the base URL
+func (c *converter) resolve(s string) string {
+	return placeholder.ReplaceAllStringFunc(c.expand(s), func(m string) string {
+		if value, ok := c.sc.Variables[m[2:len(m)-1]]; ok {
+			return value
+		}
+		return m
+	})
+}
+
+// expand converts the variables and functions of JMeter in s into those
+// of scenarios: ${name} is the same but for the columns of the CSV data
+// set, ${__UUID()} becomes ${uuid()}, ${__Random(1,10)}
+// ${random_int(1,10)} and properties such as ${__P(host,localhost)}
+// variables set to their default
+func (c *converter) expand(s string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		expr := m[2 : len(m)-1]
+		if !strings.HasPrefix(expr, "__") {
+			if slices.Contains(c.columns, expr) {
+				return "${" + scenario.DataPrefix + expr + "}"
+			}
+			return m
+		}
+
+		fn, args, _ := strings.Cut(strings.TrimSuffix(expr[2:], ")"), "(")
+		var argv []string
+		if args != "" {
+			argv = strings.Split(args, ",")
+		}
+		switch {
+		case fn == "UUID":
+			return "${uuid()}"
+		case fn == "Random" && len(argv) >= 2:
+			return "${random_int(" + argv[0] + "," + argv[1] + ")}"
+		case (fn == "P" || fn == "property") && len(argv) >= 1:
+			name, def := argv[0], ""
+			if fn == "P" && len(argv) >= 2 {
+				def = argv[1]
+			} else if fn == "property" && len(argv) >= 3 {
+				def = argv[2]
+			}
+			if _, ok := c.sc.Variables[name]; !ok {
+				c.sc.Variables[name] = def
+			}
+			return "${" + name + "}"
+		}
+		c.warn("function %s not converted", m)
+		return m
+	})
+}
+
+// data converts the CSV data set e into the scenario's data
+func (c *converter) data(e *Element) {
+	file := e.Prop("filename")
+	if c.sc.Data != nil {
+		c.warn("CSVDataSet %q not converted, a scenario reads a single data file", e.Name)
+		return
+	}
+	if strings.Contains(file, "${") {
+		c.warn("CSVDataSet %q: the file name %s has variables, set the data file", e.Name, file)
+	}
+	c.sc.Data = &scenario.Data{File: file}
+	if delimiter := e.Prop("delimiter"); delimiter != "" && delimiter != "," {
+		c.warn("CSVDataSet %q: convert %s to comma-separated values", e.Name, file)
+	}
+
+	names := e.Prop("variableNames")
+	if names == "" {
+		c.warn("CSVDataSet %q reads its columns from the first line of %s, reference them as ${%sname}", e.Name, file, scenario.DataPrefix)
+		return
+	}
+	for _, name := range strings.Split(names, ",") {
+		c.columns = append(c.columns, strings.TrimSpace(name))
+	}
+	if e.Prop("ignoreFirstLine") != "true" {
+		c.warn("CSVDataSet %q: add the column names %s as the first line of %s", e.Name, names, file)
+	}
+}
+
+// timer converts a timer into a delay
+func (c *converter) timer(e *Element) (scenario.Delay, bool) {
+	ms := func(prop string) (scenario.Duration, bool) {
+		n, ok := c.number(e.Prop(prop))
+		if !ok && e.Prop(prop) != "" {
+			c.warn("%s %q not converted, its delay is not a number", e.Type, e.Name)
+		}
+		return scenario.Duration{Duration: time.Duration(n) * time.Millisecond}, ok || e.Prop(prop) == ""
+	}
+	delay, ok := ms("ConstantTimer.delay")
+	if !ok {
+		return scenario.Delay{}, false
+	}
+	if e.Type == "ConstantTimer" {
+		return scenario.Delay{Duration: delay}, true
+	}
+	spread, ok := ms("RandomTimer.range")
+	if !ok {
+		return scenario.Delay{}, false
+	}
+	if e.Type == "GaussianRandomTimer" {
+		return scenario.Delay{Distribution: scenario.DistributionNormal, Mean: delay, StdDev: spread}, true
+	}
+	return scenario.Delay{
+		Distribution: scenario.DistributionUniform,
+		Min:          delay,
+		Max:          scenario.Duration{Duration: delay.Duration + spread.Duration},
+	}, true
+}
+
+// responseAssertion converts a ResponseAssertion into checks, one per test
+// string
+func (c *converter) responseAssertion(e *Element) []scenario.Check {
+	name := cmp.Or(e.Name, "Response Assertion")
+	kind, _ := strconv.Atoi(e.Prop("Assertion.test_type"))
+	patterns := e.Strings("Asserion.test_strings")
+	if patterns == nil {
+		patterns = e.Strings("Assertion.test_strings")
+	}
+	if kind&assertNot != 0 {
+		c.warn("ResponseAssertion %q not converted, checks cannot be negated", e.Name)
+		return nil
+	}
+
+	switch field := e.Prop("Assertion.test_field"); field {
+	case "Assertion.response_code":
+		check := scenario.Check{Name: name}
+		for _, p := range patterns {
+			if _, err := strconv.Atoi(p); err != nil || len(p) != 3 {
+				c.warn("ResponseAssertion %q not converted, %q is not a status code", e.Name, p)
+				return nil
+			}
+			check.Status = append(check.Status, p)
+		}
+		if len(check.Status) > 1 && kind&assertOr == 0 {
+			c.warn("ResponseAssertion %q: any of the status codes passes", e.Name)
+		}
+		return []scenario.Check{check}
+	case "Assertion.response_data", "Assertion.response_data_as_document", "":
+		if len(patterns) > 1 && kind&assertOr != 0 {
+			c.warn("ResponseAssertion %q: all of the patterns must match", e.Name)
+		}
+		var checks []scenario.Check
+		for i, p := range patterns {
+			check := scenario.Check{Name: name}
+			if i > 0 {
+				check.Name = fmt.Sprintf("%s (%d)", name, i+1)
+			}
+			switch {
+			case kind&assertSubstring != 0:
+				check.BodyContains = p
+			case kind&assertContains != 0:
+				check.BodyMatches = p
+			case kind&assertMatch != 0:
+				check.BodyMatches = "^(?:" + p + ")$"
+			case kind&assertEquals != 0:
+				check.BodyMatches = "^" + regexp.QuoteMeta(p) + "$"
+			default:
+				continue
+			}
+			checks = append(checks, check)
+		}
+		return checks
+	default:
+		c.warn("ResponseAssertion %q not converted, it tests %s", e.Name, strings.TrimPrefix(field, "Assertion."))
+		return nil
+	}
+}
+
+// jsonPathAssertion converts a JSONPathAssertion into a check on the
+// value, or presence, of a field
+func (c *converter) jsonPathAssertion(e *Element) (scenario.Check, bool) {
+	path, ok := jsonPath(e.Prop("JSON_PATH"))
+	if !ok || e.Prop("INVERT") == "true" || e.Prop("ISREGEX") == "true" && e.Prop("JSONVALIDATION") == "true" {
+		c.warn("JSONPathAssertion %q not converted", e.Name)
+		return scenario.Check{}, false
+	}
+	ref := "${response." + path + "}"
+	check := scenario.Check{Name: cmp.Or(e.Name, "JSON Assertion"), Condition: ref + ` != ""`}
+	if e.Prop("JSONVALIDATION") == "true" {
+		expected := e.Prop("EXPECTED_VALUE")
+		if _, err := strconv.ParseFloat(expected, 64); err != nil && expected != "true" && expected != "false" {
+			expected = strconv.Quote(expected)
+		}
+		check.Condition = ref + " == " + expected
+	}
+	return check, true
+}
+
+// jsonExtractor adds the variables the JSON extractor e saves to save,
+// which it returns
+func (c *converter) jsonExtractor(e *Element, save map[string]string) map[string]string {
+	names := strings.Split(e.Prop("JSONPostProcessor.referenceNames"), ";")
+	exprs := strings.Split(e.Prop("JSONPostProcessor.jsonPathExprs"), ";")
+	if match := e.Prop("JSONPostProcessor.match_numbers"); match != "" && match != "1" {
+		c.warn("JSONPostProcessor %q: the first match is saved rather than match %s", e.Name, match)
+	}
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || i >= len(exprs) {
+			continue
+		}
+		path, ok := jsonPath(exprs[i])
+		if !ok {
+			c.warn("JSONPostProcessor %q: %s not converted", e.Name, strings.TrimSpace(exprs[i]))
+			continue
+		}
+		save = importutil.SetKey(save, name, "response."+path)
+	}
+	return save
+}
+
+var jsonPathPart = regexp.MustCompile(`^(?:\.(\w+)|\[(\d+)\]|\['([^']+)'\]|\["([^"]+)"\])`)
+
+// jsonPath converts a simple JSONPath such as $.users[0].id into the path
+// of a response lookup, users.0.id. Filters, wildcards and deep scans are
+// not converted.
+func jsonPath(expr string) (string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(expr), "$")
+	if !ok {
+		return "", false
+	}
+	var parts []string
+	for rest != "" {
+		m := jsonPathPart.FindStringSubmatch(rest)
+		if m == nil {
+			return "", false
+		}
+		parts = append(parts, m[1]+m[2]+m[3]+m[4])
+		rest = rest[len(m[0]):]
+	}
+	return strings.Join(parts, "."), len(parts) > 0
+}
@@ -0,0 +1,343 @@
+package jmeter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/scenario"
+)
+
+const testPlan = `<?xml version="1.0" encoding="UTF-8"?>
+<jmeterTestPlan version="1.2" properties="5.0" jmeter="5.6.3">
+  <hashTree>
+    <TestPlan guiclass="TestPlanGui" testclass="TestPlan" testname="Shop" enabled="true">
+      <elementProp name="TestPlan.user_defined_variables" elementType="Arguments">
+        <collectionProp name="Arguments.arguments">
+          <elementProp name="host" elementType="Argument">
+            <stringProp name="Argument.name">host</stringProp>
+            <stringProp name="Argument.value">shop.example.com</stringProp>
+          </elementProp>
+        </collectionProp>
+      </elementProp>
+    </TestPlan>
+    <hashTree>
+      <ConfigTestElement guiclass="HttpDefaultsGui" testclass="ConfigTestElement" testname="HTTP Request Defaults">
+        <stringProp name="HTTPSampler.protocol">https</stringProp>
+        <stringProp name="HTTPSampler.domain">${host}</stringProp>
+      </ConfigTestElement>
+      <hashTree/>
+      <HeaderManager guiclass="HeaderPanel" testclass="HeaderManager" testname="Headers">
+        <collectionProp name="HeaderManager.headers">
+          <elementProp name="" elementType="Header">
+            <stringProp name="Header.name">Accept</stringProp>
+            <stringProp name="Header.value">application/json</stringProp>
+          </elementProp>
+        </collectionProp>
+      </HeaderManager>
+      <hashTree/>
+      <CookieManager guiclass="CookiePanel" testclass="CookieManager" testname="Cookies"/>
+      <hashTree/>
+      <ThreadGroup guiclass="ThreadGroupGui" testclass="ThreadGroup" testname="Shoppers">
+        <elementProp name="ThreadGroup.main_controller" elementType="LoopController">
+          <stringProp name="LoopController.loops">-1</stringProp>
+        </elementProp>
+        <stringProp name="ThreadGroup.num_threads">${__P(threads,20)}</stringProp>
+        <stringProp name="ThreadGroup.ramp_time">10</stringProp>
+        <boolProp name="ThreadGroup.scheduler">true</boolProp>
+        <stringProp name="ThreadGroup.duration">300</stringProp>
+      </ThreadGroup>
+      <hashTree>
+        <CSVDataSet guiclass="TestBeanGUI" testclass="CSVDataSet" testname="Users">
+          <stringProp name="filename">users.csv</stringProp>
+          <stringProp name="variableNames">username,password</stringProp>
+          <boolProp name="ignoreFirstLine">false</boolProp>
+          <stringProp name="delimiter">,</stringProp>
+        </CSVDataSet>
+        <hashTree/>
+        <ConstantTimer guiclass="ConstantTimerGui" testclass="ConstantTimer" testname="Think">
+          <stringProp name="ConstantTimer.delay">1000</stringProp>
+        </ConstantTimer>
+        <hashTree/>
+        <HTTPSamplerProxy guiclass="HttpTestSampleGui" testclass="HTTPSamplerProxy" testname="Login">
+          <boolProp name="HTTPSampler.postBodyRaw">true</boolProp>
+          <elementProp name="HTTPsampler.Arguments" elementType="Arguments">
+            <collectionProp name="Arguments.arguments">
+              <elementProp name="" elementType="HTTPArgument">
+                <stringProp name="Argument.value">{"user": "${username}", "password": "${password}"}</stringProp>
+              </elementProp>
+            </collectionProp>
+          </elementProp>
+          <stringProp name="HTTPSampler.path">/api/login</stringProp>
+          <stringProp name="HTTPSampler.method">POST</stringProp>
+        </HTTPSamplerProxy>
+        <hashTree>
+          <HeaderManager guiclass="HeaderPanel" testclass="HeaderManager" testname="JSON">
+            <collectionProp name="HeaderManager.headers">
+              <elementProp name="" elementType="Header">
+                <stringProp name="Header.name">Content-Type</stringProp>
+                <stringProp name="Header.value">application/json</stringProp>
+              </elementProp>
+            </collectionProp>
+          </HeaderManager>
+          <hashTree/>
+          <ResponseAssertion guiclass="AssertionGui" testclass="ResponseAssertion" testname="Logged in">
+            <collectionProp name="Asserion.test_strings">
+              <stringProp name="49586">200</stringProp>
+            </collectionProp>
+            <stringProp name="Assertion.test_field">Assertion.response_code</stringProp>
+            <intProp name="Assertion.test_type">8</intProp>
+          </ResponseAssertion>
+          <hashTree/>
+          <JSONPostProcessor guiclass="JSONPostProcessorGui" testclass="JSONPostProcessor" testname="Token">
+            <stringProp name="JSONPostProcessor.referenceNames">token;user_id</stringProp>
+            <stringProp name="JSONPostProcessor.jsonPathExprs">$.token;$.user.id</stringProp>
+          </JSONPostProcessor>
+          <hashTree/>
+          <JSR223PostProcessor guiclass="TestBeanGUI" testclass="JSR223PostProcessor" testname="Log"/>
+          <hashTree/>
+        </hashTree>
+        <IfController guiclass="IfControllerPanel" testclass="IfController" testname="Logged in?">
+          <stringProp name="IfController.condition">${token}</stringProp>
+        </IfController>
+        <hashTree>
+          <HTTPSamplerProxy guiclass="HttpTestSampleGui" testclass="HTTPSamplerProxy" testname="Orders">
+            <elementProp name="HTTPsampler.Arguments" elementType="Arguments">
+              <collectionProp name="Arguments.arguments">
+                <elementProp name="limit" elementType="HTTPArgument">
+                  <stringProp name="Argument.name">limit</stringProp>
+                  <stringProp name="Argument.value">${__Random(1,10)}</stringProp>
+                </elementProp>
+              </collectionProp>
+            </elementProp>
+            <stringProp name="HTTPSampler.path">/api/users/${user_id}/orders?page=1</stringProp>
+            <stringProp name="HTTPSampler.method">GET</stringProp>
+          </HTTPSamplerProxy>
+          <hashTree>
+            <HeaderManager guiclass="HeaderPanel" testclass="HeaderManager" testname="Auth">
+              <collectionProp name="HeaderManager.headers">
+                <elementProp name="" elementType="Header">
+                  <stringProp name="Header.name">Authorization</stringProp>
+                  <stringProp name="Header.value">Bearer ${token}</stringProp>
+                </elementProp>
+              </collectionProp>
+            </HeaderManager>
+            <hashTree/>
+            <ResponseAssertion guiclass="AssertionGui" testclass="ResponseAssertion" testname="Has orders">
+              <collectionProp name="Asserion.test_strings">
+                <stringProp name="1">"orders"</stringProp>
+              </collectionProp>
+              <stringProp name="Assertion.test_field">Assertion.response_data</stringProp>
+              <intProp name="Assertion.test_type">16</intProp>
+            </ResponseAssertion>
+            <hashTree/>
+            <DurationAssertion guiclass="DurationAssertionGui" testclass="DurationAssertion" testname="Fast">
+              <stringProp name="DurationAssertion.duration">500</stringProp>
+            </DurationAssertion>
+            <hashTree/>
+          </hashTree>
+          <HTTPSamplerProxy guiclass="HttpTestSampleGui" testclass="HTTPSamplerProxy" testname="Tracking">
+            <stringProp name="HTTPSampler.domain">analytics.example.com</stringProp>
+            <stringProp name="HTTPSampler.path">/collect</stringProp>
+          </HTTPSamplerProxy>
+          <hashTree/>
+        </hashTree>
+        <HTTPSamplerProxy guiclass="HttpTestSampleGui" testclass="HTTPSamplerProxy" testname="Old" enabled="false">
+          <stringProp name="HTTPSampler.path">/api/old</stringProp>
+        </HTTPSamplerProxy>
+        <hashTree/>
+      </hashTree>
+      <ResultCollector guiclass="ViewResultsFullVisualizer" testclass="ResultCollector" testname="View Results Tree"/>
+      <hashTree/>
+    </hashTree>
+  </hashTree>
+</jmeterTestPlan>
+`
+
+func TestConvert(t *testing.T) {
+	data := []byte(testPlan)
+	if !IsPlan(data) {
+		t.Fatal("IsPlan = false")
+	}
+	p, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, warnings, err := Convert(p, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sc.Name != "Shop" || sc.BaseURL != "https://shop.example.com" || sc.VirtualUsers != 20 || sc.Duration != 300 {
+		t.Errorf("scenario = %s %s %d VUs for %ds", sc.Name, sc.BaseURL, sc.VirtualUsers, sc.Duration)
+	}
+	if len(sc.Stages) != 10 || sc.Stages[0].VUs != 2 || sc.Stages[9].VUs != 20 || sc.Stages[0].Duration.Duration != time.Second {
+		t.Errorf("stages = %v, want a 10s ramp-up to 20 VUs", sc.Stages)
+	}
+	if sc.Data == nil || sc.Data.File != "users.csv" {
+		t.Errorf("data = %v", sc.Data)
+	}
+	var names []string
+	for i := range sc.Steps {
+		names = append(names, sc.Steps[i].Name)
+	}
+	if want := []string{"Login", "Orders"}; !slices.Equal(names, want) {
+		t.Fatalf("steps = %q, want %q", names, want)
+	}
+
+	login := sc.Steps[0]
+	if login.Request != "POST /api/login" {
+		t.Errorf("login request = %s", login.Request)
+	}
+	if want := map[string]string{"Accept": "application/json", "Content-Type": "application/json"}; !reflect.DeepEqual(login.Headers, want) {
+		t.Errorf("login headers = %v, want %v", login.Headers, want)
+	}
+	if want := map[string]any{"user": "${csv.username}", "password": "${csv.password}"}; !reflect.DeepEqual(login.Body, want) {
+		t.Errorf("login body = %v, want %v", login.Body, want)
+	}
+	if want := []scenario.Check{{Name: "Logged in", Status: []string{"200"}}}; !reflect.DeepEqual(login.Checks, want) {
+		t.Errorf("login checks = %+v", login.Checks)
+	}
+	if want := map[string]string{"token": "response.token", "user_id": "response.user.id"}; !reflect.DeepEqual(login.SaveToContext, want) {
+		t.Errorf("login save_to_context = %v, want %v", login.SaveToContext, want)
+	}
+	if login.Delay.Duration.Duration != time.Second {
+		t.Errorf("login delay = %v, want that of the timer", login.Delay.Duration)
+	}
+
+	orders := sc.Steps[1]
+	if orders.Request != "GET /api/users/${user_id}/orders" {
+		t.Errorf("orders request = %s", orders.Request)
+	}
+	if want := map[string]string{"page": "1", "limit": "${random_int(1,10)}"}; !reflect.DeepEqual(orders.Query, want) {
+		t.Errorf("orders query = %v, want %v", orders.Query, want)
+	}
+	if orders.Headers["Authorization"] != "Bearer ${token}" || orders.SaveToContext != nil {
+		t.Errorf("orders headers = %v, save_to_context = %v", orders.Headers, orders.SaveToContext)
+	}
+	wantChecks := []scenario.Check{
+		{Name: "Has orders", BodyContains: `"orders"`},
+		{Name: "Fast", MaxLatency: scenario.Duration{Duration: 500 * time.Millisecond}},
+	}
+	if !reflect.DeepEqual(orders.Checks, wantChecks) {
+		t.Errorf("orders checks = %+v", orders.Checks)
+	}
+
+	wantWarnings := []string{
+		"CSVDataSet \"Users\": add the column names username,password as the first line of users.csv",
+		"JSR223PostProcessor \"Log\" not converted",
+		"IfController \"Logged in?\" not converted, its samplers run once per iteration",
+		"HTTP sampler \"Tracking\" left out, it is sent to https://analytics.example.com rather than the base URL https://shop.example.com",
+	}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings =\n%s\nwant\n%s", strings.Join(warnings, "\n"), strings.Join(wantWarnings, "\n"))
+	}
+
+	// The data file is read when the scenario is validated
+	sc.Data.File = filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(sc.Data.File, []byte("username,password\nada,pw\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := scenario.NewParser()
+	if err := parser.ParseData(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := parser.Validate(); err != nil {
+		t.Errorf("converted scenario is invalid: %v\n%s", err, out)
+	}
+}
+
+func TestConvert_Options(t *testing.T) {
+	p, err := Parse([]byte(testPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, _, err := Convert(p, Options{BaseURL: "http://localhost:8080/", VirtualUsers: 5, Duration: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.BaseURL != "http://localhost:8080" || sc.VirtualUsers != 5 || sc.Duration != 30 {
+		t.Errorf("scenario = %s %d VUs for %ds", sc.BaseURL, sc.VirtualUsers, sc.Duration)
+	}
+	// With a base URL, requests to other hosts are sent to it too
+	if len(sc.Steps) != 3 || len(sc.Stages) != 5 {
+		t.Errorf("%d steps and %d stages, want 3 and 5", len(sc.Steps), len(sc.Stages))
+	}
+}
+
+func TestResponseAssertion(t *testing.T) {
+	tests := []struct {
+		kind int
+		want scenario.Check
+	}{
+		{assertSubstring, scenario.Check{Name: "a", BodyContains: "ok."}},
+		{assertContains, scenario.Check{Name: "a", BodyMatches: "ok."}},
+		{assertMatch, scenario.Check{Name: "a", BodyMatches: "^(?:ok.)$"}},
+		{assertEquals, scenario.Check{Name: "a", BodyMatches: `^ok\.$`}},
+	}
+	for _, tt := range tests {
+		e := element(t, `<ResponseAssertion testname="a">
+			<collectionProp name="Asserion.test_strings"><stringProp name="1">ok.</stringProp></collectionProp>
+			<stringProp name="Assertion.test_field">Assertion.response_data</stringProp>
+			<intProp name="Assertion.test_type">`+strconv.Itoa(tt.kind)+`</intProp>
+		</ResponseAssertion>`)
+		c := &converter{warned: make(map[string]bool)}
+		got := c.responseAssertion(e)
+		if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("type %d: checks = %+v, want %+v", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		expr, want string
+		ok         bool
+	}{
+		{"$.token", "token", true},
+		{"$.users[0].id", "users.0.id", true},
+		{"$['data']['id']", "data.id", true},
+		{"$..id", "", false},
+		{"$.items[*].id", "", false},
+		{"token", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := jsonPath(tt.expr)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("jsonPath(%q) = %q, %v, want %q, %v", tt.expr, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`{"log": {}}`,
+		`<testPlan/>`,
+		`<jmeterTestPlan><hashTree><ThreadGroup/></hashTree></jmeterTestPlan>`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%s) succeeded", data)
+		}
+	}
+}
+
+// element parses a test element written as in a test plan
+func element(t *testing.T, xml string) *Element {
+	t.Helper()
+	p, err := Parse([]byte(`<jmeterTestPlan><hashTree><TestPlan testname="t"/><hashTree>` + xml + `</hashTree></hashTree></jmeterTestPlan>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Root.Children[0]
+}
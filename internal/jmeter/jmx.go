@@ -0,0 +1,170 @@
+// Package jmeter converts JMeter test plans (.jmx) into scenarios on a
+// best-effort basis: HTTP samplers become steps, with the headers, CSV data
+// sets, extractors, simple assertions and timers around them. What has no
+// equivalent, such as logic controllers and scripts, is reported rather
+// than converted.
+package jmeter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// node is an XML element of a plan as written
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []node     `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+func (n *node) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child returns the child property of n named name, nil if it has none
+func (n *node) child(name string) *node {
+	for i := range n.Nodes {
+		if n.Nodes[i].attr("name") == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// Element is a test element of a plan, such as a thread group, an HTTP
+// sampler or a header manager, with the elements in its scope
+type Element struct {
+	// Type is the element's tag, e.g. HTTPSamplerProxy
+	Type string
+	// Name is the element's name in the plan
+	Name     string
+	Enabled  bool
+	Children []*Element
+
+	node *node
+}
+
+// Prop returns the value of the element's property name, e.g.
+// HTTPSampler.path, "" if it has none
+func (e *Element) Prop(name string) string {
+	return e.node.prop(name)
+}
+
+// ElementProp returns the value of the property prop of the element
+// property name, e.g. LoopController.loops of ThreadGroup.main_controller
+func (e *Element) ElementProp(name, prop string) string {
+	if n := e.node.child(name); n != nil {
+		return n.prop(prop)
+	}
+	return ""
+}
+
+func (n *node) prop(name string) string {
+	if p := n.child(name); p != nil {
+		return strings.TrimSpace(p.Text)
+	}
+	return ""
+}
+
+// Arguments returns the name and value pairs of the collection property
+// of the element at path, e.g. HTTPsampler.Arguments and
+// Arguments.arguments, whose entries hold them in the properties
+// nameProp and valueProp
+func (e *Element) Arguments(nameProp, valueProp string, path ...string) [][2]string {
+	n := e.node
+	for _, name := range path {
+		if n = n.child(name); n == nil {
+			return nil
+		}
+	}
+	var args [][2]string
+	for i := range n.Nodes {
+		arg := &n.Nodes[i]
+		args = append(args, [2]string{arg.prop(nameProp), arg.prop(valueProp)})
+	}
+	return args
+}
+
+// Strings returns the values of the collection property name, such as the
+// test strings of an assertion
+func (e *Element) Strings(name string) []string {
+	n := e.node.child(name)
+	if n == nil {
+		return nil
+	}
+	var values []string
+	for _, v := range n.Nodes {
+		values = append(values, v.Text)
+	}
+	return values
+}
+
+// Plan is a JMeter test plan
+type Plan struct {
+	// Root is the TestPlan element
+	Root *Element
+}
+
+// IsPlan reports whether data looks like a JMeter test plan
+func IsPlan(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		if end := bytes.Index(data, []byte("?>")); end >= 0 {
+			data = bytes.TrimSpace(data[end+2:])
+		}
+	}
+	return bytes.HasPrefix(data, []byte("<jmeterTestPlan"))
+}
+
+// Parse reads a test plan
+func Parse(data []byte) (*Plan, error) {
+	var root node
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse test plan: %w", err)
+	}
+	if root.XMLName.Local != "jmeterTestPlan" {
+		return nil, fmt.Errorf("not a JMeter test plan: the root element is %s", root.XMLName.Local)
+	}
+	for i := range root.Nodes {
+		if root.Nodes[i].XMLName.Local != "hashTree" {
+			continue
+		}
+		elements := elements(&root.Nodes[i])
+		if len(elements) == 0 || elements[0].Type != "TestPlan" {
+			break
+		}
+		return &Plan{Root: elements[0]}, nil
+	}
+	return nil, fmt.Errorf("the test plan has no TestPlan element")
+}
+
+// elements returns the elements of a hash tree, which lists each element
+// followed by the hash tree of its scope
+func elements(tree *node) []*Element {
+	var list []*Element
+	for i := range tree.Nodes {
+		n := &tree.Nodes[i]
+		if n.XMLName.Local == "hashTree" {
+			if len(list) > 0 {
+				last := list[len(list)-1]
+				last.Children = append(last.Children, elements(n)...)
+			}
+			continue
+		}
+		list = append(list, &Element{
+			Type:    n.XMLName.Local,
+			Name:    n.attr("testname"),
+			Enabled: n.attr("enabled") != "false",
+			node:    n,
+		})
+	}
+	return list
+}
@@ -0,0 +1,156 @@
+// Package live streams metrics snapshots to WebSocket clients while a run
+// is in progress.
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/report"
+)
+
+const (
+	// clientBuffer is the number of messages queued per client. A client
+	// that falls further behind misses snapshots rather than slowing others.
+	clientBuffer = 16
+	writeTimeout = 5 * time.Second
+)
+
+// Hub fans out messages to every connected WebSocket client
+type Hub struct {
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	closed  bool
+}
+
+type client struct {
+	send chan []byte
+}
+
+// NewHub creates a Hub without clients
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*client]struct{})}
+}
+
+// ServeHTTP upgrades the request to a WebSocket and streams broadcast
+// messages to it until the client disconnects or the hub is closed.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		// Snapshots are read-only aggregates, so dashboards served from
+		// any origin may subscribe
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		// Accept has already written the HTTP error response
+		return
+	}
+
+	c := &client{send: make(chan []byte, clientBuffer)}
+	if !h.add(c) {
+		conn.Close(websocket.StatusGoingAway, "run finished")
+		return
+	}
+	defer h.remove(c)
+
+	// Clients only listen; CloseRead handles control frames and ends ctx
+	// when the client goes away
+	ctx := conn.CloseRead(r.Context())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-c.send:
+			if !ok {
+				conn.Close(websocket.StatusNormalClosure, "run finished")
+				return
+			}
+			wctx, cancel := context.WithTimeout(ctx, writeTimeout)
+			err := conn.Write(wctx, websocket.MessageText, msg)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (h *Hub) add(c *client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.clients[c] = struct{}{}
+	return true
+}
+
+func (h *Hub) remove(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+}
+
+// Broadcast sends v as JSON to every connected client
+func (h *Hub) Broadcast(v any) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode live message: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		select {
+		case c.send <- msg:
+		default:
+		}
+	}
+	return nil
+}
+
+// Clients returns the number of connected clients
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Close disconnects all clients once their queued messages are sent and
+// rejects new ones
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for c := range h.clients {
+		close(c.send)
+		delete(h.clients, c)
+	}
+}
+
+// Publish broadcasts a report.Snapshot of the last interval of window to
+// hub every interval until ctx ends
+func Publish(ctx context.Context, hub *Hub, window *metrics.Window, activeVUs func() int64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := start
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			stats := window.Flush(now.Sub(last))
+			last = now
+			// Snapshots only hold plain values, encoding cannot fail
+			_ = hub.Broadcast(report.NewSnapshot(now, now.Sub(start), activeVUs(), stats))
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/report"
+)
+
+func dial(t *testing.T, ctx context.Context, hub *Hub) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() failed: %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+
+	// Wait until the hub has registered the client
+	for hub.Clients() == 0 {
+		if ctx.Err() != nil {
+			t.Fatal("client was never registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return conn
+}
+
+func TestPublish(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hub := NewHub()
+	conn := dial(t, ctx, hub)
+
+	window, err := metrics.NewWindow(metrics.EstimatorHDR, 0)
+	if err != nil {
+		t.Fatalf("NewWindow() failed: %v", err)
+	}
+	window.Register("GET /a")
+	window.Add(metrics.Sample{Step: "GET /a", Status: 200, Duration: 20 * time.Millisecond})
+
+	go Publish(ctx, hub, window, func() int64 { return 3 }, 10*time.Millisecond)
+
+	_, msg, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	var snap report.Snapshot
+	if err := json.Unmarshal(msg, &snap); err != nil {
+		t.Fatalf("invalid snapshot: %v", err)
+	}
+	if snap.ActiveVUs != 3 || snap.Total.Requests != 1 || snap.ElapsedSeconds <= 0 {
+		t.Errorf("unexpected snapshot: %+v", snap)
+	}
+	if len(snap.Steps) != 1 || snap.Steps[0].LatencyMS.P95 < 19 {
+		t.Errorf("unexpected steps: %+v", snap.Steps)
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hub := NewHub()
+	conn := dial(t, ctx, hub)
+
+	if err := hub.Broadcast(map[string]int{"n": 1}); err != nil {
+		t.Fatalf("Broadcast() failed: %v", err)
+	}
+	hub.Close()
+
+	// Queued messages are delivered before the connection closes
+	if _, msg, err := conn.Read(ctx); err != nil || string(msg) != `{"n":1}` {
+		t.Fatalf("expected queued message, got %q (%v)", msg, err)
+	}
+	_, _, err := conn.Read(ctx)
+	if websocket.CloseStatus(err) != websocket.StatusNormalClosure {
+		t.Errorf("expected normal closure, got %v", err)
+	}
+}
@@ -0,0 +1,414 @@
+package metrics
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sample is the outcome of a single request
+type Sample struct {
+	Time     time.Time
+	Step     string
+	Status   int
+	Duration time.Duration
+	Failed   bool
+	// BytesSent and BytesReceived are request and response body sizes
+	BytesSent     int64
+	BytesReceived int64
+	// Err is set when the request produced no response at all, in which
+	// case Status and Duration are zero and no latency is recorded.
+	Err error
+	// Scenario is the weighted scenario the sample belongs to, empty when
+	// the config has a single scenario
+	Scenario string
+	// Protocol is the protocol of the step, such as ProtocolHTTP
+	Protocol string
+	// Labels maps the scenario's label headers to their response values
+	Labels map[string]string
+}
+
+// ProtocolHTTP is the protocol of HTTP request steps whose HTTP version is
+// not known, such as requests failing before the version was negotiated
+const ProtocolHTTP = "http"
+
+// Protocols of HTTP request steps by the version their responses were
+// received over, so HTTP/1.1 and HTTP/2 latencies are not blended
+const (
+	ProtocolHTTP1 = "http/1.1"
+	ProtocolHTTP2 = "h2"
+)
+
+// HTTPProtocol returns the protocol of an HTTP response received over
+// proto, such as HTTP/2.0, or ProtocolHTTP if proto is not known
+func HTTPProtocol(proto string) string {
+	switch proto {
+	case "HTTP/1.0", "HTTP/1.1":
+		return ProtocolHTTP1
+	case "HTTP/2.0":
+		return ProtocolHTTP2
+	}
+	return ProtocolHTTP
+}
+
+// maxLabelSeries bounds the label values a Collector breaks metrics down
+// by. Once reached, samples with new values are counted under
+// "<header>=(other)", so a header carrying e.g. request IDs cannot exhaust
+// memory.
+const maxLabelSeries = 1000
+
+// OtherLabel replaces label values beyond maxLabelSeries
+const OtherLabel = "(other)"
+
+// Collector aggregates samples globally, per scenario step and per
+// protocol, so protocols with different latency characteristics are not
+// blended into one distribution
+type Collector struct {
+	estimator   string
+	compression float64
+
+	total *stepCounters
+
+	mu    sync.RWMutex
+	steps map[string]*stepCounters
+	order []string
+
+	protocols     map[string]*stepCounters
+	protocolOrder []string
+
+	labels     map[string]*stepCounters
+	labelOrder []string
+}
+
+type stepCounters struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	latency  LatencyRecorder
+
+	mu         sync.Mutex
+	errorKinds map[string]int64
+}
+
+// NewCollector creates a Collector whose latency recorders use the given
+// estimator (see NewLatencyRecorder).
+func NewCollector(estimator string, compression float64) (*Collector, error) {
+	total, err := NewLatencyRecorder(estimator, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Collector{
+		estimator:   estimator,
+		compression: compression,
+		total:       &stepCounters{latency: total},
+		steps:       make(map[string]*stepCounters),
+		protocols:   make(map[string]*stepCounters),
+		labels:      make(map[string]*stepCounters),
+	}, nil
+}
+
+// Register pre-creates the given steps so they appear in summaries in
+// this order, even if they never receive a sample.
+func (c *Collector) Register(steps ...string) {
+	for _, step := range steps {
+		c.step(step)
+	}
+}
+
+// Add records s
+func (c *Collector) Add(s Sample) {
+	all := []*stepCounters{c.total, c.step(s.Step)}
+	if s.Protocol != "" {
+		all = append(all, c.protocol(s.Protocol))
+	}
+	for header, value := range s.Labels {
+		all = append(all, c.label(header, value))
+	}
+
+	for _, counters := range all {
+		counters.requests.Add(1)
+		if s.Failed || s.Err != nil {
+			counters.errors.Add(1)
+			counters.countError(ErrorKind(s))
+		}
+		if s.Err == nil {
+			counters.latency.Record(s.Duration)
+		}
+	}
+}
+
+func (s *stepCounters) countError(kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errorKinds == nil {
+		s.errorKinds = make(map[string]int64)
+	}
+	s.errorKinds[kind]++
+}
+
+func (c *Collector) step(name string) *stepCounters {
+	return c.counters(c.steps, &c.order, name)
+}
+
+func (c *Collector) protocol(name string) *stepCounters {
+	return c.counters(c.protocols, &c.protocolOrder, name)
+}
+
+// label returns the counters of header=value, or of header=(other) once
+// maxLabelSeries is reached
+func (c *Collector) label(header, value string) *stepCounters {
+	name := header + "=" + value
+	c.mu.RLock()
+	_, ok := c.labels[name]
+	full := len(c.labels) >= maxLabelSeries
+	c.mu.RUnlock()
+	if !ok && full {
+		name = header + "=" + OtherLabel
+	}
+	return c.counters(c.labels, &c.labelOrder, name)
+}
+
+// counters returns the counters of name in m, creating them and appending
+// name to order on first use
+func (c *Collector) counters(m map[string]*stepCounters, order *[]string, name string) *stepCounters {
+	c.mu.RLock()
+	counters, ok := m[name]
+	c.mu.RUnlock()
+	if ok {
+		return counters
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if counters, ok := m[name]; ok {
+		return counters
+	}
+
+	// The estimator was validated in NewCollector, so this cannot fail
+	latency, _ := NewLatencyRecorder(c.estimator, c.compression)
+	counters = &stepCounters{latency: latency}
+	m[name] = counters
+	*order = append(*order, name)
+	return counters
+}
+
+// Summary is a point-in-time view of collected metrics
+type Summary struct {
+	Total StepStats
+	Steps []StepStats
+	// Protocols holds one entry per protocol, named after it
+	Protocols []StepStats
+	// Labels holds one entry per label header value, named header=value
+	Labels []StepStats
+}
+
+// StepStats are the aggregated metrics of one step, or of all steps
+type StepStats struct {
+	Name      string
+	Requests  int64
+	Errors    int64
+	ErrorRate float64
+	// RPS is the request throughput over the elapsed time of the run
+	RPS     float64
+	Latency LatencyStats
+	// ErrorKinds counts errors by kind, see ErrorKind
+	ErrorKinds map[string]int64
+	// ChecksPassed and ChecksFailed count check evaluations. The collector
+	// never sees checks; the agent fills them in from its check results.
+	ChecksPassed int64
+	ChecksFailed int64
+}
+
+// LatencyStats summarises a latency distribution
+type LatencyStats struct {
+	Count int64
+	Min   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	P999  time.Duration
+	Max   time.Duration
+}
+
+// Summary computes statistics for everything recorded so far. elapsed is
+// the run time used to compute throughput.
+func (c *Collector) Summary(elapsed time.Duration) Summary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	summary := Summary{
+		Total: c.total.stats("total", elapsed),
+		Steps: make([]StepStats, 0, len(c.order)),
+	}
+	for _, name := range c.order {
+		summary.Steps = append(summary.Steps, c.steps[name].stats(name, elapsed))
+	}
+	for _, name := range c.protocolOrder {
+		summary.Protocols = append(summary.Protocols, c.protocols[name].stats(name, elapsed))
+	}
+	for _, name := range c.labelOrder {
+		summary.Labels = append(summary.Labels, c.labels[name].stats(name, elapsed))
+	}
+	return summary
+}
+
+func (s *stepCounters) stats(name string, elapsed time.Duration) StepStats {
+	stats := StepStats{
+		Name:     name,
+		Requests: s.requests.Load(),
+		Errors:   s.errors.Load(),
+		Latency:  NewLatencyStats(s.latency),
+	}
+	s.mu.Lock()
+	if len(s.errorKinds) > 0 {
+		stats.ErrorKinds = maps.Clone(s.errorKinds)
+	}
+	s.mu.Unlock()
+
+	if stats.Requests > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Requests)
+	}
+	if elapsed > 0 {
+		stats.RPS = float64(stats.Requests) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// NewLatencyStats computes LatencyStats from r
+func NewLatencyStats(r LatencyRecorder) LatencyStats {
+	if r.Count() == 0 {
+		return LatencyStats{}
+	}
+	return LatencyStats{
+		Count: r.Count(),
+		Min:   r.Min(),
+		Mean:  r.Mean(),
+		P50:   r.Percentile(50),
+		P90:   r.Percentile(90),
+		P95:   r.Percentile(95),
+		P99:   r.Percentile(99),
+		P999:  r.Percentile(99.9),
+		Max:   r.Max(),
+	}
+}
+
+// CollectorState is the serializable content of a Collector, used to
+// combine the metrics of distributed agents
+type CollectorState struct {
+	Total     StepState   `json:"total"`
+	Steps     []StepState `json:"steps"`
+	Protocols []StepState `json:"protocols,omitempty"`
+	Labels    []StepState `json:"labels,omitempty"`
+}
+
+// StepState is the serializable content of the counters of one step
+type StepState struct {
+	Name       string           `json:"name"`
+	Requests   int64            `json:"requests"`
+	Errors     int64            `json:"errors"`
+	ErrorKinds map[string]int64 `json:"error_kinds,omitempty"`
+	// Latency is the recorder encoded with EncodeRecorder
+	Latency []byte `json:"latency"`
+}
+
+// State captures everything recorded so far
+func (c *Collector) State() (*CollectorState, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	total, err := c.total.state("total")
+	if err != nil {
+		return nil, err
+	}
+
+	state := &CollectorState{Total: total, Steps: make([]StepState, 0, len(c.order))}
+	for _, name := range c.order {
+		step, err := c.steps[name].state(name)
+		if err != nil {
+			return nil, err
+		}
+		state.Steps = append(state.Steps, step)
+	}
+	for _, name := range c.protocolOrder {
+		protocol, err := c.protocols[name].state(name)
+		if err != nil {
+			return nil, err
+		}
+		state.Protocols = append(state.Protocols, protocol)
+	}
+	for _, name := range c.labelOrder {
+		label, err := c.labels[name].state(name)
+		if err != nil {
+			return nil, err
+		}
+		state.Labels = append(state.Labels, label)
+	}
+	return state, nil
+}
+
+func (s *stepCounters) state(name string) (StepState, error) {
+	latency, err := EncodeRecorder(s.latency)
+	if err != nil {
+		return StepState{}, err
+	}
+
+	state := StepState{
+		Name:     name,
+		Requests: s.requests.Load(),
+		Errors:   s.errors.Load(),
+		Latency:  latency,
+	}
+	s.mu.Lock()
+	if len(s.errorKinds) > 0 {
+		state.ErrorKinds = maps.Clone(s.errorKinds)
+	}
+	s.mu.Unlock()
+	return state, nil
+}
+
+// Merge adds a state captured from a collector with the same estimator
+func (c *Collector) Merge(state *CollectorState) error {
+	if err := c.total.merge(state.Total); err != nil {
+		return fmt.Errorf("failed to merge totals: %w", err)
+	}
+	for _, step := range state.Steps {
+		if err := c.step(step.Name).merge(step); err != nil {
+			return fmt.Errorf("failed to merge step '%s': %w", step.Name, err)
+		}
+	}
+	for _, protocol := range state.Protocols {
+		if err := c.protocol(protocol.Name).merge(protocol); err != nil {
+			return fmt.Errorf("failed to merge protocol '%s': %w", protocol.Name, err)
+		}
+	}
+	for _, label := range state.Labels {
+		if err := c.counters(c.labels, &c.labelOrder, label.Name).merge(label); err != nil {
+			return fmt.Errorf("failed to merge label '%s': %w", label.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *stepCounters) merge(state StepState) error {
+	if err := MergeEncoded(s.latency, state.Latency); err != nil {
+		return err
+	}
+
+	s.requests.Add(state.Requests)
+	s.errors.Add(state.Errors)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for kind, n := range state.ErrorKinds {
+		if s.errorKinds == nil {
+			s.errorKinds = make(map[string]int64)
+		}
+		s.errorKinds[kind] += n
+	}
+	return nil
+}
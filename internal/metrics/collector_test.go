@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewCollector_InvalidEstimator(t *testing.T) {
+	if _, err := NewCollector("raw", 0); err == nil {
+		t.Error("expected error for unknown estimator, got nil")
+	}
+}
+
+func TestCollector_PerStepBreakdown(t *testing.T) {
+	c, err := NewCollector(EstimatorHDR, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Register("POST /auth/login", "GET /profile", "GET /never")
+
+	for i := 0; i < 10; i++ {
+		c.Add(Sample{Step: "GET /profile", Status: 200, Duration: 10 * time.Millisecond})
+	}
+	c.Add(Sample{Step: "POST /auth/login", Status: 200, Duration: 100 * time.Millisecond})
+	c.Add(Sample{Step: "POST /auth/login", Status: 500, Duration: 300 * time.Millisecond, Failed: true})
+	c.Add(Sample{Step: "POST /auth/login", Err: errors.New("connection refused")})
+
+	summary := c.Summary(2 * time.Second)
+
+	if summary.Total.Requests != 13 || summary.Total.Errors != 2 {
+		t.Errorf("unexpected totals: %d requests, %d errors",
+			summary.Total.Requests, summary.Total.Errors)
+	}
+	if summary.Total.Latency.Count != 12 {
+		t.Errorf("expected 12 latency samples, got %d", summary.Total.Latency.Count)
+	}
+
+	if len(summary.Steps) != 3 {
+		t.Fatalf("expected 3 steps, got %d", len(summary.Steps))
+	}
+
+	login := summary.Steps[0]
+	if login.Name != "POST /auth/login" {
+		t.Fatalf("expected registration order, got '%s' first", login.Name)
+	}
+	if login.Requests != 3 || login.Errors != 2 {
+		t.Errorf("unexpected login counts: %d requests, %d errors", login.Requests, login.Errors)
+	}
+	if login.ErrorRate < 0.66 || login.ErrorRate > 0.67 {
+		t.Errorf("expected error rate ~0.667, got %f", login.ErrorRate)
+	}
+	if login.RPS != 1.5 {
+		t.Errorf("expected 1.5 RPS, got %f", login.RPS)
+	}
+
+	profile := summary.Steps[1]
+	if profile.ErrorRate != 0 || profile.RPS != 5 {
+		t.Errorf("unexpected profile stats: %+v", profile)
+	}
+	if (profile.Latency.P95 - 10*time.Millisecond).Abs() > 10*time.Microsecond {
+		t.Errorf("expected profile p95 ~10ms, got %s", profile.Latency.P95)
+	}
+
+	if never := summary.Steps[2]; never.Requests != 0 || never.Latency.Count != 0 {
+		t.Errorf("expected empty stats for unused step, got %+v", never)
+	}
+}
+
+func TestCollector_UnregisteredStepsAppended(t *testing.T) {
+	c, err := NewCollector(EstimatorTDigest, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Add(Sample{Step: "b", Duration: time.Millisecond})
+	c.Add(Sample{Step: "a", Duration: time.Millisecond})
+
+	summary := c.Summary(time.Second)
+	if len(summary.Steps) != 2 || summary.Steps[0].Name != "b" || summary.Steps[1].Name != "a" {
+		t.Errorf("expected first-seen order [b a], got %+v", summary.Steps)
+	}
+}
+
+func TestCollector_ErrorBreakdown(t *testing.T) {
+	c, err := NewCollector(EstimatorHDR, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Add(Sample{Step: "a", Status: 503, Failed: true})
+	c.Add(Sample{Step: "a", Status: 503, Failed: true})
+	c.Add(Sample{Step: "a", Err: fmt.Errorf("request failed: %w", context.DeadlineExceeded)})
+	c.Add(Sample{Step: "b", Err: fmt.Errorf("request failed: %w", syscall.ECONNREFUSED)})
+	c.Add(Sample{Step: "b", Status: 200})
+
+	summary := c.Summary(time.Second)
+
+	want := map[string]int64{"HTTP 503": 2, "timeout": 1, "connection refused": 1}
+	if !maps.Equal(summary.Total.ErrorKinds, want) {
+		t.Errorf("expected total breakdown %v, got %v", want, summary.Total.ErrorKinds)
+	}
+	if got := summary.Steps[1].ErrorKinds; len(got) != 1 || got["connection refused"] != 1 {
+		t.Errorf("unexpected breakdown for step b: %v", got)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		sample Sample
+		want   string
+	}{
+		{Sample{Status: 404, Failed: true}, "HTTP 404"},
+		{Sample{Err: &net.DNSError{Err: "no such host", Name: "x"}}, "dns error"},
+		{Sample{Err: fmt.Errorf("wrapped: %w", syscall.ECONNRESET)}, "connection reset"},
+		{Sample{Err: errors.New("boom")}, "request error"},
+		{Sample{Err: fmt.Errorf("%w: budget 'login' of 1s spent", ErrOverBudget)}, "over budget"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorKind(tt.sample); got != tt.want {
+			t.Errorf("ErrorKind(%+v) = %s, want %s", tt.sample, got, tt.want)
+		}
+	}
+}
+
+func TestCollector_ProtocolBreakdown(t *testing.T) {
+	c, err := NewCollector(EstimatorHDR, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		c.Add(Sample{Step: "GET /feed", Status: 200, Duration: 10 * time.Millisecond, Protocol: ProtocolHTTP})
+	}
+	c.Add(Sample{Step: "subscribe", Duration: 2 * time.Second, Protocol: "ws"})
+	c.Add(Sample{Step: "subscribe", Err: errors.New("connection refused"), Protocol: "ws"})
+	c.Add(Sample{Step: "untagged", Duration: time.Millisecond})
+
+	summary := c.Summary(time.Second)
+	if len(summary.Protocols) != 2 {
+		t.Fatalf("expected 2 protocols, got %+v", summary.Protocols)
+	}
+	http, ws := summary.Protocols[0], summary.Protocols[1]
+	if http.Name != ProtocolHTTP || http.Requests != 4 || http.Latency.Max > 11*time.Millisecond {
+		t.Errorf("unexpected http stats: %+v", http)
+	}
+	if ws.Name != "ws" || ws.Requests != 2 || ws.Errors != 1 || ws.Latency.Min < time.Second {
+		t.Errorf("unexpected ws stats: %+v", ws)
+	}
+
+	merged, _ := NewCollector(EstimatorHDR, 0)
+	state, err := c.State()
+	if err != nil {
+		t.Fatalf("State() failed: %v", err)
+	}
+	if err := merged.Merge(state); err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+	if got := merged.Summary(time.Second).Protocols; len(got) != 2 || got[1].Requests != 2 {
+		t.Errorf("expected protocols to survive a merge, got %+v", got)
+	}
+}
+
+func TestHTTPProtocol(t *testing.T) {
+	for proto, want := range map[string]string{
+		"HTTP/1.0": ProtocolHTTP1,
+		"HTTP/1.1": ProtocolHTTP1,
+		"HTTP/2.0": ProtocolHTTP2,
+		"":         ProtocolHTTP,
+	} {
+		if got := HTTPProtocol(proto); got != want {
+			t.Errorf("HTTPProtocol(%q) = %q, want %q", proto, got, want)
+		}
+	}
+}
+
+func TestCollector_LabelBreakdown(t *testing.T) {
+	c, err := NewCollector(EstimatorHDR, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Add(Sample{Step: "a", Duration: 10 * time.Millisecond, Labels: map[string]string{"X-Served-By": "web-1"}})
+	c.Add(Sample{Step: "a", Duration: 900 * time.Millisecond, Labels: map[string]string{"X-Served-By": "web-2"}})
+	c.Add(Sample{Step: "a", Duration: 800 * time.Millisecond, Labels: map[string]string{"X-Served-By": "web-2"}})
+
+	labels := c.Summary(time.Second).Labels
+	if len(labels) != 2 || labels[0].Name != "X-Served-By=web-1" || labels[1].Requests != 2 {
+		t.Fatalf("unexpected label breakdown: %+v", labels)
+	}
+	if labels[1].Latency.Min < 700*time.Millisecond {
+		t.Errorf("expected web-2 latencies only, got min %s", labels[1].Latency.Min)
+	}
+
+	for i := range maxLabelSeries + 10 {
+		c.Add(Sample{Step: "a", Labels: map[string]string{"X-Request-Id": strconv.Itoa(i)}})
+	}
+	labels = c.Summary(time.Second).Labels
+	if len(labels) != maxLabelSeries+1 {
+		t.Fatalf("expected label series to be capped at %d, got %d", maxLabelSeries+1, len(labels))
+	}
+	if other := labels[len(labels)-1]; other.Name != "X-Request-Id="+OtherLabel || other.Requests != 12 {
+		t.Errorf("unexpected overflow series: %+v", other)
+	}
+}
+
+func TestCollector_MergeState(t *testing.T) {
+	for _, estimator := range []string{EstimatorHDR, EstimatorTDigest} {
+		t.Run(estimator, func(t *testing.T) {
+			a, _ := NewCollector(estimator, 0)
+			b, _ := NewCollector(estimator, 0)
+			a.Register("GET /a", "GET /b")
+			b.Register("GET /a", "GET /b")
+
+			for i := 1; i <= 100; i++ {
+				a.Add(Sample{Step: "GET /a", Status: 200, Duration: time.Duration(i) * time.Millisecond})
+				b.Add(Sample{Step: "GET /a", Status: 200, Duration: time.Duration(100+i) * time.Millisecond})
+			}
+			b.Add(Sample{Step: "GET /b", Status: 503, Duration: time.Second, Failed: true})
+
+			merged, _ := NewCollector(estimator, 0)
+			merged.Register("GET /a", "GET /b")
+			for _, c := range []*Collector{a, b} {
+				state, err := c.State()
+				if err != nil {
+					t.Fatalf("State() failed: %v", err)
+				}
+				if err := merged.Merge(state); err != nil {
+					t.Fatalf("Merge() failed: %v", err)
+				}
+			}
+
+			summary := merged.Summary(time.Second)
+			if summary.Total.Requests != 201 || summary.Total.Errors != 1 {
+				t.Errorf("unexpected totals: %d requests, %d errors",
+					summary.Total.Requests, summary.Total.Errors)
+			}
+			if summary.Steps[1].ErrorKinds["HTTP 503"] != 1 {
+				t.Errorf("expected merged error kinds, got %v", summary.Steps[1].ErrorKinds)
+			}
+
+			p50 := summary.Steps[0].Latency.P50
+			if p50 < 95*time.Millisecond || p50 > 106*time.Millisecond {
+				t.Errorf("expected merged p50 ~100ms, got %s", p50)
+			}
+			if max := summary.Steps[0].Latency.Max; max < 199*time.Millisecond {
+				t.Errorf("expected merged max ~200ms, got %s", max)
+			}
+		})
+	}
+}
+
+func TestMergeEncoded_Invalid(t *testing.T) {
+	if err := MergeEncoded(NewHistogram(), []byte("garbage")); err == nil {
+		t.Error("expected error for invalid histogram, got nil")
+	}
+	td, _ := NewTDigest(DefaultCompression)
+	if err := MergeEncoded(td, []byte{1, 2, 3}); err == nil {
+		t.Error("expected error for invalid t-digest, got nil")
+	}
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"loadforge-agent/internal/version"
+)
+
+// csvBufferSize is the number of samples that can be queued before the
+// CSV exporter starts dropping them to protect load generation.
+const csvBufferSize = 16384
+
+var csvHeader = []string{
+	"timestamp", "step", "status", "latency_ms", "bytes_sent", "bytes_received", "error", "scenario", "agent_version", "protocol", "labels",
+}
+
+// CSVExporter streams every sample to CSV for offline analysis. Samples are
+// queued and written by a background goroutine, so a slow disk never
+// blocks VUs; if the queue fills up, samples are dropped and counted.
+type CSVExporter struct {
+	samples chan Sample
+	dropped atomic.Int64
+
+	done chan struct{}
+	err  error
+
+	closeOnce sync.Once
+}
+
+// NewCSVExporter starts an exporter writing to w. w is not closed.
+func NewCSVExporter(w io.Writer) *CSVExporter {
+	e := &CSVExporter{
+		samples: make(chan Sample, csvBufferSize),
+		done:    make(chan struct{}),
+	}
+	go e.run(w)
+	return e
+}
+
+// Add queues s for writing without blocking
+func (e *CSVExporter) Add(s Sample) {
+	select {
+	case e.samples <- s:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of samples discarded because the queue was full
+func (e *CSVExporter) Dropped() int64 {
+	return e.dropped.Load()
+}
+
+// Close writes all queued samples, flushes the output and returns the
+// first write error, if any.
+func (e *CSVExporter) Close() error {
+	e.closeOnce.Do(func() { close(e.samples) })
+	<-e.done
+	return e.err
+}
+
+func (e *CSVExporter) run(w io.Writer) {
+	defer close(e.done)
+
+	bw := bufio.NewWriterSize(w, 64*1024)
+	cw := csv.NewWriter(bw)
+
+	e.setErr(cw.Write(csvHeader))
+
+	record := make([]string, len(csvHeader))
+	record[8] = version.Label()
+	for s := range e.samples {
+		if e.err != nil {
+			// Keep draining so Add never blocks, but stop writing
+			continue
+		}
+
+		record[0] = s.Time.UTC().Format(time.RFC3339Nano)
+		record[1] = s.Step
+		record[2] = strconv.Itoa(s.Status)
+		record[3] = strconv.FormatFloat(float64(s.Duration)/float64(time.Millisecond), 'f', 3, 64)
+		record[4] = strconv.FormatInt(s.BytesSent, 10)
+		record[5] = strconv.FormatInt(s.BytesReceived, 10)
+		record[6] = ""
+		if s.Err != nil {
+			record[6] = s.Err.Error()
+		}
+		record[7] = s.Scenario
+		record[9] = s.Protocol
+		record[10] = formatLabels(s.Labels)
+		e.setErr(cw.Write(record))
+	}
+
+	cw.Flush()
+	e.setErr(cw.Error())
+	e.setErr(bw.Flush())
+}
+
+// formatLabels joins labels as header=value pairs sorted by header,
+// separated by semicolons
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, header := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, header+"="+labels[header])
+	}
+	return strings.Join(pairs, ";")
+}
+
+func (e *CSVExporter) setErr(err error) {
+	if err != nil && e.err == nil {
+		e.err = fmt.Errorf("failed to write samples CSV: %w", err)
+	}
+}
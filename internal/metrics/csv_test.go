@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/version"
+)
+
+func TestCSVExporter(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewCSVExporter(&buf)
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	e.Add(Sample{Time: ts, Step: "GET /users", Status: 200, Duration: 1500 * time.Microsecond,
+		BytesSent: 0, BytesReceived: 512, Scenario: "browse", Protocol: ProtocolHTTP,
+		Labels: map[string]string{"X-Served-By": "web-1", "X-Backend-Pool": "blue"}})
+	e.Add(Sample{Time: ts, Step: "POST /login", Err: errors.New("connection refused"), BytesSent: 42})
+
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d rows", len(rows))
+	}
+
+	want := []string{"2026-01-02T03:04:05Z", "GET /users", "200", "1.500", "0", "512", "", "browse", version.Label(), "http",
+		"X-Backend-Pool=blue;X-Served-By=web-1"}
+	for i, v := range want {
+		if rows[1][i] != v {
+			t.Errorf("column %s: expected '%s', got '%s'", rows[0][i], v, rows[1][i])
+		}
+	}
+	if rows[2][6] != "connection refused" || rows[2][4] != "42" {
+		t.Errorf("unexpected error row: %v", rows[2])
+	}
+
+	if err := e.Close(); err != nil {
+		t.Errorf("second Close() failed: %v", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestCSVExporter_WriteError(t *testing.T) {
+	e := NewCSVExporter(failingWriter{})
+	e.Add(Sample{Step: "a"})
+	if err := e.Close(); err == nil {
+		t.Error("expected write error, got nil")
+	}
+}
+
+type blockingWriter struct{ release chan struct{} }
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestCSVExporter_DropsWhenFull(t *testing.T) {
+	w := blockingWriter{release: make(chan struct{})}
+	e := NewCSVExporter(w)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < csvBufferSize*2+100000; i++ {
+			e.Add(Sample{Step: "a"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Add() blocked on a slow writer")
+	}
+
+	if e.Dropped() == 0 {
+		t.Error("expected dropped samples")
+	}
+
+	close(w.release)
+	if err := e.Close(); err != nil {
+		t.Errorf("Close() failed: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// ErrOverBudget is the cause of requests cancelled because their step
+// group's time budget was spent
+var ErrOverBudget = errors.New("over budget")
+
+// ErrorKind classifies a failed sample for the error breakdown: "HTTP 503"
+// for error responses, or a short transport error category such as
+// "timeout" or "connection refused".
+func ErrorKind(s Sample) string {
+	if s.Err == nil {
+		return "HTTP " + strconv.Itoa(s.Status)
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(s.Err, ErrOverBudget):
+		return "over budget"
+	case errors.Is(s.Err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(s.Err, &dnsErr):
+		return "dns error"
+	case errors.Is(s.Err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case errors.Is(s.Err, syscall.ECONNRESET):
+		return "connection reset"
+	case errors.Is(s.Err, syscall.EADDRNOTAVAIL):
+		return "ephemeral ports exhausted"
+	case errors.Is(s.Err, syscall.EMFILE):
+		return "too many open files"
+	case errors.As(s.Err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "request error"
+	}
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// Latencies are recorded with microsecond resolution from 1µs up to
+	// maxTrackableLatency, keeping 3 significant digits at every magnitude.
+	histogramUnit       = time.Microsecond
+	maxTrackableLatency = time.Hour
+	significantFigures  = 3
+)
+
+// Histogram records latency observations in an HdrHistogram. Memory use is
+// fixed regardless of the number of samples, and any percentile is accurate
+// to 3 significant digits.
+type Histogram struct {
+	mu  sync.Mutex
+	hdr *hdrhistogram.Histogram
+}
+
+// NewHistogram creates an empty latency histogram
+func NewHistogram() *Histogram {
+	return &Histogram{
+		hdr: hdrhistogram.New(1, int64(maxTrackableLatency/histogramUnit), significantFigures),
+	}
+}
+
+// Record adds a single latency observation. Values outside the trackable
+// range are clamped rather than dropped so they still count towards totals.
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d / histogramUnit)
+	v = max(v, 1)
+	v = min(v, int64(maxTrackableLatency/histogramUnit))
+
+	h.mu.Lock()
+	_ = h.hdr.RecordValue(v)
+	h.mu.Unlock()
+}
+
+// Count returns the number of recorded observations
+func (h *Histogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hdr.TotalCount()
+}
+
+// Min returns the smallest recorded latency
+func (h *Histogram) Min() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.hdr.Min()) * histogramUnit
+}
+
+// Max returns the largest recorded latency
+func (h *Histogram) Max() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.hdr.Max()) * histogramUnit
+}
+
+// Mean returns the mean recorded latency
+func (h *Histogram) Mean() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.hdr.Mean() * float64(histogramUnit))
+}
+
+// Percentile returns the latency at percentile p (0-100), e.g. 99.9
+func (h *Histogram) Percentile(p float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.hdr.ValueAtPercentile(p)) * histogramUnit
+}
+
+// Merge adds all observations from other into h
+func (h *Histogram) Merge(other *Histogram) {
+	if other == h {
+		return
+	}
+
+	other.mu.Lock()
+	snapshot := other.hdr.Export()
+	other.mu.Unlock()
+
+	h.mu.Lock()
+	h.hdr.Merge(hdrhistogram.Import(snapshot))
+	h.mu.Unlock()
+}
+
+// Reset discards all recorded observations
+func (h *Histogram) Reset() {
+	h.mu.Lock()
+	h.hdr.Reset()
+	h.mu.Unlock()
+}
+
+// MarshalBinary encodes the histogram in the compressed HdrHistogram V2
+// format, base64 encoded
+func (h *Histogram) MarshalBinary() ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hdr.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+}
+
+// mergeBinary adds the observations of an encoded histogram into h
+func (h *Histogram) mergeBinary(data []byte) error {
+	other, err := hdrhistogram.Decode(data)
+	if err != nil {
+		return fmt.Errorf("invalid encoded histogram: %w", err)
+	}
+
+	h.mu.Lock()
+	h.hdr.Merge(other)
+	h.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogram_Empty(t *testing.T) {
+	h := NewHistogram()
+	if h.Count() != 0 {
+		t.Errorf("expected count 0, got %d", h.Count())
+	}
+	if h.Percentile(99) != 0 {
+		t.Errorf("expected p99 0, got %s", h.Percentile(99))
+	}
+}
+
+func TestHistogram_Percentiles(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 10_000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond / 10)
+	}
+
+	if h.Count() != 10_000 {
+		t.Fatalf("expected count 10000, got %d", h.Count())
+	}
+
+	tests := []struct {
+		percentile float64
+		want       time.Duration
+	}{
+		{50, 500 * time.Millisecond},
+		{99, 990 * time.Millisecond},
+		{99.9, 999 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		got := h.Percentile(tt.percentile)
+		// 3 significant digits => within 0.1% of the true value
+		if diff := (got - tt.want).Abs(); diff > tt.want/1000 {
+			t.Errorf("p%v: expected ~%s, got %s", tt.percentile, tt.want, got)
+		}
+	}
+
+	if h.Min() != 100*time.Microsecond {
+		t.Errorf("expected min 100µs, got %s", h.Min())
+	}
+	if diff := (h.Max() - time.Second).Abs(); diff > time.Millisecond {
+		t.Errorf("expected max ~1s, got %s", h.Max())
+	}
+}
+
+func TestHistogram_ClampsOutOfRange(t *testing.T) {
+	h := NewHistogram()
+	h.Record(0)
+	h.Record(2 * maxTrackableLatency)
+
+	if h.Count() != 2 {
+		t.Fatalf("expected count 2, got %d", h.Count())
+	}
+	if h.Min() != histogramUnit {
+		t.Errorf("expected min %s, got %s", histogramUnit, h.Min())
+	}
+	if diff := (h.Max() - maxTrackableLatency).Abs(); diff > maxTrackableLatency/1000 {
+		t.Errorf("expected max ~%s, got %s", maxTrackableLatency, h.Max())
+	}
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	a := NewHistogram()
+	b := NewHistogram()
+	a.Record(10 * time.Millisecond)
+	b.Record(20 * time.Millisecond)
+	b.Record(30 * time.Millisecond)
+
+	a.Merge(b)
+
+	if a.Count() != 3 {
+		t.Errorf("expected count 3, got %d", a.Count())
+	}
+	if b.Count() != 2 {
+		t.Errorf("merge must not modify source, got count %d", b.Count())
+	}
+
+	a.Merge(a)
+	if a.Count() != 3 {
+		t.Errorf("self-merge must be a no-op, got count %d", a.Count())
+	}
+}
+
+func TestHistogram_Reset(t *testing.T) {
+	h := NewHistogram()
+	h.Record(time.Millisecond)
+	h.Reset()
+	if h.Count() != 0 {
+		t.Errorf("expected count 0 after reset, got %d", h.Count())
+	}
+}
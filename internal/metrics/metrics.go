@@ -0,0 +1,21 @@
+package metrics
+
+import "time"
+
+// Sink receives measurements as they are recorded during a run.
+// Implementations must be safe for concurrent use by multiple VUs.
+type Sink interface {
+	// Timing records a latency observation
+	Timing(name string, d time.Duration, tags map[string]string)
+	// Count adds value to a counter
+	Count(name string, value int64, tags map[string]string)
+	// Close flushes buffered data and releases resources
+	Close() error
+}
+
+// SampleSink receives every raw request sample. Add is called on the hot
+// path of every VU, so implementations must not block.
+type SampleSink interface {
+	Add(s Sample)
+	Close() error
+}
@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultOutlierKeep is the number of slowest samples kept per step
+	DefaultOutlierKeep = 1000
+	// DefaultIQRFactor is Tukey's factor for extreme outliers
+	DefaultIQRFactor = 3.0
+)
+
+// GCPause is a stop-the-world garbage collection pause of the agent
+type GCPause struct {
+	End      time.Time
+	Duration time.Duration
+}
+
+// StageChange is the start of a stage of a staged run
+type StageChange struct {
+	Time time.Time
+	// Stage is the 1-based stage number
+	Stage int
+	VUs   int
+}
+
+// OutlierTracker keeps the slowest samples of every step, plus request and
+// error counts per second, so that latency outliers can be isolated and
+// explained once the run is over. It is a SampleSink.
+type OutlierTracker struct {
+	keep int
+
+	mu      sync.Mutex
+	slowest map[string]*sampleHeap
+	seconds map[int64]*secondCounts
+	errors  int64
+	total   int64
+}
+
+type secondCounts struct {
+	requests, errors int64
+}
+
+// NewOutlierTracker creates a tracker keeping the keep slowest samples of
+// every step
+func NewOutlierTracker(keep int) *OutlierTracker {
+	return &OutlierTracker{
+		keep:    keep,
+		slowest: make(map[string]*sampleHeap),
+		seconds: make(map[int64]*secondCounts),
+	}
+}
+
+// Add records s
+func (t *OutlierTracker) Add(s Sample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sec := s.Time.Unix()
+	counts, ok := t.seconds[sec]
+	if !ok {
+		counts = &secondCounts{}
+		t.seconds[sec] = counts
+	}
+	counts.requests++
+	t.total++
+	if s.Failed || s.Err != nil {
+		counts.errors++
+		t.errors++
+	}
+
+	if s.Err != nil {
+		return
+	}
+	h, ok := t.slowest[s.Step]
+	if !ok {
+		h = &sampleHeap{}
+		t.slowest[s.Step] = h
+	}
+	switch {
+	case h.Len() < t.keep:
+		heap.Push(h, s)
+	case s.Duration > (*h)[0].Duration:
+		(*h)[0] = s
+		heap.Fix(h, 0)
+	}
+}
+
+// Close is a no-op; an OutlierTracker holds no external resources
+func (t *OutlierTracker) Close() error {
+	return nil
+}
+
+// Outlier is a single request slower than its step's outlier threshold
+type Outlier struct {
+	Step     string
+	Start    time.Time
+	Duration time.Duration
+	Status   int
+}
+
+// OutlierWindow groups the outliers that started within the same second
+type OutlierWindow struct {
+	Start time.Time
+	// Offset is the time since the start of the run
+	Offset   time.Duration
+	Outliers []Outlier
+	// Causes are possible explanations, most specific first
+	Causes []string
+}
+
+// OutlierReport is the outcome of outlier detection for a run
+type OutlierReport struct {
+	// Thresholds are the per-step latencies above which a request is an
+	// outlier
+	Thresholds map[string]time.Duration
+	Count      int
+	Windows    []OutlierWindow
+}
+
+// Detect isolates the kept samples above thresholds, groups them into
+// one-second windows and annotates each window with possible causes.
+// Outliers beyond the kept samples of a step are not reported.
+func (t *OutlierTracker) Detect(start time.Time, thresholds map[string]time.Duration, pauses []GCPause,
+	stages []StageChange) *OutlierReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := &OutlierReport{Thresholds: thresholds}
+	windows := make(map[int64]*OutlierWindow)
+	for step, h := range t.slowest {
+		threshold, ok := thresholds[step]
+		if !ok {
+			continue
+		}
+		for _, s := range *h {
+			if s.Duration <= threshold {
+				continue
+			}
+			o := Outlier{Step: step, Start: s.Time.Add(-s.Duration), Duration: s.Duration, Status: s.Status}
+			sec := o.Start.Unix()
+			w, ok := windows[sec]
+			if !ok {
+				w = &OutlierWindow{Start: time.Unix(sec, 0)}
+				w.Offset = max(0, w.Start.Sub(start.Truncate(time.Second)))
+				windows[sec] = w
+			}
+			w.Outliers = append(w.Outliers, o)
+			report.Count++
+		}
+	}
+
+	for _, w := range windows {
+		slices.SortFunc(w.Outliers, func(a, b Outlier) int { return a.Start.Compare(b.Start) })
+		w.Causes = t.annotate(w, start, pauses, stages)
+		report.Windows = append(report.Windows, *w)
+	}
+	slices.SortFunc(report.Windows, func(a, b OutlierWindow) int { return a.Start.Compare(b.Start) })
+	return report
+}
+
+// annotate lists possible causes for the outliers of w
+func (t *OutlierTracker) annotate(w *OutlierWindow, start time.Time, pauses []GCPause, stages []StageChange) []string {
+	var causes []string
+
+	// A GC pause stalls every VU goroutine, so it inflates any request in
+	// flight during the pause
+	var gcCount int
+	var gcLongest time.Duration
+	for _, p := range pauses {
+		pauseStart := p.End.Add(-p.Duration)
+		for _, o := range w.Outliers {
+			if pauseStart.Before(o.Start.Add(o.Duration)) && p.End.After(o.Start) {
+				gcCount++
+				gcLongest = max(gcLongest, p.Duration)
+				break
+			}
+		}
+	}
+	if gcCount > 0 {
+		causes = append(causes, fmt.Sprintf("agent GC pause: %d pause(s) up to %s overlapped these requests",
+			gcCount, gcLongest))
+	}
+
+	// Clients retrying failed requests pile load onto a struggling target
+	if counts, ok := t.seconds[w.Start.Unix()]; ok && counts.errors >= 5 && t.total > 0 {
+		rate := float64(counts.errors) / float64(counts.requests)
+		overall := float64(t.errors) / float64(t.total)
+		if rate >= 0.05 && rate >= 3*overall {
+			causes = append(causes, fmt.Sprintf("error burst: %.1f%% of requests failed in this second vs %.1f%% overall, possible retry storm",
+				rate*100, overall*100))
+		}
+	}
+
+	if w.Start.Before(start.Add(time.Second)) {
+		causes = append(causes, "run start: connections and caches were still warming up")
+	}
+
+	// New VUs open connections at once when a stage starts, the first stage
+	// starts with the run
+	for _, s := range stages {
+		if s.Stage > 1 && !s.Time.Before(w.Start.Add(-time.Second)) && s.Time.Before(w.Start.Add(time.Second)) {
+			causes = append(causes, fmt.Sprintf("stage transition: stage %d started at +%s with %d VUs",
+				s.Stage, max(0, s.Time.Sub(start)).Round(100*time.Millisecond), s.VUs))
+		}
+	}
+
+	steps := make(map[string]bool)
+	for _, o := range w.Outliers {
+		steps[o.Step] = true
+	}
+	switch {
+	case len(steps) > 1:
+		causes = append(causes, fmt.Sprintf("%d steps slowed down together, likely target or network wide", len(steps)))
+	case len(w.Outliers) > 1:
+		causes = append(causes, fmt.Sprintf("isolated to step '%s'", w.Outliers[0].Step))
+	}
+	return causes
+}
+
+// OutlierThreshold returns the latency above which a request is an
+// outlier: the larger of p99.9 and Q3 + k×IQR
+func OutlierThreshold(r LatencyRecorder, k float64) time.Duration {
+	q1, q3 := r.Percentile(25), r.Percentile(75)
+	tukey := q3 + time.Duration(k*float64(q3-q1))
+	return max(r.Percentile(99.9), tukey)
+}
+
+// OutlierThresholds returns the outlier threshold of every step with
+// latency observations, see OutlierThreshold
+func (c *Collector) OutlierThresholds(k float64) map[string]time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	thresholds := make(map[string]time.Duration, len(c.steps))
+	for name, s := range c.steps {
+		if s.latency.Count() > 0 {
+			thresholds[name] = OutlierThreshold(s.latency, k)
+		}
+	}
+	return thresholds
+}
+
+// sampleHeap is a min-heap of samples by duration
+type sampleHeap []Sample
+
+func (h sampleHeap) Len() int           { return len(h) }
+func (h sampleHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h sampleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *sampleHeap) Push(x any)        { *h = append(*h, x.(Sample)) }
+func (h *sampleHeap) Pop() any {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutlierTracker_KeepsSlowest(t *testing.T) {
+	tr := NewOutlierTracker(3)
+	start := time.Unix(1000, 0)
+	for i := 1; i <= 10; i++ {
+		tr.Add(Sample{Time: start, Step: "s", Status: 200, Duration: time.Duration(i) * time.Millisecond})
+	}
+
+	report := tr.Detect(start, map[string]time.Duration{"s": 0}, nil, nil)
+	if report.Count != 3 {
+		t.Fatalf("expected the 3 slowest samples to be kept, got %d", report.Count)
+	}
+	for _, w := range report.Windows {
+		for _, o := range w.Outliers {
+			if o.Duration < 8*time.Millisecond {
+				t.Errorf("kept sample of %s is not among the slowest", o.Duration)
+			}
+		}
+	}
+}
+
+func TestOutlierTracker_Detect(t *testing.T) {
+	tr := NewOutlierTracker(DefaultOutlierKeep)
+	start := time.Unix(1000, 0)
+
+	// Steady traffic in the first 10 seconds
+	for sec := range 10 {
+		for i := range 100 {
+			at := start.Add(time.Duration(sec)*time.Second + time.Duration(i)*10*time.Millisecond)
+			tr.Add(Sample{Time: at, Step: "a", Status: 200, Duration: 5 * time.Millisecond})
+			tr.Add(Sample{Time: at, Step: "b", Status: 200, Duration: 5 * time.Millisecond})
+		}
+	}
+	// Second 3: step a alone is slow during a GC pause
+	at := start.Add(3*time.Second + 500*time.Millisecond)
+	tr.Add(Sample{Time: at, Step: "a", Status: 200, Duration: 200 * time.Millisecond})
+	tr.Add(Sample{Time: at, Step: "a", Status: 200, Duration: 210 * time.Millisecond})
+	pauses := []GCPause{{End: at.Add(-50 * time.Millisecond), Duration: 20 * time.Millisecond}}
+	// Second 7: both steps are slow while most requests fail
+	at = start.Add(7*time.Second + 900*time.Millisecond)
+	for range 30 {
+		tr.Add(Sample{Time: at, Step: "b", Status: 503, Failed: true, Duration: 5 * time.Millisecond})
+	}
+	tr.Add(Sample{Time: at, Step: "a", Status: 200, Duration: 400 * time.Millisecond})
+	tr.Add(Sample{Time: at, Step: "b", Status: 200, Duration: 300 * time.Millisecond})
+
+	thresholds := map[string]time.Duration{"a": 50 * time.Millisecond, "b": 50 * time.Millisecond}
+	// Stage 2 starts just before the slowdown in second 7
+	stages := []StageChange{
+		{Time: start, Stage: 1, VUs: 10},
+		{Time: start.Add(7*time.Second + 200*time.Millisecond), Stage: 2, VUs: 50},
+	}
+	report := tr.Detect(start, thresholds, pauses, stages)
+	if report.Count != 4 {
+		t.Fatalf("expected 4 outliers, got %d", report.Count)
+	}
+	if len(report.Windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(report.Windows))
+	}
+
+	gc := report.Windows[0]
+	if gc.Offset != 3*time.Second {
+		t.Errorf("expected first window at +3s, got +%s", gc.Offset)
+	}
+	assertCause(t, gc.Causes, "agent GC pause")
+	assertCause(t, gc.Causes, "isolated to step 'a'")
+
+	storm := report.Windows[1]
+	if storm.Offset != 7*time.Second {
+		t.Errorf("expected second window at +7s, got +%s", storm.Offset)
+	}
+	assertCause(t, storm.Causes, "error burst")
+	assertCause(t, storm.Causes, "2 steps slowed down together")
+	assertCause(t, storm.Causes, "stage transition: stage 2 started at +7.2s with 50 VUs")
+	for _, c := range gc.Causes {
+		if strings.HasPrefix(c, "stage transition") {
+			t.Errorf("unexpected stage cause in first window: %s", c)
+		}
+	}
+	for _, c := range storm.Causes {
+		if strings.HasPrefix(c, "agent GC pause") {
+			t.Errorf("unexpected GC cause in second window: %s", c)
+		}
+	}
+}
+
+func TestOutlierThreshold(t *testing.T) {
+	r := NewHistogram()
+	for i := 1; i <= 100; i++ {
+		r.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	// Q3 + 3×IQR = 75ms + 150ms exceeds p99.9
+	got := OutlierThreshold(r, DefaultIQRFactor)
+	if got < 220*time.Millisecond || got > 230*time.Millisecond {
+		t.Errorf("expected threshold near 225ms, got %s", got)
+	}
+}
+
+func assertCause(t *testing.T, causes []string, prefix string) {
+	t.Helper()
+	for _, c := range causes {
+		if strings.HasPrefix(c, prefix) {
+			return
+		}
+	}
+	t.Errorf("expected a cause starting with %q, got %q", prefix, causes)
+}
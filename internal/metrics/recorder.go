@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// LatencyRecorder accumulates latency observations and answers percentile
+// queries. Histogram and TDigest both implement it.
+type LatencyRecorder interface {
+	Record(d time.Duration)
+	Count() int64
+	Min() time.Duration
+	Max() time.Duration
+	Mean() time.Duration
+	Percentile(p float64) time.Duration
+}
+
+// Percentile estimators selectable with NewLatencyRecorder
+const (
+	EstimatorHDR     = "hdr"
+	EstimatorTDigest = "tdigest"
+)
+
+// NewLatencyRecorder returns a recorder for the named estimator. compression
+// only applies to t-digest; 0 selects DefaultCompression.
+func NewLatencyRecorder(estimator string, compression float64) (LatencyRecorder, error) {
+	switch estimator {
+	case "", EstimatorHDR:
+		return NewHistogram(), nil
+	case EstimatorTDigest:
+		if compression == 0 {
+			compression = DefaultCompression
+		}
+		return NewTDigest(compression)
+	default:
+		return nil, fmt.Errorf("unknown percentile estimator %q, must be one of: [%s %s]",
+			estimator, EstimatorHDR, EstimatorTDigest)
+	}
+}
+
+// EncodeRecorder serializes r, so that it can be merged into a recorder of
+// the same estimator on another agent with MergeEncoded
+func EncodeRecorder(r LatencyRecorder) ([]byte, error) {
+	switch rec := r.(type) {
+	case *Histogram:
+		return rec.MarshalBinary()
+	case *TDigest:
+		return rec.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("cannot encode latency recorder of type %T", r)
+	}
+}
+
+// MergeEncoded adds the observations of a recorder encoded with
+// EncodeRecorder into r
+func MergeEncoded(r LatencyRecorder, data []byte) error {
+	switch rec := r.(type) {
+	case *Histogram:
+		return rec.mergeBinary(data)
+	case *TDigest:
+		return rec.mergeBinary(data)
+	default:
+		return fmt.Errorf("cannot merge into latency recorder of type %T", r)
+	}
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsDConfig configures a StatsD sink
+type StatsDConfig struct {
+	// Addr is the host:port of the StatsD agent, e.g. "127.0.0.1:8125"
+	Addr string
+	// Prefix is prepended to every metric name, separated by a dot
+	Prefix string
+	// DogStatsD enables the Datadog tag extension ("|#key:value"). Plain
+	// StatsD has no tag support, so tags are dropped when it is disabled.
+	DogStatsD bool
+}
+
+// StatsD emits metrics to a StatsD or DogStatsD agent over UDP.
+// Sends are fire-and-forget: a missing agent never slows down the test.
+type StatsD struct {
+	conn      net.Conn
+	prefix    string
+	dogStatsD bool
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewStatsD creates a StatsD sink sending to cfg.Addr
+func NewStatsD(cfg StatsDConfig) (*StatsD, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("statsd address cannot be empty")
+	}
+
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial statsd: %w", err)
+	}
+
+	prefix := strings.TrimSuffix(cfg.Prefix, ".")
+	if prefix != "" {
+		prefix += "."
+	}
+
+	return &StatsD{
+		conn:      conn,
+		prefix:    prefix,
+		dogStatsD: cfg.DogStatsD,
+	}, nil
+}
+
+// Timing sends d as a "ms" timer
+func (s *StatsD) Timing(name string, d time.Duration, tags map[string]string) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	s.send(name, ms, "ms", tags)
+}
+
+// Count sends value as a "c" counter
+func (s *StatsD) Count(name string, value int64, tags map[string]string) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Close closes the underlying UDP connection
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+func (s *StatsD) send(name, value, kind string, tags map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = s.buf[:0]
+	s.buf = append(s.buf, s.prefix...)
+	s.buf = append(s.buf, sanitizeName(name)...)
+	s.buf = append(s.buf, ':')
+	s.buf = append(s.buf, value...)
+	s.buf = append(s.buf, '|')
+	s.buf = append(s.buf, kind...)
+
+	if s.dogStatsD && len(tags) > 0 {
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		s.buf = append(s.buf, "|#"...)
+		for i, k := range keys {
+			if i > 0 {
+				s.buf = append(s.buf, ',')
+			}
+			s.buf = append(s.buf, sanitizeTag(k)...)
+			s.buf = append(s.buf, ':')
+			s.buf = append(s.buf, sanitizeTag(tags[k])...)
+		}
+	}
+
+	// UDP delivery is best effort; write errors are deliberately ignored
+	_, _ = s.conn.Write(s.buf)
+}
+
+// sanitizeName replaces characters that have meaning in the StatsD line
+// protocol so a step name like "GET /users" cannot corrupt the packet.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ' ', '/', '\n':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func sanitizeTag(tag string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n':
+			return '_'
+		}
+		return r
+	}, tag)
+}
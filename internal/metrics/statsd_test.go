@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func listenUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func readPacket(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 1500)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNewStatsD_EmptyAddr(t *testing.T) {
+	if _, err := NewStatsD(StatsDConfig{}); err == nil {
+		t.Error("expected error for empty address, got nil")
+	}
+}
+
+func TestStatsD_Timing(t *testing.T) {
+	pc := listenUDP(t)
+	s, err := NewStatsD(StatsDConfig{Addr: pc.LocalAddr().String(), Prefix: "loadforge."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	s.Timing("http_req_duration", 1500*time.Microsecond, map[string]string{"step": "GET /users"})
+
+	got := readPacket(t, pc)
+	want := "loadforge.http_req_duration:1.5|ms"
+	if got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestStatsD_CountWithDogStatsDTags(t *testing.T) {
+	pc := listenUDP(t)
+	s, err := NewStatsD(StatsDConfig{Addr: pc.LocalAddr().String(), DogStatsD: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	s.Count("http_reqs", 3, map[string]string{"status": "200", "step": "GET /users"})
+
+	got := readPacket(t, pc)
+	want := "http_reqs:3|c|#status:200,step:GET /users"
+	if got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestStatsD_SanitizesName(t *testing.T) {
+	pc := listenUDP(t)
+	s, err := NewStatsD(StatsDConfig{Addr: pc.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	s.Count("GET /users|x", 1, nil)
+
+	got := readPacket(t, pc)
+	want := "GET__users_x:1|c"
+	if got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+}
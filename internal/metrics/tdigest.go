@@ -0,0 +1,280 @@
+package metrics
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultCompression is the t-digest compression used when none is given.
+// Higher values keep more centroids and give more accurate tails.
+const DefaultCompression = 100
+
+// MaxCompression bounds the t-digest compression, which sizes the buffer
+// of every digest. Accuracy gains are negligible well before it.
+const MaxCompression = 10_000
+
+// TDigest estimates latency percentiles with a merging t-digest. It keeps
+// roughly 2×compression centroids no matter how many samples are added,
+// so memory stays bounded over multi-hour soak tests while tail
+// percentiles remain accurate.
+type TDigest struct {
+	mu          sync.Mutex
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+
+	count    float64
+	sum      float64
+	min, max float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// NewTDigest creates an empty t-digest with the given compression
+func NewTDigest(compression float64) (*TDigest, error) {
+	if math.IsNaN(compression) || compression < 10 || compression > MaxCompression {
+		return nil, fmt.Errorf("t-digest compression must be between 10 and %d, got %v", MaxCompression, compression)
+	}
+
+	return &TDigest{
+		compression: compression,
+		buffer:      make([]centroid, 0, int(compression)*5),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}, nil
+}
+
+// Record adds a single latency observation
+func (t *TDigest) Record(d time.Duration) {
+	v := float64(d)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buffer = append(t.buffer, centroid{mean: v, weight: 1})
+	t.count++
+	t.sum += v
+	t.min = min(t.min, v)
+	t.max = max(t.max, v)
+
+	if len(t.buffer) == cap(t.buffer) {
+		t.compress()
+	}
+}
+
+// Count returns the number of recorded observations
+func (t *TDigest) Count() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return int64(t.count)
+}
+
+// Min returns the smallest recorded latency
+func (t *TDigest) Min() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count == 0 {
+		return 0
+	}
+	return time.Duration(t.min)
+}
+
+// Max returns the largest recorded latency
+func (t *TDigest) Max() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count == 0 {
+		return 0
+	}
+	return time.Duration(t.max)
+}
+
+// Mean returns the exact mean of all recorded latencies
+func (t *TDigest) Mean() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count == 0 {
+		return 0
+	}
+	return time.Duration(t.sum / t.count)
+}
+
+// Percentile returns the estimated latency at percentile p (0-100)
+func (t *TDigest) Percentile(p float64) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Duration(t.quantile(p / 100))
+}
+
+// Merge adds all observations from other into t
+func (t *TDigest) Merge(other *TDigest) {
+	if other == t {
+		return
+	}
+
+	other.mu.Lock()
+	other.compress()
+	centroids := slices.Clone(other.centroids)
+	count, sum, lo, hi := other.count, other.sum, other.min, other.max
+	other.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buffer = append(t.buffer, centroids...)
+	t.count += count
+	t.sum += sum
+	t.min = min(t.min, lo)
+	t.max = max(t.max, hi)
+	t.compress()
+}
+
+// Centroids returns the number of centroids currently held
+func (t *TDigest) Centroids() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.compress()
+	return len(t.centroids)
+}
+
+// compress merges buffered points into the centroid list. Adjacent
+// centroids are combined while they span less than one unit of the k1
+// scale function, which keeps centroids small near the tails.
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := make([]centroid, 0, len(t.centroids)+len(t.buffer))
+	all = append(all, t.centroids...)
+	all = append(all, t.buffer...)
+	t.buffer = t.buffer[:0]
+
+	slices.SortFunc(all, func(a, b centroid) int {
+		switch {
+		case a.mean < b.mean:
+			return -1
+		case a.mean > b.mean:
+			return 1
+		}
+		return 0
+	})
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	weightSoFar := 0.0
+	qLimit := t.kInverse(t.k(0) + 1)
+
+	for _, c := range all[1:] {
+		q := (weightSoFar + cur.weight + c.weight) / t.count
+		if q <= qLimit {
+			cur.mean += (c.mean - cur.mean) * c.weight / (cur.weight + c.weight)
+			cur.weight += c.weight
+			continue
+		}
+
+		merged = append(merged, cur)
+		weightSoFar += cur.weight
+		qLimit = t.kInverse(t.k(weightSoFar/t.count) + 1)
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+}
+
+// k is the k1 scale function mapping quantile q to a centroid index
+func (t *TDigest) k(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func (t *TDigest) kInverse(k float64) float64 {
+	if k >= t.compression/4 {
+		return 1
+	}
+	return (math.Sin(k*2*math.Pi/t.compression) + 1) / 2
+}
+
+// quantile interpolates between centroid centres; q is in [0, 1]
+func (t *TDigest) quantile(q float64) float64 {
+	t.compress()
+
+	if t.count == 0 {
+		return 0
+	}
+	if len(t.centroids) == 1 || q <= 0 {
+		if q >= 1 {
+			return t.max
+		}
+		if q <= 0 {
+			return t.min
+		}
+		return t.centroids[0].mean
+	}
+	if q >= 1 {
+		return t.max
+	}
+
+	target := q * t.count
+	first := t.centroids[0]
+	if target < first.weight/2 {
+		return t.min + (first.mean-t.min)*target/(first.weight/2)
+	}
+
+	cumulative := 0.0
+	for i := 0; i < len(t.centroids)-1; i++ {
+		a, b := t.centroids[i], t.centroids[i+1]
+		left := cumulative + a.weight/2
+		right := cumulative + a.weight + b.weight/2
+		if target <= right {
+			return a.mean + (target-left)/(right-left)*(b.mean-a.mean)
+		}
+		cumulative += a.weight
+	}
+
+	last := t.centroids[len(t.centroids)-1]
+	lastCentre := t.count - last.weight/2
+	return last.mean + (target-lastCentre)/(t.count-lastCentre)*(t.max-last.mean)
+}
+
+// MarshalBinary encodes the digest's summary statistics and centroids
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.compress()
+
+	values := []float64{t.count, t.sum, t.min, t.max}
+	for _, c := range t.centroids {
+		values = append(values, c.mean, c.weight)
+	}
+	return binary.Append(nil, binary.LittleEndian, values)
+}
+
+// mergeBinary adds the observations of an encoded digest into t
+func (t *TDigest) mergeBinary(data []byte) error {
+	if len(data)%16 != 0 || len(data) < 32 {
+		return fmt.Errorf("invalid encoded t-digest of %d bytes", len(data))
+	}
+	values := make([]float64, len(data)/8)
+	if _, err := binary.Decode(data, binary.LittleEndian, values); err != nil {
+		return fmt.Errorf("invalid encoded t-digest: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count += values[0]
+	t.sum += values[1]
+	t.min = min(t.min, values[2])
+	t.max = max(t.max, values[3])
+	for i := 4; i < len(values); i += 2 {
+		t.buffer = append(t.buffer, centroid{mean: values[i], weight: values[i+1]})
+	}
+	t.compress()
+	return nil
+}
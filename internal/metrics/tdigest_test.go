@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestNewTDigest_InvalidCompression(t *testing.T) {
+	for _, compression := range []float64{1, math.NaN(), math.Inf(1), MaxCompression + 1, 1e300} {
+		if _, err := NewTDigest(compression); err == nil {
+			t.Errorf("expected error for compression %v, got nil", compression)
+		}
+	}
+	if _, err := NewTDigest(MaxCompression); err != nil {
+		t.Errorf("unexpected error for the maximum compression: %v", err)
+	}
+}
+
+func TestTDigest_Empty(t *testing.T) {
+	td, err := NewTDigest(DefaultCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if td.Count() != 0 || td.Percentile(99) != 0 || td.Mean() != 0 {
+		t.Error("expected zero values for empty digest")
+	}
+}
+
+func TestTDigest_Percentiles(t *testing.T) {
+	td, err := NewTDigest(DefaultCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const n = 100_000
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, i := range rng.Perm(n) {
+		td.Record(time.Duration(i+1) * time.Microsecond)
+	}
+
+	if td.Count() != n {
+		t.Fatalf("expected count %d, got %d", n, td.Count())
+	}
+
+	tests := []struct {
+		percentile float64
+		tolerance  float64
+	}{
+		{50, 0.01},
+		{95, 0.005},
+		{99, 0.002},
+		{99.9, 0.001},
+	}
+
+	for _, tt := range tests {
+		want := tt.percentile / 100 * n * float64(time.Microsecond)
+		got := float64(td.Percentile(tt.percentile))
+		if diff := got - want; diff > want*tt.tolerance || -diff > want*tt.tolerance {
+			t.Errorf("p%v: expected ~%s, got %s", tt.percentile,
+				time.Duration(want), time.Duration(got))
+		}
+	}
+
+	if td.Min() != time.Microsecond || td.Max() != n*time.Microsecond {
+		t.Errorf("unexpected min/max: %s/%s", td.Min(), td.Max())
+	}
+	if c := td.Centroids(); c > 2*DefaultCompression {
+		t.Errorf("expected at most %d centroids, got %d", 2*DefaultCompression, c)
+	}
+}
+
+func TestTDigest_Merge(t *testing.T) {
+	a, _ := NewTDigest(DefaultCompression)
+	b, _ := NewTDigest(DefaultCompression)
+	for i := 1; i <= 1000; i++ {
+		a.Record(time.Duration(i) * time.Millisecond)
+		b.Record(time.Duration(i+1000) * time.Millisecond)
+	}
+
+	a.Merge(b)
+
+	if a.Count() != 2000 {
+		t.Fatalf("expected count 2000, got %d", a.Count())
+	}
+	if p50 := a.Percentile(50); (p50 - time.Second).Abs() > 20*time.Millisecond {
+		t.Errorf("expected p50 ~1s, got %s", p50)
+	}
+	if a.Max() != 2000*time.Millisecond {
+		t.Errorf("expected max 2s, got %s", a.Max())
+	}
+}
+
+func TestNewLatencyRecorder(t *testing.T) {
+	if r, err := NewLatencyRecorder("", 0); err != nil || r == nil {
+		t.Errorf("expected default recorder, got %v, %v", r, err)
+	}
+	if _, ok := mustRecorder(t, EstimatorTDigest).(*TDigest); !ok {
+		t.Error("expected *TDigest for tdigest estimator")
+	}
+	if _, ok := mustRecorder(t, EstimatorHDR).(*Histogram); !ok {
+		t.Error("expected *Histogram for hdr estimator")
+	}
+	if _, err := NewLatencyRecorder("raw", 0); err == nil {
+		t.Error("expected error for unknown estimator, got nil")
+	}
+}
+
+func mustRecorder(t *testing.T, estimator string) LatencyRecorder {
+	t.Helper()
+	r, err := NewLatencyRecorder(estimator, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return r
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Window aggregates samples over consecutive intervals, for live views that
+// show current throughput and latency rather than whole-run totals. It is a
+// SampleSink, so it can be attached to a run alongside other sinks.
+type Window struct {
+	estimator   string
+	compression float64
+
+	mu      sync.RWMutex
+	steps   []string
+	current *Collector
+}
+
+// NewWindow creates a Window whose latency recorders use the given
+// estimator (see NewLatencyRecorder).
+func NewWindow(estimator string, compression float64) (*Window, error) {
+	current, err := NewCollector(estimator, compression)
+	if err != nil {
+		return nil, err
+	}
+	return &Window{estimator: estimator, compression: compression, current: current}, nil
+}
+
+// Register pre-creates the given steps so they appear in every interval
+// in this order, even if they received no sample during it.
+func (w *Window) Register(steps ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.steps = append(w.steps, steps...)
+	w.current.Register(steps...)
+}
+
+// Add records s in the current interval
+func (w *Window) Add(s Sample) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	w.current.Add(s)
+}
+
+// Close is a no-op; a Window holds no external resources
+func (w *Window) Close() error {
+	return nil
+}
+
+// Flush ends the current interval and returns its statistics. elapsed is
+// the interval length used to compute throughput.
+func (w *Window) Flush(elapsed time.Duration) Summary {
+	// The estimator was validated in NewWindow, so this cannot fail
+	next, _ := NewCollector(w.estimator, w.compression)
+
+	w.mu.Lock()
+	next.Register(w.steps...)
+	prev := w.current
+	w.current = next
+	w.mu.Unlock()
+
+	return prev.Summary(elapsed)
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindow_Flush(t *testing.T) {
+	w, err := NewWindow(EstimatorHDR, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Register("GET /a", "GET /b")
+
+	for i := 0; i < 4; i++ {
+		w.Add(Sample{Step: "GET /a", Status: 200, Duration: 10 * time.Millisecond})
+	}
+	w.Add(Sample{Step: "GET /b", Status: 500, Duration: 50 * time.Millisecond, Failed: true})
+
+	first := w.Flush(time.Second)
+	if first.Total.Requests != 5 || first.Total.RPS != 5 {
+		t.Errorf("unexpected first interval totals: %d requests, %.1f rps",
+			first.Total.Requests, first.Total.RPS)
+	}
+	if len(first.Steps) != 2 || first.Steps[1].ErrorRate != 1 {
+		t.Errorf("unexpected first interval steps: %+v", first.Steps)
+	}
+
+	w.Add(Sample{Step: "GET /b", Status: 200, Duration: 20 * time.Millisecond})
+
+	second := w.Flush(time.Second)
+	if second.Total.Requests != 1 {
+		t.Errorf("expected samples from the first interval to be reset, got %d requests",
+			second.Total.Requests)
+	}
+	if len(second.Steps) != 2 || second.Steps[0].Name != "GET /a" || second.Steps[0].Requests != 0 {
+		t.Errorf("expected registered steps to survive a flush, got %+v", second.Steps)
+	}
+	if second.Steps[1].ErrorRate != 0 {
+		t.Errorf("expected error rate to reset, got %f", second.Steps[1].ErrorRate)
+	}
+}
@@ -0,0 +1,130 @@
+// Package netstat samples the agent host's TCP socket usage, so that port
+// and file descriptor exhaustion can be spotted before they surface as
+// mysterious dial errors.
+package netstat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned by Read on platforms without socket telemetry
+var ErrUnsupported = errors.New("socket telemetry is not supported on this platform")
+
+// WarnRatio is the share of a limit at which usage is reported as close to
+// exhaustion
+const WarnRatio = 0.8
+
+// Stats is a snapshot of socket usage. Socket counts cover the whole host
+// (network namespace), since ports are shared with other processes; file
+// counts are the agent's own.
+type Stats struct {
+	// Open counts TCP sockets in any state but LISTEN
+	Open        int
+	Established int
+	TimeWait    int
+	// EphemeralInUse counts open sockets bound to a local port in the
+	// ephemeral range, out of EphemeralPorts ports
+	EphemeralInUse int
+	EphemeralPorts int
+	// OpenFiles counts the agent's file descriptors, out of FileLimit
+	OpenFiles int
+	FileLimit int
+}
+
+// EphemeralUsage returns the share of ephemeral ports in use, 0 if unknown
+func (s Stats) EphemeralUsage() float64 {
+	if s.EphemeralPorts == 0 {
+		return 0
+	}
+	return float64(s.EphemeralInUse) / float64(s.EphemeralPorts)
+}
+
+// FileUsage returns the share of the file descriptor limit in use, 0 if
+// unknown
+func (s Stats) FileUsage() float64 {
+	if s.FileLimit == 0 {
+		return 0
+	}
+	return float64(s.OpenFiles) / float64(s.FileLimit)
+}
+
+// Max returns the field-wise maximum of s and o, to track peak usage
+func (s Stats) Max(o Stats) Stats {
+	return Stats{
+		Open:           max(s.Open, o.Open),
+		Established:    max(s.Established, o.Established),
+		TimeWait:       max(s.TimeWait, o.TimeWait),
+		EphemeralInUse: max(s.EphemeralInUse, o.EphemeralInUse),
+		EphemeralPorts: max(s.EphemeralPorts, o.EphemeralPorts),
+		OpenFiles:      max(s.OpenFiles, o.OpenFiles),
+		FileLimit:      max(s.FileLimit, o.FileLimit),
+	}
+}
+
+// TCP states as numbered in /proc/net/tcp
+const (
+	stateEstablished = 0x01
+	stateTimeWait    = 0x06
+	stateListen      = 0x0A
+)
+
+// parseTCP adds the sockets listed in r, in /proc/net/tcp format, to s.
+// Local ports within [low, high] count as ephemeral.
+func parseTCP(r io.Reader, low, high int, s *Stats) error {
+	sc := bufio.NewScanner(r)
+	sc.Scan() // header
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid socket state %q", fields[3])
+		}
+		if state == stateListen {
+			continue
+		}
+		s.Open++
+		switch state {
+		case stateEstablished:
+			s.Established++
+		case stateTimeWait:
+			s.TimeWait++
+		}
+
+		_, port, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			return fmt.Errorf("invalid local address %q", fields[1])
+		}
+		p, err := strconv.ParseUint(port, 16, 16)
+		if err != nil {
+			return fmt.Errorf("invalid local port %q", port)
+		}
+		if int(p) >= low && int(p) <= high {
+			s.EphemeralInUse++
+		}
+	}
+	return sc.Err()
+}
+
+// parsePortRange parses ip_local_port_range content, e.g. "32768\t60999"
+func parsePortRange(data string) (low, high int, err error) {
+	fields := strings.Fields(data)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("invalid port range %q", data)
+	}
+	if low, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, fmt.Errorf("invalid port range %q", data)
+	}
+	if high, err = strconv.Atoi(fields[1]); err != nil || high < low {
+		return 0, 0, fmt.Errorf("invalid port range %q", data)
+	}
+	return low, high, nil
+}
@@ -0,0 +1,53 @@
+package netstat
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Read samples the current socket usage from /proc
+func Read() (Stats, error) {
+	var s Stats
+
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_local_port_range")
+	if err != nil {
+		return s, fmt.Errorf("failed to read ephemeral port range: %w", err)
+	}
+	low, high, err := parsePortRange(string(data))
+	if err != nil {
+		return s, err
+	}
+	s.EphemeralPorts = high - low + 1
+
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// IPv6 is disabled
+			continue
+		}
+		if err != nil {
+			return s, fmt.Errorf("failed to read sockets: %w", err)
+		}
+		err = parseTCP(f, low, high, &s)
+		f.Close()
+		if err != nil {
+			return s, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return s, fmt.Errorf("failed to count open files: %w", err)
+	}
+	s.OpenFiles = len(fds)
+
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return s, fmt.Errorf("failed to read open file limit: %w", err)
+	}
+	s.FileLimit = int(min(limit.Cur, 1<<31-1))
+	return s, nil
+}
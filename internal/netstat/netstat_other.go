@@ -0,0 +1,8 @@
+//go:build !linux
+
+package netstat
+
+// Read is not supported on this platform
+func Read() (Stats, error) {
+	return Stats{}, ErrUnsupported
+}
@@ -0,0 +1,61 @@
+package netstat
+
+import (
+	"strings"
+	"testing"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 ffff 100 0 0 10 0
+   1: 0100007F:8CA0 0100007F:1F90 01 00000000:00000000 00:00000000 00000000     0        0 2 1 ffff 20 4 30 10 -1
+   2: 0100007F:8CA2 0100007F:1F90 06 00000000:00000000 03:00000F3A 00000000     0        0 0 3 ffff
+   3: 0100007F:1F90 0100007F:8CA0 01 00000000:00000000 00:00000000 00000000     0        0 3 1 ffff 20 4 30 10 -1
+`
+
+func TestParseTCP(t *testing.T) {
+	var s Stats
+	if err := parseTCP(strings.NewReader(procNetTCP), 32768, 60999, &s); err != nil {
+		t.Fatalf("parseTCP() failed: %v", err)
+	}
+
+	// The listener is skipped; ports 0x8CA0 and 0x8CA2 are ephemeral
+	want := Stats{Open: 3, Established: 2, TimeWait: 1, EphemeralInUse: 2}
+	if s != want {
+		t.Errorf("expected %+v, got %+v", want, s)
+	}
+}
+
+func TestParseTCP_Invalid(t *testing.T) {
+	var s Stats
+	data := "header\n 0: 0100007F 0100007F:1F90 01\n"
+	if err := parseTCP(strings.NewReader(data), 32768, 60999, &s); err == nil {
+		t.Error("expected error for local address without port, got nil")
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	low, high, err := parsePortRange("32768\t60999\n")
+	if err != nil || low != 32768 || high != 60999 {
+		t.Errorf("expected 32768-60999, got %d-%d (%v)", low, high, err)
+	}
+	for _, data := range []string{"", "32768", "60999 32768", "a b"} {
+		if _, _, err := parsePortRange(data); err == nil {
+			t.Errorf("expected error for %q, got nil", data)
+		}
+	}
+}
+
+func TestStats_Usage(t *testing.T) {
+	s := Stats{EphemeralInUse: 80, EphemeralPorts: 100, OpenFiles: 10, FileLimit: 1000}
+	if s.EphemeralUsage() != 0.8 || s.FileUsage() != 0.01 {
+		t.Errorf("unexpected usage: %v %v", s.EphemeralUsage(), s.FileUsage())
+	}
+	if (Stats{}).EphemeralUsage() != 0 || (Stats{}).FileUsage() != 0 {
+		t.Error("expected zero usage for unknown limits")
+	}
+
+	peak := Stats{Open: 5, TimeWait: 1}.Max(Stats{Open: 3, TimeWait: 4})
+	if peak.Open != 5 || peak.TimeWait != 4 {
+		t.Errorf("expected field-wise maximum, got %+v", peak)
+	}
+}
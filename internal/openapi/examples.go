@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Example is an example value of a request body or parameter
+type Example struct {
+	// Name is the key of the example in examples, empty for the single
+	// example of a media type, parameter or schema
+	Name    string
+	Summary string
+	Value   any
+}
+
+// RequestExamples returns the examples of the operation's JSON request
+// body in order of preference: the media type's example, its examples by
+// name, then the example of its schema. It returns nil when the operation
+// has none; see GenerateValue for bodies without examples.
+func (o *Operation) RequestExamples() []Example {
+	media := o.requestMedia()
+	if media == nil {
+		return nil
+	}
+	return mediaExamples(media)
+}
+
+// ParameterExamples returns the examples of the operation's parameter
+// named name in in, e.g. "query", in order of preference like
+// RequestExamples. Parameters of the path item are included.
+func (o *Operation) ParameterExamples(in, name string) []Example {
+	for _, param := range parameters(o.item, o.op) {
+		if param.In == in && param.Name == name {
+			return parameterExamples(param)
+		}
+	}
+	return nil
+}
+
+func mediaExamples(media *openapi3.MediaType) []Example {
+	return collectExamples(media.Example, media.Examples, media.Schema)
+}
+
+func parameterExamples(param *openapi3.Parameter) []Example {
+	return collectExamples(param.Example, param.Examples, param.Schema)
+}
+
+func collectExamples(example any, examples openapi3.Examples, schema *openapi3.SchemaRef) []Example {
+	var all []Example
+	if example != nil {
+		all = append(all, Example{Value: example})
+	}
+	for _, name := range slices.Sorted(maps.Keys(examples)) {
+		if ref := examples[name]; ref != nil && ref.Value != nil && ref.Value.Value != nil {
+			all = append(all, Example{Name: name, Summary: ref.Value.Summary, Value: ref.Value.Value})
+		}
+	}
+	if schema != nil && schema.Value != nil && schema.Value.Example != nil {
+		all = append(all, Example{Value: schema.Value.Example})
+	}
+	return all
+}
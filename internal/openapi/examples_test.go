@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+const examplesSpec = `openapi: 3.0.3
+info: {title: Orders, version: 1.0.0}
+paths:
+  /orders/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema: {type: integer, example: 7}
+        examples:
+          small: {value: 1}
+    put:
+      parameters:
+        - {name: dry, in: query, example: true, schema: {type: boolean}}
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              example: {item: pen}
+            examples:
+              gift: {summary: A gift, value: {item: card, gift: true}}
+              bulk: {value: {item: book, quantity: 100}}
+      responses:
+        '200': {description: Updated}
+    delete:
+      responses:
+        '204': {description: Deleted}`
+
+func TestOperationExamples(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(examplesSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	put, err := p.FindOperation("PUT", "/orders/{id}")
+	if err != nil || put == nil {
+		t.Fatalf("FindOperation() = %v, %v", put, err)
+	}
+
+	want := []Example{
+		{Name: "bulk", Value: map[string]any{"item": "book", "quantity": float64(100)}},
+		{Name: "gift", Summary: "A gift", Value: map[string]any{"item": "card", "gift": true}},
+		{Value: map[string]any{"item": "pen"}},
+	}
+	if got := put.RequestExamples(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected request examples %+v, got %+v", want, got)
+	}
+
+	wantID := []Example{{Name: "small", Value: float64(1)}, {Value: float64(7)}}
+	if got := put.ParameterExamples("path", "id"); !reflect.DeepEqual(got, wantID) {
+		t.Errorf("expected id examples %+v, got %+v", wantID, got)
+	}
+	if got := put.ParameterExamples("query", "dry"); !reflect.DeepEqual(got, []Example{{Value: true}}) {
+		t.Errorf("expected the dry example, got %+v", got)
+	}
+	if got := put.ParameterExamples("query", "missing"); got != nil {
+		t.Errorf("expected no examples of an unknown parameter, got %+v", got)
+	}
+
+	del, err := p.FindOperation("DELETE", "/orders/1")
+	if err != nil || del == nil {
+		t.Fatalf("FindOperation() = %v, %v", del, err)
+	}
+	if got := del.RequestExamples(); got != nil {
+		t.Errorf("expected no request examples without a body, got %+v", got)
+	}
+}
@@ -0,0 +1,56 @@
+package openapi
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// EndpointFilter selects operations of the document. An empty list does
+// not filter.
+type EndpointFilter struct {
+	// Tags selects the operations with any of these tags
+	Tags []string
+	// Methods selects the operations with one of these methods, in any case
+	Methods []string
+	// PathPrefixes selects the operations whose path starts with one of
+	// these, e.g. /v2/
+	PathPrefixes []string
+	// IncludeDeprecated keeps the operations marked deprecated, which are
+	// left out otherwise
+	IncludeDeprecated bool
+}
+
+// FilterEndpoints returns the endpoints of the document that f selects
+func (p *Parser) FilterEndpoints(f EndpointFilter) ([]Endpoint, error) {
+	endpoints, err := p.GetEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(endpoints, func(e Endpoint) bool {
+		return !f.match(e.Method, e.Path, e.Tags, e.Deprecated)
+	}), nil
+}
+
+// matchOperation reports whether f selects op, the operation of method on
+// path
+func (f *EndpointFilter) matchOperation(method, path string, op *openapi3.Operation) bool {
+	return f.match(method, path, op.Tags, op.Deprecated)
+}
+
+func (f *EndpointFilter) match(method, path string, tags []string, deprecated bool) bool {
+	if deprecated && !f.IncludeDeprecated {
+		return false
+	}
+	hasTag := func(tag string) bool { return slices.Contains(f.Tags, tag) }
+	if len(f.Tags) > 0 && !slices.ContainsFunc(tags, hasTag) {
+		return false
+	}
+	isMethod := func(m string) bool { return strings.EqualFold(m, method) }
+	if len(f.Methods) > 0 && !slices.ContainsFunc(f.Methods, isMethod) {
+		return false
+	}
+	isPrefix := func(prefix string) bool { return strings.HasPrefix(path, prefix) }
+	return len(f.PathPrefixes) == 0 || slices.ContainsFunc(f.PathPrefixes, isPrefix)
+}
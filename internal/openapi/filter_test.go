@@ -0,0 +1,77 @@
+package openapi
+
+import (
+	"slices"
+	"testing"
+)
+
+const filterSpec = `openapi: 3.0.3
+info: {title: Shop, version: 1.0.0}
+servers:
+  - url: https://api.example.com
+paths:
+  /v1/orders:
+    get:
+      deprecated: true
+      tags: [orders]
+      responses:
+        '200': {description: Orders}
+  /v2/orders:
+    get:
+      tags: [orders]
+      responses:
+        '200': {description: Orders}
+    post:
+      tags: [orders]
+      responses:
+        '201': {description: Created}
+  /v2/users:
+    get:
+      tags: [users]
+      responses:
+        '200': {description: Users}`
+
+func TestFilterEndpoints(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(filterSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter EndpointFilter
+		want   []string
+	}{
+		{name: "all but deprecated", want: []string{"GET /v2/orders", "GET /v2/users", "POST /v2/orders"}},
+		{
+			name:   "deprecated",
+			filter: EndpointFilter{IncludeDeprecated: true},
+			want:   []string{"GET /v1/orders", "GET /v2/orders", "GET /v2/users", "POST /v2/orders"},
+		},
+		{name: "methods", filter: EndpointFilter{Methods: []string{"post"}}, want: []string{"POST /v2/orders"}},
+		{
+			name:   "path prefixes",
+			filter: EndpointFilter{PathPrefixes: []string{"/v1/", "/v2/users"}, IncludeDeprecated: true},
+			want:   []string{"GET /v1/orders", "GET /v2/users"},
+		},
+		{
+			name:   "tags and methods",
+			filter: EndpointFilter{Tags: []string{"orders"}, Methods: []string{"GET"}},
+			want:   []string{"GET /v2/orders"},
+		},
+	}
+	for _, tt := range tests {
+		endpoints, err := p.FilterEndpoints(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: FilterEndpoints() failed: %v", tt.name, err)
+		}
+		var got []string
+		for _, e := range endpoints {
+			got = append(got, e.Method+" "+e.Path)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
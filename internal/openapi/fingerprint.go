@@ -0,0 +1,158 @@
+package openapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// docKeys are the keys of a document that describe rather than shape the
+// API; changing them does not change a fingerprint
+var docKeys = map[string]bool{
+	"summary":      true,
+	"description":  true,
+	"example":      true,
+	"examples":     true,
+	"externalDocs": true,
+	"tags":         true,
+	"deprecated":   true,
+}
+
+// Load parses the document at location, a file path or an http(s) URL
+// such as the /openapi.json of the API under test
+func (p *Parser) Load(location string) error {
+	if !IsURL(location) {
+		return p.ParseFile(location)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OpenAPI spec: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch OpenAPI spec: %s returned %s", location, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OpenAPI spec: %w", err)
+	}
+	return p.ParseData(data)
+}
+
+// IsURL reports whether location is an http(s) URL rather than a path
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// Fingerprint returns a checksum of the shape of the operation: its
+// parameters, request body and responses, including the components they
+// reference. Descriptions, examples and tags are left out, so only
+// changes that affect requests and responses change it.
+func (o *Operation) Fingerprint() (string, error) {
+	doc, err := toJSON(o.doc)
+	if err != nil {
+		return "", err
+	}
+	op, err := toJSON(o.op)
+	if err != nil {
+		return "", err
+	}
+	params, err := toJSON(o.item.Parameters)
+	if err != nil {
+		return "", err
+	}
+
+	shape := map[string]any{"operation": shapeOf(op, false), "parameters": shapeOf(params, false)}
+	refs := make(map[string]any)
+	collectRefs(doc, shape, refs)
+	shape["refs"] = refs
+
+	data, err := json.Marshal(shape)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func toJSON(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the document: %w", err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to encode the document: %w", err)
+	}
+	return out, nil
+}
+
+// shapeOf returns v without its descriptive keys. The keys of a properties
+// map are property names, which are kept whatever they are.
+func shapeOf(v any, properties bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			if !properties && (docKeys[k] || strings.HasPrefix(k, "x-")) {
+				continue
+			}
+			out[k] = shapeOf(child, !properties && k == "properties")
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = shapeOf(child, false)
+		}
+		return out
+	}
+	return v
+}
+
+// collectRefs adds the shape of every local component v references to
+// refs, following the references of those components in turn
+func collectRefs(doc, v any, refs map[string]any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			ref, ok := child.(string)
+			if k != "$ref" || !ok {
+				collectRefs(doc, child, refs)
+				continue
+			}
+			if _, seen := refs[ref]; seen || !strings.HasPrefix(ref, "#/") {
+				continue
+			}
+			target := shapeOf(resolvePointer(doc, ref), false)
+			refs[ref] = target
+			collectRefs(doc, target, refs)
+		}
+	case []any:
+		for _, child := range v {
+			collectRefs(doc, child, refs)
+		}
+	}
+}
+
+// resolvePointer returns the value of doc at the JSON pointer of a local
+// reference such as #/components/schemas/Pet, nil if there is none
+func resolvePointer(doc any, ref string) any {
+	v := doc
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		v = m[token]
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const fingerprintSpec = `openapi: 3.0.3
+info: {title: Pets, version: 1.0.0}
+paths:
+  /pets/{petId}:
+    parameters: [{name: petId, in: path, required: true, schema: {type: integer}}]
+    get:
+      summary: Get a pet
+      responses:
+        '200':
+          description: A pet
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string, example: Rex}
+        description: {type: string}`
+
+func fingerprint(t *testing.T, spec string) string {
+	t.Helper()
+	doc := New()
+	if err := doc.ParseData([]byte(spec)); err != nil {
+		t.Fatalf("failed to parse spec: %v", err)
+	}
+	op, err := doc.FindOperation("GET", "/pets/{id}")
+	if err != nil || op == nil {
+		t.Fatalf("expected GET /pets/{petId}, got %v, %v", op, err)
+	}
+	fp, err := op.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint() failed: %v", err)
+	}
+	return fp
+}
+
+func TestOperationFingerprint(t *testing.T) {
+	want := fingerprint(t, fingerprintSpec)
+	for _, tt := range []struct {
+		old, new string
+		changed  bool
+	}{
+		{"summary: Get a pet", "summary: Fetch a pet", false},
+		{"description: A pet", "description: The pet", false},
+		{"example: Rex", "example: Tom", false},
+		{"name: {type: string", "name: {type: string, minLength: 1", true},
+		{"description: {type: string}", "description: {type: integer}", true},
+		{"schema: {type: integer}", "schema: {type: string}", true},
+		{"required: true, schema", "required: true, style: simple, schema", true},
+		{"'200':", "'201':", true},
+	} {
+		spec := strings.Replace(fingerprintSpec, tt.old, tt.new, 1)
+		if got := fingerprint(t, spec); (got != want) != tt.changed {
+			t.Errorf("%s -> %s: expected changed %v, got %s for %s", tt.old, tt.new, tt.changed, got, want)
+		}
+	}
+}
+
+func TestLoad_URL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openapi.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(fingerprintSpec))
+	}))
+	defer server.Close()
+
+	doc := New()
+	if err := doc.Load(server.URL + "/openapi.yaml"); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if op, err := doc.FindOperation("GET", "/pets/1"); err != nil || op == nil {
+		t.Errorf("expected GET /pets/{petId}, got %v, %v", op, err)
+	}
+	if err := New().Load(server.URL + "/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
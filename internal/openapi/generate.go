@@ -0,0 +1,240 @@
+package openapi
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"loadforge-agent/internal/scenario"
+)
+
+// GenerateOptions select the operations Generate converts and the load of
+// the scenario
+type GenerateOptions struct {
+	// Tags limits the scenario to the operations with any of these tags
+	Tags []string
+	// BaseURL replaces the first server of the document
+	BaseURL      string
+	VirtualUsers uint64
+	// Duration is in seconds
+	Duration uint64
+	// ServerVariables are values of the variables of the document's first
+	// server, e.g. {region}, overriding their defaults
+	ServerVariables map[string]string
+
+	// Methods and PathPrefixes further limit the scenario, see
+	// EndpointFilter. Deprecated operations are left out unless
+	// IncludeDeprecated is set.
+	Methods           []string
+	PathPrefixes      []string
+	IncludeDeprecated bool
+}
+
+// methodOrder is the order of the steps of a path's operations
+var methodOrder = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE"}
+
+// Generate converts the document into a runnable scenario with a step per
+// operation, ordered by path. Path and required query parameters become
+// variables, set to an example value of the parameter, and JSON request
+// bodies are filled in from their examples or generated from their schemas.
+// Credentials of the operations' security schemes are sent as secrets read
+// from environment variables named after the schemes, e.g. the secret
+// api_key from API_KEY.
+func (p *Parser) Generate(opts GenerateOptions) (*scenario.Scenario, error) {
+	if p.doc == nil {
+		return nil, fmt.Errorf("no document loaded")
+	}
+
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		if len(p.doc.Servers) == 0 {
+			return nil, fmt.Errorf("the document has no servers, set a base URL")
+		}
+		var err error
+		if baseURL, err = resolveServerURL(p.doc.Servers[0], opts.ServerVariables); err != nil {
+			return nil, err
+		}
+		if !strings.Contains(baseURL, "://") {
+			return nil, fmt.Errorf("the document's server %q is relative, set a base URL", baseURL)
+		}
+	}
+	name := "generated"
+	if p.doc.Info != nil && p.doc.Info.Title != "" {
+		name = p.doc.Info.Title
+	}
+
+	sc := &scenario.Scenario{
+		Name:         name,
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		VirtualUsers: max(opts.VirtualUsers, 1),
+		Duration:     max(opts.Duration, 1),
+		Variables:    make(map[string]string),
+	}
+
+	filter := EndpointFilter{
+		Tags:              opts.Tags,
+		Methods:           opts.Methods,
+		PathPrefixes:      opts.PathPrefixes,
+		IncludeDeprecated: opts.IncludeDeprecated,
+	}
+	var paths []string
+	if p.doc.Paths != nil {
+		paths = slices.Sorted(maps.Keys(p.doc.Paths.Map()))
+	}
+	for _, path := range paths {
+		item := p.doc.Paths.Value(path)
+		for _, method := range methodOrder {
+			op := item.GetOperation(method)
+			if op == nil || !filter.matchOperation(method, path, op) {
+				continue
+			}
+			step := generateStep(method, path, item, op, sc.Variables)
+			if alternatives := security(p.doc, op); len(alternatives) > 0 {
+				sc.Secrets = applyAuth(&step, alternatives[0], sc.Secrets)
+			}
+			sc.Steps = append(sc.Steps, step)
+		}
+	}
+	if len(sc.Steps) == 0 {
+		return nil, fmt.Errorf("no operations to generate steps from")
+	}
+	if len(sc.Variables) == 0 {
+		sc.Variables = nil
+	}
+	return sc, nil
+}
+
+// generateStep returns the step of the operation of method on path, adding
+// the variables of its parameters to vars
+func generateStep(method, path string, item *openapi3.PathItem, op *openapi3.Operation, vars map[string]string) scenario.Step {
+	step := scenario.Step{Name: op.OperationID, Request: method + " " + path}
+
+	for _, param := range parameters(item, op) {
+		if param.In != openapi3.ParameterInPath && (param.In != openapi3.ParameterInQuery || !param.Required) {
+			continue
+		}
+		if _, ok := vars[param.Name]; !ok {
+			vars[param.Name] = exampleParameter(param)
+		}
+		ref := "${" + param.Name + "}"
+		if param.In == openapi3.ParameterInPath {
+			step.PathParams = setKey(step.PathParams, param.Name, ref)
+		} else {
+			step.Query = setKey(step.Query, param.Name, ref)
+		}
+	}
+
+	if op.RequestBody != nil && op.RequestBody.Value != nil {
+		if media := op.RequestBody.Value.Content.Get("application/json"); media != nil {
+			step.Body = exampleMedia(media)
+		}
+	}
+	return step
+}
+
+// applyAuth sends the credentials of auths with step and adds the secrets
+// holding them to secrets, which it returns
+func applyAuth(step *scenario.Step, auths []Auth, secrets map[string]string) map[string]string {
+	for _, auth := range auths {
+		name := secretName(auth.Scheme)
+		ref := "${" + scenario.SecretPrefix + name + "}"
+		switch {
+		case auth.Kind == AuthBasic:
+			step.Headers = setKey(step.Headers, "Authorization", "Basic "+ref)
+		case auth.Kind == AuthAPIKey && auth.In == openapi3.ParameterInQuery:
+			step.Query = setKey(step.Query, auth.Name, ref)
+		case auth.Kind == AuthAPIKey && auth.In == openapi3.ParameterInCookie:
+			step.Headers = setKey(step.Headers, "Cookie", auth.Name+"="+ref)
+		case auth.Kind == AuthAPIKey:
+			step.Headers = setKey(step.Headers, auth.Name, ref)
+		case auth.Kind == AuthBearer, auth.Kind == AuthOAuth2, auth.Kind == AuthOpenIDConnect:
+			step.Headers = setKey(step.Headers, "Authorization", "Bearer "+ref)
+		default:
+			continue
+		}
+		secrets = setKey(secrets, name, "env:"+envName(name))
+	}
+	return secrets
+}
+
+// secretName turns the name of a security scheme into a secret name,
+// replacing the characters secret names do not allow
+func secretName(scheme string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, scheme)
+}
+
+// envName turns a secret name into an environment variable name, e.g.
+// api-key into API_KEY
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, name)
+}
+
+// parameters returns the parameters of op, including those of its path
+// item it does not override
+func parameters(item *openapi3.PathItem, op *openapi3.Operation) []*openapi3.Parameter {
+	var params []*openapi3.Parameter
+	for _, ref := range append(slices.Clone(op.Parameters), item.Parameters...) {
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		if !slices.ContainsFunc(params, func(p *openapi3.Parameter) bool {
+			return p.Name == ref.Value.Name && p.In == ref.Value.In
+		}) {
+			params = append(params, ref.Value)
+		}
+	}
+	return params
+}
+
+// exampleParameter returns an example value of param: its first example,
+// or else a value generated from its schema, or a placeholder when that is
+// not a scalar
+func exampleParameter(param *openapi3.Parameter) string {
+	if examples := parameterExamples(param); len(examples) > 0 {
+		return fmt.Sprint(examples[0].Value)
+	}
+	if param.Schema == nil {
+		return "1"
+	}
+	switch v := GenerateValue(param.Schema.Value).(type) {
+	case string, bool, int64, float64:
+		return fmt.Sprint(v)
+	}
+	return "1"
+}
+
+// exampleMedia returns the first example of a request body, or else a
+// value generated from its schema; nil when it has neither
+func exampleMedia(media *openapi3.MediaType) any {
+	if examples := mediaExamples(media); len(examples) > 0 {
+		return examples[0].Value
+	}
+	if media.Schema != nil {
+		return GenerateValue(media.Schema.Value)
+	}
+	return nil
+}
+
+// serverURL returns the URL of server with its variables set to their
+// defaults
+func setKey(m map[string]string, key, value string) map[string]string {
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	return m
+}
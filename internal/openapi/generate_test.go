@@ -0,0 +1,203 @@
+package openapi
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/scenario"
+)
+
+const generateSpec = `openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+servers:
+  - url: https://{env}.example.com/v1
+    variables:
+      env: {default: api}
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [pets]
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer, example: 20}}
+        - {name: cursor, in: query, schema: {type: string}}
+      responses:
+        '200': {description: Pets}
+    post:
+      operationId: createPet
+      tags: [pets]
+      requestBody:
+        content:
+          application/json:
+            examples:
+              dog: {value: {name: Rex, kind: dog}}
+      responses:
+        '201': {description: Created}
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true, schema: {type: string, enum: [rex, tom]}}
+    delete:
+      tags: [pets]
+      responses:
+        '204': {description: Deleted}
+    put:
+      tags: [pets]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name: {type: string}
+                age: {type: integer, minimum: 2}
+      responses:
+        '200': {description: Updated}
+    get:
+      tags: [pets]
+      responses:
+        '200': {description: Pet}
+  /stores:
+    get:
+      tags: [stores]
+      responses:
+        '200': {description: Stores}`
+
+func TestGenerate(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(generateSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	sc, err := p.Generate(GenerateOptions{Tags: []string{"pets"}, VirtualUsers: 5, Duration: 30})
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	if sc.Name != "Pets" || sc.BaseURL != "https://api.example.com/v1" || sc.VirtualUsers != 5 || sc.Duration != 30 {
+		t.Errorf("unexpected scenario settings: %+v", sc)
+	}
+	if want := map[string]string{"limit": "20", "petId": "rex"}; !maps.Equal(sc.Variables, want) {
+		t.Errorf("expected variables %v, got %v", want, sc.Variables)
+	}
+
+	var requests []string
+	for _, step := range sc.Steps {
+		requests = append(requests, step.Request)
+	}
+	want := []string{"GET /pets", "POST /pets", "GET /pets/{petId}", "PUT /pets/{petId}", "DELETE /pets/{petId}"}
+	if len(requests) != len(want) {
+		t.Fatalf("expected steps %v, got %v", want, requests)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("step %d: expected %s, got %s", i, want[i], requests[i])
+		}
+	}
+
+	list, create, get, update := sc.Steps[0], sc.Steps[1], sc.Steps[2], sc.Steps[3]
+	if list.Name != "listPets" || !maps.Equal(list.Query, map[string]string{"limit": "${limit}"}) {
+		t.Errorf("unexpected list step: %+v", list)
+	}
+	if body, ok := create.Body.(map[string]any); !ok || body["name"] != "Rex" {
+		t.Errorf("expected the example body, got %v", create.Body)
+	}
+	if body, ok := update.Body.(map[string]any); !ok || body["name"] != "example" || body["age"] != int64(2) {
+		t.Errorf("expected a body generated from the schema, got %v", update.Body)
+	}
+	if !maps.Equal(get.PathParams, map[string]string{"petId": "${petId}"}) {
+		t.Errorf("unexpected path params: %v", get.PathParams)
+	}
+
+	data, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatalf("failed to marshal scenario: %v", err)
+	}
+	parser := scenario.NewParser()
+	if err := parser.ParseData(data); err != nil {
+		t.Fatalf("failed to parse generated scenario: %v", err)
+	}
+	if err := parser.Validate(); err != nil {
+		t.Errorf("generated scenario is invalid: %v\n%s", err, data)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(generateSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	if _, err := p.Generate(GenerateOptions{Tags: []string{"orders"}}); err == nil {
+		t.Error("expected error when no operation has the tags")
+	}
+
+	if err := p.ParseData([]byte(noTagsSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	if _, err := p.Generate(GenerateOptions{}); err == nil {
+		t.Error("expected error without servers or a base URL")
+	}
+	sc, err := p.Generate(GenerateOptions{BaseURL: "http://localhost:8080/"})
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	if sc.BaseURL != "http://localhost:8080" || sc.VirtualUsers != 1 || len(sc.Steps) != 1 {
+		t.Errorf("unexpected scenario: %+v", sc)
+	}
+
+	if _, err := New().Generate(GenerateOptions{}); err == nil {
+		t.Error("expected error without a document")
+	}
+}
+
+func TestGenerate_ServerVariables(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(strings.Replace(templatedServersSpec, "paths: {}",
+		"paths:\n  /health:\n    get:\n      responses:\n        '200': {description: OK}", 1))); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	sc, err := p.Generate(GenerateOptions{ServerVariables: map[string]string{"region": "us"}})
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	if sc.BaseURL != "https://us.api.example.com/v1" {
+		t.Errorf("expected the us server, got %s", sc.BaseURL)
+	}
+	if _, err := p.Generate(GenerateOptions{ServerVariables: map[string]string{"region": "ap"}}); err == nil {
+		t.Error("expected error for a region outside the enum")
+	}
+}
+
+func TestGenerate_Filters(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(filterSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	tests := []struct {
+		opts GenerateOptions
+		want []string
+	}{
+		{opts: GenerateOptions{}, want: []string{"GET /v2/orders", "POST /v2/orders", "GET /v2/users"}},
+		{opts: GenerateOptions{IncludeDeprecated: true, PathPrefixes: []string{"/v1"}}, want: []string{"GET /v1/orders"}},
+		{opts: GenerateOptions{Methods: []string{"GET"}, PathPrefixes: []string{"/v2/orders"}}, want: []string{"GET /v2/orders"}},
+	}
+	for _, tt := range tests {
+		sc, err := p.Generate(tt.opts)
+		if err != nil {
+			t.Fatalf("Generate(%+v) failed: %v", tt.opts, err)
+		}
+		var got []string
+		for _, step := range sc.Steps {
+			got = append(got, step.Request)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Generate(%+v): expected steps %v, got %v", tt.opts, tt.want, got)
+		}
+	}
+}
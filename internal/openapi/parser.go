@@ -3,10 +3,15 @@ package openapi
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"regexp"
 	"slices"
+	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+
+	"loadforge-agent/internal/scenario"
 )
 
 type Endpoint struct {
@@ -19,6 +24,75 @@ type Endpoint struct {
 	OperationID string
 	Summary     string
 	Description string
+	Deprecated  bool
+
+	// Parameters are those of the operation and of its path, the
+	// operation's overriding the path's
+	Parameters []Parameter
+}
+
+// Parameter describes a parameter of an endpoint
+type Parameter struct {
+	Name string
+	// In is where the parameter goes: path, query, header or cookie
+	In       string
+	Required bool
+	// Type is the type of the parameter's schema, e.g. integer; empty when
+	// the schema does not set one
+	Type string
+}
+
+// newParameters describes the parameters of op and its path item
+func newParameters(item *openapi3.PathItem, op *openapi3.Operation) []Parameter {
+	var params []Parameter
+	for _, param := range parameters(item, op) {
+		p := Parameter{Name: param.Name, In: param.In, Required: param.Required}
+		if param.Schema != nil && param.Schema.Value != nil && param.Schema.Value.Type != nil {
+			if types := param.Schema.Value.Type.Slice(); len(types) > 0 {
+				p.Type = types[0]
+			}
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// MissingParameters returns the required query and header parameters of
+// params that step does not set. Path parameters are part of the step's
+// request, and raw steps are sent as written, so neither is checked.
+func MissingParameters(params []Parameter, step *scenario.Step) []Parameter {
+	if step.Raw != "" {
+		return nil
+	}
+
+	// The request may carry part of the query, e.g. GET /users?page=1
+	query := make(map[string]bool)
+	for name := range step.Query {
+		query[name] = true
+	}
+	if _, rawQuery, ok := strings.Cut(step.Request, "?"); ok {
+		for _, pair := range strings.Split(rawQuery, "&") {
+			name, _, _ := strings.Cut(pair, "=")
+			query[name] = true
+		}
+	}
+	headers := make(map[string]bool)
+	for name := range step.Headers {
+		headers[http.CanonicalHeaderKey(name)] = true
+	}
+
+	var missing []Parameter
+	for _, p := range params {
+		if !p.Required {
+			continue
+		}
+		switch {
+		case p.In == openapi3.ParameterInQuery && !query[p.Name],
+			p.In == openapi3.ParameterInHeader && !headers[http.CanonicalHeaderKey(p.Name)]:
+			missing = append(missing, p)
+		}
+	}
+	return missing
 }
 
 type Parser struct {
@@ -111,6 +185,8 @@ func (p *Parser) GetEndpoints() ([]Endpoint, error) {
 				Summary:     operation.Summary,
 				Description: operation.Description,
 				Tags:        operation.Tags,
+				Deprecated:  operation.Deprecated,
+				Parameters:  newParameters(pathItem, operation),
 			}
 
 			endpoints = append(endpoints, endpoint)
@@ -181,8 +257,17 @@ func (p *Parser) GetInfo() (*openapi3.Info, error) {
 	return p.doc.Info, nil
 }
 
-// GetServerURLs returns all server URLs defined in the specification
+// GetServerURLs returns all server URLs defined in the specification, with
+// their variables set to their defaults
 func (p *Parser) GetServerURLs() ([]string, error) {
+	return p.ResolveServerURLs(nil)
+}
+
+// ResolveServerURLs returns all server URLs defined in the specification
+// with their variables, e.g. {region}, set to the values in vars, or else
+// to their defaults. A value must be one of the variable's enum, if it has
+// one; variables vars has no value for are left to their servers.
+func (p *Parser) ResolveServerURLs(vars map[string]string) ([]string, error) {
 	if p.doc == nil {
 		return nil, fmt.Errorf("no document loaded")
 	}
@@ -193,8 +278,42 @@ func (p *Parser) GetServerURLs() ([]string, error) {
 
 	urls := make([]string, 0, len(p.doc.Servers))
 	for _, server := range p.doc.Servers {
-		urls = append(urls, server.URL)
+		url, err := resolveServerURL(server, vars)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
 	}
 
 	return urls, nil
 }
+
+// serverVariable matches the variables in a server URL
+var serverVariable = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// resolveServerURL returns the URL of server with its variables set to the
+// values in vars, or else to their default or first enum value
+func resolveServerURL(server *openapi3.Server, vars map[string]string) (string, error) {
+	var err error
+	url := serverVariable.ReplaceAllStringFunc(server.URL, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		v := server.Variables[name]
+		value, ok := vars[name]
+		switch {
+		case ok && v != nil && len(v.Enum) > 0 && !slices.Contains(v.Enum, value):
+			err = fmt.Errorf("server %s: variable '%s' must be one of %v, got '%s'", server.URL, name, v.Enum, value)
+		case ok:
+		case v != nil && v.Default != "":
+			value = v.Default
+		case v != nil && len(v.Enum) > 0:
+			value = v.Enum[0]
+		default:
+			err = fmt.Errorf("server %s: variable '%s' has no default, supply a value", server.URL, name)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return url, nil
+}
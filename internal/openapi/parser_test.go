@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"slices"
 	"testing"
+
+	"loadforge-agent/internal/scenario"
 )
 
 // Test OpenAPI spec samples
@@ -609,3 +611,132 @@ func TestEndpointStruct(t *testing.T) {
 		t.Errorf("Expected tags ['users'], got %v", getUsersEndpoint.Tags)
 	}
 }
+
+func TestGetEndpoints_Parameters(t *testing.T) {
+	parser := New()
+	err := parser.ParseData([]byte(`openapi: 3.0.3
+info: {title: Test API, version: 1.0.0}
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+      - {name: X-Trace, in: header, schema: {type: string}}
+    get:
+      parameters:
+        - {name: X-Trace, in: header, required: true, schema: {type: string}}
+        - {name: fields, in: query, schema: {type: array, items: {type: string}}}
+      responses:
+        '200': {description: Success}
+`))
+	if err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	endpoints, err := parser.GetEndpoints()
+	if err != nil {
+		t.Fatalf("GetEndpoints() failed: %v", err)
+	}
+	if len(endpoints) != 1 {
+		t.Fatalf("Expected 1 endpoint, got %d", len(endpoints))
+	}
+
+	want := []Parameter{
+		{Name: "X-Trace", In: "header", Required: true, Type: "string"},
+		{Name: "fields", In: "query", Type: "array"},
+		{Name: "id", In: "path", Required: true, Type: "string"},
+	}
+	if !slices.Equal(endpoints[0].Parameters, want) {
+		t.Errorf("Expected parameters %+v, got %+v", want, endpoints[0].Parameters)
+	}
+}
+
+func TestMissingParameters(t *testing.T) {
+	params := []Parameter{
+		{Name: "id", In: "path", Required: true},
+		{Name: "page", In: "query", Required: true},
+		{Name: "X-Tenant", In: "header", Required: true},
+		{Name: "sort", In: "query"},
+	}
+
+	tests := []struct {
+		name string
+		step scenario.Step
+		want []string
+	}{
+		{
+			name: "none set",
+			step: scenario.Step{Request: "GET /users/{id}"},
+			want: []string{"page", "X-Tenant"},
+		},
+		{
+			name: "query and headers",
+			step: scenario.Step{
+				Request: "GET /users/{id}",
+				Query:   map[string]string{"page": "1"},
+				Headers: map[string]string{"x-tenant": "acme"},
+			},
+		},
+		{
+			name: "query in the request",
+			step: scenario.Step{Request: "GET /users/{id}?page=${page}", Headers: map[string]string{"X-Tenant": "acme"}},
+		},
+		{
+			name: "raw",
+			step: scenario.Step{Request: "GET /users/{id}", Raw: "GET /users/1 HTTP/1.1"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range MissingParameters(params, &tt.step) {
+			got = append(got, p.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected missing %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+const templatedServersSpec = `openapi: 3.0.3
+info: {title: Regional API, version: 1.0.0}
+servers:
+  - url: https://{region}.api.example.com/{version}
+    variables:
+      region: {default: eu, enum: [eu, us]}
+      version: {default: v1}
+  - url: https://{tenant}.example.com
+    variables:
+      tenant: {default: acme}
+paths: {}`
+
+func TestResolveServerURLs(t *testing.T) {
+	parser := New()
+	if err := parser.ParseData([]byte(templatedServersSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		vars    map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{nil, []string{"https://eu.api.example.com/v1", "https://acme.example.com"}, false},
+		{map[string]string{"region": "us", "version": "v2", "tenant": "globex"},
+			[]string{"https://us.api.example.com/v2", "https://globex.example.com"}, false},
+		{map[string]string{"version": "v2"}, []string{"https://eu.api.example.com/v2", "https://acme.example.com"}, false},
+		{map[string]string{"region": "ap"}, nil, true},
+	} {
+		urls, err := parser.ResolveServerURLs(tt.vars)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: expected error %v, got %v", tt.vars, tt.wantErr, err)
+			continue
+		}
+		if !slices.Equal(urls, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.vars, tt.want, urls)
+		}
+	}
+
+	urls, err := parser.GetServerURLs()
+	if err != nil || !slices.Equal(urls, []string{"https://eu.api.example.com/v1", "https://acme.example.com"}) {
+		t.Errorf("expected the servers with their defaults, got %v, %v", urls, err)
+	}
+}
@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// GetRequestSchema returns the schema of the JSON request body of the
+// operation with method at path, a template of the document such as
+// /users/{id} or a concrete path. It returns nil when the operation takes no
+// JSON body.
+func (p *Parser) GetRequestSchema(path, method string) (*openapi3.Schema, error) {
+	op, err := p.operation(path, method)
+	if err != nil {
+		return nil, err
+	}
+	return op.RequestSchema(), nil
+}
+
+// GetResponseSchema returns the schema of the JSON body of the response
+// with status of the operation with method at path, see GetRequestSchema.
+// It returns an error when the status is not documented and nil when its
+// response has no JSON body.
+func (p *Parser) GetResponseSchema(path, method string, status int) (*openapi3.Schema, error) {
+	op, err := p.operation(path, method)
+	if err != nil {
+		return nil, err
+	}
+	resp := op.response(status)
+	if resp == nil {
+		return nil, fmt.Errorf("%s: status %d is not documented", op, status)
+	}
+	return schemaOf(jsonMedia(resp.Content)), nil
+}
+
+// operation returns the operation with method at path, or an error if the
+// document has none
+func (p *Parser) operation(path, method string) (*Operation, error) {
+	op, err := p.FindOperation(method, path)
+	if err != nil {
+		return nil, err
+	}
+	if op == nil {
+		return nil, fmt.Errorf("no operation %s %s in the document", method, path)
+	}
+	return op, nil
+}
+
+// RequestSchema returns the schema of the operation's JSON request body,
+// nil when it takes none
+func (o *Operation) RequestSchema() *openapi3.Schema {
+	return schemaOf(o.requestMedia())
+}
+
+// requestMedia returns the JSON media type of the operation's request
+// body, nil when it takes none
+func (o *Operation) requestMedia() *openapi3.MediaType {
+	if o.op.RequestBody == nil || o.op.RequestBody.Value == nil {
+		return nil
+	}
+	return jsonMedia(o.op.RequestBody.Value.Content)
+}
+
+// response returns the response the operation documents for status: that
+// of the status itself, of its range such as 2XX, or the default one
+func (o *Operation) response(status int) *openapi3.Response {
+	if o.op.Responses == nil {
+		return nil
+	}
+	ref := o.op.Responses.Status(status)
+	if ref == nil {
+		ref = o.op.Responses.Default()
+	}
+	if ref == nil {
+		return nil
+	}
+	return ref.Value
+}
+
+// jsonMedia returns the application/json media type of content, or else
+// its first JSON one such as application/problem+json
+func jsonMedia(content openapi3.Content) *openapi3.MediaType {
+	if media := content.Get("application/json"); media != nil {
+		return media
+	}
+	for _, name := range slices.Sorted(maps.Keys(content)) {
+		if isJSON(name) {
+			return content[name]
+		}
+	}
+	return nil
+}
+
+func schemaOf(media *openapi3.MediaType) *openapi3.Schema {
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+	return media.Schema.Value
+}
@@ -0,0 +1,119 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const schemaSpec = `openapi: 3.0.3
+info: {title: Users, version: 1.0.0}
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/User'}
+      responses:
+        '201':
+          description: Created
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+        4XX:
+          description: Client error
+          content:
+            application/problem+json:
+              schema:
+                type: object
+                properties:
+                  title: {type: string}
+        default:
+          description: Error
+          content:
+            text/plain:
+              schema: {type: string}
+  /users/{id}:
+    delete:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        '204': {description: Deleted}
+components:
+  schemas:
+    User:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+`
+
+func TestGetRequestSchema(t *testing.T) {
+	parser := New()
+	if err := parser.ParseData([]byte(schemaSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	schema, err := parser.GetRequestSchema("/users", "POST")
+	if err != nil {
+		t.Fatalf("GetRequestSchema() failed: %v", err)
+	}
+	if schema == nil || !schema.Type.Is(openapi3.TypeObject) || schema.Properties["name"] == nil {
+		t.Errorf("expected the resolved User schema, got %+v", schema)
+	}
+
+	schema, err = parser.GetRequestSchema("/users/42", "delete")
+	if err != nil || schema != nil {
+		t.Errorf("expected no schema for a request without body, got %+v, %v", schema, err)
+	}
+
+	if _, err := parser.GetRequestSchema("/orders", "POST"); err == nil {
+		t.Error("expected error for an operation not in the document, got nil")
+	}
+}
+
+func TestGetResponseSchema(t *testing.T) {
+	parser := New()
+	if err := parser.ParseData([]byte(schemaSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		method   string
+		status   int
+		property string
+		wantNil  bool
+		wantErr  string
+	}{
+		{path: "/users", method: "POST", status: 201, property: "name"},
+		{path: "/users", method: "POST", status: 422, property: "title"},
+		{path: "/users", method: "POST", status: 500, wantNil: true},
+		{path: "/users/{id}", method: "DELETE", status: 204, wantNil: true},
+		{path: "/users/{id}", method: "DELETE", status: 404, wantErr: "status 404 is not documented"},
+	}
+	for _, tt := range tests {
+		schema, err := parser.GetResponseSchema(tt.path, tt.method, tt.status)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s %s %d: expected error %q, got %v", tt.method, tt.path, tt.status, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s %d: unexpected error: %v", tt.method, tt.path, tt.status, err)
+			continue
+		}
+		if tt.wantNil {
+			if schema != nil {
+				t.Errorf("%s %s %d: expected no JSON schema, got %+v", tt.method, tt.path, tt.status, schema)
+			}
+			continue
+		}
+		if schema == nil || schema.Properties[tt.property] == nil {
+			t.Errorf("%s %s %d: expected a schema with property %s, got %+v", tt.method, tt.path, tt.status, tt.property, schema)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package openapi
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Auth kinds: the type of a security scheme, or the scheme of an HTTP
+// security scheme
+const (
+	AuthBearer        = "bearer"
+	AuthBasic         = "basic"
+	AuthAPIKey        = "apiKey"
+	AuthOAuth2        = "oauth2"
+	AuthOpenIDConnect = "openIdConnect"
+)
+
+// Auth is a security scheme of the document as an operation requires it
+type Auth struct {
+	// Scheme is the name of the security scheme in the document
+	Scheme string
+	Kind   string
+	// In and Name locate the key of an AuthAPIKey scheme, e.g. the header
+	// X-API-Key; In is one of header, query and cookie
+	In   string
+	Name string
+	// Scopes are the OAuth2 scopes the operation requires
+	Scopes []string
+	// TokenURL is the token endpoint of an AuthOAuth2 scheme's client
+	// credentials or password flow, empty when it has neither
+	TokenURL string
+}
+
+// Security returns the ways requests to the operation may authenticate:
+// any one of the alternatives, each a set of schemes that must all be
+// provided. It is nil when the operation requires no authentication. The
+// operation's security requirements override the document's.
+func (o *Operation) Security() [][]Auth {
+	return security(o.doc, o.op)
+}
+
+// SecuritySchemes returns the security schemes of the document by name
+func (p *Parser) SecuritySchemes() map[string]Auth {
+	if p.doc == nil || p.doc.Components == nil {
+		return nil
+	}
+	schemes := make(map[string]Auth, len(p.doc.Components.SecuritySchemes))
+	for name, ref := range p.doc.Components.SecuritySchemes {
+		if ref != nil && ref.Value != nil {
+			schemes[name] = newAuth(name, ref.Value, nil)
+		}
+	}
+	return schemes
+}
+
+func security(doc *openapi3.T, op *openapi3.Operation) [][]Auth {
+	requirements := doc.Security
+	if op.Security != nil {
+		requirements = *op.Security
+	}
+
+	var alternatives [][]Auth
+	for _, req := range requirements {
+		// An empty requirement makes authentication optional
+		if len(req) == 0 {
+			return nil
+		}
+		var auths []Auth
+		for _, name := range slices.Sorted(maps.Keys(req)) {
+			if scheme := securityScheme(doc, name); scheme != nil {
+				auths = append(auths, newAuth(name, scheme, req[name]))
+			}
+		}
+		alternatives = append(alternatives, auths)
+	}
+	return alternatives
+}
+
+func securityScheme(doc *openapi3.T, name string) *openapi3.SecurityScheme {
+	if doc.Components == nil {
+		return nil
+	}
+	if ref := doc.Components.SecuritySchemes[name]; ref != nil {
+		return ref.Value
+	}
+	return nil
+}
+
+func newAuth(name string, scheme *openapi3.SecurityScheme, scopes []string) Auth {
+	auth := Auth{Scheme: name, Kind: scheme.Type, Scopes: scopes}
+	switch scheme.Type {
+	case "http":
+		// e.g. AuthBearer or AuthBasic
+		auth.Kind = strings.ToLower(scheme.Scheme)
+	case "apiKey":
+		auth.In, auth.Name = scheme.In, scheme.Name
+	case "oauth2":
+		if flows := scheme.Flows; flows != nil {
+			for _, flow := range []*openapi3.OAuthFlow{flows.ClientCredentials, flows.Password} {
+				if flow != nil && auth.TokenURL == "" {
+					auth.TokenURL = flow.TokenURL
+				}
+			}
+		}
+	}
+	return auth
+}
@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/scenario"
+)
+
+const securitySpec = `openapi: 3.0.3
+info: {title: Secure, version: 1.0.0}
+servers: [{url: "https://api.example.com"}]
+security: [{bearerAuth: []}]
+components:
+  securitySchemes:
+    bearerAuth: {type: http, scheme: bearer, bearerFormat: JWT}
+    basic: {type: http, scheme: basic}
+    api-key: {type: apiKey, in: header, name: X-API-Key}
+    token: {type: apiKey, in: query, name: token}
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://auth.example.com/token
+          scopes: {read: Read, write: Write}
+paths:
+  /me:
+    get:
+      responses:
+        '200': {description: Me}
+  /health:
+    get:
+      security: []
+      responses:
+        '200': {description: OK}
+  /reports:
+    get:
+      security: [{api-key: []}, {oauth: [read]}]
+      responses:
+        '200': {description: Reports}
+  /exports:
+    post:
+      security: [{basic: [], token: []}]
+      responses:
+        '201': {description: Exported}`
+
+func TestOperationSecurity(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(securitySpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	bearer := Auth{Scheme: "bearerAuth", Kind: AuthBearer}
+	apiKey := Auth{Scheme: "api-key", Kind: AuthAPIKey, In: "header", Name: "X-API-Key"}
+	oauth := Auth{Scheme: "oauth", Kind: AuthOAuth2, Scopes: []string{"read"}, TokenURL: "https://auth.example.com/token"}
+	basic := Auth{Scheme: "basic", Kind: AuthBasic, Scopes: []string{}}
+	token := Auth{Scheme: "token", Kind: AuthAPIKey, In: "query", Name: "token", Scopes: []string{}}
+	for _, tt := range []struct {
+		method, path string
+		want         [][]Auth
+	}{
+		{"GET", "/me", [][]Auth{{withScopes(bearer)}}},
+		{"GET", "/health", nil},
+		{"GET", "/reports", [][]Auth{{withScopes(apiKey)}, {oauth}}},
+		{"POST", "/exports", [][]Auth{{basic, token}}},
+	} {
+		op, err := p.FindOperation(tt.method, tt.path)
+		if err != nil || op == nil {
+			t.Fatalf("FindOperation(%s, %s) = %v, %v", tt.method, tt.path, op, err)
+		}
+		if got := op.Security(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s: expected %+v, got %+v", tt.method, tt.path, tt.want, got)
+		}
+	}
+
+	schemes := p.SecuritySchemes()
+	if len(schemes) != 5 || schemes["oauth"].TokenURL != "https://auth.example.com/token" || schemes["basic"].Kind != AuthBasic {
+		t.Errorf("unexpected security schemes: %+v", schemes)
+	}
+}
+
+func withScopes(a Auth) Auth {
+	a.Scopes = []string{}
+	return a
+}
+
+func TestGenerate_Security(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(securitySpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	sc, err := p.Generate(GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	want := map[string]string{"bearerAuth": "env:BEARERAUTH", "api-key": "env:API_KEY", "basic": "env:BASIC", "token": "env:TOKEN"}
+	if !maps.Equal(sc.Secrets, want) {
+		t.Errorf("expected secrets %v, got %v", want, sc.Secrets)
+	}
+
+	byRequest := make(map[string]map[string]string)
+	for _, step := range sc.Steps {
+		byRequest[step.Request] = step.Headers
+	}
+	for request, headers := range map[string]map[string]string{
+		"POST /exports": {"Authorization": "Basic ${secrets.basic}"},
+		"GET /health":   nil,
+		"GET /me":       {"Authorization": "Bearer ${secrets.bearerAuth}"},
+		"GET /reports":  {"X-API-Key": "${secrets.api-key}"},
+	} {
+		if !maps.Equal(byRequest[request], headers) {
+			t.Errorf("%s: expected headers %v, got %v", request, headers, byRequest[request])
+		}
+	}
+	if q := sc.Steps[0].Query; sc.Steps[0].Request != "POST /exports" || q["token"] != "${secrets.token}" {
+		t.Errorf("expected the token in the query of POST /exports, got %+v", sc.Steps[0])
+	}
+
+	data, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatalf("failed to marshal scenario: %v", err)
+	}
+	parser := scenario.NewParser()
+	if err := parser.ParseData(data); err != nil {
+		t.Fatalf("failed to parse generated scenario: %v", err)
+	}
+	if err := parser.Validate(); err != nil {
+		t.Errorf("generated scenario is invalid: %v\n%s", err, data)
+	}
+}
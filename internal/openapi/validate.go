@@ -0,0 +1,150 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Operation is an operation of the document that responses can be
+// validated against
+type Operation struct {
+	Method string
+	// Path is the path template of the operation in the document
+	Path string
+
+	op   *openapi3.Operation
+	item *openapi3.PathItem
+	doc  *openapi3.T
+}
+
+func (o *Operation) String() string {
+	return o.Method + " " + o.Path
+}
+
+// Parameters describes the parameters of the operation, see Endpoint
+func (o *Operation) Parameters() []Parameter {
+	return newParameters(o.item, o.op)
+}
+
+// FindOperation returns the operation a request with method to path goes
+// to. path is relative to the document's servers, without a query, and may
+// be concrete or a template; its parameters match those of the document
+// whatever their names. Concrete paths of the document are preferred over
+// templated ones.
+func (p *Parser) FindOperation(method, path string) (*Operation, error) {
+	if p.doc == nil {
+		return nil, fmt.Errorf("no document loaded")
+	}
+	if p.doc.Paths == nil {
+		return nil, nil
+	}
+
+	method = strings.ToUpper(method)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, template := range p.doc.Paths.InMatchingOrder() {
+		if !matchPath(strings.Split(strings.Trim(template, "/"), "/"), segments) {
+			continue
+		}
+		item := p.doc.Paths.Value(template)
+		if op := item.GetOperation(method); op != nil {
+			return &Operation{Method: method, Path: template, op: op, item: item, doc: p.doc}, nil
+		}
+	}
+	return nil, nil
+}
+
+// matchPath reports whether the segments of a path match those of a
+// template. A parameter of the template matches any segment, one of the
+// path only another parameter.
+func matchPath(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+	for i, s := range template {
+		if isParam(s) {
+			continue
+		}
+		if s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// ValidateResponse returns how a response with status, header and body
+// deviates from the responses the operation defines, nil when it conforms.
+// Bodies are validated against the schema of their content type when it is
+// JSON.
+func (o *Operation) ValidateResponse(status int, header http.Header, body []byte) []string {
+	if o.op.Responses == nil {
+		return nil
+	}
+	resp := o.response(status)
+	if resp == nil {
+		return []string{fmt.Sprintf("status %d is not documented", status)}
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+
+	contentType := header.Get("Content-Type")
+	media := resp.Content.Get(contentType)
+	if media == nil {
+		return []string{fmt.Sprintf("status %d: content type %q is not documented", status, contentType)}
+	}
+	if media.Schema == nil || media.Schema.Value == nil || !isJSON(contentType) {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("status %d: body is not JSON: %v", status, err)}
+	}
+	errs := SchemaErrors(media.Schema.Value.VisitJSON(value, openapi3.MultiErrors(), openapi3.VisitAsResponse()))
+	for i, e := range errs {
+		errs[i] = fmt.Sprintf("status %d: %s", status, e)
+	}
+	return errs
+}
+
+// isJSON reports whether contentType is JSON, e.g. application/json or
+// application/problem+json. Responses without one are taken as JSON.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	media, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (media == "application/json" || strings.HasSuffix(media, "+json"))
+}
+
+// SchemaErrors flattens an error of openapi3.Schema.VisitJSON into one
+// message per failure, each prefixed with the JSON pointer of the value
+// that failed, e.g. "/user/id: value must be an integer"
+func SchemaErrors(err error) []string {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var msgs []string
+		for _, err := range e {
+			msgs = append(msgs, SchemaErrors(err)...)
+		}
+		return msgs
+	case *openapi3.SchemaError:
+		reason := e.Reason
+		if reason == "" && e.Origin != nil {
+			reason = e.Origin.Error()
+		}
+		return []string{"/" + strings.Join(e.JSONPointer(), "/") + ": " + reason}
+	case nil:
+		return nil
+	}
+	return []string{err.Error()}
+}
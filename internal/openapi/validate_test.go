@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const contractSpec = `openapi: 3.0.3
+info:
+  title: Contract API
+  version: 1.0.0
+paths:
+  /users/me:
+    get:
+      responses:
+        '200':
+          description: Current user
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer}}
+    get:
+      responses:
+        '200':
+          description: User
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id, name]
+                properties:
+                  id: {type: integer}
+                  name: {type: string}
+        4XX:
+          description: Error
+          content:
+            application/problem+json:
+              schema:
+                type: object
+                required: [title]
+                properties:
+                  title: {type: string}
+  /orders:
+    post:
+      responses:
+        '201':
+          description: Created
+        default:
+          description: Error`
+
+func TestFindOperation(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(contractSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{"GET", "/users/42", "GET /users/{id}"},
+		{"get", "/users/{user_id}", "GET /users/{id}"},
+		{"GET", "/users/me", "GET /users/me"},
+		{"POST", "/orders/", "POST /orders"},
+		{"DELETE", "/users/42", ""},
+		{"GET", "/users/42/orders", ""},
+	}
+	for _, tt := range tests {
+		op, err := p.FindOperation(tt.method, tt.path)
+		if err != nil {
+			t.Fatalf("FindOperation(%s, %s) failed: %v", tt.method, tt.path, err)
+		}
+		var got string
+		if op != nil {
+			got = op.String()
+		}
+		if got != tt.want {
+			t.Errorf("FindOperation(%s, %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := New().FindOperation("GET", "/"); err == nil {
+		t.Error("expected error without a document")
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	p := New()
+	if err := p.ParseData([]byte(contractSpec)); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	user, _ := p.FindOperation("GET", "/users/{id}")
+	orders, _ := p.FindOperation("POST", "/orders")
+
+	json := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+	problem := http.Header{"Content-Type": {"application/problem+json"}}
+	tests := []struct {
+		name   string
+		op     *Operation
+		status int
+		header http.Header
+		body   string
+		want   []string
+	}{
+		{"valid", user, 200, json, `{"id": 1, "name": "alice"}`, nil},
+		{"invalid body", user, 200, json, `{"id": "1"}`, []string{"status 200: /id: ", "status 200: /name: "}},
+		{"not JSON", user, 200, json, `<html>`, []string{"status 200: body is not JSON"}},
+		{"status range", user, 404, problem, `{"title": "not found"}`, nil},
+		{"undocumented content type", user, 404, json, `{}`, []string{`status 404: content type "application/json`}},
+		{"undocumented status", user, 500, json, `{}`, []string{"status 500 is not documented"}},
+		{"no content", orders, 201, nil, ``, nil},
+		{"default", orders, 503, nil, `oops`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.op.ValidateResponse(tt.status, tt.header, []byte(tt.body))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d errors, got %q", len(tt.want), got)
+			}
+			for i, prefix := range tt.want {
+				if !strings.HasPrefix(got[i], prefix) {
+					t.Errorf("expected error %d to start with %q, got %q", i, prefix, got[i])
+				}
+			}
+		})
+	}
+}
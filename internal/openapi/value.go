@@ -0,0 +1,178 @@
+package openapi
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxValueDepth is the nesting below which GenerateValue fills in required
+// properties and minItems items only, so that recursive schemas end
+const maxValueDepth = 4
+
+// formatExamples are the strings generated for the string formats
+var formatExamples = map[string]string{
+	"date-time": "2024-01-01T00:00:00Z",
+	"date":      "2024-01-01",
+	"time":      "12:00:00",
+	"email":     "user@example.com",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"uri":       "https://example.com",
+	"url":       "https://example.com",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"byte":      "ZXhhbXBsZQ==",
+}
+
+// GenerateValue returns a value that validates against schema as a
+// request, for request bodies without examples. A schema's example,
+// default or first enum value is used when it has one. Otherwise objects
+// get their properties except read-only ones, arrays minItems items (at
+// least one), and strings and numbers a value of their format within
+// their bounds; patterns are not honoured. oneOf and anyOf take their
+// first schema, allOf merges its schemas.
+func GenerateValue(schema *openapi3.Schema) any {
+	return generateValue(schema, 0)
+}
+
+func generateValue(schema *openapi3.Schema, depth int) any {
+	switch {
+	case schema == nil || depth > 2*maxValueDepth:
+		return nil
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		return generateAllOf(schema, depth)
+	case len(schema.OneOf) > 0 && schema.OneOf[0].Value != nil:
+		return generateValue(schema.OneOf[0].Value, depth+1)
+	case len(schema.AnyOf) > 0 && schema.AnyOf[0].Value != nil:
+		return generateValue(schema.AnyOf[0].Value, depth+1)
+	}
+
+	switch {
+	case schema.Type.Includes(openapi3.TypeObject), schema.Type == nil && len(schema.Properties) > 0:
+		return generateObject(schema, depth)
+	case schema.Type.Includes(openapi3.TypeArray):
+		return generateArray(schema, depth)
+	case schema.Type.Includes(openapi3.TypeString):
+		return generateString(schema)
+	case schema.Type.Includes(openapi3.TypeInteger):
+		return int64(generateNumber(schema, true))
+	case schema.Type.Includes(openapi3.TypeNumber):
+		return generateNumber(schema, false)
+	case schema.Type.Includes(openapi3.TypeBoolean):
+		return true
+	}
+	return nil
+}
+
+// generateAllOf merges the objects generated for the schemas of allOf and
+// the properties of schema itself; for other values the last one wins
+func generateAllOf(schema *openapi3.Schema, depth int) any {
+	var value any
+	merged := generateObject(schema, depth)
+	for _, ref := range schema.AllOf {
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		v := generateValue(ref.Value, depth+1)
+		if obj, ok := v.(map[string]any); ok {
+			maps.Copy(merged, obj)
+			v = merged
+		}
+		value = v
+	}
+	return value
+}
+
+func generateObject(schema *openapi3.Schema, depth int) map[string]any {
+	obj := make(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+		ref := schema.Properties[name]
+		if ref == nil || ref.Value == nil || ref.Value.ReadOnly {
+			continue
+		}
+		if depth >= maxValueDepth && !slices.Contains(schema.Required, name) {
+			continue
+		}
+		obj[name] = generateValue(ref.Value, depth+1)
+	}
+	return obj
+}
+
+func generateArray(schema *openapi3.Schema, depth int) []any {
+	n := max(schema.MinItems, 1)
+	if depth >= maxValueDepth {
+		n = schema.MinItems
+	}
+	if schema.MaxItems != nil {
+		n = min(n, *schema.MaxItems)
+	}
+	items := make([]any, 0, n)
+	if schema.Items == nil || schema.Items.Value == nil {
+		return items
+	}
+	for range n {
+		items = append(items, generateValue(schema.Items.Value, depth+1))
+	}
+	return items
+}
+
+func generateString(schema *openapi3.Schema) string {
+	s := cmp.Or(formatExamples[schema.Format], "example")
+	if n := int(schema.MinLength); len(s) < n {
+		s += strings.Repeat("x", n-len(s))
+	}
+	if schema.MaxLength != nil && uint64(len(s)) > *schema.MaxLength {
+		s = s[:*schema.MaxLength]
+	}
+	return s
+}
+
+// generateNumber returns 1, or the closest value to it within the bounds
+// of schema that is a multiple of its multipleOf
+func generateNumber(schema *openapi3.Schema, integer bool) float64 {
+	// step moves a value off an exclusive bound
+	step := 1.0
+	if !integer && schema.Min != nil && schema.Max != nil {
+		step = (*schema.Max - *schema.Min) / 2
+	}
+
+	v := 1.0
+	switch {
+	case schema.Min != nil && (v < *schema.Min || v == *schema.Min && schema.ExclusiveMin):
+		v = *schema.Min
+		if schema.ExclusiveMin {
+			v += step
+		}
+		if integer {
+			v = math.Ceil(v)
+		}
+	case schema.Max != nil && (v > *schema.Max || v == *schema.Max && schema.ExclusiveMax):
+		v = *schema.Max
+		if schema.ExclusiveMax {
+			v -= step
+		}
+		if integer {
+			v = math.Floor(v)
+		}
+	}
+
+	if m := schema.MultipleOf; m != nil && *m > 0 {
+		if schema.Max != nil && schema.Min == nil {
+			v = math.Floor(v / *m) * *m
+		} else {
+			v = math.Ceil(v / *m) * *m
+		}
+	}
+	return v
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestGenerateValue(t *testing.T) {
+	for _, tt := range []struct {
+		schema string
+		want   any
+	}{
+		{`{"type": "string"}`, "example"},
+		{`{"type": "string", "format": "email"}`, "user@example.com"},
+		{`{"type": "string", "format": "date-time"}`, "2024-01-01T00:00:00Z"},
+		{`{"type": "string", "minLength": 10}`, "examplexxx"},
+		{`{"type": "string", "maxLength": 3}`, "exa"},
+		{`{"type": "string", "enum": ["small", "large"]}`, "small"},
+		{`{"type": "string", "example": "Rex", "default": "Tom"}`, "Rex"},
+		{`{"type": "integer"}`, int64(1)},
+		{`{"type": "integer", "minimum": 18}`, int64(18)},
+		{`{"type": "integer", "minimum": 1, "exclusiveMinimum": true}`, int64(2)},
+		{`{"type": "integer", "maximum": -5}`, int64(-5)},
+		{`{"type": "integer", "minimum": 3, "multipleOf": 5}`, int64(5)},
+		{`{"type": "number", "minimum": 0, "maximum": 1, "exclusiveMaximum": true}`, 0.5},
+		{`{"type": "number", "minimum": 2.5}`, 2.5},
+		{`{"type": "boolean"}`, true},
+		{`{"type": "array", "items": {"type": "integer"}, "minItems": 2}`, []any{int64(1), int64(1)}},
+		{`{"type": "array", "items": {"type": "string"}}`, []any{"example"}},
+		{`{"type": "object", "required": ["name"], "properties": {
+			"id": {"type": "integer", "readOnly": true},
+			"name": {"type": "string", "minLength": 1},
+			"size": {"type": "string", "enum": ["s", "m"]}}}`,
+			map[string]any{"name": "example", "size": "s"}},
+		{`{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, int64(1)},
+		{`{"allOf": [
+			{"type": "object", "properties": {"a": {"type": "integer"}}},
+			{"type": "object", "properties": {"b": {"type": "boolean"}}}]}`,
+			map[string]any{"a": int64(1), "b": true}},
+	} {
+		schema := &openapi3.Schema{}
+		if err := json.Unmarshal([]byte(tt.schema), schema); err != nil {
+			t.Fatalf("invalid schema %s: %v", tt.schema, err)
+		}
+		got := GenerateValue(schema)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %#v, got %#v", tt.schema, tt.want, got)
+		}
+
+		// Round-trip through JSON, as the value is sent
+		data, err := json.Marshal(got)
+		if err != nil {
+			t.Fatalf("%s: failed to marshal %v: %v", tt.schema, got, err)
+		}
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			t.Fatalf("%s: failed to unmarshal %s: %v", tt.schema, data, err)
+		}
+		if errs := SchemaErrors(schema.VisitJSON(value, openapi3.MultiErrors(), openapi3.VisitAsRequest())); errs != nil {
+			t.Errorf("%s: %s does not validate: %v", tt.schema, data, errs)
+		}
+	}
+}
+
+func TestGenerateValue_Recursive(t *testing.T) {
+	node := &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeObject}, Required: []string{"name"}}
+	node.Properties = openapi3.Schemas{
+		"name":     openapi3.NewStringSchema().NewRef(),
+		"children": openapi3.NewArraySchema().WithItems(node).NewRef(),
+		"parent":   &openapi3.SchemaRef{Value: node},
+	}
+
+	depth := 0
+	for v, ok := GenerateValue(node).(map[string]any); ok; v, ok = v["parent"].(map[string]any) {
+		if v["name"] != "example" {
+			t.Fatalf("expected the required name at depth %d, got %v", depth, v)
+		}
+		depth++
+	}
+	if depth != maxValueDepth+1 {
+		t.Errorf("expected objects nested %d deep, got %d", maxValueDepth+1, depth)
+	}
+}
@@ -0,0 +1,94 @@
+package payload
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File formats holding request bodies
+const (
+	// FormatJSON is a JSON array whose elements are the bodies
+	FormatJSON = "json"
+	// FormatJSONL holds one body per line
+	FormatJSONL = "jsonl"
+)
+
+// FileFormat infers the format of a body file from its extension
+func FileFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("cannot infer the format of '%s', use a .json or .jsonl file", path)
+	}
+}
+
+// LoadFile reads the bodies of a JSON array or JSONL file. Bodies are
+// compacted and kept in memory so picking one never touches the disk while
+// requests are measured.
+func LoadFile(path string) ([][]byte, error) {
+	format, err := FileFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload file: %w", err)
+	}
+
+	var bodies [][]byte
+	if format == FormatJSON {
+		bodies, err = parseArray(data)
+	} else {
+		bodies, err = parseLines(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(bodies) == 0 {
+		return nil, fmt.Errorf("%s: no bodies", path)
+	}
+	return bodies, nil
+}
+
+func parseArray(data []byte) ([][]byte, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, fmt.Errorf("expected a JSON array: %w", err)
+	}
+
+	bodies := make([][]byte, len(elements))
+	for i, e := range elements {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, e); err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		bodies[i] = buf.Bytes()
+	}
+	return bodies, nil
+}
+
+func parseLines(data []byte) ([][]byte, error) {
+	var bodies [][]byte
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, MaxSize)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		bodies = append(bodies, buf.Bytes())
+	}
+	return bodies, sc.Err()
+}
@@ -0,0 +1,57 @@
+package payload
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"users.json", `[{"name": "alice"}, {"name": "bob", "tags": [1, 2]}]`,
+			[]string{`{"name":"alice"}`, `{"name":"bob","tags":[1,2]}`}},
+		{"users.jsonl", "{\"name\": \"alice\"}\n\n{\"name\": \"bob\"}\r\n",
+			[]string{`{"name":"alice"}`, `{"name":"bob"}`}},
+	}
+
+	for _, tt := range tests {
+		bodies, err := LoadFile(writeFile(t, tt.name, tt.content))
+		if err != nil {
+			t.Fatalf("%s: LoadFile() failed: %v", tt.name, err)
+		}
+		if len(bodies) != len(tt.want) {
+			t.Fatalf("%s: expected %d bodies, got %d", tt.name, len(tt.want), len(bodies))
+		}
+		for i, want := range tt.want {
+			if string(bodies[i]) != want {
+				t.Errorf("%s: body %d: expected %s, got %s", tt.name, i, want, bodies[i])
+			}
+		}
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"object.json":  `{"name": "alice"}`,
+		"empty.json":   `[]`,
+		"broken.jsonl": "{\"name\": \"alice\"}\n{\"name\": \n",
+		"users.csv":    "name\nalice\n",
+	} {
+		if _, err := LoadFile(writeFile(t, name, content)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
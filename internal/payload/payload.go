@@ -0,0 +1,107 @@
+// Package payload generates synthetic request bodies of a given size and
+// shape, for tests exploring how payload size affects latency.
+package payload
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+)
+
+// MaxSize is the largest payload that can be generated
+const MaxSize = 64 << 20
+
+// letters fill JSON string values. They never need escaping, so the
+// generated document has exactly the requested size.
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// JSON returns a JSON document of exactly size bytes. It is an object with
+// fields string fields per level, nested depth levels deep under the key
+// "nested"; the string values are filled with random letters to reach size.
+func JSON(size, depth, fields int) ([]byte, error) {
+	if err := checkSize(size); err != nil {
+		return nil, err
+	}
+	if depth < 1 {
+		return nil, fmt.Errorf("depth must be at least 1, got %d", depth)
+	}
+	if fields < 1 {
+		return nil, fmt.Errorf("fields must be at least 1, got %d", fields)
+	}
+
+	var skeleton bytes.Buffer
+	writeObject(&skeleton, depth, fields, func(int) int { return 0 })
+	if skeleton.Len() > size {
+		return nil, fmt.Errorf("size %d is smaller than the %d bytes needed for depth %d with %d fields",
+			size, skeleton.Len(), depth, fields)
+	}
+
+	// Spread the remaining bytes evenly over the string values
+	values := depth * fields
+	fill := size - skeleton.Len()
+	length := func(i int) int {
+		if i < fill%values {
+			return fill/values + 1
+		}
+		return fill / values
+	}
+
+	var doc bytes.Buffer
+	doc.Grow(size)
+	writeObject(&doc, depth, fields, length)
+	return doc.Bytes(), nil
+}
+
+// writeObject writes depth nested objects, the i-th string value being
+// length(i) random letters long
+func writeObject(buf *bytes.Buffer, depth, fields int, length func(i int) int) {
+	value := 0
+	for level := range depth {
+		buf.WriteByte('{')
+		for f := range fields {
+			if f > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`"field` + strconv.Itoa(f+1) + `":"`)
+			for range length(value) {
+				buf.WriteByte(letters[rand.IntN(len(letters))])
+			}
+			buf.WriteByte('"')
+			value++
+		}
+		if level < depth-1 {
+			buf.WriteString(`,"nested":`)
+		}
+	}
+	for range depth {
+		buf.WriteByte('}')
+	}
+}
+
+// Binary returns size random bytes
+func Binary(size int) ([]byte, error) {
+	if err := checkSize(size); err != nil {
+		return nil, err
+	}
+
+	blob := make([]byte, size)
+	for i := 0; i < size; i += 8 {
+		n := rand.Uint64()
+		for j := i; j < min(i+8, size); j++ {
+			blob[j] = byte(n)
+			n >>= 8
+		}
+	}
+	return blob, nil
+}
+
+func checkSize(size int) error {
+	if size <= 0 {
+		return fmt.Errorf("size must be greater than 0")
+	}
+	if size > MaxSize {
+		return fmt.Errorf("size must not exceed %d bytes", MaxSize)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package payload
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		size, depth, fields int
+	}{
+		{size: 1024, depth: 1, fields: 4},
+		{size: 64 << 10, depth: 5, fields: 3},
+		{size: 1000, depth: 3, fields: 7},
+	}
+
+	for _, tt := range tests {
+		doc, err := JSON(tt.size, tt.depth, tt.fields)
+		if err != nil {
+			t.Fatalf("JSON(%d, %d, %d) failed: %v", tt.size, tt.depth, tt.fields, err)
+		}
+		if len(doc) != tt.size {
+			t.Errorf("JSON(%d, %d, %d): expected %d bytes, got %d", tt.size, tt.depth, tt.fields, tt.size, len(doc))
+		}
+
+		var obj map[string]any
+		if err := json.Unmarshal(doc, &obj); err != nil {
+			t.Fatalf("JSON(%d, %d, %d) is invalid: %v", tt.size, tt.depth, tt.fields, err)
+		}
+
+		depth := 1
+		for {
+			if len(obj) < tt.fields {
+				t.Errorf("expected %d fields at depth %d, got %v", tt.fields, depth, obj)
+			}
+			nested, ok := obj["nested"].(map[string]any)
+			if !ok {
+				break
+			}
+			obj = nested
+			depth++
+		}
+		if depth != tt.depth {
+			t.Errorf("expected depth %d, got %d", tt.depth, depth)
+		}
+	}
+}
+
+func TestJSON_Errors(t *testing.T) {
+	if _, err := JSON(20, 3, 4); err == nil {
+		t.Error("expected error for size smaller than the document structure, got nil")
+	}
+	if _, err := JSON(1024, 0, 4); err == nil {
+		t.Error("expected error for zero depth, got nil")
+	}
+	if _, err := JSON(MaxSize+1, 1, 1); err == nil {
+		t.Error("expected error for size above the maximum, got nil")
+	}
+}
+
+func TestBinary(t *testing.T) {
+	blob, err := Binary(1001)
+	if err != nil {
+		t.Fatalf("Binary() failed: %v", err)
+	}
+	if len(blob) != 1001 {
+		t.Errorf("expected 1001 bytes, got %d", len(blob))
+	}
+
+	other, _ := Binary(1001)
+	if string(blob) == string(other) {
+		t.Error("expected random blobs to differ")
+	}
+
+	if _, err := Binary(0); err == nil {
+		t.Error("expected error for zero size, got nil")
+	}
+}
@@ -0,0 +1,202 @@
+// Package postman converts Postman collections, format v2.1, into
+// scenarios: a step per request, folders flattened in order, with the
+// collection's variables and what its test scripts assert and save.
+package postman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SchemaV21 is the schema URL of the collections Parse reads
+const SchemaV21 = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// Collection is a Postman collection
+type Collection struct {
+	Info     Info       `json:"info"`
+	Item     []Item     `json:"item"`
+	Variable []Variable `json:"variable"`
+	Auth     *Auth      `json:"auth"`
+	Event    []Event    `json:"event"`
+}
+
+// Info describes a collection
+type Info struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// Item is a request, or a folder when it has items of its own
+type Item struct {
+	Name    string   `json:"name"`
+	Item    []Item   `json:"item"`
+	Request *Request `json:"request"`
+	Auth    *Auth    `json:"auth"`
+	Event   []Event  `json:"event"`
+}
+
+// IsFolder reports whether the item groups other items
+func (i *Item) IsFolder() bool {
+	return i.Request == nil
+}
+
+// Request is the HTTP request of an item
+type Request struct {
+	Method string     `json:"method"`
+	Header []KeyValue `json:"header"`
+	URL    URL        `json:"url"`
+	Body   *Body      `json:"body"`
+	Auth   *Auth      `json:"auth"`
+}
+
+// URL is the URL of a request, written either as a string or as an object
+// breaking it down
+type URL struct {
+	Raw      string     `json:"raw"`
+	Query    []KeyValue `json:"query"`
+	Variable []KeyValue `json:"variable"`
+}
+
+// UnmarshalJSON accepts a URL written as a string
+func (u *URL) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*u = URL{}
+		return json.Unmarshal(data, &u.Raw)
+	}
+	type plain URL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// KeyValue is a header, query parameter, form field or path variable
+type KeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Variable is a collection variable
+type Variable struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// String returns the value of the variable as text
+func (v *Variable) String() string {
+	switch value := v.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+}
+
+// Body is the body of a request
+type Body struct {
+	// Mode is raw, urlencoded, formdata, file or graphql
+	Mode       string     `json:"mode"`
+	Raw        string     `json:"raw"`
+	URLEncoded []KeyValue `json:"urlencoded"`
+	GraphQL    *GraphQL   `json:"graphql"`
+	Options    struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// GraphQL is the body of a request in graphql mode
+type GraphQL struct {
+	Query     string `json:"query"`
+	Variables string `json:"variables"`
+}
+
+// Auth is the authentication of a request, or of the requests of a folder
+// or collection that do not set their own
+type Auth struct {
+	// Type is e.g. bearer, basic, apikey or noauth
+	Type   string     `json:"type"`
+	Bearer []KeyValue `json:"bearer"`
+	Basic  []KeyValue `json:"basic"`
+	APIKey []KeyValue `json:"apikey"`
+}
+
+// param returns the value of the parameter key of the auth's type
+func (a *Auth) param(key string) string {
+	var params []KeyValue
+	switch a.Type {
+	case "bearer":
+		params = a.Bearer
+	case "basic":
+		params = a.Basic
+	case "apikey":
+		params = a.APIKey
+	}
+	for _, p := range params {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Event is a script run before a request (prerequest) or after its
+// response (test)
+type Event struct {
+	Listen string `json:"listen"`
+	Script Script `json:"script"`
+}
+
+// Script is the source of an event, as lines
+type Script struct {
+	Exec Lines `json:"exec"`
+}
+
+// Lines is a script's source, written either as an array of lines or as a
+// single string
+type Lines []string
+
+// UnmarshalJSON accepts lines written as a single string
+func (l *Lines) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*l = strings.Split(s, "\n")
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// script returns the source of the events of events listening to listen
+func script(events []Event, listen string) []string {
+	var lines []string
+	for _, e := range events {
+		if e.Listen == listen {
+			lines = append(lines, e.Script.Exec...)
+		}
+	}
+	return lines
+}
+
+// Parse reads a collection in format v2.1, or v2.0 which has the same
+// structure
+func Parse(data []byte) (*Collection, error) {
+	var c Collection
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse collection: %w", err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "/v2.") {
+		return nil, fmt.Errorf("unsupported collection schema %s, export the collection as v2.1", c.Info.Schema)
+	}
+	if len(c.Item) == 0 {
+		return nil, fmt.Errorf("the collection has no requests")
+	}
+	return &c, nil
+}
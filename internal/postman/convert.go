@@ -0,0 +1,444 @@
+package postman
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"loadforge-agent/internal/importutil"
+	"loadforge-agent/internal/scenario"
+)
+
+// Options set what a collection does not say about the scenario
+type Options struct {
+	// BaseURL replaces the scheme and host of every request, by default
+	// those of the first request
+	BaseURL      string
+	VirtualUsers uint64
+	// Duration is in seconds
+	Duration uint64
+	// Environment holds the values of a Postman environment, overriding
+	// the collection's variables, see ParseEnvironment
+	Environment map[string]string
+}
+
+var (
+	// postmanVar matches {{name}} placeholders
+	postmanVar = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+)
+
+// dynamicVars are the Postman dynamic variables with an equivalent
+// template function
+var dynamicVars = map[string]string{
+	"$guid":         "${uuid()}",
+	"$randomUUID":   "${uuid()}",
+	"$isoTimestamp": "${now_iso8601()}",
+	"$randomInt":    "${random_int(0,1000)}",
+}
+
+// contentTypes are the Content-Type headers Postman sends with raw bodies
+// of each language
+var contentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"text":       "text/plain",
+	"javascript": "application/javascript",
+}
+
+type converter struct {
+	opts Options
+	// vars are the collection's variables, with the environment's
+	vars map[string]string
+	sc   *scenario.Scenario
+	// used are the variables the steps reference, saved those the test
+	// scripts set
+	used, saved map[string]bool
+	names       map[string]bool
+	warnings    []string
+}
+
+// Convert converts c into a scenario with a step per request, in the order
+// of the collection. Steps in folders are named after them, e.g.
+// "Users / Create user". The variables of the collection and of
+// opts.Environment become scenario variables, and {{name}} placeholders
+// become ${name}. Test scripts become checks and save_to_context as far as
+// they use common assertions, see convertTests.
+//
+// Convert also returns warnings about what it could not convert, such as
+// pre-request scripts, form-data bodies or requests sent to another host
+// than the base URL, which are left out.
+func Convert(c *Collection, opts Options) (*scenario.Scenario, []string, error) {
+	name := c.Info.Name
+	if name == "" {
+		name = "imported"
+	}
+	conv := &converter{
+		opts: opts,
+		vars: make(map[string]string),
+		sc: &scenario.Scenario{
+			Name:         name,
+			BaseURL:      strings.TrimSuffix(opts.BaseURL, "/"),
+			VirtualUsers: max(opts.VirtualUsers, 1),
+			Duration:     max(opts.Duration, 1),
+			Variables:    make(map[string]string),
+		},
+		used:  make(map[string]bool),
+		saved: make(map[string]bool),
+		names: make(map[string]bool),
+	}
+	for _, v := range c.Variable {
+		if !v.Disabled && v.Key != "" {
+			conv.vars[v.Key] = v.String()
+		}
+	}
+	maps.Copy(conv.vars, opts.Environment)
+
+	if err := conv.items(c.Item, nil, c.Auth, script(c.Event, "test")); err != nil {
+		return nil, nil, err
+	}
+	if len(conv.sc.Steps) == 0 {
+		return nil, conv.warnings, fmt.Errorf("no requests to convert")
+	}
+
+	for name, value := range conv.vars {
+		conv.sc.Variables[name] = conv.expand(value)
+	}
+	for _, name := range slices.Sorted(maps.Keys(conv.used)) {
+		if _, ok := conv.sc.Variables[name]; !ok && !conv.saved[name] {
+			conv.sc.Variables[name] = ""
+			conv.warn("variable %s is not defined in the collection, set its value in variables", name)
+		}
+	}
+	if len(conv.sc.Variables) == 0 {
+		conv.sc.Variables = nil
+	}
+	return conv.sc, conv.warnings, nil
+}
+
+func (c *converter) warn(format string, args ...any) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// items converts items in folders, which inherit auth and the test scripts
+// of the folders and collection around them
+func (c *converter) items(items []Item, folders []string, auth *Auth, tests []string) error {
+	for i := range items {
+		item := &items[i]
+		path := append(slices.Clone(folders), item.Name)
+		if item.IsFolder() {
+			if err := c.items(item.Item, path, inherit(auth, item.Auth), append(slices.Clone(tests), script(item.Event, "test")...)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		step, ok, err := c.step(item, strings.Join(path, " / "), inherit(auth, item.Request.Auth), append(slices.Clone(tests), script(item.Event, "test")...))
+		if err != nil {
+			return err
+		}
+		if ok {
+			c.sc.Steps = append(c.sc.Steps, step)
+		}
+	}
+	return nil
+}
+
+// inherit returns the auth of an item, own if it sets one or else that of
+// its parent
+func inherit(parent, own *Auth) *Auth {
+	if own == nil || own.Type == "inherit" {
+		return parent
+	}
+	return own
+}
+
+// step converts the request of item, reporting false if it is left out
+func (c *converter) step(item *Item, name string, auth *Auth, tests []string) (scenario.Step, bool, error) {
+	name = c.uniqueName(name)
+	req := item.Request
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	origin, path, rawQuery := splitURL(req.URL.Raw)
+	if c.opts.BaseURL == "" {
+		base, err := c.resolveOrigin(origin)
+		if err != nil {
+			return scenario.Step{}, false, fmt.Errorf("request %s: %w", name, err)
+		}
+		if c.sc.BaseURL == "" {
+			c.sc.BaseURL = base
+		} else if base != c.sc.BaseURL {
+			c.warn("request %s: left out, it is sent to %s rather than the base URL %s", name, base, c.sc.BaseURL)
+			return scenario.Step{}, false, nil
+		}
+	}
+
+	step := scenario.Step{Name: name}
+	step.Request = method + " " + c.path(path, req.URL.Variable, &step)
+
+	if req.URL.Query != nil {
+		for _, q := range req.URL.Query {
+			if !q.Disabled && q.Key != "" {
+				step.Query = importutil.SetKey(step.Query, q.Key, c.expand(q.Value))
+			}
+		}
+	} else if rawQuery != "" {
+		for _, pair := range strings.Split(rawQuery, "&") {
+			if key, value, _ := strings.Cut(pair, "="); key != "" {
+				step.Query = importutil.SetKey(step.Query, key, c.expand(value))
+			}
+		}
+	}
+
+	for _, h := range req.Header {
+		if !h.Disabled && h.Key != "" {
+			step.Headers = importutil.SetKey(step.Headers, h.Key, c.expand(h.Value))
+		}
+	}
+	c.auth(&step, auth)
+	c.body(&step, method, req.Body)
+
+	if pre := script(item.Event, "prerequest"); strings.TrimSpace(strings.Join(pre, "")) != "" {
+		c.warn("request %s: pre-request script not converted", name)
+	}
+	t := convertTests(tests)
+	step.Checks = t.checks
+	step.SaveToContext = t.save
+	for variable := range t.save {
+		c.saved[variable] = true
+	}
+	for _, line := range t.skipped {
+		c.warn("request %s: test script line not converted: %s", name, line)
+	}
+	return step, true, nil
+}
+
+// uniqueName returns name, numbered if an earlier step has it
+func (c *converter) uniqueName(name string) string {
+	unique := name
+	for n := 2; c.names[unique]; n++ {
+		unique = name + " (" + strconv.Itoa(n) + ")"
+	}
+	c.names[unique] = true
+	return unique
+}
+
+// splitURL splits the raw URL of a request into its origin, e.g.
+// https://api.example.com or {{baseUrl}}, its path and its query
+func splitURL(raw string) (origin, path, query string) {
+	raw = strings.TrimSpace(raw)
+	raw, _, _ = strings.Cut(raw, "#")
+	raw, query, _ = strings.Cut(raw, "?")
+	start := 0
+	if i := strings.Index(raw, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if slash := strings.IndexByte(raw[start:], '/'); slash >= 0 {
+		return raw[:start+slash], raw[start+slash:], query
+	}
+	return raw, "/", query
+}
+
+// resolveOrigin returns the base URL of the requests sent to origin, with
+// its variables replaced by their values
+func (c *converter) resolveOrigin(origin string) (string, error) {
+	resolved := origin
+	// Values may reference other variables
+	for range 10 {
+		if !postmanVar.MatchString(resolved) {
+			break
+		}
+		var missing string
+		resolved = postmanVar.ReplaceAllStringFunc(resolved, func(m string) string {
+			name := postmanVar.FindStringSubmatch(m)[1]
+			value, ok := c.vars[name]
+			if !ok {
+				missing = name
+				return m
+			}
+			return value
+		})
+		if missing != "" {
+			return "", fmt.Errorf("variable %s of the URL %s is not defined, set a base URL", missing, origin)
+		}
+	}
+	if resolved == "" {
+		return "", fmt.Errorf("no URL, set a base URL")
+	}
+	if !strings.Contains(resolved, "://") {
+		// Like Postman
+		resolved = "http://" + resolved
+	}
+	return strings.TrimSuffix(resolved, "/"), nil
+}
+
+// path converts the path of a request. Path variables such as :id become
+// {id} path parameters, set to their values in variables.
+func (c *converter) path(path string, variables []KeyValue, step *scenario.Step) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		name, ok := strings.CutPrefix(seg, ":")
+		if !ok || name == "" {
+			segments[i] = c.expand(seg)
+			continue
+		}
+		value := "${" + name + "}"
+		if j := slices.IndexFunc(variables, func(v KeyValue) bool { return v.Key == name }); j >= 0 {
+			value = c.expand(variables[j].Value)
+		} else {
+			c.used[name] = true
+		}
+		segments[i] = "{" + name + "}"
+		step.PathParams = importutil.SetKey(step.PathParams, name, value)
+	}
+	return strings.Join(segments, "/")
+}
+
+// expand replaces {{name}} placeholders in s with ${name}, and dynamic
+// variables with their template functions
+func (c *converter) expand(s string) string {
+	return postmanVar.ReplaceAllStringFunc(s, func(m string) string {
+		name := postmanVar.FindStringSubmatch(m)[1]
+		if strings.HasPrefix(name, "$") {
+			if fn, ok := dynamicVars[name]; ok {
+				return fn
+			}
+			c.warn("dynamic variable %s not converted", m)
+			return m
+		}
+		c.used[name] = true
+		return "${" + name + "}"
+	})
+}
+
+// auth sends the credentials of auth with step, unless it sets an
+// Authorization header of its own
+func (c *converter) auth(step *scenario.Step, auth *Auth) {
+	if auth == nil || auth.Type == "noauth" {
+		return
+	}
+	if importutil.HasKey(step.Headers, "Authorization") && (auth.Type == "bearer" || auth.Type == "basic") {
+		return
+	}
+	switch auth.Type {
+	case "bearer":
+		step.Headers = importutil.SetKey(step.Headers, "Authorization", "Bearer "+c.expand(auth.param("token")))
+	case "basic":
+		user, pass := auth.param("username"), auth.param("password")
+		if postmanVar.MatchString(user + pass) {
+			// Variables are not expanded in the arguments of base64
+			c.warn("request %s: basic auth with variables not converted, set the Authorization header", step.Name)
+			return
+		}
+		step.Headers = importutil.SetKey(step.Headers, "Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+pass)))
+	case "apikey":
+		key, value := auth.param("key"), c.expand(auth.param("value"))
+		if auth.param("in") == "query" {
+			step.Query = importutil.SetKey(step.Query, key, value)
+		} else {
+			step.Headers = importutil.SetKey(step.Headers, key, value)
+		}
+	default:
+		c.warn("request %s: %s auth not converted", step.Name, auth.Type)
+	}
+}
+
+// body converts the body of a request. JSON bodies are kept structured so
+// they can be read and set by save_to_context and next_steps.
+func (c *converter) body(step *scenario.Step, method string, body *Body) {
+	if body == nil || body.Mode == "" {
+		return
+	}
+	switch body.Mode {
+	case "raw":
+		if strings.TrimSpace(body.Raw) == "" {
+			return
+		}
+	case "urlencoded":
+		if len(body.URLEncoded) == 0 {
+			return
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodTrace {
+		c.warn("request %s: body of a %s request left out", step.Name, method)
+		return
+	}
+
+	switch body.Mode {
+	case "raw":
+		raw := c.expand(body.Raw)
+		var v any
+		if json.Unmarshal([]byte(raw), &v) == nil && (body.Options.Raw.Language == "json" || body.Options.Raw.Language == "") {
+			if _, ok := v.(string); !ok {
+				step.Body = v
+				return
+			}
+		}
+		step.Body = raw
+		if contentType, ok := contentTypes[body.Options.Raw.Language]; ok && !importutil.HasKey(step.Headers, "Content-Type") {
+			step.Headers = importutil.SetKey(step.Headers, "Content-Type", contentType)
+		}
+	case "urlencoded":
+		var pairs []string
+		for _, f := range body.URLEncoded {
+			if !f.Disabled && f.Key != "" {
+				pairs = append(pairs, importutil.FormEscape(c.expand(f.Key))+"="+importutil.FormEscape(c.expand(f.Value)))
+			}
+		}
+		step.Body = strings.Join(pairs, "&")
+		if !importutil.HasKey(step.Headers, "Content-Type") {
+			step.Headers = importutil.SetKey(step.Headers, "Content-Type", "application/x-www-form-urlencoded")
+		}
+	case "graphql":
+		if body.GraphQL == nil {
+			return
+		}
+		query := map[string]any{"query": c.expand(body.GraphQL.Query)}
+		if vars := strings.TrimSpace(c.expand(body.GraphQL.Variables)); vars != "" {
+			var v any
+			if err := json.Unmarshal([]byte(vars), &v); err != nil {
+				c.warn("request %s: GraphQL variables left out, they are not JSON: %v", step.Name, err)
+			} else {
+				query["variables"] = v
+			}
+		}
+		step.Body = query
+	default:
+		c.warn("request %s: %s body not converted, set a payload", step.Name, body.Mode)
+	}
+}
+
+// environment is a Postman environment
+type environment struct {
+	Values []struct {
+		Key     string `json:"key"`
+		Value   any    `json:"value"`
+		Enabled *bool  `json:"enabled"`
+	} `json:"values"`
+}
+
+// ParseEnvironment reads the enabled values of a Postman environment
+func ParseEnvironment(data []byte) (map[string]string, error) {
+	var env environment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse environment: %w", err)
+	}
+	values := make(map[string]string, len(env.Values))
+	for _, v := range env.Values {
+		if v.Key == "" || v.Enabled != nil && !*v.Enabled {
+			continue
+		}
+		values[v.Key] = (&Variable{Value: v.Value}).String()
+	}
+	return values, nil
+}
@@ -0,0 +1,223 @@
+package postman
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/scenario"
+)
+
+const collection = `{
+  "info": {
+    "name": "Shop",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "variable": [
+    {"key": "baseUrl", "value": "https://api.example.com/v1"},
+    {"key": "token", "value": "secret"}
+  ],
+  "item": [
+    {
+      "name": "Auth",
+      "auth": {"type": "noauth"},
+      "item": [
+        {
+          "name": "Login",
+          "event": [{"listen": "test", "script": {"exec": [
+            "pm.test(\"logged in\", function () {",
+            "    pm.response.to.have.status(200);",
+            "});",
+            "var jsonData = pm.response.json();",
+            "pm.collectionVariables.set(\"token\", jsonData.access_token);"
+          ]}}],
+          "request": {
+            "method": "POST",
+            "header": [{"key": "Content-Type", "value": "application/json"}],
+            "body": {"mode": "raw", "raw": "{\"user\": \"{{user}}\", \"password\": \"pw\"}", "options": {"raw": {"language": "json"}}},
+            "url": {"raw": "{{baseUrl}}/login", "host": ["{{baseUrl}}"], "path": ["login"]}
+          }
+        }
+      ]
+    },
+    {
+      "name": "Users",
+      "item": [
+        {
+          "name": "Get user",
+          "event": [{"listen": "test", "script": {"exec": "pm.test(\"fast\", () => pm.expect(pm.response.responseTime).to.be.below(500));\npm.expect(pm.response.json().name).to.eql('Ada');\npm.sendRequest('https://example.com');"}}],
+          "request": {
+            "method": "GET",
+            "url": {
+              "raw": "{{baseUrl}}/users/:id?expand=true&draft=1",
+              "query": [{"key": "expand", "value": "true"}, {"key": "draft", "value": "1", "disabled": true}],
+              "variable": [{"key": "id", "value": "{{userId}}"}]
+            }
+          }
+        },
+        {
+          "name": "Update user",
+          "request": {
+            "method": "PUT",
+            "body": {"mode": "urlencoded", "urlencoded": [{"key": "name", "value": "Ada Lovelace"}, {"key": "id", "value": "{{$guid}}"}]},
+            "url": "{{baseUrl}}/users/{{userId}}"
+          }
+        },
+        {
+          "name": "Get user",
+          "request": {"method": "GET", "url": "https://other.example.com/users"}
+        }
+      ]
+    }
+  ]
+}`
+
+func TestConvert(t *testing.T) {
+	c, err := Parse([]byte(collection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, warnings, err := Convert(c, Options{VirtualUsers: 5, Duration: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sc.Name != "Shop" || sc.BaseURL != "https://api.example.com/v1" || sc.VirtualUsers != 5 || sc.Duration != 30 {
+		t.Errorf("scenario = %s %s %d VUs %ds", sc.Name, sc.BaseURL, sc.VirtualUsers, sc.Duration)
+	}
+	wantVars := map[string]string{"baseUrl": "https://api.example.com/v1", "token": "secret", "user": "", "userId": ""}
+	if !reflect.DeepEqual(sc.Variables, wantVars) {
+		t.Errorf("variables = %v, want %v", sc.Variables, wantVars)
+	}
+
+	var names []string
+	for _, step := range sc.Steps {
+		names = append(names, step.Name)
+	}
+	if want := []string{"Auth / Login", "Users / Get user", "Users / Update user"}; !slices.Equal(names, want) {
+		t.Fatalf("steps = %q, want %q", names, want)
+	}
+
+	login := sc.Steps[0]
+	if login.Request != "POST /login" || login.Headers["Authorization"] != "" {
+		t.Errorf("login = %s with headers %v", login.Request, login.Headers)
+	}
+	if body, ok := login.Body.(map[string]any); !ok || body["user"] != "${user}" {
+		t.Errorf("login body = %#v", login.Body)
+	}
+	if !reflect.DeepEqual(login.SaveToContext, map[string]string{"token": "response.access_token"}) {
+		t.Errorf("login save_to_context = %v", login.SaveToContext)
+	}
+	if len(login.Checks) != 1 || login.Checks[0].Name != "logged in" || !slices.Equal(login.Checks[0].Status, []string{"200"}) {
+		t.Errorf("login checks = %+v", login.Checks)
+	}
+
+	get := sc.Steps[1]
+	if get.Request != "GET /users/{id}" || get.PathParams["id"] != "${userId}" {
+		t.Errorf("get = %s with path params %v", get.Request, get.PathParams)
+	}
+	if !reflect.DeepEqual(get.Query, map[string]string{"expand": "true"}) {
+		t.Errorf("get query = %v", get.Query)
+	}
+	if get.Headers["Authorization"] != "Bearer ${token}" {
+		t.Errorf("get headers = %v, want the collection's bearer auth", get.Headers)
+	}
+	if len(get.Checks) != 2 || get.Checks[0].Name != "fast" || get.Checks[0].MaxLatency.Duration.Milliseconds() != 500 ||
+		get.Checks[1].Condition != `${response.name} == "Ada"` {
+		t.Errorf("get checks = %+v", get.Checks)
+	}
+
+	update := sc.Steps[2]
+	if update.Request != "PUT /users/${userId}" || update.Body != "name=Ada+Lovelace&id=${uuid()}" ||
+		update.Headers["Content-Type"] != "application/x-www-form-urlencoded" {
+		t.Errorf("update = %s %v with headers %v", update.Request, update.Body, update.Headers)
+	}
+
+	wantWarnings := []string{
+		"request Users / Get user: test script line not converted: pm.sendRequest('https://example.com');",
+		"request Users / Get user (2): left out, it is sent to https://other.example.com rather than the base URL https://api.example.com/v1",
+		"variable user is not defined in the collection, set its value in variables",
+		"variable userId is not defined in the collection, set its value in variables",
+	}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings =\n%s\nwant\n%s", strings.Join(warnings, "\n"), strings.Join(wantWarnings, "\n"))
+	}
+
+	data, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := scenario.NewParser()
+	if err := p.ParseData(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Errorf("converted scenario is invalid: %v\n%s", err, data)
+	}
+}
+
+func TestConvert_BaseURL(t *testing.T) {
+	c, err := Parse([]byte(`{"info": {"name": "x"}, "item": [
+		{"name": "a", "request": {"method": "GET", "url": "{{host}}/a"}},
+		{"name": "b", "request": {"method": "GET", "url": "https://other.example.com/b"}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Convert(c, Options{}); err == nil || !strings.Contains(err.Error(), "variable host") {
+		t.Errorf("err = %v, want the undefined host variable", err)
+	}
+
+	sc, _, err := Convert(c, Options{Environment: map[string]string{"host": "localhost:8080"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.BaseURL != "http://localhost:8080" || len(sc.Steps) != 1 {
+		t.Errorf("base URL %s with %d steps, want the environment's host and 1 step", sc.BaseURL, len(sc.Steps))
+	}
+
+	sc, _, err = Convert(c, Options{BaseURL: "https://staging.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.BaseURL != "https://staging.example.com" || len(sc.Steps) != 2 {
+		t.Errorf("base URL %s with %d steps, want every request sent to the given base URL", sc.BaseURL, len(sc.Steps))
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"not JSON", `[`, "failed to parse"},
+		{"v1", `{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}, "item": [{}]}`, "unsupported"},
+		{"empty", `{"info": {"name": "x"}}`, "no requests"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	env, err := ParseEnvironment([]byte(`{"name": "staging", "values": [
+		{"key": "baseUrl", "value": "https://staging.example.com", "enabled": true},
+		{"key": "retries", "value": 3},
+		{"key": "old", "value": "x", "enabled": false}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"baseUrl": "https://staging.example.com", "retries": "3"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("environment = %v, want %v", env, want)
+	}
+}
@@ -0,0 +1,197 @@
+package postman
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"loadforge-agent/internal/scenario"
+)
+
+// tests is what the test scripts of a request assert and save
+type tests struct {
+	checks []scenario.Check
+	// save maps the variables the scripts set to the lookups of
+	// save_to_context, e.g. response.user.id
+	save map[string]string
+	// skipped are the lines using pm that could not be converted
+	skipped []string
+}
+
+var (
+	testPattern    = regexp.MustCompile(`pm\.test\(\s*["'` + "`" + `]([^"'` + "`" + `]+)["'` + "`" + `]`)
+	bindPattern    = regexp.MustCompile(`(?:var|let|const)\s+(\w+)\s*=\s*pm\.response\.json\(\)\s*;?\s*$`)
+	setPattern     = regexp.MustCompile(`pm\.(?:environment|collectionVariables|globals|variables)\.set\(\s*["']([^"']+)["']\s*,\s*(.+?)\s*\)\s*;?\s*$`)
+	headerPattern  = regexp.MustCompile(`^pm\.response\.headers\.get\(\s*["']([^"']+)["']\s*\)$`)
+	jsonRefPattern = regexp.MustCompile(`^(pm\.response\.json\(\)|\w+)((?:\.\w+|\[\d+\]|\[["'][^"']+["']\])+)$`)
+	accessPattern  = regexp.MustCompile(`\.(\w+)|\[(\d+)\]|\[["']([^"']+)["']\]`)
+	equalPattern   = regexp.MustCompile(`pm\.expect\(\s*([^()]+(?:\(\))?[^()]*?)\s*\)\.to\.(?:eql|equal|eq|be\.equal)\(\s*("[^"]*"|'[^']*'|-?\d+(?:\.\d+)?|true|false)\s*\)`)
+)
+
+// assertion converts an assertion of a test script to the conditions of a
+// check
+type assertion struct {
+	pattern *regexp.Regexp
+	apply   func(c *scenario.Check, m []string)
+}
+
+var assertions = []assertion{
+	{regexp.MustCompile(`pm\.response\.to\.have\.status\(\s*(\d{3})\s*\)`), addStatus},
+	{regexp.MustCompile(`pm\.expect\(\s*pm\.response\.(?:code|status)\s*\)\.to\.(?:eql|equal|eq|be\.equal)\(\s*(\d{3})\s*\)`), addStatus},
+	{regexp.MustCompile(`pm\.response\.to\.be\.ok\b`), func(c *scenario.Check, _ []string) { c.Status = append(c.Status, "200") }},
+	{regexp.MustCompile(`pm\.response\.to\.be\.success\b`), func(c *scenario.Check, _ []string) { c.Status = append(c.Status, "2xx") }},
+	{regexp.MustCompile(`pm\.expect\(\s*pm\.response\.responseTime\s*\)\.to\.be\.(?:below|lessThan|at\.most)\(\s*(\d+)\s*\)`),
+		func(c *scenario.Check, m []string) {
+			ms, _ := strconv.Atoi(m[1])
+			c.MaxLatency = scenario.Duration{Duration: time.Duration(ms) * time.Millisecond}
+		}},
+	{regexp.MustCompile(`pm\.response\.to\.have\.header\(\s*["']([^"']+)["']\s*,\s*["']([^"']*)["']\s*\)`),
+		func(c *scenario.Check, m []string) {
+			if c.Headers == nil {
+				c.Headers = make(map[string]string)
+			}
+			c.Headers[m[1]] = m[2]
+		}},
+	{regexp.MustCompile(`pm\.expect\(\s*pm\.response\.text\(\)\s*\)\.to\.(?:include|contain)\(\s*["']([^"']*)["']\s*\)`),
+		func(c *scenario.Check, m []string) { c.BodyContains = m[1] }},
+	{regexp.MustCompile(`pm\.response\.to\.have\.body\(\s*["']([^"']*)["']\s*\)`),
+		func(c *scenario.Check, m []string) { c.BodyContains = m[1] }},
+}
+
+func addStatus(c *scenario.Check, m []string) {
+	c.Status = append(c.Status, m[1])
+}
+
+// convertTests converts the lines of test scripts. Assertions become the
+// conditions of a check per pm.test, named after it, and variables set to
+// a field of the JSON response or to a response header are saved to the
+// context. Lines in between, such as the closing of a pm.test callback,
+// are ignored; those using pm that were not converted are returned as
+// skipped.
+func convertTests(lines []string) tests {
+	var (
+		t       tests
+		current string
+		byName  = make(map[string]int)
+		// bound are the variables holding pm.response.json()
+		bound = make(map[string]bool)
+		// depth counts the parentheses left open, to tell when the
+		// callback of the current pm.test ends
+		depth, testDepth int
+	)
+	check := func(name string) *scenario.Check {
+		i, ok := byName[name]
+		if !ok {
+			i = len(t.checks)
+			byName[name] = i
+			t.checks = append(t.checks, scenario.Check{Name: name})
+		}
+		return &t.checks[i]
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		converted := false
+		if m := testPattern.FindStringSubmatch(line); m != nil {
+			current, converted, testDepth = m[1], true, depth
+		}
+		depth += strings.Count(line, "(") - strings.Count(line, ")")
+		if m := bindPattern.FindStringSubmatch(line); m != nil {
+			bound[m[1]] = true
+			continue
+		}
+		if m := setPattern.FindStringSubmatch(line); m != nil {
+			if ref := lookup(m[2], bound); ref != "" {
+				if t.save == nil {
+					t.save = make(map[string]string)
+				}
+				t.save[m[1]] = ref
+				continue
+			}
+			t.skipped = append(t.skipped, line)
+			continue
+		}
+
+		for _, a := range assertions {
+			if m := a.pattern.FindStringSubmatch(line); m != nil {
+				a.apply(check(current), m)
+				converted = true
+			}
+		}
+		if m := equalPattern.FindStringSubmatch(line); m != nil {
+			if path := jsonPath(m[1], bound); path != "" {
+				c := check(current)
+				cond := "${response." + path + "} == " + literal(m[2])
+				if c.Condition != "" {
+					cond = c.Condition + " && " + cond
+				}
+				c.Condition = cond
+				converted = true
+			}
+		}
+		if !converted && strings.Contains(line, "pm.") {
+			t.skipped = append(t.skipped, line)
+		}
+		if depth <= testDepth {
+			current = ""
+		}
+	}
+
+	// Drop the tests without assertions, and name the assertions made
+	// outside of any after their conditions
+	checks := t.checks[:0]
+	for _, c := range t.checks {
+		if c.Describe() == "" {
+			continue
+		}
+		if c.Name == "" {
+			c.Name = c.Describe()
+		}
+		checks = append(checks, c)
+	}
+	t.checks = checks
+	if len(t.checks) == 0 {
+		t.checks = nil
+	}
+	return t
+}
+
+// lookup returns the save_to_context lookup of a JavaScript expression
+// reading the response, e.g. response.token for pm.response.json().token,
+// or "" if it reads something else
+func lookup(expr string, bound map[string]bool) string {
+	if m := headerPattern.FindStringSubmatch(expr); m != nil {
+		return "headers." + m[1]
+	}
+	if path := jsonPath(expr, bound); path != "" {
+		return "response." + path
+	}
+	return ""
+}
+
+// jsonPath returns the path of the field of the JSON response expr reads,
+// e.g. users.0.id for pm.response.json().users[0].id, or "" if expr reads
+// something else
+func jsonPath(expr string, bound map[string]bool) string {
+	m := jsonRefPattern.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil || m[1] != "pm.response.json()" && !bound[m[1]] {
+		return ""
+	}
+	var parts []string
+	for _, a := range accessPattern.FindAllStringSubmatch(m[2], -1) {
+		parts = append(parts, a[1]+a[2]+a[3])
+	}
+	return strings.Join(parts, ".")
+}
+
+// literal returns a JavaScript literal as an operand of a condition
+func literal(js string) string {
+	if strings.HasPrefix(js, "'") {
+		return `"` + js[1:len(js)-1] + `"`
+	}
+	return js
+}
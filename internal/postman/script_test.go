@@ -0,0 +1,104 @@
+package postman
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/scenario"
+)
+
+func TestConvertTests(t *testing.T) {
+	tests := []struct {
+		name        string
+		lines       []string
+		wantChecks  []scenario.Check
+		wantSave    map[string]string
+		wantSkipped []string
+	}{
+		{
+			name: "status",
+			lines: []string{
+				`pm.test("Status code is 201", function () {`,
+				`    pm.response.to.have.status(201);`,
+				`});`,
+				`pm.test("ok", () => { pm.expect(pm.response.code).to.eql(200); });`,
+			},
+			wantChecks: []scenario.Check{
+				{Name: "Status code is 201", Status: []string{"201"}},
+				{Name: "ok", Status: []string{"200"}},
+			},
+		},
+		{
+			name: "response",
+			lines: []string{
+				`pm.test('body', function () {`,
+				`  pm.response.to.be.success;`,
+				`  pm.response.to.have.header("Content-Type", "application/json");`,
+				`  pm.expect(pm.response.text()).to.include("id");`,
+				`  pm.expect(pm.response.responseTime).to.be.below(200);`,
+				`});`,
+			},
+			wantChecks: []scenario.Check{{
+				Name:         "body",
+				Status:       []string{"2xx"},
+				Headers:      map[string]string{"Content-Type": "application/json"},
+				BodyContains: "id",
+				MaxLatency:   scenario.Duration{Duration: 200 * time.Millisecond},
+			}},
+		},
+		{
+			name: "fields",
+			lines: []string{
+				`const body = pm.response.json();`,
+				`pm.expect(body.items[0].state).to.eql("open");`,
+				`pm.expect(pm.response.json()["total"]).to.equal(3);`,
+			},
+			wantChecks: []scenario.Check{{
+				Name:      `${response.items.0.state} == "open" && ${response.total} == 3`,
+				Condition: `${response.items.0.state} == "open" && ${response.total} == 3`,
+			}},
+		},
+		{
+			name: "save",
+			lines: []string{
+				`let data = pm.response.json();`,
+				`pm.environment.set("orderId", data.order.id);`,
+				`pm.collectionVariables.set('first', pm.response.json().items[0].sku)`,
+				`pm.globals.set("etag", pm.response.headers.get("ETag"));`,
+				`pm.environment.set("now", Date.now());`,
+			},
+			wantSave: map[string]string{
+				"orderId": "response.order.id",
+				"first":   "response.items.0.sku",
+				"etag":    "headers.ETag",
+			},
+			wantSkipped: []string{`pm.environment.set("now", Date.now());`},
+		},
+		{
+			name: "unsupported",
+			lines: []string{
+				`// a comment about pm.response`,
+				`pm.test("schema", function () {`,
+				`    pm.response.to.have.jsonSchema(schema);`,
+				`});`,
+				`console.log("done");`,
+			},
+			wantSkipped: []string{`pm.response.to.have.jsonSchema(schema);`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertTests(tt.lines)
+			if !reflect.DeepEqual(got.checks, tt.wantChecks) {
+				t.Errorf("checks = %+v, want %+v", got.checks, tt.wantChecks)
+			}
+			if !reflect.DeepEqual(got.save, tt.wantSave) {
+				t.Errorf("save = %v, want %v", got.save, tt.wantSave)
+			}
+			if !reflect.DeepEqual(got.skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %q, want %q", got.skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"loadforge-agent/internal/netstat"
+)
+
+func readLimits() (Limits, error) {
+	var l Limits
+
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return l, fmt.Errorf("failed to read open file limit: %w", err)
+	}
+	l.FileLimit = int(min(rlimit.Cur, 1<<31-1))
+	l.FileHardLimit = int(min(rlimit.Max, 1<<31-1))
+
+	s, err := netstat.Read()
+	if err != nil {
+		return l, err
+	}
+	l.EphemeralPorts = s.EphemeralPorts
+
+	data, err := os.ReadFile("/proc/sys/net/core/somaxconn")
+	if err != nil {
+		return l, fmt.Errorf("failed to read somaxconn: %w", err)
+	}
+	if l.SomaxConn, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+		return l, fmt.Errorf("invalid somaxconn %q", data)
+	}
+	return l, nil
+}
+
+// raiseFileLimit sets the soft open file limit to n
+func raiseFileLimit(n int) error {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return err
+	}
+	rlimit.Cur = uint64(n)
+	return syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package preflight
+
+import "loadforge-agent/internal/netstat"
+
+func readLimits() (Limits, error) {
+	return Limits{}, netstat.ErrUnsupported
+}
+
+func raiseFileLimit(int) error {
+	return netstat.ErrUnsupported
+}
@@ -0,0 +1,146 @@
+// Package preflight checks, before a run starts, that the host's limits can
+// sustain the requested number of VUs, so that a high-VU run is refused
+// with a fix instead of failing mid-run with dial errors.
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"loadforge-agent/internal/container"
+	"loadforge-agent/internal/netstat"
+)
+
+// fileReserve is the number of file descriptors kept for everything but
+// VU connections: sinks, trace and sample files, the control API
+const fileReserve = 256
+
+// Finding is a limit that needs attention
+type Finding struct {
+	// Limit names the limit, e.g. "open files"
+	Limit   string
+	Message string
+	// Hint is how to raise the limit, empty once it was raised
+	Hint string
+	// Fatal findings make the run fail
+	Fatal bool
+}
+
+func (f Finding) String() string {
+	s := f.Limit + ": " + f.Message
+	if f.Hint != "" {
+		s += "\n  fix: " + f.Hint
+	}
+	return s
+}
+
+// Limits are the host limits relevant to generating load
+type Limits struct {
+	// FileLimit and FileHardLimit are the soft and hard open file limits
+	FileLimit     int
+	FileHardLimit int
+	// EphemeralPorts is the size of the ephemeral port range
+	EphemeralPorts int
+	// SomaxConn bounds the accept backlog of servers on this host
+	SomaxConn int
+}
+
+// Check compares the host's and container's limits with what vus VUs
+// need, raising the open file limit up to the hard limit where that
+// suffices. Host limits are skipped on platforms where they cannot be read.
+func Check(vus uint64) []Finding {
+	var findings []Finding
+
+	l, err := readLimits()
+	switch {
+	case errors.Is(err, netstat.ErrUnsupported):
+	case err != nil:
+		findings = append(findings, Finding{Limit: "limits", Message: fmt.Sprintf("not checked: %v", err)})
+	default:
+		findings = check(l, vus, raiseFileLimit)
+	}
+
+	c, err := container.Detect()
+	if err != nil {
+		return append(findings, Finding{Limit: "container", Message: fmt.Sprintf("not checked: %v", err)})
+	}
+	return append(findings, checkContainer(c, vus)...)
+}
+
+// Err returns an error listing the fatal findings, or nil if there are none
+func Err(findings []Finding) error {
+	var fatal []string
+	for _, f := range findings {
+		if f.Fatal {
+			fatal = append(fatal, f.Limit+": "+f.Message)
+		}
+	}
+	if len(fatal) == 0 {
+		return nil
+	}
+	return fmt.Errorf("host limits too low for the requested VUs: %s", strings.Join(fatal, "; "))
+}
+
+// checkContainer warns when vus exceeds what the container's allocation
+// can generate, since an overloaded agent measures itself, not the target
+func checkContainer(c container.Limits, vus uint64) []Finding {
+	capacity := c.Capacity()
+	if capacity == 0 || vus <= capacity {
+		return nil
+	}
+	return []Finding{{
+		Limit: "container",
+		Message: fmt.Sprintf("%s sustain about %d VUs, %d requested; latencies may include the agent's own delays",
+			c, capacity, vus),
+		Hint: "raise the container's CPU and memory allocation, or spread the VUs across workers",
+	}}
+}
+
+// check evaluates l for vus VUs, each holding one connection, and raises
+// the open file limit with raise when the hard limit allows
+func check(l Limits, vus uint64, raise func(n int) error) []Finding {
+	var findings []Finding
+
+	files := int(min(vus, 1<<30)) + fileReserve
+	if l.FileLimit > 0 && l.FileLimit < files {
+		f := Finding{Limit: "open files"}
+		switch {
+		case l.FileHardLimit >= files && raise(files) == nil:
+			f.Message = fmt.Sprintf("raised the limit from %d to %d for %d VUs", l.FileLimit, files, vus)
+		default:
+			f.Fatal = true
+			f.Message = fmt.Sprintf("limit is %d (hard limit %d), %d VUs need at least %d",
+				l.FileLimit, l.FileHardLimit, vus, files)
+			f.Hint = fmt.Sprintf("run 'ulimit -n %d' before starting the agent; raise the hard limit in "+
+				"/etc/security/limits.conf, or with LimitNOFILE= for a systemd unit", files)
+		}
+		findings = append(findings, f)
+	}
+
+	if l.EphemeralPorts > 0 && uint64(float64(l.EphemeralPorts)*netstat.WarnRatio) < vus {
+		f := Finding{
+			Limit: "ephemeral ports",
+			Hint:  `run 'sysctl -w net.ipv4.ip_local_port_range="1024 65535"', or spread the VUs across workers`,
+		}
+		if uint64(l.EphemeralPorts) < vus {
+			f.Fatal = true
+			f.Message = fmt.Sprintf("%d ports for %d VUs, connections to a target would fail", l.EphemeralPorts, vus)
+		} else {
+			f.Message = fmt.Sprintf("%d ports for %d VUs, connections in TIME_WAIT may exhaust them",
+				l.EphemeralPorts, vus)
+		}
+		findings = append(findings, f)
+	}
+
+	if l.SomaxConn > 0 && uint64(l.SomaxConn) < vus {
+		findings = append(findings, Finding{
+			Limit: "somaxconn",
+			Message: fmt.Sprintf("%d pending connections at most, a target on this host may refuse "+
+				"connections when %d VUs connect at once", l.SomaxConn, vus),
+			Hint: fmt.Sprintf("run 'sysctl -w net.core.somaxconn=%d'", min(vus, 65535)),
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,89 @@
+package preflight
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"loadforge-agent/internal/container"
+)
+
+func TestCheck_Sufficient(t *testing.T) {
+	l := Limits{FileLimit: 65536, FileHardLimit: 65536, EphemeralPorts: 28232, SomaxConn: 4096}
+	if findings := check(l, 1000, nil); len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}
+
+func TestCheck_RaisesFileLimit(t *testing.T) {
+	var raised int
+	raise := func(n int) error {
+		raised = n
+		return nil
+	}
+
+	l := Limits{FileLimit: 1024, FileHardLimit: 524288}
+	findings := check(l, 5000, raise)
+	if raised != 5000+fileReserve {
+		t.Errorf("expected the limit to be raised to %d, got %d", 5000+fileReserve, raised)
+	}
+	if len(findings) != 1 || findings[0].Fatal || findings[0].Hint != "" {
+		t.Errorf("expected a non-fatal finding without hint, got %v", findings)
+	}
+	if Err(findings) != nil {
+		t.Errorf("unexpected error: %v", Err(findings))
+	}
+}
+
+func TestCheck_FileLimitTooLow(t *testing.T) {
+	raise := func(int) error { return errors.New("not permitted") }
+
+	for _, l := range []Limits{
+		{FileLimit: 1024, FileHardLimit: 4096},
+		{FileLimit: 1024, FileHardLimit: 524288},
+	} {
+		findings := check(l, 5000, raise)
+		if len(findings) != 1 || !findings[0].Fatal || !strings.Contains(findings[0].Hint, "ulimit -n 5256") {
+			t.Errorf("%+v: expected a fatal finding with a ulimit hint, got %v", l, findings)
+		}
+		if err := Err(findings); err == nil || !strings.Contains(err.Error(), "open files") {
+			t.Errorf("%+v: expected an error naming the limit, got %v", l, err)
+		}
+	}
+}
+
+func TestCheck_EphemeralPortsAndSomaxconn(t *testing.T) {
+	l := Limits{EphemeralPorts: 10000, SomaxConn: 4096}
+
+	findings := check(l, 9000, nil)
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %v", findings)
+	}
+	if findings[0].Limit != "ephemeral ports" || findings[0].Fatal {
+		t.Errorf("expected a port range warning, got %v", findings[0])
+	}
+	if findings[1].Limit != "somaxconn" || findings[1].Fatal {
+		t.Errorf("expected a somaxconn warning, got %v", findings[1])
+	}
+
+	findings = check(l, 20000, nil)
+	if !findings[0].Fatal {
+		t.Errorf("expected a port range shorter than the VUs to be fatal, got %v", findings[0])
+	}
+}
+
+func TestCheckContainer(t *testing.T) {
+	c := container.Limits{CPUs: 1, Memory: 1 << 30}
+	if findings := checkContainer(c, 500); len(findings) != 0 {
+		t.Errorf("expected no findings within capacity, got %v", findings)
+	}
+
+	findings := checkContainer(c, 10000)
+	if len(findings) != 1 || findings[0].Fatal || !strings.Contains(findings[0].Message, "1 CPUs, 1024 MiB memory") {
+		t.Errorf("expected a container warning, got %v", findings)
+	}
+
+	if findings := checkContainer(container.Limits{}, 1e6); len(findings) != 0 {
+		t.Errorf("expected no findings without limits, got %v", findings)
+	}
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"loadforge-agent/internal/agent"
+)
+
+// Attachment is a file embedded in an HTML report, such as the scenario
+// the run was made from
+type Attachment struct {
+	Name    string
+	Content []byte
+}
+
+type htmlReport struct {
+	*Summary
+	Attachments []Attachment
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"ms":      func(v float64) string { return fmt.Sprintf("%.2f ms", v) },
+	"text":    func(b []byte) string { return string(b) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Scenario}} load test report</title>
+<style>
+body { font: 14px/1.4 system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.failed { color: #b00; }
+.passed { color: #080; }
+pre { background: #f6f6f6; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{.Scenario}}</h1>
+<p>
+{{if .Passed}}<strong class="passed">passed</strong>{{else}}<strong class="failed">failed</strong>{{end}}
+{{if .Aborted}}&middot; aborted: {{.Aborted}}{{end}}
+&middot; {{printf "%.1f" .DurationSeconds}}s &middot; {{.Iterations}} iterations
+&middot; agent {{.Agent.Version}}
+</p>
+{{range .CapacityGaps}}
+<p class="failed">worker {{.Worker}} lost, {{.VirtualUsers}} VUs missing for {{printf "%.1f" .DurationSeconds}}s{{if .Redistributed}} until redistributed{{end}}</p>
+{{end}}
+
+<h2>Steps</h2>
+<table>
+<tr><th>step</th><th>requests</th><th>errors</th><th>error rate</th><th>rps</th><th>p50</th><th>p95</th><th>p99</th><th>max</th></tr>
+{{range .Steps}}{{template "step" .}}{{end}}
+{{template "step" .Total}}
+</table>
+
+{{if .Thresholds}}
+<h2>Thresholds</h2>
+<table>
+<tr><th>threshold</th><th>actual</th><th>result</th></tr>
+{{range .Thresholds}}
+<tr><td>{{.Expr}}{{if .Step}} ({{.Step}}){{end}}</td><td>{{.Actual}}</td>
+<td>{{if .Passed}}<span class="passed">passed</span>{{else}}<span class="failed">failed</span>{{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{if .Checks}}
+<h2>Checks</h2>
+<table>
+<tr><th>check</th><th>passes</th><th>fails</th><th>pass rate</th></tr>
+{{range .Checks}}
+<tr><td>{{.Step}}: {{.Name}}</td><td>{{.Passes}}</td><td>{{.Fails}}</td><td>{{percent .PassRate}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{if .Events}}
+<h2>Events</h2>
+<table>
+<tr><th>time</th><th>kind</th><th>message</th></tr>
+{{range .Events}}
+<tr><td>{{.Time.Format "15:04:05.000"}}</td><td>{{.Kind}}</td><td style="text-align: left">{{.Message}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+{{range .Attachments}}
+<details>
+<summary>{{.Name}}</summary>
+<pre>{{text .Content}}</pre>
+</details>
+{{end}}
+</body>
+</html>
+{{define "step"}}<tr{{if .Errors}} class="failed"{{end}}><td>{{.Name}}</td><td>{{.Requests}}</td><td>{{.Errors}}</td><td>{{percent .ErrorRate}}</td>
+<td>{{printf "%.1f" .RPS}}</td><td>{{ms .LatencyMS.P50}}</td><td>{{ms .LatencyMS.P95}}</td><td>{{ms .LatencyMS.P99}}</td><td>{{ms .LatencyMS.Max}}</td></tr>
+{{end}}`))
+
+// WriteHTML writes a self-contained HTML report of r, with the attachments
+// embedded at its end
+func WriteHTML(w io.Writer, scenarioName string, r *agent.Result, attachments ...Attachment) error {
+	s := htmlReport{Summary: NewSummary(scenarioName, r), Attachments: attachments}
+	if err := htmlTemplate.Execute(w, s); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return nil
+}
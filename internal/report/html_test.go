@@ -0,0 +1,50 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/timeline"
+)
+
+func TestWriteHTML(t *testing.T) {
+	result := &agent.Result{
+		Iterations: 10,
+		Duration:   2 * time.Second,
+		Metrics: metrics.Summary{
+			Total: metrics.StepStats{Name: "total", Requests: 10, Errors: 2},
+			Steps: []metrics.StepStats{{Name: "GET /<items>", Requests: 10, Errors: 2, ErrorRate: 0.2}},
+		},
+		Thresholds: []threshold.Result{
+			{Expr: "error_rate < 1%", Actual: "20.00%", Passed: false},
+		},
+		Events: []timeline.Event{{Time: time.Now(), Kind: timeline.RunStarted, Message: "started 2 VUs"}},
+	}
+
+	var buf bytes.Buffer
+	err := WriteHTML(&buf, "checkout", result, Attachment{Name: "scenario.yaml", Content: []byte("name: <checkout>")})
+	if err != nil {
+		t.Fatalf("WriteHTML() failed: %v", err)
+	}
+
+	html := buf.String()
+	for _, want := range []string{
+		"<title>checkout load test report</title>",
+		`<strong class="failed">failed</strong>`,
+		"GET /&lt;items&gt;",
+		"20.00%",
+		"error_rate &lt; 1%",
+		"started 2 VUs",
+		"<summary>scenario.yaml</summary>",
+		"name: &lt;checkout&gt;",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected report to contain %q", want)
+		}
+	}
+}
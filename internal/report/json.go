@@ -0,0 +1,424 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/timeline"
+	"loadforge-agent/internal/version"
+)
+
+// Summary is the machine-readable end-of-run summary. Latencies are in
+// milliseconds so the document is usable without unit conversion.
+type Summary struct {
+	// Agent identifies the agent build that produced the results
+	Agent           version.Info    `json:"agent"`
+	Scenario        string          `json:"scenario"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Iterations      int64           `json:"iterations"`
+	Passed          bool            `json:"passed"`
+	Aborted         string          `json:"aborted,omitempty"`
+	SkippedWrites   int64           `json:"skipped_writes,omitempty"`
+	Total           StepSummary     `json:"total"`
+	Steps           []StepSummary   `json:"steps"`
+	Protocols       []StepSummary   `json:"protocols,omitempty"`
+	Labels          []StepSummary   `json:"labels,omitempty"`
+	Thresholds      []ThresholdItem `json:"thresholds"`
+	Noise           *StepSummary    `json:"noise,omitempty"`
+	Budgets         []BudgetItem    `json:"budgets,omitempty"`
+	Checks          []CheckItem     `json:"checks,omitempty"`
+	Contract        []ContractItem  `json:"contract,omitempty"`
+	Idempotency     []ReplayItem    `json:"idempotency,omitempty"`
+	Burst           *BurstItem      `json:"burst,omitempty"`
+	Outliers        *OutlierSummary `json:"outliers,omitempty"`
+	Events          []EventItem     `json:"events,omitempty"`
+	Sockets         *SocketSummary  `json:"sockets,omitempty"`
+	Profiles        []string        `json:"profiles,omitempty"`
+
+	// CapacityGaps are the windows of a distributed run missing the VUs
+	// of lost workers
+	CapacityGaps []CapacityGapItem `json:"capacity_gaps,omitempty"`
+}
+
+// StepSummary holds the statistics of one step or of the whole run
+type StepSummary struct {
+	Name      string           `json:"name"`
+	Requests  int64            `json:"requests"`
+	Errors    int64            `json:"errors"`
+	ErrorRate float64          `json:"error_rate"`
+	RPS       float64          `json:"rps"`
+	LatencyMS LatencySummary   `json:"latency_ms"`
+	ErrorKind map[string]int64 `json:"error_breakdown,omitempty"`
+}
+
+// LatencySummary holds latency statistics in milliseconds
+type LatencySummary struct {
+	Count int64   `json:"count"`
+	Min   float64 `json:"min"`
+	Avg   float64 `json:"avg"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p99_9"`
+	Max   float64 `json:"max"`
+}
+
+// ThresholdItem is the outcome of one threshold
+type ThresholdItem struct {
+	Expr   string `json:"expr"`
+	Step   string `json:"step,omitempty"`
+	Actual string `json:"actual"`
+	Passed bool   `json:"passed"`
+}
+
+// BudgetItem reports how often a step group exceeded its time budget
+type BudgetItem struct {
+	Name          string         `json:"name"`
+	Steps         []string       `json:"steps"`
+	MaxMS         float64        `json:"max_ms"`
+	Iterations    int64          `json:"iterations"`
+	Violations    int64          `json:"violations"`
+	ViolationRate float64        `json:"violation_rate"`
+	Cancelled     int64          `json:"cancelled,omitempty"`
+	DurationMS    LatencySummary `json:"duration_ms"`
+}
+
+// CheckItem counts the passes and failures of one check
+type CheckItem struct {
+	Step      string  `json:"step"`
+	Name      string  `json:"name"`
+	Condition string  `json:"condition"`
+	Passes    int64   `json:"passes"`
+	Fails     int64   `json:"fails"`
+	PassRate  float64 `json:"pass_rate"`
+	// Errors are the first reasons the check failed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ContractItem counts the responses of one step that violated the
+// OpenAPI document
+type ContractItem struct {
+	Step       string   `json:"step"`
+	Operation  string   `json:"operation,omitempty"`
+	Responses  int64    `json:"responses"`
+	Violations int64    `json:"violations"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// ReplayItem counts the replays of one step's idempotency check whose
+// response differed from the original response
+type ReplayItem struct {
+	Step       string   `json:"step"`
+	Compare    string   `json:"compare"`
+	Replays    int64    `json:"replays"`
+	Violations int64    `json:"violations"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// BurstItem is how the target coped with the burst of a burst run
+type BurstItem struct {
+	BaselineP95MS   float64 `json:"baseline_p95_ms"`
+	PeakP95MS       float64 `json:"peak_p95_ms"`
+	Recovered       bool    `json:"recovered"`
+	RecoverySeconds float64 `json:"recovery_seconds"`
+}
+
+// OutlierSummary lists latency outliers grouped by one-second windows
+type OutlierSummary struct {
+	Count        int                `json:"count"`
+	ThresholdsMS map[string]float64 `json:"thresholds_ms"`
+	Windows      []OutlierWindow    `json:"windows"`
+}
+
+// OutlierWindow holds the outliers that started within the same second
+type OutlierWindow struct {
+	Start         time.Time     `json:"start"`
+	OffsetSeconds float64       `json:"offset_seconds"`
+	Requests      []OutlierItem `json:"requests"`
+	Causes        []string      `json:"causes,omitempty"`
+}
+
+// OutlierItem is a single outlier request
+type OutlierItem struct {
+	Step      string    `json:"step"`
+	Start     time.Time `json:"start"`
+	LatencyMS float64   `json:"latency_ms"`
+	Status    int       `json:"status"`
+}
+
+// SocketSummary holds the peak socket usage of the agent host
+type SocketSummary struct {
+	Open           int     `json:"open"`
+	Established    int     `json:"established"`
+	TimeWait       int     `json:"time_wait"`
+	EphemeralInUse int     `json:"ephemeral_in_use"`
+	EphemeralPorts int     `json:"ephemeral_ports"`
+	EphemeralUsage float64 `json:"ephemeral_usage"`
+	OpenFiles      int     `json:"open_files"`
+	FileLimit      int     `json:"file_limit"`
+}
+
+// CapacityGapItem is a window of a distributed run missing the VUs of a
+// lost worker
+type CapacityGapItem struct {
+	Worker          string    `json:"worker"`
+	VirtualUsers    uint64    `json:"virtual_users"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Redistributed   bool      `json:"redistributed"`
+}
+
+// EventItem is one entry of the run's timeline
+type EventItem struct {
+	Time    time.Time         `json:"time"`
+	Kind    timeline.Kind     `json:"kind"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// NewEvents converts timeline events to their JSON representation
+func NewEvents(events []timeline.Event) []EventItem {
+	items := make([]EventItem, 0, len(events))
+	for _, e := range events {
+		items = append(items, EventItem{Time: e.Time, Kind: e.Kind, Message: e.Message, Attrs: e.Attrs})
+	}
+	return items
+}
+
+// NewSummary builds the JSON summary document for r
+func NewSummary(scenarioName string, r *agent.Result) *Summary {
+	s := &Summary{
+		Agent:           version.Get(),
+		Scenario:        scenarioName,
+		DurationSeconds: r.Duration.Seconds(),
+		Iterations:      r.Iterations,
+		Passed:          r.Passed(),
+		Aborted:         r.Aborted,
+		SkippedWrites:   r.SkippedWrites,
+		Total:           newStepSummary(r.Metrics.Total),
+		Steps:           make([]StepSummary, 0, len(r.Metrics.Steps)),
+		Thresholds:      make([]ThresholdItem, 0, len(r.Thresholds)),
+	}
+
+	for _, step := range r.Metrics.Steps {
+		s.Steps = append(s.Steps, newStepSummary(step))
+	}
+	for _, protocol := range r.Metrics.Protocols {
+		s.Protocols = append(s.Protocols, newStepSummary(protocol))
+	}
+	for _, label := range r.Metrics.Labels {
+		s.Labels = append(s.Labels, newStepSummary(label))
+	}
+
+	for _, t := range r.Thresholds {
+		s.Thresholds = append(s.Thresholds, ThresholdItem{
+			Expr:   t.Expr,
+			Step:   t.Step,
+			Actual: t.Actual,
+			Passed: t.Passed,
+		})
+	}
+
+	if r.Noise != nil {
+		noise := newStepSummary(*r.Noise)
+		s.Noise = &noise
+	}
+
+	for _, b := range r.Budgets {
+		s.Budgets = append(s.Budgets, BudgetItem{
+			Name:          b.Name,
+			Steps:         b.Steps,
+			MaxMS:         ms(b.Max),
+			Iterations:    b.Iterations,
+			Violations:    b.Violations,
+			ViolationRate: b.ViolationRate,
+			Cancelled:     b.Cancelled,
+			DurationMS:    newLatencySummary(b.Duration),
+		})
+	}
+
+	for _, c := range r.Checks {
+		s.Checks = append(s.Checks, CheckItem{
+			Step:      c.Step,
+			Name:      c.Name,
+			Condition: c.Condition,
+			Passes:    c.Passes,
+			Fails:     c.Fails,
+			PassRate:  c.PassRate,
+			Errors:    c.Errors,
+		})
+	}
+
+	if b := r.Burst; b != nil {
+		s.Burst = &BurstItem{
+			BaselineP95MS:   ms(b.BaselineP95),
+			PeakP95MS:       ms(b.PeakP95),
+			Recovered:       b.Recovered,
+			RecoverySeconds: b.RecoveryTime.Seconds(),
+		}
+	}
+
+	for _, c := range r.Contract {
+		s.Contract = append(s.Contract, ContractItem{
+			Step:       c.Step,
+			Operation:  c.Operation,
+			Responses:  c.Responses,
+			Violations: c.Violations,
+			Errors:     c.Errors,
+		})
+	}
+
+	for _, i := range r.Idempotency {
+		s.Idempotency = append(s.Idempotency, ReplayItem{
+			Step:       i.Step,
+			Compare:    i.Compare,
+			Replays:    i.Replays,
+			Violations: i.Violations,
+			Errors:     i.Errors,
+		})
+	}
+
+	if r.Outliers != nil {
+		s.Outliers = newOutlierSummary(r.Outliers)
+	}
+
+	if len(r.Events) > 0 {
+		s.Events = NewEvents(r.Events)
+	}
+
+	if k := r.Sockets; k != nil {
+		s.Sockets = &SocketSummary{
+			Open:           k.Open,
+			Established:    k.Established,
+			TimeWait:       k.TimeWait,
+			EphemeralInUse: k.EphemeralInUse,
+			EphemeralPorts: k.EphemeralPorts,
+			EphemeralUsage: k.EphemeralUsage(),
+			OpenFiles:      k.OpenFiles,
+			FileLimit:      k.FileLimit,
+		}
+	}
+	s.Profiles = r.Profiles
+
+	for _, g := range r.CapacityGaps {
+		s.CapacityGaps = append(s.CapacityGaps, CapacityGapItem{
+			Worker:          g.Worker,
+			VirtualUsers:    g.VirtualUsers,
+			Start:           g.Start,
+			End:             g.End,
+			DurationSeconds: g.End.Sub(g.Start).Seconds(),
+			Redistributed:   g.Redistributed,
+		})
+	}
+
+	return s
+}
+
+func newOutlierSummary(o *metrics.OutlierReport) *OutlierSummary {
+	s := &OutlierSummary{
+		Count:        o.Count,
+		ThresholdsMS: make(map[string]float64, len(o.Thresholds)),
+		Windows:      make([]OutlierWindow, 0, len(o.Windows)),
+	}
+	for step, d := range o.Thresholds {
+		s.ThresholdsMS[step] = ms(d)
+	}
+	for _, w := range o.Windows {
+		window := OutlierWindow{
+			Start:         w.Start,
+			OffsetSeconds: w.Offset.Seconds(),
+			Requests:      make([]OutlierItem, 0, len(w.Outliers)),
+			Causes:        w.Causes,
+		}
+		for _, out := range w.Outliers {
+			window.Requests = append(window.Requests, OutlierItem{
+				Step:      out.Step,
+				Start:     out.Start,
+				LatencyMS: ms(out.Duration),
+				Status:    out.Status,
+			})
+		}
+		s.Windows = append(s.Windows, window)
+	}
+	return s
+}
+
+// Snapshot is a live view of the last interval of a run in progress
+type Snapshot struct {
+	AgentVersion   string        `json:"agent_version"`
+	Time           time.Time     `json:"time"`
+	ElapsedSeconds float64       `json:"elapsed_seconds"`
+	ActiveVUs      int64         `json:"active_vus"`
+	Total          StepSummary   `json:"total"`
+	Steps          []StepSummary `json:"steps"`
+	Protocols      []StepSummary `json:"protocols,omitempty"`
+	Labels         []StepSummary `json:"labels,omitempty"`
+}
+
+// NewSnapshot builds a live snapshot from the statistics of one interval
+func NewSnapshot(now time.Time, elapsed time.Duration, activeVUs int64, interval metrics.Summary) *Snapshot {
+	s := &Snapshot{
+		AgentVersion:   version.Label(),
+		Time:           now,
+		ElapsedSeconds: elapsed.Seconds(),
+		ActiveVUs:      activeVUs,
+		Total:          newStepSummary(interval.Total),
+		Steps:          make([]StepSummary, 0, len(interval.Steps)),
+	}
+	for _, step := range interval.Steps {
+		s.Steps = append(s.Steps, newStepSummary(step))
+	}
+	for _, protocol := range interval.Protocols {
+		s.Protocols = append(s.Protocols, newStepSummary(protocol))
+	}
+	for _, label := range interval.Labels {
+		s.Labels = append(s.Labels, newStepSummary(label))
+	}
+	return s
+}
+
+func newStepSummary(s metrics.StepStats) StepSummary {
+	return StepSummary{
+		Name:      s.Name,
+		Requests:  s.Requests,
+		Errors:    s.Errors,
+		ErrorRate: s.ErrorRate,
+		RPS:       s.RPS,
+		ErrorKind: s.ErrorKinds,
+		LatencyMS: newLatencySummary(s.Latency),
+	}
+}
+
+func newLatencySummary(l metrics.LatencyStats) LatencySummary {
+	return LatencySummary{
+		Count: l.Count,
+		Min:   ms(l.Min),
+		Avg:   ms(l.Mean),
+		P50:   ms(l.P50),
+		P90:   ms(l.P90),
+		P95:   ms(l.P95),
+		P99:   ms(l.P99),
+		P999:  ms(l.P999),
+		Max:   ms(l.Max),
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteJSON writes the end-of-run summary for r as indented JSON
+func WriteJSON(w io.Writer, scenarioName string, r *agent.Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(NewSummary(scenarioName, r)); err != nil {
+		return fmt.Errorf("failed to encode JSON summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/metrics"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/version"
+)
+
+func TestWriteJSON(t *testing.T) {
+	login := metrics.StepStats{
+		Name:       "POST /login",
+		Requests:   10,
+		Errors:     2,
+		ErrorRate:  0.2,
+		RPS:        5,
+		Latency:    metrics.LatencyStats{Count: 10, P95: 250 * time.Millisecond},
+		ErrorKinds: map[string]int64{"HTTP 503": 2},
+	}
+	result := &agent.Result{
+		Iterations: 10,
+		Duration:   2 * time.Second,
+		Metrics: metrics.Summary{
+			Total: metrics.StepStats{Name: "total", Requests: 10, Errors: 2},
+			Steps: []metrics.StepStats{login},
+		},
+		Thresholds: []threshold.Result{
+			{Expr: "error_rate < 1%", Actual: "20.00%", Passed: false},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, "checkout", result); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	if doc["scenario"] != "checkout" || doc["passed"] != false || doc["duration_seconds"] != 2.0 {
+		t.Errorf("unexpected top-level fields: %v", doc)
+	}
+	if a, ok := doc["agent"].(map[string]any); !ok || a["version"] != version.Version || a["platform"] == "" {
+		t.Errorf("expected the agent build, got %v", doc["agent"])
+	}
+	if _, ok := doc["noise"]; ok {
+		t.Error("noise must be omitted when disabled")
+	}
+
+	steps := doc["steps"].([]any)
+	if len(steps) != 1 {
+		t.Fatalf("expected 1 step, got %d", len(steps))
+	}
+	step := steps[0].(map[string]any)
+	if step["name"] != "POST /login" || step["error_rate"] != 0.2 {
+		t.Errorf("unexpected step: %v", step)
+	}
+	if p95 := step["latency_ms"].(map[string]any)["p95"]; p95 != 250.0 {
+		t.Errorf("expected p95 250ms, got %v", p95)
+	}
+	if breakdown := step["error_breakdown"].(map[string]any); breakdown["HTTP 503"] != 2.0 {
+		t.Errorf("unexpected error breakdown: %v", breakdown)
+	}
+
+	thresholds := doc["thresholds"].([]any)
+	if len(thresholds) != 1 || thresholds[0].(map[string]any)["actual"] != "20.00%" {
+		t.Errorf("unexpected thresholds: %v", thresholds)
+	}
+}
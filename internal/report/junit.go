@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/version"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the run's threshold and check results as JUnit XML so
+// CI systems can show load test assertions alongside unit test results. A
+// check fails its test case when any of its evaluations failed.
+func WriteJUnit(w io.Writer, scenarioName string, r *agent.Result) error {
+	elapsed := strconv.FormatFloat(r.Duration.Seconds(), 'f', 3, 64)
+
+	suite := junitTestSuite{
+		Name:       scenarioName,
+		Time:       elapsed,
+		Properties: agentProperties(version.Get()),
+	}
+
+	for _, t := range r.Thresholds {
+		scope := "total"
+		if t.Step != "" {
+			scope = t.Step
+		}
+
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%s: %s", scope, t.Expr),
+			ClassName: scenarioName + ".thresholds",
+		}
+		if !t.Passed {
+			tc.Failure = &junitFailure{
+				Message: "threshold failed: actual " + t.Actual,
+				Type:    "threshold",
+				Body:    fmt.Sprintf("%s (%s) failed with actual value %s", t.Expr, scope, t.Actual),
+			}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	for _, c := range r.Checks {
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%s: %s", c.Step, c.Name),
+			ClassName: scenarioName + ".checks",
+		}
+		if c.Fails > 0 {
+			body := fmt.Sprintf("%s (%s) failed %d of %d times", c.Name, c.Condition, c.Fails, c.Passes+c.Fails)
+			for _, e := range c.Errors {
+				body += "\n" + e
+			}
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("check failed: %.2f%% passed", c.PassRate*100),
+				Type:    "check",
+				Body:    body,
+			}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	doc := junitTestSuites{
+		Name:     "loadforge",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     elapsed,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
+
+// agentProperties records the agent build as suite properties, so a failing
+// threshold can be traced back to the binary that measured it
+func agentProperties(info version.Info) []junitProperty {
+	props := []junitProperty{{Name: "agent.version", Value: info.Version}}
+	if info.Commit != "" {
+		props = append(props, junitProperty{Name: "agent.commit", Value: info.Commit})
+	}
+	if info.Date != "" {
+		props = append(props, junitProperty{Name: "agent.build_date", Value: info.Date})
+	}
+	return append(props, junitProperty{Name: "agent.platform", Value: info.Platform})
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/agent"
+	"loadforge-agent/internal/threshold"
+	"loadforge-agent/internal/version"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	result := &agent.Result{
+		Duration: 1500 * time.Millisecond,
+		Thresholds: []threshold.Result{
+			{Expr: "p95 < 500ms", Actual: "320ms", Passed: true},
+			{Expr: "error_rate < 1%", Step: "POST /login", Actual: "4.00%", Passed: false},
+		},
+		Checks: []agent.CheckResult{
+			{Step: "GET /cart", Name: "has items", Condition: "body contains \"items\"", Passes: 10, PassRate: 1},
+			{Step: "POST /login", Name: "token", Condition: "status in [200]", Passes: 9, Fails: 1, PassRate: 0.9,
+				Errors: []string{"status 503"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "checkout", result); err != nil {
+		t.Fatalf("WriteJUnit() failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Error("expected XML header")
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+
+	if doc.Tests != 4 || doc.Failures != 2 || doc.Time != "1.500" {
+		t.Errorf("unexpected totals: tests=%d failures=%d time=%s", doc.Tests, doc.Failures, doc.Time)
+	}
+	if len(doc.Suites) != 1 || doc.Suites[0].Name != "checkout" {
+		t.Fatalf("unexpected suites: %+v", doc.Suites)
+	}
+
+	if props := doc.Suites[0].Properties; len(props) == 0 || props[0] != (junitProperty{Name: "agent.version", Value: version.Version}) {
+		t.Errorf("expected the agent version as a property, got %+v", props)
+	}
+
+	cases := doc.Suites[0].TestCases
+	if len(cases) != 4 {
+		t.Fatalf("expected 4 test cases, got %d", len(cases))
+	}
+	if cases[0].Name != "total: p95 < 500ms" || cases[0].Failure != nil {
+		t.Errorf("unexpected passing case: %+v", cases[0])
+	}
+	if cases[1].Name != "POST /login: error_rate < 1%" || cases[1].Failure == nil {
+		t.Fatalf("unexpected failing case: %+v", cases[1])
+	}
+	if !strings.Contains(cases[1].Failure.Message, "4.00%") {
+		t.Errorf("expected actual value in failure message, got '%s'", cases[1].Failure.Message)
+	}
+	if cases[2].Name != "GET /cart: has items" || cases[2].ClassName != "checkout.checks" || cases[2].Failure != nil {
+		t.Errorf("unexpected passing check: %+v", cases[2])
+	}
+	if cases[3].Name != "POST /login: token" || cases[3].Failure == nil {
+		t.Fatalf("unexpected failing check: %+v", cases[3])
+	}
+	if f := cases[3].Failure; f.Message != "check failed: 90.00% passed" || !strings.Contains(f.Body, "status 503") {
+		t.Errorf("unexpected check failure: %+v", f)
+	}
+}
+
+func TestWriteJUnit_NoThresholds(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "empty", &agent.Result{}); err != nil {
+		t.Fatalf("WriteJUnit() failed: %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+	if doc.Tests != 0 || doc.Failures != 0 {
+		t.Errorf("expected empty report, got %+v", doc)
+	}
+}
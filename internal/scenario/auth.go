@@ -0,0 +1,84 @@
+package scenario
+
+import (
+	"fmt"
+
+	"loadforge-agent/internal/feeder"
+)
+
+// AuthPrefix prefixes the variable names of a VU's credentials, the
+// columns of its auth pool's credentials file
+const AuthPrefix = "auth."
+
+// AuthPoolVar is the variable holding the name of a VU's auth pool
+const AuthPoolVar = AuthPrefix + "pool"
+
+// AuthPool is a set of credentials, e.g. admin or read-only users, that a
+// share of the VUs log in with. Each VU of the pool takes a row of the
+// credentials file, runs the login steps before its first iteration and
+// again whenever its session expires, and keeps the variables they saved,
+// such as a token, across iterations.
+type AuthPool struct {
+	Name string `yaml:"name"`
+	// Weight shares the VUs between pools like weighted scenarios do
+	Weight float64 `yaml:"weight"`
+	// Credentials is a CSV file whose columns are available as
+	// ${auth.<column>}. VUs take its rows in turn, wrapping around when
+	// the pool has more VUs than rows.
+	Credentials string `yaml:"credentials"`
+	// Login steps run in order on the VU's connections and cookie jar;
+	// their requests are recorded under LoginStep names
+	Login []Step `yaml:"login"`
+	// TTL is how long a session lasts before the VU logs in again, 0 for
+	// the whole run
+	TTL Duration `yaml:"ttl,omitempty"`
+
+	// Path is Credentials resolved against the scenario file's directory
+	// once validated
+	Path string `yaml:"-"`
+}
+
+// LoginStep returns the name the login step of pool is reported under
+func LoginStep(pool, step string) string {
+	return QualifyStep("login "+pool, step)
+}
+
+// validateAuthPools checks the auth pools and resolves the paths of their
+// credentials files
+func (p *Parser) validateAuthPools() error {
+	sc := p.scenario
+	if sc.VirtualUsers < uint64(len(sc.AuthPools)) {
+		return fmt.Errorf("scenario.virtual_users must be at least the number of auth pools (%d)", len(sc.AuthPools))
+	}
+
+	names := make(map[string]struct{})
+	for i := range sc.AuthPools {
+		pool := &sc.AuthPools[i]
+		if pool.Name == "" {
+			return fmt.Errorf("scenario.auth_pools[%d]: name is required", i)
+		}
+		if _, exists := names[pool.Name]; exists {
+			return fmt.Errorf("scenario.auth_pools[%d]: duplicate name '%s'", i, pool.Name)
+		}
+		names[pool.Name] = struct{}{}
+
+		if pool.Weight <= 0 {
+			return fmt.Errorf("scenario.auth_pools[%d] (%s): weight must be greater than 0", i, pool.Name)
+		}
+		if format, err := feeder.FormatFromPath(pool.Credentials); err != nil || format != feeder.FormatCSV {
+			return fmt.Errorf("scenario.auth_pools[%d] (%s): credentials '%s' is not a .csv file",
+				i, pool.Name, pool.Credentials)
+		}
+		if len(pool.Login) == 0 {
+			return fmt.Errorf("scenario.auth_pools[%d] (%s): at least one login step is required", i, pool.Name)
+		}
+		if err := validateLifecycle(fmt.Sprintf("auth_pools[%d].login", i), pool.Login); err != nil {
+			return err
+		}
+		if pool.TTL.Duration < 0 {
+			return fmt.Errorf("scenario.auth_pools[%d] (%s): ttl must be non-negative", i, pool.Name)
+		}
+		pool.Path = p.ResolvePath(pool.Credentials)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package scenario
+
+import (
+	"fmt"
+	"net/url"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BaseURL is one of several base URLs the requests of a scenario are
+// split between, e.g. two regional endpoints of the same API
+type BaseURL struct {
+	URL    string  `yaml:"url"`
+	Weight float64 `yaml:"weight"`
+	// Name labels the metrics of the requests sent to URL, the URL's host
+	// by default
+	Name string `yaml:"name,omitempty"`
+}
+
+// BaseURLLabel is the metrics label of the base URL a request was sent to
+// when the scenario has several
+const BaseURLLabel = "base_url"
+
+// UnmarshalYAML accepts base_url as a single URL or as a list of weighted
+// base URLs, in which case BaseURL is set to the first of them
+func (s *Scenario) UnmarshalYAML(value *yaml.Node) error {
+	type plain Scenario
+	if value.Kind != yaml.MappingNode {
+		return value.Decode((*plain)(s))
+	}
+
+	node := *value
+	node.Content = append([]*yaml.Node(nil), value.Content...)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, v := node.Content[i], node.Content[i+1]
+		if key.Value != "base_url" || v.Kind != yaml.SequenceNode {
+			continue
+		}
+		var urls []BaseURL
+		if err := v.Decode(&urls); err != nil {
+			return fmt.Errorf("base_url must be a URL or a list of {url, weight}: %w", err)
+		}
+		var first string
+		if len(urls) > 0 {
+			first = urls[0].URL
+		}
+		node.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: first}
+		s.BaseURLs = urls
+	}
+	return node.Decode((*plain)(s))
+}
+
+// MarshalYAML writes base_url back as a list when the scenario has several
+// base URLs
+func (s *Scenario) MarshalYAML() (interface{}, error) {
+	type plain Scenario
+	if len(s.BaseURLs) == 0 {
+		return (*plain)(s), nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode((*plain)(s)); err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "base_url" {
+			continue
+		}
+		var urls yaml.Node
+		if err := urls.Encode(s.BaseURLs); err != nil {
+			return nil, err
+		}
+		node.Content[i+1] = &urls
+	}
+	return &node, nil
+}
+
+// validateBaseURLs checks the weighted base URLs and names those without
+// a name after their host
+func validateBaseURLs(sc *Scenario) error {
+	if len(sc.BaseURLs) == 0 {
+		return nil
+	}
+	sc.BaseURL = sc.BaseURLs[0].URL
+
+	names := make(map[string]int)
+	for i := range sc.BaseURLs {
+		b := &sc.BaseURLs[i]
+		u, err := url.Parse(b.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("scenario.base_url[%d]: '%s' is not an absolute URL", i, b.URL)
+		}
+		if b.Weight <= 0 {
+			return fmt.Errorf("scenario.base_url[%d] (%s): weight must be greater than 0", i, b.URL)
+		}
+		if b.Name == "" {
+			b.Name = u.Host
+		}
+		if j, exists := names[b.Name]; exists {
+			return fmt.Errorf("scenario.base_url[%d]: name '%s' is already used by base_url[%d], set distinct names", i, b.Name, j)
+		}
+		names[b.Name] = i
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package scenario
+
+import (
+	"fmt"
+	"time"
+)
+
+// Burst defaults
+const (
+	DefaultBurstTolerance = 10
+	DefaultBurstFor       = 3 * time.Second
+)
+
+// Burst measures how long the target takes to drain a burst of load. The
+// run holds BaselineVUs for Baseline to measure the baseline p95 latency,
+// raises the VUs to VUs for Duration, then drops back to BaselineVUs until
+// the p95 latency of every second stayed within Tolerance of the baseline
+// for For. The time from the end of the burst to then is the recovery
+// time.
+type Burst struct {
+	BaselineVUs uint64   `yaml:"baseline_vus"`
+	Baseline    Duration `yaml:"baseline"`
+	// VUs is the VU count during the burst, at most virtual_users
+	VUs      uint64   `yaml:"vus"`
+	Duration Duration `yaml:"duration"`
+	// Tolerance is how far above the baseline, in percent, the p95
+	// latency of a recovered target may be, DefaultBurstTolerance if unset
+	Tolerance float64  `yaml:"tolerance,omitempty"`
+	For       Duration `yaml:"for,omitempty"`
+	// MaxRecovery fails the run like a threshold when the target takes
+	// longer to recover, or does not recover before the run ends
+	MaxRecovery Duration `yaml:"max_recovery,omitempty"`
+}
+
+func validateBurst(sc *Scenario) error {
+	b := sc.Burst
+	if len(sc.Stages) > 0 {
+		return fmt.Errorf("burst cannot be combined with stages")
+	}
+	if b.BaselineVUs < 1 || b.BaselineVUs >= b.VUs {
+		return fmt.Errorf("baseline_vus must be at least 1 and less than vus")
+	}
+	if b.VUs > sc.VirtualUsers {
+		return fmt.Errorf("vus must be at most virtual_users (%d)", sc.VirtualUsers)
+	}
+	if b.Baseline.Duration <= 0 || b.Duration.Duration <= 0 {
+		return fmt.Errorf("baseline and duration must be greater than 0")
+	}
+	if b.Baseline.Duration+b.Duration.Duration >= time.Duration(sc.Duration)*time.Second {
+		return fmt.Errorf("baseline and duration must leave time to recover within the run's duration")
+	}
+	if b.Tolerance < 0 || b.For.Duration < 0 || b.MaxRecovery.Duration < 0 {
+		return fmt.Errorf("tolerance, for and max_recovery cannot be negative")
+	}
+
+	if b.Tolerance == 0 {
+		b.Tolerance = DefaultBurstTolerance
+	}
+	if b.For.Duration == 0 {
+		b.For.Duration = DefaultBurstFor
+	}
+	return nil
+}
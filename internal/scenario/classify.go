@@ -0,0 +1,31 @@
+package scenario
+
+import "fmt"
+
+// Results a classify rule can give a response
+const (
+	ClassifySuccess = "success"
+	ClassifyFailure = "failure"
+)
+
+// Classification is a rule of a step's classify. It matches the responses
+// that pass all the conditions it sets, which are those of a check; unlike
+// a check it needs no name.
+type Classification struct {
+	// Result is ClassifySuccess or ClassifyFailure
+	Result string `yaml:"result"`
+	Check  `yaml:",inline"`
+}
+
+func validateClassify(rules []Classification) error {
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Result != ClassifySuccess && rule.Result != ClassifyFailure {
+			return fmt.Errorf("classify[%d]: result must be '%s' or '%s'", i, ClassifySuccess, ClassifyFailure)
+		}
+		if err := validateCheck(&rule.Check); err != nil {
+			return fmt.Errorf("classify[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
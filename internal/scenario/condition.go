@@ -0,0 +1,300 @@
+package scenario
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Conditions decide whether a step with when runs. They compare variables
+// and literals:
+//
+//	condition  := and ("||" and)*
+//	and        := unary ("&&" unary)*
+//	unary      := "!" unary | "(" condition ")" | comparison
+//	comparison := operand [op operand]
+//	operand    := "${" name "}" | "'" text "'" | '"' text '"' | number | "true" | "false"
+//
+// Operators are ==, !=, <, <=, > and >=. Operands that are both numbers
+// compare numerically, others as strings. A single operand is true unless
+// it is empty, "false" or "0". The status of the previous response of the
+// iteration is ${last.status}.
+
+// LastStatus is the variable holding the status code of the previous
+// response of the iteration
+const LastStatus = "last.status"
+
+// Condition is a parsed when expression
+type Condition struct {
+	Expr string
+	root condNode
+	vars []string
+}
+
+type condNode interface {
+	eval(vars map[string]string) (string, error)
+}
+
+// ParseCondition parses a when expression
+func ParseCondition(expr string) (*Condition, error) {
+	p := &condParser{input: expr}
+	root, err := p.or()
+	if err == nil && p.peek() != 0 {
+		err = fmt.Errorf("unexpected '%s' at position %d", p.input[p.pos:], p.pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	return &Condition{Expr: expr, root: root, vars: p.vars}, nil
+}
+
+// Vars returns the names of the variables the condition references
+func (c *Condition) Vars() []string {
+	return c.vars
+}
+
+// Eval evaluates the condition against vars. A variable that is not set
+// is an error, like in placeholders.
+func (c *Condition) Eval(vars map[string]string) (bool, error) {
+	v, err := c.root.eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", c.Expr, err)
+	}
+	return truthy(v), nil
+}
+
+func truthy(v string) bool {
+	return v != "" && v != "false" && v != "0"
+}
+
+func boolString(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+type condLiteral string
+
+func (l condLiteral) eval(map[string]string) (string, error) { return string(l), nil }
+
+type condVar string
+
+func (v condVar) eval(vars map[string]string) (string, error) {
+	val, ok := vars[string(v)]
+	if !ok {
+		return "", fmt.Errorf("undefined variable %q", string(v))
+	}
+	return val, nil
+}
+
+type condNot struct{ operand condNode }
+
+func (n condNot) eval(vars map[string]string) (string, error) {
+	v, err := n.operand.eval(vars)
+	return boolString(!truthy(v)), err
+}
+
+// condLogic is && or ||, evaluated left to right with short-circuiting
+type condLogic struct {
+	and         bool
+	left, right condNode
+}
+
+func (l condLogic) eval(vars map[string]string) (string, error) {
+	v, err := l.left.eval(vars)
+	if err != nil {
+		return "", err
+	}
+	if truthy(v) != l.and {
+		return boolString(!l.and), nil
+	}
+	v, err = l.right.eval(vars)
+	return boolString(truthy(v)), err
+}
+
+type condCompare struct {
+	op          string
+	left, right condNode
+}
+
+func (c condCompare) eval(vars map[string]string) (string, error) {
+	l, err := c.left.eval(vars)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.right.eval(vars)
+	if err != nil {
+		return "", err
+	}
+
+	cmp := strings.Compare(l, r)
+	lf, lerr := strconv.ParseFloat(l, 64)
+	rf, rerr := strconv.ParseFloat(r, 64)
+	if lerr == nil && rerr == nil {
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	var result bool
+	switch c.op {
+	case "==":
+		result = cmp == 0
+	case "!=":
+		result = cmp != 0
+	case "<":
+		result = cmp < 0
+	case "<=":
+		result = cmp <= 0
+	case ">":
+		result = cmp > 0
+	case ">=":
+		result = cmp >= 0
+	}
+	return boolString(result), nil
+}
+
+// condOperators are ordered so two-character operators match first
+var condOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+type condParser struct {
+	input string
+	pos   int
+	vars  []string
+}
+
+func (p *condParser) peek() byte {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// consume skips tok and reports whether it was next
+func (p *condParser) consume(tok string) bool {
+	p.peek()
+	if !strings.HasPrefix(p.input[p.pos:], tok) {
+		return false
+	}
+	p.pos += len(tok)
+	return true
+}
+
+func (p *condParser) or() (condNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = condLogic{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) and() (condNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = condLogic{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) unary() (condNode, error) {
+	switch {
+	case p.peek() == '!' && !strings.HasPrefix(p.input[p.pos:], "!="):
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return condNot{operand: operand}, nil
+	case p.consume("("):
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+func (p *condParser) comparison() (condNode, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range condOperators {
+		if p.consume(op) {
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return condCompare{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *condParser) operand() (condNode, error) {
+	c := p.peek()
+	start := p.pos
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of condition")
+	case strings.HasPrefix(p.input[p.pos:], "${"):
+		end := strings.IndexByte(p.input[p.pos:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder at position %d", start)
+		}
+		name := strings.TrimSpace(p.input[p.pos+2 : p.pos+end])
+		if name == "" {
+			return nil, fmt.Errorf("empty placeholder at position %d", start)
+		}
+		p.pos += end + 1
+		if !slices.Contains(p.vars, name) {
+			p.vars = append(p.vars, name)
+		}
+		return condVar(name), nil
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(p.input[p.pos+1:], c)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string at position %d", start)
+		}
+		p.pos += end + 2
+		return condLiteral(p.input[start+1 : p.pos-1]), nil
+	}
+
+	for p.pos < len(p.input) && strings.IndexByte(" !=<>()&|'\"", p.input[p.pos]) < 0 {
+		p.pos++
+	}
+	word := p.input[start:p.pos]
+	if word == "true" || word == "false" {
+		return condLiteral(word), nil
+	}
+	if _, err := strconv.ParseFloat(word, 64); err != nil || word == "" {
+		return nil, fmt.Errorf("unexpected '%s' at position %d, quote strings and use ${name} for variables",
+			p.input[start:], start)
+	}
+	return condLiteral(word), nil
+}
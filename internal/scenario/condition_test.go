@@ -0,0 +1,95 @@
+package scenario
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCondition(t *testing.T) {
+	vars := map[string]string{
+		"role":        "admin",
+		"count":       "10",
+		"empty":       "",
+		"off":         "false",
+		LastStatus:    "404",
+		"with spaces": "a b",
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"${role} == 'admin'", true},
+		{`${role} != "admin"`, false},
+		{"${role}", true},
+		{"${empty}", false},
+		{"${off}", false},
+		{"!${off}", true},
+		{"${count} > 9", true},
+		{"${count} >= 10.0", true},
+		{"${count} < 9", false},
+		{"${count} <= 10", true},
+		{"'b' > 'a'", true},
+		{"${last.status} == 404", true},
+		{"${last.status} >= 400 && ${role} == 'user'", false},
+		{"${last.status} >= 400 || ${role} == 'user'", true},
+		{"!(${role} == 'admin' && ${count} > 100)", true},
+		{"${role} == 'user' || ${count} == 10 && ${empty} == ''", true},
+		{"true && !false", true},
+		{"${ with spaces } == 'a b'", true},
+		{"0", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCondition(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		got, err := c.Eval(vars)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCondition_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"${role} ==",
+		"${role",
+		"${}",
+		"role == 'admin'",
+		"'admin",
+		"(${role}",
+		"${role} == 'admin')",
+		"${a} = 1",
+	} {
+		if _, err := ParseCondition(expr); err == nil {
+			t.Errorf("ParseCondition(%q): expected error, got nil", expr)
+		}
+	}
+
+	// Short-circuiting skips undefined variables on the right
+	c, _ := ParseCondition("${missing} == 1")
+	if _, err := c.Eval(nil); err == nil {
+		t.Error("expected error for undefined variable, got nil")
+	}
+	c, _ = ParseCondition("false && ${missing} == 1")
+	if ok, err := c.Eval(nil); err != nil || ok {
+		t.Errorf("expected false without evaluating the right side, got %v, %v", ok, err)
+	}
+}
+
+func TestCondition_Vars(t *testing.T) {
+	c, err := ParseCondition("${last.status} == 200 && (${response.id} != '' || ${last.status} == 204)")
+	if err != nil {
+		t.Fatalf("ParseCondition() failed: %v", err)
+	}
+	if got := c.Vars(); !slices.Equal(got, []string{LastStatus, "response.id"}) {
+		t.Errorf("Vars() = %v, want each referenced variable once", got)
+	}
+}
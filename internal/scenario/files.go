@@ -0,0 +1,107 @@
+package scenario
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// fileRef is an external file referenced from a scenario. Field is the YAML
+// location of the reference so validation errors point at the right key.
+type fileRef struct {
+	Field string
+	Path  string
+}
+
+// fileRefs collects every external file the scenario references. Fields that
+// point at files (request bodies, data feeders, secrets, certificates, includes,
+// check schemas, OpenAPI documents) register themselves here so Validate can
+// check them before VUs start; rebase must handle them too.
+func (s *Scenario) fileRefs() []fileRef {
+	var refs []fileRef
+	if s.Data != nil && s.Data.Query == nil && s.Data.URL == "" {
+		refs = append(refs, fileRef{Field: "scenario.data.file", Path: s.Data.File})
+	}
+	if s.OpenAPI != nil && !s.OpenAPI.Remote() {
+		refs = append(refs, fileRef{Field: "scenario.openapi.spec", Path: s.OpenAPI.Spec})
+	}
+	if s.TLS != nil {
+		files := s.TLS.tlsFiles()
+		for _, key := range slices.Sorted(maps.Keys(files)) {
+			refs = append(refs, fileRef{Field: "scenario.tls." + key, Path: *files[key]})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Secrets)) {
+		if path, ok := strings.CutPrefix(s.Secrets[name], secretFilePrefix); ok {
+			refs = append(refs, fileRef{Field: "scenario.secrets." + name, Path: path})
+		}
+	}
+	for i := range s.AuthPools {
+		refs = append(refs, fileRef{Field: fmt.Sprintf("scenario.auth_pools[%d].credentials", i),
+			Path: s.AuthPools[i].Credentials})
+	}
+	for _, list := range s.stepLists() {
+		for i := range list.steps {
+			step := &list.steps[i]
+			if p := step.Payload; p != nil && p.Source == PayloadFile {
+				refs = append(refs, fileRef{Field: fmt.Sprintf("%s[%d].payload.path", list.field, i), Path: p.Path})
+			}
+			files := step.schemaFiles()
+			for _, field := range slices.Sorted(maps.Keys(files)) {
+				refs = append(refs, fileRef{Field: fmt.Sprintf("%s[%d].%s", list.field, i, field), Path: files[field].File})
+			}
+			if path := step.BodyFile; path != "" {
+				refs = append(refs, fileRef{Field: fmt.Sprintf("%s[%d].body_file", list.field, i), Path: path})
+			}
+			if m := step.Multipart; m != nil {
+				for j, f := range m.Files {
+					refs = append(refs, fileRef{Field: fmt.Sprintf("%s[%d].multipart.files[%d].path", list.field, i, j), Path: f.Path})
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// ResolvePath returns path resolved against the directory of the scenario
+// file. Absolute paths are returned unchanged.
+func (p *Parser) ResolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.baseDir, path)
+}
+
+func (p *Parser) validateFiles() error {
+	for _, ref := range p.scenario.fileRefs() {
+		if err := checkReadable(p.ResolvePath(ref.Path)); err != nil {
+			return fmt.Errorf("%s: %w", ref.Field, err)
+		}
+	}
+	return nil
+}
+
+func checkReadable(path string) error {
+	if path == "" {
+		return fmt.Errorf("file path cannot be empty")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("file %q is not readable: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file %q: %w", path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("file %q is a directory", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,751 @@
+package scenario
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolvePath_RelativeToScenarioFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "scenario.yaml")
+	data := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\nsteps:\n  - request: GET /\n"
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write scenario: %v", err)
+	}
+
+	p := NewParser()
+	if err := p.ParseFile(file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := p.ResolvePath("data/users.csv")
+	want := filepath.Join(dir, "data", "users.csv")
+	if got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestResolvePath_Absolute(t *testing.T) {
+	p := NewParser()
+	p.baseDir = "/scenarios"
+
+	abs := filepath.Join(t.TempDir(), "body.json")
+	if got := p.ResolvePath(abs); got != abs {
+		t.Errorf("expected '%s', got '%s'", abs, got)
+	}
+}
+
+func TestResolvePath_ParseDataUsesWorkingDir(t *testing.T) {
+	p := NewParser()
+	if err := p.ParseData([]byte("name: test")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.ResolvePath("body.json"); got != "body.json" {
+		t.Errorf("expected 'body.json', got '%s'", got)
+	}
+}
+
+func TestCheckReadable(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "body.json")
+	if err := os.WriteFile(file, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := checkReadable(file); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkReadable(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for missing file, got nil")
+	}
+	if err := checkReadable(dir); err == nil {
+		t.Error("expected error for directory, got nil")
+	}
+	if err := checkReadable(""); err == nil {
+		t.Error("expected error for empty path, got nil")
+	}
+}
+
+func TestValidate_Data(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.csv"), []byte("username\nalice\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users.json"), []byte("[]"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users.parquet"), nil, 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users.jsonl"), []byte("{\"username\": \"alice\"}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	tests := []struct {
+		data    string
+		wantErr bool
+	}{
+		{`{file: users.csv}`, false},
+		{`{file: users.csv, strategy: unique}`, false},
+		{`{file: users.csv, strategy: random}`, false},
+		{`{file: users.csv, strategy: shuffle}`, true},
+		{`{file: users.csv, partition: hash, key: username}`, false},
+		{`{file: users.csv, partition: range}`, false},
+		{`{file: users.csv, partition: modulo}`, true},
+		{`{file: users.csv, key: username}`, true},
+		{`{file: missing.csv}`, true},
+		{`{file: users.json}`, true},
+		{`{file: users.parquet}`, false},
+		{`{file: users.jsonl}`, false},
+		{`{file: users.json, format: ndjson}`, false},
+		{`{file: users.csv, format: xml}`, true},
+		{`{query: {driver: postgres, dsn: "postgres://db/shop", sql: "SELECT username FROM users"}}`, false},
+		{`{query: {driver: mysql, dsn: "user@tcp(db)/shop", sql: "SELECT 1", stream: true}}`, false},
+		{`{query: {driver: sqlite, dsn: shop.db, sql: "SELECT 1"}}`, true},
+		{`{query: {driver: postgres, dsn: "postgres://db/shop"}}`, true},
+		{`{file: users.csv, query: {driver: postgres, dsn: "postgres://db/shop", sql: "SELECT 1"}}`, true},
+		{`{query: {driver: postgres, dsn: "postgres://db/shop", sql: "SELECT 1", stream: true}, strategy: unique}`, true},
+		{`{url: "https://promo.example.com/codes", refresh: 5m}`, false},
+		{`{url: "ftp://promo.example.com/codes"}`, true},
+		{`{url: "https://promo.example.com/codes", format: ndjson}`, true},
+		{`{file: users.csv, refresh: 5m}`, true},
+		{`{file: users.csv, url: "https://promo.example.com/codes"}`, true},
+		{`{strategy: unique}`, true},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" +
+			"data: " + tt.data + "\nsteps:\n  - request: GET /\n"
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("data %s: expected error %v, got %v", tt.data, tt.wantErr, err)
+			continue
+		}
+		if err == nil {
+			if p.scenario.Data.File != "" && p.scenario.Data.Path != filepath.Join(dir, p.scenario.Data.File) {
+				t.Errorf("data %s: unexpected path '%s'", tt.data, p.scenario.Data.Path)
+			}
+			if p.scenario.Data.Strategy == "" || p.scenario.Data.Partition == "" ||
+				p.scenario.Data.File != "" && p.scenario.Data.Format == "" {
+				t.Errorf("data %s: expected a default strategy, partition and format", tt.data)
+			}
+		}
+	}
+}
+
+func TestValidate_AuthPools(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "admins.csv"), []byte("user,password\nroot,secret\n"), 0o644); err != nil {
+		t.Fatalf("failed to write credentials file: %v", err)
+	}
+
+	const login = "login: [{request: POST /login, save_to_context: {token: response.token}}]"
+	tests := []struct {
+		pools   string
+		wantErr bool
+	}{
+		{`[{name: admin, weight: 1, credentials: admins.csv, ` + login + `}]`, false},
+		{`[{name: admin, weight: 1, credentials: admins.csv, ttl: 15m, ` + login + `}]`, false},
+		{`[{name: admin, weight: 1, credentials: admins.csv, ` + login + `}, ` +
+			`{name: admin, weight: 1, credentials: admins.csv, ` + login + `}]`, true},
+		{`[{weight: 1, credentials: admins.csv, ` + login + `}]`, true},
+		{`[{name: admin, credentials: admins.csv, ` + login + `}]`, true},
+		{`[{name: admin, weight: 1, credentials: missing.csv, ` + login + `}]`, true},
+		{`[{name: admin, weight: 1, credentials: admins.csv}]`, true},
+		{`[{name: admin, weight: 1, credentials: admins.csv, ttl: -1s, ` + login + `}]`, true},
+		{`[{name: admin, weight: 1, credentials: admins.csv, ` +
+			`login: [{request: POST /login, retry: {max: 2}}]}]`, true},
+		{`[{name: a, weight: 1, credentials: admins.csv, ` + login + `}, ` +
+			`{name: b, weight: 1, credentials: admins.csv, ` + login + `}, ` +
+			`{name: c, weight: 1, credentials: admins.csv, ` + login + `}]`, true},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 2\nduration: 1\n" +
+			"auth_pools: " + tt.pools + "\nsteps:\n  - request: GET /\n"
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("auth_pools %s: expected error %v, got %v", tt.pools, tt.wantErr, err)
+			continue
+		}
+		if err == nil && p.scenario.AuthPools[0].Path != filepath.Join(dir, "admins.csv") {
+			t.Errorf("auth_pools %s: unexpected path '%s'", tt.pools, p.scenario.AuthPools[0].Path)
+		}
+	}
+}
+
+func TestValidate_FilePayload(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.jsonl"), []byte(`{"name":"alice"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write payload file: %v", err)
+	}
+
+	file := filepath.Join(dir, "scenario.yaml")
+	content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\nsteps:\n" +
+		"  - request: POST /users\n    payload: {source: file, path: users.jsonl}\n" +
+		"  - request: POST /admins\n    payload: {source: file, path: admins.jsonl, order: random}\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write scenario: %v", err)
+	}
+
+	p := NewParser()
+	if err := p.ParseFile(file); err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if err := p.Validate(); err == nil {
+		t.Fatal("expected error for missing payload file, got nil")
+	}
+
+	p.scenario.Steps = p.scenario.Steps[:1]
+	if err := p.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pl := p.scenario.Steps[0].Payload
+	if pl.ResolvedPath != filepath.Join(dir, "users.jsonl") || pl.Order != PayloadNext {
+		t.Errorf("unexpected payload after validation: %+v", pl)
+	}
+}
+
+func TestValidate_LifecyclePayloadFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "scenario.yaml")
+	content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" +
+		"steps:\n  - request: GET /\n" +
+		"setup:\n  - request: POST /users\n    payload: {source: file, path: users.jsonl}\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write scenario: %v", err)
+	}
+
+	p := NewParser()
+	if err := p.ParseFile(file); err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	err := p.Validate()
+	if err == nil || !strings.Contains(err.Error(), "scenario.setup[0].payload.path") {
+		t.Fatalf("expected error for missing setup payload file, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "users.jsonl"), []byte(`{"name":"alice"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write payload file: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pl := p.scenario.Setup[0].Payload; pl.ResolvedPath != filepath.Join(dir, "users.jsonl") {
+		t.Errorf("unexpected payload after validation: %+v", pl)
+	}
+}
+
+func TestValidate_BodyFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "upload.bin"), []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to write body file: %v", err)
+	}
+
+	for _, tt := range []struct {
+		steps   string
+		wantErr string
+	}{
+		{"  - request: PUT /files\n    body_file: upload.bin\n", ""},
+		{"  - request: PUT /files\n    body_file: missing.bin\n", "step[0].body_file"},
+		{"  - request: GET /files\n    body_file: upload.bin\n", "cannot have a body_file"},
+		{"  - request: PUT /files\n    body_file: upload.bin\n    body: data\n", "body and body_file are mutually exclusive"},
+		{"  - request: PUT /files\n    body_file: upload.bin\n    payload: {size: 10}\n", "payload and body_file are mutually exclusive"},
+		{"  - request: PUT /files\n    body_file: upload.bin\n    raw: \"PUT /files HTTP/1.1\"\n", "body_file cannot be combined with raw"},
+	} {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" +
+			"setup:\n  - request: POST /files\n    body_file: upload.bin\nsteps:\n" + tt.steps
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%q: expected error %q, got %v", tt.steps, tt.wantErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		want := filepath.Join(dir, "upload.bin")
+		if got := p.scenario.Steps[0].BodyFile; got != want {
+			t.Errorf("step body_file = %s, want %s", got, want)
+		}
+		if got := p.scenario.Setup[0].BodyFile; got != want {
+			t.Errorf("setup body_file = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestValidate_Multipart(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.pdf"), []byte("%PDF-1.7"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	file := "\n      files:\n        - {field: document, path: report.pdf, content_type: application/pdf}\n"
+	for _, tt := range []struct {
+		steps   string
+		wantErr string
+	}{
+		{"  - request: POST /documents\n    multipart:\n      fields: {title: Q3}" + file, ""},
+		{"  - request: POST /documents\n    multipart:\n      fields: {title: Q3}\n", ""},
+		{"  - request: POST /documents\n    multipart:\n      files:\n        - {field: document, path: missing.pdf}\n", "step[0].multipart.files[0].path"},
+		{"  - request: POST /documents\n    multipart:\n      files:\n        - {path: report.pdf}\n", "field is required"},
+		{"  - request: POST /documents\n    multipart:\n      files:\n        - {field: document}\n", "path is required"},
+		{"  - request: POST /documents\n    multipart:\n      files:\n        - {field: document, path: report.pdf, content_type: /pdf}\n", "invalid content_type"},
+		{"  - request: POST /documents\n    multipart: {}\n", "at least one field or file"},
+		{"  - request: GET /documents\n    multipart:\n      fields: {title: Q3}\n", "cannot have a multipart body"},
+		{"  - request: POST /documents\n    body: data\n    multipart:\n      fields: {title: Q3}\n", "body and multipart are mutually exclusive"},
+		{"  - request: POST /documents\n    body_file: report.pdf\n    multipart:\n      fields: {title: Q3}\n", "body_file and multipart are mutually exclusive"},
+		{"  - request: POST /documents\n    headers: {content-type: text/plain}\n    multipart:\n      fields: {title: Q3}\n", "sets the Content-Type header"},
+		{"  - request: POST /documents\n    raw: \"POST /documents HTTP/1.1\"\n    multipart:\n      fields: {title: Q3}\n", "multipart cannot be combined with raw"},
+	} {
+		scenarioFile := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" +
+			"setup:\n  - request: POST /documents\n    multipart:" + file + "steps:\n" + tt.steps
+		if err := os.WriteFile(scenarioFile, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(scenarioFile); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%q: expected error %q, got %v", tt.steps, tt.wantErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		want := filepath.Join(dir, "report.pdf")
+		if got := p.scenario.Setup[0].Multipart.Files[0].Path; got != want {
+			t.Errorf("setup multipart file path = %s, want %s", got, want)
+		}
+	}
+
+	// The scenario's Content-Type header is for the other steps
+	p := NewParser()
+	err := p.ParseData([]byte("name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" +
+		"headers: {content-type: application/json, X-Team: qa}\nsteps:\n" +
+		"  - request: POST /documents\n    multipart:\n      fields: {title: Q3}\n  - request: POST /orders\n"))
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("unexpected error with a scenario Content-Type header: %v", err)
+	}
+	if got := p.scenario.Steps[0].Headers; len(got) != 1 || got["X-Team"] != "qa" {
+		t.Errorf("multipart step headers = %v, want only X-Team", got)
+	}
+	if got := p.scenario.Steps[1].Headers["content-type"]; got != "application/json" {
+		t.Errorf("expected the other step to keep the Content-Type header, got %q", got)
+	}
+}
+
+// certificatePEM returns a self-signed certificate and its key as PEM
+func certificatePEM(t *testing.T) (cert, key []byte) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "load-tester"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestValidate_TLS(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := certificatePEM(t)
+	otherCert, _ := certificatePEM(t)
+	for name, data := range map[string][]byte{"client.crt": cert, "client.key": key} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	inline := func(pem []byte) string {
+		return "|\n    " + strings.ReplaceAll(strings.TrimSpace(string(pem)), "\n", "\n    ")
+	}
+
+	for _, tt := range []struct {
+		name, tls string
+		wantErr   bool
+	}{
+		{"files", "\n  cert: client.crt\n  key: client.key", false},
+		{"inline", "\n  cert: " + inline(cert) + "\n  key: " + inline(key) + "\n  ca: " + inline(otherCert), false},
+		{"file and inline", "\n  cert: client.crt\n  key: " + inline(key), false},
+		{"CA only", "\n  ca: client.crt", false},
+		{"missing key", "\n  cert: client.crt", true},
+		{"missing file", "\n  cert: client.crt\n  key: missing.key", true},
+		{"key of another certificate", "\n  cert: " + inline(otherCert) + "\n  key: client.key", true},
+		{"not a CA", "\n  ca: client.key", true},
+		{"insecure", "\n  insecure_skip_verify: true", false},
+		{"insecure with a CA", "\n  ca: client.crt\n  insecure_skip_verify: true", true},
+	} {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: https://localhost\nvirtual_users: 1\nduration: 1\ntls:" + tt.tls +
+			"\nsteps:\n  - request: GET /\n"
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", tt.name, err)
+		}
+		err := p.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got := p.scenario.TLS
+		if (got.Certificate != nil) != (got.Cert != "") || (got.RootCAs != nil) != (got.CA != "") {
+			t.Errorf("%s: certificate %v and CAs %v not loaded", tt.name, got.Certificate != nil, got.RootCAs != nil)
+		}
+	}
+}
+
+func TestValidate_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	tests := []struct {
+		secrets string
+		wantErr bool
+	}{
+		{`{token: "env:API_TOKEN"}`, false},
+		{`{token: "file:token"}`, false},
+		{`{token: "vault:secret/data/app#token"}`, false},
+		{`{token: "file:missing"}`, true},
+		{`{token: "API_TOKEN"}`, true},
+		{`{token: "s3:bucket/key"}`, true},
+		{`{"bad name": "env:API_TOKEN"}`, true},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" +
+			"secrets: " + tt.secrets + "\nsteps:\n  - request: GET /\n"
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("secrets %s: expected error %v, got %v", tt.secrets, tt.wantErr, err)
+		}
+	}
+
+	// File references are resolved against the scenario's directory
+	p := NewParser()
+	if err := p.ParseFile(filepath.Join(dir, "scenario.yaml")); err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	p.scenario.Secrets = map[string]string{"token": "file:token"}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := p.scenario.Secrets["token"], "file:"+filepath.Join(dir, "token"); got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		yaml    string
+		wantErr string
+	}{
+		{"steps:\n  - request: GET /\n", ""},
+		{"include: [common.yaml]\nsteps:\n  - request: GET /\n", "scenario.include"},
+		{"data: {file: /etc/passwd}\nsteps:\n  - request: GET /\n", "scenario.data.file"},
+		{"steps:\n  - request: POST /\n    body_file: /etc/passwd\n", "step[0].body_file"},
+		{"setup:\n  - request: POST /\n    body_file: /etc/passwd\nsteps:\n  - request: GET /\n", "scenario.setup[0].body_file"},
+		{"secrets: {token: \"env:HOME\"}\nsteps:\n  - request: GET /\n", "scenario.secrets.token"},
+		{"secrets: {token: \"file:/etc/passwd\"}\nsteps:\n  - request: GET /\n", "scenario.secrets.token"},
+		{"secrets: {token: \"vault:secret/data/app#token\"}\nsteps:\n  - request: GET /\n", "scenario.secrets.token"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser()
+		err := p.ParseRemote([]byte(scenarioHeader + tt.yaml))
+		if err == nil {
+			err = p.Validate()
+		}
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.yaml, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%q: expected error about %s, got %v", tt.yaml, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_CheckSchema(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"user.json":    `{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}}}`,
+		"user.yaml":    "type: object\nrequired: [id]\n",
+		"invalid.json": `{"type": "thing"}`,
+		"broken.json":  `{"type": `,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write schema file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		schema  string
+		wantErr bool
+	}{
+		{"user.json", false},
+		{"user.yaml", false},
+		{"{type: object, properties: {id: {type: integer}}}", false},
+		{"missing.json", true},
+		{"invalid.json", true},
+		{"broken.json", true},
+		{"{type: thing}", true},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\nsteps:\n" +
+			"  - request: GET /users/1\n    checks: [{name: user, schema: " + tt.schema + "}]\n"
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("schema %s: expected error %v, got %v", tt.schema, tt.wantErr, err)
+			continue
+		}
+		schema := p.scenario.Steps[0].Checks[0].Schema
+		if err == nil && schema.File != "" && schema.Path != filepath.Join(dir, tt.schema) {
+			t.Errorf("schema %s: unexpected path '%s'", tt.schema, schema.Path)
+		}
+	}
+}
+
+func TestValidate_OpenAPISpec(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.yaml"), []byte("openapi: 3.0.3\n"), 0o644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	for _, tt := range []struct {
+		spec    string
+		pinned  string
+		path    string
+		wantErr bool
+	}{
+		{"api.yaml", "{}", filepath.Join(dir, "api.yaml"), false},
+		{"missing.yaml", "{}", "", true},
+		{"'https://api.example.com/openapi.json'", "{}", "https://api.example.com/openapi.json", false},
+		{"api.yaml", "{'GET /users/{id}': 3a8f0c1d2e4b5a69}", filepath.Join(dir, "api.yaml"), false},
+		{"api.yaml", "{'/users': 3a8f0c1d2e4b5a69}", "", true},
+	} {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" +
+			"openapi: {spec: " + tt.spec + ", validate_responses: true, pinned: " + tt.pinned + "}\nsteps:\n  - request: GET /\n"
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("spec %s: expected error %v, got %v", tt.spec, tt.wantErr, err)
+			continue
+		}
+		if err == nil && p.scenario.OpenAPI.Path != tt.path {
+			t.Errorf("spec %s: unexpected path '%s'", tt.spec, p.scenario.OpenAPI.Path)
+		}
+	}
+}
+
+func TestValidate_Operations(t *testing.T) {
+	dir := t.TempDir()
+	spec := `
+openapi: 3.0.3
+info: {title: users, version: "1"}
+paths:
+  /v2/users:
+    post:
+      operationId: createUser
+      responses: {"201": {description: created}}
+    get:
+      operationId: listUsers
+      parameters:
+        - {name: page, in: query, required: true, schema: {type: integer}}
+      responses: {"200": {description: ok}}
+  /v2/users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+    get:
+      operationId: getUser
+      responses: {"200": {description: ok}}
+`
+	if err := os.WriteFile(filepath.Join(dir, "api.yaml"), []byte(spec), 0o644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		openapi string
+		steps   string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "resolved",
+			openapi: "openapi: {spec: api.yaml}\n",
+			steps: `
+setup:
+  - operation: createUser
+steps:
+  - operation: getUser
+    path_params: {id: "7"}
+  - name: first page
+    operation: listUsers
+    query: {page: "1"}
+`,
+			want: []string{"createUser = POST /v2/users", "getUser = GET /v2/users/{id}", "first page = GET /v2/users"},
+		},
+		{
+			name:    "matching request",
+			openapi: "openapi: {spec: api.yaml}\n",
+			steps:   "steps:\n  - operation: createUser\n    request: POST /v2/users\n",
+			want:    []string{"createUser = POST /v2/users"},
+		},
+		{
+			name:    "renamed path",
+			openapi: "openapi: {spec: api.yaml}\n",
+			steps:   "steps:\n  - operation: createUser\n    request: POST /users\n",
+			wantErr: "operation createUser is POST /v2/users in the OpenAPI document",
+		},
+		{
+			name:    "unknown operation",
+			openapi: "openapi: {spec: api.yaml}\n",
+			steps:   "steps:\n  - operation: deleteUser\n",
+			wantErr: "operation deleteUser is not in the OpenAPI document",
+		},
+		{
+			name:    "missing path param",
+			openapi: "openapi: {spec: api.yaml}\n",
+			steps:   "steps:\n  - operation: getUser\n",
+			wantErr: "path_params.id is required",
+		},
+		{
+			name:    "missing required query",
+			openapi: "openapi: {spec: api.yaml}\n",
+			steps:   "steps:\n  - operation: listUsers\n",
+			wantErr: "query.page is required",
+		},
+		{
+			name:    "no document",
+			steps:   "steps:\n  - operation: createUser\n",
+			wantErr: "operation requires scenario.openapi",
+		},
+	} {
+		file := filepath.Join(dir, "scenario.yaml")
+		content := "name: test\nbase_url: http://localhost\nvirtual_users: 1\nduration: 1\n" + tt.openapi + tt.steps
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write scenario: %v", err)
+		}
+
+		p := NewParser()
+		if err := p.ParseFile(file); err != nil {
+			t.Fatalf("unexpected parse error: %v", err)
+		}
+		err := p.Validate()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		// Validating again keeps the resolved requests
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s: unexpected error validating again: %v", tt.name, err)
+		}
+
+		var got []string
+		for _, step := range slices.Concat(p.scenario.Setup, p.scenario.Steps) {
+			got = append(got, step.ID()+" = "+step.Request)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected steps %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package scenario
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// templateFunc computes the value of a ${name(args)} placeholder. Functions
+// run every time a placeholder is substituted, so each request gets a fresh
+// value.
+type templateFunc func(args []string) (string, error)
+
+// templateFuncs are the functions available in placeholders
+var templateFuncs = map[string]templateFunc{
+	"uuid":        uuidFunc,
+	"now_iso8601": nowISO8601Func,
+	"random_int":  randomIntFunc,
+	"base64":      base64Func,
+}
+
+// call evaluates expr when it is a function call such as random_int(1,10).
+// It reports false when expr is not a call and should be looked up as a
+// variable. Arguments are integers, quoted strings or variable names.
+func call(expr string, vars map[string]string) (string, bool, error) {
+	expr = strings.TrimSpace(expr)
+	open := strings.IndexByte(expr, '(')
+	if open < 0 || !strings.HasSuffix(expr, ")") {
+		return "", false, nil
+	}
+	name := strings.TrimSpace(expr[:open])
+	fn, ok := templateFuncs[name]
+	if !ok {
+		return "", true, fmt.Errorf("unknown function %q", name)
+	}
+
+	args, err := callArgs(expr[open+1:len(expr)-1], vars)
+	if err != nil {
+		return "", true, fmt.Errorf("%s: %w", name, err)
+	}
+	val, err := fn(args)
+	if err != nil {
+		return "", true, fmt.Errorf("%s: %w", name, err)
+	}
+	return val, true, nil
+}
+
+func callArgs(list string, vars map[string]string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+
+	split, err := splitArgs(list)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	for _, arg := range split {
+		arg = strings.TrimSpace(arg)
+		switch {
+		case len(arg) >= 2 && (arg[0] == '"' || arg[0] == '\'') && arg[len(arg)-1] == arg[0]:
+			args = append(args, arg[1:len(arg)-1])
+		case arg != "" && (arg[0] == '-' || arg[0] >= '0' && arg[0] <= '9'):
+			args = append(args, arg)
+		default:
+			val, ok := vars[arg]
+			if !ok {
+				return nil, fmt.Errorf("undefined variable %q", arg)
+			}
+			args = append(args, val)
+		}
+	}
+	return args, nil
+}
+
+// splitArgs splits list at the commas outside quoted strings
+func splitArgs(list string) ([]string, error) {
+	var (
+		args  []string
+		quote byte
+		start int
+	)
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			args = append(args, list[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string in %q", list)
+	}
+	return append(args, list[start:]), nil
+}
+
+func arity(args []string, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("takes %d arguments, got %d", n, len(args))
+	}
+	return nil
+}
+
+func uuidFunc(args []string) (string, error) {
+	if err := arity(args, 0); err != nil {
+		return "", err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+func nowISO8601Func(args []string) (string, error) {
+	if err := arity(args, 0); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"), nil
+}
+
+// randomIntFunc returns an integer between its two arguments, inclusive
+func randomIntFunc(args []string) (string, error) {
+	if err := arity(args, 2); err != nil {
+		return "", err
+	}
+	lo, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid minimum %q", args[0])
+	}
+	hi, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid maximum %q", args[1])
+	}
+	if hi < lo {
+		return "", fmt.Errorf("maximum %d is below minimum %d", hi, lo)
+	}
+	span := uint64(hi-lo) + 1
+	if span == 0 {
+		// The whole int64 range
+		return strconv.FormatInt(int64(rand.Uint64()), 10), nil
+	}
+	return strconv.FormatInt(lo+int64(rand.Uint64N(span)), 10), nil
+}
+
+func base64Func(args []string) (string, error) {
+	if err := arity(args, 1); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(args[0])), nil
+}
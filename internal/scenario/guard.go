@@ -0,0 +1,132 @@
+package scenario
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Guard actions
+const (
+	// GuardStop ends the run when the guard trips
+	GuardStop = "stop"
+	// GuardHold pauses the VUs while the guard is tripped
+	GuardHold = "hold"
+)
+
+// DefaultGuardInterval is how often guards are polled by default
+const DefaultGuardInterval = 5 * time.Second
+
+// Guard polls a signal from outside the run, such as the target's CPU
+// usage or queue depth, and stops or holds the load while it meets
+// Condition. The signal is a number read from URL, either its whole
+// response or the JSON field at Field, or the result of a Prometheus
+// instant query.
+type Guard struct {
+	Name string `yaml:"name"`
+	// URL is polled with GET requests
+	URL   string `yaml:"url,omitempty"`
+	Field string `yaml:"field,omitempty"`
+	// Prometheus is the base URL of the Prometheus server Query is sent to
+	Prometheus string `yaml:"prometheus,omitempty"`
+	Query      string `yaml:"query,omitempty"`
+	// Condition trips the guard, a comparison of the signal such as "> 90"
+	Condition string `yaml:"condition"`
+	// Interval is the time between polls, DefaultGuardInterval if unset
+	Interval Duration `yaml:"interval,omitempty"`
+	// Action is GuardStop (default) or GuardHold
+	Action string `yaml:"action,omitempty"`
+}
+
+// guardOperators are ordered so two-character operators match first
+var guardOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// Trips reports whether value meets the guard's condition
+func (g *Guard) Trips(value float64) (bool, error) {
+	op, limit, err := parseGuardCondition(g.Condition)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "<":
+		return value < limit, nil
+	case "<=":
+		return value <= limit, nil
+	case ">":
+		return value > limit, nil
+	case ">=":
+		return value >= limit, nil
+	case "==":
+		return value == limit, nil
+	}
+	return value != limit, nil
+}
+
+func parseGuardCondition(cond string) (string, float64, error) {
+	cond = strings.TrimSpace(cond)
+	for _, op := range guardOperators {
+		if rest, ok := strings.CutPrefix(cond, op); ok {
+			limit, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid condition %q: %q is not a number", cond, strings.TrimSpace(rest))
+			}
+			return op, limit, nil
+		}
+	}
+	return "", 0, fmt.Errorf("invalid condition %q, expected a comparison such as '> 90'", cond)
+}
+
+func validateGuards(guards []Guard) error {
+	names := make(map[string]bool, len(guards))
+	for i := range guards {
+		g := &guards[i]
+		if g.Name == "" {
+			return fmt.Errorf("scenario.guards[%d].name is required", i)
+		}
+		if names[g.Name] {
+			return fmt.Errorf("scenario.guards[%d]: duplicate name '%s'", i, g.Name)
+		}
+		names[g.Name] = true
+
+		if err := validateGuard(g); err != nil {
+			return fmt.Errorf("scenario.guards[%d] (%s): %w", i, g.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateGuard(g *Guard) error {
+	switch {
+	case (g.URL == "") == (g.Prometheus == ""):
+		return fmt.Errorf("exactly one of url and prometheus is required")
+	case g.Prometheus != "" && g.Query == "":
+		return fmt.Errorf("query is required with prometheus")
+	case g.URL != "" && g.Query != "":
+		return fmt.Errorf("query only applies to prometheus")
+	case g.Prometheus != "" && g.Field != "":
+		return fmt.Errorf("field only applies to url")
+	}
+	target := g.URL + g.Prometheus
+	if u, err := url.Parse(target); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("'%s' must be an absolute URL", target)
+	}
+	if _, _, err := parseGuardCondition(g.Condition); err != nil {
+		return err
+	}
+	if g.Interval.Duration < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	if g.Interval.Duration == 0 {
+		g.Interval.Duration = DefaultGuardInterval
+	}
+	switch g.Action {
+	case "":
+		g.Action = GuardStop
+	case GuardStop, GuardHold:
+	default:
+		return fmt.Errorf("action must be %s or %s", GuardStop, GuardHold)
+	}
+	return nil
+}
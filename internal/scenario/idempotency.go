@@ -0,0 +1,82 @@
+package scenario
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Idempotency comparisons of a replayed response with the original one
+const (
+	// CompareJSON compares the status and the JSON bodies, leaving out
+	// the ignored fields; bodies that are not JSON are compared as bytes
+	CompareJSON = "json"
+	// CompareBody compares the status and the bodies byte for byte
+	CompareBody = "body"
+	// CompareStatus compares the status only
+	CompareStatus = "status"
+)
+
+// DefaultReplays is how many times an idempotency check sends a request
+// again unless it sets replays
+const DefaultReplays = 1
+
+// maxReplays bounds the replays of a request
+const maxReplays = 20
+
+// Idempotency replays the request of a step and compares the responses to
+// the replays with the original response. Each replay that differs is an
+// idempotency violation, e.g. a payment charged twice for one idempotency
+// key. Replays send the request exactly as the original was sent, with
+// the same variables, so generated values such as ${uuid} repeat too.
+type Idempotency struct {
+	// Replays is how many times the request is sent again
+	Replays int `yaml:"replays,omitempty"`
+	// Concurrent sends the replays at the same time rather than one after
+	// the other, to catch races between duplicate requests
+	Concurrent bool `yaml:"concurrent,omitempty"`
+	// Compare is how responses are compared: json (default), body or
+	// status
+	Compare string `yaml:"compare,omitempty"`
+	// Ignore lists the fields of JSON bodies that may differ, such as
+	// timestamps, as dot-separated paths, e.g. created_at or
+	// items.*.updated_at, where * matches any key or index
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// ReplayStep returns the name the replays of an idempotency check of step
+// are reported under
+func ReplayStep(step string) string {
+	return step + " (replay)"
+}
+
+func validateIdempotency(step *Step) error {
+	i := step.Idempotency
+	if i == nil {
+		return nil
+	}
+	if i.Concurrent && step.Raw != "" {
+		return fmt.Errorf("concurrent cannot be combined with raw, which sends requests on one connection")
+	}
+	if i.Replays == 0 {
+		i.Replays = DefaultReplays
+	}
+	if i.Replays < 0 || i.Replays > maxReplays {
+		return fmt.Errorf("replays must be between 1 and %d", maxReplays)
+	}
+	switch i.Compare {
+	case "":
+		i.Compare = CompareJSON
+	case CompareJSON, CompareBody, CompareStatus:
+	default:
+		return fmt.Errorf("unknown compare '%s', expected json, body or status", i.Compare)
+	}
+	if len(i.Ignore) > 0 && i.Compare != CompareJSON {
+		return fmt.Errorf("ignore only applies to compare json")
+	}
+	for j, path := range i.Ignore {
+		if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+			return fmt.Errorf("ignore[%d]: invalid path '%s'", j, path)
+		}
+	}
+	return nil
+}
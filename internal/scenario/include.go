@@ -0,0 +1,268 @@
+package scenario
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxIncludeDepth bounds how deeply included files may include others
+const maxIncludeDepth = 16
+
+// resolveIncludes merges the files sc includes into it, recursively.
+// Included files are partial scenarios read relative to dir, the directory
+// of the including file. They merge in order with the including file last:
+// later values win for settings and maps such as variables, while lists
+// such as steps and thresholds are concatenated, so shared login steps
+// come before the file's own.
+func resolveIncludes(sc *Scenario, dir string, chain []string) error {
+	if len(sc.Include) == 0 {
+		return nil
+	}
+	if len(chain) > maxIncludeDepth {
+		return fmt.Errorf("includes are nested more than %d levels deep", maxIncludeDepth)
+	}
+
+	merged := &Scenario{}
+	for _, inc := range sc.Include {
+		path := inc
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("include %q: %w", inc, err)
+		}
+		if slices.Contains(chain, abs) {
+			return fmt.Errorf("include cycle: %s", strings.Join(append(chain, abs), " -> "))
+		}
+
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return fmt.Errorf("include %q: failed to read file: %w", inc, err)
+		}
+		var part Scenario
+		if err := yaml.Unmarshal(data, &part); err != nil {
+			return fmt.Errorf("include %q: failed to parse YAML: %w", inc, err)
+		}
+		if err := resolveIncludes(&part, filepath.Dir(abs), append(chain, abs)); err != nil {
+			return fmt.Errorf("include %q: %w", inc, err)
+		}
+		part.rebase(filepath.Dir(abs))
+		merged.merge(&part)
+	}
+
+	include := sc.Include
+	merged.merge(sc)
+	merged.Include = include
+	*sc = *merged
+	return nil
+}
+
+// merge applies o over s: settings and map entries set in o replace those
+// of s, lists of o are appended to those of s
+func (s *Scenario) merge(o *Scenario) {
+	if o.Name != "" {
+		s.Name = o.Name
+	}
+	if o.BaseURL != "" {
+		s.BaseURL, s.BaseURLs = o.BaseURL, o.BaseURLs
+	}
+	if o.VirtualUsers != 0 {
+		s.VirtualUsers = o.VirtualUsers
+	}
+	if o.Duration != 0 {
+		s.Duration = o.Duration
+	}
+	if o.HTTPVersion != "" {
+		s.HTTPVersion = o.HTTPVersion
+	}
+	if o.Connections != nil {
+		s.Connections = o.Connections
+	}
+	if o.TLS != nil {
+		s.TLS = o.TLS
+	}
+	if o.Proxy != nil {
+		s.Proxy = o.Proxy
+	}
+	if o.DNS != nil {
+		s.DNS = o.DNS
+	}
+	if o.Redirects != nil {
+		s.Redirects = o.Redirects
+	}
+	if o.ResponseBody != nil {
+		s.ResponseBody = o.ResponseBody
+	}
+	if o.TransportRetry != nil {
+		s.TransportRetry = o.TransportRetry
+	}
+	if o.MaxRPS != 0 {
+		s.MaxRPS = o.MaxRPS
+	}
+	if o.Seed != 0 {
+		s.Seed = o.Seed
+	}
+	if len(o.Stages) > 0 {
+		s.Stages = o.Stages
+	}
+	if o.Burst != nil {
+		s.Burst = o.Burst
+	}
+	if o.Noise != nil {
+		s.Noise = o.Noise
+	}
+	if o.Data != nil {
+		s.Data = o.Data
+	}
+	if o.OpenAPI != nil {
+		s.OpenAPI = o.OpenAPI
+	}
+
+	s.Variables = mergeMap(s.Variables, o.Variables)
+	s.Headers = mergeMap(s.Headers, o.Headers)
+	s.Secrets = mergeMap(s.Secrets, o.Secrets)
+	s.Hosts = mergeMap(s.Hosts, o.Hosts)
+	s.RateLimits = mergeMap(s.RateLimits, o.RateLimits)
+
+	s.LabelHeaders = append(s.LabelHeaders, o.LabelHeaders...)
+	s.Thresholds = append(s.Thresholds, o.Thresholds...)
+	s.Budgets = append(s.Budgets, o.Budgets...)
+	s.Guards = append(s.Guards, o.Guards...)
+	s.Setup = append(s.Setup, o.Setup...)
+	s.Teardown = append(s.Teardown, o.Teardown...)
+	s.Steps = append(s.Steps, o.Steps...)
+	s.Scenarios = append(s.Scenarios, o.Scenarios...)
+	s.AuthPools = append(s.AuthPools, o.AuthPools...)
+}
+
+func mergeMap[V any](dst, src map[string]V) map[string]V {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]V, len(src))
+	}
+	maps.Copy(dst, src)
+	return dst
+}
+
+// Resolved returns the loaded scenario as YAML with its includes merged in
+// and every file reference made absolute, so that it parses to the same
+// scenario from any working directory. Cluster workers are handed this
+// rather than the scenario file, which they would resolve against their
+// own working directory.
+//
+// It starts over from the source rather than from the loaded scenario,
+// which Validate fills with defaults and derived steps.
+func (p *Parser) Resolved() ([]byte, error) {
+	if p.scenario == nil {
+		return nil, fmt.Errorf("no scenario loaded")
+	}
+
+	var sc Scenario
+	if err := yaml.Unmarshal(p.source, &sc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if err := resolveIncludes(&sc, p.baseDir, p.chain); err != nil {
+		return nil, err
+	}
+	sc.Include = nil
+	sc.rebase(p.baseDir)
+	return yaml.Marshal(&sc)
+}
+
+// rebase makes the relative file references of an included file absolute,
+// as they are relative to the included file rather than to the scenario
+// including it. It covers the same fields as fileRefs.
+func (s *Scenario) rebase(dir string) {
+	abs := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		if p, err := filepath.Abs(filepath.Join(dir, path)); err == nil {
+			return p
+		}
+		return path
+	}
+
+	if s.Data != nil {
+		s.Data.File = abs(s.Data.File)
+	}
+	if s.OpenAPI != nil && !s.OpenAPI.Remote() {
+		s.OpenAPI.Spec = abs(s.OpenAPI.Spec)
+	}
+	if s.TLS != nil {
+		for _, path := range s.TLS.tlsFiles() {
+			*path = abs(*path)
+		}
+	}
+	for name, ref := range s.Secrets {
+		if path, ok := strings.CutPrefix(ref, secretFilePrefix); ok {
+			s.Secrets[name] = secretFilePrefix + abs(path)
+		}
+	}
+	rebaseSteps := func(steps []Step) {
+		for i := range steps {
+			if p := steps[i].Payload; p != nil && p.Source == PayloadFile {
+				p.Path = abs(p.Path)
+			}
+			for _, schema := range steps[i].schemaFiles() {
+				schema.File = abs(schema.File)
+			}
+			steps[i].BodyFile = abs(steps[i].BodyFile)
+			if m := steps[i].Multipart; m != nil {
+				for j := range m.Files {
+					m.Files[j].Path = abs(m.Files[j].Path)
+				}
+			}
+		}
+	}
+	for _, list := range s.stepLists() {
+		rebaseSteps(list.steps)
+	}
+	for i := range s.AuthPools {
+		s.AuthPools[i].Credentials = abs(s.AuthPools[i].Credentials)
+	}
+}
+
+// applyHeaders adds the scenario's headers to every step but raw ones,
+// keeping headers a step sets itself. Multipart steps do not get the
+// Content-Type header.
+func (s *Scenario) applyHeaders() {
+	if len(s.Headers) == 0 {
+		return
+	}
+	apply := func(steps []Step) {
+		for i := range steps {
+			if steps[i].Raw != "" {
+				continue
+			}
+			headers := maps.Clone(s.Headers)
+			if steps[i].Multipart != nil {
+				// Multipart bodies set their own Content-Type, with the
+				// boundary
+				maps.DeleteFunc(headers, func(name, _ string) bool {
+					return strings.EqualFold(name, "Content-Type")
+				})
+			}
+			maps.Copy(headers, steps[i].Headers)
+			steps[i].Headers = headers
+		}
+	}
+	apply(s.Setup)
+	apply(s.Teardown)
+	apply(s.Steps)
+	for i := range s.Scenarios {
+		apply(s.Scenarios[i].Steps)
+	}
+	for i := range s.AuthPools {
+		apply(s.AuthPools[i].Login)
+	}
+}
@@ -0,0 +1,178 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestParseFile_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"common/base.yaml": `
+base_url: http://localhost
+headers: {Accept: application/json, X-Client: loadforge}
+variables: {user: alice, tenant: acme}
+`,
+		"common/auth.yaml": `
+include: [base.yaml]
+thresholds: ["p95 < 1s"]
+steps:
+  - name: login
+    request: POST /login
+    payload: {source: file, path: bodies/login.jsonl}
+`,
+		"common/bodies/login.jsonl": "{\"user\":\"alice\"}\n",
+		"checkout.yaml": `
+include: [common/auth.yaml]
+name: checkout
+virtual_users: 1
+duration: 10
+variables: {user: bob}
+thresholds: ["error_rate < 1%"]
+steps:
+  - request: GET /cart
+    headers: {Accept: text/html}
+`,
+	})
+
+	p := NewParser()
+	if err := p.ParseFile(filepath.Join(dir, "checkout.yaml")); err != nil {
+		t.Fatalf("ParseFile() failed: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	sc := p.scenario
+
+	if sc.Name != "checkout" || sc.BaseURL != "http://localhost" {
+		t.Errorf("expected settings from both files, got name %q, base_url %q", sc.Name, sc.BaseURL)
+	}
+	if sc.Variables["user"] != "bob" || sc.Variables["tenant"] != "acme" {
+		t.Errorf("expected the including file's variables to win, got %v", sc.Variables)
+	}
+	if len(sc.Thresholds) != 2 || sc.Thresholds[0] != "p95 < 1s" {
+		t.Errorf("expected included thresholds first, got %v", sc.Thresholds)
+	}
+	if len(sc.Steps) != 2 || sc.Steps[0].ID() != "login" || sc.Steps[1].ID() != "GET /cart" {
+		t.Fatalf("expected the included login step first, got %+v", sc.Steps)
+	}
+
+	if h := sc.Steps[0].Headers; h["Accept"] != "application/json" || h["X-Client"] != "loadforge" {
+		t.Errorf("expected shared headers on the included step, got %v", h)
+	}
+	if h := sc.Steps[1].Headers; h["Accept"] != "text/html" || h["X-Client"] != "loadforge" {
+		t.Errorf("expected the step's own headers to win, got %v", h)
+	}
+
+	want := filepath.Join(dir, "common", "bodies", "login.jsonl")
+	if got := sc.Steps[0].Payload.ResolvedPath; got != want {
+		t.Errorf("expected payload path relative to the included file, got %q, want %q", got, want)
+	}
+}
+
+func TestParseFile_IncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml":       "include: [b.yaml]\n",
+		"b.yaml":       "include: [a.yaml]\n",
+		"missing.yaml": "include: [nope.yaml]\n",
+		"broken.yaml":  "include: [invalid.yaml]\n",
+		"invalid.yaml": "steps: [\n",
+	})
+
+	tests := map[string]string{
+		"a.yaml":       "include cycle",
+		"missing.yaml": "failed to read file",
+		"broken.yaml":  "failed to parse YAML",
+	}
+	for file, want := range tests {
+		err := NewParser().ParseFile(filepath.Join(dir, file))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", file, want, err)
+		}
+	}
+}
+
+func TestResolved(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"common/steps.yaml": `
+scenarios:
+  - name: browse
+    weight: 3
+    steps:
+      - request: GET /items
+        checks: [{name: schema, schema: schemas/items.json}]
+        response_body: {}
+  - name: buy
+    weight: 1
+    steps:
+      - request: POST /orders
+        body_file: bodies/order.json
+`,
+		"common/schemas/items.json": `{"type": "object"}`,
+		"common/bodies/order.json":  `{"sku": 1}`,
+		"users.csv":                 "username\nalice\n",
+		"shop.yaml": `
+include: [common/steps.yaml]
+name: shop
+base_url: http://localhost
+virtual_users: 4
+duration: 10
+response_body: {max_size: 64KB}
+data: {file: users.csv}
+`,
+	})
+
+	p := NewParser()
+	if err := p.ParseFile(filepath.Join(dir, "shop.yaml")); err != nil {
+		t.Fatalf("ParseFile() failed: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	data, err := p.Resolved()
+	if err != nil {
+		t.Fatalf("Resolved() failed: %v", err)
+	}
+
+	// Parsed without a directory, as cluster workers do
+	resolved := NewParser()
+	if err := resolved.ParseData(data); err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	if err := resolved.Validate(); err != nil {
+		t.Fatalf("Validate() of the resolved scenario failed: %v", err)
+	}
+	sc := resolved.scenario
+	if len(sc.Include) != 0 || len(sc.Steps) != 2 {
+		t.Fatalf("expected the includes merged, got includes %v and %d steps", sc.Include, len(sc.Steps))
+	}
+	if want := filepath.Join(dir, "users.csv"); sc.Data.File != want {
+		t.Errorf("expected data file %s, got %s", want, sc.Data.File)
+	}
+	if want := filepath.Join(dir, "common/bodies/order.json"); sc.Steps[1].BodyFile != want {
+		t.Errorf("expected body file %s, got %s", want, sc.Steps[1].BodyFile)
+	}
+	if want := filepath.Join(dir, "common/schemas/items.json"); sc.Steps[0].Checks[0].Schema.File != want {
+		t.Errorf("expected schema file %s, got %s", want, sc.Steps[0].Checks[0].Schema.File)
+	}
+	if sc.ResponseBody.MaxSize.Bytes != 64<<10 {
+		t.Errorf("expected max_size to survive, got %d", sc.ResponseBody.MaxSize.Bytes)
+	}
+}
@@ -0,0 +1,168 @@
+package scenario
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OpenAPI links the scenario to the OpenAPI document of the API under test
+type OpenAPI struct {
+	// Spec is the path of the document, relative to the scenario file, or
+	// the http(s) URL the API serves it at
+	Spec string `yaml:"spec"`
+	// ValidateResponses checks the status and body of every response
+	// against the responses the document defines for the step's
+	// operation, reporting mismatches as contract violations
+	ValidateResponses bool `yaml:"validate_responses,omitempty"`
+	// Pinned maps the operations the scenario exercises, e.g.
+	// "GET /pets/{petId}", to the fingerprint of their shape when the
+	// scenario was written. Runs warn about those that changed since.
+	Pinned map[string]string `yaml:"pinned,omitempty"`
+
+	// Path is Spec resolved against the scenario file's directory once
+	// validated
+	Path string `yaml:"-"`
+}
+
+// Remote reports whether Spec is a URL rather than a file
+func (o *OpenAPI) Remote() bool {
+	return strings.HasPrefix(o.Spec, "http://") || strings.HasPrefix(o.Spec, "https://")
+}
+
+// validateOpenAPI resolves the document's path and checks the pinned
+// operations are requests
+func (p *Parser) validateOpenAPI() error {
+	o := p.scenario.OpenAPI
+	if o == nil {
+		return nil
+	}
+	o.Path = p.specPath()
+	for op := range o.Pinned {
+		if _, _, err := ParseRequest(op); err != nil {
+			return fmt.Errorf("scenario.openapi.pinned: %w", err)
+		}
+	}
+	return nil
+}
+
+// specPath returns the location of the scenario's OpenAPI document, a path
+// resolved against the scenario file's directory or a URL
+func (p *Parser) specPath() string {
+	o := p.scenario.OpenAPI
+	if o.Remote() {
+		return o.Spec
+	}
+	return p.ResolvePath(o.Spec)
+}
+
+// resolveOperations sets the request of every step naming an operation to
+// the operation's method and path in the OpenAPI document. Steps without a
+// name are named after their operation, so that their metrics keep their
+// name when the API renames the path.
+func (p *Parser) resolveOperations() error {
+	sc := p.scenario
+	type stepList struct {
+		field string
+		steps []Step
+	}
+	lists := []stepList{{"scenario.steps", sc.Steps}, {"scenario.setup", sc.Setup}, {"scenario.teardown", sc.Teardown}}
+	for i, w := range sc.Scenarios {
+		lists = append(lists, stepList{fmt.Sprintf("scenario.scenarios[%d] (%s).steps", i, w.Name), w.Steps})
+	}
+
+	var doc *openapi3.T
+	for _, list := range lists {
+		field, steps := list.field, list.steps
+		for i := range steps {
+			step := &steps[i]
+			if step.Operation == "" {
+				continue
+			}
+			if sc.OpenAPI == nil {
+				return fmt.Errorf("%s[%d]: operation requires scenario.openapi", field, i)
+			}
+			if doc == nil {
+				var err error
+				if doc, err = loadOpenAPI(p.specPath()); err != nil {
+					return fmt.Errorf("scenario.openapi.spec: %w", err)
+				}
+			}
+			if err := resolveOperation(doc, step); err != nil {
+				return fmt.Errorf("%s[%d]: %w", field, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadOpenAPI parses the OpenAPI document at location, a file path or a URL
+func loadOpenAPI(location string) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+
+	var (
+		doc *openapi3.T
+		err error
+	)
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		var u *url.URL
+		if u, err = url.Parse(location); err != nil {
+			return nil, err
+		}
+		doc, err = loader.LoadFromURI(u)
+	} else {
+		doc, err = loader.LoadFromFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+// resolveOperation sets step's request to that of its operation in doc and
+// checks that the step gives a value to the operation's path parameters
+// and required query parameters
+func resolveOperation(doc *openapi3.T, step *Step) error {
+	for _, path := range doc.Paths.InMatchingOrder() {
+		item := doc.Paths.Value(path)
+		for method, op := range item.Operations() {
+			if op.OperationID != step.Operation {
+				continue
+			}
+
+			request := method + " " + path
+			if step.Request != "" && step.Request != request {
+				return fmt.Errorf("operation %s is %s in the OpenAPI document, not %s; remove the request",
+					step.Operation, request, step.Request)
+			}
+			step.Request = request
+			if step.Name == "" {
+				step.Name = step.Operation
+			}
+
+			params := slices.Concat(item.Parameters, op.Parameters)
+			for _, ref := range params {
+				param := ref.Value
+				if param == nil {
+					continue
+				}
+				switch {
+				case param.In == openapi3.ParameterInPath:
+					if _, ok := step.PathParams[param.Name]; !ok && step.Raw == "" {
+						return fmt.Errorf("operation %s: path_params.%s is required", step.Operation, param.Name)
+					}
+				case param.In == openapi3.ParameterInQuery && param.Required:
+					if _, ok := step.Query[param.Name]; !ok && step.Raw == "" {
+						return fmt.Errorf("operation %s: query.%s is required", step.Operation, param.Name)
+					}
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("operation %s is not in the OpenAPI document", step.Operation)
+}
@@ -1,41 +1,96 @@
 package scenario
 
 import (
+	"cmp"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"maps"
+	"math"
+	"mime"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"loadforge-agent/internal/feeder"
+	"loadforge-agent/internal/payload"
+	"loadforge-agent/internal/secrets"
+	"loadforge-agent/internal/threshold"
 )
 
 type Parser struct {
 	scenario *Scenario
+	// baseDir is the directory relative file references are resolved
+	// against. It is the scenario file's directory when loaded with
+	// ParseFile and empty (the working directory) for ParseData.
+	baseDir string
+	// source and chain are what the scenario was parsed from, for Resolved
+	source []byte
+	chain  []string
+	// remote is set for scenarios submitted over the network, see
+	// ParseRemote
+	remote bool
 }
 
 func NewParser() *Parser {
 	return &Parser{}
 }
 
-func (p *Parser) ParseFile(filepath string) error {
-	data, err := os.ReadFile(filepath)
+func (p *Parser) ParseFile(filename string) error {
+	data, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return p.ParseData(data)
+	var chain []string
+	if abs, err := filepath.Abs(filename); err == nil {
+		chain = []string{abs}
+	}
+	return p.parse(data, filepath.Dir(filename), chain)
 }
 
+// ParseData parses scenario file content. Includes are resolved against
+// the working directory.
 func (p *Parser) ParseData(data []byte) error {
+	return p.parse(data, "", nil)
+}
+
+// ParseRemote parses scenario file content submitted over the network, such
+// as through the control API. Whoever submits it has no access to the
+// agent's host, so includes are rejected, and Validate rejects references to
+// the host's files and secrets, which the run would otherwise read and could
+// send to any base URL.
+func (p *Parser) ParseRemote(data []byte) error {
+	p.remote = true
+	return p.parse(data, "", nil)
+}
+
+func (p *Parser) parse(data []byte, dir string, chain []string) error {
 	var scenario Scenario
 	if err := yaml.Unmarshal(data, &scenario); err != nil {
 		return fmt.Errorf("failed to parse YAML: %w", err)
 	}
+	if p.remote && len(scenario.Include) > 0 {
+		return fmt.Errorf("scenario.include: includes are not allowed in remotely submitted scenarios")
+	}
+	if err := resolveIncludes(&scenario, dir, chain); err != nil {
+		return err
+	}
+	scenario.applyHeaders()
 
 	p.scenario = &scenario
+	p.baseDir = dir
+	p.source, p.chain = data, chain
 	return nil
 }
 
@@ -46,26 +101,92 @@ func (p *Parser) GetScenario() (*Scenario, error) {
 	return p.scenario, nil
 }
 
-func (s *Scenario) FindStep(request string) *Step {
+// FindStep returns the step referenced by ref, or nil when there is none.
+// See StepIndex for how references are resolved.
+func (s *Scenario) FindStep(ref string) *Step {
+	if i := s.StepIndex(ref); i >= 0 {
+		return &s.Steps[i]
+	}
+	return nil
+}
+
+// StepIndex returns the position of the step referenced by ref. A step name
+// always wins; otherwise ref must match the request line of exactly one
+// step. It returns -1 when no step or more than one step matches.
+func (s *Scenario) StepIndex(ref string) int {
 	for i := range s.Steps {
-		if s.Steps[i].Request == request {
-			return &s.Steps[i]
+		if s.Steps[i].Name != "" && s.Steps[i].Name == ref {
+			return i
 		}
 	}
-	return nil
+
+	found := -1
+	for i := range s.Steps {
+		if s.Steps[i].Request == ref {
+			if found >= 0 {
+				return -1
+			}
+			found = i
+		}
+	}
+	return found
+}
+
+// stepList is a list of steps of a scenario and its YAML location
+type stepList struct {
+	field string
+	steps []Step
+}
+
+// stepLists returns every list of steps of s: its steps, those of its
+// weighted scenarios until they are expanded into its steps, its setup and
+// teardown steps and the login steps of its auth pools
+func (s *Scenario) stepLists() []stepList {
+	lists := []stepList{{"step", s.Steps}}
+	if !s.expanded {
+		for i := range s.Scenarios {
+			lists = append(lists, stepList{fmt.Sprintf("scenario.scenarios[%d].steps", i), s.Scenarios[i].Steps})
+		}
+	}
+	lists = append(lists, stepList{"scenario.setup", s.Setup}, stepList{"scenario.teardown", s.Teardown})
+	for i := range s.AuthPools {
+		lists = append(lists, stepList{fmt.Sprintf("scenario.auth_pools[%d].login", i), s.AuthPools[i].Login})
+	}
+	return lists
 }
 
 const maxDelay = 10 * time.Minute
 
+// maxTimeout bounds a step's request timeout
+const maxTimeout = 10 * time.Minute
+
+// maxRepeat bounds a step's repeat count
+const maxRepeat = 10_000
+
+// maxRetries and maxBackoff bound a step's retry policy
+const (
+	maxRetries = 10
+	maxBackoff = time.Minute
+)
+
 func (p *Parser) Validate() error {
 	if p.scenario == nil {
 		return fmt.Errorf("no scenario loaded")
 	}
 
+	if p.remote {
+		if err := validateRemote(p.scenario); err != nil {
+			return err
+		}
+	}
+
 	if p.scenario.Name == "" {
 		return fmt.Errorf("scenario.name is required")
 	}
 
+	if err := validateBaseURLs(p.scenario); err != nil {
+		return err
+	}
 	if p.scenario.BaseURL == "" {
 		return fmt.Errorf("scenario.base_url is required")
 	}
@@ -82,59 +203,295 @@ func (p *Parser) Validate() error {
 		return fmt.Errorf("scenario.duration must be less than 1 year (31556952 seconds)")
 	}
 
-	if len(p.scenario.Steps) == 0 {
+	switch p.scenario.HTTPVersion {
+	case "":
+		p.scenario.HTTPVersion = HTTPVersionAuto
+	case HTTPVersionAuto, HTTPVersion1, HTTPVersion2:
+	default:
+		return fmt.Errorf("scenario.http_version: unknown version '%s', must be one of: %s, %s, %s",
+			p.scenario.HTTPVersion, HTTPVersionAuto, HTTPVersion1, HTTPVersion2)
+	}
+
+	if p.scenario.Connections != nil {
+		if err := validateConnections(p.scenario.Connections); err != nil {
+			return fmt.Errorf("scenario.connections: %w", err)
+		}
+	}
+
+	if p.scenario.Proxy != nil {
+		if err := validateProxy(p.scenario.Proxy); err != nil {
+			return fmt.Errorf("scenario.proxy: %w", err)
+		}
+	}
+
+	if p.scenario.DNS != nil {
+		if err := validateDNS(p.scenario.DNS); err != nil {
+			return fmt.Errorf("scenario.dns: %w", err)
+		}
+	}
+
+	if err := validateHosts(p.scenario.Hosts); err != nil {
+		return fmt.Errorf("scenario.hosts: %w", err)
+	}
+
+	if p.scenario.Redirects != nil {
+		if err := validateRedirects(p.scenario.Redirects); err != nil {
+			return fmt.Errorf("scenario.redirects: %w", err)
+		}
+	}
+
+	if p.scenario.TransportRetry != nil {
+		if err := validateTransportRetry(p.scenario.TransportRetry); err != nil {
+			return fmt.Errorf("scenario.transport_retry: %w", err)
+		}
+	}
+
+	for i, expr := range p.scenario.Thresholds {
+		if _, err := threshold.Parse(expr); err != nil {
+			return fmt.Errorf("scenario.thresholds[%d]: %w", i, err)
+		}
+	}
+
+	if err := validateStages(p.scenario); err != nil {
+		return err
+	}
+
+	if p.scenario.Burst != nil {
+		if err := validateBurst(p.scenario); err != nil {
+			return fmt.Errorf("scenario.burst: %w", err)
+		}
+	}
+
+	if p.scenario.Noise != nil {
+		if err := validateNoise(p.scenario.Noise); err != nil {
+			return fmt.Errorf("scenario.noise: %w", err)
+		}
+	}
+
+	if err := validateGuards(p.scenario.Guards); err != nil {
+		return err
+	}
+
+	if err := p.resolveOperations(); err != nil {
+		return err
+	}
+
+	if len(p.scenario.Scenarios) > 0 {
+		if err := p.validateScenarios(); err != nil {
+			return err
+		}
+	} else if err := validateSteps(p.scenario); err != nil {
+		return err
+	}
+
+	if err := validateLifecycle("setup", p.scenario.Setup); err != nil {
+		return err
+	}
+
+	if err := validateLifecycle("teardown", p.scenario.Teardown); err != nil {
+		return err
+	}
+
+	if err := validateRateLimits(p.scenario); err != nil {
+		return err
+	}
+
+	if err := validateResponseBodies(p.scenario); err != nil {
+		return err
+	}
+
+	if err := validateSecrets(p.scenario.Secrets); err != nil {
+		return err
+	}
+
+	if err := validateLabelHeaders(p.scenario); err != nil {
+		return err
+	}
+
+	if err := p.validateFiles(); err != nil {
+		return err
+	}
+
+	if err := p.validateData(); err != nil {
+		return err
+	}
+
+	if err := p.validateTLS(); err != nil {
+		return fmt.Errorf("scenario.tls: %w", err)
+	}
+
+	if err := p.validateAuthPools(); err != nil {
+		return err
+	}
+
+	for _, list := range p.scenario.stepLists() {
+		steps := list.steps
+		for i := range steps {
+			if pl := steps[i].Payload; pl != nil && pl.Source == PayloadFile {
+				pl.ResolvedPath = p.ResolvePath(pl.Path)
+			}
+			steps[i].BodyFile = p.ResolvePath(steps[i].BodyFile)
+			if m := steps[i].Multipart; m != nil {
+				for j := range m.Files {
+					m.Files[j].Path = p.ResolvePath(m.Files[j].Path)
+				}
+			}
+		}
+	}
+	if err := p.validateOpenAPI(); err != nil {
+		return err
+	}
+
+	if err := p.validateSchemas(); err != nil {
+		return err
+	}
+	for name, ref := range p.scenario.Secrets {
+		if path, ok := strings.CutPrefix(ref, secretFilePrefix); ok {
+			p.scenario.Secrets[name] = secretFilePrefix + p.ResolvePath(path)
+		}
+	}
+
+	return nil
+}
+
+// validateSteps checks the steps and budgets of sc
+func validateSteps(sc *Scenario) error {
+	if len(sc.Steps) == 0 {
 		return fmt.Errorf("scenario.steps: at least one step is required")
 	}
 
-	uniqueRequests := make(map[string]struct{})
+	uniqueSteps := make(map[string]struct{})
+	requestCount := make(map[string]int)
+	for i := range sc.Steps {
+		requestCount[sc.Steps[i].Request]++
+	}
 
-	for i := range p.scenario.Steps {
-		step := &p.scenario.Steps[i]
+	for i := range sc.Steps {
+		step := &sc.Steps[i]
 
 		if step.Request == "" {
-			return fmt.Errorf("step[%d]: request field is required", i)
+			return fmt.Errorf("step[%d]: request or operation is required", i)
 		}
 
-		if _, exists := uniqueRequests[step.Request]; exists {
-			return fmt.Errorf("step[%d]: duplicate request '%s'", i, step.Request)
+		if _, exists := uniqueSteps[step.ID()]; exists {
+			if step.Name != "" {
+				return fmt.Errorf("step[%d]: duplicate name '%s'", i, step.Name)
+			}
+			return fmt.Errorf("step[%d]: duplicate request '%s', give the steps distinct names",
+				i, step.Request)
 		}
-		uniqueRequests[step.Request] = struct{}{}
+		uniqueSteps[step.ID()] = struct{}{}
 
-		httpMethod, _, err := parseRequest(step.Request)
+		httpMethod, _, err := ParseRequest(step.Request)
 		if err != nil {
 			return fmt.Errorf("step[%d]: %w", i, err)
 		}
 
-		if (httpMethod == http.MethodGet || httpMethod == http.MethodHead) &&
-			step.Body != nil {
-			return fmt.Errorf("step[%d] (%s): GET and HEAD requests cannot have a body",
+		if (httpMethod == http.MethodGet || httpMethod == http.MethodHead ||
+			httpMethod == http.MethodTrace) && step.Body != nil {
+			return fmt.Errorf("step[%d] (%s): GET, HEAD and TRACE requests cannot have a body",
 				i, step.Request)
 		}
 
-		if step.Delay.Duration < 0 {
-			return fmt.Errorf("step[%d] (%s): delay must be non-negative", i, step.Request)
+		if err := validateBodyFile(step); err != nil {
+			return fmt.Errorf("step[%d] (%s): %w", i, step.Request, err)
+		}
+		if err := validateMultipart(step); err != nil {
+			return fmt.Errorf("step[%d] (%s): %w", i, step.Request, err)
+		}
+
+		if step.Payload != nil {
+			if step.Body != nil {
+				return fmt.Errorf("step[%d] (%s): body and payload are mutually exclusive", i, step.Request)
+			}
+			if httpMethod == http.MethodGet || httpMethod == http.MethodHead || httpMethod == http.MethodTrace {
+				return fmt.Errorf("step[%d] (%s): GET, HEAD and TRACE requests cannot have a payload",
+					i, step.Request)
+			}
+			if err := validatePayload(step.Payload); err != nil {
+				return fmt.Errorf("step[%d] (%s), payload: %w", i, step.Request, err)
+			}
+		}
+
+		if err := validateRaw(step); err != nil {
+			return fmt.Errorf("step[%d] (%s): %w", i, step.Request, err)
 		}
 
-		if step.Delay.Duration > maxDelay {
-			return fmt.Errorf("step[%d] (%s): delay must not exceed %s", i, step.Request, maxDelay)
+		if err := validateDryRun(step); err != nil {
+			return fmt.Errorf("step[%d] (%s): %w", i, step.Request, err)
 		}
 
+		if err := validateDelay(&step.Delay); err != nil {
+			return fmt.Errorf("step[%d] (%s): %w", i, step.Request, err)
+		}
+
+		if step.Timeout.Duration < 0 || step.Timeout.Duration > maxTimeout {
+			return fmt.Errorf("step[%d] (%s): timeout must be between 0 and %s", i, step.Request, maxTimeout)
+		}
+
+		if step.Repeat < 0 || step.Repeat > maxRepeat {
+			return fmt.Errorf("step[%d] (%s): repeat must be between 0 and %d", i, step.Request, maxRepeat)
+		}
+
+		if step.When != "" {
+			if _, err := ParseCondition(step.When); err != nil {
+				return fmt.Errorf("step[%d] (%s), when: %w", i, step.Request, err)
+			}
+		}
+
+		if step.Retry != nil {
+			if err := validateRetry(step.Retry); err != nil {
+				return fmt.Errorf("step[%d] (%s), retry: %w", i, step.Request, err)
+			}
+		}
+
+		if err := validateChecks(step.Checks); err != nil {
+			return fmt.Errorf("step[%d] (%s), %w", i, step.Request, err)
+		}
+		if err := validateIdempotency(step); err != nil {
+			return fmt.Errorf("step[%d] (%s), idempotency: %w", i, step.Request, err)
+		}
+		if err := validateClassify(step.Classify); err != nil {
+			return fmt.Errorf("step[%d] (%s), %w", i, step.Request, err)
+		}
+
+		for j, expr := range step.Thresholds {
+			if _, err := threshold.Parse(expr); err != nil {
+				return fmt.Errorf("step[%d] (%s), thresholds[%d]: %w", i, step.Request, j, err)
+			}
+		}
+
+		weighted := 0
 		for j := range step.NextSteps {
 			nextStep := &step.NextSteps[j]
-
-			if nextStep.Request == "" {
-				return fmt.Errorf("step[%d], next_step[%d]: request field is required", i, j)
+			if nextStep.Weight < 0 {
+				return fmt.Errorf("step[%d], next_step[%d]: weight cannot be negative", i, j)
+			}
+			if nextStep.Weight > 0 {
+				weighted++
 			}
 
-			_, _, err := parseRequest(nextStep.Request)
-			if err != nil {
-				return fmt.Errorf("step[%d], next_step[%d]: %w", i, j, err)
+			switch {
+			case nextStep.Request == "" && nextStep.Step == "":
+				return fmt.Errorf("step[%d], next_step[%d]: request or step field is required", i, j)
+			case nextStep.Request != "" && nextStep.Step != "":
+				return fmt.Errorf("step[%d], next_step[%d]: request and step are mutually exclusive", i, j)
+			case nextStep.Request != "":
+				_, _, err := ParseRequest(nextStep.Request)
+				if err != nil {
+					return fmt.Errorf("step[%d], next_step[%d]: %w", i, j, err)
+				}
 			}
 
-			targetStep := p.scenario.FindStep(nextStep.Request)
+			target := nextStep.Target()
+			targetStep := sc.FindStep(target)
 			if targetStep == nil {
+				if nextStep.Step == "" && requestCount[target] > 1 {
+					return fmt.Errorf("step[%d], next_step[%d]: request '%s' is ambiguous, "+
+						"reference the target step by name", i, j, target)
+				}
 				return fmt.Errorf("step[%d], next_step[%d]: target step '%s' not found",
-					i, j, nextStep.Request)
+					i, j, target)
 			}
 
 			for k, code := range nextStep.StatusCodes {
@@ -151,12 +508,365 @@ func (p *Parser) Validate() error {
 				}
 			}
 		}
+		if weighted > 0 && weighted < len(step.NextSteps) {
+			return fmt.Errorf("step[%d]: either every next_step or none must have a weight", i)
+		}
+	}
+
+	uniqueBudgets := make(map[string]struct{})
+	for i := range sc.Budgets {
+		budget := &sc.Budgets[i]
+		if err := validateBudget(sc, budget); err != nil {
+			return fmt.Errorf("scenario.budgets[%d]: %w", i, err)
+		}
+		if _, exists := uniqueBudgets[budget.Name]; exists {
+			return fmt.Errorf("scenario.budgets[%d]: duplicate name '%s'", i, budget.Name)
+		}
+		uniqueBudgets[budget.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+// validateLifecycle checks setup or teardown steps, which run once in
+// order and so cannot branch, be rate limited, retry or have thresholds,
+// checks and idempotency checks
+func validateLifecycle(field string, steps []Step) error {
+	for i := range steps {
+		step := &steps[i]
+		if step.Request == "" {
+			return fmt.Errorf("scenario.%s[%d]: request or operation is required", field, i)
+		}
+		method, _, err := ParseRequest(step.Request)
+		if err != nil {
+			return fmt.Errorf("scenario.%s[%d]: %w", field, i, err)
+		}
+		if (method == http.MethodGet || method == http.MethodHead || method == http.MethodTrace) && step.Body != nil {
+			return fmt.Errorf("scenario.%s[%d] (%s): GET, HEAD and TRACE requests cannot have a body",
+				field, i, step.Request)
+		}
+		if err := validateRaw(step); err != nil {
+			return fmt.Errorf("scenario.%s[%d] (%s): %w", field, i, step.Request, err)
+		}
+		if err := validateBodyFile(step); err != nil {
+			return fmt.Errorf("scenario.%s[%d] (%s): %w", field, i, step.Request, err)
+		}
+		if err := validateMultipart(step); err != nil {
+			return fmt.Errorf("scenario.%s[%d] (%s): %w", field, i, step.Request, err)
+		}
+		if err := validateDryRun(step); err != nil {
+			return fmt.Errorf("scenario.%s[%d] (%s): %w", field, i, step.Request, err)
+		}
+		if step.Payload != nil {
+			if step.Body != nil {
+				return fmt.Errorf("scenario.%s[%d] (%s): body and payload are mutually exclusive", field, i, step.Request)
+			}
+			if method == http.MethodGet || method == http.MethodHead || method == http.MethodTrace {
+				return fmt.Errorf("scenario.%s[%d] (%s): GET, HEAD and TRACE requests cannot have a payload",
+					field, i, step.Request)
+			}
+			if err := validatePayload(step.Payload); err != nil {
+				return fmt.Errorf("scenario.%s[%d] (%s), payload: %w", field, i, step.Request, err)
+			}
+		}
+		if err := validateDelay(&step.Delay); err != nil {
+			return fmt.Errorf("scenario.%s[%d] (%s): %w", field, i, step.Request, err)
+		}
+		if step.Timeout.Duration < 0 || step.Timeout.Duration > maxTimeout {
+			return fmt.Errorf("scenario.%s[%d] (%s): timeout must be between 0 and %s", field, i, step.Request, maxTimeout)
+		}
+		if step.Repeat < 0 || step.Repeat > maxRepeat {
+			return fmt.Errorf("scenario.%s[%d] (%s): repeat must be between 0 and %d", field, i, step.Request, maxRepeat)
+		}
+		if step.When != "" {
+			if _, err := ParseCondition(step.When); err != nil {
+				return fmt.Errorf("scenario.%s[%d] (%s), when: %w", field, i, step.Request, err)
+			}
+		}
+
+		var unsupported string
+		switch {
+		case len(step.NextSteps) > 0:
+			unsupported = "next_steps"
+		case len(step.Thresholds) > 0:
+			unsupported = "thresholds"
+		case step.RateLimit != "":
+			unsupported = "rate_limit"
+		case step.Retry != nil:
+			unsupported = "retry"
+		case len(step.Checks) > 0:
+			unsupported = "checks"
+		case len(step.Classify) > 0:
+			unsupported = "classify"
+		case step.Idempotency != nil:
+			unsupported = "idempotency"
+		}
+		if unsupported != "" {
+			return fmt.Errorf("scenario.%s[%d] (%s): %s is not supported in %s steps",
+				field, i, step.Request, unsupported, field)
+		}
+	}
+	return nil
+}
+
+func validateChecks(checks []Check) error {
+	names := make(map[string]bool, len(checks))
+	for i, c := range checks {
+		if c.Name == "" {
+			return fmt.Errorf("checks[%d]: name is required", i)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("checks[%d]: duplicate name '%s'", i, c.Name)
+		}
+		names[c.Name] = true
+		if err := validateCheck(&c); err != nil {
+			return fmt.Errorf("checks[%d] (%s): %w", i, c.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateCheck checks the conditions of c, of which there must be at
+// least one
+func validateCheck(c *Check) error {
+	if c.Describe() == "" {
+		return fmt.Errorf("set a condition, status, body_contains, body_matches, headers, max_latency or schema")
+	}
+	if c.Condition != "" {
+		if _, err := ParseCondition(c.Condition); err != nil {
+			return err
+		}
+	}
+	for j, code := range c.Status {
+		if err := validateStatusCode(code); err != nil {
+			return fmt.Errorf("status[%d]: %w", j, err)
+		}
+	}
+	if c.BodyMatches != "" {
+		if _, err := regexp.Compile(c.BodyMatches); err != nil {
+			return fmt.Errorf("body_matches: %w", err)
+		}
+	}
+	for name := range c.Headers {
+		if !headerNamePattern.MatchString(name) {
+			return fmt.Errorf("headers: invalid header name '%s'", name)
+		}
+	}
+	if c.MaxLatency.Duration < 0 {
+		return fmt.Errorf("max_latency must be non-negative")
+	}
+	if c.Schema != nil && c.Schema.File == "" {
+		if _, err := c.Schema.Load(); err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateRetry checks a retry policy and defaults its conditions
+func validateRetry(r *Retry) error {
+	if r.Max < 1 || r.Max > maxRetries {
+		return fmt.Errorf("max must be between 1 and %d", maxRetries)
+	}
+	if r.Backoff.Duration < 0 || r.Backoff.Duration > maxBackoff {
+		return fmt.Errorf("backoff must be between 0 and %s", maxBackoff)
+	}
+	if len(r.On) == 0 {
+		r.On = []string{"5xx", RetryOnError}
+	}
+	for i, cond := range r.On {
+		if cond == RetryOnTimeout || cond == RetryOnError {
+			continue
+		}
+		if err := validateStatusCode(cond); err != nil {
+			return fmt.Errorf("on[%d]: %w, or use '%s' or '%s'", i, err, RetryOnTimeout, RetryOnError)
+		}
+	}
+	return nil
+}
+
+// validateResponseBodies checks the response_body of the scenario and of
+// its steps and logins, which must keep the bodies they read. Setup and
+// teardown steps always keep whole bodies.
+func validateResponseBodies(sc *Scenario) error {
+	if sc.ResponseBody != nil {
+		if err := validateResponseBody(sc.ResponseBody); err != nil {
+			return fmt.Errorf("scenario.response_body: %w", err)
+		}
+	}
+	// Only the responses of steps are validated against the OpenAPI document
+	contract := sc.OpenAPI != nil && sc.OpenAPI.ValidateResponses
+	check := func(field string, i int, step *Step, contract bool) error {
+		if step.ResponseBody != nil {
+			if err := validateResponseBody(step.ResponseBody); err != nil {
+				return fmt.Errorf("%s[%d] (%s), response_body: %w", field, i, step.ID(), err)
+			}
+		}
+		body := cmp.Or(step.ResponseBody, sc.ResponseBody)
+		switch {
+		case body == nil:
+		case body.Discard && readsBody(step, contract):
+			return fmt.Errorf("%s[%d] (%s): the step reads the response body, which response_body.discard drops; "+
+				"set response_body: {} on the step to keep it", field, i, step.ID())
+		case body.MaxSize.Bytes > 0 && needsWholeBody(step, contract):
+			return fmt.Errorf("%s[%d] (%s): the step validates or compares whole response bodies, which "+
+				"response_body.max_size truncates; set response_body: {} on the step to keep them", field, i, step.ID())
+		}
+		return nil
+	}
+	for i := range sc.Steps {
+		if err := check("step", i, &sc.Steps[i], contract); err != nil {
+			return err
+		}
+	}
+	for _, phase := range []string{"setup", "teardown"} {
+		steps := sc.Setup
+		if phase == "teardown" {
+			steps = sc.Teardown
+		}
+		for i := range steps {
+			if steps[i].ResponseBody != nil {
+				return fmt.Errorf("scenario.%s[%d] (%s): response_body is not supported, %s steps keep whole bodies",
+					phase, i, steps[i].ID(), phase)
+			}
+		}
+	}
+	for j, pool := range sc.AuthPools {
+		for i := range pool.Login {
+			if err := check(fmt.Sprintf("scenario.auth_pools[%d].login", j), i, &pool.Login[i], false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateResponseBody(body *ResponseBody) error {
+	if body.MaxSize.Bytes < 0 {
+		return fmt.Errorf("max_size cannot be negative")
+	}
+	if body.Discard && body.MaxSize.Bytes > 0 {
+		return fmt.Errorf("max_size and discard are mutually exclusive")
+	}
+	return nil
+}
+
+// readsBody reports whether step saves, maps, checks, classifies or
+// compares values of its response body, or has it validated against the
+// OpenAPI document when contract is set
+func readsBody(step *Step, contract bool) bool {
+	if contract || needsWholeBody(step, false) {
+		return true
+	}
+	fromBody := func(ref string) bool { return strings.HasPrefix(ref, "response.") }
+	for _, ref := range step.SaveToContext {
+		if fromBody(ref) {
+			return true
+		}
+	}
+	for _, next := range step.NextSteps {
+		for ref := range next.Map {
+			if fromBody(ref) {
+				return true
+			}
+		}
+	}
+	for i := range step.Checks {
+		if checkReadsBody(&step.Checks[i]) {
+			return true
+		}
+	}
+	for i := range step.Classify {
+		if checkReadsBody(&step.Classify[i].Check) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsWholeBody reports whether step validates its response body against
+// a JSON Schema or compares it with those of replays, which a truncated
+// body fails. contract is set when the responses of step are validated
+// against the OpenAPI document.
+func needsWholeBody(step *Step, contract bool) bool {
+	if contract {
+		return true
+	}
+	if i := step.Idempotency; i != nil && i.Compare != CompareStatus {
+		return true
+	}
+	for i := range step.Checks {
+		if step.Checks[i].Schema != nil {
+			return true
+		}
 	}
+	for i := range step.Classify {
+		if step.Classify[i].Schema != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func checkReadsBody(c *Check) bool {
+	return c.BodyContains != "" || c.BodyMatches != "" || c.Schema != nil || strings.Contains(c.Condition, "${response.")
+}
 
+func validateTransportRetry(r *TransportRetry) error {
+	if r.Max < 1 || r.Max > maxRetries {
+		return fmt.Errorf("max must be between 1 and %d", maxRetries)
+	}
+	if r.Backoff.Duration == 0 {
+		r.Backoff.Duration = DefaultTransportBackoff
+	}
+	if r.MaxBackoff.Duration == 0 {
+		r.MaxBackoff.Duration = max(DefaultTransportMaxBackoff, r.Backoff.Duration)
+	}
+	if r.Backoff.Duration < 0 || r.MaxBackoff.Duration > maxBackoff || r.MaxBackoff.Duration < r.Backoff.Duration {
+		return fmt.Errorf("backoff and max_backoff must be ordered between 0 and %s", maxBackoff)
+	}
+	for i, code := range r.On {
+		if err := validateStatusCode(code); err != nil {
+			return fmt.Errorf("on[%d]: %w", i, err)
+		}
+	}
+	if r.NonIdempotent && len(r.On) == 0 {
+		return fmt.Errorf("non_idempotent requires statuses to retry on")
+	}
+	if r.Budget == 0 {
+		r.Budget = DefaultRetryBudget
+	}
+	if r.Budget < 0 || r.Budget > 1 {
+		return fmt.Errorf("budget must be between 0 and 1")
+	}
 	return nil
 }
 
-func parseRequest(request string) (method string, path string, err error) {
+func validateBudget(sc *Scenario, budget *Budget) error {
+	if budget.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if len(budget.Steps) == 0 {
+		return fmt.Errorf("at least one step is required")
+	}
+
+	for j, ref := range budget.Steps {
+		if sc.FindStep(ref) == nil {
+			return fmt.Errorf("steps[%d]: step '%s' not found", j, ref)
+		}
+	}
+
+	if budget.Max.Duration <= 0 {
+		return fmt.Errorf("max must be greater than 0")
+	}
+
+	return nil
+}
+
+// ParseRequest splits a step request of the form "METHOD /path" into its
+// method and path, validating both.
+func ParseRequest(request string) (method string, path string, err error) {
 	if request == "" {
 		return "", "", fmt.Errorf("request cannot be empty")
 	}
@@ -176,6 +886,8 @@ func parseRequest(request string) (method string, path string, err error) {
 		http.MethodPatch,
 		http.MethodDelete,
 		http.MethodHead,
+		http.MethodOptions,
+		http.MethodTrace,
 	}
 
 	if !slices.Contains(validMethods, method) {
@@ -190,6 +902,476 @@ func parseRequest(request string) (method string, path string, err error) {
 	return method, path, nil
 }
 
+// Bounds of the noise rate in requests per second. At the lowest, one
+// request an hour, the interval between requests stays representable.
+const (
+	minNoiseRate = 1.0 / 3600
+	maxNoiseRate = 1000
+)
+
+func validateConnections(c *Connections) error {
+	switch {
+	case c.MaxIdle < 0:
+		return fmt.Errorf("max_idle must not be negative")
+	case c.MaxIdlePerHost < 0:
+		return fmt.Errorf("max_idle_per_host must not be negative")
+	case c.MaxPerHost < 0:
+		return fmt.Errorf("max_per_host must not be negative")
+	case c.IdleTimeout.Duration < 0:
+		return fmt.Errorf("idle_timeout must not be negative")
+	}
+	return nil
+}
+
+func validateProxy(proxy *Proxy) error {
+	u, err := url.Parse(proxy.URL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("url: '%s' must be an absolute URL", proxy.URL)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("url: unsupported scheme '%s', must be one of: http, https, socks5, socks5h", u.Scheme)
+	}
+	if proxy.Password != "" && proxy.Username == "" {
+		return fmt.Errorf("password requires a username")
+	}
+	for i, host := range proxy.NoProxy {
+		if host == "" || strings.ContainsAny(host, ", /") && !isCIDR(host) {
+			return fmt.Errorf("no_proxy[%d]: invalid host '%s'", i, host)
+		}
+	}
+	return nil
+}
+
+// maxDNSCacheTTL bounds dns.cache_ttl
+const maxDNSCacheTTL = time.Hour
+
+func validateDNS(dns *DNS) error {
+	if dns.Server != "" {
+		host, port, err := net.SplitHostPort(dns.Server)
+		if err != nil {
+			host, port = dns.Server, "53"
+		}
+		if _, err := netip.ParseAddr(host); err != nil {
+			return fmt.Errorf("server: '%s' must be an IP address with an optional port", dns.Server)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("server: invalid port '%s'", port)
+		}
+	}
+	if dns.CacheTTL.Duration < 0 || dns.CacheTTL.Duration > maxDNSCacheTTL {
+		return fmt.Errorf("cache_ttl must be between 0 and %s", maxDNSCacheTTL)
+	}
+	return nil
+}
+
+func validateHosts(hosts map[string]string) error {
+	for _, host := range slices.Sorted(maps.Keys(hosts)) {
+		if host == "" || strings.ContainsAny(host, ",=/: ") {
+			return fmt.Errorf("invalid host name '%s'", host)
+		}
+		if _, err := netip.ParseAddr(hosts[host]); err != nil {
+			return fmt.Errorf("%s: '%s' must be an IP address", host, hosts[host])
+		}
+	}
+	return nil
+}
+
+// maxRedirects bounds redirects.max
+const maxRedirects = 100
+
+func validateRedirects(r *Redirects) error {
+	switch r.Policy {
+	case "":
+		r.Policy = RedirectFollow
+	case RedirectFollow, RedirectNone, RedirectRecord:
+	default:
+		return fmt.Errorf("unknown policy '%s', must be one of: %s, %s, %s",
+			r.Policy, RedirectFollow, RedirectNone, RedirectRecord)
+	}
+	if r.Max < 0 || r.Max > maxRedirects {
+		return fmt.Errorf("max must be between 0 and %d", maxRedirects)
+	}
+	if r.Max > 0 && r.Policy == RedirectNone {
+		return fmt.Errorf("max does not apply to policy '%s'", RedirectNone)
+	}
+	return nil
+}
+
+func isCIDR(s string) bool {
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+// validateTLS loads the client certificate and CA of the scenario, once
+// validateFiles checked the files among them
+func (p *Parser) validateTLS() error {
+	t := p.scenario.TLS
+	if t == nil {
+		return nil
+	}
+	read := func(value string) ([]byte, error) {
+		if isPEM(value) {
+			return []byte(value), nil
+		}
+		return os.ReadFile(p.ResolvePath(value))
+	}
+
+	if t.InsecureSkipVerify && t.CA != "" {
+		return fmt.Errorf("ca and insecure_skip_verify are mutually exclusive")
+	}
+	if (t.Cert == "") != (t.Key == "") {
+		return fmt.Errorf("cert and key must be set together")
+	}
+	if t.Cert != "" {
+		cert, err := read(t.Cert)
+		if err != nil {
+			return fmt.Errorf("cert: %w", err)
+		}
+		key, err := read(t.Key)
+		if err != nil {
+			return fmt.Errorf("key: %w", err)
+		}
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+		t.Certificate = &pair
+	}
+	if t.CA != "" {
+		ca, err := read(t.CA)
+		if err != nil {
+			return fmt.Errorf("ca: %w", err)
+		}
+		t.RootCAs = x509.NewCertPool()
+		if !t.RootCAs.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("ca: no PEM certificate found")
+		}
+	}
+	return nil
+}
+
+func validateNoise(noise *Noise) error {
+	if len(noise.URLs) == 0 {
+		return fmt.Errorf("at least one url is required")
+	}
+
+	for i, raw := range noise.URLs {
+		if strings.HasPrefix(raw, "/") {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("urls[%d]: '%s' must be an absolute URL or a path starting with '/'", i, raw)
+		}
+	}
+
+	if math.IsNaN(noise.Rate) || noise.Rate <= 0 {
+		return fmt.Errorf("rate must be greater than 0")
+	}
+
+	if noise.Rate < minNoiseRate {
+		return fmt.Errorf("rate must be at least one request per hour")
+	}
+
+	if noise.Rate > maxNoiseRate {
+		return fmt.Errorf("rate must not exceed %d requests per second", maxNoiseRate)
+	}
+
+	return nil
+}
+
+// validateData checks the data feeder strategy, partition and format, and
+// resolves the data file's path
+func (p *Parser) validateData() error {
+	data := p.scenario.Data
+	if data == nil {
+		return nil
+	}
+
+	switch data.Strategy {
+	case "":
+		data.Strategy = DataRoundRobin
+	case DataUnique, DataRoundRobin, DataRandom:
+	default:
+		return fmt.Errorf("scenario.data.strategy: unknown strategy '%s', must be one of: %s, %s, %s",
+			data.Strategy, DataUnique, DataRoundRobin, DataRandom)
+	}
+
+	switch data.Partition {
+	case "":
+		data.Partition = DataPartitionRange
+	case DataPartitionRange, DataPartitionHash:
+	default:
+		return fmt.Errorf("scenario.data.partition: unknown partition '%s', must be one of: %s, %s",
+			data.Partition, DataPartitionRange, DataPartitionHash)
+	}
+	if data.Key != "" && data.Partition != DataPartitionHash {
+		return fmt.Errorf("scenario.data.key only applies to the %s partition", DataPartitionHash)
+	}
+
+	if data.Query != nil {
+		return validateDataQuery(data)
+	}
+	if data.URL != "" {
+		return validateDataURL(data)
+	}
+	if !data.Refresh.IsZero() {
+		return fmt.Errorf("scenario.data.refresh only applies to a url")
+	}
+
+	switch data.Format {
+	case "":
+		format, err := feeder.FormatFromPath(data.File)
+		if err != nil {
+			return fmt.Errorf("scenario.data.file: %w", err)
+		}
+		data.Format = string(format)
+	case DataFormatCSV, DataFormatNDJSON, DataFormatParquet:
+	default:
+		return fmt.Errorf("scenario.data.format: unknown format '%s', must be one of: %s, %s, %s",
+			data.Format, DataFormatCSV, DataFormatNDJSON, DataFormatParquet)
+	}
+
+	data.Path = p.ResolvePath(data.File)
+	return nil
+}
+
+// validateDataQuery checks data fed from a SQL query rather than a file
+func validateDataQuery(data *Data) error {
+	q := data.Query
+	switch {
+	case data.File != "" || data.URL != "":
+		return fmt.Errorf("scenario.data: file, query and url are mutually exclusive")
+	case !data.Refresh.IsZero():
+		return fmt.Errorf("scenario.data.refresh only applies to a url")
+	case data.Format != "":
+		return fmt.Errorf("scenario.data.format only applies to a file")
+	case q.Driver != DataDriverPostgres && q.Driver != DataDriverMySQL:
+		return fmt.Errorf("scenario.data.query.driver: unknown driver '%s', must be one of: %s, %s",
+			q.Driver, DataDriverPostgres, DataDriverMySQL)
+	case q.DSN == "":
+		return fmt.Errorf("scenario.data.query.dsn is required")
+	case strings.TrimSpace(q.SQL) == "":
+		return fmt.Errorf("scenario.data.query.sql is required")
+	case q.Stream && data.Strategy != DataRoundRobin:
+		return fmt.Errorf("scenario.data.query.stream only supports the %s strategy", DataRoundRobin)
+	case q.Stream && data.Partition == DataPartitionHash:
+		return fmt.Errorf("scenario.data.query.stream cannot be combined with the %s partition", DataPartitionHash)
+	}
+	return nil
+}
+
+// validateDataURL checks data fed from an HTTP endpoint rather than a file
+func validateDataURL(data *Data) error {
+	u, err := url.Parse(data.URL)
+	switch {
+	case data.File != "":
+		return fmt.Errorf("scenario.data: file, query and url are mutually exclusive")
+	case data.Format != "":
+		return fmt.Errorf("scenario.data.format only applies to a file")
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		return fmt.Errorf("scenario.data.url: '%s' is not an http(s) URL", data.URL)
+	case data.Refresh.Duration < 0:
+		return fmt.Errorf("scenario.data.refresh cannot be negative")
+	}
+	return nil
+}
+
+func validateStages(sc *Scenario) error {
+	for i := range sc.Stages {
+		stage := &sc.Stages[i]
+		if stage.VUs < 1 || stage.VUs > sc.VirtualUsers {
+			return fmt.Errorf("scenario.stages[%d].vus must be between 1 and virtual_users (%d)", i, sc.VirtualUsers)
+		}
+		if stage.Duration.Duration <= 0 {
+			return fmt.Errorf("scenario.stages[%d].duration must be greater than 0", i)
+		}
+		if stage.Gate == nil {
+			continue
+		}
+		if i == len(sc.Stages)-1 {
+			return fmt.Errorf("scenario.stages[%d].gate: the last stage has no next stage to hold back", i)
+		}
+		if len(stage.Gate.Conditions) == 0 {
+			return fmt.Errorf("scenario.stages[%d].gate.conditions cannot be empty", i)
+		}
+		for j, expr := range stage.Gate.Conditions {
+			if _, err := threshold.Parse(expr); err != nil {
+				return fmt.Errorf("scenario.stages[%d].gate.conditions[%d]: %w", i, j, err)
+			}
+		}
+		if stage.Gate.For.Duration < 0 {
+			return fmt.Errorf("scenario.stages[%d].gate.for cannot be negative", i)
+		}
+	}
+	return nil
+}
+
+// MaxRPSLimit names the rate limit of max_rps, and with the name of a
+// weighted scenario appended after a dot, that of the scenario's max_rps
+const MaxRPSLimit = "max_rps"
+
+func validateRateLimits(sc *Scenario) error {
+	if sc.MaxRPS < 0 {
+		return fmt.Errorf("scenario.max_rps cannot be negative")
+	}
+	for _, name := range slices.Sorted(maps.Keys(sc.RateLimits)) {
+		limit := sc.RateLimits[name]
+		if name == MaxRPSLimit || strings.HasPrefix(name, MaxRPSLimit+".") {
+			return fmt.Errorf("scenario.rate_limits.%s: the name is reserved for max_rps", name)
+		}
+		if limit.Rate <= 0 {
+			return fmt.Errorf("scenario.rate_limits.%s.rate must be greater than 0", name)
+		}
+		if limit.Burst < 0 {
+			return fmt.Errorf("scenario.rate_limits.%s.burst cannot be negative", name)
+		}
+		if limit.Burst == 0 {
+			limit.Burst = 1
+			sc.RateLimits[name] = limit
+		}
+	}
+
+	for i := range sc.Steps {
+		name := sc.Steps[i].RateLimit
+		if _, ok := sc.RateLimits[name]; name != "" && !ok {
+			return fmt.Errorf("step[%d].rate_limit: rate limit '%s' is not defined in scenario.rate_limits", i, name)
+		}
+	}
+	return nil
+}
+
+// secretFilePrefix starts references to secrets read from files, whose
+// paths are relative to the scenario file like other file references
+const secretFilePrefix = "file:"
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// hostSecretSchemes are the secret providers reading from the agent's host:
+// its environment, its files and Vault with the agent's token
+var hostSecretSchemes = []string{"env", "file", "vault"}
+
+// validateRemote rejects the references of a remotely submitted scenario to
+// the agent's files and secrets, see ParseRemote
+func validateRemote(sc *Scenario) error {
+	if refs := sc.fileRefs(); len(refs) > 0 {
+		return fmt.Errorf("%s: files of the agent cannot be referenced by remotely submitted scenarios", refs[0].Field)
+	}
+	for _, name := range slices.Sorted(maps.Keys(sc.Secrets)) {
+		if scheme, _, err := secrets.ParseRef(sc.Secrets[name]); err == nil && slices.Contains(hostSecretSchemes, scheme) {
+			return fmt.Errorf("scenario.secrets.%s: %s secrets of the agent cannot be referenced by remotely submitted scenarios", name, scheme)
+		}
+	}
+	return nil
+}
+
+func validateSecrets(refs map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(refs)) {
+		if !secretNamePattern.MatchString(name) {
+			return fmt.Errorf("scenario.secrets: invalid name '%s', use letters, digits, '_', '-' and '.'", name)
+		}
+		if _, _, err := secrets.ParseRef(refs[name]); err != nil {
+			return fmt.Errorf("scenario.secrets.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// maxLabelHeaders bounds label_headers, since every header multiplies the
+// number of metric series
+const maxLabelHeaders = 5
+
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+// validateLabelHeaders checks label_headers and canonicalizes their names
+func validateLabelHeaders(sc *Scenario) error {
+	if len(sc.LabelHeaders) > maxLabelHeaders {
+		return fmt.Errorf("scenario.label_headers: at most %d headers are allowed", maxLabelHeaders)
+	}
+	seen := make(map[string]bool, len(sc.LabelHeaders))
+	for i, name := range sc.LabelHeaders {
+		if !headerNamePattern.MatchString(name) {
+			return fmt.Errorf("scenario.label_headers[%d]: invalid header name '%s'", i, name)
+		}
+		name = http.CanonicalHeaderKey(name)
+		if seen[name] {
+			return fmt.Errorf("scenario.label_headers[%d]: duplicate header '%s'", i, name)
+		}
+		seen[name] = true
+		sc.LabelHeaders[i] = name
+	}
+	return nil
+}
+
+func validatePayload(p *Payload) error {
+	switch p.Source {
+	case "":
+		p.Source = PayloadGenerated
+	case PayloadGenerated:
+	case PayloadFile:
+		return validateFilePayload(p)
+	default:
+		return fmt.Errorf("source must be one of: %s, %s", PayloadGenerated, PayloadFile)
+	}
+	if p.Path != "" || p.Order != "" {
+		return fmt.Errorf("path and order only apply to file payloads")
+	}
+
+	switch p.Kind {
+	case PayloadJSON:
+		if p.Depth < 0 || p.Fields < 0 {
+			return fmt.Errorf("depth and fields must be non-negative")
+		}
+	case PayloadBinary:
+		if p.Depth != 0 || p.Fields != 0 {
+			return fmt.Errorf("depth and fields only apply to json payloads")
+		}
+	default:
+		return fmt.Errorf("kind must be one of: %s, %s", PayloadJSON, PayloadBinary)
+	}
+
+	if p.Size.Bytes <= 0 {
+		return fmt.Errorf("size must be greater than 0")
+	}
+	if p.Size.Bytes > payload.MaxSize {
+		return fmt.Errorf("size must not exceed %dMB", payload.MaxSize>>20)
+	}
+	return nil
+}
+
+func validateFilePayload(p *Payload) error {
+	if p.Kind != "" || p.Size.Bytes != 0 || p.Depth != 0 || p.Fields != 0 {
+		return fmt.Errorf("kind, size, depth and fields only apply to generated payloads")
+	}
+	if p.Path == "" {
+		return fmt.Errorf("path is required for file payloads")
+	}
+	if _, err := payload.FileFormat(p.Path); err != nil {
+		return err
+	}
+
+	switch p.Order {
+	case "":
+		p.Order = PayloadNext
+	case PayloadNext, PayloadRandom:
+	default:
+		return fmt.Errorf("order must be one of: %s, %s", PayloadNext, PayloadRandom)
+	}
+	return nil
+}
+
+// MatchStatus reports whether status matches code, which is either an exact
+// status ("404") or a class wildcard ("2xx").
+func MatchStatus(code string, status int) bool {
+	if len(code) == 3 && code[1:] == "xx" {
+		return status/100 == int(code[0]-'0')
+	}
+	exact, err := strconv.Atoi(code)
+	return err == nil && exact == status
+}
+
 func validateStatusCode(code string) error {
 	if code == "" {
 		return fmt.Errorf("status code cannot be empty")
@@ -241,3 +1423,136 @@ func validateMapping(source, target string) error {
 
 	return nil
 }
+
+// validateBodyFile checks that a step streaming its body from a file sets
+// no other body and uses a method that takes one
+func validateBodyFile(step *Step) error {
+	if step.BodyFile == "" {
+		return nil
+	}
+	switch {
+	case step.Body != nil:
+		return fmt.Errorf("body and body_file are mutually exclusive")
+	case step.Payload != nil:
+		return fmt.Errorf("payload and body_file are mutually exclusive")
+	}
+	method, _, _ := ParseRequest(step.Request)
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodTrace {
+		return fmt.Errorf("GET, HEAD and TRACE requests cannot have a body_file")
+	}
+	return nil
+}
+
+func validateMultipart(step *Step) error {
+	m := step.Multipart
+	if m == nil {
+		return nil
+	}
+	switch {
+	case step.Body != nil:
+		return fmt.Errorf("body and multipart are mutually exclusive")
+	case step.Payload != nil:
+		return fmt.Errorf("payload and multipart are mutually exclusive")
+	case step.BodyFile != "":
+		return fmt.Errorf("body_file and multipart are mutually exclusive")
+	}
+	method, _, _ := ParseRequest(step.Request)
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodTrace {
+		return fmt.Errorf("GET, HEAD and TRACE requests cannot have a multipart body")
+	}
+	for name := range step.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return fmt.Errorf("multipart sets the Content-Type header, with its boundary")
+		}
+	}
+	if len(m.Fields) == 0 && len(m.Files) == 0 {
+		return fmt.Errorf("multipart needs at least one field or file")
+	}
+	for i, f := range m.Files {
+		if f.Field == "" {
+			return fmt.Errorf("multipart.files[%d]: field is required", i)
+		}
+		if f.Path == "" {
+			return fmt.Errorf("multipart.files[%d]: path is required", i)
+		}
+		if f.ContentType != "" {
+			if _, _, err := mime.ParseMediaType(f.ContentType); err != nil {
+				return fmt.Errorf("multipart.files[%d]: invalid content_type '%s': %w", i, f.ContentType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRaw checks that a raw step sets none of the fields the raw
+// request replaces, and that it has a request line
+func validateRaw(step *Step) error {
+	if step.Raw == "" {
+		return nil
+	}
+	var replaced string
+	switch {
+	case len(step.Headers) > 0:
+		replaced = "headers"
+	case len(step.Query) > 0:
+		replaced = "query"
+	case len(step.PathParams) > 0:
+		replaced = "path_params"
+	case step.Body != nil:
+		replaced = "body"
+	case step.Payload != nil:
+		replaced = "payload"
+	case step.BodyFile != "":
+		replaced = "body_file"
+	case step.Multipart != nil:
+		replaced = "multipart"
+	}
+	if replaced != "" {
+		return fmt.Errorf("%s cannot be combined with raw, write it into the raw request", replaced)
+	}
+	if line, _, _ := strings.Cut(strings.TrimLeft(step.Raw, "\r\n"), "\n"); len(strings.Fields(line)) < 2 {
+		return fmt.Errorf("raw must start with a request line such as 'GET / HTTP/1.1'")
+	}
+	return nil
+}
+
+// validateDelay checks a fixed delay or the parameters of its distribution,
+// all of which must lie between 0 and maxDelay
+func validateDelay(d *Delay) error {
+	for _, f := range []struct {
+		name string
+		v    Duration
+	}{{"delay", d.Duration}, {"delay.min", d.Min}, {"delay.max", d.Max}, {"delay.mean", d.Mean}, {"delay.stddev", d.StdDev}} {
+		if f.v.Duration < 0 || f.v.Duration > maxDelay {
+			return fmt.Errorf("%s must be between 0 and %s", f.name, maxDelay)
+		}
+	}
+	if !d.Max.IsZero() && d.Max.Duration < d.Min.Duration {
+		return fmt.Errorf("delay.max must not be less than delay.min")
+	}
+	switch d.Distribution {
+	case "":
+	case DistributionUniform:
+		if d.Max.IsZero() {
+			return fmt.Errorf("a uniform delay requires max")
+		}
+		if !d.Mean.IsZero() || !d.StdDev.IsZero() {
+			return fmt.Errorf("a uniform delay takes only min and max")
+		}
+	case DistributionNormal:
+		if d.Mean.IsZero() || d.StdDev.IsZero() {
+			return fmt.Errorf("a normal delay requires mean and stddev")
+		}
+	case DistributionExponential:
+		if d.Mean.IsZero() {
+			return fmt.Errorf("an exponential delay requires mean")
+		}
+		if !d.StdDev.IsZero() {
+			return fmt.Errorf("an exponential delay takes no stddev")
+		}
+	default:
+		return fmt.Errorf("unknown delay distribution %q, expected %s, %s or %s", d.Distribution,
+			DistributionUniform, DistributionNormal, DistributionExponential)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package scenario
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// IsMutating reports whether requests with method change state on the
+// server: POST, PUT, PATCH and DELETE requests do
+func IsMutating(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Mutating reports whether the step's request is mutating, see IsMutating.
+// The method of a raw step is that of its request line.
+func (s *Step) Mutating() bool {
+	method, _, _ := strings.Cut(s.Request, " ")
+	if s.Raw != "" {
+		method, _, _ = strings.Cut(strings.TrimLeft(s.Raw, "\r\n"), " ")
+	}
+	return IsMutating(method)
+}
+
+func validateDryRun(step *Step) error {
+	if step.DryRun == "" {
+		return nil
+	}
+	if !step.Mutating() {
+		return fmt.Errorf("dry_run only applies to POST, PUT, PATCH and DELETE steps")
+	}
+	if step.Raw != "" {
+		return fmt.Errorf("dry_run cannot be combined with raw")
+	}
+	if _, _, err := ParseRequest(step.DryRun); err != nil {
+		return fmt.Errorf("dry_run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package scenario
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is a JSON Schema a response body must validate against, either
+// the path of a JSON or YAML file or written inline in the scenario. It
+// supports the keywords of OpenAPI schemas; $ref is not resolved.
+type Schema struct {
+	// File is the path of the schema file, relative to the scenario file
+	File string
+	// Inline is the schema when written in the scenario
+	Inline map[string]any
+	// Path is File resolved against the scenario file's directory once
+	// validated
+	Path string
+}
+
+func (s *Schema) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&s.File); err == nil {
+		return nil
+	}
+	if err := unmarshal(&s.Inline); err != nil {
+		return fmt.Errorf("schema must be a file path or an inline schema: %w", err)
+	}
+	return nil
+}
+
+func (s *Schema) MarshalYAML() (interface{}, error) {
+	if s.File != "" {
+		return s.File, nil
+	}
+	return s.Inline, nil
+}
+
+// String describes the schema for reports
+func (s *Schema) String() string {
+	if s.File != "" {
+		return s.File
+	}
+	return "inline"
+}
+
+// Load reads and validates the schema
+func (s *Schema) Load() (*openapi3.Schema, error) {
+	var (
+		raw []byte
+		err error
+	)
+	if s.File != "" {
+		raw, err = readSchemaFile(cmp.Or(s.Path, s.File))
+	} else {
+		raw, err = json.Marshal(s.Inline)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &openapi3.Schema{}
+	if err := json.Unmarshal(raw, schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return schema, nil
+}
+
+// schemaFiles returns the schema files of the checks and classify rules
+// of step, keyed by their field within it, e.g. "checks[0].schema"
+func (step *Step) schemaFiles() map[string]*Schema {
+	files := make(map[string]*Schema)
+	add := func(field string, c *Check) {
+		if c.Schema != nil && c.Schema.File != "" {
+			files[field] = c.Schema
+		}
+	}
+	for i := range step.Checks {
+		add(fmt.Sprintf("checks[%d].schema", i), &step.Checks[i])
+	}
+	for i := range step.Classify {
+		add(fmt.Sprintf("classify[%d].schema", i), &step.Classify[i].Check)
+	}
+	return files
+}
+
+// validateSchemas resolves the paths of schema files and checks that
+// every schema is valid; inline schemas were checked with their check
+func (p *Parser) validateSchemas() error {
+	for _, list := range p.scenario.stepLists() {
+		for i := range list.steps {
+			files := list.steps[i].schemaFiles()
+			for _, field := range slices.Sorted(maps.Keys(files)) {
+				schema := files[field]
+				schema.Path = p.ResolvePath(schema.File)
+				if _, err := schema.Load(); err != nil {
+					return fmt.Errorf("%s[%d].%s: %w", list.field, i, field, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readSchemaFile returns the content of a JSON or YAML schema file as JSON
+func readSchemaFile(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc map[string]any
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse schema %q: %w", path, err)
+		}
+		return json.Marshal(doc)
+	}
+	return raw, nil
+}
@@ -7,7 +7,7 @@ import (
 	"strings"
 )
 
-// varPattern matches ${varName} placeholders.
+// varPattern matches ${varName} and ${func(args)} placeholders.
 var varPattern = regexp.MustCompile(`\${([^}]+)}`)
 
 type Substitutor struct{}
@@ -17,17 +17,48 @@ func NewSubstitutor() *Substitutor {
 }
 
 func substitute(s string, vars map[string]string) (string, error) {
+	return expand(s, vars, false)
+}
+
+// expand replaces placeholders in s. With inJSON set, s is encoded JSON:
+// placeholders are decoded before evaluation and values are escaped so they
+// stay valid inside JSON strings.
+func expand(s string, vars map[string]string, inJSON bool) (string, error) {
 	var firstErr error
 	result := varPattern.ReplaceAllStringFunc(s, func(match string) string {
 		if firstErr != nil {
 			return match
 		}
-		name := match[2 : len(match)-1]
-		val, ok := vars[name]
-		if !ok {
-			firstErr = fmt.Errorf("undefined variable %q", name)
+		expr := match[2 : len(match)-1]
+		if inJSON {
+			var decoded string
+			if err := json.Unmarshal([]byte(`"`+expr+`"`), &decoded); err != nil {
+				firstErr = fmt.Errorf("invalid placeholder %q: %w", expr, err)
+				return match
+			}
+			expr = decoded
+		}
+
+		val, isCall, err := call(expr, vars)
+		if !isCall {
+			var ok bool
+			if val, ok = vars[expr]; !ok {
+				err = fmt.Errorf("undefined variable %q", expr)
+			}
+		}
+		if err != nil {
+			firstErr = err
 			return match
 		}
+
+		if inJSON {
+			escaped, err := json.Marshal(val)
+			if err != nil {
+				firstErr = fmt.Errorf("failed to JSON-escape %q: %w", expr, err)
+				return match
+			}
+			val = string(escaped[1 : len(escaped)-1])
+		}
 		return val
 	})
 	if firstErr != nil {
@@ -94,16 +125,7 @@ func (s *Substitutor) ApplyToBody(body interface{}, vars map[string]string) (int
 		return body, nil
 	}
 
-	jsonVars := make(map[string]string, len(vars))
-	for k, v := range vars {
-		escaped, err := json.Marshal(v)
-		if err != nil {
-			return nil, fmt.Errorf("failed to JSON-escape variable %q: %w", k, err)
-		}
-		jsonVars[k] = string(escaped[1 : len(escaped)-1])
-	}
-
-	substituted, err := substitute(string(raw), jsonVars)
+	substituted, err := expand(string(raw), vars, true)
 	if err != nil {
 		return nil, fmt.Errorf("body substitution failed: %w", err)
 	}
@@ -162,5 +184,23 @@ func (s *Substitutor) ApplyToStep(step Step, vars map[string]string) (Step, erro
 		result.Body = body
 	}
 
+	if step.Multipart != nil {
+		fields, err := s.ApplyToQuery(step.Multipart.Fields, vars)
+		if err != nil {
+			return Step{}, fmt.Errorf("multipart substitution failed: %w", err)
+		}
+		multipart := *step.Multipart
+		multipart.Fields = fields
+		result.Multipart = &multipart
+	}
+
+	if step.Raw != "" {
+		raw, err := substitute(step.Raw, vars)
+		if err != nil {
+			return Step{}, fmt.Errorf("raw request substitution failed: %w", err)
+		}
+		result.Raw = raw
+	}
+
 	return result, nil
 }
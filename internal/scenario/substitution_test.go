@@ -1,7 +1,10 @@
 package scenario
 
 import (
+	"regexp"
+	"strconv"
 	"testing"
+	"time"
 )
 
 // ============================================================================
@@ -417,6 +420,32 @@ func TestApplyToStep_DoesNotMutateOriginal(t *testing.T) {
 	}
 }
 
+func TestApplyToStep_Multipart(t *testing.T) {
+	s := NewSubstitutor()
+	step := Step{
+		Request: "POST /documents",
+		Multipart: &Multipart{
+			Fields: map[string]string{"owner": "${user_id}"},
+			Files:  []MultipartFile{{Field: "document", Path: "report.pdf"}},
+		},
+	}
+
+	result, err := s.ApplyToStep(step, map[string]string{"user_id": "7"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Multipart.Fields["owner"] != "7" || len(result.Multipart.Files) != 1 {
+		t.Errorf("unexpected multipart: %+v", result.Multipart)
+	}
+	if step.Multipart.Fields["owner"] != "${user_id}" {
+		t.Errorf("original multipart was mutated: %+v", step.Multipart)
+	}
+
+	if _, err := s.ApplyToStep(step, nil); err == nil {
+		t.Error("expected error for undefined variable in multipart field, got nil")
+	}
+}
+
 func TestApplyToStep_EmptyVarsNoPlaceholders(t *testing.T) {
 	s := NewSubstitutor()
 	step := Step{
@@ -530,3 +559,115 @@ func TestApplyToBody_LargeIntegerPreservedAfterSubstitution(t *testing.T) {
 		t.Error("json.Number string should not be empty")
 	}
 }
+
+// ============================================================================
+// Functions
+// ============================================================================
+
+func TestApplyToURL_UUIDFunction(t *testing.T) {
+	s := NewSubstitutor()
+	first, err := s.ApplyToURL("/orders/${uuid()}", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := s.ApplyToURL("/orders/${uuid()}", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !regexp.MustCompile(`^/orders/[0-9a-f-]{36}$`).MatchString(first) {
+		t.Errorf("expected a UUID path, got '%s'", first)
+	}
+	if first == second {
+		t.Errorf("expected a new UUID per substitution, got '%s' twice", first)
+	}
+}
+
+func TestApplyToHeaders_NowISO8601Function(t *testing.T) {
+	s := NewSubstitutor()
+	result, err := s.ApplyToHeaders(map[string]string{"X-Sent-At": "${now_iso8601()}"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent, err := time.Parse(time.RFC3339, result["X-Sent-At"])
+	if err != nil {
+		t.Fatalf("expected an ISO 8601 timestamp, got '%s': %v", result["X-Sent-At"], err)
+	}
+	if d := time.Since(sent); d < 0 || d > time.Minute {
+		t.Errorf("expected the current time, got %v", sent)
+	}
+}
+
+func TestApplyToQuery_RandomIntFunction(t *testing.T) {
+	s := NewSubstitutor()
+	for range 100 {
+		result, err := s.ApplyToQuery(map[string]string{"page": "${random_int(1, 3)}"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n, err := strconv.Atoi(result["page"])
+		if err != nil || n < 1 || n > 3 {
+			t.Fatalf("expected an integer in [1, 3], got '%s'", result["page"])
+		}
+	}
+}
+
+func TestApplyToURL_Base64Function(t *testing.T) {
+	s := NewSubstitutor()
+	vars := map[string]string{"user": "alice:secret"}
+	tests := map[string]string{
+		"/auth/${base64(user)}":     "/auth/YWxpY2U6c2VjcmV0",
+		"/auth/${base64('bob')}":    "/auth/Ym9i",
+		`/auth/${base64("bob")}`:    "/auth/Ym9i",
+		"/auth/${ base64( user ) }": "/auth/YWxpY2U6c2VjcmV0",
+		`/auth/${base64("a,b")}`:    "/auth/YSxi",
+		"/auth/${base64('a, b')}":   "/auth/YSwgYg==",
+	}
+	for url, want := range tests {
+		result, err := s.ApplyToURL(url, vars)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", url, err)
+		}
+		if result != want {
+			t.Errorf("%s: expected '%s', got '%s'", url, want, result)
+		}
+	}
+}
+
+func TestApplyToBody_FunctionInJSON(t *testing.T) {
+	s := NewSubstitutor()
+	body := map[string]interface{}{
+		"auth":  `${base64("a:b")}`,
+		"token": "${base64(token)}",
+	}
+	vars := map[string]string{"token": `quote"d`}
+
+	result, err := s.ApplyToBody(body, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := result.(map[string]interface{})
+	if m["auth"] != "YTpi" {
+		t.Errorf("expected 'YTpi', got %v", m["auth"])
+	}
+	if m["token"] != "cXVvdGUiZA==" {
+		t.Errorf("expected 'cXVvdGUiZA==', got %v", m["token"])
+	}
+}
+
+func TestApplyToURL_FunctionErrors(t *testing.T) {
+	s := NewSubstitutor()
+	for _, url := range []string{
+		"/${nope()}",
+		"/${uuid(1)}",
+		"/${random_int(1)}",
+		"/${random_int(5, 1)}",
+		"/${random_int(a, 1)}",
+		"/${base64(missing)}",
+		`/${base64("a,b)}`,
+		`/${base64("a", "b")}`,
+	} {
+		if _, err := s.ApplyToURL(url, map[string]string{"a": "x"}); err == nil {
+			t.Errorf("%s: expected error, got nil", url)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package scenario
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Weighted is one of several scenarios run concurrently under one test.
+// Each VU runs a single weighted scenario for the whole test; the VUs are
+// shared between them in proportion to their weights.
+type Weighted struct {
+	Name   string  `yaml:"name"`
+	Weight float64 `yaml:"weight"`
+	// Variables are merged over the test's variables
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Budgets   []Budget          `yaml:"budgets,omitempty"`
+	// MaxRPS caps the requests per second of the scenario's VUs, within
+	// the test's max_rps
+	MaxRPS float64 `yaml:"max_rps,omitempty"`
+	Steps  []Step  `yaml:"steps"`
+
+	// FirstStep and StepCount locate the scenario's steps within the
+	// test's Steps once validated
+	FirstStep int `yaml:"-"`
+	StepCount int `yaml:"-"`
+}
+
+// QualifyStep returns the name a step of a weighted scenario is known by
+// in the test, and its metrics are reported under
+func QualifyStep(scenario, step string) string {
+	return scenario + ": " + step
+}
+
+// validateScenarios checks every weighted scenario on its own, then merges
+// their steps and budgets into the test's so that the rest of the run
+// treats them as a single scenario with qualified step names
+func (p *Parser) validateScenarios() error {
+	sc := p.scenario
+	if sc.expanded {
+		return nil
+	}
+
+	if len(sc.Steps) > 0 {
+		return fmt.Errorf("scenario.steps and scenario.scenarios are mutually exclusive")
+	}
+	if len(sc.Budgets) > 0 {
+		return fmt.Errorf("scenario.budgets: define budgets within each of scenario.scenarios")
+	}
+	if sc.VirtualUsers < uint64(len(sc.Scenarios)) {
+		return fmt.Errorf("scenario.virtual_users must be at least the number of scenarios (%d)", len(sc.Scenarios))
+	}
+
+	names := make(map[string]struct{})
+	for i := range sc.Scenarios {
+		w := &sc.Scenarios[i]
+		if w.Name == "" {
+			return fmt.Errorf("scenario.scenarios[%d]: name is required", i)
+		}
+		if _, exists := names[w.Name]; exists {
+			return fmt.Errorf("scenario.scenarios[%d]: duplicate name '%s'", i, w.Name)
+		}
+		names[w.Name] = struct{}{}
+
+		if w.Weight <= 0 {
+			return fmt.Errorf("scenario.scenarios[%d] (%s): weight must be greater than 0", i, w.Name)
+		}
+		if w.MaxRPS < 0 {
+			return fmt.Errorf("scenario.scenarios[%d] (%s): max_rps cannot be negative", i, w.Name)
+		}
+		if err := validateSteps(w.scenario()); err != nil {
+			return fmt.Errorf("scenario.scenarios[%d] (%s): %w", i, w.Name, err)
+		}
+	}
+
+	for i := range sc.Scenarios {
+		sc.expand(&sc.Scenarios[i])
+	}
+	sc.expanded = true
+	return nil
+}
+
+// scenario returns w as a standalone scenario, to resolve step references
+func (w *Weighted) scenario() *Scenario {
+	return &Scenario{Name: w.Name, Variables: w.Variables, Budgets: w.Budgets, Steps: w.Steps}
+}
+
+// expand appends the steps and budgets of w to sc under qualified names.
+// Step references are rewritten to the qualified names of their targets.
+func (sc *Scenario) expand(w *Weighted) {
+	sub := w.scenario()
+	qualify := func(ref string) string {
+		return QualifyStep(w.Name, sub.FindStep(ref).ID())
+	}
+
+	w.FirstStep = len(sc.Steps)
+	w.StepCount = len(w.Steps)
+	for _, step := range w.Steps {
+		step.NextSteps = slices.Clone(step.NextSteps)
+		for j := range step.NextSteps {
+			next := &step.NextSteps[j]
+			next.Step, next.Request = qualify(next.Target()), ""
+		}
+		step.Name = QualifyStep(w.Name, step.ID())
+		sc.Steps = append(sc.Steps, step)
+	}
+
+	for _, b := range w.Budgets {
+		steps := make([]string, len(b.Steps))
+		for j, ref := range b.Steps {
+			steps[j] = qualify(ref)
+		}
+		sc.Budgets = append(sc.Budgets, Budget{Name: QualifyStep(w.Name, b.Name), Steps: steps, Max: b.Max})
+	}
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Env reads secrets from environment variables
+type Env struct{}
+
+func (Env) Lookup(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// File reads secrets from files, such as Docker and Kubernetes secret
+// mounts. A trailing newline is not part of the secret.
+type File struct{}
+
+func (File) Lookup(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// vaultTimeout bounds a single Vault request
+const vaultTimeout = 10 * time.Second
+
+// Vault reads fields of HashiCorp Vault secrets over the HTTP API. Paths
+// are API paths below /v1/ with the field after '#', e.g.
+// secret/data/loadforge#token for the KV version 2 engine mounted at
+// secret/. The field may be omitted when the secret has a single field.
+//
+// The server and token come from VAULT_ADDR, VAULT_TOKEN and
+// VAULT_NAMESPACE unless set on the Vault.
+type Vault struct {
+	Address   string
+	Token     string
+	Namespace string
+	Client    *http.Client
+}
+
+func (v *Vault) Lookup(ctx context.Context, ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+
+	addr := cmp.Or(v.Address, os.Getenv("VAULT_ADDR"))
+	token := cmp.Or(v.Token, os.Getenv("VAULT_TOKEN"))
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("vault: VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, vaultTimeout)
+	defer cancel()
+
+	url := strings.TrimSuffix(addr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := cmp.Or(v.Namespace, os.Getenv("VAULT_NAMESPACE")); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// The error body lists Vault's reasons, never secret data
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("vault: reading %s failed with HTTP %d: %s", path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: invalid response for %s: %w", path, err)
+	}
+	fields := body.Data
+	// KV version 2 nests the fields under data.data, next to data.metadata
+	if inner, ok := fields["data"].(map[string]any); ok {
+		if _, ok := fields["metadata"]; ok {
+			fields = inner
+		}
+	}
+
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("vault: %s has %d fields, select one with %s#<field>", path, len(fields), path)
+		}
+		for k := range fields {
+			field = k
+		}
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("vault: %s has no field %q", path, field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault: field %q of %s is not a string", field, path)
+	}
+	return s, nil
+}
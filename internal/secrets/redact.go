@@ -0,0 +1,45 @@
+package secrets
+
+import (
+	"sort"
+	"strings"
+)
+
+// Redacted replaces secret values in traces, samples and errors
+const Redacted = "[REDACTED]"
+
+// Redactor masks secret values in text that is logged or reported. A nil
+// Redactor leaves text unchanged.
+type Redactor struct {
+	r *strings.Replacer
+}
+
+// NewRedactor masks every non-empty value in values. It returns nil when
+// there is nothing to mask.
+func NewRedactor(values map[string]string) *Redactor {
+	var secrets []string
+	for _, v := range values {
+		if v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	// Longest first, so a secret containing another is masked whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		pairs = append(pairs, s, Redacted)
+	}
+	return &Redactor{r: strings.NewReplacer(pairs...)}
+}
+
+// String returns s with secret values masked
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	return r.r.Replace(s)
+}
@@ -0,0 +1,88 @@
+// Package secrets resolves credentials that scenarios reference by name,
+// so tokens and client secrets never have to be written into scenario
+// files. A reference names a provider and a provider-specific path:
+//
+//	env:API_TOKEN                      environment variable API_TOKEN
+//	file:/run/secrets/client_secret    content of a file
+//	vault:secret/data/loadforge#token  field of a HashiCorp Vault secret
+//
+// Further providers can be added with Register.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Provider looks up the secret at path. Errors must not contain the value.
+type Provider interface {
+	Lookup(ctx context.Context, path string) (string, error)
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{
+		"env":   Env{},
+		"file":  File{},
+		"vault": &Vault{},
+	}
+)
+
+// Register makes p available for references starting with scheme:,
+// replacing any provider registered for it before
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[scheme] = p
+}
+
+func provider(scheme string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[scheme]
+	return p, ok
+}
+
+// ParseRef splits a reference such as env:API_TOKEN into its scheme and
+// path and checks that a provider is registered for the scheme
+func ParseRef(ref string) (scheme, path string, err error) {
+	scheme, path, ok := strings.Cut(ref, ":")
+	if !ok || scheme == "" || path == "" {
+		return "", "", fmt.Errorf("invalid secret reference %q, expected <provider>:<path>", ref)
+	}
+	if _, ok := provider(scheme); !ok {
+		return "", "", fmt.Errorf("unknown secrets provider %q", scheme)
+	}
+	return scheme, path, nil
+}
+
+// Resolve looks up every reference in refs, keyed by secret name, and
+// returns the values under the same names
+func Resolve(ctx context.Context, refs map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(refs))
+	for _, name := range sortedKeys(refs) {
+		scheme, path, err := ParseRef(refs[name])
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", name, err)
+		}
+		p, _ := provider(scheme)
+		value, err := p.Lookup(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
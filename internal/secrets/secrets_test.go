@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	scheme, path, err := ParseRef("vault:secret/data/app#token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scheme != "vault" || path != "secret/data/app#token" {
+		t.Errorf("unexpected split: %s %s", scheme, path)
+	}
+
+	for _, ref := range []string{"", "API_TOKEN", "env:", ":x", "s3:bucket/key"} {
+		if _, _, err := ParseRef(ref); err == nil {
+			t.Errorf("%q: expected error, got nil", ref)
+		}
+	}
+}
+
+func TestResolve_EnvAndFile(t *testing.T) {
+	t.Setenv("LOADFORGE_TEST_TOKEN", "s3cr3t")
+	path := filepath.Join(t.TempDir(), "client_secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := Resolve(context.Background(), map[string]string{
+		"token":  "env:LOADFORGE_TEST_TOKEN",
+		"client": "file:" + path,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["token"] != "s3cr3t" || values["client"] != "from-file" {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestResolve_MissingEnv(t *testing.T) {
+	_, err := Resolve(context.Background(), map[string]string{"token": "env:LOADFORGE_TEST_UNSET"})
+	if err == nil || !strings.Contains(err.Error(), `secret "token"`) {
+		t.Errorf("expected error naming the secret, got %v", err)
+	}
+}
+
+type staticProvider map[string]string
+
+func (p staticProvider) Lookup(ctx context.Context, path string) (string, error) {
+	return p[path], nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", staticProvider{"a": "b"})
+	values, err := Resolve(context.Background(), map[string]string{"x": "test:a"})
+	if err != nil || values["x"] != "b" {
+		t.Errorf("expected registered provider to resolve, got %v (%v)", values, err)
+	}
+}
+
+func TestVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-Vault-Namespace") != "team" {
+			t.Errorf("expected namespace header, got %q", r.Header.Get("X-Vault-Namespace"))
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			w.Write([]byte(`{"data":{"data":{"token":"kv2-token","user":"svc"},"metadata":{"version":3}}}`))
+		case "/v1/kv/single":
+			w.Write([]byte(`{"data":{"password":"kv1-password"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("VAULT_NAMESPACE", "team")
+	v := &Vault{}
+	ctx := context.Background()
+
+	if got, err := v.Lookup(ctx, "secret/data/app#token"); err != nil || got != "kv2-token" {
+		t.Errorf("expected KV v2 field, got %q (%v)", got, err)
+	}
+	if got, err := v.Lookup(ctx, "kv/single"); err != nil || got != "kv1-password" {
+		t.Errorf("expected the only field, got %q (%v)", got, err)
+	}
+	for _, path := range []string{"secret/data/app", "secret/data/app#missing", "secret/data/other#token"} {
+		if _, err := v.Lookup(ctx, path); err == nil {
+			t.Errorf("%s: expected error, got nil", path)
+		}
+	}
+
+	denied := &Vault{Token: "wrong"}
+	if _, err := denied.Lookup(ctx, "kv/single"); err == nil || !strings.Contains(err.Error(), "HTTP 403") {
+		t.Errorf("expected permission error, got %v", err)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor(map[string]string{"short": "abc", "long": "abcdef", "empty": ""})
+	if got := r.String("Bearer abcdef, key=abc"); got != "Bearer [REDACTED], key=[REDACTED]" {
+		t.Errorf("unexpected redaction: %s", got)
+	}
+
+	var none *Redactor = NewRedactor(nil)
+	if none != nil || none.String("abc") != "abc" {
+		t.Error("expected a nil Redactor to leave text unchanged")
+	}
+}
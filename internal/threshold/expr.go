@@ -0,0 +1,306 @@
+package threshold
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Thresholds are small arithmetic expressions over run metrics compared
+// with a single comparison operator:
+//
+//	threshold := expr op expr
+//	expr      := term (("+" | "-") term)*
+//	term      := unary (("*" | "/") unary)*
+//	unary     := "-" unary | primary
+//	primary   := number [unit] | metric | "(" expr ")"
+//
+// Units are ns, us, µs, ms, s, m, h for durations and % for rates. A bare
+// number next to a duration is taken as milliseconds, so "p95 < 500" and
+// "p95 < 500ms" are equivalent.
+
+type valueKind int
+
+const (
+	// kindUntyped is a bare number literal, which adopts the kind of the
+	// operand it is combined with
+	kindUntyped valueKind = iota
+	kindNumber
+	kindDuration
+	kindRate
+)
+
+type node interface {
+	eval(values map[string]float64) float64
+	kind() valueKind
+}
+
+type literal struct {
+	value float64
+	k     valueKind
+}
+
+func (l literal) eval(map[string]float64) float64 { return l.value }
+func (l literal) kind() valueKind                 { return l.k }
+
+type metricRef struct {
+	name string
+	k    valueKind
+}
+
+func (m metricRef) eval(values map[string]float64) float64 { return values[m.name] }
+func (m metricRef) kind() valueKind                        { return m.k }
+
+type negate struct{ operand node }
+
+func (n negate) eval(values map[string]float64) float64 { return -n.operand.eval(values) }
+func (n negate) kind() valueKind                        { return n.operand.kind() }
+
+type binary struct {
+	op          byte
+	left, right node
+	k           valueKind
+}
+
+func (b binary) kind() valueKind { return b.k }
+
+func (b binary) eval(values map[string]float64) float64 {
+	l, r := b.left.eval(values), b.right.eval(values)
+	switch b.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	case '/':
+		// A ratio over an empty run (e.g. errors / requests with no
+		// requests) is defined as 0 rather than NaN
+		if r == 0 {
+			return 0
+		}
+		return l / r
+	}
+	return 0
+}
+
+// newBinary builds a binary node, coercing bare numbers next to durations
+// to milliseconds and inferring the kind of the result.
+func newBinary(op byte, left, right node) node {
+	lk, rk := left.kind(), right.kind()
+
+	if op == '+' || op == '-' {
+		left, right = coerce(left, rk), coerce(right, lk)
+		lk, rk = left.kind(), right.kind()
+	}
+
+	var k valueKind
+	switch {
+	case op == '/' && lk == rk:
+		k = kindNumber
+	case lk == kindDuration || rk == kindDuration:
+		k = kindDuration
+	case lk == kindRate || rk == kindRate:
+		k = kindRate
+	case lk == kindUntyped && rk == kindUntyped:
+		k = kindUntyped
+	default:
+		k = kindNumber
+	}
+
+	return binary{op: op, left: left, right: right, k: k}
+}
+
+// coerce converts a bare number literal to the kind of the other operand
+func coerce(n node, other valueKind) node {
+	lit, ok := n.(literal)
+	if !ok || lit.k != kindUntyped {
+		return n
+	}
+	if other == kindDuration {
+		return literal{value: lit.value * float64(time.Millisecond), k: kindDuration}
+	}
+	return n
+}
+
+var units = map[string]struct {
+	scale float64
+	kind  valueKind
+}{
+	"ns": {float64(time.Nanosecond), kindDuration},
+	"us": {float64(time.Microsecond), kindDuration},
+	"µs": {float64(time.Microsecond), kindDuration},
+	"ms": {float64(time.Millisecond), kindDuration},
+	"s":  {float64(time.Second), kindDuration},
+	"m":  {float64(time.Minute), kindDuration},
+	"h":  {float64(time.Hour), kindDuration},
+	"%":  {0.01, kindRate},
+}
+
+type exprParser struct {
+	input string
+	pos   int
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// comparison parses "expr op expr"
+func (p *exprParser) comparison() (left node, op string, right node, err error) {
+	if left, err = p.expr(); err != nil {
+		return nil, "", nil, err
+	}
+
+	p.skipSpace()
+	for _, candidate := range operators {
+		if strings.HasPrefix(p.input[p.pos:], candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, "", nil, fmt.Errorf("expected comparison operator at position %d", p.pos)
+	}
+	p.pos += len(op)
+
+	if right, err = p.expr(); err != nil {
+		return nil, "", nil, err
+	}
+	if p.peek() != 0 {
+		return nil, "", nil, fmt.Errorf("unexpected '%s' at position %d", p.input[p.pos:], p.pos)
+	}
+
+	return coerce(left, right.kind()), op, coerce(right, left.kind()), nil
+}
+
+func (p *exprParser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = newBinary(op, left, right)
+	}
+}
+
+func (p *exprParser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = newBinary(op, left, right)
+	}
+}
+
+func (p *exprParser) unary() (node, error) {
+	if p.peek() == '-' {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return negate{operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (node, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at position %d", p.pos)
+		}
+		p.pos++
+		return inner, nil
+	case c >= '0' && c <= '9' || c == '.':
+		return p.number()
+	case unicode.IsLetter(rune(c)) || c == '_':
+		return p.metric()
+	default:
+		return nil, fmt.Errorf("unexpected '%c' at position %d", c, p.pos)
+	}
+}
+
+func (p *exprParser) number() (node, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number '%s'", p.input[start:p.pos])
+	}
+
+	unitStart := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(" +-*/()<>=!", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	unit := p.input[unitStart:p.pos]
+	if unit == "" {
+		return literal{value: value, k: kindUntyped}, nil
+	}
+
+	u, ok := units[unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit '%s'", unit)
+	}
+	return literal{value: value * u.scale, k: u.kind}, nil
+}
+
+func (p *exprParser) metric() (node, error) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := rune(p.input[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+			break
+		}
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+
+	k, ok := metricKinds[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric '%s'", name)
+	}
+	return metricRef{name: name, k: k}, nil
+}
@@ -0,0 +1,192 @@
+package threshold
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+)
+
+// Threshold is a pass/fail condition on run metrics. It is either a single
+// metric comparison ("p95 < 500ms") or an expression combining metrics
+// ("errors / requests < 0.01", "p99 - p50 < 200ms").
+type Threshold struct {
+	Expr string
+
+	left, right node
+	op          string
+}
+
+// Result is the outcome of evaluating a threshold at the end of a run
+type Result struct {
+	Expr string
+	// Step is the step the threshold applies to, empty for run totals
+	Step string
+	// Actual is the evaluated left-hand side of the threshold
+	Actual string
+	Passed bool
+}
+
+var metricKinds = map[string]valueKind{
+	"min":           kindDuration,
+	"avg":           kindDuration,
+	"p50":           kindDuration,
+	"p90":           kindDuration,
+	"p95":           kindDuration,
+	"p99":           kindDuration,
+	"p99.9":         kindDuration,
+	"max":           kindDuration,
+	"error_rate":    kindRate,
+	"requests":      kindNumber,
+	"errors":        kindNumber,
+	"rps":           kindNumber,
+	"checks_passed": kindNumber,
+	"checks_failed": kindNumber,
+}
+
+// operators are ordered so two-character operators match first
+var operators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// Parse parses a threshold expression; see expr.go for the grammar
+func Parse(expr string) (*Threshold, error) {
+	p := &exprParser{input: expr}
+	left, op, right, err := p.comparison()
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold %q: %w", expr, err)
+	}
+
+	return &Threshold{Expr: expr, left: left, op: op, right: right}, nil
+}
+
+// Evaluate checks the threshold against stats
+func (t *Threshold) Evaluate(stats metrics.StepStats) Result {
+	values := metricValues(stats)
+	actual := t.left.eval(values)
+
+	return Result{
+		Expr:   t.Expr,
+		Actual: formatValue(t.left.kind(), actual),
+		Passed: compare(actual, t.op, t.right.eval(values)),
+	}
+}
+
+func metricValues(s metrics.StepStats) map[string]float64 {
+	return map[string]float64{
+		"min":           float64(s.Latency.Min),
+		"avg":           float64(s.Latency.Mean),
+		"p50":           float64(s.Latency.P50),
+		"p90":           float64(s.Latency.P90),
+		"p95":           float64(s.Latency.P95),
+		"p99":           float64(s.Latency.P99),
+		"p99.9":         float64(s.Latency.P999),
+		"max":           float64(s.Latency.Max),
+		"error_rate":    s.ErrorRate,
+		"requests":      float64(s.Requests),
+		"errors":        float64(s.Errors),
+		"rps":           s.RPS,
+		"checks_passed": float64(s.ChecksPassed),
+		"checks_failed": float64(s.ChecksFailed),
+	}
+}
+
+func compare(actual float64, op string, value float64) bool {
+	switch op {
+	case "<":
+		return actual < value
+	case "<=":
+		return actual <= value
+	case ">":
+		return actual > value
+	case ">=":
+		return actual >= value
+	case "==":
+		return actual == value
+	case "!=":
+		return actual != value
+	}
+	return false
+}
+
+func formatValue(kind valueKind, v float64) string {
+	switch kind {
+	case kindDuration:
+		return time.Duration(v).String()
+	case kindRate:
+		return strconv.FormatFloat(v*100, 'f', 2, 64) + "%"
+	default:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+}
+
+// Set is a collection of thresholds for run totals and individual steps
+type Set struct {
+	total []*Threshold
+	steps map[string][]*Threshold
+	order []string
+}
+
+// NewSet parses total (run-wide) and per-step thresholds
+func NewSet(total []string, steps map[string][]string, stepOrder []string) (*Set, error) {
+	set := &Set{steps: make(map[string][]*Threshold)}
+
+	for _, expr := range total {
+		t, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		set.total = append(set.total, t)
+	}
+
+	for _, step := range stepOrder {
+		for _, expr := range steps[step] {
+			t, err := Parse(expr)
+			if err != nil {
+				return nil, fmt.Errorf("step '%s': %w", step, err)
+			}
+			set.steps[step] = append(set.steps[step], t)
+		}
+		if len(set.steps[step]) > 0 {
+			set.order = append(set.order, step)
+		}
+	}
+
+	return set, nil
+}
+
+// Empty reports whether the set has no thresholds
+func (s *Set) Empty() bool {
+	return len(s.total) == 0 && len(s.order) == 0
+}
+
+// Evaluate checks all thresholds against summary
+func (s *Set) Evaluate(summary metrics.Summary) []Result {
+	var results []Result
+	for _, t := range s.total {
+		results = append(results, t.Evaluate(summary.Total))
+	}
+
+	byName := make(map[string]metrics.StepStats, len(summary.Steps))
+	for _, step := range summary.Steps {
+		byName[step.Name] = step
+	}
+
+	for _, step := range s.order {
+		for _, t := range s.steps[step] {
+			result := t.Evaluate(byName[step])
+			result.Step = step
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// Passed reports whether every result passed
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
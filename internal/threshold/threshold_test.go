@@ -0,0 +1,144 @@
+package threshold
+
+import (
+	"testing"
+	"time"
+
+	"loadforge-agent/internal/metrics"
+)
+
+func TestParse(t *testing.T) {
+	stats := metrics.StepStats{
+		Requests:     1000,
+		Errors:       5,
+		ErrorRate:    0.005,
+		RPS:          150,
+		ChecksPassed: 980,
+		ChecksFailed: 20,
+		Latency: metrics.LatencyStats{
+			P50:  100 * time.Millisecond,
+			P95:  400 * time.Millisecond,
+			P99:  250 * time.Millisecond,
+			P999: 1200 * time.Millisecond,
+			Mean: 150 * time.Millisecond,
+		},
+	}
+
+	tests := []struct {
+		expr   string
+		passed bool
+		actual string
+	}{
+		{"p95 < 500ms", true, "400ms"},
+		{"p99.9<=1.5s", true, "1.2s"},
+		{"avg < 200", true, "150ms"},
+		{"avg < 100", false, "150ms"},
+		{"error_rate < 1%", true, "0.50%"},
+		{"error_rate <= 0.001", false, "0.50%"},
+		{"rps >= 100", true, "150"},
+		{"errors == 0", false, "5"},
+		{"errors / requests < 0.01", true, "0.005"},
+		{"p99 - p50 < 200ms", true, "150ms"},
+		{"p99 - p50 < 100", false, "150ms"},
+		{"(p95 + p50) / 2 <= 250ms", true, "250ms"},
+		{"p95 / p50 < 3", false, "4"},
+		{"requests * error_rate > 4", true, "500.00%"},
+		{"-errors < 0", true, "-5"},
+		{"checks_failed / requests < 0.01", false, "0.02"},
+		{"checks_passed >= 980", true, "980"},
+	}
+
+	for _, tt := range tests {
+		th, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.expr, err)
+			continue
+		}
+		result := th.Evaluate(stats)
+		if result.Passed != tt.passed || result.Actual != tt.actual {
+			t.Errorf("%q: expected passed=%v actual=%s, got passed=%v actual=%s",
+				tt.expr, tt.passed, tt.actual, result.Passed, result.Actual)
+		}
+	}
+}
+
+func TestEvaluate_DivisionByZero(t *testing.T) {
+	th, err := Parse("errors / requests < 0.01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := th.Evaluate(metrics.StepStats{}); !result.Passed || result.Actual != "0" {
+		t.Errorf("expected 0 for division by zero, got %+v", result)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"p95",
+		"p42 < 1s",
+		"p95 < fast",
+		"error_rate < x%",
+		"requests >",
+		"p95 < 5parsecs",
+		"(p95 < 1s",
+		"p95 + < 1s",
+		"p95 < 1s extra",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", expr)
+		}
+	}
+}
+
+func TestSet_Evaluate(t *testing.T) {
+	set, err := NewSet(
+		[]string{"p95 < 500ms", "error_rate < 1%"},
+		map[string][]string{"login": {"p99 < 100ms"}},
+		[]string{"login", "profile"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	summary := metrics.Summary{
+		Total: metrics.StepStats{
+			Requests:  100,
+			Errors:    5,
+			ErrorRate: 0.05,
+			Latency:   metrics.LatencyStats{P95: 300 * time.Millisecond},
+		},
+		Steps: []metrics.StepStats{
+			{Name: "login", Latency: metrics.LatencyStats{P99: 250 * time.Millisecond}},
+			{Name: "profile"},
+		},
+	}
+
+	results := set.Evaluate(summary)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	if !results[0].Passed {
+		t.Errorf("expected p95 threshold to pass: %+v", results[0])
+	}
+	if results[1].Passed || results[1].Actual != "5.00%" {
+		t.Errorf("expected error_rate threshold to fail with 5.00%%: %+v", results[1])
+	}
+	if results[2].Passed || results[2].Step != "login" || results[2].Actual != "250ms" {
+		t.Errorf("expected login p99 threshold to fail: %+v", results[2])
+	}
+
+	if Passed(results) {
+		t.Error("expected Passed() to be false")
+	}
+	if !Passed(results[:1]) {
+		t.Error("expected Passed() to be true for passing results")
+	}
+}
+
+func TestNewSet_InvalidStepThreshold(t *testing.T) {
+	_, err := NewSet(nil, map[string][]string{"login": {"p99 <"}}, []string{"login"})
+	if err == nil {
+		t.Error("expected error for invalid step threshold, got nil")
+	}
+}
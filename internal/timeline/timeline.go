@@ -0,0 +1,100 @@
+// Package timeline keeps a structured, ordered log of what happened during
+// a run: lifecycle changes, workers joining and leaving, sink failures and
+// threshold breaches. It is the source of truth for reports and the
+// control API; the same events can be echoed as log lines while the run is
+// in progress.
+package timeline
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind classifies an event
+type Kind string
+
+const (
+	SetupFinished     Kind = "setup_finished"
+	RunStarted        Kind = "run_started"
+	RunFinished       Kind = "run_finished"
+	Aborted           Kind = "aborted"
+	Paused            Kind = "paused"
+	Resumed           Kind = "resumed"
+	Scaled            Kind = "scaled"
+	StageStarted      Kind = "stage_started"
+	GateBlocked       Kind = "gate_blocked"
+	GateCleared       Kind = "gate_cleared"
+	BurstStarted      Kind = "burst_started"
+	BurstEnded        Kind = "burst_ended"
+	Recovered         Kind = "recovered"
+	GuardTripped      Kind = "guard_tripped"
+	GuardCleared      Kind = "guard_cleared"
+	GuardFailed       Kind = "guard_failed"
+	DrainTimedOut     Kind = "drain_timed_out"
+	TeardownFinished  Kind = "teardown_finished"
+	TeardownFailed    Kind = "teardown_failed"
+	WorkerJoined      Kind = "worker_joined"
+	WorkerLeft        Kind = "worker_left"
+	WorkerFailed      Kind = "worker_failed"
+	CapacityRestored  Kind = "capacity_restored"
+	SinkFailed        Kind = "sink_failed"
+	SocketPressure    Kind = "socket_pressure"
+	Saturated         Kind = "saturated"
+	ThresholdBreached Kind = "threshold_breached"
+)
+
+// Event is one entry of the log
+type Event struct {
+	Time    time.Time
+	Kind    Kind
+	Message string
+	// Attrs holds structured details, e.g. the worker address
+	Attrs map[string]string
+}
+
+// String formats e as a single log line
+func (e Event) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: %s", e.Time.Format(time.TimeOnly), e.Kind, e.Message)
+	for _, k := range slices.Sorted(maps.Keys(e.Attrs)) {
+		fmt.Fprintf(&b, " %s=%s", k, e.Attrs[k])
+	}
+	return b.String()
+}
+
+// Log is an append-only event log, safe for concurrent use
+type Log struct {
+	mu     sync.Mutex
+	w      io.Writer
+	events []Event
+}
+
+// New creates a Log. If w is not nil every event is also written to it as
+// a line when added.
+func New(w io.Writer) *Log {
+	return &Log{w: w}
+}
+
+// Add appends an event of kind with the given message and attributes
+func (l *Log) Add(kind Kind, message string, attrs map[string]string) {
+	e := Event{Time: time.Now(), Kind: kind, Message: message, Attrs: attrs}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+	if l.w != nil {
+		fmt.Fprintln(l.w, e)
+	}
+}
+
+// Events returns a copy of the events so far, oldest first
+func (l *Log) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.events)
+}
@@ -0,0 +1,36 @@
+package timeline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out)
+	l.Add(RunStarted, "running 'checkout'", map[string]string{"vus": "10", "duration": "1m0s"})
+	l.Add(WorkerFailed, "connection refused", map[string]string{"worker": "http://w1:7070"})
+
+	events := l.Events()
+	if len(events) != 2 || events[0].Kind != RunStarted || events[1].Kind != WorkerFailed {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if events[1].Time.Before(events[0].Time) {
+		t.Error("expected events oldest first")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected every event echoed as a line, got %q", out.String())
+	}
+	if !strings.HasSuffix(lines[0], "run_started: running 'checkout' duration=1m0s vus=10") {
+		t.Errorf("unexpected line with sorted attributes: %q", lines[0])
+	}
+
+	// Events returns a copy
+	events[0].Message = "changed"
+	if l.Events()[0].Message == "changed" {
+		t.Error("expected Events() to return a copy")
+	}
+}
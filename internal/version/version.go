@@ -0,0 +1,105 @@
+// Package version identifies the agent build, so results from a fleet of
+// agents can be traced back to the binary that produced them. Release
+// builds stamp it through the linker:
+//
+//	go build -ldflags "-X loadforge-agent/internal/version.Version=v1.2.0
+//	  -X loadforge-agent/internal/version.Commit=$(git rev-parse HEAD)
+//	  -X loadforge-agent/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Unstamped builds fall back to the VCS details the go command records.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set with -ldflags -X at build time
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running agent binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	// Modified is set when an unstamped build had uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+}
+
+var get = sync.OnceValue(func() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = Commit == "" && s.Value == "true"
+		}
+	}
+	return info
+})
+
+// Get returns the build details of the running agent
+func Get() Info {
+	return get()
+}
+
+// Label identifies the build in metric labels and sample exports: the
+// version, followed by the short commit when known
+func Label() string {
+	return get().Label()
+}
+
+// Label is the compact form of i used by the package-level Label
+func (i Info) Label() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	label := i.Version + "+" + i.Commit[:min(len(i.Commit), 12)]
+	if i.Modified {
+		label += "-dirty"
+	}
+	return label
+}
+
+// String formats i for humans, e.g. for agent version
+func (i Info) String() string {
+	s := i.Version
+	if i.Commit != "" {
+		s += " (commit " + i.Commit
+		if i.Modified {
+			s += "-dirty"
+		}
+		if i.Date != "" {
+			s += ", built " + i.Date
+		}
+		s += ")"
+	} else if i.Date != "" {
+		s += " (built " + i.Date + ")"
+	}
+	return s + " " + i.GoVersion + " " + i.Platform
+}
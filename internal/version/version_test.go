@@ -0,0 +1,40 @@
+package version
+
+import "testing"
+
+func TestInfo_Label(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{Info{Version: "dev"}, "dev"},
+		{Info{Version: "v1.2.0", Commit: "0123456789abcdef0123"}, "v1.2.0+0123456789ab"},
+		{Info{Version: "dev", Commit: "abc123", Modified: true}, "dev+abc123-dirty"},
+	}
+	for _, tt := range tests {
+		if got := tt.info.Label(); got != tt.want {
+			t.Errorf("%+v: expected '%s', got '%s'", tt.info, tt.want, got)
+		}
+	}
+}
+
+func TestInfo_String(t *testing.T) {
+	info := Info{
+		Version:   "v1.2.0",
+		Commit:    "abc123",
+		Date:      "2026-10-01T12:00:00Z",
+		GoVersion: "go1.26.0",
+		Platform:  "linux/arm64",
+	}
+	want := "v1.2.0 (commit abc123, built 2026-10-01T12:00:00Z) go1.26.0 linux/arm64"
+	if got := info.String(); got != want {
+		t.Errorf("expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestGet_Stamped(t *testing.T) {
+	info := Get()
+	if info.Version != Version || info.GoVersion == "" || info.Platform == "" {
+		t.Errorf("unexpected build info: %+v", info)
+	}
+}
@@ -0,0 +1,136 @@
+// Package ratelimit provides token buckets shared by name. The agent
+// shapes the request rate of HTTP steps with them, and step types added by
+// plugins draw from the same buckets so that every protocol shares one
+// rate budget:
+//
+//	bucket, ok := agent.RateLimits().Get("api")
+//	if ok {
+//		if err := bucket.Wait(ctx); err != nil {
+//			return err
+//		}
+//	}
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket refilled at a constant rate up to its burst.
+// It is safe for concurrent use.
+type Bucket struct {
+	rate  float64
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates a full bucket allowing rate events per second, with
+// bursts of up to burst events
+func NewBucket(rate float64, burst int) (*Bucket, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("rate must be greater than 0, got %v", rate)
+	}
+	if burst < 1 {
+		return nil, fmt.Errorf("burst must be at least 1, got %d", burst)
+	}
+	return &Bucket{rate: rate, burst: burst, tokens: float64(burst), last: time.Now()}, nil
+}
+
+// Rate returns the events per second the bucket allows
+func (b *Bucket) Rate() float64 { return b.rate }
+
+// Burst returns the number of events the bucket allows at once
+func (b *Bucket) Burst() int { return b.burst }
+
+// Allow takes a token if one is available right now
+func (b *Bucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Wait takes a token, blocking until one is available or ctx ends. Waiters
+// are served in the order they called Wait.
+func (b *Bucket) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.refill(now)
+	// The token is reserved now; tokens going negative queues later callers
+	// behind this one
+	b.tokens--
+	wait := time.Duration(0)
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return context.Cause(ctx)
+	}
+}
+
+func (b *Bucket) refill(now time.Time) {
+	b.tokens = min(float64(b.burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// Registry holds the named buckets of a run
+type Registry struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{buckets: make(map[string]*Bucket)}
+}
+
+// Define creates the bucket name, or returns it if it exists with the same
+// rate and burst. Redefining a bucket with other settings is an error, so
+// two step types cannot silently disagree on a shared limit.
+func (r *Registry) Define(name string, rate float64, burst int) (*Bucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.buckets[name]; ok {
+		if b.rate != rate || b.burst != burst {
+			return nil, fmt.Errorf("rate limit %q is already defined with rate %v and burst %d", name, b.rate, b.burst)
+		}
+		return b, nil
+	}
+	b, err := NewBucket(rate, burst)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit %q: %w", name, err)
+	}
+	r.buckets[name] = b
+	return b, nil
+}
+
+// Get returns the bucket name
+func (r *Registry) Get(name string) (*Bucket, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buckets[name]
+	return b, ok
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewBucket_Invalid(t *testing.T) {
+	if _, err := NewBucket(0, 1); err == nil {
+		t.Error("expected error for zero rate, got nil")
+	}
+	if _, err := NewBucket(10, 0); err == nil {
+		t.Error("expected error for zero burst, got nil")
+	}
+}
+
+func TestBucket_AllowBurst(t *testing.T) {
+	b, err := NewBucket(1, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range 3 {
+		if !b.Allow() {
+			t.Fatalf("expected token %d of the burst", i+1)
+		}
+	}
+	if b.Allow() {
+		t.Error("expected the bucket to be empty after its burst")
+	}
+}
+
+func TestBucket_WaitPacesCallers(t *testing.T) {
+	b, err := NewBucket(100, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Go(func() {
+			if err := b.Wait(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	// One token at once, then five more at 10ms each
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected about 50ms for 6 events at 100/s, took %s", elapsed)
+	}
+}
+
+func TestBucket_WaitCancelled(t *testing.T) {
+	b, err := NewBucket(1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b.Allow()
+
+	cause := errors.New("run ended")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(cause)
+	if err := b.Wait(ctx); !errors.Is(err, cause) {
+		t.Errorf("expected the context's cause, got %v", err)
+	}
+	// The cancelled reservation is given back
+	if b.tokens < -0.01 || b.tokens > 0.1 {
+		t.Errorf("expected the reservation to be returned, got %v tokens", b.tokens)
+	}
+}
+
+func TestRegistry_Define(t *testing.T) {
+	r := NewRegistry()
+	a, err := r.Define("api", 50, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := r.Define("api", 50, 5)
+	if err != nil || a != b {
+		t.Errorf("expected the same bucket for the same settings, got %v", err)
+	}
+	if _, err := r.Define("api", 100, 5); err == nil {
+		t.Error("expected error redefining a bucket with another rate, got nil")
+	}
+	if got, ok := r.Get("api"); !ok || got != a {
+		t.Error("expected Get to return the defined bucket")
+	}
+	if _, ok := r.Get("missing"); ok {
+		t.Error("expected no bucket for an undefined name")
+	}
+}